@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Config describes a single backtest run.
+//
+// Example:
+//
+//	{
+//	  "data": {"path": "eth.csv", "resample": "1h"},
+//	  "strategy": {"name": "target_weights", "params": {"weights": {"ETH/USD": 0.5}}},
+//	  "initial_cash": "10000",
+//	  "costs": {"fee_rate": "0.001", "min_trade_value": "10"},
+//	  "output": {"dir": "reports"}
+//	}
+type Config struct {
+	Data        DataConfig     `json:"data"`
+	Strategy    StrategyConfig `json:"strategy"`
+	InitialCash string         `json:"initial_cash"`
+	Costs       CostConfig     `json:"costs"`
+	Output      OutputConfig   `json:"output"`
+}
+
+// DataConfig locates the market data and how to prepare it.
+type DataConfig struct {
+	// Path is a CSV file with a header row; see loadSnapshots for the format
+	Path string `json:"path"`
+
+	// Resample optionally downsamples the data to a coarser interval (e.g., "1h")
+	Resample string `json:"resample,omitempty"`
+}
+
+// StrategyConfig selects a built-in strategy and its parameters.
+type StrategyConfig struct {
+	Name   string          `json:"name"`
+	Params strategy.Params `json:"params,omitempty"`
+}
+
+// CostConfig holds trading cost assumptions applied by built-in strategies.
+type CostConfig struct {
+	// FeeRate is the proportional cost per trade (e.g., "0.001" for 10 bps)
+	FeeRate string `json:"fee_rate,omitempty"`
+
+	// MinTradeValue skips trades smaller than this notional
+	MinTradeValue string `json:"min_trade_value,omitempty"`
+}
+
+// OutputConfig controls where reports are written.
+type OutputConfig struct {
+	// Dir receives equity.csv and summary.json; empty disables report files
+	Dir string `json:"dir,omitempty"`
+}
+
+// Costs is the parsed form of CostConfig.
+type Costs struct {
+	FeeRate       primitives.Decimal
+	MinTradeValue primitives.Decimal
+}
+
+// loadConfig reads and validates a JSON config file.
+func loadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate checks required fields and that numeric strings parse.
+func (c *Config) validate() error {
+	if c.Data.Path == "" {
+		return fmt.Errorf("data.path is required")
+	}
+	if c.Strategy.Name == "" {
+		return fmt.Errorf("strategy.name is required")
+	}
+	if _, err := c.initialCash(); err != nil {
+		return err
+	}
+	if _, err := c.costs(); err != nil {
+		return err
+	}
+	if _, err := c.resampleStep(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// initialCash parses InitialCash, defaulting to the backtest engine default.
+func (c *Config) initialCash() (primitives.Amount, error) {
+	if c.InitialCash == "" {
+		return primitives.MustAmount(primitives.MustDecimalFromString("10000")), nil
+	}
+	d, err := primitives.NewDecimalFromString(c.InitialCash)
+	if err != nil {
+		return primitives.Amount{}, fmt.Errorf("initial_cash: %w", err)
+	}
+	amount, err := primitives.NewAmount(d)
+	if err != nil {
+		return primitives.Amount{}, fmt.Errorf("initial_cash: %w", err)
+	}
+	return amount, nil
+}
+
+// costs parses the cost assumptions, treating missing values as zero.
+func (c *Config) costs() (Costs, error) {
+	fee, err := parseOptionalDecimal(c.Costs.FeeRate)
+	if err != nil {
+		return Costs{}, fmt.Errorf("costs.fee_rate: %w", err)
+	}
+	minTrade, err := parseOptionalDecimal(c.Costs.MinTradeValue)
+	if err != nil {
+		return Costs{}, fmt.Errorf("costs.min_trade_value: %w", err)
+	}
+	if fee.IsNegative() || minTrade.IsNegative() {
+		return Costs{}, fmt.Errorf("costs must be non-negative")
+	}
+	return Costs{FeeRate: fee, MinTradeValue: minTrade}, nil
+}
+
+// resampleStep parses Data.Resample, returning zero when resampling is disabled.
+func (c *Config) resampleStep() (primitives.Duration, error) {
+	if c.Data.Resample == "" {
+		return primitives.Duration{}, nil
+	}
+	d, err := time.ParseDuration(c.Data.Resample)
+	if err != nil {
+		return primitives.Duration{}, fmt.Errorf("data.resample: %w", err)
+	}
+	if d <= 0 {
+		return primitives.Duration{}, fmt.Errorf("data.resample must be positive")
+	}
+	return primitives.NewDuration(d), nil
+}
+
+func parseOptionalDecimal(s string) (primitives.Decimal, error) {
+	if s == "" {
+		return primitives.Zero(), nil
+	}
+	return primitives.NewDecimalFromString(s)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/marketdata"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// loadSnapshots reads market data from a CSV file and, when step is
+// positive, downsamples it to that interval.
+func loadSnapshots(path string, step primitives.Duration) ([]strategy.MarketSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data: %w", err)
+	}
+	defer f.Close()
+
+	snapshots, err := parseSnapshots(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	if step.Duration() > 0 {
+		snapshots, err = marketdata.Downsample(snapshots, step)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resample data: %w", err)
+		}
+	}
+	return snapshots, nil
+}
+
+// parseSnapshots converts CSV rows into snapshots, one per distinct timestamp.
+//
+// The header must contain "timestamp", "pair" and either "price" or "close".
+// When "open", "high" and "low" are also present, rows become OHLCV bars
+// ("volume" is optional). Timestamps are RFC 3339 or Unix seconds.
+func parseSnapshots(r io.Reader) ([]strategy.MarketSnapshot, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "pair"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	closeCol, ok := cols["close"]
+	if !ok {
+		if closeCol, ok = cols["price"]; !ok {
+			return nil, fmt.Errorf("missing \"close\" or \"price\" column")
+		}
+	}
+	_, hasOpen := cols["open"]
+	_, hasHigh := cols["high"]
+	_, hasLow := cols["low"]
+	withBars := hasOpen && hasHigh && hasLow
+
+	type rows struct {
+		time primitives.Time
+		bars map[string]strategy.Bar
+	}
+	byTime := make(map[int64]*rows)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		ts, err := parseTimestamp(record[cols["timestamp"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		closePrice, err := parsePrice(record[closeCol])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: close: %w", line, err)
+		}
+		bar := strategy.Bar{Open: closePrice, High: closePrice, Low: closePrice, Close: closePrice, Volume: primitives.ZeroAmount()}
+		if withBars {
+			if bar.Open, err = parsePrice(record[cols["open"]]); err != nil {
+				return nil, fmt.Errorf("line %d: open: %w", line, err)
+			}
+			if bar.High, err = parsePrice(record[cols["high"]]); err != nil {
+				return nil, fmt.Errorf("line %d: high: %w", line, err)
+			}
+			if bar.Low, err = parsePrice(record[cols["low"]]); err != nil {
+				return nil, fmt.Errorf("line %d: low: %w", line, err)
+			}
+		}
+		if i, ok := cols["volume"]; ok && record[i] != "" {
+			d, err := primitives.NewDecimalFromString(record[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: volume: %w", line, err)
+			}
+			if bar.Volume, err = primitives.NewAmount(d); err != nil {
+				return nil, fmt.Errorf("line %d: volume: %w", line, err)
+			}
+		}
+
+		key := ts.UnixNano()
+		entry, ok := byTime[key]
+		if !ok {
+			entry = &rows{time: ts, bars: make(map[string]strategy.Bar)}
+			byTime[key] = entry
+		}
+		entry.bars[record[cols["pair"]]] = bar
+	}
+
+	if len(byTime) == 0 {
+		return nil, fmt.Errorf("no data rows")
+	}
+
+	keys := make([]int64, 0, len(byTime))
+	for k := range byTime {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	snapshots := make([]strategy.MarketSnapshot, 0, len(keys))
+	for _, k := range keys {
+		entry := byTime[k]
+		snap, err := strategy.NewOHLCVSnapshot(entry.time, entry.bars, nil)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot at %s: %w", entry.time, err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, nil
+}
+
+// parseTimestamp accepts RFC 3339 timestamps or Unix seconds.
+func parseTimestamp(s string) (primitives.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return primitives.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return primitives.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return primitives.NewTime(t), nil
+}
+
+func parsePrice(s string) (primitives.Price, error) {
+	d, err := primitives.NewDecimalFromString(s)
+	if err != nil {
+		return primitives.Price{}, err
+	}
+	return primitives.NewPrice(d)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Golden regression tests: each testdata/golden/<name>.config.json is run
+// through the CLI pipeline (load, resample, strategy, engine, metrics) and
+// compared with the committed <name>.golden.json. A diff means a change
+// altered numbers users depend on; if intended, regenerate with
+//
+//	go test ./cmd/cqt -run TestGolden -update
+//
+// and review the golden diff in the commit.
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// goldenTolerance is the absolute difference allowed between decimal fields,
+// absorbing float-derived noise (e.g., square roots in Sharpe) across platforms.
+var goldenTolerance = primitives.MustDecimalFromString("0.000000001")
+
+// goldenResult is the committed form of a regression run.
+type goldenResult struct {
+	Summary summaryReport `json:"summary"`
+	Equity  []string      `json:"equity"`
+}
+
+func TestGolden(t *testing.T) {
+	configs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) == 0 {
+		t.Fatal("no golden configs found")
+	}
+
+	for _, configPath := range configs {
+		name := strings.TrimSuffix(filepath.Base(configPath), ".config.json")
+		t.Run(name, func(t *testing.T) {
+			cfg, err := loadConfig(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := run(context.Background(), cfg, nil)
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+
+			got := goldenResult{Summary: newSummaryReport(cfg.Strategy.Name, result)}
+			history := result.History()
+			for i := 0; i < history.Len(); i++ {
+				got.Equity = append(got.Equity, history.Value(i).String())
+			}
+
+			goldenPath := filepath.Join("testdata", "golden", name+".golden.json")
+			if *update {
+				raw, err := json.MarshalIndent(got, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(goldenPath, append(raw, '\n'), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			raw, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update to create): %v", err)
+			}
+			var want goldenResult
+			if err := json.Unmarshal(raw, &want); err != nil {
+				t.Fatalf("invalid golden file: %v", err)
+			}
+			compareGolden(t, want, got)
+		})
+	}
+}
+
+// compareGolden reports every field that differs, using goldenTolerance for decimals.
+func compareGolden(t *testing.T, want, got goldenResult) {
+	t.Helper()
+
+	w, g := want.Summary, got.Summary
+	if w.Strategy != g.Strategy || w.Start != g.Start || w.End != g.End || w.DataPoints != g.DataPoints {
+		t.Errorf("run shape changed: got %s %s..%s (%d points), want %s %s..%s (%d points)",
+			g.Strategy, g.Start, g.End, g.DataPoints, w.Strategy, w.Start, w.End, w.DataPoints)
+	}
+	fields := []struct {
+		name      string
+		want, got string
+	}{
+		{"initial_value", w.InitialValue, g.InitialValue},
+		{"final_value", w.FinalValue, g.FinalValue},
+		{"total_return", w.TotalReturn, g.TotalReturn},
+		{"annualized_return", w.AnnualizedReturn, g.AnnualizedReturn},
+		{"sharpe", w.Sharpe, g.Sharpe},
+		{"max_drawdown", w.MaxDrawdown, g.MaxDrawdown},
+		{"max_drawdown_amount", w.MaxDrawdownAmount, g.MaxDrawdownAmount},
+	}
+	for _, f := range fields {
+		if !decimalsClose(f.want, f.got) {
+			t.Errorf("%s = %s, want %s", f.name, f.got, f.want)
+		}
+	}
+
+	if len(want.Equity) != len(got.Equity) {
+		t.Fatalf("equity has %d points, want %d", len(got.Equity), len(want.Equity))
+	}
+	for i := range want.Equity {
+		if !decimalsClose(want.Equity[i], got.Equity[i]) {
+			t.Errorf("equity[%d] = %s, want %s (further points not compared)", i, got.Equity[i], want.Equity[i])
+			return
+		}
+	}
+}
+
+func decimalsClose(want, got string) bool {
+	w, err := primitives.NewDecimalFromString(want)
+	if err != nil {
+		return want == got
+	}
+	g, err := primitives.NewDecimalFromString(got)
+	if err != nil {
+		return false
+	}
+	return !w.Sub(g).Abs().GreaterThan(goldenTolerance)
+}
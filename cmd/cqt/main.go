@@ -0,0 +1,110 @@
+// Command cqt runs a backtest described by a JSON config file.
+//
+// Usage:
+//
+//	cqt -config backtest.json [-out reports] [-log info,orders=debug]
+//	cqt -list
+//
+// The config selects a CSV data file, a built-in strategy with parameters,
+// the starting cash and trading costs. cqt prints the result summary and,
+// when an output directory is configured, writes equity.csv, summary.json
+// and experiment.json, the run's experiment record (see backtest.Experiment).
+// -log enables structured engine logs on standard error, with an optional
+// level per component (engine, portfolio, orders).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the JSON backtest config")
+	outDir := flag.String("out", "", "report directory (overrides output.dir in the config)")
+	list := flag.Bool("list", false, "list built-in strategies and exit")
+	logSpec := flag.String("log", "", `log levels, e.g. "info" or "warn,orders=debug" (empty disables logging)`)
+	flag.Parse()
+
+	if *list {
+		for _, name := range strategy.Registered() {
+			fmt.Println(name)
+		}
+		return
+	}
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outDir != "" {
+		cfg.Output.Dir = *outDir
+	}
+
+	var logger *slog.Logger
+	if *logSpec != "" {
+		fallback, levels, err := logging.ParseLevels(*logSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+		logger = slog.New(logging.NewLevelHandler(handler, fallback, levels))
+	}
+
+	result, err := run(context.Background(), cfg, logger)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+
+	fmt.Println(result.Summary())
+	if cfg.Output.Dir != "" {
+		if err := writeReports(cfg.Output.Dir, cfg.Strategy.Name, result); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\nReports written to %s\n", cfg.Output.Dir)
+	}
+}
+
+// run loads data, builds the strategy and executes the backtest for cfg,
+// logging to logger when it is not nil.
+func run(ctx context.Context, cfg *Config, logger *slog.Logger) (*backtest.Result, error) {
+	step, err := cfg.resampleStep()
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := loadSnapshots(cfg.Data.Path, step)
+	if err != nil {
+		return nil, err
+	}
+
+	costs, err := cfg.costs()
+	if err != nil {
+		return nil, err
+	}
+	strat, err := buildStrategy(cfg.Strategy, costs)
+	if err != nil {
+		return nil, err
+	}
+
+	initialCash, err := cfg.initialCash()
+	if err != nil {
+		return nil, err
+	}
+	engine := backtest.NewEngine(backtest.Config{
+		InitialCash: initialCash,
+		Logger:      logger,
+		Experiment:  &backtest.ExperimentConfig{Name: cfg.Strategy.Name, Parameters: cfg.Strategy.Params},
+	})
+	return engine.Run(ctx, strat, snapshots)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+)
+
+// writeTestData writes an hourly ETH/USD CSV with a rise followed by a fall.
+func writeTestData(t *testing.T, dir string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("timestamp,pair,open,high,low,close,volume\n")
+	start := int64(1700000000)
+	for i := 0; i < 48; i++ {
+		price := 2000 + 10*i
+		if i >= 24 {
+			price = 2240 - 10*(i-24)
+		}
+		fmt.Fprintf(&b, "%d,ETH/USD,%d,%d,%d,%d,1.5\n", start+int64(i)*3600, price, price+5, price-5, price)
+	}
+	path := filepath.Join(dir, "eth.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseSnapshots(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    int
+		wantErr bool
+	}{
+		{"close only", "timestamp,pair,price\n2024-01-01T00:00:00Z,ETH/USD,2000\n2024-01-01T01:00:00Z,ETH/USD,2010\n", 2, false},
+		{"multiple pairs per timestamp", "timestamp,pair,close\n1,ETH/USD,2000\n1,BTC/USD,40000\n2,ETH/USD,2010\n", 2, false},
+		{"missing price column", "timestamp,pair\n1,ETH/USD\n", 0, true},
+		{"bad timestamp", "timestamp,pair,close\nyesterday,ETH/USD,1\n", 0, true},
+		{"inconsistent bar", "timestamp,pair,open,high,low,close\n1,ETH/USD,10,9,8,9\n", 0, true},
+		{"no rows", "timestamp,pair,close\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snaps, err := parseSnapshots(strings.NewReader(tt.csv))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(snaps) != tt.want {
+				t.Errorf("expected %d snapshots, got %d", tt.want, len(snaps))
+			}
+		})
+	}
+}
+
+func TestBuildStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     StrategyConfig
+		wantErr bool
+	}{
+		{"buy and hold", StrategyConfig{Name: "buy_and_hold", Params: map[string]interface{}{"pair": "ETH/USD"}}, false},
+		{"target weights", StrategyConfig{Name: "target_weights", Params: map[string]interface{}{
+			"weights": map[string]interface{}{"ETH/USD": 0.4, "BTC/USD": "0.4"},
+		}}, false},
+		{"sma crossover", StrategyConfig{Name: "sma_crossover", Params: map[string]interface{}{"pair": "ETH/USD", "fast": 3.0, "slow": 8.0}}, false},
+		{"dca", StrategyConfig{Name: "dca", Params: map[string]interface{}{"pair": "ETH/USD", "amount": "500", "cadence": "24h"}}, false},
+		{"dca without amount", StrategyConfig{Name: "dca", Params: map[string]interface{}{"pair": "ETH/USD"}}, true},
+		{"dca bad cadence", StrategyConfig{Name: "dca", Params: map[string]interface{}{"pair": "ETH/USD", "amount": "500", "cadence": "weekly"}}, true},
+		{"unknown", StrategyConfig{Name: "martingale"}, true},
+		{"missing pair", StrategyConfig{Name: "buy_and_hold"}, true},
+		{"over-allocated", StrategyConfig{Name: "target_weights", Params: map[string]interface{}{
+			"weights": map[string]interface{}{"ETH/USD": 0.8, "BTC/USD": 0.8},
+		}}, true},
+		{"fast not shorter than slow", StrategyConfig{Name: "sma_crossover", Params: map[string]interface{}{"pair": "ETH/USD", "fast": 8.0, "slow": 8.0}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildStrategy(tt.cfg, Costs{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWritesReports(t *testing.T) {
+	dir := t.TempDir()
+	cfgJSON := fmt.Sprintf(`{
+		"data": {"path": %q, "resample": "2h"},
+		"strategy": {"name": "sma_crossover", "params": {"pair": "ETH/USD", "fast": 2, "slow": 4}},
+		"initial_cash": "10000",
+		"costs": {"fee_rate": "0.001"},
+		"output": {"dir": %q}
+	}`, writeTestData(t, dir), filepath.Join(dir, "out"))
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte(cfgJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := run(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Portfolio.PositionCount() > 1 {
+		t.Errorf("expected at most one position, got %d", result.Portfolio.PositionCount())
+	}
+	if err := writeReports(cfg.Output.Dir, cfg.Strategy.Name, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	equity, err := os.ReadFile(filepath.Join(cfg.Output.Dir, "equity.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(equity), "\n"); lines != result.History().Len()+1 {
+		t.Errorf("expected %d equity lines, got %d", result.History().Len()+1, lines)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(cfg.Output.Dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary summaryReport
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		t.Fatalf("invalid summary JSON: %v", err)
+	}
+	if summary.Strategy != "sma_crossover" || summary.FinalValue != result.FinalValue.String() {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	raw, err = os.ReadFile(filepath.Join(cfg.Output.Dir, "experiment.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var experiment backtest.Experiment
+	if err := json.Unmarshal(raw, &experiment); err != nil {
+		t.Fatalf("invalid experiment JSON: %v", err)
+	}
+	if experiment.RunID == "" || experiment.Strategy != "sma_crossover" || experiment.Parameters["slow"] != float64(4) {
+		t.Errorf("unexpected experiment: %+v", experiment)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"missing data path", `{"strategy": {"name": "buy_and_hold"}}`},
+		{"missing strategy", `{"data": {"path": "x.csv"}}`},
+		{"bad cash", `{"data": {"path": "x.csv"}, "strategy": {"name": "a"}, "initial_cash": "-5"}`},
+		{"bad resample", `{"data": {"path": "x.csv", "resample": "soon"}, "strategy": {"name": "a"}}`},
+		{"negative fee", `{"data": {"path": "x.csv"}, "strategy": {"name": "a"}, "costs": {"fee_rate": "-0.1"}}`},
+		{"malformed", `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadConfig(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
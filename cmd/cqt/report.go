@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+)
+
+// summaryReport is the JSON form of a backtest result.
+type summaryReport struct {
+	Strategy          string `json:"strategy"`
+	Start             string `json:"start"`
+	End               string `json:"end"`
+	DataPoints        int    `json:"data_points"`
+	InitialValue      string `json:"initial_value"`
+	FinalValue        string `json:"final_value"`
+	TotalReturn       string `json:"total_return"`
+	AnnualizedReturn  string `json:"annualized_return"`
+	Sharpe            string `json:"sharpe"`
+	MaxDrawdown       string `json:"max_drawdown"`
+	MaxDrawdownAmount string `json:"max_drawdown_amount"`
+}
+
+func newSummaryReport(name string, result *backtest.Result) summaryReport {
+	history := result.History()
+	n := history.Len()
+	return summaryReport{
+		Strategy:          name,
+		Start:             history.Time(0).Time().UTC().Format(time.RFC3339),
+		End:               history.Time(n - 1).Time().UTC().Format(time.RFC3339),
+		DataPoints:        n,
+		InitialValue:      result.InitialValue.String(),
+		FinalValue:        result.FinalValue.String(),
+		TotalReturn:       result.TotalReturn.String(),
+		AnnualizedReturn:  result.AnnualizedReturn.String(),
+		Sharpe:            result.Sharpe.String(),
+		MaxDrawdown:       result.MaxDrawdown.String(),
+		MaxDrawdownAmount: result.MaxDrawdownAmount.String(),
+	}
+}
+
+// writeReports writes equity.csv (the value history), summary.json and,
+// when the run was tracked, experiment.json into dir.
+func writeReports(dir, name string, result *backtest.Result) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := writeEquityCSV(filepath.Join(dir, "equity.csv"), result); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, "summary.json"), newSummaryReport(name, result)); err != nil {
+		return err
+	}
+	if result.Experiment == nil {
+		return nil
+	}
+	return writeJSON(filepath.Join(dir, "experiment.json"), result.Experiment)
+}
+
+func writeEquityCSV(path string, result *backtest.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"timestamp", "value"}); err != nil {
+		return err
+	}
+	history := result.History()
+	for i := 0; i < history.Len(); i++ {
+		point := history.At(i)
+		row := []string{point.Time.Time().UTC().Format(time.RFC3339), point.Value.String()}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func writeJSON(path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
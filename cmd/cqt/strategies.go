@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/indicators"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func init() {
+	strategy.Register("buy_and_hold", newBuyAndHold)
+	strategy.Register("dca", newDCA)
+	strategy.Register("target_weights", newTargetWeights)
+	strategy.Register("sma_crossover", newSMACrossover)
+}
+
+// buildStrategy constructs a registered strategy, passing the configured
+// trading costs as the "fee_rate" and "min_trade_value" parameters unless
+// the strategy params already set them.
+func buildStrategy(cfg StrategyConfig, costs Costs) (strategy.Strategy, error) {
+	params := make(strategy.Params, len(cfg.Params)+2)
+	for k, v := range cfg.Params {
+		params[k] = v
+	}
+	if _, ok := params["fee_rate"]; !ok {
+		params["fee_rate"] = costs.FeeRate
+	}
+	if _, ok := params["min_trade_value"]; !ok {
+		params["min_trade_value"] = costs.MinTradeValue
+	}
+
+	strat, err := strategy.New(cfg.Name, params)
+	if err != nil {
+		if errors.Is(err, strategy.ErrUnknownStrategy) {
+			return nil, fmt.Errorf("%w (available: %s)", err, strings.Join(strategy.Registered(), ", "))
+		}
+		return nil, err
+	}
+	return strat, nil
+}
+
+// spotPosition is a plain holding of quantity units valued at the pair price.
+type spotPosition struct {
+	pair     string
+	quantity primitives.Decimal
+}
+
+func (p *spotPosition) ID() string                  { return "spot:" + p.pair }
+func (p *spotPosition) Type() strategy.PositionType { return strategy.PositionTypeSpot }
+
+func (p *spotPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price(p.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return primitives.NewAmount(p.quantity.Mul(price.Decimal()))
+}
+
+// spotTarget returns a rebalancer target holding weight of the portfolio in pair.
+func spotTarget(pair string, weight primitives.Decimal) strategy.WeightTarget {
+	return strategy.WeightTarget{
+		PositionID: "spot:" + pair,
+		Weight:     weight,
+		Pair:       pair,
+		Build: func(_ strategy.MarketSnapshot, quantity primitives.Decimal) (strategy.Position, error) {
+			return &spotPosition{pair: pair, quantity: quantity}, nil
+		},
+	}
+}
+
+// rebalancerConfig reads the cost parameters shared by all built-in strategies.
+func rebalancerConfig(params strategy.Params, tolerance primitives.Decimal) (strategy.RebalancerConfig, error) {
+	fee, err := params.Decimal("fee_rate", primitives.Zero())
+	if err != nil {
+		return strategy.RebalancerConfig{}, err
+	}
+	minTrade, err := params.Decimal("min_trade_value", primitives.Zero())
+	if err != nil {
+		return strategy.RebalancerConfig{}, err
+	}
+	return strategy.RebalancerConfig{
+		Tolerance:     tolerance,
+		MinTradeValue: minTrade,
+		CostRate:      fee,
+	}, nil
+}
+
+// newBuyAndHold builds the strategy.BuyAndHold baseline, investing
+// "weight" of the portfolio in "pair".
+func newBuyAndHold(params strategy.Params) (strategy.Strategy, error) {
+	pair, err := params.String("pair", "")
+	if err != nil {
+		return nil, err
+	}
+	weight, err := params.Decimal("weight", primitives.MustDecimalFromString("0.99"))
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := strategy.NewBuyAndHold(pair, weight)
+	if err != nil {
+		return nil, err
+	}
+	return withTradingCosts(baseline, params)
+}
+
+// newDCA builds the strategy.DCA baseline, buying "amount" of "pair" every
+// "cadence" (e.g., "168h").
+func newDCA(params strategy.Params) (strategy.Strategy, error) {
+	pair, err := params.String("pair", "")
+	if err != nil {
+		return nil, err
+	}
+	amount, err := params.Decimal("amount", primitives.Zero())
+	if err != nil {
+		return nil, err
+	}
+	cadenceParam, err := params.String("cadence", "168h")
+	if err != nil {
+		return nil, err
+	}
+	cadence, err := time.ParseDuration(cadenceParam)
+	if err != nil {
+		return nil, fmt.Errorf("%w: param \"cadence\": %v", strategy.ErrInvalidParams, err)
+	}
+	notional, err := primitives.NewAmount(amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: param \"amount\": %v", strategy.ErrInvalidParams, err)
+	}
+	baseline, err := strategy.NewDCA(pair, notional, primitives.NewDuration(cadence))
+	if err != nil {
+		return nil, err
+	}
+	return withTradingCosts(baseline, params)
+}
+
+// tradingCosts charges "fee_rate" on the purchases of a library baseline,
+// which trade without costs, as the rebalancer charges the other built-in
+// strategies.
+type tradingCosts struct {
+	strategy.Strategy
+	feeRate primitives.Decimal
+}
+
+func withTradingCosts(baseline strategy.Strategy, params strategy.Params) (strategy.Strategy, error) {
+	fee, err := params.Decimal("fee_rate", primitives.Zero())
+	if err != nil {
+		return nil, err
+	}
+	if fee.IsZero() {
+		return baseline, nil
+	}
+	return &tradingCosts{Strategy: baseline, feeRate: fee}, nil
+}
+
+// Rebalance batches each purchase of the baseline with its fee, so a
+// skipped purchase is not charged.
+func (s *tradingCosts) Rebalance(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	actions, err := s.Strategy.Rebalance(ctx, portfolio, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	for i, action := range actions {
+		var pair string
+		var units primitives.Decimal
+		switch a := action.(type) {
+		case *strategy.OpenPositionAction:
+			holding, ok := a.Position.(*strategy.Holding)
+			if !ok {
+				continue
+			}
+			pair, units = holding.Pair(), holding.Quantity()
+		case *strategy.ResizePositionAction:
+			position, err := portfolio.GetPosition(a.PositionID)
+			if err != nil {
+				return nil, err
+			}
+			holding, ok := position.(*strategy.Holding)
+			if !ok || a.Relative {
+				continue
+			}
+			pair, units = holding.Pair(), a.Size.Sub(holding.Quantity()).Abs()
+		default:
+			continue
+		}
+		price, err := snapshot.Price(pair)
+		if err != nil {
+			return nil, err
+		}
+		// A batch does not resolve the actions in it
+		if deferred, ok := action.(strategy.DeferredAction); ok {
+			if action, err = deferred.Resolve(snapshot); err != nil {
+				return nil, err
+			}
+		}
+		fee := units.Mul(price.Decimal()).Mul(s.feeRate)
+		actions[i] = strategy.NewBatchAction(action, strategy.NewAdjustCashAction(fee.Neg(), "trading fee"))
+	}
+	return actions, nil
+}
+
+// targetWeights keeps a fixed allocation across pairs, trading when drift exceeds tolerance.
+type targetWeights struct {
+	rebalancer *strategy.TargetWeightRebalancer
+}
+
+func newTargetWeights(params strategy.Params) (strategy.Strategy, error) {
+	weights, err := params.Map("weights")
+	if err != nil {
+		return nil, err
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("%w: param \"weights\" must map pairs to weights", strategy.ErrInvalidParams)
+	}
+	tolerance, err := params.Decimal("tolerance", primitives.MustDecimalFromString("0.05"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := rebalancerConfig(params, tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]string, 0, len(weights))
+	for pair := range weights {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	targets := make([]strategy.WeightTarget, 0, len(pairs))
+	for _, pair := range pairs {
+		weight, err := weights.Decimal(pair, primitives.Zero())
+		if err != nil {
+			return nil, fmt.Errorf("weights: %w", err)
+		}
+		targets = append(targets, spotTarget(pair, weight))
+	}
+
+	rebalancer, err := strategy.NewTargetWeightRebalancer(targets, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &targetWeights{rebalancer: rebalancer}, nil
+}
+
+func (s *targetWeights) Rebalance(_ context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	return s.rebalancer.Actions(portfolio, snapshot)
+}
+
+// smaCrossover holds a pair while its fast moving average is above the slow one.
+type smaCrossover struct {
+	pair     string
+	fast     *indicators.SMA
+	slow     *indicators.SMA
+	long     *strategy.TargetWeightRebalancer
+	flat     *strategy.TargetWeightRebalancer
+	inMarket bool
+}
+
+func newSMACrossover(params strategy.Params) (strategy.Strategy, error) {
+	pair, err := params.String("pair", "")
+	if err != nil {
+		return nil, err
+	}
+	fastPeriod, err := params.Int("fast", 10)
+	if err != nil {
+		return nil, err
+	}
+	slowPeriod, err := params.Int("slow", 30)
+	if err != nil {
+		return nil, err
+	}
+	if fastPeriod >= slowPeriod {
+		return nil, fmt.Errorf("fast period %d must be shorter than slow period %d", fastPeriod, slowPeriod)
+	}
+	weight, err := params.Decimal("weight", primitives.MustDecimalFromString("0.99"))
+	if err != nil {
+		return nil, err
+	}
+
+	fast, err := indicators.NewSMA(fastPeriod)
+	if err != nil {
+		return nil, err
+	}
+	slow, err := indicators.NewSMA(slowPeriod)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := rebalancerConfig(params, primitives.Zero())
+	if err != nil {
+		return nil, err
+	}
+	long, err := strategy.NewTargetWeightRebalancer([]strategy.WeightTarget{spotTarget(pair, weight)}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	flat, err := strategy.NewTargetWeightRebalancer([]strategy.WeightTarget{spotTarget(pair, primitives.Zero())}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &smaCrossover{pair: pair, fast: fast, slow: slow, long: long, flat: flat}, nil
+}
+
+func (s *smaCrossover) Rebalance(_ context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	price, err := snapshot.Price(s.pair)
+	if err != nil {
+		return nil, err
+	}
+	s.fast.Update(price.Decimal())
+	s.slow.Update(price.Decimal())
+	if !s.slow.Ready() {
+		return nil, nil
+	}
+
+	fast, err := s.fast.Value()
+	if err != nil {
+		return nil, err
+	}
+	slow, err := s.slow.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	wantLong := fast.GreaterThan(slow)
+	if wantLong == s.inMarket {
+		return nil, nil
+	}
+	s.inMarket = wantLong
+	if wantLong {
+		return s.long.Actions(portfolio, snapshot)
+	}
+	return s.flat.Actions(portfolio, snapshot)
+}
@@ -16,13 +16,14 @@ import (
 	"time"
 
 	"github.com/daoleno/uniswapv3-sdk/constants"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
 	cl "github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/concentrated_liquidity"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/perpetual"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/tokens"
 )
 
 // LPPosition wraps a mechanisms.PoolPosition to implement strategy.Position interface.
@@ -52,13 +53,20 @@ func (lp *LPPosition) Type() strategy.PositionType {
 }
 
 func (lp *LPPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	return lp.ValueIn(strategy.NewValuationContext(context.Background(), snapshot))
+}
+
+// ValueIn values the position with the run's context, so the pool call is
+// cancelled and traced with the backtest, and prices resolved by the
+// valuation context.
+func (lp *LPPosition) ValueIn(vc *strategy.ValuationContext) (primitives.Amount, error) {
 	// Calculate LP position value
-	amounts, err := lp.pool.RemoveLiquidity(context.Background(), lp.poolPosition)
+	amounts, err := lp.pool.RemoveLiquidity(vc.Context(), lp.poolPosition)
 	if err != nil {
 		return primitives.ZeroAmount(), fmt.Errorf("failed to calculate LP value: %w", err)
 	}
 
-	tokenAPrice, err := snapshot.Price("WETH/USDC")
+	tokenAPrice, err := vc.PriceOf(primitives.MustPair("WETH/USDC"))
 	if err != nil {
 		return primitives.ZeroAmount(), fmt.Errorf("failed to get WETH price: %w", err)
 	}
@@ -90,27 +98,30 @@ func (pp *PerpPosition) Type() strategy.PositionType {
 }
 
 func (pp *PerpPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	return pp.ValueIn(strategy.NewValuationContext(context.Background(), snapshot))
+}
+
+// ValueIn prices the perpetual with the valuation's context and market data.
+func (pp *PerpPosition) ValueIn(vc *strategy.ValuationContext) (primitives.Amount, error) {
 	// Get current mark price
-	markPriceRaw, err := snapshot.Price("WETH/USDC")
+	markPriceRaw, err := vc.PriceOf(primitives.MustPair("WETH/USDC"))
 	if err != nil {
 		return primitives.ZeroAmount(), fmt.Errorf("failed to get mark price: %w", err)
 	}
 
 	// Get funding rate from snapshot metadata
-	fundingRate, ok := snapshot.Get("perp:eth:funding_rate")
-	if !ok {
-		fundingRate = 0.0001 // Default 0.01% per period
+	fundingRateDecimal, err := vc.Decimal(snapshotkeys.PerpFundingRate("eth"))
+	if err != nil {
+		fundingRateDecimal = primitives.MustDecimalFromString("0.0001") // Default 0.01% per period
 	}
 
-	fundingRateDecimal := primitives.NewDecimalFromFloat(fundingRate.(float64))
-
 	// Calculate perpetual value using Price method
 	params := mechanisms.PriceParams{
 		MarkPrice:   markPriceRaw,
 		FundingRate: fundingRateDecimal,
 	}
 
-	value, err := pp.future.Price(context.Background(), params)
+	value, err := pp.future.Price(vc.Context(), params)
 	if err != nil {
 		return primitives.ZeroAmount(), fmt.Errorf("failed to price perpetual: %w", err)
 	}
@@ -161,9 +172,9 @@ func (s *DeltaNeutralStrategy) Rebalance(
 	}
 
 	// 1. Create LP position
-	sqrtPriceX96Str, ok := snapshot.Get("pool:eth-usdc-pool:sqrt_price_x96")
-	if !ok {
-		return nil, fmt.Errorf("sqrt price not available")
+	sqrtPriceX96Str, err := strategy.GetString(snapshot, snapshotkeys.PoolSqrtPrice("eth-usdc-pool"))
+	if err != nil {
+		return nil, fmt.Errorf("sqrt price not available: %w", err)
 	}
 
 	lpPoolPosition := mechanisms.PoolPosition{
@@ -177,7 +188,7 @@ func (s *DeltaNeutralStrategy) Rebalance(
 			"liquidity":      s.lpLiquidityAmt.Decimal().String(),
 			"tick_lower":     s.tickLower,
 			"tick_upper":     s.tickUpper,
-			"sqrt_price_x96": sqrtPriceX96Str.(string),
+			"sqrt_price_x96": sqrtPriceX96Str,
 		},
 	}
 
@@ -206,6 +217,8 @@ func (s *DeltaNeutralStrategy) Rebalance(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create perpetual: %w", err)
 	}
+	// Stamp funding with snapshot time so the backtest is reproducible
+	perpFuture.SetClock(strategy.Clock(ctx))
 
 	perpPos := NewPerpPosition(perpFuture)
 
@@ -246,12 +259,12 @@ func createHistoricalSnapshots() []strategy.MarketSnapshot {
 
 		// Pool metadata
 		currentTick := 200000 + (day * 100)
-		snapshot.Set("pool:eth-usdc-pool:current_tick", currentTick)
-		snapshot.Set("pool:eth-usdc-pool:sqrt_price_x96", "1584563250000000000000000000000")
+		snapshot.Set(snapshotkeys.PoolCurrentTick("eth-usdc-pool"), currentTick)
+		snapshot.Set(snapshotkeys.PoolSqrtPrice("eth-usdc-pool"), "1584563250000000000000000000000")
 
 		// Perpetual funding rate (varies slightly)
 		fundingRate := 0.0001 + (float64(day%5) * 0.00001)
-		snapshot.Set("perp:eth:funding_rate", fundingRate)
+		snapshot.Set(snapshotkeys.PerpFundingRate("eth"), fundingRate)
 
 		snapshots = append(snapshots, snapshot)
 	}
@@ -264,15 +277,16 @@ func main() {
 	fmt.Println("Combining LP position with perpetual hedge")
 	fmt.Println()
 
-	// 1. Create concentrated liquidity pool
-	pool, err := cl.NewPool(
-		"eth-usdc-pool",
-		common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"), // WETH
-		18,
-		common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), // USDC
-		6,
-		constants.FeeAmount(3000),
-	)
+	// 1. Create concentrated liquidity pool from registered mainnet tokens
+	weth, err := tokens.Lookup(tokens.Mainnet, "WETH")
+	if err != nil {
+		log.Fatalf("Failed to look up WETH: %v", err)
+	}
+	usdc, err := tokens.Lookup(tokens.Mainnet, "USDC")
+	if err != nil {
+		log.Fatalf("Failed to look up USDC: %v", err)
+	}
+	pool, err := cl.NewPoolForTokens("eth-usdc-pool", weth, usdc, constants.FeeAmount(3000))
 	if err != nil {
 		log.Fatalf("Failed to create pool: %v", err)
 	}
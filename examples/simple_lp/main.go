@@ -245,7 +245,7 @@ func main() {
 	for _, pos := range positions {
 		fmt.Printf("  - Position ID: %s\n", pos.ID())
 		fmt.Printf("    Type: %s\n", pos.Type())
-		if len(result.ValueHistory) > 0 {
+		if result.History().Len() > 0 {
 			lastSnapshot := snapshots[len(snapshots)-1]
 			value, err := pos.Value(lastSnapshot)
 			if err != nil {
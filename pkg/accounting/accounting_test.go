@@ -0,0 +1,268 @@
+package accounting_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/accounting"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var testStart = primitives.Unix(1700000000, 0)
+
+// holding is a spot position valued at quantity * price.
+type holding struct {
+	id       string
+	pair     string
+	quantity primitives.Decimal
+}
+
+func (h *holding) ID() string                  { return h.id }
+func (h *holding) Type() strategy.PositionType { return strategy.PositionTypeSpot }
+func (h *holding) Value(s strategy.MarketSnapshot) (primitives.Amount, error) {
+	price, err := s.Price(h.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return primitives.NewAmount(h.quantity.Mul(price.Decimal()))
+}
+
+func snapshotAt(hours int64, price int64) strategy.MarketSnapshot {
+	return strategy.NewSimpleSnapshot(testStart.Add(primitives.Hours(hours)), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(price)),
+	})
+}
+
+func dec(v int64) primitives.Decimal { return primitives.NewDecimal(v) }
+
+// assertBalanced checks that total debits equal total credits.
+func assertBalanced(t *testing.T, ledger *accounting.Ledger) {
+	t.Helper()
+	total := primitives.Zero()
+	for _, account := range ledger.Accounts() {
+		total = total.Add(ledger.Balance(account))
+	}
+	if !total.IsZero() {
+		t.Errorf("ledger out of balance by %s", total)
+	}
+}
+
+func TestLedgerLifecycle(t *testing.T) {
+	portfolio := strategy.NewPortfolio(primitives.MustAmount(dec(10000)))
+	ledger := accounting.NewLedger()
+
+	buy := strategy.NewBatchAction(
+		strategy.NewAddPositionAction(&holding{id: "eth", pair: "ETH/USD", quantity: dec(2)}),
+		strategy.NewAdjustCashAction(dec(-4010), "buy 2 ETH incl. 10 fee"),
+	)
+	if err := ledger.Apply(portfolio, snapshotAt(0, 2000), buy); !errors.Is(err, accounting.ErrNotOpened) {
+		t.Fatalf("expected %v, got %v", accounting.ErrNotOpened, err)
+	}
+
+	if err := ledger.Open(testStart, portfolio, snapshotAt(0, 2000)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ledger.Apply(portfolio, snapshotAt(0, 2000), buy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("trade posts position, cash and fee residual", func(t *testing.T) {
+		checks := map[string]int64{
+			accounting.AccountCash:            5990,
+			accounting.PositionAccount("eth"): 4000,
+			accounting.AccountCapital:         -10000,
+			accounting.AccountSettlement:      10,
+			accounting.AccountUnrealizedPnL:   0,
+			accounting.AccountRealizedPnL:     0,
+		}
+		for account, want := range checks {
+			if got := ledger.Balance(account); !got.Equal(dec(want)) {
+				t.Errorf("%s: expected %d, got %s", account, want, got)
+			}
+		}
+		assertBalanced(t, ledger)
+	})
+
+	t.Run("mark to market", func(t *testing.T) {
+		if err := ledger.MarkToMarket(portfolio, snapshotAt(1, 2100)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ledger.Balance(accounting.PositionAccount("eth")); !got.Equal(dec(4200)) {
+			t.Errorf("expected carried value 4200, got %s", got)
+		}
+		if got := ledger.Balance(accounting.AccountUnrealizedPnL); !got.Equal(dec(-200)) {
+			t.Errorf("expected unrealized gain of 200 (credit), got %s", got)
+		}
+		assertBalanced(t, ledger)
+	})
+
+	t.Run("close realizes gain", func(t *testing.T) {
+		sell := strategy.NewBatchAction(
+			strategy.NewRemovePositionAction("eth"),
+			strategy.NewAdjustCashAction(dec(4400), "sell 2 ETH"),
+		)
+		if err := ledger.Apply(portfolio, snapshotAt(2, 2200), sell); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ledger.Balance(accounting.PositionAccount("eth")); !got.IsZero() {
+			t.Errorf("expected closed position account to be zero, got %s", got)
+		}
+		if got := ledger.Balance(accounting.AccountRealizedPnL); !got.Equal(dec(-200)) {
+			t.Errorf("expected realized gain of 200 (credit), got %s", got)
+		}
+		if got := ledger.Balance(accounting.AccountCash); !got.Equal(portfolio.CashDecimal()) {
+			t.Errorf("ledger cash %s does not reconcile with portfolio cash %s", got, portfolio.CashDecimal())
+		}
+		assertBalanced(t, ledger)
+	})
+
+	t.Run("failed action posts nothing", func(t *testing.T) {
+		before := len(ledger.Entries())
+		err := ledger.Apply(portfolio, snapshotAt(3, 2200), strategy.NewRemovePositionAction("missing"))
+		if !errors.Is(err, strategy.ErrPositionNotFound) {
+			t.Errorf("expected %v, got %v", strategy.ErrPositionNotFound, err)
+		}
+		if len(ledger.Entries()) != before {
+			t.Error("expected no entries for failed action")
+		}
+	})
+
+	t.Run("valuation failure leaves ledger unchanged", func(t *testing.T) {
+		before := len(ledger.Entries())
+		unpriced := &holding{id: "btc", pair: "BTC/USD", quantity: dec(1)}
+		err := ledger.Apply(portfolio, snapshotAt(3, 2200), strategy.NewAddPositionAction(unpriced))
+		if !errors.Is(err, strategy.ErrPriceNotAvailable) {
+			t.Errorf("expected %v, got %v", strategy.ErrPriceNotAvailable, err)
+		}
+		if len(ledger.Entries()) != before {
+			t.Error("expected no entries when a new position cannot be valued")
+		}
+		// The action itself succeeded; rolling back is the caller's job
+		if err := portfolio.RemovePosition("btc"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ledger.MarkToMarket(portfolio, snapshotAt(3, 2200)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ledger.Balance(accounting.PositionAccount("btc")); !got.IsZero() {
+			t.Errorf("expected no carried value for btc, got %s", got)
+		}
+		assertBalanced(t, ledger)
+	})
+
+	t.Run("savepoint rollback restores carried values", func(t *testing.T) {
+		ledger.BeginJournal()
+		defer ledger.EndJournal()
+		portfolio.BeginJournal()
+		defer portfolio.EndJournal()
+
+		before, cashBefore := len(ledger.Entries()), portfolio.CashDecimal()
+		outer, outerPortfolio := ledger.Savepoint(), portfolio.Savepoint()
+		buy := strategy.NewBatchAction(
+			strategy.NewAddPositionAction(&holding{id: "eth2", pair: "ETH/USD", quantity: dec(1)}),
+			strategy.NewAdjustCashAction(dec(-2200), "buy 1 ETH"),
+		)
+		if err := ledger.Apply(portfolio, snapshotAt(4, 2200), buy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		inner := ledger.Savepoint()
+		if err := ledger.MarkToMarket(portfolio, snapshotAt(5, 2300)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ledger.RollbackTo(inner)
+		unrealized := ledger.Balance(accounting.AccountUnrealizedPnL)
+		if err := ledger.MarkToMarket(portfolio, snapshotAt(5, 2200)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ledger.Balance(accounting.AccountUnrealizedPnL); !got.Equal(unrealized) {
+			t.Errorf("expected carried value restored to 2200, unrealized moved to %s", got)
+		}
+
+		ledger.RollbackTo(outer)
+		portfolio.RollbackTo(outerPortfolio)
+		if len(ledger.Entries()) != before {
+			t.Errorf("expected %d entries after rollback, got %d", before, len(ledger.Entries()))
+		}
+		if portfolio.HasPosition("eth2") || !portfolio.CashDecimal().Equal(cashBefore) {
+			t.Errorf("expected portfolio rolled back, got %s", portfolio.Summary(nil))
+		}
+		assertBalanced(t, ledger)
+	})
+
+	t.Run("CSV export", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ledger.WriteCSV(&buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if lines[0] != "tx_id,time,account,debit,credit,reason" {
+			t.Errorf("unexpected header %q", lines[0])
+		}
+		if len(lines) != len(ledger.Entries())+1 {
+			t.Errorf("expected %d lines, got %d", len(ledger.Entries())+1, len(lines))
+		}
+	})
+}
+
+// coinHolding is a holding valued in ETH rather than the base currency.
+type coinHolding struct {
+	holding
+}
+
+func (h *coinHolding) Denomination() string { return "ETH" }
+func (h *coinHolding) Value(strategy.MarketSnapshot) (primitives.Amount, error) {
+	return primitives.NewAmount(h.quantity)
+}
+
+func TestLedgerBaseCurrency(t *testing.T) {
+	portfolio := strategy.NewPortfolio(primitives.MustAmount(dec(10000)))
+	portfolio.SetBaseCurrency("USD")
+	ledger := accounting.NewLedger()
+	if err := ledger.Open(testStart, portfolio, snapshotAt(0, 2000)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buy := strategy.NewBatchAction(
+		strategy.NewAddPositionAction(&coinHolding{holding{id: "eth", quantity: dec(2)}}),
+		strategy.NewAdjustCashAction(dec(-4000), "buy 2 ETH"),
+	)
+	if err := ledger.Apply(portfolio, snapshotAt(0, 2000), buy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ledger.Balance(accounting.PositionAccount("eth")); !got.Equal(dec(4000)) {
+		t.Errorf("expected 2 ETH carried at 4000 USD, got %s", got)
+	}
+	if got := ledger.Balance(accounting.AccountSettlement); !got.IsZero() {
+		t.Errorf("expected the purchase to offset in USD, settlement %s", got)
+	}
+
+	if err := ledger.MarkToMarket(portfolio, snapshotAt(1, 2100)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ledger.Balance(accounting.AccountUnrealizedPnL); !got.Equal(dec(-200)) {
+		t.Errorf("expected unrealized gain of 200 USD (credit), got %s", got)
+	}
+
+	// Cross rates in a valuation context convert positions whose
+	// denomination the snapshot quotes only through another asset
+	resolver, err := strategy.NewCrossRateResolver(2, primitives.Duration{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	crossed := strategy.NewValuationContext(context.Background(), strategy.NewSimpleSnapshot(testStart.Add(primitives.Hours(2)), map[string]primitives.Price{
+		"ETH/BTC": primitives.MustPrice(primitives.MustDecimalFromString("0.05")),
+		"BTC/USD": primitives.MustPrice(dec(44000)),
+	}), strategy.WithCrossRates(resolver))
+	if err := ledger.MarkToMarket(portfolio, crossed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ledger.Balance(accounting.PositionAccount("eth")); !got.Equal(dec(4400)) {
+		t.Errorf("expected 2 ETH carried at 4400 USD through BTC, got %s", got)
+	}
+	assertBalanced(t, ledger)
+}
@@ -0,0 +1,33 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteCSV writes all entries as CSV with the header
+// tx_id,time,account,debit,credit,reason.
+func (l *Ledger) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"tx_id", "time", "account", "debit", "credit", "reason"}); err != nil {
+		return fmt.Errorf("failed to write ledger header: %w", err)
+	}
+	for _, e := range l.entries {
+		row := []string{
+			strconv.Itoa(e.TxID),
+			e.Time.Time().UTC().Format(time.RFC3339),
+			e.Account,
+			e.Debit.String(),
+			e.Credit.String(),
+			e.Reason,
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write ledger entry: %w", err)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
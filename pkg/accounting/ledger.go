@@ -0,0 +1,573 @@
+// Package accounting records portfolio activity as double-entry ledger
+// entries so simulated P&L can be audited and reconciled.
+//
+// Every change to a portfolio is posted as a balanced transaction: the sum
+// of debits equals the sum of credits. Entries are derived by comparing the
+// portfolio before and after an Action is applied, so any Action type —
+// including custom ones — is recorded without the ledger knowing about it.
+//
+// Accounts:
+//   - cash: the portfolio's cash balance
+//   - position:<id>: the carried value of each position, converted like
+//     cash to the portfolio's base currency (see
+//     strategy.Portfolio.PositionValue)
+//   - equity:capital: opening capital
+//   - settlement: the counterparty to trades; a non-zero balance means cash
+//     and position changes did not offset (e.g., fees or funding)
+//   - pnl:realized / pnl:unrealized: gains and losses on positions
+//   - pnl:rewards: incentive rewards accrued by positions implementing
+//     RewardBearer, split out of unrealized P&L at each mark
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Standard account names.
+const (
+	AccountCash          = "cash"
+	AccountCapital       = "equity:capital"
+	AccountSettlement    = "settlement"
+	AccountRealizedPnL   = "pnl:realized"
+	AccountUnrealizedPnL = "pnl:unrealized"
+	AccountRewardPnL     = "pnl:rewards"
+	AccountInterestPnL   = "pnl:interest"
+	AccountFinancingPnL  = "pnl:financing"
+)
+
+var (
+	// ErrUnbalanced indicates a transaction whose debits and credits differ
+	ErrUnbalanced = errors.New("transaction does not balance")
+
+	// ErrNotOpened indicates the ledger was used before Open
+	ErrNotOpened = errors.New("ledger not opened")
+)
+
+// PositionAccount returns the account name carrying a position's value.
+func PositionAccount(positionID string) string {
+	return "position:" + positionID
+}
+
+// RewardBearer is an optional interface for positions whose value includes
+// accrued incentive rewards (e.g., liquidity-mining emissions).
+// MarkToMarket posts the change in reward value to AccountRewardPnL and
+// only the remainder to AccountUnrealizedPnL.
+type RewardBearer interface {
+	strategy.Position
+
+	// RewardValue returns the part of Value due to unclaimed rewards.
+	RewardValue(snapshot strategy.MarketSnapshot) (primitives.Amount, error)
+}
+
+// rewardKey is the book key carrying a position's reward value. Keeping it
+// in the book lets journaling and checkpoints cover it.
+func rewardKey(positionID string) string {
+	return "rewards:" + positionID
+}
+
+// Entry is one line of a ledger transaction. Exactly one of Debit and
+// Credit is positive.
+type Entry struct {
+	TxID    int
+	Time    primitives.Time
+	Account string
+	Debit   primitives.Decimal
+	Credit  primitives.Decimal
+	Reason  string
+}
+
+// Ledger is an append-only double-entry journal of portfolio activity.
+//
+// Thread Safety: Ledger is not thread-safe, matching the backtest engine's
+// single-goroutine execution model.
+type Ledger struct {
+	entries []Entry
+	nextTx  int
+	opened  bool
+
+	// book is the carried value of each open position
+	book map[string]primitives.Decimal
+
+	// undo records prior book values while journaling (see BeginJournal)
+	undo       []bookChange
+	journaling bool
+
+	// valuer values positions, PortfolioValuer if nil (see SetValuer)
+	valuer Valuer
+}
+
+// Valuer values positions for a Ledger, in the portfolio's base currency.
+// The backtest engine sets its own so the ledger values positions as the
+// run does, under its valuation context and valuation error policy.
+type Valuer interface {
+	// PositionValue returns position's value.
+	PositionValue(portfolio *strategy.Portfolio, position strategy.Position, snapshot strategy.MarketSnapshot) (primitives.Decimal, error)
+
+	// RewardValue returns the part of bearer's value due to unclaimed
+	// rewards.
+	RewardValue(portfolio *strategy.Portfolio, bearer RewardBearer, snapshot strategy.MarketSnapshot) (primitives.Decimal, error)
+}
+
+// PortfolioValuer is the default Valuer: it values positions with
+// strategy.Portfolio.PositionValue, converting rewards likewise.
+type PortfolioValuer struct{}
+
+// PositionValue implements Valuer.
+func (PortfolioValuer) PositionValue(portfolio *strategy.Portfolio, position strategy.Position, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	value, err := portfolio.PositionValue(position, snapshot)
+	if err != nil {
+		return primitives.Decimal{}, err
+	}
+	return value.Decimal(), nil
+}
+
+// RewardValue implements Valuer.
+func (PortfolioValuer) RewardValue(portfolio *strategy.Portfolio, bearer RewardBearer, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	reward, err := bearer.RewardValue(snapshot)
+	if err != nil {
+		return primitives.Decimal{}, err
+	}
+	if reward, err = portfolio.ToBase(bearer, snapshot, reward); err != nil {
+		return primitives.Decimal{}, err
+	}
+	return reward.Decimal(), nil
+}
+
+// bookChange records a book entry's value before it was changed.
+type bookChange struct {
+	id     string
+	value  primitives.Decimal
+	exists bool
+}
+
+// NewLedger creates an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{book: make(map[string]primitives.Decimal)}
+}
+
+// SetValuer sets how the ledger values positions; nil restores
+// PortfolioValuer.
+func (l *Ledger) SetValuer(valuer Valuer) {
+	l.valuer = valuer
+}
+
+// Open records opening capital and any positions already in the portfolio.
+// Must be called before Apply or MarkToMarket. Opening an already opened
+// ledger discards its entries and starts a fresh journal, so an engine
+// configured with a ledger can be run more than once.
+func (l *Ledger) Open(t primitives.Time, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) error {
+	if portfolio == nil {
+		return strategy.ErrNilPortfolio
+	}
+
+	tx := l.newTx(t, "opening balance")
+	tx.move(AccountCash, AccountCapital, portfolio.CashDecimal())
+	book := make(map[string]primitives.Decimal)
+	for _, position := range sortedPositions(portfolio) {
+		value, err := l.value(portfolio, position, snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to value position %s: %w", position.ID(), err)
+		}
+		tx.move(PositionAccount(position.ID()), AccountCapital, value)
+		book[position.ID()] = value
+		if bearer, ok := position.(RewardBearer); ok {
+			reward, err := l.rewardValue(portfolio, bearer, snapshot)
+			if err != nil {
+				return fmt.Errorf("failed to value rewards of position %s: %w", position.ID(), err)
+			}
+			book[rewardKey(position.ID())] = reward
+		}
+	}
+
+	l.entries = nil
+	l.nextTx = 0
+	l.book = book
+	l.undo = l.undo[:0]
+	if err := l.post(tx); err != nil {
+		l.opened = false
+		return err
+	}
+	l.opened = true
+	return nil
+}
+
+// Apply applies action to portfolio and posts the resulting changes.
+// Positions are valued with snapshot in the portfolio's base currency.
+//
+// If the action fails, or the action succeeds but a changed position
+// cannot be valued, nothing is posted and the ledger is unchanged. In the
+// second case the portfolio has already been modified: the caller must
+// roll it back (see strategy.Portfolio.Checkpoint), as the backtest engine
+// does for every failed batch.
+func (l *Ledger) Apply(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, action strategy.Action) error {
+	if !l.opened {
+		return ErrNotOpened
+	}
+	if portfolio == nil {
+		return strategy.ErrNilPortfolio
+	}
+
+	before := positionMap(portfolio)
+	cashBefore := portfolio.CashDecimal()
+
+	if err := action.Apply(portfolio); err != nil {
+		return err
+	}
+
+	after := positionMap(portfolio)
+	tx := l.newTx(snapshot.Time(), action.String())
+
+	// Value every changed position before touching the book, so a valuation
+	// failure leaves the ledger as it was
+	var closed []string
+	for _, id := range sortedIDs(before) {
+		if next, ok := after[id]; ok && samePosition(next, before[id]) {
+			continue
+		}
+		// Release the carried value, realizing the difference to the current value
+		value, err := l.value(portfolio, before[id], snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to value closed position %s: %w", id, err)
+		}
+		l.release(tx, id, value)
+		closed = append(closed, id)
+	}
+
+	opened := make(map[string]primitives.Decimal)
+	for _, id := range sortedIDs(after) {
+		if prev, ok := before[id]; ok && samePosition(prev, after[id]) {
+			continue
+		}
+		// Carry at current value
+		value, err := l.value(portfolio, after[id], snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to value new position %s: %w", id, err)
+		}
+		tx.move(PositionAccount(id), AccountSettlement, value)
+		opened[id] = value
+		if bearer, ok := after[id].(RewardBearer); ok {
+			reward, err := l.rewardValue(portfolio, bearer, snapshot)
+			if err != nil {
+				return fmt.Errorf("failed to value rewards of new position %s: %w", id, err)
+			}
+			opened[rewardKey(id)] = reward
+		}
+	}
+
+	tx.move(AccountCash, AccountSettlement, portfolio.CashDecimal().Sub(cashBefore))
+	if err := l.post(tx); err != nil {
+		return err
+	}
+
+	for _, id := range closed {
+		l.unbook(id)
+		if _, ok := l.book[rewardKey(id)]; ok {
+			l.unbook(rewardKey(id))
+		}
+	}
+	for id, value := range opened {
+		l.setBook(id, value)
+	}
+	return nil
+}
+
+// MarkToMarket revalues every carried position at snapshot prices, posting
+// the change against unrealized P&L. For a RewardBearer the change in
+// reward value is posted against reward P&L instead.
+func (l *Ledger) MarkToMarket(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) error {
+	if !l.opened {
+		return ErrNotOpened
+	}
+	if portfolio == nil {
+		return strategy.ErrNilPortfolio
+	}
+
+	tx := l.newTx(snapshot.Time(), "mark to market")
+	marks := make(map[string]primitives.Decimal)
+	for _, position := range sortedPositions(portfolio) {
+		id := position.ID()
+		value, err := l.value(portfolio, position, snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to value position %s: %w", id, err)
+		}
+		change := value.Sub(l.book[id])
+		if bearer, ok := position.(RewardBearer); ok {
+			reward, err := l.rewardValue(portfolio, bearer, snapshot)
+			if err != nil {
+				return fmt.Errorf("failed to value rewards of position %s: %w", id, err)
+			}
+			accrued := reward.Sub(l.book[rewardKey(id)])
+			tx.move(PositionAccount(id), AccountRewardPnL, accrued)
+			change = change.Sub(accrued)
+			marks[rewardKey(id)] = reward
+		}
+		tx.move(PositionAccount(id), AccountUnrealizedPnL, change)
+		marks[id] = value
+	}
+	if err := l.post(tx); err != nil {
+		return err
+	}
+
+	for id, value := range marks {
+		l.setBook(id, value)
+	}
+	return nil
+}
+
+// value returns position's value in the portfolio's base currency, the
+// currency of its cash.
+func (l *Ledger) value(portfolio *strategy.Portfolio, position strategy.Position, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	return l.valuation().PositionValue(portfolio, position, snapshot)
+}
+
+// rewardValue returns bearer's reward value in the portfolio's base
+// currency.
+func (l *Ledger) rewardValue(portfolio *strategy.Portfolio, bearer RewardBearer, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	return l.valuation().RewardValue(portfolio, bearer, snapshot)
+}
+
+func (l *Ledger) valuation() Valuer {
+	if l.valuer == nil {
+		return PortfolioValuer{}
+	}
+	return l.valuer
+}
+
+// AccrueCash posts amount of cash income, or expense if negative, against
+// account at t, such as interest earned on idle cash. The cash must
+// already have been added to the portfolio.
+func (l *Ledger) AccrueCash(t primitives.Time, reason, account string, amount primitives.Decimal) error {
+	if !l.opened {
+		return ErrNotOpened
+	}
+	tx := l.newTx(t, reason)
+	tx.move(AccountCash, account, amount)
+	return l.post(tx)
+}
+
+// Entries returns a copy of all posted entries in order.
+func (l *Ledger) Entries() []Entry {
+	entries := make([]Entry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+// Balance returns debits minus credits for account.
+// Asset accounts (cash, positions) carry positive balances; capital and
+// gains carry negative balances.
+func (l *Ledger) Balance(account string) primitives.Decimal {
+	balance := primitives.Zero()
+	for _, e := range l.entries {
+		if e.Account == account {
+			balance = balance.Add(e.Debit).Sub(e.Credit)
+		}
+	}
+	return balance
+}
+
+// Accounts returns every account with at least one entry, sorted by name.
+func (l *Ledger) Accounts() []string {
+	seen := make(map[string]bool)
+	for _, e := range l.entries {
+		seen[e.Account] = true
+	}
+	accounts := make([]string, 0, len(seen))
+	for account := range seen {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// Checkpoint is a saved ledger state that Rollback can return to.
+type Checkpoint struct {
+	entries int
+	nextTx  int
+	book    map[string]primitives.Decimal
+}
+
+// Checkpoint saves the current journal position and carried values so
+// postings for a batch of actions can be undone with Rollback.
+func (l *Ledger) Checkpoint() Checkpoint {
+	book := make(map[string]primitives.Decimal, len(l.book))
+	for id, value := range l.book {
+		book[id] = value
+	}
+	return Checkpoint{entries: len(l.entries), nextTx: l.nextTx, book: book}
+}
+
+// Rollback discards every transaction posted since cp was taken.
+// The checkpoint can be reused.
+func (l *Ledger) Rollback(cp Checkpoint) {
+	if cp.entries <= len(l.entries) {
+		l.entries = l.entries[:cp.entries]
+	}
+	l.nextTx = cp.nextTx
+	l.book = make(map[string]primitives.Decimal, len(cp.book))
+	for id, value := range cp.book {
+		l.book[id] = value
+	}
+}
+
+// Savepoint marks a point in an open journal that RollbackTo can return to.
+type Savepoint struct {
+	entries int
+	nextTx  int
+	undo    int
+}
+
+// BeginJournal starts recording changes to carried values so postings can
+// be undone with RollbackTo. A savepoint is O(1), unlike Checkpoint which
+// copies every carried value. Calling BeginJournal again discards the
+// previous log.
+func (l *Ledger) BeginJournal() {
+	l.undo = l.undo[:0]
+	l.journaling = true
+}
+
+// EndJournal stops recording and discards the undo log.
+func (l *Ledger) EndJournal() {
+	l.undo = nil
+	l.journaling = false
+}
+
+// Savepoint marks the current journal position.
+// BeginJournal must be called first.
+func (l *Ledger) Savepoint() Savepoint {
+	return Savepoint{entries: len(l.entries), nextTx: l.nextTx, undo: len(l.undo)}
+}
+
+// RollbackTo discards every transaction posted since sp was taken.
+// Rolling back to a savepoint invalidates savepoints taken after it.
+func (l *Ledger) RollbackTo(sp Savepoint) {
+	if sp.entries <= len(l.entries) {
+		l.entries = l.entries[:sp.entries]
+	}
+	l.nextTx = sp.nextTx
+	for i := len(l.undo) - 1; i >= sp.undo; i-- {
+		if change := l.undo[i]; change.exists {
+			l.book[change.id] = change.value
+		} else {
+			delete(l.book, change.id)
+		}
+	}
+	if sp.undo < len(l.undo) {
+		l.undo = l.undo[:sp.undo]
+	}
+}
+
+// setBook sets a carried value, journaling the prior value.
+func (l *Ledger) setBook(id string, value primitives.Decimal) {
+	l.journalBook(id)
+	l.book[id] = value
+}
+
+// unbook removes a carried value, journaling the prior value.
+func (l *Ledger) unbook(id string) {
+	l.journalBook(id)
+	delete(l.book, id)
+}
+
+func (l *Ledger) journalBook(id string) {
+	if l.journaling {
+		value, exists := l.book[id]
+		l.undo = append(l.undo, bookChange{id: id, value: value, exists: exists})
+	}
+}
+
+// release posts the removal of a position from the book at its current
+// value. The caller deletes the book entry once the transaction is posted.
+func (l *Ledger) release(tx *transaction, id string, value primitives.Decimal) {
+	carried := l.book[id]
+
+	tx.move(AccountSettlement, PositionAccount(id), carried)
+	tx.move(AccountSettlement, AccountRealizedPnL, value.Sub(carried))
+}
+
+func (l *Ledger) newTx(t primitives.Time, reason string) *transaction {
+	return &transaction{time: t, reason: reason}
+}
+
+// post validates and appends a transaction. Empty transactions are dropped.
+func (l *Ledger) post(tx *transaction) error {
+	if len(tx.entries) == 0 {
+		return nil
+	}
+	debits, credits := primitives.Zero(), primitives.Zero()
+	for _, e := range tx.entries {
+		debits = debits.Add(e.Debit)
+		credits = credits.Add(e.Credit)
+	}
+	if !debits.Equal(credits) {
+		return fmt.Errorf("%w: debits %s, credits %s (%s)", ErrUnbalanced, debits, credits, tx.reason)
+	}
+
+	l.nextTx++
+	for _, e := range tx.entries {
+		e.TxID = l.nextTx
+		l.entries = append(l.entries, e)
+	}
+	return nil
+}
+
+// transaction accumulates balanced entries before posting.
+type transaction struct {
+	time    primitives.Time
+	reason  string
+	entries []Entry
+}
+
+// move debits one account and credits another by amount. A negative amount
+// reverses the direction; zero records nothing.
+func (tx *transaction) move(debit, credit string, amount primitives.Decimal) {
+	if amount.IsZero() {
+		return
+	}
+	if amount.IsNegative() {
+		debit, credit, amount = credit, debit, amount.Neg()
+	}
+	tx.entries = append(tx.entries,
+		Entry{Time: tx.time, Account: debit, Debit: amount, Credit: primitives.Zero(), Reason: tx.reason},
+		Entry{Time: tx.time, Account: credit, Debit: primitives.Zero(), Credit: amount, Reason: tx.reason},
+	)
+}
+
+// samePosition reports whether a and b are the same position value.
+// Positions of non-comparable types are always treated as changed.
+func samePosition(a, b strategy.Position) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func positionMap(portfolio *strategy.Portfolio) map[string]strategy.Position {
+	positions := portfolio.Positions()
+	m := make(map[string]strategy.Position, len(positions))
+	for _, p := range positions {
+		m[p.ID()] = p
+	}
+	return m
+}
+
+func sortedIDs(positions map[string]strategy.Position) []string {
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedPositions(portfolio *strategy.Portfolio) []strategy.Position {
+	positions := portfolio.Positions()
+	sort.Slice(positions, func(i, j int) bool { return positions[i].ID() < positions[j].ID() })
+	return positions
+}
@@ -0,0 +1,278 @@
+// Package allocation chooses portfolio weights across candidate strategies
+// or assets from their return streams: mean-variance with weight bounds,
+// minimum variance, or risk parity.
+//
+//	streams, err := allocation.FromResults(map[string]*backtest.Result{
+//		"lp": lpResult, "basis": basisResult, "trend": trendResult,
+//	})
+//	alloc, err := streams.Allocate(allocation.Config{
+//		Method:    allocation.MinimumVariance,
+//		MaxWeight: primitives.MustDecimalFromString("0.5"),
+//	})
+//	// alloc.Weights["lp"], alloc.Weights["basis"], ...
+//
+// Weights are long-only, sum to one and are keyed by the candidates'
+// names, the form sizing.RiskParityWeights returns: multiply a weight by
+// equity for the capital to give a strategy or, for an asset, divide that
+// by its price for a quantity.
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/sizing"
+)
+
+var (
+	// ErrInvalidConfig indicates an allocation was configured incorrectly
+	ErrInvalidConfig = errors.New("invalid allocation configuration")
+
+	// ErrInsufficientData indicates too few returns to allocate from
+	ErrInsufficientData = errors.New("insufficient data")
+)
+
+// Method selects the allocation objective.
+type Method int
+
+const (
+	// MeanVariance maximizes wᵀμ − (λ/2)·wᵀΣw, Markowitz's trade-off of
+	// expected return against variance at risk aversion λ
+	MeanVariance Method = iota
+
+	// MinimumVariance minimizes wᵀΣw, ignoring expected returns, which
+	// are the noisiest input
+	MinimumVariance
+
+	// RiskParity equalizes each candidate's contribution to portfolio
+	// variance (see sizing.RiskParityWeights)
+	RiskParity
+)
+
+// String returns the method name.
+func (m Method) String() string {
+	switch m {
+	case MeanVariance:
+		return "mean-variance"
+	case MinimumVariance:
+		return "minimum-variance"
+	case RiskParity:
+		return "risk-parity"
+	default:
+		return fmt.Sprintf("Method(%d)", int(m))
+	}
+}
+
+// Config configures Allocate.
+type Config struct {
+	Method Method
+
+	// RiskAversion is λ for MeanVariance (default 1); higher values trade
+	// return for lower variance
+	RiskAversion primitives.Decimal
+
+	// MinWeight and MaxWeight bound every weight (default 0 and 1). They
+	// apply to MeanVariance and MinimumVariance; risk parity fixes the
+	// weights, so RiskParity rejects bounds.
+	MinWeight primitives.Decimal
+	MaxWeight primitives.Decimal
+}
+
+// Allocation is the outcome of Allocate. Figures are annualized as the
+// covariance and expected returns given.
+type Allocation struct {
+	Method Method
+
+	// Weights are the candidates' weights, summing to one
+	Weights map[string]primitives.Decimal
+
+	// ExpectedReturn is wᵀμ; zero when no expected returns were given
+	ExpectedReturn primitives.Decimal
+
+	// Volatility is √(wᵀΣw)
+	Volatility primitives.Decimal
+
+	// RiskContributions are each candidate's share wᵢ·(Σw)ᵢ/wᵀΣw of the
+	// portfolio variance, summing to one
+	RiskContributions map[string]primitives.Decimal
+}
+
+// Iteration bounds of the projected gradient descent in Allocate.
+const (
+	allocationIterations = 20000
+	allocationTolerance  = 1e-13
+)
+
+// Allocate returns the weights across the pairs of cov under config.
+// expected holds the candidates' expected returns, required by
+// MeanVariance and otherwise only used for Allocation.ExpectedReturn.
+// Returns ErrInvalidConfig if the bounds cannot sum to one, a candidate
+// has no variance, or MeanVariance lacks an expected return.
+func Allocate(cov analytics.Covariance, expected map[string]primitives.Decimal, config Config) (*Allocation, error) {
+	pairs := cov.Pairs()
+	n := len(pairs)
+	sigma := make([][]float64, n)
+	for i, row := range cov.Matrix() {
+		sigma[i] = make([]float64, n)
+		for j, v := range row {
+			sigma[i][j] = v.Float64()
+		}
+		if sigma[i][i] <= 0 {
+			return nil, fmt.Errorf("%w: %s has no variance", ErrInvalidConfig, pairs[i])
+		}
+	}
+	mu := make([]float64, n)
+	for i, pair := range pairs {
+		r, ok := expected[pair]
+		if !ok && config.Method == MeanVariance {
+			return nil, fmt.Errorf("%w: no expected return for %s", ErrInvalidConfig, pair)
+		}
+		mu[i] = r.Float64()
+	}
+
+	lo, hi := config.MinWeight.Float64(), 1.0
+	if !config.MaxWeight.IsZero() {
+		hi = config.MaxWeight.Float64()
+	}
+	bounded := !config.MinWeight.IsZero() || !config.MaxWeight.IsZero()
+	if lo < 0 || hi > 1 || lo > hi {
+		return nil, fmt.Errorf("%w: weight bounds must satisfy 0 ≤ min ≤ max ≤ 1, got [%s, %s]",
+			ErrInvalidConfig, config.MinWeight, config.MaxWeight)
+	}
+	if float64(n)*lo > 1+1e-12 || float64(n)*hi < 1-1e-12 {
+		return nil, fmt.Errorf("%w: %d weights in [%g, %g] cannot sum to one", ErrInvalidConfig, n, lo, hi)
+	}
+
+	var w []float64
+	switch config.Method {
+	case RiskParity:
+		if bounded {
+			return nil, fmt.Errorf("%w: risk parity does not take weight bounds", ErrInvalidConfig)
+		}
+		weights, err := sizing.RiskParityWeights(cov)
+		if err != nil {
+			return nil, err
+		}
+		w = make([]float64, n)
+		for i, pair := range pairs {
+			w[i] = weights[pair].Float64()
+		}
+	case MinimumVariance:
+		w = minimizeQuadratic(sigma, 2, make([]float64, n), lo, hi)
+	case MeanVariance:
+		lambda := 1.0
+		if !config.RiskAversion.IsZero() {
+			lambda = config.RiskAversion.Float64()
+		}
+		if lambda <= 0 {
+			return nil, fmt.Errorf("%w: risk aversion must be positive, got %s", ErrInvalidConfig, config.RiskAversion)
+		}
+		w = minimizeQuadratic(sigma, lambda, mu, lo, hi)
+	default:
+		return nil, fmt.Errorf("%w: unknown method %s", ErrInvalidConfig, config.Method)
+	}
+
+	alloc := &Allocation{
+		Method:            config.Method,
+		Weights:           make(map[string]primitives.Decimal, n),
+		RiskContributions: make(map[string]primitives.Decimal, n),
+	}
+	marginal := mulVec(sigma, w)
+	variance, ret := 0.0, 0.0
+	for i := range w {
+		variance += w[i] * marginal[i]
+		ret += w[i] * mu[i]
+	}
+	for i, pair := range pairs {
+		alloc.Weights[pair] = primitives.NewDecimalFromFloat(w[i])
+		alloc.RiskContributions[pair] = primitives.NewDecimalFromFloat(w[i] * marginal[i] / variance)
+	}
+	alloc.ExpectedReturn = primitives.NewDecimalFromFloat(ret)
+	alloc.Volatility = primitives.NewDecimalFromFloat(math.Sqrt(variance))
+	return alloc, nil
+}
+
+// minimizeQuadratic minimizes (λ/2)·wᵀΣw − μᵀw over weights in [lo, hi]
+// summing to one, by projected gradient descent with step 1/L for the
+// gradient's Lipschitz constant L, bounded by λ times Σ's largest absolute
+// row sum. The objective is convex, so descent reaches the optimum.
+func minimizeQuadratic(sigma [][]float64, lambda float64, mu []float64, lo, hi float64) []float64 {
+	n := len(sigma)
+	lipschitz := 0.0
+	for _, row := range sigma {
+		sum := 0.0
+		for _, v := range row {
+			sum += math.Abs(v)
+		}
+		lipschitz = math.Max(lipschitz, lambda*sum)
+	}
+	step := 1 / lipschitz
+
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1 / float64(n)
+	}
+	w = project(w, lo, hi)
+	next := make([]float64, n)
+	for iter := 0; iter < allocationIterations; iter++ {
+		gradient := mulVec(sigma, w)
+		for i := range next {
+			next[i] = w[i] - step*(lambda*gradient[i]-mu[i])
+		}
+		next = project(next, lo, hi)
+		change := 0.0
+		for i := range w {
+			change = math.Max(change, math.Abs(next[i]-w[i]))
+		}
+		w, next = next, w
+		if change < allocationTolerance {
+			break
+		}
+	}
+	return w
+}
+
+// project returns the Euclidean projection of v onto the weights in
+// [lo, hi] summing to one: clip(vᵢ − τ, lo, hi) for the shift τ, found by
+// bisection, at which they sum to one.
+func project(v []float64, lo, hi float64) []float64 {
+	sum := func(tau float64) float64 {
+		total := 0.0
+		for _, x := range v {
+			total += math.Min(math.Max(x-tau, lo), hi)
+		}
+		return total
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, x := range v {
+		low = math.Min(low, x-hi)
+		high = math.Max(high, x-lo)
+	}
+	for i := 0; i < 200 && high-low > 1e-15; i++ {
+		mid := (low + high) / 2
+		if sum(mid) > 1 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	tau := (low + high) / 2
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Min(math.Max(x-tau, lo), hi)
+	}
+	return out
+}
+
+func mulVec(m [][]float64, v []float64) []float64 {
+	out := make([]float64, len(m))
+	for i, row := range m {
+		for j, x := range row {
+			out[i] += x * v[j]
+		}
+	}
+	return out
+}
@@ -0,0 +1,233 @@
+package allocation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/allocation"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/sizing"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+// diagonal builds an uncorrelated covariance with the given variances.
+func diagonal(t *testing.T, variances map[string]string) analytics.Covariance {
+	t.Helper()
+	pairs := []string{"A", "B", "C"}[:len(variances)]
+	matrix := make([][]primitives.Decimal, len(pairs))
+	for i, pair := range pairs {
+		matrix[i] = make([]primitives.Decimal, len(pairs))
+		for j := range pairs {
+			matrix[i][j] = primitives.Zero()
+		}
+		matrix[i][i] = dec(variances[pair])
+	}
+	cov, err := analytics.NewCovariance(pairs, matrix)
+	if err != nil {
+		t.Fatalf("NewCovariance failed: %v", err)
+	}
+	return cov
+}
+
+func assertWeights(t *testing.T, got map[string]primitives.Decimal, want map[string]float64) {
+	t.Helper()
+	total := 0.0
+	for pair, w := range want {
+		if math.Abs(got[pair].Float64()-w) > 1e-6 {
+			t.Errorf("weight of %s: expected %g, got %s", pair, w, got[pair])
+		}
+		total += got[pair].Float64()
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("weights sum to %g", total)
+	}
+}
+
+func TestMinimumVariance(t *testing.T) {
+	cov := diagonal(t, map[string]string{"A": "0.04", "B": "0.16"})
+
+	// Uncorrelated minimum variance weights are inverse variance
+	alloc, err := allocation.Allocate(cov, nil, allocation.Config{Method: allocation.MinimumVariance})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	assertWeights(t, alloc.Weights, map[string]float64{"A": 0.8, "B": 0.2})
+	// σ² = 0.8²·0.04 + 0.2²·0.16 = 0.032
+	if math.Abs(alloc.Volatility.Float64()-math.Sqrt(0.032)) > 1e-9 {
+		t.Errorf("expected volatility %g, got %s", math.Sqrt(0.032), alloc.Volatility)
+	}
+	// At the minimum every candidate's marginal risk is equal, so risk
+	// contributions equal the weights
+	if math.Abs(alloc.RiskContributions["A"].Float64()-0.8) > 1e-6 {
+		t.Errorf("expected A's risk contribution 0.8, got %s", alloc.RiskContributions["A"])
+	}
+
+	capped, err := allocation.Allocate(cov, nil, allocation.Config{Method: allocation.MinimumVariance, MaxWeight: dec("0.6")})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	assertWeights(t, capped.Weights, map[string]float64{"A": 0.6, "B": 0.4})
+}
+
+func TestMeanVariance(t *testing.T) {
+	cov := diagonal(t, map[string]string{"A": "0.04", "B": "0.04"})
+	expected := map[string]primitives.Decimal{"A": dec("0.1"), "B": dec("0.2")}
+
+	// wᵢ = (μᵢ − γ)/(λσ²) with γ = 0.05 making them sum to one
+	alloc, err := allocation.Allocate(cov, expected, allocation.Config{Method: allocation.MeanVariance, RiskAversion: dec("5")})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	assertWeights(t, alloc.Weights, map[string]float64{"A": 0.25, "B": 0.75})
+	if math.Abs(alloc.ExpectedReturn.Float64()-0.175) > 1e-6 {
+		t.Errorf("expected return 0.175, got %s", alloc.ExpectedReturn)
+	}
+
+	// Low risk aversion piles into the better asset up to the bound
+	greedy, err := allocation.Allocate(cov, expected, allocation.Config{
+		Method:       allocation.MeanVariance,
+		RiskAversion: dec("0.1"),
+		MinWeight:    dec("0.1"),
+	})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	assertWeights(t, greedy.Weights, map[string]float64{"A": 0.1, "B": 0.9})
+
+	if _, err := allocation.Allocate(cov, map[string]primitives.Decimal{"A": dec("0.1")}, allocation.Config{}); !errors.Is(err, allocation.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for a missing expected return, got %v", err)
+	}
+}
+
+func TestRiskParity(t *testing.T) {
+	cov := diagonal(t, map[string]string{"A": "0.04", "B": "0.16", "C": "0.09"})
+	alloc, err := allocation.Allocate(cov, nil, allocation.Config{Method: allocation.RiskParity})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	want, err := sizing.RiskParityWeights(cov)
+	if err != nil {
+		t.Fatalf("RiskParityWeights failed: %v", err)
+	}
+	for pair, w := range want {
+		if !alloc.Weights[pair].Equal(w) {
+			t.Errorf("weight of %s: expected %s, got %s", pair, w, alloc.Weights[pair])
+		}
+		if math.Abs(alloc.RiskContributions[pair].Float64()-1.0/3) > 1e-6 {
+			t.Errorf("expected equal risk contributions, %s has %s", pair, alloc.RiskContributions[pair])
+		}
+	}
+
+	if _, err := allocation.Allocate(cov, nil, allocation.Config{Method: allocation.RiskParity, MaxWeight: dec("0.5")}); !errors.Is(err, allocation.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for bounded risk parity, got %v", err)
+	}
+}
+
+func TestAllocateErrors(t *testing.T) {
+	cov := diagonal(t, map[string]string{"A": "0.04", "B": "0.16"})
+	invalid := []allocation.Config{
+		{Method: allocation.MinimumVariance, MaxWeight: dec("0.4")},
+		{Method: allocation.MinimumVariance, MinWeight: dec("0.6")},
+		{Method: allocation.MinimumVariance, MinWeight: dec("0.5"), MaxWeight: dec("0.4")},
+		{Method: allocation.Method(7)},
+	}
+	for i, config := range invalid {
+		if _, err := allocation.Allocate(cov, nil, config); !errors.Is(err, allocation.ErrInvalidConfig) {
+			t.Errorf("config %d: expected ErrInvalidConfig, got %v", i, err)
+		}
+	}
+	flat := diagonal(t, map[string]string{"A": "0.04", "B": "0"})
+	if _, err := allocation.Allocate(flat, nil, allocation.Config{Method: allocation.MinimumVariance}); !errors.Is(err, allocation.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig without variance, got %v", err)
+	}
+}
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func day(i int) primitives.Time {
+	return primitives.NewTime(testStart.Add(time.Duration(i) * 24 * time.Hour))
+}
+
+func TestFromResults(t *testing.T) {
+	// Daily results; "b" misses day 2 and has an extra day 5
+	steady := &backtest.Result{}
+	swingy := &backtest.Result{}
+	for i, v := range []string{"100", "101", "102", "103", "104"} {
+		steady.ValueHistory = append(steady.ValueHistory, backtest.ValuePoint{Time: day(i), Value: primitives.MustAmount(dec(v))})
+	}
+	for i, v := range []string{"100", "110", "", "99", "120", "80"} {
+		if v != "" {
+			swingy.ValueHistory = append(swingy.ValueHistory, backtest.ValuePoint{Time: day(i), Value: primitives.MustAmount(dec(v))})
+		}
+	}
+	streams, err := allocation.FromResults(map[string]*backtest.Result{"b": swingy, "a": steady})
+	if err != nil {
+		t.Fatalf("FromResults failed: %v", err)
+	}
+	if streams.Names[0] != "a" || len(streams.Returns[0]) != 3 {
+		t.Fatalf("expected 3 returns of a and b on their 4 common days, got %v with %d", streams.Names, len(streams.Returns[0]))
+	}
+	// 100 → 110 → 99 → 120
+	if math.Abs(streams.Returns[1][1].Float64()-(-0.1)) > 1e-12 {
+		t.Errorf("expected b's second return -10%%, got %s", streams.Returns[1][1])
+	}
+	// 3 intervals over 4 days
+	if math.Abs(streams.PeriodsPerYear-365.25*3/4) > 1e-9 {
+		t.Errorf("expected %g periods per year, got %g", 365.25*3/4, streams.PeriodsPerYear)
+	}
+
+	alloc, err := streams.Allocate(allocation.Config{Method: allocation.MinimumVariance})
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if alloc.Weights["a"].Float64() < 0.9 {
+		t.Errorf("expected the steady result to dominate, got %v", alloc.Weights)
+	}
+
+	if _, err := allocation.FromResults(map[string]*backtest.Result{"a": steady, "b": {}}); !errors.Is(err, allocation.ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData without common times, got %v", err)
+	}
+}
+
+func TestFromPrices(t *testing.T) {
+	var snapshots []strategy.MarketSnapshot
+	for i, p := range [][2]string{{"100", "10"}, {"102", "11"}, {"101", ""}, {"103", "10"}, {"104", "12"}} {
+		prices := map[string]primitives.Price{"ETH/USD": primitives.MustPrice(dec(p[0]))}
+		if p[1] != "" {
+			prices["SOL/USD"] = primitives.MustPrice(dec(p[1]))
+		}
+		snapshots = append(snapshots, strategy.NewSimpleSnapshot(day(i), prices))
+	}
+	streams, err := allocation.FromPrices(snapshots, "ETH/USD", "SOL/USD")
+	if err != nil {
+		t.Fatalf("FromPrices failed: %v", err)
+	}
+	if len(streams.Returns[0]) != 3 {
+		t.Fatalf("expected 3 returns skipping the unpriced snapshot, got %d", len(streams.Returns[0]))
+	}
+	expected := streams.ExpectedReturns()
+	// ETH: 100 → 102 → 103 → 104
+	mean := (0.02 + 1.0/102 + 1.0/103) / 3
+	if math.Abs(expected["ETH/USD"].Float64()-mean*streams.PeriodsPerYear) > 1e-9 {
+		t.Errorf("expected annualized ETH return %g, got %s", mean*streams.PeriodsPerYear, expected["ETH/USD"])
+	}
+	cov, err := streams.Covariance()
+	if err != nil {
+		t.Fatalf("Covariance failed: %v", err)
+	}
+	if v, _ := cov.Volatility("SOL/USD"); !v.GreaterThan(primitives.Zero()) {
+		t.Errorf("expected SOL volatility, got %s", v)
+	}
+
+	if _, err := allocation.NewStreams([]string{"x", "x"}, [][]primitives.Decimal{{dec("0.1"), dec("0.2")}, {dec("0.1"), dec("0.2")}}, 365); !errors.Is(err, allocation.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for duplicate names, got %v", err)
+	}
+}
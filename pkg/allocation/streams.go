@@ -0,0 +1,238 @@
+package allocation
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Streams are the aligned return streams of candidate strategies or
+// assets, the input to allocation.
+type Streams struct {
+	// Names identify the candidates; weights are keyed by them
+	Names []string
+
+	// Returns[i] are the periodic returns of Names[i]. All streams have
+	// the same length and their periods coincide.
+	Returns [][]primitives.Decimal
+
+	// PeriodsPerYear annualizes the streams' means and covariances
+	PeriodsPerYear float64
+}
+
+// NewStreams builds Streams from return series. Returns ErrInvalidConfig
+// if names and returns differ in number, a name repeats, the series
+// differ in length or periodsPerYear is not positive, and
+// ErrInsufficientData with fewer than two returns.
+func NewStreams(names []string, returns [][]primitives.Decimal, periodsPerYear float64) (*Streams, error) {
+	if len(names) == 0 || len(names) != len(returns) {
+		return nil, fmt.Errorf("%w: need one return series per name", ErrInvalidConfig)
+	}
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate name %s", ErrInvalidConfig, name)
+		}
+		seen[name] = true
+		if len(returns[i]) != len(returns[0]) {
+			return nil, fmt.Errorf("%w: %s has %d returns, %s has %d", ErrInvalidConfig, name, len(returns[i]), names[0], len(returns[0]))
+		}
+	}
+	if len(returns[0]) < 2 {
+		return nil, fmt.Errorf("%w: need at least 2 returns, got %d", ErrInsufficientData, len(returns[0]))
+	}
+	if periodsPerYear <= 0 {
+		return nil, fmt.Errorf("%w: periods per year must be positive, got %g", ErrInvalidConfig, periodsPerYear)
+	}
+	return &Streams{
+		Names:          append([]string(nil), names...),
+		Returns:        returns,
+		PeriodsPerYear: periodsPerYear,
+	}, nil
+}
+
+// FromResults builds the return streams of named backtests from their
+// value histories (Result.History). Only the times every result has a
+// value at are used, so results sampled at different frequencies are
+// compared on their common grid, and the streams are annualized by the
+// average spacing of those times. Names are sorted.
+func FromResults(results map[string]*backtest.Result) (*Streams, error) {
+	names := make([]string, 0, len(results))
+	for name, result := range results {
+		if result == nil {
+			return nil, fmt.Errorf("%w: result %q is nil", ErrInvalidConfig, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no results", ErrInvalidConfig)
+	}
+
+	counts := make(map[int64]int)
+	histories := make([]*backtest.ValueSeries, len(names))
+	for i, name := range names {
+		histories[i] = results[name].History()
+		seen := make(map[int64]bool, histories[i].Len())
+		for n := 0; n < histories[i].Len(); n++ {
+			if t := histories[i].Time(n).UnixNano(); !seen[t] {
+				seen[t] = true
+				counts[t]++
+			}
+		}
+	}
+	var times []primitives.Time
+	values := make([][]float64, len(names))
+	for i, history := range histories {
+		var last int64
+		for n := 0; n < history.Len(); n++ {
+			t := history.Time(n).UnixNano()
+			if counts[t] != len(names) || (len(values[i]) > 0 && t == last) {
+				continue
+			}
+			last = t
+			values[i] = append(values[i], history.Value(n).Decimal().Float64())
+			if i == 0 {
+				times = append(times, history.Time(n))
+			}
+		}
+	}
+	if len(times) < 3 {
+		return nil, fmt.Errorf("%w: results share %d times, need at least 3", ErrInsufficientData, len(times))
+	}
+	returns := make([][]primitives.Decimal, len(names))
+	for i := range values {
+		returns[i] = simpleReturns(values[i])
+	}
+	return NewStreams(names, returns, periodsPerYear(times))
+}
+
+// FromPrices builds the return streams of pairs from the snapshots'
+// prices. Snapshots missing a price for any pair are skipped, and the
+// streams are annualized by the average spacing of the rest.
+func FromPrices(snapshots []strategy.MarketSnapshot, pairs ...string) (*Streams, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("%w: no pairs", ErrInvalidConfig)
+	}
+	var times []primitives.Time
+	values := make([][]float64, len(pairs))
+	for _, snapshot := range snapshots {
+		prices := make([]float64, len(pairs))
+		complete := true
+		for i, pair := range pairs {
+			price, err := snapshot.Price(pair)
+			if err != nil {
+				complete = false
+				break
+			}
+			prices[i] = price.Decimal().Float64()
+		}
+		if !complete {
+			continue
+		}
+		times = append(times, snapshot.Time())
+		for i, price := range prices {
+			values[i] = append(values[i], price)
+		}
+	}
+	if len(times) < 3 {
+		return nil, fmt.Errorf("%w: %d snapshots price every pair, need at least 3", ErrInsufficientData, len(times))
+	}
+	returns := make([][]primitives.Decimal, len(pairs))
+	for i := range values {
+		returns[i] = simpleReturns(values[i])
+	}
+	return NewStreams(pairs, returns, periodsPerYear(times))
+}
+
+// Covariance returns the annualized sample covariance of the streams.
+func (s *Streams) Covariance() (analytics.Covariance, error) {
+	returns := s.floats()
+	means := make([]float64, len(returns))
+	for i, r := range returns {
+		means[i] = mean(r)
+	}
+	n := len(s.Names)
+	matrix := make([][]primitives.Decimal, n)
+	for i := range matrix {
+		matrix[i] = make([]primitives.Decimal, n)
+	}
+	periods := len(returns[0])
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sum := 0.0
+			for k := 0; k < periods; k++ {
+				sum += (returns[i][k] - means[i]) * (returns[j][k] - means[j])
+			}
+			cov := primitives.NewDecimalFromFloat(sum / float64(periods-1) * s.PeriodsPerYear)
+			matrix[i][j], matrix[j][i] = cov, cov
+		}
+	}
+	return analytics.NewCovariance(s.Names, matrix)
+}
+
+// ExpectedReturns returns the annualized mean return of each stream.
+func (s *Streams) ExpectedReturns() map[string]primitives.Decimal {
+	expected := make(map[string]primitives.Decimal, len(s.Names))
+	for i, r := range s.floats() {
+		expected[s.Names[i]] = primitives.NewDecimalFromFloat(mean(r) * s.PeriodsPerYear)
+	}
+	return expected
+}
+
+// Allocate allocates across the streams with their sample covariance and
+// mean returns (see Allocate).
+func (s *Streams) Allocate(config Config) (*Allocation, error) {
+	cov, err := s.Covariance()
+	if err != nil {
+		return nil, err
+	}
+	return Allocate(cov, s.ExpectedReturns(), config)
+}
+
+func (s *Streams) floats() [][]float64 {
+	floats := make([][]float64, len(s.Returns))
+	for i, series := range s.Returns {
+		floats[i] = make([]float64, len(series))
+		for k, r := range series {
+			floats[i][k] = r.Float64()
+		}
+	}
+	return floats
+}
+
+// simpleReturns returns the point-to-point returns of values, counting a
+// step from zero as no return so streams stay aligned.
+func simpleReturns(values []float64) []primitives.Decimal {
+	returns := make([]primitives.Decimal, 0, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		r := 0.0
+		if values[i-1] != 0 {
+			r = values[i]/values[i-1] - 1
+		}
+		returns = append(returns, primitives.NewDecimalFromFloat(r))
+	}
+	return returns
+}
+
+// periodsPerYear returns the number of intervals of the times' average
+// spacing in a year, or zero if no time elapses.
+func periodsPerYear(times []primitives.Time) float64 {
+	elapsed := times[len(times)-1].Sub(times[0])
+	if elapsed.Hours() <= 0 {
+		return 0
+	}
+	return float64(len(times)-1) * primitives.Year.Hours() / elapsed.Hours()
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
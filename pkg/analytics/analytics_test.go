@@ -0,0 +1,721 @@
+package analytics_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/sizing"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// priceSnapshots builds daily snapshots for a single pair from float prices.
+func priceSnapshots(pair string, prices []float64) []*strategy.SimpleSnapshot {
+	snaps := make([]*strategy.SimpleSnapshot, len(prices))
+	for i, p := range prices {
+		snaps[i] = strategy.NewSimpleSnapshot(
+			primitives.NewTime(testStart.Add(time.Duration(i)*24*time.Hour)),
+			map[string]primitives.Price{pair: primitives.MustPrice(primitives.NewDecimalFromFloat(p))},
+		)
+	}
+	return snaps
+}
+
+func TestRegimeDetectorConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *analytics.RegimeConfig)
+	}{
+		{"empty pair", func(c *analytics.RegimeConfig) { c.Pair = "" }},
+		{"window too small", func(c *analytics.RegimeConfig) { c.Window = 2 * c.Lag }},
+		{"zero lag", func(c *analytics.RegimeConfig) { c.Lag = 0 }},
+		{"zero periods", func(c *analytics.RegimeConfig) { c.PeriodsPerYear = 0 }},
+		{"inverted thresholds", func(c *analytics.RegimeConfig) {
+			c.HighVolThreshold = primitives.MustDecimalFromString("0.1")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := analytics.DefaultRegimeConfig("ETH/USD")
+			tt.modify(&cfg)
+			if _, err := analytics.NewRegimeDetector(cfg); !errors.Is(err, analytics.ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegimeDetectorClassification(t *testing.T) {
+	const n = 31
+
+	trending := make([]float64, n)
+	choppy := make([]float64, n)
+	for i := 0; i < n; i++ {
+		// Steady 1% per day grind with small noise: trending, low vol
+		trending[i] = 100 * (1 + 0.01*float64(i))
+		if i%2 == 0 {
+			trending[i] += 0.05
+		}
+		// Alternating ±8%: mean reverting, high vol
+		choppy[i] = 100
+		if i%2 == 1 {
+			choppy[i] = 108
+		}
+	}
+
+	tests := []struct {
+		name      string
+		prices    []float64
+		wantVol   analytics.VolatilityRegime
+		wantTrend analytics.TrendRegime
+	}{
+		{"trending low vol", trending, analytics.VolatilityLow, analytics.TrendTrending},
+		{"choppy high vol", choppy, analytics.VolatilityHigh, analytics.TrendRanging},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector, err := analytics.NewRegimeDetector(analytics.DefaultRegimeConfig("ETH/USD"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var provider analytics.RegimeProvider = detector
+			if _, err := provider.Regime(); !errors.Is(err, analytics.ErrInsufficientData) {
+				t.Errorf("expected ErrInsufficientData before warm-up, got %v", err)
+			}
+
+			snaps := priceSnapshots("ETH/USD", tt.prices)
+			var regime analytics.Regime
+			for i, snap := range snaps {
+				regime, err = detector.Update(snap)
+				if i < len(snaps)-1 {
+					if !errors.Is(err, analytics.ErrInsufficientData) {
+						t.Fatalf("expected warm-up at snapshot %d, got %v", i, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if regime.Volatility != tt.wantVol {
+				t.Errorf("expected volatility %s, got %s (vol %s)", tt.wantVol, regime.Volatility, regime.RealizedVol)
+			}
+			if regime.Trend != tt.wantTrend {
+				t.Errorf("expected trend %s, got %s (VR %s)", tt.wantTrend, regime.Trend, regime.VarianceRatio)
+			}
+
+			last := snaps[len(snaps)-1]
+			if err := detector.Annotate(last); err != nil {
+				t.Fatalf("unexpected annotate error: %v", err)
+			}
+			if v, ok := last.Get(analytics.RegimeVolatilityKey("ETH/USD")); !ok || v != tt.wantVol {
+				t.Errorf("expected annotated volatility %s, got %v", tt.wantVol, v)
+			}
+			if v, ok := last.Get(analytics.RegimeTrendKey("ETH/USD")); !ok || v != tt.wantTrend {
+				t.Errorf("expected annotated trend %s, got %v", tt.wantTrend, v)
+			}
+		})
+	}
+}
+
+func TestRegimeDetectorMissingPrice(t *testing.T) {
+	detector, _ := analytics.NewRegimeDetector(analytics.DefaultRegimeConfig("ETH/USD"))
+	snap := strategy.NewSimpleSnapshot(primitives.NewTime(testStart), map[string]primitives.Price{})
+	if _, err := detector.Update(snap); !errors.Is(err, strategy.ErrPriceNotAvailable) {
+		t.Errorf("expected ErrPriceNotAvailable, got %v", err)
+	}
+}
+
+// gbmBars simulates daily OHLC bars from a driftless geometric Brownian
+// motion with annualized volatility sigma, sampling each day in steps.
+func gbmBars(seed int64, days, steps int, sigma float64) []*strategy.OHLCVSnapshot {
+	rng := rand.New(rand.NewSource(seed))
+	stepVol := sigma / math.Sqrt(365*float64(steps))
+	price := 2000.0
+	snaps := make([]*strategy.OHLCVSnapshot, days)
+	for d := 0; d < days; d++ {
+		open, high, low := price, price, price
+		for s := 0; s < steps; s++ {
+			price *= math.Exp(stepVol*rng.NormFloat64() - stepVol*stepVol/2)
+			high, low = math.Max(high, price), math.Min(low, price)
+		}
+		p := func(x float64) primitives.Price { return primitives.MustPrice(primitives.NewDecimalFromFloat(x)) }
+		bar := strategy.Bar{Open: p(open), High: p(high), Low: p(low), Close: p(price)}
+		snaps[d], _ = strategy.NewOHLCVSnapshot(
+			primitives.NewTime(testStart.Add(time.Duration(d)*24*time.Hour)),
+			map[string]strategy.Bar{"ETH/USD": bar}, nil)
+	}
+	return snaps
+}
+
+func TestVolatilityEstimators(t *testing.T) {
+	const sigma = 0.8
+	snaps := gbmBars(7, 366, 200, sigma)
+
+	for _, estimator := range []analytics.Estimator{
+		analytics.EstimatorCloseToClose,
+		analytics.EstimatorParkinson,
+		analytics.EstimatorGarmanKlass,
+		analytics.EstimatorYangZhang,
+	} {
+		t.Run(estimator.String(), func(t *testing.T) {
+			cfg := analytics.DefaultVolatilityConfig("ETH/USD")
+			cfg.Estimator = estimator
+			cfg.Window = 365
+			vol, err := analytics.NewVolatilityEstimator(cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var provider analytics.VolatilityProvider = vol
+			var estimate primitives.Decimal
+			for i, snap := range snaps {
+				estimate, err = vol.Update(snap)
+				if i < len(snaps)-1 && !errors.Is(err, analytics.ErrInsufficientData) {
+					t.Fatalf("expected warm-up at snapshot %d, got %v", i, err)
+				}
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Discrete sampling biases range estimators slightly low
+			if got := estimate.Float64(); math.Abs(got-sigma) > 0.1*sigma {
+				t.Errorf("estimate %.4f not within 10%% of %.2f", got, sigma)
+			}
+			if latest, err := provider.Volatility(); err != nil || !latest.Equal(estimate) {
+				t.Errorf("Volatility() = %s (%v), want %s", latest, err, estimate)
+			}
+		})
+	}
+}
+
+func TestVolatilityEstimatorCloseOnlyData(t *testing.T) {
+	prices := []float64{100, 102, 99, 101, 104, 100}
+	snaps := priceSnapshots("ETH/USD", prices)
+
+	cfg := analytics.DefaultVolatilityConfig("ETH/USD")
+	cfg.Estimator = analytics.EstimatorParkinson
+	cfg.Window = 5
+	parkinson, _ := analytics.NewVolatilityEstimator(cfg)
+	if _, err := parkinson.Update(snaps[0]); !errors.Is(err, strategy.ErrBarNotAvailable) {
+		t.Errorf("expected ErrBarNotAvailable, got %v", err)
+	}
+
+	cfg.Estimator = analytics.EstimatorCloseToClose
+	closes, _ := analytics.NewVolatilityEstimator(cfg)
+	var estimate primitives.Decimal
+	var err error
+	for _, snap := range snaps {
+		estimate, err = closes.Update(snap)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	returns := make([]float64, len(prices)-1)
+	var mean float64
+	for i := range returns {
+		returns[i] = math.Log(prices[i+1] / prices[i])
+		mean += returns[i] / float64(len(returns))
+	}
+	var ss float64
+	for _, r := range returns {
+		ss += (r - mean) * (r - mean)
+	}
+	want := math.Sqrt(ss / float64(len(returns)-1) * 365)
+	if math.Abs(estimate.Float64()-want) > 1e-9 {
+		t.Errorf("close-to-close = %s, want %f", estimate, want)
+	}
+}
+
+func TestVolatilityEstimatorAnnotateAndSizing(t *testing.T) {
+	cfg := analytics.DefaultVolatilityConfig("ETH/USD")
+	cfg.Estimator = analytics.EstimatorCloseToClose
+	cfg.Window = 4
+	vol, _ := analytics.NewVolatilityEstimator(cfg)
+	snaps := priceSnapshots("ETH/USD", []float64{100, 110, 95, 105, 100})
+	if err := vol.Annotate(snaps[0]); !errors.Is(err, analytics.ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData before warm-up, got %v", err)
+	}
+	for _, snap := range snaps {
+		_, _ = vol.Update(snap)
+	}
+	realized, _ := vol.Volatility()
+
+	last := snaps[len(snaps)-1]
+	if err := vol.Annotate(last); err != nil {
+		t.Fatalf("unexpected annotate error: %v", err)
+	}
+	if got, err := strategy.GetDecimal(last, analytics.RealizedVolatilityKey("ETH/USD")); err != nil || !got.Equal(realized) {
+		t.Errorf("annotated realized vol = %s (%v), want %s", got, err, realized)
+	}
+	if got, _ := strategy.GetDecimal(last, snapshotkeys.OptionImpliedVol("ETH/USD")); !got.Equal(realized) {
+		t.Errorf("implied vol fallback = %s, want %s", got, realized)
+	}
+
+	quoted := snaps[len(snaps)-2]
+	quoted.Set(snapshotkeys.OptionImpliedVol("ETH/USD"), primitives.MustDecimalFromString("0.5"))
+	_ = vol.Annotate(quoted)
+	if got, _ := strategy.GetDecimal(quoted, snapshotkeys.OptionImpliedVol("ETH/USD")); !got.Equal(primitives.MustDecimalFromString("0.5")) {
+		t.Errorf("annotate overwrote quoted implied vol with %s", got)
+	}
+
+	// Realized vol well above target scales a vol-targeted position below 1x
+	sizer, _ := sizing.NewVolatilityTarget(primitives.MustDecimalFromString("0.2"), primitives.One())
+	qty, err := sizer.Size(sizing.Params{
+		Equity:     primitives.MustAmount(primitives.NewDecimal(10000)),
+		Price:      primitives.MustPrice(primitives.NewDecimal(100)),
+		Volatility: realized,
+	})
+	if err != nil || !qty.IsPositive() || !qty.LessThan(primitives.NewDecimal(100)) {
+		t.Errorf("vol-targeted quantity = %s (%v), want between 0 and 100", qty, err)
+	}
+}
+
+func TestVolatilityEstimatorConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *analytics.VolatilityConfig)
+	}{
+		{"empty pair", func(c *analytics.VolatilityConfig) { c.Pair = "" }},
+		{"window too small", func(c *analytics.VolatilityConfig) { c.Window = 1 }},
+		{"zero periods", func(c *analytics.VolatilityConfig) { c.PeriodsPerYear = 0 }},
+		{"unknown estimator", func(c *analytics.VolatilityConfig) { c.Estimator = 99 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := analytics.DefaultVolatilityConfig("ETH/USD")
+			tt.modify(&cfg)
+			if _, err := analytics.NewVolatilityEstimator(cfg); !errors.Is(err, analytics.ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+// multiSnapshots builds daily snapshots from per-pair float price series.
+func multiSnapshots(series map[string][]float64) []*strategy.SimpleSnapshot {
+	var n int
+	for _, prices := range series {
+		n = len(prices)
+	}
+	snaps := make([]*strategy.SimpleSnapshot, n)
+	for i := range snaps {
+		prices := make(map[string]primitives.Price, len(series))
+		for pair, p := range series {
+			prices[pair] = primitives.MustPrice(primitives.NewDecimalFromFloat(p[i]))
+		}
+		snaps[i] = strategy.NewSimpleSnapshot(primitives.NewTime(testStart.Add(time.Duration(i)*24*time.Hour)), prices)
+	}
+	return snaps
+}
+
+// correlatedSeries simulates two price paths whose daily log returns have
+// the given volatilities and correlation.
+func correlatedSeries(seed int64, n int, volA, volB, rho float64) map[string][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	a, b := make([]float64, n), make([]float64, n)
+	a[0], b[0] = 40000, 2000
+	da, db := volA/math.Sqrt(365), volB/math.Sqrt(365)
+	for i := 1; i < n; i++ {
+		z1, z2 := rng.NormFloat64(), rng.NormFloat64()
+		a[i] = a[i-1] * math.Exp(da*z1)
+		b[i] = b[i-1] * math.Exp(db*(rho*z1+math.Sqrt(1-rho*rho)*z2))
+	}
+	return map[string][]float64{"BTC/USD": a, "ETH/USD": b}
+}
+
+func runCovariance(t *testing.T, cfg analytics.CovarianceConfig, snaps []*strategy.SimpleSnapshot) analytics.Covariance {
+	t.Helper()
+	estimator, err := analytics.NewCovarianceEstimator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var provider analytics.CovarianceProvider = estimator
+	if _, err := provider.Covariance(); !errors.Is(err, analytics.ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData before warm-up, got %v", err)
+	}
+	for i, snap := range snaps {
+		if _, err = estimator.Update(snap); i < cfg.Window && !errors.Is(err, analytics.ErrInsufficientData) {
+			t.Fatalf("expected warm-up at snapshot %d, got %v", i, err)
+		}
+	}
+	cov, err := provider.Covariance()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cov
+}
+
+func TestCovarianceEstimatorMethods(t *testing.T) {
+	snaps := multiSnapshots(correlatedSeries(11, 731, 0.6, 0.8, 0.7))
+	tests := []struct {
+		method    analytics.CovarianceMethod
+		tolerance float64
+	}{
+		{analytics.CovarianceSample, 0.1},
+		{analytics.CovarianceEWMA, 0.25},
+		{analytics.CovarianceLedoitWolf, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method.String(), func(t *testing.T) {
+			cfg := analytics.DefaultCovarianceConfig("BTC/USD", "ETH/USD")
+			cfg.Method = tt.method
+			cfg.Window = 730
+			cov := runCovariance(t, cfg, snaps)
+
+			for pair, want := range map[string]float64{"BTC/USD": 0.6, "ETH/USD": 0.8} {
+				if vol, _ := cov.Volatility(pair); math.Abs(vol.Float64()-want) > tt.tolerance*want {
+					t.Errorf("%s volatility %s, want about %.1f", pair, vol, want)
+				}
+			}
+			rho, _ := cov.Correlation("BTC/USD", "ETH/USD")
+			if math.Abs(rho.Float64()-0.7) > tt.tolerance {
+				t.Errorf("correlation %s, want about 0.7", rho)
+			}
+			if !cov.Time.Equal(snaps[len(snaps)-1].Time()) {
+				t.Errorf("estimate time %s, want last snapshot", cov.Time)
+			}
+		})
+	}
+}
+
+func TestCovarianceSampleExact(t *testing.T) {
+	series := map[string][]float64{
+		"BTC/USD": {100, 101, 99, 102},
+		"ETH/USD": {50, 51, 50.5, 52},
+	}
+	cfg := analytics.DefaultCovarianceConfig("BTC/USD", "ETH/USD")
+	cfg.Window = 3
+	cfg.PeriodsPerYear = 1
+	cov := runCovariance(t, cfg, multiSnapshots(series))
+
+	logReturns := func(p []float64) []float64 {
+		r := make([]float64, len(p)-1)
+		for i := range r {
+			r[i] = math.Log(p[i+1] / p[i])
+		}
+		return r
+	}
+	a, b := logReturns(series["BTC/USD"]), logReturns(series["ETH/USD"])
+	var ma, mb float64
+	for i := range a {
+		ma += a[i] / 3
+		mb += b[i] / 3
+	}
+	var want float64
+	for i := range a {
+		want += (a[i] - ma) * (b[i] - mb) / 2
+	}
+	if got, _ := cov.Covariance("ETH/USD", "BTC/USD"); math.Abs(got.Float64()-want) > 1e-12 {
+		t.Errorf("covariance %s, want %g", got, want)
+	}
+	if corr := cov.CorrelationMatrix(); !corr[0][0].Equal(primitives.One()) || !corr[0][1].Equal(corr[1][0]) {
+		t.Errorf("correlation matrix %v should have a unit diagonal and be symmetric", corr)
+	}
+}
+
+func TestCovarianceLedoitWolfShrinks(t *testing.T) {
+	// Ten returns of two independent assets: the sample correlation is noisy
+	snaps := multiSnapshots(correlatedSeries(3, 11, 0.6, 0.6, 0))
+	cfg := analytics.DefaultCovarianceConfig("BTC/USD", "ETH/USD")
+	cfg.Window = 10
+
+	sample := runCovariance(t, cfg, snaps)
+	cfg.Method = analytics.CovarianceLedoitWolf
+	shrunk := runCovariance(t, cfg, snaps)
+
+	if s := shrunk.Shrinkage.Float64(); s <= 0 || s > 1 {
+		t.Errorf("shrinkage %s, want in (0, 1]", shrunk.Shrinkage)
+	}
+	rawRho, _ := sample.Correlation("BTC/USD", "ETH/USD")
+	shrunkRho, _ := shrunk.Correlation("BTC/USD", "ETH/USD")
+	if math.Abs(shrunkRho.Float64()) >= math.Abs(rawRho.Float64()) {
+		t.Errorf("shrunk correlation %s should be nearer zero than sample %s", shrunkRho, rawRho)
+	}
+}
+
+func TestCovarianceValueAtRisk(t *testing.T) {
+	d := primitives.MustDecimalFromString
+	cov, err := analytics.NewCovariance([]string{"BTC/USD", "ETH/USD"}, [][]primitives.Decimal{
+		{d("0.36"), d("0.24")},
+		{d("0.24"), d("0.64")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	year := primitives.Year
+
+	// One asset: 99% VaR = 2.3263 × 60% × $10k
+	single, err := cov.ValueAtRisk(map[string]primitives.Decimal{"BTC/USD": d("10000")}, d("0.99"), year)
+	if err != nil || math.Abs(single.Float64()-2.326348*0.6*10000) > 0.1 {
+		t.Errorf("single-asset VaR %s (%v)", single, err)
+	}
+
+	// A long/short pair hedges: σ² = 0.36 + 0.64 − 2·0.24 = 0.52
+	hedged, _ := cov.PortfolioVariance(map[string]primitives.Decimal{"BTC/USD": d("1"), "ETH/USD": d("-1")})
+	if math.Abs(hedged.Float64()-0.52) > 1e-12 {
+		t.Errorf("hedged variance %s, want 0.52", hedged)
+	}
+
+	// VaR scales with the square root of the horizon
+	day, _ := cov.ValueAtRisk(map[string]primitives.Decimal{"BTC/USD": d("10000")}, d("0.99"), primitives.NewDuration(year.Duration()/4))
+	if math.Abs(day.Float64()-single.Float64()/2) > 1e-6 {
+		t.Errorf("quarter-year VaR %s, want half of %s", day, single)
+	}
+
+	if _, err := cov.ValueAtRisk(map[string]primitives.Decimal{"SOL/USD": d("1")}, d("0.99"), year); !errors.Is(err, analytics.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for unknown pair, got %v", err)
+	}
+	if _, err := cov.ValueAtRisk(nil, d("0.4"), year); !errors.Is(err, analytics.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for confidence, got %v", err)
+	}
+}
+
+func TestCovarianceConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *analytics.CovarianceConfig)
+	}{
+		{"one pair", func(c *analytics.CovarianceConfig) { c.Pairs = c.Pairs[:1] }},
+		{"duplicate pair", func(c *analytics.CovarianceConfig) { c.Pairs = []string{"BTC/USD", "BTC/USD"} }},
+		{"window too small", func(c *analytics.CovarianceConfig) { c.Window = 1 }},
+		{"zero periods", func(c *analytics.CovarianceConfig) { c.PeriodsPerYear = 0 }},
+		{"ewma decay of one", func(c *analytics.CovarianceConfig) {
+			c.Method = analytics.CovarianceEWMA
+			c.Decay = primitives.One()
+		}},
+		{"unknown method", func(c *analytics.CovarianceConfig) { c.Method = 99 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := analytics.DefaultCovarianceConfig("BTC/USD", "ETH/USD")
+			tt.modify(&cfg)
+			if _, err := analytics.NewCovarianceEstimator(cfg); !errors.Is(err, analytics.ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+
+	if _, err := analytics.NewCovariance([]string{"A", "B"}, [][]primitives.Decimal{
+		{primitives.One(), primitives.Zero()},
+		{primitives.One(), primitives.One()},
+	}); !errors.Is(err, analytics.ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for asymmetric matrix, got %v", err)
+	}
+}
+
+func TestCompareFeeTiers(t *testing.T) {
+	d := primitives.MustDecimalFromString
+	volume := primitives.MustAmount(primitives.NewDecimal(1_000_000))
+	tvl := primitives.MustAmount(primitives.NewDecimal(10_000_000))
+	config := analytics.FeeTierConfig{
+		Tiers: []analytics.FeeTier{
+			{Fee: d("0.0005"), DailyVolume: volume, TVL: tvl},
+			{Fee: d("0.003"), DailyVolume: volume, TVL: tvl},
+			{Fee: d("0.01"), DailyVolume: primitives.MustAmount(primitives.NewDecimal(50_000)), TVL: tvl},
+		},
+		Volatility: d("0.8"),
+		Widths:     []primitives.Decimal{primitives.Zero(), d("0.1"), d("3")},
+		Horizon:    primitives.NewDuration(primitives.Year.Duration()),
+	}
+	estimates, err := analytics.CompareFeeTiers(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimates) != 9 {
+		t.Fatalf("got %d estimates, want 9", len(estimates))
+	}
+	for i := 1; i < len(estimates); i++ {
+		if estimates[i].NetAPR.GreaterThan(estimates[i-1].NetAPR) {
+			t.Fatalf("estimates not ranked by net APR: %s before %s", estimates[i-1].NetAPR, estimates[i].NetAPR)
+		}
+	}
+
+	find := func(fee, width string) analytics.FeeTierEstimate {
+		for _, e := range estimates {
+			if e.Fee.Equal(d(fee)) && e.Width.Equal(d(width)) {
+				return e
+			}
+		}
+		t.Fatalf("no estimate for fee %s width %s", fee, width)
+		return analytics.FeeTierEstimate{}
+	}
+
+	// Full range: always in range, fee × volume / TVL annualized, and
+	// expected IL 1 - E[√P] = 1 - exp(-σ²T/8)
+	full := find("0.003", "0")
+	if !full.InRange.Equal(primitives.One()) || !full.Efficiency.Equal(primitives.One()) {
+		t.Errorf("full range in range %s, efficiency %s, want 1 and 1", full.InRange, full.Efficiency)
+	}
+	if got := full.FeeAPR.Float64(); math.Abs(got-0.003*0.1*365.25) > 1e-9 {
+		t.Errorf("full-range fee APR = %v, want %v", got, 0.003*0.1*365.25)
+	}
+	if got, want := full.ILAPR.Float64(), 1-math.Exp(-0.64/8); math.Abs(got-want) > 1e-6 {
+		t.Errorf("full-range IL = %v, want %v", got, want)
+	}
+
+	// [P/4, 4P] doubles capital efficiency; narrower ranges lose more to
+	// IL and spend less time in range
+	wide, narrow := find("0.003", "3"), find("0.003", "0.1")
+	if got := wide.Efficiency.Float64(); math.Abs(got-2) > 1e-9 {
+		t.Errorf("efficiency of [P/4, 4P] = %v, want 2", got)
+	}
+	if !narrow.ILAPR.GreaterThan(wide.ILAPR) || !wide.ILAPR.GreaterThan(full.ILAPR) {
+		t.Errorf("IL not increasing with concentration: %s, %s, %s", full.ILAPR, wide.ILAPR, narrow.ILAPR)
+	}
+	if !narrow.InRange.LessThan(wide.InRange) || !wide.InRange.LessThan(primitives.One()) {
+		t.Errorf("in-range time not decreasing with concentration: %s, %s", wide.InRange, narrow.InRange)
+	}
+
+	// The thinly traded 1% tier ranks below the busy 0.3% tier
+	if best := estimates[0]; !best.Fee.Equal(d("0.003")) {
+		t.Errorf("best estimate is the %s tier, want 0.003", best.Fee)
+	}
+
+	for name, bad := range map[string]analytics.FeeTierConfig{
+		"no tiers":       {Volatility: d("0.8"), Widths: config.Widths},
+		"no widths":      {Tiers: config.Tiers, Volatility: d("0.8")},
+		"zero vol":       {Tiers: config.Tiers, Widths: config.Widths},
+		"negative width": {Tiers: config.Tiers, Volatility: d("0.8"), Widths: []primitives.Decimal{d("-0.1")}},
+		"zero TVL":       {Tiers: []analytics.FeeTier{{Fee: d("0.003"), DailyVolume: volume}}, Volatility: d("0.8"), Widths: config.Widths},
+	} {
+		if _, err := analytics.CompareFeeTiers(bad); !errors.Is(err, analytics.ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+}
+
+// noiseReturns returns n trials of t normal returns with zero mean.
+func noiseReturns(rng *rand.Rand, n, t int) [][]primitives.Decimal {
+	returns := make([][]primitives.Decimal, n)
+	for i := range returns {
+		returns[i] = make([]primitives.Decimal, t)
+		for j := range returns[i] {
+			returns[i][j] = primitives.NewDecimalFromFloat(0.01 * rng.NormFloat64())
+		}
+	}
+	return returns
+}
+
+// periodSharpe is the per-period Sharpe ratio with the sample deviation.
+func periodSharpe(returns []primitives.Decimal) float64 {
+	mean, variance := 0.0, 0.0
+	for _, r := range returns {
+		mean += r.Float64()
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r.Float64() - mean) * (r.Float64() - mean)
+	}
+	return mean / math.Sqrt(variance/float64(len(returns)-1))
+}
+
+func TestDeflatedSharpe(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// A single trial with a real edge is significant
+	skilled := make([]primitives.Decimal, 250)
+	for i := range skilled {
+		skilled[i] = primitives.NewDecimalFromFloat(0.002 + 0.01*rng.NormFloat64())
+	}
+	dsr, err := analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{Returns: skilled})
+	if err != nil {
+		t.Fatalf("DeflatedSharpe failed: %v", err)
+	}
+	if !dsr.ExpectedMax.IsZero() || dsr.Probability.Float64() < 0.95 {
+		t.Errorf("expected a significant single trial, got %s (expected max %s)", dsr.Probability, dsr.ExpectedMax)
+	}
+
+	// The best of 200 noise trials looks good but deflates away
+	trials := noiseReturns(rng, 200, 250)
+	sharpes := make([]primitives.Decimal, len(trials))
+	best := 0
+	for i, returns := range trials {
+		sharpes[i] = primitives.NewDecimalFromFloat(periodSharpe(returns))
+		if sharpes[i].GreaterThan(sharpes[best]) {
+			best = i
+		}
+	}
+	dsr, err = analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{Returns: trials[best], TrialSharpes: sharpes})
+	if err != nil {
+		t.Fatalf("DeflatedSharpe failed: %v", err)
+	}
+	if dsr.Trials != 200 || dsr.Probability.Float64() > 0.9 {
+		t.Errorf("expected the best noise trial to deflate, got %s over %d trials", dsr.Probability, dsr.Trials)
+	}
+	undeflated, err := analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{Returns: trials[best]})
+	if err != nil {
+		t.Fatalf("DeflatedSharpe failed: %v", err)
+	}
+	if undeflated.Probability.Float64() < 0.95 {
+		t.Errorf("expected the best noise trial to look significant alone, got %s", undeflated.Probability)
+	}
+	if math.Abs(dsr.Sharpe.Float64()-sharpes[best].Float64()) > 1e-9 {
+		t.Errorf("expected Sharpe %s, got %s", sharpes[best], dsr.Sharpe)
+	}
+
+	if _, err := analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{Returns: skilled[:2]}); !errors.Is(err, analytics.ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData, got %v", err)
+	}
+	flat := []primitives.Decimal{primitives.Zero(), primitives.Zero(), primitives.Zero()}
+	if _, err := analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{Returns: flat}); !errors.Is(err, analytics.ErrInsufficientData) {
+		t.Errorf("expected ErrInsufficientData for flat returns, got %v", err)
+	}
+}
+
+func TestProbabilityOfBacktestOverfitting(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	// Selection among pure noise is a coin flip out of sample
+	noise, err := analytics.ProbabilityOfBacktestOverfitting(noiseReturns(rng, 20, 400), 10)
+	if err != nil {
+		t.Fatalf("PBO failed: %v", err)
+	}
+	if noise.Combinations != 252 || len(noise.Logits) != 252 {
+		t.Errorf("expected C(10, 5) = 252 combinations, got %d", noise.Combinations)
+	}
+	if p := noise.Probability.Float64(); p < 0.2 || p > 0.8 {
+		t.Errorf("expected PBO near 0.5 for noise, got %g", p)
+	}
+
+	// A trial with a persistent edge is selected in and out of sample
+	edge := noiseReturns(rng, 20, 400)
+	for j := range edge[7] {
+		edge[7][j] = edge[7][j].Add(primitives.NewDecimalFromFloat(0.01))
+	}
+	skilled, err := analytics.ProbabilityOfBacktestOverfitting(edge, 0)
+	if err != nil {
+		t.Fatalf("PBO failed: %v", err)
+	}
+	if skilled.Blocks != analytics.DefaultOverfittingBlocks || skilled.Probability.Float64() > 0.05 {
+		t.Errorf("expected PBO near 0 for a skilled trial over %d blocks, got %s", skilled.Blocks, skilled.Probability)
+	}
+	if skilled.ProbabilityOfLoss.Float64() > 0.05 {
+		t.Errorf("expected the skilled trial to keep its edge out of sample, got loss probability %s", skilled.ProbabilityOfLoss)
+	}
+
+	invalid := []struct {
+		returns [][]primitives.Decimal
+		blocks  int
+	}{
+		{noiseReturns(rng, 1, 100), 4},
+		{noiseReturns(rng, 3, 100), 5},
+		{noiseReturns(rng, 3, 10), 8},
+		{append(noiseReturns(rng, 2, 100), noiseReturns(rng, 1, 99)...), 4},
+	}
+	for i, tc := range invalid {
+		if _, err := analytics.ProbabilityOfBacktestOverfitting(tc.returns, tc.blocks); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// CovarianceMethod selects how a CovarianceEstimator weights its window.
+type CovarianceMethod int
+
+const (
+	// CovarianceSample is the unbiased sample covariance of the window,
+	// every return weighted equally
+	CovarianceSample CovarianceMethod = iota
+
+	// CovarianceEWMA weights returns by Decay^age (RiskMetrics-style, zero
+	// mean), so recent moves dominate
+	CovarianceEWMA
+
+	// CovarianceLedoitWolf shrinks the sample covariance toward a scaled
+	// identity with the Ledoit-Wolf optimal intensity. Use it when the
+	// window is short relative to the number of assets.
+	CovarianceLedoitWolf
+)
+
+// String returns the method name.
+func (m CovarianceMethod) String() string {
+	switch m {
+	case CovarianceSample:
+		return "sample"
+	case CovarianceEWMA:
+		return "ewma"
+	case CovarianceLedoitWolf:
+		return "ledoit-wolf"
+	default:
+		return fmt.Sprintf("CovarianceMethod(%d)", int(m))
+	}
+}
+
+// CovarianceProvider exposes the most recent covariance estimate to risk
+// parity allocation, VaR and pairs-trading components.
+type CovarianceProvider interface {
+	// Covariance returns the latest estimate.
+	// Returns ErrInsufficientData until enough history has been observed.
+	Covariance() (Covariance, error)
+}
+
+// CovarianceConfig configures a CovarianceEstimator.
+type CovarianceConfig struct {
+	// Pairs are the price pairs to estimate across (at least two)
+	Pairs []string
+
+	// Method selects the estimator
+	Method CovarianceMethod
+
+	// Window is the number of returns each estimate covers
+	Window int
+
+	// Decay is the EWMA weight λ in (0, 1) applied per period of age
+	// (e.g., 0.94 for daily data); used only by CovarianceEWMA
+	Decay primitives.Decimal
+
+	// PeriodsPerYear annualizes the per-period covariance
+	PeriodsPerYear int64
+}
+
+// DefaultCovarianceConfig returns a 60-day sample covariance configuration
+// for daily snapshots, with the RiskMetrics decay of 0.94 ready for EWMA.
+func DefaultCovarianceConfig(pairs ...string) CovarianceConfig {
+	return CovarianceConfig{
+		Pairs:          pairs,
+		Method:         CovarianceSample,
+		Window:         60,
+		Decay:          primitives.MustDecimalFromString("0.94"),
+		PeriodsPerYear: 365,
+	}
+}
+
+// Covariance is an annualized covariance matrix of log returns.
+//
+// Thread Safety: Covariance is immutable and safe for concurrent use.
+type Covariance struct {
+	// Time is the snapshot time of the newest return in the estimate
+	Time primitives.Time
+
+	// Shrinkage is the Ledoit-Wolf intensity in [0, 1] applied to the
+	// sample covariance; zero for the other methods
+	Shrinkage primitives.Decimal
+
+	pairs  []string
+	index  map[string]int
+	matrix [][]float64
+}
+
+// NewCovariance builds a Covariance from an annualized matrix whose rows
+// and columns follow pairs. It is useful for covariances estimated
+// elsewhere. Returns ErrInvalidConfig unless matrix is square, symmetric
+// and matches pairs.
+func NewCovariance(pairs []string, matrix [][]primitives.Decimal) (Covariance, error) {
+	if len(pairs) == 0 || len(matrix) != len(pairs) {
+		return Covariance{}, fmt.Errorf("%w: matrix must have one row per pair", ErrInvalidConfig)
+	}
+	for i, row := range matrix {
+		if len(row) != len(pairs) {
+			return Covariance{}, fmt.Errorf("%w: row %d has %d columns, want %d", ErrInvalidConfig, i, len(row), len(pairs))
+		}
+	}
+	values := make([][]float64, len(pairs))
+	for i, row := range matrix {
+		values[i] = make([]float64, len(row))
+		for j, v := range row {
+			if !v.Equal(matrix[j][i]) {
+				return Covariance{}, fmt.Errorf("%w: matrix is not symmetric at (%d, %d)", ErrInvalidConfig, i, j)
+			}
+			values[i][j] = v.Float64()
+		}
+	}
+	return newCovariance(pairs, values)
+}
+
+func newCovariance(pairs []string, matrix [][]float64) (Covariance, error) {
+	index := make(map[string]int, len(pairs))
+	for i, pair := range pairs {
+		if _, dup := index[pair]; dup {
+			return Covariance{}, fmt.Errorf("%w: duplicate pair %s", ErrInvalidConfig, pair)
+		}
+		index[pair] = i
+	}
+	return Covariance{
+		Shrinkage: primitives.Zero(),
+		pairs:     append([]string(nil), pairs...),
+		index:     index,
+		matrix:    matrix,
+	}, nil
+}
+
+// Pairs returns the pairs in matrix order.
+func (c Covariance) Pairs() []string {
+	return append([]string(nil), c.pairs...)
+}
+
+// Matrix returns a copy of the annualized covariance matrix in Pairs order.
+func (c Covariance) Matrix() [][]primitives.Decimal {
+	matrix := make([][]primitives.Decimal, len(c.matrix))
+	for i, row := range c.matrix {
+		matrix[i] = make([]primitives.Decimal, len(row))
+		for j, v := range row {
+			matrix[i][j] = primitives.NewDecimalFromFloat(v)
+		}
+	}
+	return matrix
+}
+
+// Covariance returns the annualized covariance between a and b.
+// Returns ErrInvalidConfig if either pair is not in the matrix.
+func (c Covariance) Covariance(a, b string) (primitives.Decimal, error) {
+	i, j, err := c.indices(a, b)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return primitives.NewDecimalFromFloat(c.matrix[i][j]), nil
+}
+
+// Volatility returns the annualized volatility of pair.
+func (c Covariance) Volatility(pair string) (primitives.Decimal, error) {
+	i, _, err := c.indices(pair, pair)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return primitives.NewDecimalFromFloat(math.Sqrt(c.matrix[i][i])), nil
+}
+
+// Correlation returns the correlation between a and b, or zero if either
+// has no variance.
+func (c Covariance) Correlation(a, b string) (primitives.Decimal, error) {
+	i, j, err := c.indices(a, b)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return primitives.NewDecimalFromFloat(c.correlation(i, j)), nil
+}
+
+// CorrelationMatrix returns the correlation matrix in Pairs order.
+func (c Covariance) CorrelationMatrix() [][]primitives.Decimal {
+	matrix := make([][]primitives.Decimal, len(c.matrix))
+	for i := range c.matrix {
+		matrix[i] = make([]primitives.Decimal, len(c.matrix))
+		for j := range c.matrix {
+			matrix[i][j] = primitives.NewDecimalFromFloat(c.correlation(i, j))
+		}
+	}
+	return matrix
+}
+
+// PortfolioVariance returns eᵀΣe, the annualized variance of a portfolio
+// with the given exposures (notional value per pair; negative for shorts).
+// Pairs without an exposure contribute nothing.
+// Returns ErrInvalidConfig for an exposure to a pair not in the matrix.
+func (c Covariance) PortfolioVariance(exposures map[string]primitives.Decimal) (primitives.Decimal, error) {
+	e, err := c.vector(exposures)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	var variance float64
+	for i := range e {
+		for j := range e {
+			variance += e[i] * c.matrix[i][j] * e[j]
+		}
+	}
+	return primitives.NewDecimalFromFloat(math.Max(variance, 0)), nil
+}
+
+// ValueAtRisk returns the parametric (variance-covariance) VaR of the
+// exposures over horizon at the given confidence (e.g., 0.99): the loss
+// exceeded with probability 1 − confidence under normal, zero-mean returns,
+// z·σ·√(horizon in years).
+func (c Covariance) ValueAtRisk(exposures map[string]primitives.Decimal, confidence primitives.Decimal, horizon primitives.Duration) (primitives.Decimal, error) {
+	p := confidence.Float64()
+	if p <= 0.5 || p >= 1 {
+		return primitives.Zero(), fmt.Errorf("%w: confidence must be in (0.5, 1)", ErrInvalidConfig)
+	}
+	if horizon.Duration() <= 0 {
+		return primitives.Zero(), fmt.Errorf("%w: horizon must be positive", ErrInvalidConfig)
+	}
+	variance, err := c.PortfolioVariance(exposures)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	z := math.Sqrt2 * math.Erfinv(2*p-1)
+	years := horizon.Hours() / primitives.Year.Hours()
+	return primitives.NewDecimalFromFloat(z * math.Sqrt(variance.Float64()*years)), nil
+}
+
+func (c Covariance) indices(a, b string) (int, int, error) {
+	i, ok := c.index[a]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %s not in covariance matrix", ErrInvalidConfig, a)
+	}
+	j, ok := c.index[b]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %s not in covariance matrix", ErrInvalidConfig, b)
+	}
+	return i, j, nil
+}
+
+func (c Covariance) correlation(i, j int) float64 {
+	if i == j {
+		return 1
+	}
+	denom := math.Sqrt(c.matrix[i][i] * c.matrix[j][j])
+	if denom == 0 {
+		return 0
+	}
+	return c.matrix[i][j] / denom
+}
+
+// vector returns exposures as a slice in matrix order.
+func (c Covariance) vector(exposures map[string]primitives.Decimal) ([]float64, error) {
+	e := make([]float64, len(c.pairs))
+	for pair, exposure := range exposures {
+		i, ok := c.index[pair]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s not in covariance matrix", ErrInvalidConfig, pair)
+		}
+		e[i] = exposure.Float64()
+	}
+	return e, nil
+}
+
+// CovarianceEstimator estimates the covariance of log returns across
+// pairs over a rolling window. Call Update once per snapshot; it implements
+// CovarianceProvider.
+//
+// Thread Safety: CovarianceEstimator is not thread-safe.
+type CovarianceEstimator struct {
+	config  CovarianceConfig
+	prices  [][]float64
+	current Covariance
+	ready   bool
+}
+
+// NewCovarianceEstimator creates an estimator with the given configuration.
+// Returns error if the configuration is inconsistent.
+func NewCovarianceEstimator(config CovarianceConfig) (*CovarianceEstimator, error) {
+	if len(config.Pairs) < 2 {
+		return nil, fmt.Errorf("%w: covariance needs at least two pairs", ErrInvalidConfig)
+	}
+	seen := make(map[string]bool, len(config.Pairs))
+	for _, pair := range config.Pairs {
+		if pair == "" || seen[pair] {
+			return nil, fmt.Errorf("%w: pairs must be non-empty and unique", ErrInvalidConfig)
+		}
+		seen[pair] = true
+	}
+	if config.Window < 2 {
+		return nil, fmt.Errorf("%w: window must be at least 2", ErrInvalidConfig)
+	}
+	if config.PeriodsPerYear <= 0 {
+		return nil, fmt.Errorf("%w: periods per year must be positive", ErrInvalidConfig)
+	}
+	switch config.Method {
+	case CovarianceSample, CovarianceLedoitWolf:
+	case CovarianceEWMA:
+		if !config.Decay.IsPositive() || !config.Decay.LessThan(primitives.One()) {
+			return nil, fmt.Errorf("%w: EWMA decay must be in (0, 1)", ErrInvalidConfig)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown method %s", ErrInvalidConfig, config.Method)
+	}
+	config.Pairs = append([]string(nil), config.Pairs...)
+	return &CovarianceEstimator{
+		config: config,
+		prices: make([][]float64, 0, config.Window+1),
+	}, nil
+}
+
+// Update records the snapshot prices and re-estimates the covariance.
+// Returns the new estimate, or ErrInsufficientData while warming up.
+func (e *CovarianceEstimator) Update(snapshot strategy.MarketSnapshot) (Covariance, error) {
+	row := make([]float64, len(e.config.Pairs))
+	for i, pair := range e.config.Pairs {
+		price, err := snapshot.Price(pair)
+		if err != nil {
+			return Covariance{}, fmt.Errorf("covariance estimator: failed to get price for %s: %w", pair, err)
+		}
+		if price.IsZero() {
+			return Covariance{}, fmt.Errorf("covariance estimator: %w: zero price for %s", strategy.ErrInvalidMarketData, pair)
+		}
+		row[i] = price.Decimal().Float64()
+	}
+
+	e.prices = append(e.prices, row)
+	if len(e.prices) > e.config.Window+1 {
+		e.prices = e.prices[1:]
+	}
+	if len(e.prices) < e.config.Window+1 {
+		return Covariance{}, ErrInsufficientData
+	}
+
+	cov, err := e.estimate()
+	if err != nil {
+		return Covariance{}, err
+	}
+	cov.Time = snapshot.Time()
+	e.current = cov
+	e.ready = true
+	return cov, nil
+}
+
+// Covariance returns the latest estimate.
+func (e *CovarianceEstimator) Covariance() (Covariance, error) {
+	if !e.ready {
+		return Covariance{}, ErrInsufficientData
+	}
+	return e.current, nil
+}
+
+// estimate computes the annualized covariance of the current window.
+func (e *CovarianceEstimator) estimate() (Covariance, error) {
+	returns := make([][]float64, len(e.prices)-1)
+	for t := range returns {
+		returns[t] = make([]float64, len(e.config.Pairs))
+		for i := range e.config.Pairs {
+			returns[t][i] = math.Log(e.prices[t+1][i] / e.prices[t][i])
+		}
+	}
+
+	var matrix [][]float64
+	shrinkage := 0.0
+	switch e.config.Method {
+	case CovarianceEWMA:
+		matrix = ewmaCovariance(returns, e.config.Decay.Float64())
+	case CovarianceLedoitWolf:
+		matrix, shrinkage = ledoitWolf(returns)
+	default:
+		matrix = sampleCovariance(returns, float64(len(returns)-1))
+	}
+
+	periods := float64(e.config.PeriodsPerYear)
+	for i := range matrix {
+		for j := range matrix[i] {
+			matrix[i][j] *= periods
+		}
+	}
+	cov, err := newCovariance(e.config.Pairs, matrix)
+	if err != nil {
+		return Covariance{}, err
+	}
+	cov.Shrinkage = primitives.NewDecimalFromFloat(shrinkage)
+	return cov, nil
+}
+
+// demean returns returns minus each column's mean.
+func demean(returns [][]float64) [][]float64 {
+	p := len(returns[0])
+	means := make([]float64, p)
+	for _, r := range returns {
+		for i, x := range r {
+			means[i] += x / float64(len(returns))
+		}
+	}
+	centered := make([][]float64, len(returns))
+	for t, r := range returns {
+		centered[t] = make([]float64, p)
+		for i, x := range r {
+			centered[t][i] = x - means[i]
+		}
+	}
+	return centered
+}
+
+// sampleCovariance returns Σ xᵀx / divisor over demeaned returns.
+func sampleCovariance(returns [][]float64, divisor float64) [][]float64 {
+	x := demean(returns)
+	p := len(x[0])
+	matrix := make([][]float64, p)
+	for i := range matrix {
+		matrix[i] = make([]float64, p)
+	}
+	for _, r := range x {
+		for i := 0; i < p; i++ {
+			for j := i; j < p; j++ {
+				matrix[i][j] += r[i] * r[j] / divisor
+			}
+		}
+	}
+	for i := 0; i < p; i++ {
+		for j := 0; j < i; j++ {
+			matrix[i][j] = matrix[j][i]
+		}
+	}
+	return matrix
+}
+
+// ewmaCovariance returns the zero-mean covariance with weight decay^age,
+// normalized over the window.
+func ewmaCovariance(returns [][]float64, decay float64) [][]float64 {
+	p := len(returns[0])
+	matrix := make([][]float64, p)
+	for i := range matrix {
+		matrix[i] = make([]float64, p)
+	}
+	weight, total := 1.0, 0.0
+	for t := len(returns) - 1; t >= 0; t-- {
+		for i := 0; i < p; i++ {
+			for j := 0; j < p; j++ {
+				matrix[i][j] += weight * returns[t][i] * returns[t][j]
+			}
+		}
+		total += weight
+		weight *= decay
+	}
+	for i := range matrix {
+		for j := range matrix[i] {
+			matrix[i][j] /= total
+		}
+	}
+	return matrix
+}
+
+// ledoitWolf shrinks the (1/n) sample covariance S toward μI, μ = tr(S)/p,
+// with intensity δ = min(b², d²)/d² where d² = ‖S − μI‖² and
+// b² = Σₜ ‖xₜxₜᵀ − S‖² / n² (Ledoit & Wolf, 2004).
+func ledoitWolf(returns [][]float64) ([][]float64, float64) {
+	n := float64(len(returns))
+	x := demean(returns)
+	s := sampleCovariance(returns, n)
+	p := len(s)
+
+	var mu float64
+	for i := 0; i < p; i++ {
+		mu += s[i][i] / float64(p)
+	}
+	var d2, b2 float64
+	for i := 0; i < p; i++ {
+		for j := 0; j < p; j++ {
+			target := 0.0
+			if i == j {
+				target = mu
+			}
+			d2 += (s[i][j] - target) * (s[i][j] - target)
+		}
+	}
+	for _, r := range x {
+		for i := 0; i < p; i++ {
+			for j := 0; j < p; j++ {
+				diff := r[i]*r[j] - s[i][j]
+				b2 += diff * diff
+			}
+		}
+	}
+	b2 /= n * n
+
+	shrinkage := 1.0
+	if d2 > 0 {
+		shrinkage = math.Min(b2, d2) / d2
+	}
+	for i := 0; i < p; i++ {
+		for j := 0; j < p; j++ {
+			s[i][j] *= 1 - shrinkage
+			if i == j {
+				s[i][j] += shrinkage * mu
+			}
+		}
+	}
+	return s, shrinkage
+}
@@ -0,0 +1,291 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DeflatedSharpeConfig configures DeflatedSharpe.
+type DeflatedSharpeConfig struct {
+	// Returns are the periodic returns of the selected strategy, typically
+	// the best of an optimizer's trials
+	Returns []primitives.Decimal
+
+	// TrialSharpes are the per-period (not annualized) Sharpe ratios of
+	// every trial the strategy was selected from, itself included
+	TrialSharpes []primitives.Decimal
+
+	// Trials is the number of independent trials; len(TrialSharpes) if
+	// zero. Correlated trials count for less than one each, so a smaller
+	// effective number may be given.
+	Trials int
+}
+
+// DeflatedSharpeRatio is the outcome of DeflatedSharpe. Sharpe ratios are
+// per period of the returns.
+type DeflatedSharpeRatio struct {
+	// Sharpe is the selected strategy's Sharpe ratio
+	Sharpe primitives.Decimal
+
+	// ExpectedMax is the Sharpe ratio the best of the trials is expected
+	// to reach by luck alone, when no trial has any skill
+	ExpectedMax primitives.Decimal
+
+	// Probability is the deflated Sharpe ratio: the probability that the
+	// strategy's true Sharpe ratio exceeds ExpectedMax, given the length,
+	// skewness and kurtosis of its returns. Values above 0.95 are
+	// conventionally significant.
+	Probability primitives.Decimal
+
+	Skewness     primitives.Decimal
+	Kurtosis     primitives.Decimal
+	Observations int
+	Trials       int
+}
+
+// DeflatedSharpe computes the deflated Sharpe ratio of Bailey and López
+// de Prado (2014), which corrects a selected strategy's Sharpe ratio for
+// the number of trials it was selected from and for non-normal returns:
+//
+//	SR₀ = √V[SRₙ] · ((1−γ)·Φ⁻¹(1−1/N) + γ·Φ⁻¹(1−1/(N·e)))
+//	DSR = Φ((SR − SR₀)·√(T−1) / √(1 − γ₃·SR + (γ₄−1)/4·SR²))
+//
+// where γ is the Euler-Mascheroni constant, γ₃ and γ₄ the skewness and
+// kurtosis of the T returns, and V[SRₙ] the variance of the N trials'
+// Sharpe ratios. With fewer than two trials SR₀ is zero, and DSR is the
+// probabilistic Sharpe ratio. Returns ErrInsufficientData with fewer than
+// three returns or returns without variance.
+func DeflatedSharpe(config DeflatedSharpeConfig) (DeflatedSharpeRatio, error) {
+	returns := toFloats(config.Returns)
+	t := len(returns)
+	if t < 3 {
+		return DeflatedSharpeRatio{}, fmt.Errorf("%w: deflated Sharpe needs at least 3 returns, got %d", ErrInsufficientData, t)
+	}
+	if config.Trials < 0 {
+		return DeflatedSharpeRatio{}, fmt.Errorf("%w: trials cannot be negative", ErrInvalidConfig)
+	}
+	mean, std := meanStd(returns)
+	if std == 0 {
+		return DeflatedSharpeRatio{}, fmt.Errorf("%w: returns have no variance", ErrInsufficientData)
+	}
+	sharpe := mean / std
+	var skew, kurt float64
+	for _, r := range returns {
+		z := (r - mean) / std
+		skew += z * z * z
+		kurt += z * z * z * z
+	}
+	skew /= float64(t)
+	kurt /= float64(t)
+
+	n := config.Trials
+	if n == 0 {
+		n = len(config.TrialSharpes)
+	}
+	expectedMax := 0.0
+	if sharpes := toFloats(config.TrialSharpes); n >= 2 && len(sharpes) >= 2 {
+		_, spread := meanStd(sharpes)
+		const eulerGamma = 0.5772156649015329
+		expectedMax = spread * ((1-eulerGamma)*normalQuantile(1-1/float64(n)) +
+			eulerGamma*normalQuantile(1-1/(float64(n)*math.E)))
+	}
+
+	// The variance term can only vanish for extreme skew; floor it rather
+	// than divide by zero
+	denominator := math.Sqrt(math.Max(1-skew*sharpe+(kurt-1)/4*sharpe*sharpe, 1e-12))
+	probability := numerics.NormalCDF((sharpe - expectedMax) * math.Sqrt(float64(t-1)) / denominator)
+	return DeflatedSharpeRatio{
+		Sharpe:       primitives.NewDecimalFromFloat(sharpe),
+		ExpectedMax:  primitives.NewDecimalFromFloat(expectedMax),
+		Probability:  primitives.NewDecimalFromFloat(probability),
+		Skewness:     primitives.NewDecimalFromFloat(skew),
+		Kurtosis:     primitives.NewDecimalFromFloat(kurt),
+		Observations: t,
+		Trials:       n,
+	}, nil
+}
+
+// BacktestOverfitting is the outcome of ProbabilityOfBacktestOverfitting.
+type BacktestOverfitting struct {
+	// Probability is the PBO: the share of combinations in which the
+	// trial best in sample ranks in the bottom half out of sample. Near
+	// zero, selection carries over out of sample; near 0.5 or above, it is
+	// no better than chance.
+	Probability primitives.Decimal
+
+	// ProbabilityOfLoss is the share of combinations in which the trial
+	// best in sample has a negative Sharpe ratio out of sample
+	ProbabilityOfLoss primitives.Decimal
+
+	// Logits are, per combination, the logit ln(ω/(1−ω)) of the relative
+	// out-of-sample rank ω of the trial best in sample; PBO is the share
+	// that are not positive
+	Logits []primitives.Decimal
+
+	Blocks       int
+	Combinations int
+}
+
+// DefaultOverfittingBlocks is the number of blocks
+// ProbabilityOfBacktestOverfitting splits the returns into when given
+// zero: C(16, 8) = 12870 combinations.
+const DefaultOverfittingBlocks = 16
+
+// ProbabilityOfBacktestOverfitting computes the probability of backtest
+// overfitting by combinatorially symmetric cross-validation (Bailey,
+// Borwein, López de Prado and Zhu, 2015). returns[i] is the return series
+// of trial i, all of equal length and aligned in time. The series are cut
+// into blocks (an even number, DefaultOverfittingBlocks if zero) of equal
+// length, dropping the remainder at the start; every choice of half the
+// blocks is an in-sample set and the other half its out-of-sample set.
+// Trials are ranked by Sharpe ratio, and PBO is the share of combinations
+// in which the best in-sample trial ranks at or below the median out of
+// sample. Returns ErrInsufficientData with fewer than two trials or two
+// returns per block, and ErrInvalidConfig for an odd number of blocks,
+// more than 24, or series of unequal length.
+func ProbabilityOfBacktestOverfitting(returns [][]primitives.Decimal, blocks int) (BacktestOverfitting, error) {
+	if blocks == 0 {
+		blocks = DefaultOverfittingBlocks
+	}
+	if blocks < 2 || blocks%2 != 0 || blocks > 24 {
+		return BacktestOverfitting{}, fmt.Errorf("%w: blocks must be even and in [2, 24], got %d", ErrInvalidConfig, blocks)
+	}
+	if len(returns) < 2 {
+		return BacktestOverfitting{}, fmt.Errorf("%w: PBO needs at least 2 trials, got %d", ErrInsufficientData, len(returns))
+	}
+	t := len(returns[0])
+	series := make([][]float64, len(returns))
+	for i, r := range returns {
+		if len(r) != t {
+			return BacktestOverfitting{}, fmt.Errorf("%w: trial %d has %d returns, trial 0 has %d", ErrInvalidConfig, i, len(r), t)
+		}
+		series[i] = toFloats(r)
+	}
+	size := t / blocks
+	if size < 2 {
+		return BacktestOverfitting{}, fmt.Errorf("%w: %d returns make fewer than 2 per block for %d blocks", ErrInsufficientData, t, blocks)
+	}
+	offset := t - size*blocks
+
+	// Per trial and block, the sums that make up a Sharpe ratio over any
+	// union of blocks
+	type moments struct{ sum, sumSq float64 }
+	stats := make([][]moments, len(series))
+	for i, s := range series {
+		stats[i] = make([]moments, blocks)
+		for b := range stats[i] {
+			for _, r := range s[offset+b*size : offset+(b+1)*size] {
+				stats[i][b].sum += r
+				stats[i][b].sumSq += r * r
+			}
+		}
+	}
+	sharpe := func(trial int, mask uint32) float64 {
+		var sum, sumSq float64
+		n := 0
+		for b := 0; b < blocks; b++ {
+			if mask&(1<<b) != 0 {
+				sum += stats[trial][b].sum
+				sumSq += stats[trial][b].sumSq
+				n += size
+			}
+		}
+		mean := sum / float64(n)
+		variance := (sumSq - float64(n)*mean*mean) / float64(n-1)
+		if variance <= 0 {
+			switch {
+			case mean > 0:
+				return math.Inf(1)
+			case mean < 0:
+				return math.Inf(-1)
+			}
+			return 0
+		}
+		return mean / math.Sqrt(variance)
+	}
+
+	result := BacktestOverfitting{Blocks: blocks}
+	all := uint32(1)<<blocks - 1
+	overfit, losses := 0, 0
+	inSample := make([]float64, len(series))
+	outSample := make([]float64, len(series))
+	for mask := uint32(0); mask <= all; mask++ {
+		if bits.OnesCount32(mask) != blocks/2 {
+			continue
+		}
+		for i := range series {
+			inSample[i] = sharpe(i, mask)
+			outSample[i] = sharpe(i, all&^mask)
+		}
+		best := 0
+		for i := range inSample {
+			if inSample[i] > inSample[best] {
+				best = i
+			}
+		}
+		omega := relativeRank(outSample, best)
+		logit := math.Log(omega / (1 - omega))
+		result.Logits = append(result.Logits, primitives.NewDecimalFromFloat(logit))
+		if logit <= 0 {
+			overfit++
+		}
+		if outSample[best] < 0 {
+			losses++
+		}
+		result.Combinations++
+	}
+	combinations := float64(result.Combinations)
+	result.Probability = primitives.NewDecimalFromFloat(float64(overfit) / combinations)
+	result.ProbabilityOfLoss = primitives.NewDecimalFromFloat(float64(losses) / combinations)
+	return result, nil
+}
+
+// relativeRank returns the rank of values[i] among values, from 1 for the
+// lowest to len(values) for the highest with ties sharing their mean rank,
+// divided by len(values)+1 so it lies in (0, 1).
+func relativeRank(values []float64, i int) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	lo := sort.SearchFloat64s(sorted, values[i])
+	hi := lo
+	for hi < len(sorted) && sorted[hi] == values[i] {
+		hi++
+	}
+	rank := float64(lo+1+hi) / 2
+	return rank / float64(len(values)+1)
+}
+
+// normalQuantile is the inverse of the standard normal distribution.
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+func toFloats(values []primitives.Decimal) []float64 {
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		floats[i] = v.Float64()
+	}
+	return floats
+}
+
+// meanStd returns the mean and sample standard deviation of values.
+func meanStd(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)-1))
+}
@@ -0,0 +1,241 @@
+package analytics
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// VolatilityRegime buckets realized volatility into discrete states.
+type VolatilityRegime string
+
+const (
+	// VolatilityLow indicates realized volatility below the low threshold
+	VolatilityLow VolatilityRegime = "low"
+
+	// VolatilityMid indicates realized volatility between the thresholds
+	VolatilityMid VolatilityRegime = "mid"
+
+	// VolatilityHigh indicates realized volatility at or above the high threshold
+	VolatilityHigh VolatilityRegime = "high"
+)
+
+// TrendRegime classifies whether price is trending or mean-reverting.
+type TrendRegime string
+
+const (
+	// TrendTrending indicates persistent price moves (variance ratio above threshold)
+	TrendTrending TrendRegime = "trending"
+
+	// TrendRanging indicates choppy, mean-reverting price action
+	TrendRanging TrendRegime = "ranging"
+)
+
+// Regime is the market state classification for a single snapshot.
+type Regime struct {
+	// Time is the snapshot time the classification applies to
+	Time primitives.Time
+
+	// Volatility is the realized volatility bucket
+	Volatility VolatilityRegime
+
+	// Trend is the trend/chop classification
+	Trend TrendRegime
+
+	// RealizedVol is the annualized realized volatility used for bucketing
+	RealizedVol primitives.Decimal
+
+	// VarianceRatio is the Lo-MacKinlay variance ratio used for trend classification.
+	// Values above 1 indicate trending behaviour, below 1 mean reversion.
+	VarianceRatio primitives.Decimal
+}
+
+// RegimeProvider exposes the most recent regime classification to strategies.
+type RegimeProvider interface {
+	// Regime returns the latest classification.
+	// Returns ErrInsufficientData until enough history has been observed.
+	Regime() (Regime, error)
+}
+
+// RegimeConfig configures a RegimeDetector.
+type RegimeConfig struct {
+	// Pair is the price pair to classify (e.g., "ETH/USD")
+	Pair string
+
+	// Window is the number of snapshots of history used for both estimators
+	Window int
+
+	// Lag is the multi-period horizon q used by the variance ratio (must be < Window/2)
+	Lag int
+
+	// PeriodsPerYear annualizes realized volatility (e.g., 365 for daily snapshots)
+	PeriodsPerYear int64
+
+	// LowVolThreshold is the annualized volatility below which the regime is low
+	LowVolThreshold primitives.Decimal
+
+	// HighVolThreshold is the annualized volatility at or above which the regime is high
+	HighVolThreshold primitives.Decimal
+
+	// TrendThreshold is the variance ratio at or above which price is trending
+	// (1.0 corresponds to a random walk)
+	TrendThreshold primitives.Decimal
+}
+
+// DefaultRegimeConfig returns a configuration suited to daily crypto data:
+// 30-day window, 5-day variance ratio lag, 40%/80% volatility thresholds.
+func DefaultRegimeConfig(pair string) RegimeConfig {
+	return RegimeConfig{
+		Pair:             pair,
+		Window:           30,
+		Lag:              5,
+		PeriodsPerYear:   365,
+		LowVolThreshold:  primitives.MustDecimalFromString("0.4"),
+		HighVolThreshold: primitives.MustDecimalFromString("0.8"),
+		TrendThreshold:   primitives.One(),
+	}
+}
+
+// Metadata keys under which Annotate stores the classification.
+// The pair is interpolated into each key (e.g., "regime:ETH/USD:volatility").
+const (
+	regimeVolatilityKeyFormat = "regime:%s:volatility"
+	regimeTrendKeyFormat      = "regime:%s:trend"
+)
+
+// RegimeVolatilityKey returns the snapshot metadata key holding the volatility regime for pair.
+func RegimeVolatilityKey(pair string) string {
+	return fmt.Sprintf(regimeVolatilityKeyFormat, pair)
+}
+
+// RegimeTrendKey returns the snapshot metadata key holding the trend regime for pair.
+func RegimeTrendKey(pair string) string {
+	return fmt.Sprintf(regimeTrendKeyFormat, pair)
+}
+
+// RegimeDetector classifies market regime from a rolling price history.
+// Call Update once per snapshot; it implements RegimeProvider.
+//
+// Volatility is the annualized standard deviation of one-period returns.
+// Trend uses the variance ratio VR(q) = Var(q-period returns) / (q * Var(1-period returns)).
+//
+// Thread Safety: RegimeDetector is not thread-safe.
+type RegimeDetector struct {
+	config  RegimeConfig
+	prices  []primitives.Decimal
+	current Regime
+	ready   bool
+}
+
+// NewRegimeDetector creates a detector with the given configuration.
+// Returns error if the configuration is inconsistent.
+func NewRegimeDetector(config RegimeConfig) (*RegimeDetector, error) {
+	if config.Pair == "" {
+		return nil, fmt.Errorf("%w: pair cannot be empty", ErrInvalidConfig)
+	}
+	if config.Lag < 1 || config.Window < 2*config.Lag+1 {
+		return nil, fmt.Errorf("%w: window must exceed twice the lag", ErrInvalidConfig)
+	}
+	if config.PeriodsPerYear <= 0 {
+		return nil, fmt.Errorf("%w: periods per year must be positive", ErrInvalidConfig)
+	}
+	if config.HighVolThreshold.LessThan(config.LowVolThreshold) {
+		return nil, fmt.Errorf("%w: high volatility threshold below low threshold", ErrInvalidConfig)
+	}
+	return &RegimeDetector{
+		config: config,
+		prices: make([]primitives.Decimal, 0, config.Window+1),
+	}, nil
+}
+
+// Update records the snapshot price and reclassifies the regime.
+// Returns the new classification, or ErrInsufficientData while warming up.
+func (d *RegimeDetector) Update(snapshot strategy.MarketSnapshot) (Regime, error) {
+	price, err := snapshot.Price(d.config.Pair)
+	if err != nil {
+		return Regime{}, fmt.Errorf("regime detector: failed to get price for %s: %w", d.config.Pair, err)
+	}
+
+	d.prices = append(d.prices, price.Decimal())
+	if len(d.prices) > d.config.Window+1 {
+		d.prices = d.prices[1:]
+	}
+	if len(d.prices) < d.config.Window+1 {
+		return Regime{}, ErrInsufficientData
+	}
+
+	regime, err := d.classify()
+	if err != nil {
+		return Regime{}, err
+	}
+	regime.Time = snapshot.Time()
+	d.current = regime
+	d.ready = true
+	return regime, nil
+}
+
+// Regime returns the latest classification.
+func (d *RegimeDetector) Regime() (Regime, error) {
+	if !d.ready {
+		return Regime{}, ErrInsufficientData
+	}
+	return d.current, nil
+}
+
+// Annotate writes the latest classification into snapshot metadata under
+// RegimeVolatilityKey and RegimeTrendKey, so downstream consumers that only
+// see snapshots can read it.
+func (d *RegimeDetector) Annotate(snapshot *strategy.SimpleSnapshot) error {
+	regime, err := d.Regime()
+	if err != nil {
+		return err
+	}
+	snapshot.Set(RegimeVolatilityKey(d.config.Pair), regime.Volatility)
+	snapshot.Set(RegimeTrendKey(d.config.Pair), regime.Trend)
+	return nil
+}
+
+// classify computes the regime from the current price window.
+func (d *RegimeDetector) classify() (Regime, error) {
+	oneReturns := simpleReturns(d.prices, 1)
+	oneVar, err := variance(oneReturns)
+	if err != nil {
+		return Regime{}, err
+	}
+
+	realizedVol := oneVar.Sqrt().Mul(primitives.NewDecimal(d.config.PeriodsPerYear).Sqrt())
+
+	volRegime := VolatilityMid
+	switch {
+	case realizedVol.LessThan(d.config.LowVolThreshold):
+		volRegime = VolatilityLow
+	case !realizedVol.LessThan(d.config.HighVolThreshold):
+		volRegime = VolatilityHigh
+	}
+
+	// A flat series has no dispersion; treat it as a random walk
+	varianceRatio := primitives.One()
+	if !oneVar.IsZero() {
+		qVar, err := variance(simpleReturns(d.prices, d.config.Lag))
+		if err != nil {
+			return Regime{}, err
+		}
+		varianceRatio, err = qVar.Div(oneVar.Mul(primitives.NewDecimal(int64(d.config.Lag))))
+		if err != nil {
+			return Regime{}, err
+		}
+	}
+
+	trend := TrendRanging
+	if !varianceRatio.LessThan(d.config.TrendThreshold) {
+		trend = TrendTrending
+	}
+
+	return Regime{
+		Volatility:    volRegime,
+		Trend:         trend,
+		RealizedVol:   realizedVol,
+		VarianceRatio: varianceRatio,
+	}, nil
+}
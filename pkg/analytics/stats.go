@@ -0,0 +1,47 @@
+// Package analytics provides market and performance analysis components that
+// sit alongside strategies: regime classification, volatility and correlation
+// estimation, and other diagnostics derived from snapshot histories.
+//
+// Components in this package observe data; they never mutate portfolios or
+// emit actions. Strategies consume their outputs to make decisions.
+package analytics
+
+import (
+	"errors"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInsufficientData indicates not enough observations for a calculation
+	ErrInsufficientData = errors.New("insufficient data")
+
+	// ErrInvalidConfig indicates an analytics component was configured incorrectly
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// variance returns the population variance of values.
+func variance(values []primitives.Decimal) (primitives.Decimal, error) {
+	if len(values) == 0 {
+		return primitives.Zero(), ErrInsufficientData
+	}
+	_, v, err := primitives.MeanVariance(values)
+	return v, err
+}
+
+// simpleReturns returns period-over-period returns of prices with the given lag.
+// Pairs with a zero base price are skipped.
+func simpleReturns(prices []primitives.Decimal, lag int) []primitives.Decimal {
+	if lag <= 0 || len(prices) <= lag {
+		return nil
+	}
+	returns := make([]primitives.Decimal, 0, len(prices)-lag)
+	for i := lag; i < len(prices); i++ {
+		ret, err := prices[i].Sub(prices[i-lag]).Div(prices[i-lag])
+		if err != nil {
+			continue
+		}
+		returns = append(returns, ret)
+	}
+	return returns
+}
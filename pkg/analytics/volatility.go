@@ -0,0 +1,273 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Estimator selects how realized volatility is computed from a bar window.
+type Estimator int
+
+const (
+	// EstimatorCloseToClose is the standard deviation of log close-to-close
+	// returns. It is the only estimator that works on close-only data.
+	EstimatorCloseToClose Estimator = iota
+
+	// EstimatorParkinson uses each bar's high-low range:
+	// σ² = Σ ln(H/L)² / (4n·ln 2). It ignores overnight gaps and drift.
+	EstimatorParkinson
+
+	// EstimatorGarmanKlass adds the open-close move to the range:
+	// σ² = Σ [½·ln(H/L)² − (2·ln 2 − 1)·ln(C/O)²] / n.
+	EstimatorGarmanKlass
+
+	// EstimatorYangZhang combines the open-jump, open-close and
+	// Rogers-Satchell variances. It handles both gaps between bars and
+	// drift, and is the most efficient of the range estimators.
+	EstimatorYangZhang
+)
+
+// String returns the estimator name.
+func (e Estimator) String() string {
+	switch e {
+	case EstimatorCloseToClose:
+		return "close-to-close"
+	case EstimatorParkinson:
+		return "parkinson"
+	case EstimatorGarmanKlass:
+		return "garman-klass"
+	case EstimatorYangZhang:
+		return "yang-zhang"
+	default:
+		return fmt.Sprintf("Estimator(%d)", int(e))
+	}
+}
+
+// VolatilityProvider exposes the most recent realized volatility estimate,
+// annualized, to strategies and sizers (e.g., as sizing.Params.Volatility).
+type VolatilityProvider interface {
+	// Volatility returns the latest annualized estimate.
+	// Returns ErrInsufficientData until enough history has been observed.
+	Volatility() (primitives.Decimal, error)
+}
+
+// VolatilityConfig configures a VolatilityEstimator.
+type VolatilityConfig struct {
+	// Pair is the price pair to measure (e.g., "ETH/USD")
+	Pair string
+
+	// Estimator selects the volatility formula
+	Estimator Estimator
+
+	// Window is the number of bars (returns) each estimate covers
+	Window int
+
+	// PeriodsPerYear annualizes the per-bar variance (e.g., 365 for daily
+	// bars, 8760 for hourly)
+	PeriodsPerYear int64
+}
+
+// DefaultVolatilityConfig returns a 30-day Yang-Zhang configuration for
+// daily bars.
+func DefaultVolatilityConfig(pair string) VolatilityConfig {
+	return VolatilityConfig{
+		Pair:           pair,
+		Estimator:      EstimatorYangZhang,
+		Window:         30,
+		PeriodsPerYear: 365,
+	}
+}
+
+// Metadata key under which Annotate stores the estimate
+// (e.g., "volatility:ETH/USD:realized").
+const realizedVolatilityKeyFormat = "volatility:%s:realized"
+
+// RealizedVolatilityKey returns the snapshot metadata key holding the
+// realized volatility for pair.
+func RealizedVolatilityKey(pair string) string {
+	return fmt.Sprintf(realizedVolatilityKeyFormat, pair)
+}
+
+// VolatilityEstimator measures realized volatility over a rolling window
+// of bars. Call Update once per snapshot; it implements VolatilityProvider.
+//
+// Range-based estimators read OHLCV bars through strategy.BarSnapshot.
+// Close-to-close also accepts plain snapshots, using the snapshot price.
+// Every estimator keeps Window+1 bars so that close-to-close and
+// Yang-Zhang see Window returns.
+//
+// Thread Safety: VolatilityEstimator is not thread-safe.
+type VolatilityEstimator struct {
+	config  VolatilityConfig
+	bars    []strategy.Bar
+	current primitives.Decimal
+	ready   bool
+}
+
+// NewVolatilityEstimator creates an estimator with the given configuration.
+// Returns error if the configuration is inconsistent.
+func NewVolatilityEstimator(config VolatilityConfig) (*VolatilityEstimator, error) {
+	if config.Pair == "" {
+		return nil, fmt.Errorf("%w: pair cannot be empty", ErrInvalidConfig)
+	}
+	if config.Window < 2 {
+		return nil, fmt.Errorf("%w: window must be at least 2", ErrInvalidConfig)
+	}
+	if config.PeriodsPerYear <= 0 {
+		return nil, fmt.Errorf("%w: periods per year must be positive", ErrInvalidConfig)
+	}
+	if config.Estimator < EstimatorCloseToClose || config.Estimator > EstimatorYangZhang {
+		return nil, fmt.Errorf("%w: unknown estimator %s", ErrInvalidConfig, config.Estimator)
+	}
+	return &VolatilityEstimator{
+		config: config,
+		bars:   make([]strategy.Bar, 0, config.Window+1),
+	}, nil
+}
+
+// Update records the snapshot's bar for the pair and re-estimates
+// volatility. Returns the new annualized estimate, or ErrInsufficientData
+// while warming up.
+//
+// Range estimators return an error wrapping strategy.ErrBarNotAvailable for
+// snapshots without a bar for the pair.
+func (v *VolatilityEstimator) Update(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	bar, err := v.bar(snapshot)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("volatility estimator: %w", err)
+	}
+	if bar.Low.IsZero() || bar.Open.IsZero() || bar.Close.IsZero() {
+		return primitives.Zero(), fmt.Errorf("volatility estimator: %w: zero price in %s bar", strategy.ErrInvalidMarketData, v.config.Pair)
+	}
+
+	v.bars = append(v.bars, bar)
+	if len(v.bars) > v.config.Window+1 {
+		v.bars = v.bars[1:]
+	}
+	if len(v.bars) < v.config.Window+1 {
+		return primitives.Zero(), ErrInsufficientData
+	}
+
+	variance := v.variance()
+	v.current = primitives.NewDecimalFromFloat(math.Sqrt(math.Max(variance, 0) * float64(v.config.PeriodsPerYear)))
+	v.ready = true
+	return v.current, nil
+}
+
+// Volatility returns the latest annualized estimate.
+func (v *VolatilityEstimator) Volatility() (primitives.Decimal, error) {
+	if !v.ready {
+		return primitives.Zero(), ErrInsufficientData
+	}
+	return v.current, nil
+}
+
+// Annotate writes the latest estimate into snapshot metadata under
+// RealizedVolatilityKey. If the snapshot carries no implied volatility for
+// the pair it is also written to snapshotkeys.OptionImpliedVol, so option
+// pricers that read implied volatility fall back to realized volatility.
+func (v *VolatilityEstimator) Annotate(snapshot *strategy.SimpleSnapshot) error {
+	vol, err := v.Volatility()
+	if err != nil {
+		return err
+	}
+	snapshot.Set(RealizedVolatilityKey(v.config.Pair), vol)
+	if _, ok := snapshot.Get(snapshotkeys.OptionImpliedVol(v.config.Pair)); !ok {
+		snapshot.Set(snapshotkeys.OptionImpliedVol(v.config.Pair), vol)
+	}
+	return nil
+}
+
+// bar returns the snapshot's bar for the pair. Close-to-close falls back to
+// a flat bar at the snapshot price when no bar is available.
+func (v *VolatilityEstimator) bar(snapshot strategy.MarketSnapshot) (strategy.Bar, error) {
+	if bars, ok := snapshot.(strategy.BarSnapshot); ok {
+		bar, err := bars.Bar(v.config.Pair)
+		if err == nil {
+			return bar, nil
+		}
+		if !errors.Is(err, strategy.ErrBarNotAvailable) {
+			return strategy.Bar{}, err
+		}
+	}
+	if v.config.Estimator != EstimatorCloseToClose {
+		return strategy.Bar{}, fmt.Errorf("%w: %s needs OHLC bars for %s", strategy.ErrBarNotAvailable, v.config.Estimator, v.config.Pair)
+	}
+	price, err := snapshot.Price(v.config.Pair)
+	if err != nil {
+		return strategy.Bar{}, fmt.Errorf("failed to get price for %s: %w", v.config.Pair, err)
+	}
+	return strategy.Bar{Open: price, High: price, Low: price, Close: price}, nil
+}
+
+// variance returns the per-bar variance of the current window.
+func (v *VolatilityEstimator) variance() float64 {
+	// prev holds each bar's preceding bar; window holds the Window newest
+	prev, window := v.bars[:len(v.bars)-1], v.bars[1:]
+	n := float64(len(window))
+
+	switch v.config.Estimator {
+	case EstimatorParkinson:
+		var sum float64
+		for _, b := range window {
+			hl := logRatio(b.High, b.Low)
+			sum += hl * hl
+		}
+		return sum / (4 * n * math.Ln2)
+
+	case EstimatorGarmanKlass:
+		var sum float64
+		for _, b := range window {
+			hl, co := logRatio(b.High, b.Low), logRatio(b.Close, b.Open)
+			sum += 0.5*hl*hl - (2*math.Ln2-1)*co*co
+		}
+		return sum / n
+
+	case EstimatorYangZhang:
+		overnight := make([]float64, len(window))
+		openClose := make([]float64, len(window))
+		var rogersSatchell float64
+		for i, b := range window {
+			overnight[i] = logRatio(b.Open, prev[i].Close)
+			openClose[i] = logRatio(b.Close, b.Open)
+			rogersSatchell += logRatio(b.High, b.Close)*logRatio(b.High, b.Open) +
+				logRatio(b.Low, b.Close)*logRatio(b.Low, b.Open)
+		}
+		k := 0.34 / (1.34 + (n+1)/(n-1))
+		return sampleVariance(overnight) + k*sampleVariance(openClose) + (1-k)*rogersSatchell/n
+
+	default:
+		returns := make([]float64, len(window))
+		for i, b := range window {
+			returns[i] = logRatio(b.Close, prev[i].Close)
+		}
+		return sampleVariance(returns)
+	}
+}
+
+// logRatio returns ln(a/b).
+func logRatio(a, b primitives.Price) float64 {
+	return math.Log(a.Decimal().Float64() / b.Decimal().Float64())
+}
+
+// sampleVariance returns the unbiased (n−1) variance of values.
+func sampleVariance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, x := range values {
+		mean += x
+	}
+	mean /= float64(len(values))
+	var sum float64
+	for _, x := range values {
+		sum += (x - mean) * (x - mean)
+	}
+	return sum / float64(len(values)-1)
+}
@@ -0,0 +1,164 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// AuditCheck names the determinism check an AuditFinding failed.
+type AuditCheck string
+
+const (
+	// AuditValuation is a portfolio that valued differently when its
+	// positions were valued again, in another order, at the same snapshot:
+	// a position's Value reads the wall clock, mutates state or depends on
+	// the positions valued before it.
+	AuditValuation AuditCheck = "valuation"
+
+	// AuditReplay is a second run of the same strategy on the same
+	// snapshots that diverged from the first: the strategy or its
+	// positions read the wall clock, iterate a map, or draw randomness
+	// other than strategy.Rand.
+	AuditReplay AuditCheck = "replay"
+)
+
+// AuditFinding is nondeterministic behavior detected during a run under
+// Config.Audit or by Engine.Audit.
+type AuditFinding struct {
+	Check AuditCheck
+
+	// Snapshot is the index of the snapshot the behavior was detected at
+	Snapshot int
+	Time     primitives.Time
+
+	// Detail describes the values that disagreed
+	Detail string
+}
+
+// String returns a description of the finding.
+func (f AuditFinding) String() string {
+	return fmt.Sprintf("%s at snapshot %d (%s): %s", f.Check, f.Snapshot, f.Time, f.Detail)
+}
+
+// auditValuation values the portfolio again, visiting positions in an
+// order shuffled by the seed and snapshot index, and returns a finding if
+// the total differs from value.
+func (e *Engine) auditValuation(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, index int, value primitives.Amount) (*AuditFinding, error) {
+	valuation := e.valuationContext(ctx, snapshot)
+	total := portfolio.CashDecimal()
+	for _, position := range portfolio.PositionsSeeded(e.config.Seed + int64(index)) {
+		positionValue, err := e.positionValue(ctx, portfolio, position, valuation)
+		if err != nil {
+			return nil, fmt.Errorf("audit failed to revalue position %s at snapshot %d: %w", position.ID(), index, err)
+		}
+		total = total.Add(positionValue)
+	}
+	if total.IsNegative() {
+		total = primitives.Zero()
+	}
+	if total.Equal(value.Decimal()) {
+		return nil, nil
+	}
+
+	finding := &AuditFinding{
+		Check:    AuditValuation,
+		Snapshot: index,
+		Time:     snapshot.Time(),
+		Detail:   fmt.Sprintf("portfolio valued at %s, then at %s with positions in another order", value, total),
+	}
+	e.log.WarnContext(ctx, "nondeterminism detected",
+		logging.KeySnapshot, index,
+		"check", finding.Check,
+		"detail", finding.Detail)
+	return finding, nil
+}
+
+// Audit checks that a strategy backtests deterministically. It runs the
+// strategy newStrategy returns twice on snapshots, each a fresh instance,
+// with Config.Audit set and without Config.Ledger, and returns the first
+// run's findings followed by the points where the second run diverged
+// from it: the first differing portfolio value, the first differing fill,
+// and a differing final book. Routing time through strategy.Clock,
+// randomness through strategy.Rand and iteration through sorted keys
+// leaves nothing to report.
+//
+// A nondeterministic strategy can still agree with itself on two runs by
+// chance, so an empty report is evidence rather than proof.
+func (e *Engine) Audit(ctx context.Context, newStrategy func() (strategy.Strategy, error), snapshots []strategy.MarketSnapshot) ([]AuditFinding, error) {
+	config := e.config
+	config.Audit = true
+	config.Ledger = nil
+
+	var runs [2]*Result
+	for i := range runs {
+		strat, err := newStrategy()
+		if err != nil {
+			return nil, fmt.Errorf("audit failed to create strategy: %w", err)
+		}
+		if runs[i], err = NewEngine(config).Run(ctx, strat, snapshots); err != nil {
+			return nil, fmt.Errorf("audit run %d failed: %w", i+1, err)
+		}
+	}
+	first, second := runs[0], runs[1]
+	findings := append([]AuditFinding(nil), first.AuditFindings...)
+	replay := func(t primitives.Time, format string, args ...any) {
+		findings = append(findings, AuditFinding{
+			Check:    AuditReplay,
+			Snapshot: snapshotIndex(snapshots, t),
+			Time:     t,
+			Detail:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	a, b := first.History(), second.History()
+	for i := 0; i < a.Len() && i < b.Len(); i++ {
+		if !a.Value(i).Decimal().Equal(b.Value(i).Decimal()) {
+			replay(a.Time(i), "portfolio valued at %s in the first run, %s in the second", a.Value(i), b.Value(i))
+			break
+		}
+	}
+
+	for i := 0; i < len(first.Fills) || i < len(second.Fills); i++ {
+		if i >= len(first.Fills) || i >= len(second.Fills) {
+			last := snapshots[len(snapshots)-1].Time()
+			replay(last, "%d fills in the first run, %d in the second", len(first.Fills), len(second.Fills))
+			break
+		}
+		if x, y := describeFill(first.Fills[i]), describeFill(second.Fills[i]); x != y {
+			replay(first.Fills[i].Time, "fill %d was %s in the first run, %s in the second", i, x, y)
+			break
+		}
+	}
+
+	if x, y := describeBook(first.Portfolio), describeBook(second.Portfolio); x != y {
+		replay(snapshots[len(snapshots)-1].Time(), "final book was %s in the first run, %s in the second", x, y)
+	}
+	return findings, nil
+}
+
+// snapshotIndex returns the index of the last snapshot at or before t.
+func snapshotIndex(snapshots []strategy.MarketSnapshot, t primitives.Time) int {
+	i := sort.Search(len(snapshots), func(i int) bool { return snapshots[i].Time().After(t) })
+	return max(i-1, 0)
+}
+
+// describeFill returns a comparable description of a fill.
+func describeFill(fill strategy.Fill) string {
+	return fmt.Sprintf("%s %s %s %s @ %s", fill.OrderID, fill.Side, fill.Quantity, fill.Pair, fill.Price)
+}
+
+// describeBook returns a comparable description of a portfolio's cash and
+// position IDs.
+func describeBook(portfolio *strategy.Portfolio) string {
+	ids := make([]string, 0, portfolio.PositionCount())
+	for _, position := range portfolio.Positions() {
+		ids = append(ids, position.ID())
+	}
+	return fmt.Sprintf("cash %s with positions [%s]", portfolio.CashDecimal(), strings.Join(ids, ", "))
+}
@@ -0,0 +1,421 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// maxCascadeRounds bounds the liquidation rounds at one step of a path.
+// Each round removes at least one position, so the bound is only reached
+// by a portfolio with more positions than this.
+const maxCascadeRounds = 1000
+
+// CascadeConfig configures SimulateLiquidations.
+type CascadeConfig struct {
+	// MaintenanceMargin is the equity, as a fraction of notional, below
+	// which a levered position is liquidated (e.g., 0.05). It applies to
+	// positions reporting risk metrics with a Pair: notional is
+	// |Delta| × the pair's price, and positions at leverage 1 or less are
+	// never liquidated by it. Zero leaves liquidation to positions that
+	// implement strategy.LiquidatablePosition.
+	MaintenanceMargin primitives.Decimal
+
+	// Penalty is the fraction of a liquidated position's value the venue
+	// or protocol keeps (e.g., 0.05 for a 5% liquidation fee)
+	Penalty primitives.Decimal
+
+	// Slippage is the fraction of a liquidated position's value lost
+	// unwinding it into the market
+	Slippage primitives.Decimal
+
+	// Impact maps a pair to the fractional price move per unit of value
+	// liquidated in it (e.g., 0.000001 moves the price 1% per 10,000
+	// liquidated). Liquidating a long pushes the price down and a short
+	// pushes it up, for the rest of the path, which can breach further
+	// positions: the cascade. Pairs without an entry are not moved.
+	Impact map[string]primitives.Decimal
+}
+
+// Liquidation is a position closed by SimulateLiquidations.
+type Liquidation struct {
+	// Step is the index of the path snapshot the position was liquidated at
+	Step int
+
+	// Round is the cascade round within the step: 0 for positions breached
+	// by the path itself, higher for those breached by price impact
+	Round int
+
+	PositionID string
+	Time       primitives.Time
+
+	// Value is the position's value when liquidated
+	Value primitives.Decimal
+
+	// Penalty and Slippage are the shares of Value lost; Proceeds, the
+	// rest, is returned to cash
+	Penalty  primitives.Decimal
+	Slippage primitives.Decimal
+	Proceeds primitives.Decimal
+}
+
+// CascadeReport is the outcome of SimulateLiquidations.
+type CascadeReport struct {
+	InitialEquity primitives.Decimal
+	FinalEquity   primitives.Decimal
+
+	// MinEquity is the lowest equity after any step's liquidations
+	MinEquity primitives.Decimal
+
+	// Equity is the portfolio's equity after each step's liquidations
+	Equity []primitives.Decimal
+
+	Liquidations []Liquidation
+
+	// TotalPenalty and TotalSlippage sum the Liquidations' losses
+	TotalPenalty  primitives.Decimal
+	TotalSlippage primitives.Decimal
+
+	// Impact is the cumulative fractional price move liquidations caused
+	// in each pair by the end of the path
+	Impact map[string]primitives.Decimal
+
+	// Portfolio is the simulated portfolio at the end of the path
+	Portfolio *strategy.Portfolio
+}
+
+// Survived reports whether the portfolio ended the path with positive
+// equity.
+func (r *CascadeReport) Survived() bool {
+	return r.FinalEquity.IsPositive()
+}
+
+// SimulateLiquidations stress-tests a leveraged portfolio along a price
+// path, such as a historical crash or a hypothetical shock. At each
+// snapshot it liquidates every position that breaches its threshold:
+// positions implementing strategy.LiquidatablePosition (perpetuals, lending
+// farms) when they report a breach, and levered positions below
+// config.MaintenanceMargin. A liquidated position is removed and its value,
+// less the penalty and slippage, returned to cash. Each liquidation moves
+// its pair's price by config.Impact, and liquidation repeats in rounds at
+// the same snapshot until no position breaches.
+//
+// The simulation runs on a clone of portfolio, which is left unchanged;
+// positions are not rebalanced or topped up along the path. Returns an
+// error for an empty path or fractions outside [0, 1].
+func SimulateLiquidations(portfolio *strategy.Portfolio, path []strategy.MarketSnapshot, config CascadeConfig) (*CascadeReport, error) {
+	if portfolio == nil {
+		return nil, fmt.Errorf("portfolio cannot be nil")
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("price path cannot be empty")
+	}
+	for _, fraction := range []struct {
+		name  string
+		value primitives.Decimal
+	}{
+		{"maintenance margin", config.MaintenanceMargin},
+		{"penalty", config.Penalty},
+		{"slippage", config.Slippage},
+	} {
+		if fraction.value.IsNegative() || fraction.value.GreaterThan(primitives.One()) {
+			return nil, fmt.Errorf("%s must be in [0, 1], got %s", fraction.name, fraction.value)
+		}
+	}
+
+	sim := &cascade{
+		config:    config,
+		portfolio: portfolio.Clone(),
+		impact:    make(map[string]primitives.Decimal),
+	}
+	report := &CascadeReport{
+		TotalPenalty:  primitives.Zero(),
+		TotalSlippage: primitives.Zero(),
+		Impact:        sim.impact,
+		Portfolio:     sim.portfolio,
+	}
+
+	initial, err := sim.equity(path[0])
+	if err != nil {
+		return nil, err
+	}
+	report.InitialEquity, report.MinEquity = initial, initial
+
+	for step, snapshot := range path {
+		for round := 0; ; round++ {
+			if round == maxCascadeRounds {
+				return nil, fmt.Errorf("liquidation cascade at step %d did not settle in %d rounds", step, maxCascadeRounds)
+			}
+			liquidations, err := sim.liquidate(step, round, snapshot)
+			if err != nil {
+				return nil, err
+			}
+			if len(liquidations) == 0 {
+				break
+			}
+			for _, liquidation := range liquidations {
+				report.TotalPenalty = report.TotalPenalty.Add(liquidation.Penalty)
+				report.TotalSlippage = report.TotalSlippage.Add(liquidation.Slippage)
+			}
+			report.Liquidations = append(report.Liquidations, liquidations...)
+		}
+
+		equity, err := sim.equity(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		report.Equity = append(report.Equity, equity)
+		if equity.LessThan(report.MinEquity) {
+			report.MinEquity = equity
+		}
+	}
+	report.FinalEquity = report.Equity[len(report.Equity)-1]
+	return report, nil
+}
+
+// cascade is the state of a liquidation simulation.
+type cascade struct {
+	config    CascadeConfig
+	portfolio *strategy.Portfolio
+
+	// impact is the cumulative fractional price move per pair
+	impact map[string]primitives.Decimal
+}
+
+// equity values the portfolio at snapshot with liquidation impact applied.
+func (c *cascade) equity(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	value, err := c.portfolio.Value(c.shocked(snapshot))
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("failed to value portfolio at %s: %w", snapshot.Time(), err)
+	}
+	return value.Decimal(), nil
+}
+
+// liquidate runs one cascade round: it finds every position breached at
+// snapshot, then removes them and applies their price impact. Positions
+// are checked against the prices at the start of the round, so the order
+// they are visited in does not matter.
+func (c *cascade) liquidate(step, round int, snapshot strategy.MarketSnapshot) ([]Liquidation, error) {
+	shocked := c.shocked(snapshot)
+
+	type breach struct {
+		position strategy.Position
+		value    primitives.Decimal
+	}
+	var breached []breach
+	for _, position := range c.portfolio.Positions() {
+		ok, err := c.breached(position, shocked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check position %s at step %d: %w", position.ID(), step, err)
+		}
+		if !ok {
+			continue
+		}
+		value, err := c.portfolio.PositionValue(position, shocked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to value position %s at step %d: %w", position.ID(), step, err)
+		}
+		breached = append(breached, breach{position: position, value: value.Decimal()})
+	}
+
+	liquidations := make([]Liquidation, 0, len(breached))
+	for _, b := range breached {
+		penalty := b.value.Mul(c.config.Penalty)
+		slippage := b.value.Mul(c.config.Slippage)
+		proceeds := b.value.Sub(penalty).Sub(slippage)
+		if proceeds.IsNegative() {
+			slippage = slippage.Add(proceeds)
+			proceeds = primitives.Zero()
+		}
+
+		if err := c.portfolio.RemovePosition(b.position.ID()); err != nil {
+			return nil, err
+		}
+		if err := c.portfolio.AdjustCash(proceeds); err != nil {
+			return nil, err
+		}
+		if err := c.applyImpact(b.position, shocked); err != nil {
+			return nil, fmt.Errorf("failed to apply impact of position %s at step %d: %w", b.position.ID(), step, err)
+		}
+
+		liquidations = append(liquidations, Liquidation{
+			Step:       step,
+			Round:      round,
+			PositionID: b.position.ID(),
+			Time:       snapshot.Time(),
+			Value:      b.value,
+			Penalty:    penalty,
+			Slippage:   slippage,
+			Proceeds:   proceeds,
+		})
+	}
+	return liquidations, nil
+}
+
+// breached reports whether position breaches its liquidation threshold.
+func (c *cascade) breached(position strategy.Position, snapshot strategy.MarketSnapshot) (bool, error) {
+	if liquidatable, ok := position.(strategy.LiquidatablePosition); ok {
+		if breached, err := liquidatable.Liquidatable(snapshot); err != nil || breached {
+			return breached, err
+		}
+	}
+	if !c.config.MaintenanceMargin.IsPositive() {
+		return false, nil
+	}
+
+	notional, ok, err := c.notional(position, snapshot)
+	if err != nil || !ok {
+		return false, err
+	}
+	value, err := c.portfolio.PositionValue(position, snapshot)
+	if err != nil {
+		return false, err
+	}
+	if !notional.GreaterThan(value.Decimal()) {
+		return false, nil // unlevered
+	}
+	return value.Decimal().LessThan(notional.Mul(c.config.MaintenanceMargin)), nil
+}
+
+// notional returns |Delta| × price for a position reporting risk metrics
+// with a Pair; ok is false for other positions.
+func (c *cascade) notional(position strategy.Position, snapshot strategy.MarketSnapshot) (notional primitives.Decimal, ok bool, err error) {
+	risky, isRisky := position.(strategy.PositionWithRisk)
+	paired, isPaired := position.(interface{ Pair() string })
+	if !isRisky || !isPaired {
+		return primitives.Zero(), false, nil
+	}
+	metrics, err := risky.Risk(snapshot)
+	if err != nil {
+		return primitives.Zero(), false, err
+	}
+	price, err := snapshot.Price(paired.Pair())
+	if err != nil {
+		return primitives.Zero(), false, err
+	}
+	return metrics.Delta.Abs().Mul(price.Decimal()), true, nil
+}
+
+// applyImpact moves the price of a liquidated position's pair by
+// Impact × its notional, down for longs and up for shorts.
+func (c *cascade) applyImpact(position strategy.Position, snapshot strategy.MarketSnapshot) error {
+	paired, ok := position.(interface{ Pair() string })
+	if !ok {
+		return nil
+	}
+	rate, ok := c.config.Impact[paired.Pair()]
+	if !ok || rate.IsZero() {
+		return nil
+	}
+	risky, ok := position.(strategy.PositionWithRisk)
+	if !ok {
+		return nil
+	}
+	metrics, err := risky.Risk(snapshot)
+	if err != nil {
+		return err
+	}
+	price, err := snapshot.Price(paired.Pair())
+	if err != nil {
+		return err
+	}
+
+	// Unwinding a long sells into the market; a short buys back
+	move := metrics.Delta.Mul(price.Decimal()).Mul(rate).Neg()
+	total, ok := c.impact[paired.Pair()]
+	if !ok {
+		total = primitives.Zero()
+	}
+	total = total.Add(move)
+	if !total.GreaterThan(primitives.One().Neg()) {
+		return errors.New("liquidation impact would drive the price to zero")
+	}
+	c.impact[paired.Pair()] = total
+	return nil
+}
+
+// shocked returns snapshot with the cumulative liquidation impact so far
+// applied to its prices. Later impact does not move the returned prices.
+func (c *cascade) shocked(snapshot strategy.MarketSnapshot) strategy.MarketSnapshot {
+	if len(c.impact) == 0 {
+		return snapshot
+	}
+	impact := make(map[string]primitives.Decimal, len(c.impact))
+	for pair, move := range c.impact {
+		impact[pair] = move
+	}
+	return &impactSnapshot{MarketSnapshot: snapshot, impact: impact}
+}
+
+// impactSnapshot is a MarketSnapshot whose prices are moved by a
+// fractional impact per pair.
+type impactSnapshot struct {
+	strategy.MarketSnapshot
+	impact map[string]primitives.Decimal
+}
+
+// Price returns the underlying price × (1 + impact).
+func (s *impactSnapshot) Price(pair string) (primitives.Price, error) {
+	price, err := s.MarketSnapshot.Price(pair)
+	if err != nil {
+		return price, err
+	}
+	return s.move(pair, price), nil
+}
+
+// Prices returns every price with impact applied.
+func (s *impactSnapshot) Prices() map[string]primitives.Price {
+	prices := make(map[string]primitives.Price)
+	for pair, price := range s.MarketSnapshot.Prices() {
+		prices[pair] = s.move(pair, price)
+	}
+	return prices
+}
+
+// move applies pair's impact to price.
+func (s *impactSnapshot) move(pair string, price primitives.Price) primitives.Price {
+	impact, ok := s.impact[pair]
+	if !ok {
+		return price
+	}
+	return primitives.MustPrice(price.Decimal().Mul(primitives.One().Add(impact)))
+}
+
+// ShockPath returns a scenario path for SimulateLiquidations: copies of
+// base, one per return, with pair's price compounded by each fractional
+// return (e.g., -0.1 for a 10% fall) and the time advanced by interval.
+// Other prices and metadata are carried over from base. Returns an error
+// if base has no price for pair or a return is -1 or below.
+func ShockPath(base *strategy.SimpleSnapshot, pair string, returns []primitives.Decimal, interval primitives.Duration) ([]strategy.MarketSnapshot, error) {
+	price, err := base.Price(pair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to shock %s: %w", pair, err)
+	}
+	path := make([]strategy.MarketSnapshot, 0, len(returns))
+	level := price.Decimal()
+	t := base.Time()
+	for i, r := range returns {
+		if !r.GreaterThan(primitives.One().Neg()) {
+			return nil, fmt.Errorf("return %d is %s, at or below -1", i, r)
+		}
+		level = level.Mul(primitives.One().Add(r))
+		t = t.Add(interval)
+
+		prices := make(map[string]primitives.Price, len(base.Prices()))
+		for key, price := range base.Prices() {
+			prices[key] = price
+		}
+		if prices[pair], err = primitives.NewPrice(level); err != nil {
+			return nil, err
+		}
+		snapshot := strategy.NewSimpleSnapshot(t, prices)
+		for _, key := range base.MetadataKeys() {
+			value, _ := base.Get(key)
+			snapshot.Set(key, value)
+		}
+		path = append(path, snapshot)
+	}
+	return path, nil
+}
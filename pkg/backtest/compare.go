@@ -0,0 +1,287 @@
+package backtest
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ComparisonRow holds one result's headline metrics in a Comparison.
+type ComparisonRow struct {
+	Name             string
+	FinalValue       primitives.Amount
+	TotalReturn      primitives.Decimal
+	AnnualizedReturn primitives.Decimal
+	Sharpe           primitives.Decimal
+	MaxDrawdown      primitives.Decimal
+	Points           int
+}
+
+// Comparison is a side-by-side view of several backtests, built by Compare.
+//
+// Relative statistics use only the times every result has a value at, so
+// results sampled at different frequencies are compared on their common
+// grid:
+//   - Correlation is the Pearson correlation of point-to-point returns
+//     (zero when a series has fewer than two returns or no variance)
+//   - DrawdownOverlap is the share of points at which either result is
+//     below its running peak where both are (zero when neither ever is)
+type Comparison struct {
+	// Names lists the results in ascending order; Rows and the matrices
+	// are indexed in this order
+	Names []string
+	Rows  []ComparisonRow
+
+	Correlation     [][]primitives.Decimal
+	DrawdownOverlap [][]primitives.Decimal
+
+	// Common is the number of times shared by every result
+	Common int
+}
+
+// Compare builds a Comparison of named results. Returns error if there
+// are no results, a result is nil, or the results share fewer than two
+// times.
+func Compare(results map[string]*Result) (*Comparison, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results to compare")
+	}
+	names := make([]string, 0, len(results))
+	for name, result := range results {
+		if result == nil {
+			return nil, fmt.Errorf("result %q is nil", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := &Comparison{Names: names}
+	histories := make([]*ValueSeries, len(names))
+	for i, name := range names {
+		result := results[name]
+		histories[i] = result.History()
+		c.Rows = append(c.Rows, ComparisonRow{
+			Name:             name,
+			FinalValue:       result.FinalValue,
+			TotalReturn:      result.TotalReturn,
+			AnnualizedReturn: result.AnnualizedReturn,
+			Sharpe:           result.Sharpe,
+			MaxDrawdown:      result.MaxDrawdown,
+			Points:           histories[i].Len(),
+		})
+	}
+
+	aligned := alignValues(histories)
+	if len(aligned) == 0 || len(aligned[0]) < 2 {
+		return nil, fmt.Errorf("results share fewer than 2 times")
+	}
+	c.Common = len(aligned[0])
+
+	returns := make([][]float64, len(names))
+	drawdowns := make([][]bool, len(names))
+	for i, values := range aligned {
+		returns[i] = simpleReturns(values)
+		drawdowns[i] = underwater(values)
+	}
+	c.Correlation = make([][]primitives.Decimal, len(names))
+	c.DrawdownOverlap = make([][]primitives.Decimal, len(names))
+	for i := range names {
+		c.Correlation[i] = make([]primitives.Decimal, len(names))
+		c.DrawdownOverlap[i] = make([]primitives.Decimal, len(names))
+		for j := range names {
+			c.Correlation[i][j] = primitives.NewDecimalFromFloat(correlation(returns[i], returns[j]))
+			c.DrawdownOverlap[i][j] = primitives.NewDecimalFromFloat(overlap(drawdowns[i], drawdowns[j]))
+		}
+	}
+	return c, nil
+}
+
+// Markdown renders the comparison as Markdown tables.
+func (c *Comparison) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Metrics\n\n")
+	b.WriteString("| Strategy | Final Value | Total Return | Annualized | Sharpe | Max Drawdown | Points |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, row := range c.Rows {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %.2f | %s | %d |\n",
+			row.Name, row.FinalValue, percent(row.TotalReturn), percent(row.AnnualizedReturn),
+			row.Sharpe.Float64(), percent(row.MaxDrawdown), row.Points)
+	}
+	fmt.Fprintf(&b, "\n## Return Correlation\n\n%s", c.markdownMatrix(c.Correlation, ratio))
+	fmt.Fprintf(&b, "\n## Drawdown Overlap\n\n%s", c.markdownMatrix(c.DrawdownOverlap, percent))
+	fmt.Fprintf(&b, "\nRelative statistics use the %d times shared by every strategy.\n", c.Common)
+	return b.String()
+}
+
+func (c *Comparison) markdownMatrix(matrix [][]primitives.Decimal, format func(primitives.Decimal) string) string {
+	var b strings.Builder
+	b.WriteString("| |")
+	for _, name := range c.Names {
+		fmt.Fprintf(&b, " %s |", name)
+	}
+	b.WriteString("\n|---|" + strings.Repeat("---:|", len(c.Names)) + "\n")
+	for i, name := range c.Names {
+		fmt.Fprintf(&b, "| %s |", name)
+		for j := range c.Names {
+			fmt.Fprintf(&b, " %s |", format(matrix[i][j]))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// HTML renders the comparison as a standalone HTML page.
+func (c *Comparison) HTML() (string, error) {
+	var b strings.Builder
+	if err := comparisonTemplate.Execute(&b, c); err != nil {
+		return "", fmt.Errorf("failed to render comparison: %w", err)
+	}
+	return b.String(), nil
+}
+
+var reportFuncs = template.FuncMap{
+	"percent": percent,
+	"ratio":   ratio,
+}
+
+var comparisonTemplate = template.Must(template.New("comparison").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Backtest Comparison</title>` + reportStyle + `</head>
+<body>
+<h2>Metrics</h2>
+<table>
+<tr><th>Strategy</th><th>Final Value</th><th>Total Return</th><th>Annualized</th><th>Sharpe</th><th>Max Drawdown</th><th>Points</th></tr>
+{{range .Rows}}<tr><td>{{.Name}}</td><td>{{.FinalValue}}</td><td>{{percent .TotalReturn}}</td><td>{{percent .AnnualizedReturn}}</td><td>{{ratio .Sharpe}}</td><td>{{percent .MaxDrawdown}}</td><td>{{.Points}}</td></tr>
+{{end}}</table>
+<h2>Return Correlation</h2>
+<table>
+<tr><th></th>{{range .Names}}<th>{{.}}</th>{{end}}</tr>
+{{range $i, $row := .Correlation}}<tr><th>{{index $.Names $i}}</th>{{range $row}}<td>{{ratio .}}</td>{{end}}</tr>
+{{end}}</table>
+<h2>Drawdown Overlap</h2>
+<table>
+<tr><th></th>{{range .Names}}<th>{{.}}</th>{{end}}</tr>
+{{range $i, $row := .DrawdownOverlap}}<tr><th>{{index $.Names $i}}</th>{{range $row}}<td>{{percent .}}</td>{{end}}</tr>
+{{end}}</table>
+<p>Relative statistics use the {{.Common}} times shared by every strategy.</p>
+</body>
+</html>
+`))
+
+const reportStyle = `<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+</style>`
+
+// percent formats a fraction as a percentage with two decimals.
+func percent(d primitives.Decimal) string {
+	return fmt.Sprintf("%.2f%%", d.Mul(primitives.NewDecimal(100)).Float64())
+}
+
+// ratio formats a dimensionless statistic with two decimals.
+func ratio(d primitives.Decimal) string {
+	return fmt.Sprintf("%.2f", d.Float64())
+}
+
+// alignValues returns each series' values at the times present in every
+// series, in time order.
+func alignValues(histories []*ValueSeries) [][]float64 {
+	counts := make(map[int64]int)
+	for _, history := range histories {
+		seen := make(map[int64]bool, history.Len())
+		for _, t := range history.times {
+			if !seen[t] {
+				seen[t] = true
+				counts[t]++
+			}
+		}
+	}
+
+	aligned := make([][]float64, len(histories))
+	for i, history := range histories {
+		var last int64
+		for n, t := range history.times {
+			if counts[t] != len(histories) || (n > 0 && t == last) {
+				continue
+			}
+			last = t
+			aligned[i] = append(aligned[i], history.Value(n).Decimal().Float64())
+		}
+	}
+	return aligned
+}
+
+// simpleReturns returns the point-to-point returns of values, counting a
+// step from zero as no return so series stay aligned.
+func simpleReturns(values []float64) []float64 {
+	returns := make([]float64, 0, len(values))
+	for i := 1; i < len(values); i++ {
+		var ret float64
+		if values[i-1] != 0 {
+			ret = values[i]/values[i-1] - 1
+		}
+		returns = append(returns, ret)
+	}
+	return returns
+}
+
+// underwater reports for each value whether it is below its running peak.
+func underwater(values []float64) []bool {
+	flags := make([]bool, len(values))
+	peak := math.Inf(-1)
+	for i, v := range values {
+		peak = math.Max(peak, v)
+		flags[i] = v < peak
+	}
+	return flags
+}
+
+// correlation returns the Pearson correlation of two equal-length series,
+// or zero if either has no variance.
+func correlation(a, b []float64) float64 {
+	n := min(len(a), len(b))
+	if n < 2 {
+		return 0
+	}
+	var meanA, meanB float64
+	for i := 0; i < n; i++ {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(n)
+	meanB /= float64(n)
+
+	var cov, varA, varB float64
+	for i := 0; i < n; i++ {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+// overlap returns |a ∧ b| / |a ∨ b|, or zero when neither flag is ever set.
+func overlap(a, b []bool) float64 {
+	var both, either int
+	for i := range a {
+		if a[i] && b[i] {
+			both++
+		}
+		if a[i] || b[i] {
+			either++
+		}
+	}
+	if either == 0 {
+		return 0
+	}
+	return float64(both) / float64(either)
+}
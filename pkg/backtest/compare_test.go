@@ -0,0 +1,179 @@
+package backtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// resultFrom builds a result with a value history at the given times.
+func resultFrom(times []time.Time, values ...int64) *backtest.Result {
+	result := &backtest.Result{
+		InitialValue: primitives.MustAmount(primitives.NewDecimal(values[0])),
+		FinalValue:   primitives.MustAmount(primitives.NewDecimal(values[len(values)-1])),
+	}
+	for i, v := range values {
+		result.ValueHistory = append(result.ValueHistory, backtest.ValuePoint{
+			Time:  primitives.NewTime(times[i]),
+			Value: primitives.MustAmount(primitives.NewDecimal(v)),
+		})
+	}
+	return result
+}
+
+func days(start time.Time, n int) []time.Time {
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = start.AddDate(0, 0, i)
+	}
+	return times
+}
+
+func TestCompare(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	comparison, err := backtest.Compare(map[string]*backtest.Result{
+		"trend":   resultFrom(days(start, 4), 100, 110, 99, 120),
+		"carry":   resultFrom(days(start, 5), 100, 120, 108, 130, 140), // extra day ignored
+		"inverse": resultFrom(days(start, 4), 100, 90, 99, 80),
+	})
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	if want := []string{"carry", "inverse", "trend"}; strings.Join(comparison.Names, ",") != strings.Join(want, ",") {
+		t.Fatalf("names = %v, want %v", comparison.Names, want)
+	}
+	if comparison.Common != 4 {
+		t.Errorf("common = %d, want 4", comparison.Common)
+	}
+	if comparison.Rows[0].Points != 5 {
+		t.Errorf("carry points = %d, want 5", comparison.Rows[0].Points)
+	}
+
+	carry, inverse, trend := 0, 1, 2
+	if got := comparison.Correlation[trend][trend].Float64(); got < 0.999 || got > 1.001 {
+		t.Errorf("self correlation = %f, want 1", got)
+	}
+	if got := comparison.Correlation[trend][carry].Float64(); got <= 0.9 {
+		t.Errorf("trend/carry correlation = %f, want strongly positive", got)
+	}
+	if got := comparison.Correlation[trend][inverse].Float64(); got >= 0 {
+		t.Errorf("trend/inverse correlation = %f, want negative", got)
+	}
+
+	// trend and carry are both underwater only on day 2; inverse is
+	// underwater on days 1-3
+	if got := comparison.DrawdownOverlap[trend][carry].Float64(); got != 1 {
+		t.Errorf("trend/carry overlap = %f, want 1", got)
+	}
+	if got := comparison.DrawdownOverlap[trend][inverse].Float64(); got < 0.333 || got > 0.334 {
+		t.Errorf("trend/inverse overlap = %f, want 1/3", got)
+	}
+
+	markdown := comparison.Markdown()
+	for _, want := range []string{"| trend |", "## Return Correlation", "## Drawdown Overlap", "100.00%"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+
+	comparison.Names[0] = "<carry>"
+	html, err := comparison.HTML()
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	if !strings.Contains(html, "&lt;carry&gt;") || strings.Contains(html, "<carry>") {
+		t.Errorf("strategy names not escaped:\n%s", html)
+	}
+
+	if _, err := backtest.Compare(map[string]*backtest.Result{
+		"a": resultFrom(days(start, 2), 100, 110),
+		"b": resultFrom(days(start.AddDate(0, 1, 0), 2), 100, 110),
+	}); err == nil {
+		t.Error("expected an error for results without common times")
+	}
+}
+
+func TestTearSheet(t *testing.T) {
+	times := []time.Time{
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+	sheet, err := backtest.NewTearSheet("Trend", resultFrom(times, 100, 110, 99, 120), primitives.Duration{})
+	if err != nil {
+		t.Fatalf("NewTearSheet: %v", err)
+	}
+
+	want := []string{"0.1", "-0.1", "0.2121"}
+	if len(sheet.Monthly) != len(want) {
+		t.Fatalf("%d monthly returns, want %d", len(sheet.Monthly), len(want))
+	}
+	for i, m := range sheet.Monthly {
+		if !m.Start.Equal(primitives.NewTime(time.Date(2024, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC))) || !strings.HasPrefix(m.Return.String(), want[i]) {
+			t.Errorf("month %d = %+v, want %s", i, m, want[i])
+		}
+	}
+	if len(sheet.RollingSharpe) != 3 || !sheet.RollingSharpe[0].Time.Equal(primitives.NewTime(times[1])) {
+		t.Errorf("unexpected rolling Sharpe %v", sheet.RollingSharpe)
+	}
+	if sheet.Exposure != nil {
+		t.Errorf("exposure without portfolio history: %v", sheet.Exposure)
+	}
+
+	markdown := sheet.Markdown()
+	for _, want := range []string{"# Trend", "| 2024 | 10.00% | -10.00% | 21.21% |", "20.00% |", "Config.RecordExposure"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+	html, err := sheet.HTML()
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	if !strings.Contains(html, "<h2>Monthly Returns</h2>") || !strings.Contains(html, "<td>21.21%</td>") {
+		t.Errorf("unexpected HTML:\n%s", html)
+	}
+}
+
+func TestTearSheetExposure(t *testing.T) {
+	strat := &mockStrategy{
+		rebalanceFunc: func(ctx context.Context, p *strategy.Portfolio, m strategy.MarketSnapshot) ([]strategy.Action, error) {
+			if p.PositionCount() > 0 {
+				return nil, nil
+			}
+			pos := &mockPosition{id: "eth", posType: strategy.PositionTypeSpot, value: primitives.MustAmount(primitives.NewDecimal(2500))}
+			return []strategy.Action{
+				strategy.NewAddPositionAction(pos),
+				strategy.NewAdjustCashAction(primitives.NewDecimal(-2500), "buy"),
+			}, nil
+		},
+	}
+	config := backtest.DefaultConfig()
+	config.RecordHistory = true
+	result, err := backtest.NewEngine(config).Run(context.Background(), strat, createMockSnapshots(3, time.Now(), time.Hour))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	sheet, err := backtest.NewTearSheet("Spot", result, primitives.NewDuration(time.Hour))
+	if err != nil {
+		t.Fatalf("NewTearSheet: %v", err)
+	}
+	// The first value precedes the buy; later values hold 2,500 of 10,000
+	want := []string{"0", "0.25", "0.25"}
+	if len(sheet.Exposure) != len(want) {
+		t.Fatalf("%d exposure points, want %d", len(sheet.Exposure), len(want))
+	}
+	for i, point := range sheet.Exposure {
+		if point.Invested.String() != want[i] {
+			t.Errorf("exposure %d = %s, want %s", i, point.Invested, want[i])
+		}
+	}
+}
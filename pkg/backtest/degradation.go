@@ -0,0 +1,192 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/accounting"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ValuationErrorPolicy controls how the engine handles a position that
+// cannot be valued, so exploratory backtests on imperfect data can still
+// complete. Every position valued under a fallback is recorded in
+// Result.Degradations and logged at warn.
+type ValuationErrorPolicy int
+
+const (
+	// AbortOnValuationError stops the run with the position's error
+	AbortOnValuationError ValuationErrorPolicy = iota
+
+	// CarryLastValueOnValuationError values the position at its last
+	// successful valuation, marking it stale, or excludes it if it has
+	// never been valued
+	CarryLastValueOnValuationError
+
+	// ExcludeOnValuationError values the position at zero until it can be
+	// valued again
+	ExcludeOnValuationError
+)
+
+// String returns the policy name.
+func (p ValuationErrorPolicy) String() string {
+	switch p {
+	case AbortOnValuationError:
+		return "abort"
+	case CarryLastValueOnValuationError:
+		return "carry-last-value"
+	case ExcludeOnValuationError:
+		return "exclude"
+	default:
+		return fmt.Sprintf("ValuationErrorPolicy(%d)", int(p))
+	}
+}
+
+// Degradation records a position that could not be valued at a snapshot
+// and the fallback used under Config.OnValuationError.
+type Degradation struct {
+	// Snapshot is the index of the snapshot being valued
+	Snapshot int
+	Time     primitives.Time
+
+	PositionID string
+
+	// Stale is set when the position was valued at its last successful
+	// valuation, Value, made at snapshot LastValued. Otherwise it was
+	// excluded and Value is zero.
+	Stale      bool
+	Value      primitives.Decimal
+	LastValued int
+
+	// Err is the valuation error
+	Err error
+}
+
+// String describes the degradation.
+func (d Degradation) String() string {
+	if d.Stale {
+		return fmt.Sprintf("position %s at snapshot %d (%s): carried %s from snapshot %d: %v",
+			d.PositionID, d.Snapshot, d.Time, d.Value, d.LastValued, d.Err)
+	}
+	return fmt.Sprintf("position %s at snapshot %d (%s): excluded: %v", d.PositionID, d.Snapshot, d.Time, d.Err)
+}
+
+// lastValue is a position's last successful valuation.
+type lastValue struct {
+	value    primitives.Decimal
+	snapshot int
+}
+
+// valuationGuard applies Config.OnValuationError over a run.
+type valuationGuard struct {
+	policy ValuationErrorPolicy
+
+	// index and now identify the snapshot being valued
+	index int
+	now   primitives.Time
+
+	// last holds each position's last successful valuation, and rewards
+	// its last reward value for the ledger, under
+	// CarryLastValueOnValuationError
+	last    map[string]lastValue
+	rewards map[string]primitives.Decimal
+
+	// degraded marks the positions already recorded at this snapshot, which
+	// may be valued several times
+	degraded     map[string]bool
+	degradations []Degradation
+}
+
+func newValuationGuard(policy ValuationErrorPolicy) *valuationGuard {
+	guard := &valuationGuard{policy: policy}
+	if policy == CarryLastValueOnValuationError {
+		guard.last = make(map[string]lastValue)
+		guard.rewards = make(map[string]primitives.Decimal)
+	}
+	return guard
+}
+
+// at moves the guard to the snapshot at index.
+func (g *valuationGuard) at(index int, now primitives.Time) {
+	g.index, g.now = index, now
+	if len(g.degraded) > 0 {
+		g.degraded = nil
+	}
+}
+
+// positionValue values position in the base currency, falling back under
+// the policy if it fails.
+func (e *Engine) positionValue(ctx context.Context, portfolio *strategy.Portfolio, position strategy.Position, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	g := e.valuations
+	value, err := portfolio.PositionValue(position, snapshot)
+	if err == nil {
+		if g.last != nil {
+			g.last[position.ID()] = lastValue{value: value.Decimal(), snapshot: g.index}
+		}
+		return value.Decimal(), nil
+	}
+	if g.policy == AbortOnValuationError {
+		return primitives.Decimal{}, err
+	}
+
+	degradation := Degradation{
+		Snapshot:   g.index,
+		Time:       g.now,
+		PositionID: position.ID(),
+		Value:      primitives.Zero(),
+		Err:        err,
+	}
+	if last, ok := g.last[position.ID()]; ok {
+		degradation.Stale, degradation.Value, degradation.LastValued = true, last.value, last.snapshot
+	}
+	if !g.degraded[position.ID()] {
+		if g.degraded == nil {
+			g.degraded = make(map[string]bool)
+		}
+		g.degraded[position.ID()] = true
+		g.degradations = append(g.degradations, degradation)
+		e.log.WarnContext(ctx, "position valuation degraded",
+			logging.KeySnapshot, g.index,
+			logging.KeyPosition, position.ID(),
+			"policy", g.policy.String(),
+			"stale", degradation.Stale,
+			"value", degradation.Value.String(),
+			logging.KeyError, err)
+	}
+	return degradation.Value, nil
+}
+
+// ledgerValuer values positions for Config.Ledger as the engine values
+// them, under the run's valuation context and Config.OnValuationError.
+type ledgerValuer struct {
+	engine *Engine
+	ctx    context.Context
+}
+
+// PositionValue implements accounting.Valuer.
+func (v ledgerValuer) PositionValue(portfolio *strategy.Portfolio, position strategy.Position, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	return v.engine.positionValue(v.ctx, portfolio, position, v.engine.valuationContext(v.ctx, snapshot))
+}
+
+// RewardValue implements accounting.Valuer. A reward that cannot be
+// valued falls back with its position: to its last reward value when
+// carrying last values, otherwise to zero.
+func (v ledgerValuer) RewardValue(portfolio *strategy.Portfolio, bearer accounting.RewardBearer, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	g := v.engine.valuations
+	reward, err := accounting.PortfolioValuer{}.RewardValue(portfolio, bearer, v.engine.valuationContext(v.ctx, snapshot))
+	if err == nil {
+		if g.rewards != nil {
+			g.rewards[bearer.ID()] = reward
+		}
+		return reward, nil
+	}
+	if g.policy == AbortOnValuationError {
+		return primitives.Decimal{}, err
+	}
+	if last, ok := g.rewards[bearer.ID()]; ok {
+		return last, nil
+	}
+	return primitives.Zero(), nil
+}
@@ -9,9 +9,14 @@ package backtest
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/accounting"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/telemetry"
 )
 
 // Engine orchestrates backtesting of trading strategies against historical market data.
@@ -29,6 +34,18 @@ import (
 type Engine struct {
 	// config holds engine configuration
 	config Config
+
+	// orders holds the open orders of the run in progress
+	orders *orderBook
+
+	// log is the engine logger of the run in progress
+	log *slog.Logger
+
+	// states holds the mechanism states of the run in progress
+	states *strategy.MechanismStates
+
+	// valuations applies Config.OnValuationError in the run in progress
+	valuations *valuationGuard
 }
 
 // Config contains backtest engine configuration options.
@@ -36,9 +53,275 @@ type Config struct {
 	// InitialCash is the starting portfolio cash balance
 	InitialCash primitives.Amount
 
-	// EnableDetailedLogging enables verbose logging of each rebalancing step
-	// (useful for debugging but may impact performance)
+	// EnableDetailedLogging logs every step at debug level to standard
+	// error when Logger is nil (useful for debugging but may impact
+	// performance)
+	//
+	// Deprecated: set Logger, which controls the handler and levels.
 	EnableDetailedLogging bool
+
+	// Logger receives structured records from the engine, portfolio and
+	// order book, tagged with logging.KeyComponent: run summaries at info,
+	// skipped actions at warn, and each snapshot, applied action, portfolio
+	// change and order update at debug. Wrap the handler in a
+	// logging.LevelHandler to set levels per component. Nil disables
+	// logging.
+	Logger *slog.Logger
+
+	// Instrumentation receives spans around the run, each Rebalance call,
+	// each batch of actions applied and each portfolio valuation, and
+	// counters of snapshots, applied and failed actions and fills (see the
+	// telemetry package for names and an OpenTelemetry adapter). The
+	// Rebalance span is in the context passed to the strategy, so its own
+	// spans nest under it. The zero value disables instrumentation.
+	Instrumentation telemetry.Instrumentation
+
+	// RecordHistory enables portfolio history so Result.Portfolio.History()
+	// and Result.Portfolio.At() can reconstruct holdings after the run
+	// (retains every position ever held)
+	RecordHistory bool
+
+	// Ledger, when set, receives double-entry postings for the opening
+	// balance, every applied action and each mark-to-market revaluation.
+	// During a run the engine sets its accounting.Valuer, so positions are
+	// valued as for the value history, including under OnValuationError.
+	Ledger *accounting.Ledger
+
+	// OnActionError selects how a failed action is handled. The default
+	// aborts the run; either way the portfolio and ledger are never left
+	// with a partially applied batch.
+	OnActionError ActionErrorPolicy
+
+	// OnValuationError selects how a position that cannot be valued is
+	// handled: the default aborts the run, the others value it at its last
+	// value or exclude it and record the fallback in Result.Degradations.
+	// Under them, positions lacking their declared requirements are valued
+	// as usual rather than aborting the run.
+	OnValuationError ValuationErrorPolicy
+
+	// RecordExposure records the valuation at each snapshot broken down by
+	// position type and venue in Result.ExposureHistory
+	RecordExposure bool
+
+	// Valuation selects when each snapshot's value is recorded in
+	// Result.History: before the snapshot's actions (the default), after
+	// them, or both, with the post-action values in
+	// Result.PostActionHistory
+	Valuation ValuationTiming
+
+	// CrossRates, when set, resolves prices the snapshots do not quote
+	// through its price graph when positions are valued. Positions value
+	// in a strategy.ValuationContext bound to the run's context either way.
+	CrossRates *strategy.CrossRateResolver
+
+	// Requirements are market data every snapshot must provide. They are
+	// checked, with those of a strategy.DataDependent strategy, against
+	// every snapshot before the run starts; the requirements of
+	// strategy.DataDependent positions are checked at each snapshot before
+	// the positions are valued. Either way the run fails with a
+	// MissingDataError listing each missing input and what needs it.
+	Requirements []strategy.DataKey
+
+	// ColumnarHistory skips filling the deprecated Result.ValueHistory, so
+	// the value history is kept only in the compact columnar ValueSeries
+	// behind Result.History. Metrics are identical; use it for very long
+	// runs to cut memory.
+	ColumnarHistory bool
+
+	// Latency delays actions routed to a venue with strategy.NewVenueAction.
+	// A delayed action executes at the first later snapshot that satisfies
+	// its venue's latency, before the strategy rebalances, so a signal seen
+	// at one venue cannot be traded at another in the same instant. Venues
+	// without an entry, and unrouted actions, execute immediately.
+	Latency map[string]VenueLatency
+
+	// Preflight dry-runs each batch of actions with Portfolio.Validate
+	// before applying it, checking Constraints as well as the built-in
+	// checks (an action may not drive cash negative). Problems are handled
+	// under OnActionError: aborting reports every diagnostic, skipping the
+	// snapshot skips the batch, and skipping actions drops the failing
+	// actions, or the whole batch when a constraint fails.
+	Preflight bool
+
+	// Constraints are portfolio rules checked by Preflight after each batch
+	Constraints []strategy.Constraint
+
+	// GreekLimits bounds the book's Greeks, aggregated with
+	// strategy.Portfolio.Greeks, and OnGreekBreach selects how the engine
+	// enforces them. Breaches left after each snapshot's actions are logged
+	// at warn and recorded in Result.GreekBreaches. The zero value sets no
+	// limits.
+	GreekLimits   strategy.GreekLimits
+	OnGreekBreach GreekBreachPolicy
+
+	// Hedge returns the actions that bring the book back within
+	// GreekLimits under HedgeOnGreekBreach
+	Hedge HedgeFunc
+
+	// Orders configures how orders submitted with strategy.SubmitOrderAction
+	// are filled (see OrderConfig)
+	Orders OrderConfig
+
+	// TrackReplication maps each hedged underlying, as Portfolio.Greeks
+	// groups them, to the snapshot pair that prices it (e.g., "ETH":
+	// "WETH/USDC"). When set, the book's residual delta after every
+	// snapshot and the attribution of its P&L to delta, gamma and carry
+	// are recorded in Result.Replication (see ReplicationPoint).
+	TrackReplication map[string]string
+
+	// CashYield accrues interest on idle cash between snapshots, credited
+	// before each snapshot is valued and posted to
+	// accounting.AccountInterestPnL when a Ledger is set. The zero value
+	// earns nothing.
+	CashYield CashYield
+
+	// Financing charges interest on negative cash between snapshots,
+	// debited before each snapshot is valued and posted to
+	// accounting.AccountFinancingPnL when a Ledger is set. The zero value
+	// makes borrowing free.
+	Financing Financing
+
+	// WarmUp and WarmUpDuration set a warm-up window at the start of the
+	// run: the first WarmUp snapshots, and every snapshot less than
+	// WarmUpDuration after the first. Warm-up snapshots are passed to the
+	// strategy so its indicators gather history (strategy.WarmingUp
+	// reports them), but the actions it returns are discarded and the
+	// snapshots are left out of Result.History and the metrics.
+	WarmUp         int
+	WarmUpDuration primitives.Duration
+
+	// Seed seeds the run's source of randomness, which strategies draw
+	// from with strategy.Rand. Each Run starts a fresh generator from it,
+	// so the same configuration, seed and snapshots always yield the same
+	// result.
+	Seed int64
+
+	// Clock, when set, is the simulated clock of the run: it is moved to
+	// each snapshot's time and is what strategy.Clock returns. Share it
+	// with components built before the run that stamp by time (such as a
+	// perpetual.Future, via SetClock) so they read snapshot time too, or
+	// read it from a test to travel with the run. Nil uses a clock private
+	// to each run.
+	Clock *primitives.ManualClock
+
+	// Audit checks the run for nondeterminism: at each snapshot the
+	// portfolio is valued a second time with its positions in a shuffled
+	// order, and any difference is recorded in Result.AuditFindings and
+	// logged as a warning. It doubles the cost of valuation; use it in
+	// tests, or Engine.Audit to also replay the strategy.
+	Audit bool
+
+	// States registers the pools and venues whose state evolves over the
+	// run (see strategy.MechanismState). Each Run starts from clones of the
+	// registered states, advances them at every snapshot before it is
+	// valued, and applies strategy.UpdateStateAction in the same
+	// transaction as the snapshot's other actions. The snapshots passed to
+	// the strategy and to Position.Value carry the evolved states, which
+	// strategy.StateOf looks up; the final states are in Result.States.
+	States *strategy.MechanismStates
+
+	// PoolImpact moves the pools tracked in States by the strategy's own
+	// liquidity changes and fills (see PoolImpact)
+	PoolImpact PoolImpact
+
+	// BaseCurrency is the currency InitialCash and portfolio values are
+	// denominated in (e.g., "USD"); see strategy.Portfolio.SetBaseCurrency.
+	// Empty performs no conversion.
+	BaseCurrency string
+
+	// Denominations lists other currencies to report results in (e.g.,
+	// "ETH"). The rate from BaseCurrency to each is recorded at every
+	// snapshot, so Result.Denominated can restate the run; requires
+	// BaseCurrency.
+	Denominations []string
+
+	// Experiment, when set, records in Result.Experiment the run ID,
+	// serialized configuration, strategy parameters, data fingerprint and
+	// code versions behind the run (see Experiment). Fingerprinting reads
+	// every snapshot once before the run.
+	Experiment *ExperimentConfig
+}
+
+// ValuationTiming selects when the engine records the portfolio value at
+// each snapshot.
+type ValuationTiming int
+
+const (
+	// ValueBeforeActions values the portfolio before the snapshot's
+	// settlements, fills and actions, so positions opened at a snapshot
+	// first appear in the history at the next one
+	ValueBeforeActions ValuationTiming = iota
+
+	// ValueAfterActions values the portfolio once the snapshot's actions,
+	// fills and hedges are applied, so History and the metrics include
+	// them at the snapshot they happened
+	ValueAfterActions
+
+	// ValueBeforeAndAfterActions records both: History, and the metrics,
+	// use the values before actions and Result.PostActionHistory holds
+	// the values after
+	ValueBeforeAndAfterActions
+)
+
+// VenueLatency is the execution delay of a venue. When both fields are set
+// an action waits until both have elapsed.
+type VenueLatency struct {
+	// Delay is the minimum time between the snapshot an action was decided
+	// at and the snapshot it executes at
+	Delay primitives.Duration
+
+	// Snapshots is the minimum number of snapshots an action waits
+	Snapshots int
+}
+
+// delayed reports whether the latency holds actions back at all.
+func (l VenueLatency) delayed() bool {
+	return l.Snapshots > 0 || l.Delay.Duration() > 0
+}
+
+// ActionErrorPolicy controls how the engine handles an action that fails
+// to apply. Each snapshot's actions are applied as a transaction: the
+// portfolio (including recorded history) and ledger are checkpointed first
+// and restored if the batch does not commit.
+type ActionErrorPolicy int
+
+const (
+	// AbortOnActionError rolls back the snapshot's actions and stops the run
+	// with an error
+	AbortOnActionError ActionErrorPolicy = iota
+
+	// SkipSnapshotOnActionError rolls back all of the snapshot's actions,
+	// records the failure in Result.SkippedActions and continues
+	SkipSnapshotOnActionError
+
+	// SkipActionOnActionError rolls back only the failed action, records it
+	// in Result.SkippedActions and applies the remaining actions
+	SkipActionOnActionError
+)
+
+// SkippedAction records an action that failed and was rolled back under a
+// skip policy.
+type SkippedAction struct {
+	// Snapshot is the index of the snapshot being processed
+	Snapshot int
+
+	// Time is the snapshot time
+	Time primitives.Time
+
+	// Index is the action's position in the strategy's returned actions,
+	// or -1 for an order fill, an expiry settlement, a hedge, a preflight
+	// constraint violation or a batch blocked by Greek limits
+	Index int
+
+	// Submitted is the index of the snapshot whose rebalance returned the
+	// action; it differs from Snapshot for latency-delayed actions
+	Submitted int
+
+	// Action is the action that failed; nil for a constraint violation
+	Action strategy.Action
+
+	// Err is the error returned when applying the action
+	Err error
 }
 
 // DefaultConfig returns sensible default configuration.
@@ -75,16 +358,33 @@ func NewEngineWithDefaults() *Engine {
 // Error Handling:
 //   - Returns error if strategy is nil or snapshots is empty
 //   - Returns error if strategy.Rebalance() fails
-//   - Returns error if action application fails
+//   - Returns error if action application fails (unless Config.OnActionError
+//     is a skip policy)
 //   - Respects context cancellation (returns ctx.Err())
+//   - Returns a *MissingDataError if a snapshot lacks declared required
+//     data (see Config.Requirements)
 //
 // Execution Flow:
-//  1. Initialize portfolio with configured initial cash
+//  1. Check every snapshot against Config.Requirements and a
+//     strategy.DataDependent strategy's, then initialize portfolio with
+//     configured initial cash
 //  2. For each market snapshot (in order):
-//     a. Check context cancellation
-//     b. Call strategy.Rebalance(ctx, portfolio, snapshot)
-//     c. Apply returned actions to portfolio
-//     d. Calculate and record portfolio value
+//     a. Check context cancellation and advance Config.States; during the Config.WarmUp window,
+//     call strategy.Rebalance, discard its actions and go to the next
+//     snapshot
+//     b. Credit interest on cash, or charge it on negative cash, since the
+//     previous snapshot under Config.CashYield and Config.Financing
+//     c. Check the snapshot against strategy.DataDependent positions'
+//     requirements, then calculate and record portfolio value (and FX
+//     rates to Config.Denominations), checking it under Config.Audit
+//     d. Settle strategy.Expirable positions whose expiry has passed
+//     e. Apply latency-delayed actions that have come due
+//     f. Fill, expire and cancel open orders, notifying an OrderListener
+//     g. Call strategy.Rebalance(ctx, portfolio, snapshot)
+//     h. Apply returned actions, queueing those routed to delayed venues
+//     i. Check Config.GreekLimits, hedging under HedgeOnGreekBreach
+//     j. Record the book's residual delta under Config.TrackReplication
+//     k. Record the value after actions under Config.Valuation
 //  3. Calculate performance metrics from value history
 //  4. Return results
 //
@@ -92,12 +392,15 @@ func NewEngineWithDefaults() *Engine {
 //   - Snapshots processed in order
 //   - Portfolio value calculated after each rebalancing
 //   - All actions applied atomically per snapshot
+//   - Deterministic execution: positions are visited in ID order and
+//     strategy.Clock reads snapshot time, so the same strategy and
+//     snapshots always produce the same Result (see Engine.Audit)
 //   - No assumptions about position or mechanism types
 func (e *Engine) Run(
 	ctx context.Context,
 	strat strategy.Strategy,
 	snapshots []strategy.MarketSnapshot,
-) (*Result, error) {
+) (result *Result, err error) {
 	// Validate inputs
 	if strat == nil {
 		return nil, fmt.Errorf("strategy cannot be nil")
@@ -105,12 +408,97 @@ func (e *Engine) Run(
 	if len(snapshots) == 0 {
 		return nil, fmt.Errorf("snapshots cannot be empty")
 	}
+	if len(e.config.Denominations) > 0 && e.config.BaseCurrency == "" {
+		return nil, fmt.Errorf("denominations %v require a base currency", e.config.Denominations)
+	}
+	if err := e.config.PoolImpact.validate(e.config.States); err != nil {
+		return nil, err
+	}
+	if e.config.WarmUp < 0 {
+		return nil, fmt.Errorf("warm-up cannot be negative, got %d snapshots", e.config.WarmUp)
+	}
+	warmUp := e.warmUp(snapshots)
+	if warmUp > 0 && warmUp > len(snapshots)-2 {
+		return nil, fmt.Errorf("warm-up of %d snapshots leaves fewer than 2 of %d to measure", warmUp, len(snapshots))
+	}
+
+	if err := e.checkSnapshots(strat, snapshots); err != nil {
+		return nil, err
+	}
+
+	var experiment *Experiment
+	if e.config.Experiment != nil {
+		var err error
+		if experiment, err = newExperiment(e.config, strat, snapshots); err != nil {
+			return nil, err
+		}
+	}
+
+	logger := e.logger()
+	e.log = logging.For(logger, logging.ComponentEngine)
+	e.log.Info("backtest started",
+		"strategy", fmt.Sprintf("%T", strat),
+		"snapshots", len(snapshots),
+		"initial_cash", e.config.InitialCash.String(),
+		"seed", e.config.Seed)
+
+	ctx, span := e.config.Instrumentation.Start(ctx, telemetry.SpanRun)
+	defer func() { span.End(err) }()
+	ctx = strategy.WithRand(ctx, strategy.NewRand(e.config.Seed))
+	clock := e.config.Clock
+	if clock == nil {
+		clock = primitives.NewManualClock(snapshots[0].Time())
+	}
+	clock.Set(snapshots[0].Time())
+	ctx = strategy.WithClock(ctx, clock)
 
 	// Initialize portfolio
 	portfolio := strategy.NewPortfolio(e.config.InitialCash)
+	portfolio.SetBaseCurrency(e.config.BaseCurrency)
+	if e.config.RecordHistory {
+		portfolio.EnableHistory()
+	}
+	if logger != nil {
+		portfolio.SetLogger(logger)
+	}
+	if e.config.Ledger != nil {
+		e.config.Ledger.SetValuer(ledgerValuer{engine: e, ctx: ctx})
+		defer e.config.Ledger.SetValuer(nil)
+		if err := e.config.Ledger.Open(snapshots[0].Time(), portfolio, snapshots[0]); err != nil {
+			return nil, fmt.Errorf("failed to open ledger: %w", err)
+		}
+	}
 
 	// Track portfolio values over time
-	valueHistory := make([]ValuePoint, 0, len(snapshots))
+	series := NewValueSeries(len(snapshots))
+	var postSeries *ValueSeries
+	if e.config.Valuation == ValueBeforeAndAfterActions {
+		postSeries = NewValueSeries(len(snapshots))
+	}
+	var valueHistory []ValuePoint
+	if !e.config.ColumnarHistory {
+		valueHistory = make([]ValuePoint, 0, len(snapshots))
+	}
+	var exposures []Exposure
+	if e.config.RecordExposure {
+		exposures = make([]Exposure, 0, len(snapshots))
+	}
+	var skipped []SkippedAction
+	var breaches []GreekBreachEvent
+	var findings []AuditFinding
+	interest, financing := primitives.Zero(), primitives.Zero()
+	var pending []queuedAction
+	var fxHistory map[string][]primitives.Decimal
+	if len(e.config.Denominations) > 0 {
+		fxHistory = make(map[string][]primitives.Decimal, len(e.config.Denominations))
+	}
+	var replication *replicationTracker
+	if len(e.config.TrackReplication) > 0 {
+		replication = newReplicationTracker(e.config.TrackReplication, len(snapshots))
+	}
+	e.orders = &orderBook{log: logging.For(logger, logging.ComponentOrders)}
+	e.states = e.config.States.Clone()
+	e.valuations = newValuationGuard(e.config.OnValuationError)
 
 	// Event loop: process each market snapshot
 	for i, snapshot := range snapshots {
@@ -121,74 +509,682 @@ func (e *Engine) Run(
 		default:
 		}
 
+		// Stamp any portfolio and order changes with the snapshot time,
+		// which is also the time strategy.Clock reads
+		portfolio.SetTime(snapshot.Time())
+		clock.Set(snapshot.Time())
+		e.orders.index, e.orders.now = i, snapshot.Time()
+		e.valuations.at(i, snapshot.Time())
+
+		// Evolve tracked mechanism states to the snapshot and expose them
+		// to the strategy and positions through it
+		if e.states.Len() > 0 {
+			if err := e.states.Advance(snapshot); err != nil {
+				return nil, fmt.Errorf("failed to advance mechanism states at snapshot %d: %w", i, err)
+			}
+			snapshot = e.states.Attach(snapshot)
+		}
+
+		if i < warmUp {
+			if err := e.warm(ctx, strat, portfolio, snapshot, i); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Credit interest on cash, or charge it on borrowed cash, since the
+		// previous snapshot
+		if i > warmUp {
+			earned, charged, err := e.accrueCash(ctx, portfolio, snapshots[i-1], snapshot)
+			if err != nil {
+				return nil, fmt.Errorf("failed to accrue cash interest at snapshot %d: %w", i, err)
+			}
+			interest, financing = interest.Add(earned), financing.Add(charged)
+		}
+
 		// Calculate portfolio value BEFORE rebalancing
 		// (first snapshot uses initial cash, subsequent use actual portfolio value)
-		portfolioValue, err := e.calculatePortfolioValue(portfolio, snapshot)
+		e.config.Instrumentation.Add(ctx, telemetry.CounterSnapshots, 1)
+		var breakdown *Exposure
+		if e.config.RecordExposure {
+			exposures = append(exposures, Exposure{Time: snapshot.Time()})
+			breakdown = &exposures[len(exposures)-1]
+		}
+		if err := e.checkPositions(portfolio, snapshot, i); err != nil && e.config.OnValuationError == AbortOnValuationError {
+			return nil, err
+		}
+		portfolioValue, err := e.calculatePortfolioValue(ctx, portfolio, snapshot, breakdown)
 		if err != nil {
 			return nil, fmt.Errorf("failed to calculate portfolio value at snapshot %d: %w", i, err)
 		}
+		if e.config.Audit {
+			finding, err := e.auditValuation(ctx, portfolio, snapshot, i, portfolioValue)
+			if err != nil {
+				return nil, err
+			}
+			if finding != nil {
+				findings = append(findings, *finding)
+			}
+		}
+
+		if e.log.Enabled(ctx, slog.LevelDebug) {
+			e.log.LogAttrs(ctx, slog.LevelDebug, "snapshot",
+				slog.Int(logging.KeySnapshot, i),
+				slog.Time(logging.KeySnapshotTime, snapshot.Time().Time()),
+				slog.String("value", portfolioValue.String()))
+		}
 
 		// Record value point
-		valueHistory = append(valueHistory, ValuePoint{
-			Time:  snapshot.Time(),
-			Value: portfolioValue,
-		})
+		if e.config.Valuation != ValueAfterActions {
+			point := ValuePoint{Time: snapshot.Time(), Value: portfolioValue}
+			series.Append(point)
+			if valueHistory != nil {
+				valueHistory = append(valueHistory, point)
+			}
+		}
+		for _, currency := range e.config.Denominations {
+			rate, err := strategy.FXRate(snapshot, e.config.BaseCurrency, currency)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert to %s at snapshot %d: %w", currency, i, err)
+			}
+			fxHistory[currency] = append(fxHistory[currency], rate)
+		}
+
+		if e.config.Ledger != nil {
+			if err := e.config.Ledger.MarkToMarket(portfolio, snapshot); err != nil {
+				return nil, fmt.Errorf("failed to mark ledger to market at snapshot %d: %w", i, err)
+			}
+		}
+
+		// Settle positions that expired by this snapshot before anything
+		// else trades against them
+		failures, err := e.settleExpired(ctx, portfolio, snapshot, i)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+
+		// Execute delayed actions that reached their venue, so the strategy
+		// sees their effect when it rebalances
+		var due []queuedAction
+		due, pending = dueActions(pending, i, snapshot.Time())
+		if due, failures, err = e.blockGreeks(ctx, portfolio, snapshot, i, due); err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+		failures, err = e.applyActions(ctx, portfolio, snapshot, i, due)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+
+		// Match orders submitted at earlier snapshots
+		failures, err = e.matchOrders(ctx, portfolio, snapshot, i)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+		e.orders.notify(strat)
 
 		// Call strategy rebalancing logic
-		actions, err := strat.Rebalance(ctx, portfolio, snapshot)
+		rebalanceCtx, span := e.config.Instrumentation.Start(ctx, telemetry.SpanRebalance)
+		actions, err := strat.Rebalance(rebalanceCtx, portfolio, snapshot)
+		span.End(err)
 		if err != nil {
+			e.log.Error("strategy rebalance failed", logging.KeySnapshot, i, logging.KeyError, err)
 			return nil, fmt.Errorf("strategy rebalance failed at snapshot %d: %w", i, err)
 		}
 
-		// Apply actions to portfolio
+		// Apply immediate actions to portfolio as a single transaction
+		var immediate []queuedAction
 		for actionIdx, action := range actions {
-			if err := action.Apply(portfolio); err != nil {
-				return nil, fmt.Errorf("failed to apply action %d at snapshot %d: %w", actionIdx, i, err)
+			queued := queuedAction{action: action, submitted: i, index: actionIdx}
+			if latency, ok := e.latency(action); ok {
+				queued.dueSnapshot = i + latency.Snapshots
+				queued.dueTime = snapshot.Time().Add(latency.Delay)
+				pending = append(pending, queued)
+				if e.log.Enabled(ctx, slog.LevelDebug) {
+					e.log.LogAttrs(ctx, slog.LevelDebug, "action delayed",
+						slog.Int(logging.KeySnapshot, i),
+						slog.String(logging.KeyAction, action.String()),
+						slog.Int("due_snapshot", queued.dueSnapshot),
+						slog.Time("due_time", queued.dueTime.Time()))
+				}
+				continue
+			}
+			immediate = append(immediate, queued)
+		}
+		if immediate, failures, err = e.blockGreeks(ctx, portfolio, snapshot, i, immediate); err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+		failures, err = e.applyActions(ctx, portfolio, snapshot, i, immediate)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+
+		// Check the book against Greek limits, hedging if configured
+		breach, failures, err := e.enforceGreeks(ctx, portfolio, snapshot, i)
+		if err != nil {
+			return nil, err
+		}
+		if breach != nil {
+			breaches = append(breaches, *breach)
+		}
+		skipped = append(skipped, failures...)
+		e.orders.notify(strat)
+
+		if replication != nil {
+			if err := replication.mark(ctx, e, portfolio, snapshot, i, portfolioValue); err != nil {
+				return nil, err
+			}
+		}
+
+		// Record the value once the snapshot's actions are applied
+		if e.config.Valuation != ValueBeforeActions {
+			if err := e.checkPositions(portfolio, snapshot, i); err != nil && e.config.OnValuationError == AbortOnValuationError {
+				return nil, err
+			}
+			postValue, err := e.calculatePortfolioValue(ctx, portfolio, snapshot, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate post-action portfolio value at snapshot %d: %w", i, err)
+			}
+			point := ValuePoint{Time: snapshot.Time(), Value: postValue}
+			if postSeries != nil {
+				postSeries.Append(point)
+			} else {
+				series.Append(point)
+				if valueHistory != nil {
+					valueHistory = append(valueHistory, point)
+				}
 			}
 		}
 	}
 
 	// Calculate final portfolio value
 	finalSnapshot := snapshots[len(snapshots)-1]
-	finalValue, err := e.calculatePortfolioValue(portfolio, finalSnapshot)
+	if e.states.Len() > 0 {
+		finalSnapshot = e.states.Attach(finalSnapshot)
+	}
+	finalValue, err := e.calculatePortfolioValue(ctx, portfolio, finalSnapshot, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate final portfolio value: %w", err)
 	}
+	pnl, err := e.pnl(ctx, portfolio, finalSnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate final P&L: %w", err)
+	}
 
 	// Build result with performance metrics
-	result := &Result{
+	result = &Result{
 		InitialValue: e.config.InitialCash,
 		FinalValue:   finalValue,
 		ValueHistory: valueHistory,
+		series:       series,
+		postSeries:   postSeries,
+		valuation:    e.config.Valuation,
+		snapshots:    snapshots[warmUp:],
 		Portfolio:    portfolio,
+
+		ExposureHistory: exposures,
+
+		SkippedActions: skipped,
+		GreekBreaches:  breaches,
+		Fills:          e.orders.fills(),
+		Orders:         e.orders.orders(),
+		FXHistory:      fxHistory,
+		CashInterest:   interest,
+		FinancingCost:  financing,
+		PnL:            pnl,
+		WarmUp:         warmUp,
+		AuditFindings:  findings,
+		Degradations:   e.valuations.degradations,
+		Experiment:     experiment,
+	}
+	if e.states.Len() > 0 {
+		result.States = e.states
+	}
+	if replication != nil {
+		result.Replication = replication.points
+	}
+	for _, queued := range pending {
+		result.PendingActions = append(result.PendingActions, queued.action)
 	}
 
 	// Calculate derived metrics
 	if err := result.calculateMetrics(); err != nil {
 		return nil, fmt.Errorf("failed to calculate performance metrics: %w", err)
 	}
+	e.log.Info("backtest finished",
+		"final_value", finalValue.String(),
+		"total_return", result.TotalReturn.String(),
+		"skipped_actions", len(skipped),
+		"fills", len(result.Fills))
 
 	return result, nil
 }
 
+// queuedAction is an action waiting to be applied, with the snapshot and
+// position it was returned at.
+type queuedAction struct {
+	action      strategy.Action
+	submitted   int
+	index       int
+	dueSnapshot int
+	dueTime     primitives.Time
+}
+
+// latency returns the latency of the venue an action is routed to, if the
+// venue delays actions.
+func (e *Engine) latency(action strategy.Action) (VenueLatency, bool) {
+	routed, ok := action.(*strategy.VenueAction)
+	if !ok {
+		return VenueLatency{}, false
+	}
+	latency, ok := e.config.Latency[routed.Venue]
+	return latency, ok && latency.delayed()
+}
+
+// dueActions splits pending into the actions due at a snapshot and those
+// still waiting, preserving submission order.
+func dueActions(pending []queuedAction, index int, now primitives.Time) (due, waiting []queuedAction) {
+	waiting = pending[:0]
+	for _, queued := range pending {
+		if index >= queued.dueSnapshot && !now.Before(queued.dueTime) {
+			due = append(due, queued)
+		} else {
+			waiting = append(waiting, queued)
+		}
+	}
+	return due, waiting
+}
+
+// settleExpired settles each strategy.Expirable position whose expiry is
+// at or before the snapshot. Each settlement is applied as its own
+// transaction under the configured ActionErrorPolicy; a position whose
+// settlement is skipped is retried at the next snapshot.
+func (e *Engine) settleExpired(
+	ctx context.Context,
+	portfolio *strategy.Portfolio,
+	snapshot strategy.MarketSnapshot,
+	index int,
+) ([]SkippedAction, error) {
+	var skipped []SkippedAction
+	for _, position := range portfolio.Positions() {
+		if !strategy.Expired(position, snapshot.Time()) {
+			continue
+		}
+		failures, err := e.applyActions(ctx, portfolio, snapshot, index, []queuedAction{{
+			action:    strategy.NewSettlePositionAction(position.ID()),
+			submitted: index,
+			index:     -1,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, failures...)
+	}
+	return skipped, nil
+}
+
+// applyActions applies a snapshot's actions transactionally according to
+// the configured ActionErrorPolicy, returning any skipped actions.
+func (e *Engine) applyActions(
+	ctx context.Context,
+	portfolio *strategy.Portfolio,
+	snapshot strategy.MarketSnapshot,
+	index int,
+	actions []queuedAction,
+) (skipped []SkippedAction, err error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	ctx, span := e.config.Instrumentation.Start(ctx, telemetry.SpanApplyActions)
+	defer func() { span.End(err) }()
+
+	if e.config.Preflight {
+		if actions, skipped, err = e.preflight(ctx, portfolio, snapshot, index, actions); err != nil || len(actions) == 0 {
+			return skipped, err
+		}
+	}
+
+	// Journal changes rather than copying the portfolio per action, so a
+	// savepoint is O(1) and a rollback costs only what the action changed
+	e.beginJournal(portfolio)
+	defer e.endJournal(portfolio)
+
+	batch := e.savepoint(portfolio)
+
+	for n, queued := range actions {
+		single := batch
+		if e.config.OnActionError == SkipActionOnActionError && n > 0 {
+			single = e.savepoint(portfolio)
+		}
+
+		err := e.apply(portfolio, snapshot, queued.action)
+		if err == nil {
+			e.config.Instrumentation.Add(ctx, telemetry.CounterActions, 1)
+			if e.log.Enabled(ctx, slog.LevelDebug) {
+				e.log.LogAttrs(ctx, slog.LevelDebug, "action applied",
+					slog.Int(logging.KeySnapshot, index),
+					slog.Time(logging.KeySnapshotTime, snapshot.Time().Time()),
+					slog.String(logging.KeyAction, queued.action.String()))
+			}
+			continue
+		}
+
+		failure := SkippedAction{
+			Snapshot:  index,
+			Time:      snapshot.Time(),
+			Index:     queued.index,
+			Submitted: queued.submitted,
+			Action:    queued.action,
+			Err:       err,
+		}
+		switch e.config.OnActionError {
+		case SkipActionOnActionError:
+			single.rollback()
+			e.orders.reject(queued)
+			e.logSkipped(ctx, failure)
+			skipped = append(skipped, failure)
+		case SkipSnapshotOnActionError:
+			batch.rollback()
+			e.orders.reject(actions...)
+			e.logSkipped(ctx, failure)
+			return append(skipped, failure), nil
+		default:
+			batch.rollback()
+			e.config.Instrumentation.Add(ctx, telemetry.CounterActionErrors, 1)
+			e.log.Error("action failed",
+				logging.KeySnapshot, index,
+				logging.KeyAction, queued.action.String(),
+				logging.KeyError, err)
+			if queued.submitted != index {
+				return nil, fmt.Errorf("failed to apply action %d from snapshot %d at snapshot %d: %w",
+					queued.index, queued.submitted, index, err)
+			}
+			return nil, fmt.Errorf("failed to apply action %d at snapshot %d: %w", queued.index, index, err)
+		}
+	}
+	return skipped, nil
+}
+
+// preflight validates a batch and returns the actions still to apply and
+// the diagnostics skipped under the ActionErrorPolicy.
+func (e *Engine) preflight(
+	ctx context.Context,
+	portfolio *strategy.Portfolio,
+	snapshot strategy.MarketSnapshot,
+	index int,
+	actions []queuedAction,
+) ([]queuedAction, []SkippedAction, error) {
+	batch := make([]strategy.Action, len(actions))
+	for n, queued := range actions {
+		batch[n] = queued.action
+	}
+	report := portfolio.Validate(batch, snapshot, e.config.Constraints...)
+	if report.OK() {
+		return actions, nil, nil
+	}
+	if e.config.OnActionError == AbortOnActionError {
+		e.config.Instrumentation.Add(ctx, telemetry.CounterActionErrors, int64(len(report.Diagnostics)))
+		return nil, nil, fmt.Errorf("preflight failed at snapshot %d: %w", index, report.Err())
+	}
+
+	var skipped []SkippedAction
+	failed := make(map[int]bool)
+	wholeBatch := e.config.OnActionError == SkipSnapshotOnActionError
+	for _, d := range report.Diagnostics {
+		failure := SkippedAction{Snapshot: index, Time: snapshot.Time(), Index: -1, Submitted: index, Err: d}
+		if d.Index >= 0 {
+			queued := actions[d.Index]
+			failure.Index, failure.Submitted, failure.Action = queued.index, queued.submitted, queued.action
+			failed[d.Index] = true
+		} else {
+			wholeBatch = true
+		}
+		e.logSkipped(ctx, failure)
+		skipped = append(skipped, failure)
+	}
+	if wholeBatch {
+		e.orders.reject(actions...)
+		return nil, skipped, nil
+	}
+	var remaining []queuedAction
+	for n, queued := range actions {
+		if failed[n] {
+			e.orders.reject(queued)
+			continue
+		}
+		remaining = append(remaining, queued)
+	}
+	return remaining, skipped, nil
+}
+
+// beginJournal starts undo journals on the portfolio and ledger.
+func (e *Engine) beginJournal(portfolio *strategy.Portfolio) {
+	portfolio.BeginJournal()
+	if e.config.Ledger != nil {
+		e.config.Ledger.BeginJournal()
+	}
+}
+
+// endJournal discards the undo journals.
+func (e *Engine) endJournal(portfolio *strategy.Portfolio) {
+	portfolio.EndJournal()
+	if e.config.Ledger != nil {
+		e.config.Ledger.EndJournal()
+	}
+}
+
+// savepoint marks the portfolio and ledger journals for rollback.
+func (e *Engine) savepoint(portfolio *strategy.Portfolio) engineSavepoint {
+	sp := engineSavepoint{portfolio: portfolio, saved: portfolio.Savepoint(), ledger: e.config.Ledger, orders: e.orders}
+	if sp.ledger != nil {
+		sp.ledgerSaved = sp.ledger.Savepoint()
+	}
+	if e.states.Len() > 0 {
+		sp.states, sp.statesSaved = e.states, e.states.Clone()
+	}
+	if sp.orders != nil {
+		sp.ordersSaved = sp.orders.savepoint()
+	}
+	return sp
+}
+
+// engineSavepoint pairs portfolio, ledger, order book and mechanism state
+// savepoints.
+type engineSavepoint struct {
+	portfolio   *strategy.Portfolio
+	saved       strategy.PortfolioSavepoint
+	ledger      *accounting.Ledger
+	ledgerSaved accounting.Savepoint
+	orders      *orderBook
+	ordersSaved orderBookSavepoint
+	states      *strategy.MechanismStates
+	statesSaved *strategy.MechanismStates
+}
+
+func (sp engineSavepoint) rollback() {
+	sp.portfolio.RollbackTo(sp.saved)
+	if sp.ledger != nil {
+		sp.ledger.RollbackTo(sp.ledgerSaved)
+	}
+	if sp.orders != nil {
+		sp.orders.rollbackTo(sp.ordersSaved)
+	}
+	if sp.states != nil {
+		sp.states.Restore(sp.statesSaved)
+	}
+}
+
+// logger returns the configured logger, a debug logger on standard error
+// under EnableDetailedLogging, or nil.
+func (e *Engine) logger() *slog.Logger {
+	if e.config.Logger != nil {
+		return e.config.Logger
+	}
+	if e.config.EnableDetailedLogging {
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return nil
+}
+
+// logSkipped logs and counts a skipped action.
+func (e *Engine) logSkipped(ctx context.Context, failure SkippedAction) {
+	e.config.Instrumentation.Add(ctx, telemetry.CounterActionErrors, 1)
+	action := "constraint"
+	if failure.Action != nil {
+		action = failure.Action.String()
+	}
+	e.log.Warn("action skipped",
+		logging.KeySnapshot, failure.Snapshot,
+		logging.KeySnapshotTime, failure.Time.Time(),
+		"index", failure.Index,
+		logging.KeyAction, action,
+		logging.KeyError, failure.Err)
+}
+
+// apply applies an action, posting it to the ledger when one is configured.
+// A strategy.DeferredAction is first resolved against the snapshot, order
+// submissions and cancellations go to the order book, state updates to the
+// tracked states, and the action's trades against tracked pools are
+// applied under Config.PoolImpact.
+func (e *Engine) apply(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, action strategy.Action) error {
+	if order, ok := orderAction(action); ok {
+		return e.applyOrderAction(order)
+	}
+	if update, ok := stateAction(action); ok {
+		return e.states.Update(update)
+	}
+	if deferred, ok := action.(strategy.DeferredAction); ok {
+		resolved, err := deferred.Resolve(snapshot)
+		if err != nil {
+			return err
+		}
+		action = resolved
+	}
+	var trades []stateTrade
+	if e.config.PoolImpact.enabled() {
+		trades = e.poolTrades(portfolio, action)
+	}
+	var err error
+	if e.config.Ledger != nil {
+		err = e.config.Ledger.Apply(portfolio, snapshot, action)
+	} else {
+		err = action.Apply(portfolio)
+	}
+	if err != nil {
+		return err
+	}
+	return e.applyPoolTrades(trades)
+}
+
+// stateAction returns the mechanism state update in action, unwrapping a
+// venue route.
+func stateAction(action strategy.Action) (*strategy.UpdateStateAction, bool) {
+	if routed, ok := action.(*strategy.VenueAction); ok {
+		action = routed.Action
+	}
+	update, ok := action.(*strategy.UpdateStateAction)
+	return update, ok
+}
+
+// pnl splits the portfolio's P&L at snapshot into realized and
+// unrealized, valuing positions as calculatePortfolioValue does.
+func (e *Engine) pnl(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) (strategy.PnL, error) {
+	valuation := e.valuationContext(ctx, snapshot)
+	unrealized := primitives.Zero()
+	for _, position := range portfolio.Positions() {
+		value, err := e.positionValue(ctx, portfolio, position, valuation)
+		if err != nil {
+			return strategy.PnL{}, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
+		}
+		unrealized = unrealized.Add(value.Sub(portfolio.CostBasis(position.ID())))
+	}
+	return strategy.PnL{Realized: portfolio.RealizedPnL(), Unrealized: unrealized}, nil
+}
+
+// warmUp returns the number of snapshots in the warm-up window.
+func (e *Engine) warmUp(snapshots []strategy.MarketSnapshot) int {
+	n := e.config.WarmUp
+	if n > len(snapshots) {
+		n = len(snapshots)
+	}
+	if e.config.WarmUpDuration.Duration() > 0 {
+		end := snapshots[0].Time().Add(e.config.WarmUpDuration)
+		for n < len(snapshots) && snapshots[n].Time().Before(end) {
+			n++
+		}
+	}
+	return n
+}
+
+// warm passes a warm-up snapshot to the strategy, discarding its actions.
+func (e *Engine) warm(ctx context.Context, strat strategy.Strategy, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, index int) error {
+	rebalanceCtx, span := e.config.Instrumentation.Start(strategy.WithWarmUp(ctx), telemetry.SpanRebalance)
+	actions, err := strat.Rebalance(rebalanceCtx, portfolio, snapshot)
+	span.End(err)
+	if err != nil {
+		e.log.Error("strategy rebalance failed", logging.KeySnapshot, index, logging.KeyError, err)
+		return fmt.Errorf("strategy rebalance failed at warm-up snapshot %d: %w", index, err)
+	}
+	if len(actions) > 0 && e.log.Enabled(ctx, slog.LevelDebug) {
+		e.log.LogAttrs(ctx, slog.LevelDebug, "warm-up actions discarded",
+			slog.Int(logging.KeySnapshot, index),
+			slog.Int("actions", len(actions)))
+	}
+	return nil
+}
+
+// valuationContext returns the context positions are valued in at
+// snapshot.
+func (e *Engine) valuationContext(ctx context.Context, snapshot strategy.MarketSnapshot) *strategy.ValuationContext {
+	if e.config.CrossRates == nil {
+		return strategy.NewValuationContext(ctx, snapshot)
+	}
+	return strategy.NewValuationContext(ctx, snapshot, strategy.WithCrossRates(e.config.CrossRates))
+}
+
 // calculatePortfolioValue computes the total value of the portfolio at the given market snapshot.
-// Returns the sum of cash plus all position values.
+// Returns the sum of cash plus all position values, attributing them to
+// breakdown when it is not nil.
 func (e *Engine) calculatePortfolioValue(
+	ctx context.Context,
 	portfolio *strategy.Portfolio,
 	snapshot strategy.MarketSnapshot,
-) (primitives.Amount, error) {
-	// Start with cash balance
-	totalValue := portfolio.Cash()
+	breakdown *Exposure,
+) (value primitives.Amount, err error) {
+	ctx, span := e.config.Instrumentation.Start(ctx, telemetry.SpanValuation)
+	defer func() { span.End(err) }()
+	valuation := e.valuationContext(ctx, snapshot)
+
+	// Start with the signed cash balance, so borrowed cash counts against
+	// the book; accumulate as Decimal and wrap once
+	totalValue := portfolio.CashDecimal()
 
 	// Add value of all positions
 	positions := portfolio.Positions()
 	for _, position := range positions {
-		posValue, err := position.Value(snapshot)
+		posValue, err := e.positionValue(ctx, portfolio, position, valuation)
 		if err != nil {
 			return primitives.Amount{}, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
 		}
 		totalValue = totalValue.Add(posValue)
+		if breakdown != nil {
+			breakdown.add(position, portfolio.Tags(position.ID()), posValue)
+		}
+	}
+	if breakdown != nil {
+		breakdown.closeTags()
+		breakdown.Cash = portfolio.CashDecimal()
 	}
 
-	return totalValue, nil
+	// A book whose debt exceeds its assets is worth nothing
+	if totalValue.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.MustAmount(totalValue), nil
 }
@@ -0,0 +1,73 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// RunEvents executes a backtest at event granularity: the strategy is
+// called once per market event rather than once per uniformly sampled
+// snapshot.
+//
+// A strategy.MarketState folds the events in order, and the snapshot after
+// each event drives the step exactly as in Run, so snapshot indices in the
+// Result (skipped actions, order updates, breaches) are event indices.
+// Strategies implementing strategy.EventStrategy receive each event through
+// OnEvent; others are rebalanced against the snapshot after each event. To
+// run a snapshot strategy at a coarser step, fold the events with
+// marketdata.Coalesce and call Run instead.
+//
+// Returns error if events is empty or not in chronological order, or for
+// any reason Run would.
+func (e *Engine) RunEvents(ctx context.Context, strat strategy.Strategy, events []strategy.Event) (*Result, error) {
+	if strat == nil {
+		return nil, fmt.Errorf("strategy cannot be nil")
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("events cannot be empty")
+	}
+
+	state := strategy.NewMarketState()
+	snapshots := make([]strategy.MarketSnapshot, len(events))
+	for i, event := range events {
+		if i > 0 && event.Time().Before(events[i-1].Time()) {
+			return nil, fmt.Errorf("event %d at %s precedes event %d", i, event.Time(), i-1)
+		}
+		state.Apply(event)
+		snapshot, err := state.Snapshot(event.Time())
+		if err != nil {
+			return nil, fmt.Errorf("failed to build snapshot for event %d: %w", i, err)
+		}
+		snapshots[i] = snapshot
+	}
+	return e.Run(ctx, &eventDriver{strat: strat, events: events}, snapshots)
+}
+
+// eventDriver adapts a strategy to the snapshot loop of Run, routing each
+// step to OnEvent with the event that produced its snapshot. Run calls
+// Rebalance exactly once per snapshot, in order, so a counter identifies
+// the event.
+type eventDriver struct {
+	strat  strategy.Strategy
+	events []strategy.Event
+	next   int
+}
+
+// Rebalance implements strategy.Strategy.
+func (d *eventDriver) Rebalance(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	event := d.events[d.next]
+	d.next++
+	if es, ok := d.strat.(strategy.EventStrategy); ok {
+		return es.OnEvent(ctx, portfolio, snapshot, event)
+	}
+	return d.strat.Rebalance(ctx, portfolio, snapshot)
+}
+
+// OnOrderUpdate forwards order updates to a strategy.OrderListener.
+func (d *eventDriver) OnOrderUpdate(update strategy.OrderUpdate) {
+	if listener, ok := d.strat.(strategy.OrderListener); ok {
+		listener.OnOrderUpdate(update)
+	}
+}
@@ -0,0 +1,235 @@
+package backtest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// modulePath is the toolkit's module path, looked up in the build info.
+const modulePath = "github.com/johnayoung/go-crypto-quant-toolkit"
+
+// ExperimentConfig enables experiment tracking for a run (see Experiment).
+type ExperimentConfig struct {
+	// RunID identifies the run; a random ID is generated if empty
+	RunID string
+
+	// Name labels the strategy, e.g. its registry name; the strategy's Go
+	// type if empty
+	Name string
+
+	// Parameters are the strategy's parameters; if nil they are read from
+	// a strategy implementing strategy.ParameterReporter
+	Parameters strategy.Params
+}
+
+// Experiment records what produced a run, so any number in its Result can
+// be traced back and reproduced months later: the engine configuration,
+// the strategy and its parameters, a fingerprint of the data and the
+// versions of the code.
+type Experiment struct {
+	RunID string `json:"run_id"`
+
+	// StartedAt is the wall-clock time the run started
+	StartedAt primitives.Time `json:"started_at"`
+
+	// Strategy is ExperimentConfig.Name, or the strategy's Go type
+	Strategy   string          `json:"strategy"`
+	Parameters strategy.Params `json:"parameters,omitempty"`
+
+	// Config is the engine Config as JSON. Values that do not serialize,
+	// such as loggers, hooks and registered states, are recorded by type.
+	Config json.RawMessage `json:"config"`
+
+	// ConfigHash is the hex SHA-256 of Config and Parameters, for finding
+	// runs of the same setup
+	ConfigHash string `json:"config_hash"`
+
+	// DataFingerprint is the hex SHA-256 of the snapshots: their times,
+	// prices, bars and the JSON form of their metadata
+	DataFingerprint string `json:"data_fingerprint"`
+	Snapshots       int    `json:"snapshots"`
+
+	// ToolkitVersion is the toolkit's module version in the build, or
+	// "(devel)" when built from the toolkit's own tree
+	ToolkitVersion string `json:"toolkit_version"`
+	GoVersion      string `json:"go_version"`
+
+	// GitCommit is the VCS revision the binary was built from, and
+	// GitDirty whether it had uncommitted changes; empty when the build
+	// carries no VCS information (as under go test)
+	GitCommit string `json:"git_commit,omitempty"`
+	GitDirty  bool   `json:"git_dirty,omitempty"`
+}
+
+// newExperiment builds the experiment record of a run.
+func newExperiment(config Config, strat strategy.Strategy, snapshots []strategy.MarketSnapshot) (*Experiment, error) {
+	settings := config.Experiment
+	experiment := &Experiment{
+		RunID:      settings.RunID,
+		StartedAt:  primitives.Now(),
+		Strategy:   settings.Name,
+		Parameters: settings.Parameters,
+		Snapshots:  len(snapshots),
+		GoVersion:  runtime.Version(),
+	}
+	if experiment.RunID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("failed to generate run ID: %w", err)
+		}
+		experiment.RunID = hex.EncodeToString(id)
+	}
+	if experiment.Strategy == "" {
+		experiment.Strategy = fmt.Sprintf("%T", strat)
+	}
+	if reporter, ok := strat.(strategy.ParameterReporter); ok && experiment.Parameters == nil {
+		experiment.Parameters = reporter.Parameters()
+	}
+
+	var err error
+	if experiment.Config, err = configJSON(config); err != nil {
+		return nil, fmt.Errorf("failed to serialize config: %w", err)
+	}
+	parameters, err := json.Marshal(experiment.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize parameters: %w", err)
+	}
+	hash := sha256.New()
+	hash.Write(experiment.Config)
+	hash.Write([]byte{'\n'})
+	hash.Write(parameters)
+	experiment.ConfigHash = hex.EncodeToString(hash.Sum(nil))
+	experiment.DataFingerprint = fingerprint(snapshots)
+
+	experiment.ToolkitVersion = "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath {
+			experiment.ToolkitVersion = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				experiment.ToolkitVersion = dep.Version
+				if dep.Replace != nil {
+					experiment.ToolkitVersion += " => " + dep.Replace.Path + " " + dep.Replace.Version
+				}
+			}
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				experiment.GitCommit = setting.Value
+			case "vcs.modified":
+				experiment.GitDirty = setting.Value == "true"
+			}
+		}
+	}
+	return experiment, nil
+}
+
+// configJSON serializes config without Experiment itself. Fields are
+// described by describe, so the result is deterministic and never
+// includes the internals of loggers or hooks.
+func configJSON(config Config) (json.RawMessage, error) {
+	fields := describe(reflect.ValueOf(config)).(map[string]interface{})
+	delete(fields, "Experiment")
+	return json.Marshal(fields)
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// describe returns a JSON-serializable description of v: values with
+// their own JSON or text form as is, structs as objects of their exported
+// fields, and pointers, interfaces, functions and channels by their type.
+func describe(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Func, reflect.Chan, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.Type().Implements(jsonMarshaler) || v.Type().Implements(textMarshaler) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Sprintf("%T", v.Interface())
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.IsExported() {
+				fields[field.Name] = describe(v.Field(i))
+			}
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = describe(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(iter.Key().Interface())] = describe(iter.Value())
+		}
+		return entries
+	}
+	return v.Interface()
+}
+
+// fingerprint hashes the snapshots' times, prices, bars and metadata.
+// Metadata values are hashed by their JSON form, or their type when they
+// have none.
+func fingerprint(snapshots []strategy.MarketSnapshot) string {
+	hash := sha256.New()
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(hash, "t %d\n", snapshot.Time().UnixNano())
+		prices := snapshot.Prices()
+		pairs := make([]string, 0, len(prices))
+		for pair := range prices {
+			pairs = append(pairs, pair)
+		}
+		sort.Strings(pairs)
+		bars, hasBars := snapshot.(strategy.BarSnapshot)
+		for _, pair := range pairs {
+			fmt.Fprintf(hash, "p %s %s\n", pair, prices[pair])
+			if !hasBars {
+				continue
+			}
+			if bar, err := bars.Bar(pair); err == nil {
+				fmt.Fprintf(hash, "b %s %s %s %s %s %s\n", pair, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume)
+			}
+		}
+		if lister, ok := snapshot.(strategy.MetadataLister); ok {
+			keys := lister.MetadataKeys()
+			sort.Strings(keys)
+			for _, key := range keys {
+				value, _ := snapshot.Get(key)
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					encoded = []byte(fmt.Sprintf("%T", value))
+				}
+				fmt.Fprintf(hash, "m %s %s\n", key, encoded)
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
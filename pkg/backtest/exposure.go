@@ -0,0 +1,78 @@
+package backtest
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// UnknownVenue is the ByVenue key of positions that do not report a venue
+// through strategy.PositionMetadata.
+const UnknownVenue = ""
+
+// Exposure is the portfolio valuation at one snapshot broken down by
+// position type, venue and tag, recorded under Config.RecordExposure. Cash
+// plus any one breakdown sums to the portfolio value.
+type Exposure struct {
+	Time primitives.Time
+	Cash primitives.Decimal
+
+	// ByType is the value held in each position type present
+	ByType map[strategy.PositionType]primitives.Decimal
+
+	// ByVenue is the value held at each venue present, keyed by
+	// strategy.PositionMetadata.Venue or UnknownVenue
+	ByVenue map[string]primitives.Decimal
+
+	// ByTag maps each tag key used by a held position to the value held
+	// under each of its values, with positions lacking the key under
+	// strategy.Untagged (see strategy.Tags); nil when no position is
+	// tagged
+	ByTag map[string]map[string]primitives.Decimal
+}
+
+// Invested returns the value held in positions.
+func (e Exposure) Invested() primitives.Decimal {
+	total := primitives.Zero()
+	for _, value := range e.ByType {
+		total = total.Add(value)
+	}
+	return total
+}
+
+// add attributes a position's value to its type, venue and tags.
+func (e *Exposure) add(position strategy.Position, tags strategy.Tags, value primitives.Decimal) {
+	if e.ByType == nil {
+		e.ByType = make(map[strategy.PositionType]primitives.Decimal)
+		e.ByVenue = make(map[string]primitives.Decimal)
+	}
+	venue := UnknownVenue
+	if described, ok := position.(strategy.PositionMetadata); ok {
+		venue = described.Venue()
+	}
+	e.ByType[position.Type()] = e.ByType[position.Type()].Add(value)
+	e.ByVenue[venue] = e.ByVenue[venue].Add(value)
+	for key, tag := range tags {
+		if e.ByTag == nil {
+			e.ByTag = make(map[string]map[string]primitives.Decimal)
+		}
+		if e.ByTag[key] == nil {
+			e.ByTag[key] = make(map[string]primitives.Decimal)
+		}
+		e.ByTag[key][tag] = e.ByTag[key][tag].Add(value)
+	}
+}
+
+// closeTags attributes the value of positions lacking each tag key to
+// strategy.Untagged, once every position has been added.
+func (e *Exposure) closeTags() {
+	invested := e.Invested()
+	for _, groups := range e.ByTag {
+		untagged := invested
+		for _, value := range groups {
+			untagged = untagged.Sub(value)
+		}
+		if !untagged.IsZero() {
+			groups[strategy.Untagged] = groups[strategy.Untagged].Add(untagged)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// GreekBreachPolicy controls how the engine enforces Config.GreekLimits.
+type GreekBreachPolicy int
+
+const (
+	// WarnOnGreekBreach only logs and records breaches
+	WarnOnGreekBreach GreekBreachPolicy = iota
+
+	// BlockOnGreekBreach rejects a batch of strategy actions that would
+	// leave a limit breached and the Greek further from zero than before,
+	// recording it in Result.SkippedActions. Batches that reduce a breach
+	// the market caused are allowed, so the strategy can always de-risk.
+	BlockOnGreekBreach
+
+	// HedgeOnGreekBreach calls Config.Hedge when the book is in breach
+	// after a snapshot's actions and applies the returned actions at once,
+	// ignoring Config.Latency, under Config.OnActionError
+	HedgeOnGreekBreach
+)
+
+// HedgeFunc returns actions that bring the book back within its Greek
+// limits, given the breaches found at snapshot.
+type HedgeFunc func(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, breaches []strategy.GreekBreach) ([]strategy.Action, error)
+
+// GreekBreachEvent records a snapshot whose book exceeded
+// Config.GreekLimits after rebalancing.
+type GreekBreachEvent struct {
+	Snapshot int
+	Time     primitives.Time
+
+	// Breaches lists the limits exceeded before any hedge
+	Breaches []strategy.GreekBreach
+
+	// Hedge is the actions Config.Hedge returned, if it was called
+	Hedge []strategy.Action
+}
+
+// blockGreeks dry-runs a batch under BlockOnGreekBreach and returns no
+// actions, with the batch recorded as skipped, if it would breach a limit
+// further than the book already does.
+func (e *Engine) blockGreeks(
+	ctx context.Context,
+	portfolio *strategy.Portfolio,
+	snapshot strategy.MarketSnapshot,
+	index int,
+	actions []queuedAction,
+) ([]queuedAction, []SkippedAction, error) {
+	if e.config.OnGreekBreach != BlockOnGreekBreach || e.config.GreekLimits.IsZero() || len(actions) == 0 {
+		return actions, nil, nil
+	}
+	before, err := portfolio.Greeks(snapshot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate greeks at snapshot %d: %w", index, err)
+	}
+
+	batch := make([]strategy.Action, len(actions))
+	for n, queued := range actions {
+		batch[n] = queued.action
+	}
+	var blocked *strategy.GreekBreach
+	var greeksErr error
+	portfolio.Validate(batch, snapshot, strategy.ConstraintFunc(func(after *strategy.Portfolio, snapshot strategy.MarketSnapshot) error {
+		greeks, err := after.Greeks(snapshot)
+		if err != nil {
+			greeksErr = err
+			return err
+		}
+		for _, breach := range e.config.GreekLimits.Breaches(greeks) {
+			if breach.Value.Abs().GreaterThan(before.Value(breach.Greek, breach.Underlying).Abs()) {
+				blocked = &breach
+				return breach
+			}
+		}
+		return nil
+	}))
+	if greeksErr != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate greeks at snapshot %d: %w", index, greeksErr)
+	}
+	if blocked == nil {
+		return actions, nil, nil
+	}
+
+	failure := SkippedAction{
+		Snapshot:  index,
+		Time:      snapshot.Time(),
+		Index:     -1,
+		Submitted: actions[0].submitted,
+		Err:       fmt.Errorf("batch of %d actions blocked: %w", len(actions), *blocked),
+	}
+	e.logSkipped(ctx, failure)
+	return nil, []SkippedAction{failure}, nil
+}
+
+// enforceGreeks checks the book against Config.GreekLimits after a
+// snapshot's actions, logging any breach and hedging it under
+// HedgeOnGreekBreach. It returns nil when the book is within its limits.
+func (e *Engine) enforceGreeks(
+	ctx context.Context,
+	portfolio *strategy.Portfolio,
+	snapshot strategy.MarketSnapshot,
+	index int,
+) (*GreekBreachEvent, []SkippedAction, error) {
+	if e.config.GreekLimits.IsZero() {
+		return nil, nil, nil
+	}
+	greeks, err := portfolio.Greeks(snapshot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate greeks at snapshot %d: %w", index, err)
+	}
+	breaches := e.config.GreekLimits.Breaches(greeks)
+	if len(breaches) == 0 {
+		return nil, nil, nil
+	}
+	for _, breach := range breaches {
+		e.log.Warn("greek limit breached",
+			logging.KeySnapshot, index,
+			logging.KeySnapshotTime, snapshot.Time().Time(),
+			"greek", string(breach.Greek),
+			"underlying", breach.Underlying,
+			"value", breach.Value.String(),
+			"limit", breach.Limit.String())
+	}
+
+	event := &GreekBreachEvent{Snapshot: index, Time: snapshot.Time(), Breaches: breaches}
+	if e.config.OnGreekBreach != HedgeOnGreekBreach || e.config.Hedge == nil {
+		return event, nil, nil
+	}
+	actions, err := e.config.Hedge(ctx, portfolio, snapshot, breaches)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hedge failed at snapshot %d: %w", index, err)
+	}
+	event.Hedge = actions
+	queued := make([]queuedAction, len(actions))
+	for n, action := range actions {
+		queued[n] = queuedAction{action: action, submitted: index, index: -1}
+	}
+	skipped, err := e.applyActions(ctx, portfolio, snapshot, index, queued)
+	if err != nil {
+		return nil, nil, err
+	}
+	return event, skipped, nil
+}
@@ -0,0 +1,144 @@
+package backtest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// PoolImpact configures the market impact of the strategy's own trades on
+// the pools tracked under Config.States. The engine turns each applied
+// action into strategy.PoolTrade values and applies them to the pool's
+// strategy.ImpactedState in the same transaction, so a large deposit or
+// swap moves the pool's price and liquidity for every later valuation in
+// the run. How long the impact lasts against observed data is up to the
+// state (see strategy.TrackedPool.Persistence). The zero value leaves
+// tracked states to strategy.UpdateStateAction.
+type PoolImpact struct {
+	// Liquidity deposits the PoolAmounts of each
+	// strategy.PoolLiquidityPosition into its pool when the position is
+	// added or opened, and withdraws them when it is removed, closed,
+	// settled or replaced. Resizes do not move the pool.
+	Liquidity bool
+
+	// Routes maps a pair to the ID of the tracked pool its order fills
+	// trade through (e.g., "ETH/USDC": "eth-usdc"). A buy fill swaps its
+	// notional of token B into the pool, a sell fill its quantity of token
+	// A. Fill prices are set by the order book as usual; only the pool
+	// state moves.
+	Routes map[string]string
+}
+
+// enabled reports whether any trades move pools.
+func (p PoolImpact) enabled() bool {
+	return p.Liquidity || len(p.Routes) > 0
+}
+
+// validate checks that every route names a tracked pool.
+func (p PoolImpact) validate(states *strategy.MechanismStates) error {
+	pairs := make([]string, 0, len(p.Routes))
+	for pair := range p.Routes {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		if _, ok := states.Get(p.Routes[pair]); !ok {
+			return fmt.Errorf("pool impact route %s names untracked pool %s", pair, p.Routes[pair])
+		}
+	}
+	return nil
+}
+
+// stateTrade is a pool trade with the ID of the state it moves.
+type stateTrade struct {
+	stateID string
+	trade   strategy.PoolTrade
+}
+
+// poolTrades returns the trades action makes against tracked pools. It is
+// called before the action is applied, so positions it removes can still
+// be read from portfolio.
+func (e *Engine) poolTrades(portfolio *strategy.Portfolio, action strategy.Action) []stateTrade {
+	impact := e.config.PoolImpact
+	switch a := action.(type) {
+	case *strategy.VenueAction:
+		return e.poolTrades(portfolio, a.Action)
+	case *strategy.BatchAction:
+		var trades []stateTrade
+		for _, inner := range a.Actions {
+			trades = append(trades, e.poolTrades(portfolio, inner)...)
+		}
+		return trades
+	case *strategy.AddPositionAction:
+		return liquidityTrade(impact, a.Position, strategy.PoolDeposit)
+	case *strategy.OpenPositionAction:
+		return liquidityTrade(impact, a.Position, strategy.PoolDeposit)
+	case *strategy.RemovePositionAction:
+		return e.withdrawal(portfolio, a.PositionID)
+	case *strategy.ClosePositionAction:
+		return e.withdrawal(portfolio, a.PositionID)
+	case *strategy.SettlePositionAction:
+		return e.withdrawal(portfolio, a.PositionID)
+	case *strategy.ReplacePositionAction:
+		return append(e.withdrawal(portfolio, a.OldPositionID), liquidityTrade(impact, a.NewPosition, strategy.PoolDeposit)...)
+	case *strategy.FillAction:
+		stateID, ok := impact.Routes[a.Fill.Pair]
+		if !ok {
+			return nil
+		}
+		trade := strategy.PoolTrade{Kind: strategy.PoolSwap, Side: a.Fill.Side}
+		if a.Fill.Side == mechanisms.OrderSideSell {
+			trade.Amounts.AmountA = primitives.MustAmount(a.Fill.Quantity)
+		} else {
+			trade.Amounts.AmountB = primitives.MustAmount(a.Fill.Notional())
+		}
+		return []stateTrade{{stateID: stateID, trade: trade}}
+	}
+	return nil
+}
+
+// withdrawal returns the withdrawal of the held position positionID from
+// its pool, if it provides liquidity to one.
+func (e *Engine) withdrawal(portfolio *strategy.Portfolio, positionID string) []stateTrade {
+	position, err := portfolio.GetPosition(positionID)
+	if err != nil {
+		// The action fails on its own
+		return nil
+	}
+	return liquidityTrade(e.config.PoolImpact, position, strategy.PoolWithdraw)
+}
+
+// liquidityTrade returns a deposit or withdrawal of position's pool
+// amounts when position provides liquidity to a tracked pool.
+func liquidityTrade(impact PoolImpact, position strategy.Position, kind strategy.PoolTradeKind) []stateTrade {
+	provider, ok := position.(strategy.PoolLiquidityPosition)
+	if !impact.Liquidity || !ok {
+		return nil
+	}
+	return []stateTrade{{
+		stateID: provider.TrackedPoolID(),
+		trade:   strategy.PoolTrade{Kind: kind, Amounts: provider.PoolAmounts()},
+	}}
+}
+
+// applyPoolTrades applies trades to the tracked states. Returns
+// strategy.ErrStateNotFound if a trade names an untracked pool.
+func (e *Engine) applyPoolTrades(trades []stateTrade) error {
+	for _, t := range trades {
+		state, ok := e.states.Get(t.stateID)
+		if !ok {
+			return fmt.Errorf("%w: pool %s is not tracked", strategy.ErrStateNotFound, t.stateID)
+		}
+		impacted, ok := state.(strategy.ImpactedState)
+		if !ok {
+			return fmt.Errorf("mechanism state %s (%T) does not accept trades", t.stateID, state)
+		}
+		if err := impacted.ApplyTrade(t.trade); err != nil {
+			return fmt.Errorf("failed to apply impact on pool %s: %w", t.stateID, err)
+		}
+	}
+	return nil
+}
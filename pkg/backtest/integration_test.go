@@ -534,7 +534,6 @@ func TestIntegrationValidation(t *testing.T) {
 
 // BenchmarkMultiMechanismStrategy benchmarks performance with multiple mechanisms.
 func BenchmarkMultiMechanismStrategy(b *testing.B) {
-	snapshot := createIntegrationSnapshot()
 	lpPos := createLPPosition(&testing.T{})
 	optionPos := createOptionPosition(&testing.T{})
 	perpPos := createPerpPosition(&testing.T{})
@@ -548,8 +547,14 @@ func BenchmarkMultiMechanismStrategy(b *testing.B) {
 	config := backtest.DefaultConfig()
 	engine := backtest.NewEngine(config)
 
-	snapshots := []strategy.MarketSnapshot{snapshot, snapshot, snapshot, snapshot, snapshot}
+	// Distinct timestamps: metrics need a non-zero period
+	start := time.Now()
+	snapshots := make([]strategy.MarketSnapshot, 5)
+	for i := range snapshots {
+		snapshots[i] = createIntegrationSnapshotAtTime(start.Add(time.Duration(i) * 7 * 24 * time.Hour))
+	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, err := engine.Run(context.Background(), strat, snapshots)
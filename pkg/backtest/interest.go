@@ -0,0 +1,119 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/accounting"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// CashYield accrues interest on a positive cash balance between
+// snapshots, as idle stablecoins would earn in sDAI or T-bills. The zero
+// value earns nothing.
+type CashYield struct {
+	// Rate is the yield earned, e.g.
+	// primitives.NewAnnualRate(0.05, primitives.CompoundingContinuous)
+	Rate primitives.Rate
+
+	// RateKey, when set, names snapshot metadata holding the annual yield
+	// as a fraction (see snapshotkeys.RateCashYield), compounded as Rate.
+	// Snapshots without it earn Rate.
+	RateKey string
+}
+
+// rateAt returns the yield in effect from snapshot until the next one.
+func (y CashYield) rateAt(snapshot strategy.MarketSnapshot) (primitives.Rate, error) {
+	return metadataRate(snapshot, y.RateKey, y.Rate)
+}
+
+// Financing charges interest on a negative cash balance between
+// snapshots, the cost of the borrowing that funds leverage. The zero value
+// charges nothing.
+type Financing struct {
+	// Rate is the borrow rate charged, e.g.
+	// primitives.NewAnnualRate(0.08, primitives.CompoundingContinuous)
+	Rate primitives.Rate
+
+	// RateKey, when set, names snapshot metadata holding the annual borrow
+	// rate as a fraction (see snapshotkeys.RateBorrow), compounded as Rate.
+	// Snapshots without it charge Rate.
+	RateKey string
+}
+
+// rateAt returns the borrow rate in effect from snapshot until the next
+// one.
+func (f Financing) rateAt(snapshot strategy.MarketSnapshot) (primitives.Rate, error) {
+	return metadataRate(snapshot, f.RateKey, f.Rate)
+}
+
+// metadataRate returns the annual rate snapshot holds under key,
+// compounded as fallback, or fallback if it holds none.
+func metadataRate(snapshot strategy.MarketSnapshot, key string, fallback primitives.Rate) (primitives.Rate, error) {
+	if key == "" {
+		return fallback, nil
+	}
+	if _, ok := snapshot.Get(key); !ok {
+		return fallback, nil
+	}
+	annual, err := strategy.GetDecimal(snapshot, key)
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	return primitives.NewAnnualRate(annual, fallback.Compounding()), nil
+}
+
+// accrueCash credits interest earned on positive cash under
+// Config.CashYield, or charges interest on negative cash under
+// Config.Financing, from the previous snapshot to snapshot at the rate in
+// effect at the previous one. It returns the interest earned and the
+// financing cost, one of which is zero.
+func (e *Engine) accrueCash(ctx context.Context, portfolio *strategy.Portfolio, previous, snapshot strategy.MarketSnapshot) (interest, financing primitives.Decimal, err error) {
+	interest, financing = primitives.Zero(), primitives.Zero()
+	cash := portfolio.CashDecimal()
+	var rate primitives.Rate
+	var account, reason string
+	switch {
+	case cash.IsPositive():
+		rate, err = e.config.CashYield.rateAt(previous)
+		account, reason = accounting.AccountInterestPnL, "cash interest"
+	case cash.IsNegative():
+		rate, err = e.config.Financing.rateAt(previous)
+		account, reason = accounting.AccountFinancingPnL, "financing"
+	default:
+		return interest, financing, nil
+	}
+	if err != nil {
+		return interest, financing, fmt.Errorf("%s rate: %w", reason, err)
+	}
+	if rate.Period().Duration() <= 0 || rate.Fraction().IsZero() {
+		return interest, financing, nil
+	}
+
+	// Interest on a negative balance is negative: a charge
+	accrued := cash.Mul(rate.Over(snapshot.Time().Sub(previous.Time())))
+	if accrued.IsZero() {
+		return interest, financing, nil
+	}
+	if err := portfolio.AdjustCash(accrued); err != nil {
+		return interest, financing, err
+	}
+	if e.config.Ledger != nil {
+		if err := e.config.Ledger.AccrueCash(snapshot.Time(), reason, account, accrued); err != nil {
+			return interest, financing, err
+		}
+	}
+	if e.log.Enabled(ctx, slog.LevelDebug) {
+		e.log.LogAttrs(ctx, slog.LevelDebug, reason,
+			slog.Time(logging.KeySnapshotTime, snapshot.Time().Time()),
+			slog.String("rate", rate.String()),
+			slog.String("amount", accrued.String()))
+	}
+	if cash.IsNegative() {
+		return interest, accrued.Neg(), nil
+	}
+	return accrued, financing, nil
+}
@@ -0,0 +1,279 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Leverage band names reported by KellyReport.
+const (
+	// LeverageBandConservative runs up to half-Kelly: at least three
+	// quarters of the optimal growth for a quarter of the variance
+	LeverageBandConservative = "conservative"
+
+	// LeverageBandAggressive runs from half- to full Kelly: growth still
+	// rises, but little, while variance quadruples
+	LeverageBandAggressive = "aggressive"
+
+	// LeverageBandOverbet runs from full to twice Kelly, where growth falls
+	// back to zero; leverage here only adds risk
+	LeverageBandOverbet = "overbet"
+)
+
+// KellyConfig configures KellyLeverage.
+type KellyConfig struct {
+	// MaintenanceMargin is the equity, as a fraction of notional, below
+	// which a levered position is liquidated (e.g., 0.05); zero
+	// liquidates only when equity is exhausted
+	MaintenanceMargin primitives.Decimal
+
+	// MaxLeverage is the venue's leverage limit, one over the initial
+	// margin; zero means no limit
+	MaxLeverage primitives.Decimal
+
+	// Horizon is the holding period liquidation probabilities are
+	// computed over (default 30 days)
+	Horizon primitives.Duration
+
+	// MaxLiquidationProbability is the highest liquidation probability
+	// over Horizon a recommended leverage may carry (default 0.01)
+	MaxLiquidationProbability primitives.Decimal
+}
+
+// LeveragePoint is the expected outcome of running a strategy at a
+// leverage.
+type LeveragePoint struct {
+	Leverage primitives.Decimal
+
+	// Growth is the annualized expected log growth rate, L·μ - L²σ²/2
+	Growth primitives.Decimal
+
+	// LiquidationProbability is the probability that equity falls to the
+	// maintenance margin within the horizon
+	LiquidationProbability primitives.Decimal
+}
+
+// LeverageBand is a range of leverage with similar growth and risk
+// character, evaluated at its upper end.
+type LeverageBand struct {
+	Name string
+	Min  primitives.Decimal
+	Max  primitives.Decimal
+	LeveragePoint
+}
+
+// KellyReport is the growth-optimal leverage analysis of a return stream.
+// Leverage scales the stream's returns, funding and fee income included,
+// which are modeled as geometric Brownian motion with the estimated drift
+// and volatility.
+type KellyReport struct {
+	// Drift and Volatility are the annualized mean and standard deviation
+	// of the unlevered returns
+	Drift      primitives.Decimal
+	Volatility primitives.Decimal
+
+	// FullKelly is the unconstrained growth-optimal leverage, μ/σ² (zero
+	// without a positive drift); HalfKelly is half of it
+	FullKelly primitives.Decimal
+	HalfKelly primitives.Decimal
+
+	// MarginLimit is the highest leverage the margin model allows: the
+	// lower of MaxLeverage and one over the maintenance margin (zero if
+	// neither is set)
+	MarginLimit primitives.Decimal
+
+	// LiquidationLimit is the highest leverage whose liquidation
+	// probability over the horizon is at most MaxLiquidationProbability
+	LiquidationLimit primitives.Decimal
+
+	// Optimal is the growth-optimal leverage within both limits
+	Optimal LeveragePoint
+
+	// Bands are the conservative, aggressive and overbet ranges, cut at
+	// the limits; bands entirely above them are omitted
+	Bands []LeverageBand
+
+	drift, variance, horizon, maintenance float64
+}
+
+// KellyLeverage estimates growth-optimal leverage from the run's value
+// history. Returns error if the history has fewer than three points or
+// the config is invalid.
+func (r *Result) KellyLeverage(config KellyConfig) (KellyReport, error) {
+	history := r.History()
+	if history.Len() < 3 {
+		return KellyReport{}, fmt.Errorf("insufficient value history (need at least 3 points)")
+	}
+	values := make([]float64, history.Len())
+	for i := range values {
+		values[i] = history.Value(i).Decimal().Float64()
+	}
+	period := history.Elapsed().Duration() / time.Duration(history.Len()-1)
+	return kellyLeverage(simpleReturns(values), period, config)
+}
+
+// KellyLeverageFromReturns estimates growth-optimal leverage from simple
+// returns sampled every period. Returns error if there are fewer than two
+// returns, period is not positive or the config is invalid.
+func KellyLeverageFromReturns(returns []primitives.Decimal, period primitives.Duration, config KellyConfig) (KellyReport, error) {
+	values := make([]float64, len(returns))
+	for i, ret := range returns {
+		values[i] = ret.Float64()
+	}
+	return kellyLeverage(values, period.Duration(), config)
+}
+
+func kellyLeverage(returns []float64, period time.Duration, config KellyConfig) (KellyReport, error) {
+	if len(returns) < 2 {
+		return KellyReport{}, fmt.Errorf("need at least 2 returns, got %d", len(returns))
+	}
+	if period <= 0 {
+		return KellyReport{}, fmt.Errorf("return period must be positive, got %s", period)
+	}
+	maintenance := config.MaintenanceMargin.Float64()
+	if maintenance < 0 || maintenance >= 1 {
+		return KellyReport{}, fmt.Errorf("maintenance margin must be in [0, 1), got %s", config.MaintenanceMargin)
+	}
+	if config.MaxLeverage.IsNegative() {
+		return KellyReport{}, fmt.Errorf("max leverage cannot be negative, got %s", config.MaxLeverage)
+	}
+	horizon := config.Horizon.Duration()
+	if horizon == 0 {
+		horizon = 30 * 24 * time.Hour
+	}
+	if horizon < 0 {
+		return KellyReport{}, fmt.Errorf("horizon must be positive, got %s", config.Horizon)
+	}
+	bound := 0.01
+	if !config.MaxLiquidationProbability.IsZero() {
+		bound = config.MaxLiquidationProbability.Float64()
+	}
+	if bound <= 0 || bound > 1 {
+		return KellyReport{}, fmt.Errorf("max liquidation probability must be in (0, 1], got %s", config.MaxLiquidationProbability)
+	}
+
+	var mean float64
+	for _, ret := range returns {
+		mean += ret
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, ret := range returns {
+		variance += (ret - mean) * (ret - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	if variance == 0 {
+		return KellyReport{}, fmt.Errorf("returns have no variance")
+	}
+
+	const secondsPerYear = 365.25 * 24 * 60 * 60
+	periodsPerYear := secondsPerYear / period.Seconds()
+	report := KellyReport{
+		drift:       mean * periodsPerYear,
+		variance:    variance * periodsPerYear,
+		horizon:     horizon.Seconds() / secondsPerYear,
+		maintenance: maintenance,
+	}
+	report.Drift = primitives.NewDecimalFromFloat(report.drift)
+	report.Volatility = primitives.NewDecimalFromFloat(math.Sqrt(report.variance))
+
+	full := math.Max(report.drift/report.variance, 0)
+	report.FullKelly = primitives.NewDecimalFromFloat(full)
+	report.HalfKelly = primitives.NewDecimalFromFloat(full / 2)
+
+	limit := math.Inf(1)
+	if config.MaxLeverage.IsPositive() {
+		limit = config.MaxLeverage.Float64()
+	}
+	if maintenance > 0 {
+		limit = math.Min(limit, 1/maintenance)
+	}
+	if !math.IsInf(limit, 1) {
+		report.MarginLimit = primitives.NewDecimalFromFloat(limit)
+	}
+
+	// Liquidation probability rises with leverage: bracket the bound by
+	// doubling, then bisect
+	hi := 1.0
+	for report.liquidationProbability(hi) <= bound && hi < 1e6 {
+		hi *= 2
+	}
+	lo := 0.0
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if report.liquidationProbability(mid) <= bound {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	report.LiquidationLimit = primitives.NewDecimalFromFloat(lo)
+	limit = math.Min(limit, lo)
+
+	report.Optimal = report.evaluate(math.Min(full, limit))
+	bands := []struct {
+		name     string
+		min, max float64
+	}{
+		{LeverageBandConservative, 0, full / 2},
+		{LeverageBandAggressive, full / 2, full},
+		{LeverageBandOverbet, full, 2 * full},
+	}
+	for _, b := range bands {
+		upper := math.Min(b.max, limit)
+		if upper <= b.min {
+			continue
+		}
+		report.Bands = append(report.Bands, LeverageBand{
+			Name:          b.name,
+			Min:           primitives.NewDecimalFromFloat(b.min),
+			Max:           primitives.NewDecimalFromFloat(upper),
+			LeveragePoint: report.evaluate(upper),
+		})
+	}
+	return report, nil
+}
+
+// Evaluate returns the growth rate and liquidation probability of running
+// the analyzed returns at leverage.
+func (k KellyReport) Evaluate(leverage primitives.Decimal) LeveragePoint {
+	return k.evaluate(leverage.Float64())
+}
+
+func (k KellyReport) evaluate(leverage float64) LeveragePoint {
+	return LeveragePoint{
+		Leverage:               primitives.NewDecimalFromFloat(leverage),
+		Growth:                 primitives.NewDecimalFromFloat(leverage*k.drift - leverage*leverage*k.variance/2),
+		LiquidationProbability: primitives.NewDecimalFromFloat(k.liquidationProbability(leverage)),
+	}
+}
+
+// liquidationProbability returns the probability that the unlevered value
+// path falls far enough within the horizon for equity at leverage to reach
+// the maintenance margin. Equity per unit of starting equity after a
+// return x is 1 + L·x against a notional of L·(1 + x), so liquidation
+// comes at x = -(1 - m·L) / (L·(1 - m)). The value's log follows a
+// Brownian motion with drift μ - σ²/2, whose first passage below the
+// barrier has the closed form used here.
+func (k KellyReport) liquidationProbability(leverage float64) float64 {
+	if leverage <= 0 {
+		return 0
+	}
+	if k.maintenance*leverage >= 1 {
+		return 1
+	}
+	drop := (1 - k.maintenance*leverage) / (leverage * (1 - k.maintenance))
+	if drop >= 1 {
+		return 0
+	}
+	barrier := math.Log(1 - drop)
+	nu := k.drift - k.variance/2
+	spread := math.Sqrt(k.variance * k.horizon)
+	p := numerics.NormalCDF((barrier-nu*k.horizon)/spread) +
+		math.Exp(2*nu*barrier/k.variance)*numerics.NormalCDF((barrier+nu*k.horizon)/spread)
+	return math.Min(math.Max(p, 0), 1)
+}
@@ -0,0 +1,406 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/telemetry"
+)
+
+// LimitFillPolicy controls when a resting limit order is considered
+// reached by a snapshot's prices.
+type LimitFillPolicy int
+
+const (
+	// FillOnCross fills a limit order only when the price trades through
+	// the limit, the conservative assumption that orders resting at the
+	// limit itself are behind the queue
+	FillOnCross LimitFillPolicy = iota
+
+	// FillOnTouch fills a limit order as soon as the price reaches it
+	FillOnTouch
+)
+
+// OrderConfig configures how the engine fills orders submitted with
+// strategy.SubmitOrderAction.
+//
+// Orders rest from the snapshot after they are submitted and are matched
+// against each later snapshot before the strategy rebalances. When the
+// snapshot is a strategy.BarSnapshot with a bar for the order's pair, the
+// bar's range decides whether a limit or stop was reached and orders fill
+// no worse than the open; otherwise the snapshot price is used for all
+// four. Market orders, and stops once triggered, walk the book when the
+// snapshot is a strategy.DepthSnapshot with depth for the pair, filling
+// only what the book holds. A submission the engine does not apply, because
+// it fails or is skipped under the ActionErrorPolicy or preflight, is
+// reported as strategy.OrderStatusRejected.
+type OrderConfig struct {
+	// LimitFill selects touch or cross semantics for limit orders
+	LimitFill LimitFillPolicy
+
+	// MaxParticipation caps each fill at this fraction of the bar's volume
+	// (e.g., 0.1 for 10%), leaving the rest of the order open. Zero
+	// disables the cap, as does a snapshot without a bar.
+	MaxParticipation primitives.Decimal
+}
+
+// restingOrder is an open order and its fill state.
+type restingOrder struct {
+	order     strategy.Order
+	filled    primitives.Decimal
+	submitted int
+	triggered bool
+}
+
+// remaining returns the unfilled size.
+func (r restingOrder) remaining() primitives.Decimal {
+	return r.order.Size.Decimal().Sub(r.filled)
+}
+
+// orderBook holds a run's open orders and the updates reported so far.
+type orderBook struct {
+	open     []restingOrder
+	updates  []strategy.OrderUpdate
+	notified int
+
+	// index and now are the snapshot being processed
+	index int
+	now   primitives.Time
+
+	log *slog.Logger
+}
+
+// orderBookSavepoint restores an orderBook on rollback.
+type orderBookSavepoint struct {
+	open    []restingOrder
+	updates int
+}
+
+func (b *orderBook) savepoint() orderBookSavepoint {
+	return orderBookSavepoint{open: append([]restingOrder(nil), b.open...), updates: len(b.updates)}
+}
+
+func (b *orderBook) rollbackTo(sp orderBookSavepoint) {
+	b.open = sp.open
+	b.updates = b.updates[:sp.updates]
+}
+
+// submit adds an order to the book.
+func (b *orderBook) submit(order strategy.Order) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	for _, resting := range b.open {
+		if resting.order.ID == order.ID {
+			return fmt.Errorf("%w: order %s is already open", strategy.ErrInvalidAction, order.ID)
+		}
+	}
+	b.open = append(b.open, restingOrder{order: order, filled: primitives.Zero(), submitted: b.index})
+	b.report(order, strategy.OrderStatusOpen, primitives.Zero(), nil)
+	return nil
+}
+
+// reject reports the orders submitted by actions the engine did not apply,
+// so a listener stops waiting on them. Other actions are ignored.
+func (b *orderBook) reject(actions ...queuedAction) {
+	if b == nil {
+		return
+	}
+	for _, queued := range actions {
+		if action, ok := orderAction(queued.action); ok {
+			if submit, ok := action.(*strategy.SubmitOrderAction); ok {
+				b.report(submit.Order, strategy.OrderStatusRejected, primitives.Zero(), nil)
+			}
+		}
+	}
+}
+
+// cancel removes an open order from the book.
+func (b *orderBook) cancel(orderID string) error {
+	for n, resting := range b.open {
+		if resting.order.ID == orderID {
+			b.open = append(b.open[:n:n], b.open[n+1:]...)
+			b.report(resting.order, strategy.OrderStatusCanceled, resting.filled, nil)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", strategy.ErrOrderNotFound, orderID)
+}
+
+func (b *orderBook) report(order strategy.Order, status strategy.OrderStatus, filled primitives.Decimal, fill *strategy.Fill) {
+	b.updates = append(b.updates, strategy.OrderUpdate{Order: order, Status: status, Time: b.now, Filled: filled, Fill: fill})
+
+	ctx := context.Background()
+	if !b.log.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.Int(logging.KeySnapshot, b.index),
+		slog.Time(logging.KeySnapshotTime, b.now.Time()),
+		slog.String(logging.KeyOrder, order.ID),
+		slog.String(logging.KeyPosition, order.Holding()),
+		slog.String("status", string(status)),
+		slog.String("filled", filled.String()),
+	}
+	message := "order " + string(status)
+	if fill != nil {
+		message = "order filled"
+		attrs = append(attrs, slog.String("quantity", fill.Quantity.String()), slog.String("price", fill.Price.String()))
+	}
+	b.log.LogAttrs(ctx, slog.LevelDebug, message, attrs...)
+}
+
+// notify passes updates not yet reported to the strategy's OrderListener.
+func (b *orderBook) notify(strat strategy.Strategy) {
+	if listener, ok := strat.(strategy.OrderListener); ok {
+		for _, update := range b.updates[b.notified:] {
+			listener.OnOrderUpdate(update)
+		}
+	}
+	b.notified = len(b.updates)
+}
+
+// fills returns every fill in execution order.
+func (b *orderBook) fills() []strategy.Fill {
+	var fills []strategy.Fill
+	for _, update := range b.updates {
+		if update.Fill != nil {
+			fills = append(fills, *update.Fill)
+		}
+	}
+	return fills
+}
+
+// orders returns the latest update of every order, in submission order.
+func (b *orderBook) orders() []strategy.OrderUpdate {
+	latest := make(map[string]int)
+	var orders []strategy.OrderUpdate
+	for _, update := range b.updates {
+		if n, ok := latest[update.Order.ID]; ok && orders[n].Status == strategy.OrderStatusOpen {
+			orders[n] = update
+			continue
+		}
+		latest[update.Order.ID] = len(orders)
+		orders = append(orders, update)
+	}
+	return orders
+}
+
+// orderAction returns the order submission or cancellation an action
+// carries, looking through venue routing.
+func orderAction(action strategy.Action) (strategy.Action, bool) {
+	if routed, ok := action.(*strategy.VenueAction); ok {
+		action = routed.Action
+	}
+	switch action.(type) {
+	case *strategy.SubmitOrderAction, *strategy.CancelOrderAction:
+		return action, true
+	}
+	return nil, false
+}
+
+// applyOrderAction applies a submission or cancellation to the order book.
+func (e *Engine) applyOrderAction(action strategy.Action) error {
+	switch a := action.(type) {
+	case *strategy.SubmitOrderAction:
+		return e.orders.submit(a.Order)
+	case *strategy.CancelOrderAction:
+		return e.orders.cancel(a.OrderID)
+	}
+	return nil
+}
+
+// matchOrders fills, expires and cancels open orders against a snapshot.
+// Each fill is applied as its own transaction under the configured
+// ActionErrorPolicy; an order whose fill is skipped stays open.
+func (e *Engine) matchOrders(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, index int) ([]SkippedAction, error) {
+	book := e.orders
+	var skipped []SkippedAction
+	open := book.open[:0:0]
+	for _, resting := range book.open {
+		if resting.submitted >= index {
+			open = append(open, resting)
+			continue
+		}
+		order := resting.order
+		if order.TimeInForce == mechanisms.TimeInForceGTD && snapshot.Time().After(order.ExpiryTime) {
+			book.report(order, strategy.OrderStatusExpired, resting.filled, nil)
+			continue
+		}
+
+		price, quantity, err := e.match(portfolio, snapshot, &resting)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match order %s at snapshot %d: %w", order.ID, index, err)
+		}
+		if order.TimeInForce == mechanisms.TimeInForceFOK && quantity.LessThan(resting.remaining()) {
+			book.report(order, strategy.OrderStatusCanceled, resting.filled, nil)
+			continue
+		}
+
+		if quantity.IsPositive() {
+			fill := strategy.Fill{
+				OrderID:  order.ID,
+				Pair:     order.Pair,
+				Side:     order.Side,
+				Time:     snapshot.Time(),
+				Quantity: quantity,
+				Price:    price,
+			}
+			failures, err := e.applyActions(ctx, portfolio, snapshot, index, []queuedAction{{
+				action:    strategy.NewFillAction(fill, order.Holding()),
+				submitted: resting.submitted,
+				index:     -1,
+			}})
+			if err != nil {
+				return nil, err
+			}
+			skipped = append(skipped, failures...)
+			if len(failures) == 0 {
+				e.config.Instrumentation.Add(ctx, telemetry.CounterFills, 1)
+				resting.filled = resting.filled.Add(quantity)
+				status := strategy.OrderStatusOpen
+				if !resting.remaining().IsPositive() {
+					status = strategy.OrderStatusFilled
+				}
+				book.report(order, status, resting.filled, &fill)
+				if status == strategy.OrderStatusFilled {
+					continue
+				}
+			}
+		}
+
+		if order.TimeInForce == mechanisms.TimeInForceIOC {
+			book.report(order, strategy.OrderStatusCanceled, resting.filled, nil)
+			continue
+		}
+		open = append(open, resting)
+	}
+	book.open = open
+	return skipped, nil
+}
+
+// match returns the price and quantity an order fills at on a snapshot;
+// the quantity is zero if it does not fill. Stops are marked triggered on
+// the resting order once the price reaches them.
+func (e *Engine) match(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, resting *restingOrder) (primitives.Price, primitives.Decimal, error) {
+	order := resting.order
+	none := primitives.Zero()
+
+	quantity := resting.remaining()
+	if order.Side == mechanisms.OrderSideSell {
+		held := primitives.Zero()
+		if position, err := portfolio.GetPosition(order.Holding()); err == nil {
+			if holding, ok := position.(*strategy.Holding); ok {
+				held = holding.Quantity()
+			}
+		}
+		quantity = minDecimal(quantity, held)
+	}
+	if !quantity.IsPositive() {
+		return primitives.ZeroPrice(), none, nil
+	}
+
+	bar, ok, err := barFor(snapshot, order.Pair)
+	if err != nil || !ok {
+		return primitives.ZeroPrice(), none, err
+	}
+	if bar.Volume.Decimal().IsPositive() && e.config.Orders.MaxParticipation.IsPositive() {
+		quantity = minDecimal(quantity, bar.Volume.Decimal().Mul(e.config.Orders.MaxParticipation))
+	}
+
+	buy := order.Side == mechanisms.OrderSideBuy
+	open, high, low := bar.Open.Decimal(), bar.High.Decimal(), bar.Low.Decimal()
+
+	marketable := order.Type == mechanisms.OrderTypeMarket
+	if order.Type == mechanisms.OrderTypeStopLoss || order.Type == mechanisms.OrderTypeStopLimit {
+		stop := order.StopPrice.Decimal()
+		if !resting.triggered {
+			if (buy && high.LessThan(stop)) || (!buy && low.GreaterThan(stop)) {
+				return primitives.ZeroPrice(), none, nil
+			}
+			resting.triggered = true
+			if order.Type == mechanisms.OrderTypeStopLoss {
+				// The stop becomes a market order at the stop price, or at
+				// the open if the price gapped through it
+				price := minDecimal(open, stop)
+				if buy {
+					price = maxDecimal(open, stop)
+				}
+				return fillPrice(price, quantity)
+			}
+		}
+		marketable = order.Type == mechanisms.OrderTypeStopLoss
+	}
+
+	if marketable {
+		if depthSnapshot, ok := snapshot.(strategy.DepthSnapshot); ok {
+			if depth, err := depthSnapshot.Depth(order.Pair); err == nil {
+				price, filled, err := strategy.EstimateFill(depth, order.Side, primitives.MustAmount(quantity))
+				if err != nil {
+					return primitives.ZeroPrice(), none, nil
+				}
+				return price, filled.Decimal(), nil
+			}
+		}
+		return fillPrice(open, quantity)
+	}
+
+	limit := order.Price.Decimal()
+	reached := low.LessThan(limit)
+	if !buy {
+		reached = high.GreaterThan(limit)
+	}
+	if e.config.Orders.LimitFill == FillOnTouch {
+		reached = reached || (buy && low.Equal(limit)) || (!buy && high.Equal(limit))
+	}
+	if !reached {
+		return primitives.ZeroPrice(), none, nil
+	}
+	if buy {
+		return fillPrice(minDecimal(open, limit), quantity)
+	}
+	return fillPrice(maxDecimal(open, limit), quantity)
+}
+
+// barFor returns the snapshot's bar for pair, or a flat bar at the
+// snapshot price when there is none. Returns false if the pair is not
+// priced at all.
+func barFor(snapshot strategy.MarketSnapshot, pair string) (strategy.Bar, bool, error) {
+	if bars, ok := snapshot.(strategy.BarSnapshot); ok {
+		if bar, err := bars.Bar(pair); err == nil {
+			return bar, true, nil
+		}
+	}
+	price, err := snapshot.Price(pair)
+	if err != nil {
+		return strategy.Bar{}, false, nil
+	}
+	return strategy.Bar{Open: price, High: price, Low: price, Close: price, Volume: primitives.ZeroAmount()}, true, nil
+}
+
+func fillPrice(price, quantity primitives.Decimal) (primitives.Price, primitives.Decimal, error) {
+	p, err := primitives.NewPrice(price)
+	if err != nil {
+		return primitives.ZeroPrice(), primitives.Zero(), err
+	}
+	return p, quantity, nil
+}
+
+func minDecimal(a, b primitives.Decimal) primitives.Decimal {
+	if b.LessThan(a) {
+		return b
+	}
+	return a
+}
+
+func maxDecimal(a, b primitives.Decimal) primitives.Decimal {
+	if b.GreaterThan(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,133 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Period is a calendar period that value history is bucketed into, in UTC.
+type Period int
+
+const (
+	// PeriodDay buckets by calendar day
+	PeriodDay Period = iota
+
+	// PeriodWeek buckets by ISO week, starting Monday
+	PeriodWeek
+
+	// PeriodMonth buckets by calendar month
+	PeriodMonth
+
+	// PeriodYear buckets by calendar year
+	PeriodYear
+)
+
+// String returns the period name.
+func (p Period) String() string {
+	switch p {
+	case PeriodDay:
+		return "day"
+	case PeriodWeek:
+		return "week"
+	case PeriodMonth:
+		return "month"
+	case PeriodYear:
+		return "year"
+	default:
+		return fmt.Sprintf("Period(%d)", int(p))
+	}
+}
+
+// Start returns the start of the period containing t, in UTC.
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeek:
+		// Weekday counts from Sunday; ISO weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case PeriodYear:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// PeriodReturn is the return of one calendar period, measured from the
+// last value of the previous period (or the first value of the run) to the
+// last value in the period.
+type PeriodReturn struct {
+	// Start is the start of the period
+	Start primitives.Time
+
+	// End is the time of the period's last value point
+	End primitives.Time
+
+	Return primitives.Decimal
+}
+
+// PeriodReturns is the per-period breakdown of a backtest, from
+// Result.PeriodReturns.
+type PeriodReturns struct {
+	Period  Period
+	Returns []PeriodReturn
+
+	// Best and Worst are the periods with the highest and lowest return;
+	// the earliest wins a tie
+	Best  PeriodReturn
+	Worst PeriodReturn
+
+	// HitRate is the share of periods with a positive return
+	HitRate primitives.Decimal
+}
+
+// PeriodReturns buckets the value history into calendar periods and
+// computes each period's return, the best and worst periods and the hit
+// rate. Periods without value points are omitted, and the first and last
+// periods are usually partial. Returns error if the period is unknown or
+// the history has fewer than two points.
+func (r *Result) PeriodReturns(period Period) (PeriodReturns, error) {
+	if period < PeriodDay || period > PeriodYear {
+		return PeriodReturns{}, fmt.Errorf("unknown period %s", period)
+	}
+	history := r.History()
+	if history.Len() < 2 {
+		return PeriodReturns{}, fmt.Errorf("insufficient value history (need at least 2 points)")
+	}
+
+	breakdown := PeriodReturns{Period: period}
+	base := history.Value(0).Decimal()
+	wins := 0
+	for i := 0; i < history.Len(); i++ {
+		start := period.Start(history.Time(i).Time())
+		if i < history.Len()-1 && period.Start(history.Time(i+1).Time()).Equal(start) {
+			continue
+		}
+
+		end := history.Value(i).Decimal()
+		ret := primitives.Zero()
+		if !base.IsZero() {
+			ret, _ = end.Sub(base).Div(base)
+		}
+		base = end
+
+		bucket := PeriodReturn{Start: primitives.NewTime(start), End: history.Time(i), Return: ret}
+		if len(breakdown.Returns) == 0 || ret.GreaterThan(breakdown.Best.Return) {
+			breakdown.Best = bucket
+		}
+		if len(breakdown.Returns) == 0 || ret.LessThan(breakdown.Worst.Return) {
+			breakdown.Worst = bucket
+		}
+		if ret.IsPositive() {
+			wins++
+		}
+		breakdown.Returns = append(breakdown.Returns, bucket)
+	}
+
+	breakdown.HitRate, _ = primitives.NewDecimal(int64(wins)).Div(primitives.NewDecimal(int64(len(breakdown.Returns))))
+	return breakdown, nil
+}
@@ -0,0 +1,285 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ReplicationPoint is the hedging record of one snapshot under
+// Config.TrackReplication: the residual delta the book carried out of the
+// snapshot, and the attribution of its P&L over the interval since the
+// previous snapshot (all zero at the first). The interval P&L is the value
+// change of the book as it stood after the previous snapshot's actions, so
+// trades and cash adjustments are excluded. It is split, from the Greeks
+// held over the interval, into
+//
+//	DeltaPnL    = Σ Δ·dS          the residual delta left unhedged
+//	GammaPnL    = Σ ½·Γ·dS²       the convexity lost or gained between
+//	                              discrete rebalances
+//	Carry       = Θ·days          fees, funding and other income accrued
+//	                              as theta
+//	Unexplained = PnL - the above
+type ReplicationPoint struct {
+	Time primitives.Time
+
+	// ResidualDelta is the book's net delta to each tracked underlying
+	// after the snapshot's actions
+	ResidualDelta map[string]primitives.Decimal
+
+	// ResidualNotional is the sum of the absolute residual deltas valued
+	// at the snapshot prices
+	ResidualNotional primitives.Decimal
+
+	PnL         primitives.Decimal
+	DeltaPnL    primitives.Decimal
+	GammaPnL    primitives.Decimal
+	Carry       primitives.Decimal
+	Unexplained primitives.Decimal
+
+	// ByTag attributes the interval P&L of the positions held over it by
+	// tag key, then tag value, with positions lacking a key under
+	// strategy.Untagged (see strategy.Tags); nil when none was tagged
+	ByTag map[string]map[string]TagAttribution
+}
+
+// TagAttribution is the attribution of the interval P&L of the positions
+// sharing a tag value, split as ReplicationPoint splits the book's.
+type TagAttribution struct {
+	PnL         primitives.Decimal
+	DeltaPnL    primitives.Decimal
+	GammaPnL    primitives.Decimal
+	Carry       primitives.Decimal
+	Unexplained primitives.Decimal
+}
+
+// add returns the sum of two attributions.
+func (a TagAttribution) add(other TagAttribution) TagAttribution {
+	return TagAttribution{
+		PnL:         a.PnL.Add(other.PnL),
+		DeltaPnL:    a.DeltaPnL.Add(other.DeltaPnL),
+		GammaPnL:    a.GammaPnL.Add(other.GammaPnL),
+		Carry:       a.Carry.Add(other.Carry),
+		Unexplained: a.Unexplained.Add(other.Unexplained),
+	}
+}
+
+// HedgingError returns the interval P&L the book was meant to earn but
+// did not: PnL less Carry.
+func (p ReplicationPoint) HedgingError() primitives.Decimal {
+	return p.PnL.Sub(p.Carry)
+}
+
+// ReplicationReport summarizes Result.Replication over the run.
+type ReplicationReport struct {
+	// Intervals is the number of intervals attributed
+	Intervals int
+
+	// Totals of the per-interval attribution
+	PnL         primitives.Decimal
+	DeltaPnL    primitives.Decimal
+	GammaPnL    primitives.Decimal
+	Carry       primitives.Decimal
+	Unexplained primitives.Decimal
+
+	// TrackingError is the root mean square of the per-interval
+	// HedgingError
+	TrackingError primitives.Decimal
+
+	// MeanResidualNotional and MaxResidualNotional describe the unhedged
+	// exposure carried between snapshots
+	MeanResidualNotional primitives.Decimal
+	MaxResidualNotional  primitives.Decimal
+
+	// ByTag totals the per-interval attribution by tag key and value; nil
+	// when no position was tagged
+	ByTag map[string]map[string]TagAttribution
+}
+
+// HedgingError returns the total P&L not explained by carry.
+func (r ReplicationReport) HedgingError() primitives.Decimal {
+	return r.PnL.Sub(r.Carry)
+}
+
+// NetEdge returns the carry earned net of gamma losses, the return a
+// delta-neutral book keeps if its hedge is perfect.
+func (r ReplicationReport) NetEdge() primitives.Decimal {
+	return r.Carry.Add(r.GammaPnL)
+}
+
+// ReplicationReport summarizes the replication record. Returns error if
+// the run was not made with Config.TrackReplication or covered a single
+// snapshot.
+func (r *Result) ReplicationReport() (ReplicationReport, error) {
+	if len(r.Replication) < 2 {
+		return ReplicationReport{}, fmt.Errorf("no replication record (set Config.TrackReplication and run at least 2 snapshots)")
+	}
+	report := ReplicationReport{Intervals: len(r.Replication) - 1}
+	var sumSquares, sumNotional float64
+	for i, point := range r.Replication {
+		sumNotional += point.ResidualNotional.Float64()
+		if point.ResidualNotional.GreaterThan(report.MaxResidualNotional) {
+			report.MaxResidualNotional = point.ResidualNotional
+		}
+		if i == 0 {
+			continue
+		}
+		report.PnL = report.PnL.Add(point.PnL)
+		report.DeltaPnL = report.DeltaPnL.Add(point.DeltaPnL)
+		report.GammaPnL = report.GammaPnL.Add(point.GammaPnL)
+		report.Carry = report.Carry.Add(point.Carry)
+		report.Unexplained = report.Unexplained.Add(point.Unexplained)
+		for key, groups := range point.ByTag {
+			if report.ByTag == nil {
+				report.ByTag = make(map[string]map[string]TagAttribution)
+			}
+			if report.ByTag[key] == nil {
+				report.ByTag[key] = make(map[string]TagAttribution)
+			}
+			for tag, attribution := range groups {
+				report.ByTag[key][tag] = report.ByTag[key][tag].add(attribution)
+			}
+		}
+		hedgingError := point.HedgingError().Float64()
+		sumSquares += hedgingError * hedgingError
+	}
+	report.TrackingError = primitives.NewDecimalFromFloat(math.Sqrt(sumSquares / float64(report.Intervals)))
+	report.MeanResidualNotional = primitives.NewDecimalFromFloat(sumNotional / float64(len(r.Replication)))
+	return report, nil
+}
+
+// replicationTracker holds the book as it stood after the previous
+// snapshot's actions.
+type replicationTracker struct {
+	pairs  map[string]string
+	points []ReplicationPoint
+
+	time   primitives.Time
+	value  primitives.Decimal
+	greeks strategy.BookGreeks
+	prices map[string]primitives.Decimal
+
+	// groups holds the book's tag groups by key and value
+	groups map[string]map[string]*tagGroup
+}
+
+// tagGroup is the positions sharing a tag value as they stood at the
+// previous mark.
+type tagGroup struct {
+	positions []strategy.Position
+	value     primitives.Decimal
+	greeks    strategy.BookGreeks
+}
+
+func newReplicationTracker(pairs map[string]string, capacity int) *replicationTracker {
+	return &replicationTracker{pairs: pairs, points: make([]ReplicationPoint, 0, capacity)}
+}
+
+// mark records the book after a snapshot's actions, attributing the P&L
+// from the previous mark to value, the book's value before the actions.
+func (t *replicationTracker) mark(ctx context.Context, e *Engine, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, index int, value primitives.Amount) error {
+	snapshot = e.valuationContext(ctx, snapshot)
+	prices := make(map[string]primitives.Decimal, len(t.pairs))
+	for underlying, pair := range t.pairs {
+		price, err := snapshot.Price(pair)
+		if err != nil {
+			return fmt.Errorf("failed to price %s for replication at snapshot %d: %w", underlying, index, err)
+		}
+		prices[underlying] = price.Decimal()
+	}
+
+	point := ReplicationPoint{Time: snapshot.Time(), ResidualDelta: make(map[string]primitives.Decimal, len(t.pairs))}
+	if len(t.points) > 0 {
+		book := t.attribute(t.greeks, value.Decimal().Sub(t.value), prices, snapshot.Time())
+		point.PnL, point.DeltaPnL, point.GammaPnL = book.PnL, book.DeltaPnL, book.GammaPnL
+		point.Carry, point.Unexplained = book.Carry, book.Unexplained
+		for key, groups := range t.groups {
+			if point.ByTag == nil {
+				point.ByTag = make(map[string]map[string]TagAttribution)
+			}
+			point.ByTag[key] = make(map[string]TagAttribution, len(groups))
+			for tag, group := range groups {
+				groupValue := primitives.Zero()
+				for _, position := range group.positions {
+					v, err := e.positionValue(ctx, portfolio, position, snapshot)
+					if err != nil {
+						return fmt.Errorf("failed to value position %s for replication at snapshot %d: %w", position.ID(), index, err)
+					}
+					groupValue = groupValue.Add(v)
+				}
+				point.ByTag[key][tag] = t.attribute(group.greeks, groupValue.Sub(group.value), prices, snapshot.Time())
+			}
+		}
+	}
+
+	after, err := e.calculatePortfolioValue(ctx, portfolio, snapshot, nil)
+	if err != nil {
+		return fmt.Errorf("failed to value portfolio for replication at snapshot %d: %w", index, err)
+	}
+	greeks, err := portfolio.Greeks(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate greeks at snapshot %d: %w", index, err)
+	}
+	for underlying := range t.pairs {
+		delta := greeks.NetDelta(underlying)
+		point.ResidualDelta[underlying] = delta
+		point.ResidualNotional = point.ResidualNotional.Add(delta.Mul(prices[underlying]).Abs())
+	}
+
+	groups, err := e.tagGroups(ctx, portfolio, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to group positions for replication at snapshot %d: %w", index, err)
+	}
+
+	t.points = append(t.points, point)
+	t.time, t.value, t.greeks, t.prices, t.groups = snapshot.Time(), after.Decimal(), greeks, prices, groups
+	return nil
+}
+
+// attribute splits pnl, earned since the previous mark by positions that
+// held greeks there, into its delta, gamma and carry parts.
+func (t *replicationTracker) attribute(greeks strategy.BookGreeks, pnl primitives.Decimal, prices map[string]primitives.Decimal, now primitives.Time) TagAttribution {
+	attribution := TagAttribution{PnL: pnl}
+	for underlying := range t.pairs {
+		held := greeks.ByUnderlying[underlying]
+		move := prices[underlying].Sub(t.prices[underlying])
+		attribution.DeltaPnL = attribution.DeltaPnL.Add(held.Delta.Mul(move))
+		attribution.GammaPnL = attribution.GammaPnL.Add(held.Gamma.Mul(move).Mul(move).Mul(primitives.NewDecimalFromFloat(0.5)))
+	}
+	days := primitives.NewDecimalFromFloat(now.Sub(t.time).Hours() / 24)
+	attribution.Carry = greeks.Total.Theta.Mul(days)
+	attribution.Unexplained = pnl.Sub(attribution.DeltaPnL).Sub(attribution.GammaPnL).Sub(attribution.Carry)
+	return attribution
+}
+
+// tagGroups returns the portfolio's positions grouped by each tag key in
+// use, with their values and Greeks at snapshot; nil when none is tagged.
+func (e *Engine) tagGroups(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) (map[string]map[string]*tagGroup, error) {
+	keys := portfolio.TagKeys()
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	groups := make(map[string]map[string]*tagGroup, len(keys))
+	for _, key := range keys {
+		greeks, err := portfolio.GreeksByTag(snapshot, key)
+		if err != nil {
+			return nil, err
+		}
+		groups[key] = make(map[string]*tagGroup)
+		for tag, positions := range portfolio.GroupByTag(key) {
+			group := &tagGroup{positions: positions, value: primitives.Zero(), greeks: greeks[tag]}
+			for _, position := range positions {
+				v, err := e.positionValue(ctx, portfolio, position, snapshot)
+				if err != nil {
+					return nil, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
+				}
+				group.value = group.value.Add(v)
+			}
+			groups[key][tag] = group
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,138 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Sources of requirements that are not positions, as reported in
+// MissingInput.NeededBy.
+const (
+	NeededByStrategy = "strategy"
+	NeededByConfig   = "config"
+)
+
+// MissingInput is a required input a snapshot lacks.
+type MissingInput struct {
+	Snapshot int
+	Time     primitives.Time
+	Key      strategy.DataKey
+
+	// NeededBy are the IDs of the positions requiring Key, or
+	// NeededByStrategy and NeededByConfig for the strategy's and
+	// Config.Requirements
+	NeededBy []string
+}
+
+// String describes the missing input.
+func (m MissingInput) String() string {
+	return fmt.Sprintf("%s at snapshot %d (%s), needed by %s", m.Key, m.Snapshot, m.Time, strings.Join(m.NeededBy, ", "))
+}
+
+// MissingDataError reports every required input missing from the
+// snapshots it checked. It wraps strategy.ErrMissingData.
+type MissingDataError struct {
+	Missing []MissingInput
+}
+
+// maxMissingReported caps the inputs listed by MissingDataError.Error.
+const maxMissingReported = 10
+
+// Error lists the missing inputs.
+func (e *MissingDataError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d missing", strategy.ErrMissingData, len(e.Missing))
+	for i, missing := range e.Missing {
+		if i == maxMissingReported {
+			fmt.Fprintf(&b, "; and %d more", len(e.Missing)-i)
+			break
+		}
+		b.WriteString("; ")
+		b.WriteString(missing.String())
+	}
+	return b.String()
+}
+
+// Unwrap returns strategy.ErrMissingData.
+func (e *MissingDataError) Unwrap() error {
+	return strategy.ErrMissingData
+}
+
+// fixedRequirements returns Config.Requirements and those of strat, if it
+// is strategy.DataDependent, with their sources.
+func (e *Engine) fixedRequirements(strat strategy.Strategy) map[strategy.DataKey][]string {
+	var requirements map[strategy.DataKey][]string
+	add := func(keys []strategy.DataKey, source string) {
+		for _, key := range keys {
+			if requirements == nil {
+				requirements = make(map[strategy.DataKey][]string)
+			}
+			sources := requirements[key]
+			if len(sources) == 0 || sources[len(sources)-1] != source {
+				requirements[key] = append(sources, source)
+			}
+		}
+	}
+	add(e.config.Requirements, NeededByConfig)
+	if dependent, ok := strat.(strategy.DataDependent); ok {
+		add(dependent.Requirements(), NeededByStrategy)
+	}
+	return requirements
+}
+
+// checkSnapshots checks every snapshot against the fixed requirements
+// before the run, so a gap anywhere is reported before any work is done.
+// Snapshots are checked with Config.States attached and Config.CrossRates
+// resolving, as positions see them.
+func (e *Engine) checkSnapshots(strat strategy.Strategy, snapshots []strategy.MarketSnapshot) error {
+	requirements := e.fixedRequirements(strat)
+	if len(requirements) == 0 {
+		return nil
+	}
+	var missing []MissingInput
+	for i, snapshot := range snapshots {
+		if e.config.States.Len() > 0 {
+			snapshot = e.config.States.Attach(snapshot)
+		}
+		missing = appendMissing(missing, i, e.valuationContext(context.Background(), snapshot), requirements)
+	}
+	if len(missing) > 0 {
+		return &MissingDataError{Missing: missing}
+	}
+	return nil
+}
+
+// checkPositions checks snapshot against the requirements of the held
+// positions before they are valued.
+func (e *Engine) checkPositions(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, index int) error {
+	requirements := portfolio.DataRequirements()
+	if len(requirements) == 0 {
+		return nil
+	}
+	if missing := appendMissing(nil, index, e.valuationContext(context.Background(), snapshot), requirements); len(missing) > 0 {
+		return &MissingDataError{Missing: missing}
+	}
+	return nil
+}
+
+// appendMissing appends the requirements snapshot lacks, in key order.
+func appendMissing(missing []MissingInput, index int, snapshot strategy.MarketSnapshot, requirements map[strategy.DataKey][]string) []MissingInput {
+	keys := make([]strategy.DataKey, 0, len(requirements))
+	for key := range requirements {
+		keys = append(keys, key)
+	}
+	strategy.SortDataKeys(keys)
+	for _, key := range strategy.MissingData(snapshot, keys) {
+		missing = append(missing, MissingInput{
+			Snapshot: index,
+			Time:     snapshot.Time(),
+			Key:      key,
+			NeededBy: requirements[key],
+		})
+	}
+	return missing
+}
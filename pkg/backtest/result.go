@@ -2,7 +2,6 @@ package backtest
 
 import (
 	"fmt"
-	"math"
 
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
@@ -21,12 +20,91 @@ type Result struct {
 	// FinalValue is the ending portfolio value
 	FinalValue primitives.Amount
 
-	// ValueHistory tracks portfolio value at each rebalancing point
+	// ValueHistory tracks portfolio value at each rebalancing point as
+	// boxed points. It is nil when Config.ColumnarHistory is set.
+	//
+	// Deprecated: use History, which works under every Config.
 	ValueHistory []ValuePoint
 
+	// series is the value history recorded by the engine
+	series *ValueSeries
+
+	// postSeries is the post-action value history under
+	// ValueBeforeAndAfterActions, and valuation the timing of series
+	postSeries *ValueSeries
+	valuation  ValuationTiming
+
+	// snapshots are the snapshots of the run, one per History point, for
+	// Rebase
+	snapshots []strategy.MarketSnapshot
+
 	// Portfolio is the final portfolio state after backtest completion
 	Portfolio *strategy.Portfolio
 
+	// ExposureHistory breaks the value at each rebalancing point down by
+	// position type and venue; nil unless Config.RecordExposure is set
+	ExposureHistory []Exposure
+
+	// SkippedActions lists actions rolled back under a skip
+	// Config.OnActionError policy (empty when aborting on error)
+	SkippedActions []SkippedAction
+
+	// GreekBreaches lists the snapshots whose book exceeded
+	// Config.GreekLimits after rebalancing, in order
+	GreekBreaches []GreekBreachEvent
+
+	// PendingActions lists latency-delayed actions that had not reached
+	// their venue when the snapshots ran out (see Config.Latency)
+	PendingActions []strategy.Action
+
+	// Fills lists every order fill in execution order
+	Fills []strategy.Fill
+
+	// Orders holds the final update of every submitted order, in submission
+	// order; orders still resting at the end have status open
+	Orders []strategy.OrderUpdate
+
+	// Replication holds a hedging record per snapshot under
+	// Config.TrackReplication; see ReplicationReport
+	Replication []ReplicationPoint
+
+	// FXHistory holds, for each Config.Denominations currency, the units
+	// of it one unit of the base currency was worth at each History point;
+	// see Denominated
+	FXHistory map[string][]primitives.Decimal
+
+	// CashInterest is the total interest credited under Config.CashYield
+	CashInterest primitives.Decimal
+
+	// FinancingCost is the total interest charged on negative cash under
+	// Config.Financing, as a positive amount
+	FinancingCost primitives.Decimal
+
+	// PnL splits the run's P&L, FinalValue less the initial cash, into
+	// the realized P&L of closed positions and cash flows and the
+	// unrealized P&L of the positions still open, in the base currency
+	// (see strategy.PnL)
+	PnL strategy.PnL
+
+	// WarmUp is the number of snapshots in the Config.WarmUp window,
+	// excluded from History and the metrics
+	WarmUp int
+
+	// AuditFindings lists the nondeterminism detected under Config.Audit
+	AuditFindings []AuditFinding
+
+	// Degradations lists the positions valued at a stale value or excluded
+	// under Config.OnValuationError, once per position and snapshot
+	Degradations []Degradation
+
+	// States holds the mechanism states tracked under Config.States as they
+	// stood at the end of the run; nil when none were registered
+	States *strategy.MechanismStates
+
+	// Experiment records what produced the run under Config.Experiment;
+	// nil otherwise
+	Experiment *Experiment
+
 	// Calculated metrics (populated by calculateMetrics)
 	TotalReturn       primitives.Decimal // Total return as decimal (e.g., 0.15 = 15%)
 	AnnualizedReturn  primitives.Decimal // Annualized return
@@ -41,6 +119,119 @@ type ValuePoint struct {
 	Value primitives.Amount
 }
 
+// History returns the portfolio value at each rebalancing point. It is
+// the one accessor for value history whatever Config.ColumnarHistory is
+// set to; for a Result built by hand it is derived from ValueHistory.
+func (r *Result) History() *ValueSeries {
+	if r.series != nil {
+		return r.series
+	}
+	return newValueSeriesFrom(r.ValueHistory)
+}
+
+// PreActionHistory returns the values recorded before each snapshot's
+// actions, or nil if Config.Valuation recorded only values after them.
+func (r *Result) PreActionHistory() *ValueSeries {
+	if r.valuation == ValueAfterActions {
+		return nil
+	}
+	return r.History()
+}
+
+// PostActionHistory returns the values recorded after each snapshot's
+// actions, or nil if Config.Valuation recorded only values before them.
+func (r *Result) PostActionHistory() *ValueSeries {
+	switch r.valuation {
+	case ValueAfterActions:
+		return r.History()
+	case ValueBeforeAndAfterActions:
+		return r.postSeries
+	}
+	return nil
+}
+
+// Denominated returns the result restated in currency, one of
+// Config.Denominations: the value history, initial and final values and
+// every metric are converted at the rate recorded at each point, so
+// returns include the base currency's move against it (e.g., the
+// ETH-denominated return of a USD book). Other records are shared with r
+// and stay in the base currency. Returns error if no rates were recorded
+// for currency.
+func (r *Result) Denominated(currency string) (*Result, error) {
+	rates := r.FXHistory[currency]
+	if len(rates) == 0 || len(rates) != r.History().Len() {
+		return nil, fmt.Errorf("no %s rates recorded (add it to Config.Denominations)", currency)
+	}
+	return r.restate(currency, func(i int, value primitives.Amount) (primitives.Amount, error) {
+		return value.Mul(rates[i]), nil
+	})
+}
+
+// Rebase returns the result denominated in the base asset of pair, a pair
+// quoted in the portfolio's currency such as "ETH/USD": each value is
+// divided by the pair's price at its snapshot, and the metrics are
+// recomputed, so a positive TotalReturn means the strategy outperformed
+// holding the asset. Unlike Denominated it needs no configuration, but
+// only a Result returned by Engine.Run holds the snapshots it prices
+// from. Other records are shared with r and stay in the portfolio's
+// currency. Returns error if pair is not quoted at some snapshot.
+func (r *Result) Rebase(pair string) (*Result, error) {
+	if len(r.snapshots) == 0 || len(r.snapshots) != r.History().Len() {
+		return nil, fmt.Errorf("result has no snapshot price history to rebase to %s", pair)
+	}
+	return r.restate(pair, func(i int, value primitives.Amount) (primitives.Amount, error) {
+		price, err := r.snapshots[i].Price(pair)
+		if err != nil {
+			return primitives.Amount{}, fmt.Errorf("failed to price %s at snapshot %d: %w", pair, i, err)
+		}
+		return value.DivPrice(price)
+	})
+}
+
+// restate returns a copy of r with the value history, initial and final
+// values converted by convert, which receives each History index, and the
+// metrics recomputed.
+func (r *Result) restate(unit string, convert func(i int, value primitives.Amount) (primitives.Amount, error)) (*Result, error) {
+	history := r.History()
+	last := history.Len() - 1
+	converted := *r
+	var err error
+	if converted.InitialValue, err = convert(0, r.InitialValue); err != nil {
+		return nil, err
+	}
+	if converted.FinalValue, err = convert(last, r.FinalValue); err != nil {
+		return nil, err
+	}
+	if converted.series, err = restateSeries(history, convert); err != nil {
+		return nil, err
+	}
+	if r.postSeries != nil {
+		if converted.postSeries, err = restateSeries(r.postSeries, convert); err != nil {
+			return nil, err
+		}
+	}
+	if r.ValueHistory != nil {
+		converted.ValueHistory = converted.series.Points()
+	}
+	if err := converted.calculateMetrics(); err != nil {
+		return nil, fmt.Errorf("failed to calculate %s metrics: %w", unit, err)
+	}
+	return &converted, nil
+}
+
+// restateSeries returns series with each value converted.
+func restateSeries(series *ValueSeries, convert func(i int, value primitives.Amount) (primitives.Amount, error)) (*ValueSeries, error) {
+	converted := NewValueSeries(series.Len())
+	for i := 0; i < series.Len(); i++ {
+		value, err := convert(i, series.Value(i))
+		if err != nil {
+			return nil, err
+		}
+		converted.Append(ValuePoint{Time: series.Time(i), Value: value})
+	}
+	return converted, nil
+}
+
 // calculateMetrics computes derived performance metrics from the backtest results.
 // This method is called automatically by Engine.Run() after backtest completion.
 //
@@ -54,7 +245,7 @@ func (r *Result) calculateMetrics() error {
 	if r.InitialValue.IsZero() {
 		return fmt.Errorf("initial value cannot be zero")
 	}
-	if len(r.ValueHistory) < 2 {
+	if r.History().Len() < 2 {
 		return fmt.Errorf("insufficient value history (need at least 2 points)")
 	}
 
@@ -88,14 +279,13 @@ func (r *Result) calculateMetrics() error {
 // calculateAnnualizedReturn computes the annualized return based on the time period.
 // Formula: AnnualizedReturn = (1 + TotalReturn)^(365.25*24*60*60 / period_seconds) - 1
 func (r *Result) calculateAnnualizedReturn() error {
-	if len(r.ValueHistory) < 2 {
+	history := r.History()
+	if history.Len() < 2 {
 		return fmt.Errorf("insufficient history")
 	}
 
 	// Get time period in seconds
-	startTime := r.ValueHistory[0].Time
-	endTime := r.ValueHistory[len(r.ValueHistory)-1].Time
-	periodSeconds := endTime.Sub(startTime).Seconds()
+	periodSeconds := history.Elapsed().Seconds()
 
 	if periodSeconds <= 0 {
 		return fmt.Errorf("invalid time period: %f seconds", periodSeconds)
@@ -104,15 +294,24 @@ func (r *Result) calculateAnnualizedReturn() error {
 	// Seconds in a year (accounting for leap years)
 	const secondsPerYear = 365.25 * 24 * 60 * 60
 
-	// Convert to float64 for exponentiation (necessary for annualization)
-	// Note: This is acceptable here as we're calculating a percentage, not a dollar amount
-	totalReturnFloat := r.TotalReturn.Float64()
-	exponent := secondsPerYear / periodSeconds
+	// A total loss annualizes to a total loss
+	growth := primitives.One().Add(r.TotalReturn)
+	if !growth.IsPositive() {
+		r.AnnualizedReturn = primitives.NewDecimal(-1)
+		return nil
+	}
 
 	// Calculate: (1 + TotalReturn)^(secondsPerYear/periodSeconds) - 1
-	annualizedFloat := math.Pow(1+totalReturnFloat, exponent) - 1
+	exponent, err := primitives.NewDecimalFromFloat(secondsPerYear).Div(primitives.NewDecimalFromFloat(periodSeconds))
+	if err != nil {
+		return fmt.Errorf("failed to calculate annualization exponent: %w", err)
+	}
+	annualized, err := growth.Pow(exponent)
+	if err != nil {
+		return fmt.Errorf("annualized return overflows over %f seconds: %w", periodSeconds, err)
+	}
 
-	r.AnnualizedReturn = primitives.NewDecimalFromFloat(annualizedFloat)
+	r.AnnualizedReturn = annualized.Sub(primitives.One())
 	return nil
 }
 
@@ -120,15 +319,18 @@ func (r *Result) calculateAnnualizedReturn() error {
 // Formula: Sharpe = Mean(returns) / StdDev(returns) * sqrt(periods_per_year)
 // Assumes risk-free rate = 0
 func (r *Result) calculateSharpe() error {
-	if len(r.ValueHistory) < 2 {
+	history := r.History()
+	n := history.Len()
+	if n < 2 {
 		return fmt.Errorf("insufficient history for Sharpe calculation")
 	}
 
 	// Calculate point-to-point returns
-	returns := make([]primitives.Decimal, 0, len(r.ValueHistory)-1)
-	for i := 1; i < len(r.ValueHistory); i++ {
-		prevValue := r.ValueHistory[i-1].Value.Decimal()
-		currValue := r.ValueHistory[i].Value.Decimal()
+	returns := make([]primitives.Decimal, 0, n-1)
+	currValue := history.Value(0).Decimal()
+	for i := 1; i < n; i++ {
+		prevValue := currValue
+		currValue = history.Value(i).Decimal()
 
 		if prevValue.IsZero() {
 			continue // Skip if previous value is zero
@@ -169,9 +371,7 @@ func (r *Result) calculateSharpe() error {
 		return fmt.Errorf("failed to calculate variance: %w", err)
 	}
 
-	// Convert to float for sqrt (standard deviation calculation)
-	varianceFloat := variance.Float64()
-	stdDev := primitives.NewDecimalFromFloat(math.Sqrt(varianceFloat))
+	stdDev := variance.Sqrt()
 
 	if stdDev.IsZero() {
 		// Zero volatility means infinite Sharpe, but we'll set to zero
@@ -180,19 +380,22 @@ func (r *Result) calculateSharpe() error {
 	}
 
 	// Calculate average time between snapshots (for annualization)
-	totalSeconds := r.ValueHistory[len(r.ValueHistory)-1].Time.Sub(r.ValueHistory[0].Time).Seconds()
+	totalSeconds := history.Elapsed().Seconds()
 	avgSecondsPerPeriod := totalSeconds / float64(len(returns))
 
 	// Periods per year
 	const secondsPerYear = 365.25 * 24 * 60 * 60
-	periodsPerYear := secondsPerYear / avgSecondsPerPeriod
+	periodsPerYear, err := primitives.NewDecimalFromFloat(secondsPerYear).Div(primitives.NewDecimalFromFloat(avgSecondsPerPeriod))
+	if err != nil {
+		return fmt.Errorf("failed to calculate periods per year: %w", err)
+	}
 
 	// Sharpe = (Mean / StdDev) * sqrt(periods_per_year)
 	sharpeRaw, err := mean.Div(stdDev)
 	if err != nil {
 		return fmt.Errorf("failed to calculate Sharpe: %w", err)
 	}
-	annualizationFactor := primitives.NewDecimalFromFloat(math.Sqrt(periodsPerYear))
+	annualizationFactor := periodsPerYear.Sqrt()
 	sharpe := sharpeRaw.Mul(annualizationFactor)
 
 	r.Sharpe = sharpe
@@ -202,16 +405,18 @@ func (r *Result) calculateSharpe() error {
 // calculateMaxDrawdown computes the maximum peak-to-trough decline.
 // Drawdown = (Trough - Peak) / Peak
 func (r *Result) calculateMaxDrawdown() error {
-	if len(r.ValueHistory) < 2 {
+	history := r.History()
+	n := history.Len()
+	if n < 2 {
 		return fmt.Errorf("insufficient history")
 	}
 
 	maxDrawdown := primitives.Zero()
 	maxDrawdownAmount := primitives.Zero()
-	peak := r.ValueHistory[0].Value.Decimal()
+	peak := history.Value(0).Decimal()
 
-	for i := 1; i < len(r.ValueHistory); i++ {
-		currentValue := r.ValueHistory[i].Value.Decimal()
+	for i := 1; i < n; i++ {
+		currentValue := history.Value(i).Decimal()
 
 		// Update peak if we've reached a new high
 		if currentValue.GreaterThan(peak) {
@@ -266,6 +471,6 @@ func (r *Result) Summary() string {
 		r.Sharpe.Float64(),
 		maxDDPct.Float64(),
 		r.MaxDrawdownAmount.String(),
-		len(r.ValueHistory),
+		r.History().Len(),
 	)
 }
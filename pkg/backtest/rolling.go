@@ -0,0 +1,114 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// RollingPoint is a rolling statistic at the end of its window.
+type RollingPoint struct {
+	Time  primitives.Time
+	Value primitives.Decimal
+}
+
+// RollingMetrics holds statistics over a trailing time window, one point
+// per value point from the first at which a full window of history exists.
+// Each window covers the points no older than Window before its end.
+type RollingMetrics struct {
+	Window primitives.Duration
+
+	// Sharpe is the annualized Sharpe ratio, computed like Result.Sharpe
+	Sharpe []RollingPoint
+
+	// Volatility is the annualized standard deviation of returns
+	Volatility []RollingPoint
+
+	// Drawdown is the maximum peak-to-trough decline within the window
+	Drawdown []RollingPoint
+}
+
+// RollingMetrics computes rolling Sharpe, volatility and drawdown over
+// window from the value history, showing when a strategy worked rather
+// than only its end-of-period aggregates. The series are empty when the
+// run is shorter than window. Returns error if window is not positive or
+// the history has fewer than two points.
+func (r *Result) RollingMetrics(window primitives.Duration) (RollingMetrics, error) {
+	if window.Duration() <= 0 {
+		return RollingMetrics{}, fmt.Errorf("rolling window must be positive, got %s", window)
+	}
+	history := r.History()
+	if history.Len() < 2 {
+		return RollingMetrics{}, fmt.Errorf("insufficient value history (need at least 2 points)")
+	}
+
+	metrics := RollingMetrics{Window: window}
+	span := window.Duration().Nanoseconds()
+	values := make([]float64, history.Len())
+	for i := range values {
+		values[i] = history.Value(i).Decimal().Float64()
+	}
+
+	start := 0
+	for i := 1; i < history.Len(); i++ {
+		end := history.times[i]
+		if end-history.times[0] < span {
+			continue
+		}
+		for end-history.times[start] > span {
+			start++
+		}
+
+		returns := simpleReturns(values[start : i+1])
+		elapsed := time.Duration(end - history.times[start]).Seconds()
+		sharpe, volatility := annualizedStats(returns, elapsed)
+		at := history.Time(i)
+		metrics.Sharpe = append(metrics.Sharpe, RollingPoint{Time: at, Value: primitives.NewDecimalFromFloat(sharpe)})
+		metrics.Volatility = append(metrics.Volatility, RollingPoint{Time: at, Value: primitives.NewDecimalFromFloat(volatility)})
+		metrics.Drawdown = append(metrics.Drawdown, RollingPoint{Time: at, Value: primitives.NewDecimalFromFloat(maxDrawdown(values[start : i+1]))})
+	}
+	return metrics, nil
+}
+
+// annualizedStats returns the annualized Sharpe ratio and volatility of
+// returns spanning elapsed seconds, assuming a zero risk-free rate. Both
+// are zero with fewer than two returns; the Sharpe ratio is zero with no
+// volatility.
+func annualizedStats(returns []float64, elapsed float64) (sharpe, volatility float64) {
+	if len(returns) < 2 || elapsed <= 0 {
+		return 0, 0
+	}
+	var mean float64
+	for _, ret := range returns {
+		mean += ret
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, ret := range returns {
+		variance += (ret - mean) * (ret - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)))
+
+	const secondsPerYear = 365.25 * 24 * 60 * 60
+	annualization := math.Sqrt(secondsPerYear / (elapsed / float64(len(returns))))
+	if stdDev == 0 {
+		return 0, 0
+	}
+	return mean / stdDev * annualization, stdDev * annualization
+}
+
+// maxDrawdown returns the largest peak-to-trough decline of values as a
+// fraction of the peak.
+func maxDrawdown(values []float64) float64 {
+	var peak, worst float64
+	for _, v := range values {
+		peak = math.Max(peak, v)
+		if peak > 0 {
+			worst = math.Max(worst, (peak-v)/peak)
+		}
+	}
+	return worst
+}
@@ -0,0 +1,250 @@
+package backtest
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DefaultRollingWindow is the rolling Sharpe window NewTearSheet uses when
+// none is given.
+var DefaultRollingWindow = primitives.NewDuration(30 * 24 * time.Hour)
+
+// ExposurePoint is the share of portfolio value held in positions rather
+// than cash at a point in time.
+type ExposurePoint struct {
+	Time     primitives.Time
+	Invested primitives.Decimal
+}
+
+// TearSheet is a one-strategy performance report: headline metrics, a
+// monthly return table, rolling Sharpe ratio and exposure over time.
+type TearSheet struct {
+	Name   string
+	Result *Result
+
+	// Window is the rolling Sharpe window
+	Window primitives.Duration
+
+	// Monthly is the return of each calendar month
+	Monthly       []PeriodReturn
+	RollingSharpe []RollingPoint
+
+	// Exposure is the invested share at each value point, measured with
+	// the holdings the value was computed from. It comes from
+	// Result.ExposureHistory under Config.RecordExposure, or else from the
+	// portfolio history of Config.RecordHistory, and is nil without either.
+	Exposure []ExposurePoint
+}
+
+// NewTearSheet builds a tear sheet for result, computing the rolling
+// Sharpe ratio over window (DefaultRollingWindow if zero). Returns error if
+// result is nil, has fewer than two value points, or window is negative.
+func NewTearSheet(name string, result *Result, window primitives.Duration) (*TearSheet, error) {
+	if result == nil {
+		return nil, fmt.Errorf("result cannot be nil")
+	}
+	history := result.History()
+	if history.Len() < 2 {
+		return nil, fmt.Errorf("insufficient value history (need at least 2 points)")
+	}
+	if window.IsZero() {
+		window = DefaultRollingWindow
+	}
+	monthly, err := result.PeriodReturns(PeriodMonth)
+	if err != nil {
+		return nil, err
+	}
+	rolling, err := result.RollingMetrics(window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TearSheet{
+		Name:          name,
+		Result:        result,
+		Window:        window,
+		Monthly:       monthly.Returns,
+		RollingSharpe: rolling.Sharpe,
+		Exposure:      exposure(result),
+	}, nil
+}
+
+// Markdown renders the tear sheet as Markdown.
+func (t *TearSheet) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", t.Name)
+	b.WriteString("| Metric | Value |\n|---|---:|\n")
+	for _, row := range t.metrics() {
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], row[1])
+	}
+
+	b.WriteString("\n## Monthly Returns\n\n| Year |")
+	for m := time.January; m <= time.December; m++ {
+		fmt.Fprintf(&b, " %s |", m.String()[:3])
+	}
+	b.WriteString(" Year |\n|---|" + strings.Repeat("---:|", 13) + "\n")
+	for _, year := range t.years() {
+		fmt.Fprintf(&b, "| %d |", year.Year)
+		for _, cell := range year.Cells {
+			fmt.Fprintf(&b, " %s |", cell)
+		}
+		fmt.Fprintf(&b, " %s |\n", year.Total)
+	}
+
+	fmt.Fprintf(&b, "\n## Rolling Sharpe (%s)\n\n", t.Window)
+	if len(t.RollingSharpe) == 0 {
+		b.WriteString("The run is shorter than the window.\n")
+	} else {
+		b.WriteString("| Time | Sharpe |\n|---|---:|\n")
+		for _, point := range t.RollingSharpe {
+			fmt.Fprintf(&b, "| %s | %s |\n", point.Time, ratio(point.Value))
+		}
+	}
+
+	b.WriteString("\n## Exposure\n\n")
+	if t.Exposure == nil {
+		b.WriteString("Exposure needs Config.RecordExposure or Config.RecordHistory.\n")
+	} else {
+		b.WriteString("| Time | Invested |\n|---|---:|\n")
+		for _, point := range t.Exposure {
+			fmt.Fprintf(&b, "| %s | %s |\n", point.Time, percent(point.Invested))
+		}
+	}
+	return b.String()
+}
+
+// HTML renders the tear sheet as a standalone HTML page.
+func (t *TearSheet) HTML() (string, error) {
+	data := struct {
+		*TearSheet
+		Metrics [][2]string
+		Months  []string
+		Years   []tearSheetYear
+	}{TearSheet: t, Metrics: t.metrics(), Years: t.years()}
+	for m := time.January; m <= time.December; m++ {
+		data.Months = append(data.Months, m.String()[:3])
+	}
+
+	var b strings.Builder
+	if err := tearSheetTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render tear sheet: %w", err)
+	}
+	return b.String(), nil
+}
+
+var tearSheetTemplate = template.Must(template.New("tearsheet").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}}</title>` + reportStyle + `</head>
+<body>
+<h1>{{.Name}}</h1>
+<table>
+{{range .Metrics}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+<h2>Monthly Returns</h2>
+<table>
+<tr><th>Year</th>{{range .Months}}<th>{{.}}</th>{{end}}<th>Year</th></tr>
+{{range .Years}}<tr><th>{{.Year}}</th>{{range .Cells}}<td>{{.}}</td>{{end}}<td>{{.Total}}</td></tr>
+{{end}}</table>
+<h2>Rolling Sharpe ({{.Window}})</h2>
+{{if .RollingSharpe}}<table>
+<tr><th>Time</th><th>Sharpe</th></tr>
+{{range .RollingSharpe}}<tr><td>{{.Time}}</td><td>{{ratio .Value}}</td></tr>
+{{end}}</table>{{else}}<p>The run is shorter than the window.</p>{{end}}
+<h2>Exposure</h2>
+{{if .Exposure}}<table>
+<tr><th>Time</th><th>Invested</th></tr>
+{{range .Exposure}}<tr><td>{{.Time}}</td><td>{{percent .Invested}}</td></tr>
+{{end}}</table>{{else}}<p>Exposure needs Config.RecordExposure or Config.RecordHistory.</p>{{end}}
+</body>
+</html>
+`))
+
+// tearSheetYear is a formatted row of the monthly return table.
+type tearSheetYear struct {
+	Year  int
+	Cells [12]string
+	Total string
+}
+
+func (t *TearSheet) metrics() [][2]string {
+	r := t.Result
+	return [][2]string{
+		{"Start", r.History().Time(0).String()},
+		{"End", r.History().Time(r.History().Len() - 1).String()},
+		{"Initial Value", r.InitialValue.String()},
+		{"Final Value", r.FinalValue.String()},
+		{"Total Return", percent(r.TotalReturn)},
+		{"Annualized Return", percent(r.AnnualizedReturn)},
+		{"Sharpe Ratio", ratio(r.Sharpe)},
+		{"Max Drawdown", percent(r.MaxDrawdown)},
+	}
+}
+
+// years lays the monthly returns out by year, compounding each year's
+// months into its total.
+func (t *TearSheet) years() []tearSheetYear {
+	var rows []tearSheetYear
+	growth := 1.0
+	for _, m := range t.Monthly {
+		start := m.Start.Time()
+		if len(rows) == 0 || rows[len(rows)-1].Year != start.Year() {
+			if len(rows) > 0 {
+				rows[len(rows)-1].Total = fmt.Sprintf("%.2f%%", (growth-1)*100)
+			}
+			rows = append(rows, tearSheetYear{Year: start.Year()})
+			growth = 1
+		}
+		rows[len(rows)-1].Cells[start.Month()-1] = percent(m.Return)
+		growth *= 1 + m.Return.Float64()
+	}
+	if len(rows) > 0 {
+		rows[len(rows)-1].Total = fmt.Sprintf("%.2f%%", (growth-1)*100)
+	}
+	return rows
+}
+
+// exposure returns the invested share at each value point, or nil without
+// exposure or portfolio history. The engine values the portfolio before
+// rebalancing, so the holdings behind a point are those left by the
+// previous snapshot.
+func exposure(result *Result) []ExposurePoint {
+	if result.ExposureHistory != nil {
+		points := make([]ExposurePoint, len(result.ExposureHistory))
+		for i, e := range result.ExposureHistory {
+			points[i] = ExposurePoint{Time: e.Time, Invested: primitives.Zero()}
+			if total := e.Cash.Add(e.Invested()); total.IsPositive() {
+				points[i].Invested, _ = e.Invested().Div(total)
+			}
+		}
+		return points
+	}
+	if result.Portfolio == nil || !result.Portfolio.HistoryEnabled() {
+		return nil
+	}
+	events := result.Portfolio.History()
+	history := result.History()
+	points := make([]ExposurePoint, 0, history.Len())
+
+	cash := result.InitialValue.Decimal()
+	next := 0
+	for i := 0; i < history.Len(); i++ {
+		if i > 0 {
+			previous := history.Time(i - 1)
+			for next < len(events) && !events[next].Time.After(previous) {
+				cash = events[next].Cash
+				next++
+			}
+		}
+		value := history.Value(i).Decimal()
+		invested := primitives.Zero()
+		if value.IsPositive() {
+			invested, _ = value.Sub(cash).Div(value)
+		}
+		points = append(points, ExposurePoint{Time: history.Time(i), Invested: invested})
+	}
+	return points
+}
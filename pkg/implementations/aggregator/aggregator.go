@@ -0,0 +1,271 @@
+// Package aggregator implements a DEX aggregator that routes spot swaps
+// across a set of registered liquidity pools.
+//
+// Like on-chain aggregators, it splits an order into slices and sends each
+// slice to the pool with the best marginal output, so large orders are
+// spread across pools instead of walking a single pool's curve. The result
+// is a blended execution price plus the fill in each pool, which strategies
+// use to price spot buys and sells realistically.
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrNoRoute is returned when no registered pool can fill an order
+	ErrNoRoute = errors.New("no route for swap")
+
+	// ErrInvalidAmount is returned for non-positive swap amounts
+	ErrInvalidAmount = errors.New("swap amount must be positive")
+
+	// ErrInvalidSide is returned for an order side other than buy or sell
+	ErrInvalidSide = errors.New("invalid swap side")
+
+	// ErrDuplicatePool is returned when registering a pool ID twice
+	ErrDuplicatePool = errors.New("pool already registered")
+
+	// ErrUnknownPool is returned when updating a pool that is not registered
+	ErrUnknownPool = errors.New("pool not registered")
+)
+
+// DefaultSplits is the number of slices an order is divided into.
+const DefaultSplits = 20
+
+// Fill is the part of an aggregated swap executed in one pool.
+type Fill struct {
+	// PoolID is the ID the pool was registered under
+	PoolID string
+
+	// Venue is the pool's venue
+	Venue string
+
+	// AmountIn is the input routed to the pool
+	AmountIn primitives.Amount
+
+	// AmountOut is the output received from the pool
+	AmountOut primitives.Amount
+
+	// Price is the fill's price of token A in token B
+	Price primitives.Price
+}
+
+// Quote is the result of routing a swap across pools.
+type Quote struct {
+	// Side is buy (pay token B for token A) or sell (pay token A for token B)
+	Side mechanisms.OrderSide
+
+	// AmountIn is the total input
+	AmountIn primitives.Amount
+
+	// AmountOut is the total output across all fills
+	AmountOut primitives.Amount
+
+	// Price is the blended price of token A in token B
+	Price primitives.Price
+
+	// Fills lists the pools used, in registration order
+	Fills []Fill
+}
+
+// route is a registered pool and the parameters it is quoted against.
+type route struct {
+	id     string
+	pool   mechanisms.LiquidityPool
+	params mechanisms.PoolParams
+}
+
+// Aggregator routes swaps across registered liquidity pools. All pools
+// must trade the same token pair in the same A/B orientation.
+//
+// Thread Safety: Aggregator is not thread-safe.
+type Aggregator struct {
+	venue  string
+	splits int
+	routes []*route
+	byID   map[string]*route
+}
+
+// NewAggregator creates an aggregator identified by venue that splits
+// orders into DefaultSplits slices.
+func NewAggregator(venue string) *Aggregator {
+	return &Aggregator{venue: venue, splits: DefaultSplits, byID: make(map[string]*route)}
+}
+
+// Mechanism implements mechanisms.MarketMechanism.
+func (a *Aggregator) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeAggregator
+}
+
+// Venue implements mechanisms.MarketMechanism.
+func (a *Aggregator) Venue() string {
+	return a.venue
+}
+
+// SetSplits sets the number of slices orders are divided into. More
+// slices approach the optimal split more closely at the cost of more
+// quotes (splits × pools per order). Values below 1 are treated as 1.
+func (a *Aggregator) SetSplits(splits int) {
+	if splits < 1 {
+		splits = 1
+	}
+	a.splits = splits
+}
+
+// Register adds a pool quoted against params.
+// Returns ErrDuplicatePool if id is already registered.
+func (a *Aggregator) Register(id string, pool mechanisms.LiquidityPool, params mechanisms.PoolParams) error {
+	if pool == nil {
+		return fmt.Errorf("pool %s is nil", id)
+	}
+	if _, exists := a.byID[id]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicatePool, id)
+	}
+	r := &route{id: id, pool: pool, params: params}
+	a.routes = append(a.routes, r)
+	a.byID[id] = r
+	return nil
+}
+
+// Update replaces the parameters a registered pool is quoted against,
+// e.g. with reserves from a new snapshot.
+func (a *Aggregator) Update(id string, params mechanisms.PoolParams) error {
+	r, ok := a.byID[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPool, id)
+	}
+	r.params = params
+	return nil
+}
+
+// Quote routes a swap of amountIn across the registered pools without
+// modifying any pool.
+//
+// The order is divided into equal slices and each slice goes to the pool
+// whose output grows most from it, given what that pool has already been
+// allocated. Pools that fail to quote a slice are skipped for it.
+// Returns ErrNoRoute if no pool can fill any part of the order.
+func (a *Aggregator) Quote(ctx context.Context, side mechanisms.OrderSide, amountIn primitives.Amount) (Quote, error) {
+	if side != mechanisms.OrderSideBuy && side != mechanisms.OrderSideSell {
+		return Quote{}, fmt.Errorf("%w: %q", ErrInvalidSide, side)
+	}
+	if amountIn.IsZero() {
+		return Quote{}, ErrInvalidAmount
+	}
+	if len(a.routes) == 0 {
+		return Quote{}, ErrNoRoute
+	}
+
+	slice, err := amountIn.Decimal().Div(primitives.NewDecimal(int64(a.splits)))
+	if err != nil {
+		return Quote{}, err
+	}
+
+	allocated := make([]primitives.Decimal, len(a.routes))
+	outputs := make([]primitives.Decimal, len(a.routes))
+	remaining := amountIn.Decimal()
+	for n := 0; n < a.splits && remaining.IsPositive(); n++ {
+		if err := ctx.Err(); err != nil {
+			return Quote{}, err
+		}
+		size := slice
+		if n == a.splits-1 || size.GreaterThan(remaining) {
+			size = remaining
+		}
+
+		best, bestOut := -1, primitives.Zero()
+		for i, r := range a.routes {
+			out, err := quote(ctx, r, side, allocated[i].Add(size))
+			if err != nil {
+				continue
+			}
+			if gain := out.Sub(outputs[i]); best < 0 || gain.GreaterThan(bestOut.Sub(outputs[best])) {
+				best, bestOut = i, out
+			}
+		}
+		if best < 0 {
+			break
+		}
+		allocated[best] = allocated[best].Add(size)
+		outputs[best] = bestOut
+		remaining = remaining.Sub(size)
+	}
+
+	result := Quote{Side: side, AmountIn: amountIn, AmountOut: primitives.ZeroAmount()}
+	for i, r := range a.routes {
+		if !allocated[i].IsPositive() {
+			continue
+		}
+		fill := Fill{
+			PoolID:    r.id,
+			Venue:     r.pool.Venue(),
+			AmountIn:  primitives.MustAmount(allocated[i]),
+			AmountOut: primitives.MustAmount(outputs[i]),
+		}
+		if fill.Price, err = price(side, allocated[i], outputs[i]); err != nil {
+			return Quote{}, fmt.Errorf("pool %s: %w", r.id, err)
+		}
+		result.Fills = append(result.Fills, fill)
+		result.AmountOut = result.AmountOut.Add(fill.AmountOut)
+	}
+	if len(result.Fills) == 0 || remaining.IsPositive() {
+		return Quote{}, fmt.Errorf("%w: %s of %s unfilled", ErrNoRoute, remaining, amountIn)
+	}
+	if result.Price, err = price(side, amountIn.Decimal(), result.AmountOut.Decimal()); err != nil {
+		return Quote{}, err
+	}
+	return result, nil
+}
+
+// quote returns a pool's output for amountIn, using the pool's own quote
+// when it implements mechanisms.SwapQuoter.
+func quote(ctx context.Context, r *route, side mechanisms.OrderSide, amountIn primitives.Decimal) (primitives.Decimal, error) {
+	in := primitives.MustAmount(amountIn)
+	if q, ok := r.pool.(mechanisms.SwapQuoter); ok {
+		out, err := q.QuoteSwap(ctx, r.params, side, in)
+		if err != nil {
+			return primitives.Zero(), err
+		}
+		return out.Decimal(), nil
+	}
+	return ConstantProductOut(r.params, side, in)
+}
+
+// ConstantProductOut returns the output of swapping amountIn through an
+// x*y=k pool with the reserves and fee rate in params.
+func ConstantProductOut(params mechanisms.PoolParams, side mechanisms.OrderSide, amountIn primitives.Amount) (primitives.Decimal, error) {
+	reserveIn, reserveOut := params.ReserveB.Decimal(), params.ReserveA.Decimal()
+	if side == mechanisms.OrderSideSell {
+		reserveIn, reserveOut = reserveOut, reserveIn
+	}
+	if !reserveIn.IsPositive() || !reserveOut.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: pool has no reserves", ErrNoRoute)
+	}
+
+	inAfterFee := amountIn.Decimal().Mul(primitives.One().Sub(params.FeeRate))
+	return reserveOut.Mul(inAfterFee).Div(reserveIn.Add(inAfterFee))
+}
+
+// price returns the price of token A in token B for a swap.
+func price(side mechanisms.OrderSide, in, out primitives.Decimal) (primitives.Price, error) {
+	if !out.IsPositive() {
+		return primitives.ZeroPrice(), fmt.Errorf("%w: zero output", ErrNoRoute)
+	}
+	if side == mechanisms.OrderSideBuy {
+		p, err := in.Div(out)
+		if err != nil {
+			return primitives.ZeroPrice(), err
+		}
+		return primitives.NewPrice(p)
+	}
+	p, err := out.Div(in)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	return primitives.NewPrice(p)
+}
@@ -0,0 +1,164 @@
+package aggregator_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/aggregator"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// pool is a minimal LiquidityPool quoted with constant-product math.
+type pool struct{ venue string }
+
+func (p *pool) Mechanism() mechanisms.MechanismType { return mechanisms.MechanismTypeLiquidityPool }
+func (p *pool) Venue() string                       { return p.venue }
+func (p *pool) Calculate(context.Context, mechanisms.PoolParams) (mechanisms.PoolState, error) {
+	return mechanisms.PoolState{}, nil
+}
+func (p *pool) AddLiquidity(context.Context, mechanisms.TokenAmounts) (mechanisms.PoolPosition, error) {
+	return mechanisms.PoolPosition{}, nil
+}
+func (p *pool) RemoveLiquidity(context.Context, mechanisms.PoolPosition) (mechanisms.TokenAmounts, error) {
+	return mechanisms.TokenAmounts{}, nil
+}
+
+// fixedPool quotes every swap at a fixed price of token A in token B.
+type fixedPool struct {
+	pool
+	price primitives.Decimal
+}
+
+func (p *fixedPool) QuoteSwap(_ context.Context, _ mechanisms.PoolParams, side mechanisms.OrderSide, in primitives.Amount) (primitives.Amount, error) {
+	if side == mechanisms.OrderSideSell {
+		return primitives.MustAmount(in.Decimal().Mul(p.price)), nil
+	}
+	out, err := in.Decimal().Div(p.price)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return primitives.NewAmount(out)
+}
+
+func amt(s string) primitives.Amount {
+	return primitives.MustAmount(primitives.MustDecimalFromString(s))
+}
+
+// reserves returns parameters for an ETH/USDC pool priced at 2000.
+func reserves(eth string) mechanisms.PoolParams {
+	a := amt(eth)
+	return mechanisms.PoolParams{
+		ReserveA: a,
+		ReserveB: primitives.MustAmount(a.Decimal().Mul(primitives.NewDecimal(2000))),
+		FeeRate:  primitives.MustDecimalFromString("0.003"),
+	}
+}
+
+func TestAggregatorQuote(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("splits across equal pools", func(t *testing.T) {
+		agg := aggregator.NewAggregator("test-agg")
+		for _, id := range []string{"uni", "sushi"} {
+			if err := agg.Register(id, &pool{venue: id}, reserves("1000")); err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+		}
+		quote, err := agg.Quote(ctx, mechanisms.OrderSideSell, amt("100"))
+		if err != nil {
+			t.Fatalf("Quote: %v", err)
+		}
+		if len(quote.Fills) != 2 || !quote.Fills[0].AmountIn.Equal(amt("50")) || !quote.Fills[1].AmountIn.Equal(amt("50")) {
+			t.Fatalf("expected an even split, got %+v", quote.Fills)
+		}
+
+		single := aggregator.NewAggregator("single")
+		_ = single.Register("uni", &pool{venue: "uni"}, reserves("1000"))
+		alone, err := single.Quote(ctx, mechanisms.OrderSideSell, amt("100"))
+		if err != nil {
+			t.Fatalf("Quote: %v", err)
+		}
+		if !quote.Price.GreaterThan(alone.Price) {
+			t.Errorf("split price %s should beat single-pool price %s", quote.Price, alone.Price)
+		}
+		if !quote.Price.LessThan(primitives.MustPrice(primitives.NewDecimal(2000))) {
+			t.Errorf("sell price %s should be below spot after fees and impact", quote.Price)
+		}
+	})
+
+	t.Run("favours deeper pool", func(t *testing.T) {
+		agg := aggregator.NewAggregator("test-agg")
+		_ = agg.Register("deep", &pool{venue: "deep"}, reserves("3000"))
+		_ = agg.Register("shallow", &pool{venue: "shallow"}, reserves("1000"))
+		quote, err := agg.Quote(ctx, mechanisms.OrderSideBuy, amt("400000"))
+		if err != nil {
+			t.Fatalf("Quote: %v", err)
+		}
+		if len(quote.Fills) != 2 || !quote.Fills[0].AmountIn.GreaterThan(quote.Fills[1].AmountIn) {
+			t.Fatalf("expected most flow in the deep pool, got %+v", quote.Fills)
+		}
+		total := quote.Fills[0].AmountIn.Add(quote.Fills[1].AmountIn)
+		if !total.Equal(quote.AmountIn) {
+			t.Errorf("fills sum to %s, want %s", total, quote.AmountIn)
+		}
+		if !quote.Price.GreaterThan(primitives.MustPrice(primitives.NewDecimal(2000))) {
+			t.Errorf("buy price %s should be above spot", quote.Price)
+		}
+	})
+
+	t.Run("uses pool quotes when available", func(t *testing.T) {
+		agg := aggregator.NewAggregator("test-agg")
+		_ = agg.Register("amm", &pool{venue: "amm"}, reserves("1000"))
+		_ = agg.Register("rfq", &fixedPool{pool: pool{venue: "rfq"}, price: primitives.NewDecimal(2100)}, mechanisms.PoolParams{})
+		quote, err := agg.Quote(ctx, mechanisms.OrderSideSell, amt("10"))
+		if err != nil {
+			t.Fatalf("Quote: %v", err)
+		}
+		if len(quote.Fills) != 1 || quote.Fills[0].PoolID != "rfq" || quote.Price.String() != "2100" {
+			t.Errorf("expected everything routed to the better-priced RFQ pool, got %+v", quote)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		agg := aggregator.NewAggregator("test-agg")
+		if _, err := agg.Quote(ctx, mechanisms.OrderSideBuy, amt("1")); !errors.Is(err, aggregator.ErrNoRoute) {
+			t.Errorf("expected %v, got %v", aggregator.ErrNoRoute, err)
+		}
+		_ = agg.Register("empty", &pool{}, mechanisms.PoolParams{})
+		if _, err := agg.Quote(ctx, mechanisms.OrderSideBuy, amt("1")); !errors.Is(err, aggregator.ErrNoRoute) {
+			t.Errorf("expected %v for a pool without reserves, got %v", aggregator.ErrNoRoute, err)
+		}
+		if err := agg.Register("empty", &pool{}, mechanisms.PoolParams{}); !errors.Is(err, aggregator.ErrDuplicatePool) {
+			t.Errorf("expected %v, got %v", aggregator.ErrDuplicatePool, err)
+		}
+		if err := agg.Update("missing", mechanisms.PoolParams{}); !errors.Is(err, aggregator.ErrUnknownPool) {
+			t.Errorf("expected %v, got %v", aggregator.ErrUnknownPool, err)
+		}
+		if err := agg.Update("empty", reserves("10")); err != nil {
+			t.Errorf("Update: %v", err)
+		}
+		if _, err := agg.Quote(ctx, mechanisms.OrderSideBuy, primitives.ZeroAmount()); !errors.Is(err, aggregator.ErrInvalidAmount) {
+			t.Errorf("expected %v, got %v", aggregator.ErrInvalidAmount, err)
+		}
+		if _, err := agg.Quote(ctx, "hold", amt("1")); !errors.Is(err, aggregator.ErrInvalidSide) {
+			t.Errorf("expected %v, got %v", aggregator.ErrInvalidSide, err)
+		}
+		if agg.Mechanism() != mechanisms.MechanismTypeAggregator || agg.Venue() != "test-agg" {
+			t.Errorf("unexpected identity %s/%s", agg.Mechanism(), agg.Venue())
+		}
+	})
+}
+
+func TestConstantProductOut(t *testing.T) {
+	out, err := aggregator.ConstantProductOut(reserves("1000"), mechanisms.OrderSideSell, amt("1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 2,000,000 * 0.997 / (1000 + 0.997)
+	want := primitives.MustDecimalFromString("1992.01396")
+	if out.Sub(want).Abs().GreaterThan(primitives.MustDecimalFromString("0.0001")) {
+		t.Errorf("expected ~%s, got %s", want, out)
+	}
+}
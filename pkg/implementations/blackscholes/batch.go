@@ -0,0 +1,223 @@
+package blackscholes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PriceParams describes one European option to price in a batch.
+type PriceParams struct {
+	// Type is call or put
+	Type mechanisms.OptionType
+
+	// Underlying is the underlying price (S) and Strike the strike (K)
+	Underlying primitives.Price
+	Strike     primitives.Price
+
+	// TimeToExpiry is the time to expiry in years (T); zero prices at
+	// intrinsic value
+	TimeToExpiry primitives.Decimal
+
+	// Volatility (σ) and RiskFreeRate (r) are annualized decimals
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+}
+
+// Valuation is the Black-Scholes price and Greeks of one option, in the
+// units of Option.Price and Option.Greeks.
+type Valuation struct {
+	Price  primitives.Price
+	Greeks mechanisms.Greeks
+}
+
+// PriceMany prices params with a fresh BatchPricer. Use a BatchPricer
+// directly to reuse its buffers across snapshots.
+func PriceMany(params []PriceParams) ([]Valuation, error) {
+	return NewBatchPricer().PriceMany(params)
+}
+
+// expiryKey identifies the terms shared by every option of an expiry.
+type expiryKey struct {
+	t, sigma, r float64
+}
+
+// expiryTerms are √T, σ√T, e^(-rT) and the drift (r + σ²/2)T.
+type expiryTerms struct {
+	sqrtT, sigmaT, discount, drift float64
+}
+
+// strikeKey identifies the terms shared by a call and put on one strike
+// and expiry.
+type strikeKey struct {
+	s, k float64
+	expiryKey
+}
+
+// strikeTerms are N(d1), N(d2) and φ(d1).
+type strikeTerms struct {
+	nd1, nd2, pdf float64
+}
+
+// BatchPricer prices many options at once, such as a strike ladder or a
+// volatility surface. Inputs are converted to float64 once, and the terms
+// options share are computed once per batch: √T, e^(-rT) and the drift per
+// expiry and volatility, and d1, d2, N(d1), N(d2) and φ(d1) per strike, so
+// a call and put on the same strike cost one evaluation of the normal CDF
+// pair. Results match Option.Price and Option.Greeks.
+//
+// Thread Safety: BatchPricer is not thread-safe; use one per goroutine.
+type BatchPricer struct {
+	expiries map[expiryKey]expiryTerms
+	strikes  map[strikeKey]strikeTerms
+	inputs   []batchInput
+	cdf      numerics.CDF
+}
+
+// batchInput is an option's inputs as float64.
+type batchInput struct {
+	call bool
+	strikeKey
+}
+
+// NewBatchPricer creates a batch pricer.
+func NewBatchPricer() *BatchPricer {
+	return &BatchPricer{
+		expiries: make(map[expiryKey]expiryTerms),
+		strikes:  make(map[strikeKey]strikeTerms),
+		cdf:      numerics.NormalCDF,
+	}
+}
+
+// SetCDF selects the normal CDF, as Option.SetCDF does.
+func (b *BatchPricer) SetCDF(cdf numerics.CDF) {
+	if cdf == nil {
+		cdf = numerics.NormalCDF
+	}
+	b.cdf = cdf
+}
+
+// PriceMany returns the valuation of each of params, in order. Returns an
+// error naming the first invalid option, with the same errors as
+// Option.Price.
+func (b *BatchPricer) PriceMany(params []PriceParams) ([]Valuation, error) {
+	clear(b.expiries)
+	clear(b.strikes)
+	b.inputs = b.inputs[:0]
+	for i, p := range params {
+		input, err := batchInputOf(p)
+		if err != nil {
+			return nil, fmt.Errorf("option %d: %w", i, err)
+		}
+		b.inputs = append(b.inputs, input)
+	}
+
+	valuations := make([]Valuation, len(params))
+	for i, input := range b.inputs {
+		if input.t == 0 || input.sigma == 0 {
+			valuations[i] = intrinsicValuation(params[i])
+			continue
+		}
+		valuations[i] = b.valuation(input)
+	}
+	return valuations, nil
+}
+
+func batchInputOf(p PriceParams) (batchInput, error) {
+	switch {
+	case p.Type != mechanisms.OptionTypeCall && p.Type != mechanisms.OptionTypePut:
+		return batchInput{}, errors.New("invalid option type")
+	case p.Underlying.IsZero():
+		return batchInput{}, ErrInvalidUnderlying
+	case p.Strike.IsZero():
+		return batchInput{}, ErrInvalidStrike
+	case p.Volatility.IsNegative():
+		return batchInput{}, ErrInvalidVolatility
+	case p.TimeToExpiry.IsNegative():
+		return batchInput{}, ErrInvalidTimeToExpiry
+	}
+	return batchInput{
+		call: p.Type == mechanisms.OptionTypeCall,
+		strikeKey: strikeKey{
+			s: p.Underlying.Decimal().Float64(),
+			k: p.Strike.Decimal().Float64(),
+			expiryKey: expiryKey{
+				t:     p.TimeToExpiry.Float64(),
+				sigma: p.Volatility.Float64(),
+				r:     p.RiskFreeRate.Float64(),
+			},
+		},
+	}, nil
+}
+
+// valuation prices an option with T and σ positive from the cached terms.
+func (b *BatchPricer) valuation(in batchInput) Valuation {
+	e, ok := b.expiries[in.expiryKey]
+	if !ok {
+		e.sqrtT = math.Sqrt(in.t)
+		e.sigmaT = in.sigma * e.sqrtT
+		e.discount = math.Exp(-in.r * in.t)
+		e.drift = (in.r + 0.5*in.sigma*in.sigma) * in.t
+		b.expiries[in.expiryKey] = e
+	}
+	st, ok := b.strikes[in.strikeKey]
+	if !ok {
+		d1 := (math.Log(in.s/in.k) + e.drift) / e.sigmaT
+		st = strikeTerms{
+			nd1: b.cdf(d1),
+			nd2: b.cdf(d1 - e.sigmaT),
+			pdf: numerics.NormalPDF(d1),
+		}
+		b.strikes[in.strikeKey] = st
+	}
+
+	// N(-x) = 1 - N(x), so puts reuse the call terms
+	kd := in.k * e.discount
+	var price, delta, theta, rho float64
+	decay := -(in.s * st.pdf * in.sigma) / (2 * e.sqrtT)
+	if in.call {
+		price = in.s*st.nd1 - kd*st.nd2
+		delta = st.nd1
+		theta = decay - in.r*kd*st.nd2
+		rho = kd * in.t * st.nd2 / 100
+	} else {
+		price = kd*(1-st.nd2) - in.s*(1-st.nd1)
+		delta = st.nd1 - 1
+		theta = decay + in.r*kd*(1-st.nd2)
+		rho = -kd * in.t * (1 - st.nd2) / 100
+	}
+	if price < 0 {
+		price = 0
+	}
+	return Valuation{
+		Price: primitives.MustPrice(primitives.NewDecimalFromFloat(price)),
+		Greeks: mechanisms.Greeks{
+			Delta: primitives.NewDecimalFromFloat(delta),
+			Gamma: primitives.NewDecimalFromFloat(st.pdf / (in.s * e.sigmaT)),
+			Theta: primitives.NewDecimalFromFloat(theta),
+			Vega:  primitives.NewDecimalFromFloat(in.s * st.pdf * e.sqrtT / 100),
+			Rho:   primitives.NewDecimalFromFloat(rho),
+		},
+	}
+}
+
+// intrinsicValuation values an option at expiry or with zero volatility:
+// intrinsic value, with delta 1 (call) or -1 (put) in the money and the
+// other Greeks zero.
+func intrinsicValuation(p PriceParams) Valuation {
+	s, k := p.Underlying.Decimal(), p.Strike.Decimal()
+	valuation := Valuation{Price: primitives.ZeroPrice()}
+	switch {
+	case p.Type == mechanisms.OptionTypeCall && s.GreaterThan(k):
+		valuation.Price = primitives.MustPrice(s.Sub(k))
+		valuation.Greeks.Delta = primitives.One()
+	case p.Type == mechanisms.OptionTypePut && s.LessThan(k):
+		valuation.Price = primitives.MustPrice(k.Sub(s))
+		valuation.Greeks.Delta = primitives.NewDecimal(-1)
+	}
+	return valuation
+}
@@ -0,0 +1,156 @@
+package blackscholes_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ladder builds a call and put at each of strikes around 100 for each
+// expiry, all at 60% volatility and a 5% rate.
+func ladder(strikes int, expiries ...float64) []blackscholes.PriceParams {
+	var params []blackscholes.PriceParams
+	for _, expiry := range expiries {
+		for i := 0; i < strikes; i++ {
+			strike := primitives.MustPrice(primitives.NewDecimalFromFloat(50 + 100*float64(i)/float64(strikes)))
+			for _, optionType := range []mechanisms.OptionType{mechanisms.OptionTypeCall, mechanisms.OptionTypePut} {
+				params = append(params, blackscholes.PriceParams{
+					Type:         optionType,
+					Underlying:   primitives.MustPrice(primitives.NewDecimal(100)),
+					Strike:       strike,
+					TimeToExpiry: primitives.NewDecimalFromFloat(expiry),
+					Volatility:   primitives.NewDecimalFromFloat(0.6),
+					RiskFreeRate: primitives.NewDecimalFromFloat(0.05),
+				})
+			}
+		}
+	}
+	return params
+}
+
+func TestPriceManyMatchesOption(t *testing.T) {
+	params := ladder(20, 0.02, 0.25, 1)
+	params = append(params, blackscholes.PriceParams{
+		Type:       mechanisms.OptionTypePut,
+		Underlying: primitives.MustPrice(primitives.NewDecimal(90)),
+		Strike:     primitives.MustPrice(primitives.MustDecimalFromString("100.5")),
+	})
+	valuations, err := blackscholes.PriceMany(params)
+	if err != nil {
+		t.Fatalf("PriceMany: %v", err)
+	}
+	if len(valuations) != len(params) {
+		t.Fatalf("%d valuations for %d options", len(valuations), len(params))
+	}
+
+	ctx := context.Background()
+	near := func(got, want primitives.Decimal) bool {
+		return math.Abs(got.Float64()-want.Float64()) <= 1e-9*math.Max(1, math.Abs(want.Float64()))
+	}
+	for i, p := range params {
+		option, err := blackscholes.NewOption("o", p.Type, p.Strike, p.TimeToExpiry, p.Strike, primitives.One())
+		if err != nil {
+			t.Fatalf("NewOption: %v", err)
+		}
+		market := mechanisms.PriceParams{UnderlyingPrice: p.Underlying, Volatility: p.Volatility, RiskFreeRate: p.RiskFreeRate, TimeToExpiry: p.TimeToExpiry}
+		price, err := option.Price(ctx, market)
+		if err != nil {
+			t.Fatalf("Price: %v", err)
+		}
+		got := valuations[i]
+		if !near(got.Price.Decimal(), price.Decimal()) {
+			t.Errorf("option %d: batch price %s, scalar %s", i, got.Price, price)
+		}
+		if p.TimeToExpiry.IsZero() {
+			continue
+		}
+		greeks, err := option.Greeks(ctx, market)
+		if err != nil {
+			t.Fatalf("Greeks: %v", err)
+		}
+		if !near(got.Greeks.Delta, greeks.Delta) || !near(got.Greeks.Gamma, greeks.Gamma) || !near(got.Greeks.Theta, greeks.Theta) ||
+			!near(got.Greeks.Vega, greeks.Vega) || !near(got.Greeks.Rho, greeks.Rho) {
+			t.Errorf("option %d: batch greeks %+v, scalar %+v", i, got.Greeks, greeks)
+		}
+	}
+
+	expired := valuations[len(valuations)-1]
+	if !expired.Price.Decimal().Equal(primitives.MustDecimalFromString("10.5")) || !expired.Greeks.Delta.Equal(primitives.NewDecimal(-1)) {
+		t.Errorf("expired put = %s, delta %s; want 10.5, -1", expired.Price, expired.Greeks.Delta)
+	}
+}
+
+func TestPriceManyValidation(t *testing.T) {
+	params := ladder(2, 0.5)
+	params[3].Volatility = primitives.NewDecimalFromFloat(-0.1)
+	if _, err := blackscholes.PriceMany(params); !errors.Is(err, blackscholes.ErrInvalidVolatility) {
+		t.Errorf("expected ErrInvalidVolatility, got %v", err)
+	}
+	params = ladder(2, 0.5)
+	params[1].Underlying = primitives.ZeroPrice()
+	if _, err := blackscholes.PriceMany(params); !errors.Is(err, blackscholes.ErrInvalidUnderlying) {
+		t.Errorf("expected ErrInvalidUnderlying, got %v", err)
+	}
+
+	// A pricer is reusable across batches
+	pricer := blackscholes.NewBatchPricer()
+	first, err := pricer.PriceMany(ladder(5, 0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := ladder(5, 0.5)
+	for i := range moved {
+		moved[i].Underlying = primitives.MustPrice(primitives.NewDecimal(110))
+	}
+	second, err := pricer.PriceMany(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second[0].Price.GreaterThan(first[0].Price) {
+		t.Errorf("call price did not rise with the underlying: %s -> %s", first[0].Price, second[0].Price)
+	}
+}
+
+func BenchmarkPriceMany(b *testing.B) {
+	params := ladder(100, 0.02, 0.08, 0.25, 0.5, 1)
+	pricer := blackscholes.NewBatchPricer()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pricer.PriceMany(params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPriceScalar(b *testing.B) {
+	params := ladder(100, 0.02, 0.08, 0.25, 0.5, 1)
+	options := make([]*blackscholes.Option, len(params))
+	for i, p := range params {
+		option, err := blackscholes.NewOption("o", p.Type, p.Strike, p.TimeToExpiry, p.Strike, primitives.One())
+		if err != nil {
+			b.Fatal(err)
+		}
+		options[i] = option
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for n, option := range options {
+			p := params[n]
+			market := mechanisms.PriceParams{UnderlyingPrice: p.Underlying, Volatility: p.Volatility, RiskFreeRate: p.RiskFreeRate}
+			if _, err := option.Price(ctx, market); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := option.Greeks(ctx, market); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
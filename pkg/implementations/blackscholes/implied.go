@@ -0,0 +1,65 @@
+package blackscholes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ErrNoImpliedVolatility is returned when a price lies outside the
+// no-arbitrage bounds, so no volatility reproduces it
+var ErrNoImpliedVolatility = errors.New("price admits no implied volatility")
+
+const (
+	// impliedVolatilityMin and impliedVolatilityMax bracket the search
+	impliedVolatilityMin = 1e-6
+	impliedVolatilityMax = 10
+
+	// impliedVolatilityTolerance is the absolute tolerance on σ
+	impliedVolatilityTolerance = 1e-10
+)
+
+// ImpliedVolatility returns the volatility at which the Black-Scholes
+// price of the option described by params equals price. params.Volatility
+// is ignored. The root is found by Brent's method over σ in [1e-6, 10];
+// a price outside the no-arbitrage bounds (below intrinsic value on the
+// discounted strike, or above S for a call and Ke^(-rT) for a put)
+// returns ErrNoImpliedVolatility.
+func ImpliedVolatility(params PriceParams, price primitives.Price) (primitives.Decimal, error) {
+	params.Volatility = primitives.Zero()
+	input, err := batchInputOf(params)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if input.t == 0 {
+		return primitives.Zero(), ErrOptionExpired
+	}
+
+	target := price.Decimal().Float64()
+	f := func(sigma float64) float64 {
+		return blackScholesPrice(input.call, input.s, input.k, input.t, sigma, input.r) - target
+	}
+	sigma, err := numerics.Brent(f, impliedVolatilityMin, impliedVolatilityMax, impliedVolatilityTolerance, 100)
+	if errors.Is(err, numerics.ErrNoBracket) {
+		return primitives.Zero(), fmt.Errorf("%w: %s %s", ErrNoImpliedVolatility, params.Type, price)
+	}
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("implied volatility: %w", err)
+	}
+	return primitives.NewDecimalFromFloat(sigma), nil
+}
+
+// blackScholesPrice is the Black-Scholes price with T and σ positive.
+func blackScholesPrice(call bool, s, k, t, sigma, r float64) float64 {
+	sigmaT := sigma * math.Sqrt(t)
+	d1 := (math.Log(s/k) + (r+0.5*sigma*sigma)*t) / sigmaT
+	d2 := d1 - sigmaT
+	kd := k * math.Exp(-r*t)
+	if call {
+		return s*numerics.NormalCDF(d1) - kd*numerics.NormalCDF(d2)
+	}
+	return kd*numerics.NormalCDF(-d2) - s*numerics.NormalCDF(-d1)
+}
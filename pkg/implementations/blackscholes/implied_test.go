@@ -0,0 +1,96 @@
+package blackscholes_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+func TestImpliedVolatility(t *testing.T) {
+	// Round trip a ladder's prices back to its 60% volatility
+	params := ladder(10, 0.05, 0.5, 2)
+	valuations, err := blackscholes.PriceMany(params)
+	if err != nil {
+		t.Fatalf("PriceMany: %v", err)
+	}
+	for i, p := range params {
+		sigma, err := blackscholes.ImpliedVolatility(p, valuations[i].Price)
+		if err != nil {
+			t.Fatalf("option %d: %v", i, err)
+		}
+		if math.Abs(sigma.Float64()-0.6) > 1e-6 {
+			t.Errorf("option %d (%s %s, T=%s): implied volatility %s, want 0.6", i, p.Type, p.Strike, p.TimeToExpiry, sigma)
+		}
+	}
+
+	// A call worth more than the underlying has no implied volatility
+	p := params[0]
+	if _, err := blackscholes.ImpliedVolatility(p, primitives.MustPrice(primitives.NewDecimal(101))); !errors.Is(err, blackscholes.ErrNoImpliedVolatility) {
+		t.Errorf("expected ErrNoImpliedVolatility, got %v", err)
+	}
+	p.TimeToExpiry = primitives.Zero()
+	if _, err := blackscholes.ImpliedVolatility(p, primitives.MustPrice(primitives.NewDecimal(50))); !errors.Is(err, blackscholes.ErrOptionExpired) {
+		t.Errorf("expected ErrOptionExpired, got %v", err)
+	}
+}
+
+func TestOptionCDFSelection(t *testing.T) {
+	// An out-of-the-money put, where N(x) is in the tail
+	option, err := blackscholes.NewOption("tail", mechanisms.OptionTypePut,
+		primitives.MustPrice(primitives.NewDecimal(60)), primitives.NewDecimalFromFloat(0.25),
+		primitives.MustPrice(primitives.One()), primitives.One())
+	if err != nil {
+		t.Fatal(err)
+	}
+	market := mechanisms.PriceParams{
+		UnderlyingPrice: primitives.MustPrice(primitives.NewDecimal(100)),
+		Volatility:      primitives.NewDecimalFromFloat(0.5),
+		RiskFreeRate:    primitives.NewDecimalFromFloat(0.05),
+	}
+	ctx := context.Background()
+
+	// Reference: the discounted expected payoff, integrated over the
+	// terminal log price
+	s, k, sigma, r, T := 100.0, 60.0, 0.5, 0.05, 0.25
+	mu, sd := math.Log(s)+(r-sigma*sigma/2)*T, sigma*math.Sqrt(T)
+	want, err := numerics.Integrate(func(x float64) float64 {
+		return math.Max(k-math.Exp(x), 0) * numerics.NormalPDF((x-mu)/sd) / sd
+	}, mu-12*sd, math.Log(k), 1e-13)
+	if err != nil {
+		t.Fatalf("Integrate: %v", err)
+	}
+	want *= math.Exp(-r * T)
+
+	greeks, err := option.Greeks(ctx, market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := option.Price(ctx, market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel := math.Abs(price.Decimal().Float64()/want - 1); rel > 1e-9 {
+		t.Errorf("erf price %g, integrated %g (relative error %g)", price.Decimal().Float64(), want, rel)
+	}
+
+	option.SetCDF(numerics.NormalCDFAbramowitzStegun)
+	approxGreeks, err := option.Greeks(ctx, market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approxGreeks.Delta.Equal(greeks.Delta) {
+		t.Errorf("expected the approximation to change the tail delta %s", greeks.Delta)
+	}
+
+	option.SetCDF(nil)
+	restored, err := option.Greeks(ctx, market)
+	if err != nil || !restored.Delta.Equal(greeks.Delta) {
+		t.Errorf("SetCDF(nil) delta %s (%v), want default %s", restored.Delta, err, greeks.Delta)
+	}
+}
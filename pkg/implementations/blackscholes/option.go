@@ -9,6 +9,7 @@ import (
 	"math"
 
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 )
 
@@ -65,6 +66,9 @@ type Option struct {
 
 	// settled indicates if the option has been settled
 	settled bool
+
+	// cdf is the normal CDF used for N(x)
+	cdf numerics.CDF
 }
 
 // NewOption creates a new European option.
@@ -121,9 +125,21 @@ func NewOption(
 		positionSize: positionSize,
 		direction:    direction,
 		settled:      false,
+		cdf:          numerics.NormalCDF,
 	}, nil
 }
 
+// SetCDF selects the normal CDF used by Price and Greeks. The default is
+// the erf-based numerics.NormalCDF; numerics.NormalCDFAbramowitzStegun
+// reproduces prices from the earlier polynomial approximation, which loses
+// relative precision in the tails. A nil cdf restores the default.
+func (o *Option) SetCDF(cdf numerics.CDF) {
+	if cdf == nil {
+		cdf = numerics.NormalCDF
+	}
+	o.cdf = cdf
+}
+
 // Mechanism returns the mechanism type identifier.
 func (o *Option) Mechanism() mechanisms.MechanismType {
 	return mechanisms.MechanismTypeDerivative
@@ -149,7 +165,7 @@ func (o *Option) Venue() string {
 // Where:
 //   - d1 = [ln(S/K) + (r + σ²/2)*T] / (σ*√T)
 //   - d2 = d1 - σ*√T
-//   - N(x) is the cumulative standard normal distribution
+//   - N(x) is the cumulative standard normal distribution (see SetCDF)
 func (o *Option) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
 	// Validate required parameters
 	if params.UnderlyingPrice.IsZero() {
@@ -200,10 +216,10 @@ func (o *Option) Price(ctx context.Context, params mechanisms.PriceParams) (prim
 	var price float64
 	if o.optionType == mechanisms.OptionTypeCall {
 		// Call: C = S*N(d1) - K*e^(-rT)*N(d2)
-		price = S*cumulativeNormal(d1) - K*math.Exp(-r*T)*cumulativeNormal(d2)
+		price = S*o.cdf(d1) - K*math.Exp(-r*T)*o.cdf(d2)
 	} else {
 		// Put: P = K*e^(-rT)*N(-d2) - S*N(-d1)
-		price = K*math.Exp(-r*T)*cumulativeNormal(-d2) - S*cumulativeNormal(-d1)
+		price = K*math.Exp(-r*T)*o.cdf(-d2) - S*o.cdf(-d1)
 	}
 
 	// Ensure non-negative price
@@ -291,31 +307,31 @@ func (o *Option) Greeks(ctx context.Context, params mechanisms.PriceParams) (mec
 
 	// Delta: ∂V/∂S
 	if o.optionType == mechanisms.OptionTypeCall {
-		delta = cumulativeNormal(d1)
+		delta = o.cdf(d1)
 	} else {
-		delta = cumulativeNormal(d1) - 1
+		delta = o.cdf(d1) - 1
 	}
 
 	// Gamma: ∂²V/∂S² (same for calls and puts)
-	gamma = standardNormal(d1) / (S * sigma * sqrtT)
+	gamma = numerics.NormalPDF(d1) / (S * sigma * sqrtT)
 
 	// Vega: ∂V/∂σ (same for calls and puts, per 1% change)
-	vega = S * standardNormal(d1) * sqrtT / 100
+	vega = S * numerics.NormalPDF(d1) * sqrtT / 100
 
 	// Theta: ∂V/∂t (per year)
 	discountFactor := math.Exp(-r * T)
-	term1 := -(S * standardNormal(d1) * sigma) / (2 * sqrtT)
+	term1 := -(S * numerics.NormalPDF(d1) * sigma) / (2 * sqrtT)
 	if o.optionType == mechanisms.OptionTypeCall {
-		theta = term1 - r*K*discountFactor*cumulativeNormal(d2)
+		theta = term1 - r*K*discountFactor*o.cdf(d2)
 	} else {
-		theta = term1 + r*K*discountFactor*cumulativeNormal(-d2)
+		theta = term1 + r*K*discountFactor*o.cdf(-d2)
 	}
 
 	// Rho: ∂V/∂r (per 1% change)
 	if o.optionType == mechanisms.OptionTypeCall {
-		rho = K * T * discountFactor * cumulativeNormal(d2) / 100
+		rho = K * T * discountFactor * o.cdf(d2) / 100
 	} else {
-		rho = -K * T * discountFactor * cumulativeNormal(-d2) / 100
+		rho = -K * T * discountFactor * o.cdf(-d2) / 100
 	}
 
 	// Convert to primitives.Decimal
@@ -352,25 +368,52 @@ func (o *Option) Settle(ctx context.Context) (primitives.Amount, error) {
 	return primitives.ZeroAmount(), errors.New("settle requires final underlying price in context metadata with key 'final_price'")
 }
 
-// SettleWithPrice settles the option given a final underlying price.
-// This is a helper method that calculates settlement value.
+// SettleWithPrice settles the option given a final underlying price and
+// returns the absolute value of its P&L.
+//
+// Deprecated: the result does not say whether the option made or lost
+// money; use SettlePnL.
 func (o *Option) SettleWithPrice(finalPrice primitives.Price) (primitives.Amount, error) {
+	pnl, err := o.SettlePnL(finalPrice)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return primitives.NewAmount(pnl.Abs())
+}
+
+// SettlePnL settles the option given a final underlying price and returns
+// its P&L: (intrinsic value - entry price) * position size, negative for a
+// loss such as a long option expiring worth less than its premium.
+// Returns error if the option is already settled.
+func (o *Option) SettlePnL(finalPrice primitives.Price) (primitives.Decimal, error) {
 	if o.settled {
-		return primitives.ZeroAmount(), errors.New("option already settled")
+		return primitives.Zero(), errors.New("option already settled")
 	}
 
 	intrinsic, err := o.intrinsicValue(finalPrice)
 	if err != nil {
-		return primitives.ZeroAmount(), err
+		return primitives.Zero(), err
 	}
 
 	// Calculate P&L: (intrinsic value - entry price) * position size
 	pnlPerContract := intrinsic.Decimal().Sub(o.entryPrice.Decimal())
-	totalPnl := pnlPerContract.Mul(o.positionSize)
-
 	o.settled = true
 
-	return primitives.NewAmount(totalPnl.Abs())
+	return pnlPerContract.Mul(o.positionSize), nil
+}
+
+// Payoff returns the cash the position receives at expiry given a final
+// underlying price: intrinsic value * position size, negative for a short
+// option finishing in the money. The premium is not deducted, so this is
+// the cash flow of settling a position whose premium was paid at entry,
+// as strategy.Expirable.Settle returns. Unlike SettlePnL it does not mark
+// the option settled.
+func (o *Option) Payoff(finalPrice primitives.Price) (primitives.Decimal, error) {
+	intrinsic, err := o.intrinsicValue(finalPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return intrinsic.Decimal().Mul(o.positionSize), nil
 }
 
 // intrinsicValue calculates the intrinsic value of the option.
@@ -396,37 +439,6 @@ func (o *Option) intrinsicValue(underlyingPrice primitives.Price) (primitives.Pr
 	return primitives.NewPrice(intrinsic)
 }
 
-// cumulativeNormal calculates the cumulative standard normal distribution N(x).
-// Uses the approximation by Abramowitz and Stegun (1964).
-// Accurate to about 7.5e-8.
-func cumulativeNormal(x float64) float64 {
-	// Constants for the approximation
-	const (
-		a1 = 0.31938153
-		a2 = -0.356563782
-		a3 = 1.781477937
-		a4 = -1.821255978
-		a5 = 1.330274429
-	)
-
-	k := 1.0 / (1.0 + 0.2316419*math.Abs(x))
-	w := ((((a5*k+a4)*k+a3)*k+a2)*k + a1) * k
-
-	// Standard normal PDF
-	phi := standardNormal(x)
-
-	if x >= 0 {
-		return 1.0 - phi*w
-	}
-	return phi * w
-}
-
-// standardNormal calculates the standard normal probability density function φ(x).
-// φ(x) = (1/√(2π)) * e^(-x²/2)
-func standardNormal(x float64) float64 {
-	return math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
-}
-
 // OptionID returns the option identifier.
 func (o *Option) OptionID() string {
 	return o.optionID
@@ -3,11 +3,15 @@ package blackscholes_test
 import (
 	"context"
 	"math"
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms/mechanismtest"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
 )
 
 // Test constants for option pricing
@@ -691,3 +695,206 @@ func TestGreeksValidation(t *testing.T) {
 		}
 	})
 }
+
+// TestDerivativeContract runs the Derivative property suite for calls and puts.
+func TestDerivativeContract(t *testing.T) {
+	params := func(r *rand.Rand) mechanisms.PriceParams {
+		return mechanisms.PriceParams{
+			UnderlyingPrice: primitives.MustPrice(primitives.NewDecimalFromFloat(50 + r.Float64()*100)),
+			TimeToExpiry:    primitives.NewDecimalFromFloat(0.01 + r.Float64()*2),
+			Volatility:      primitives.NewDecimalFromFloat(0.05 + r.Float64()),
+			RiskFreeRate:    primitives.NewDecimalFromFloat(r.Float64() * 0.1),
+		}
+	}
+
+	tests := []struct {
+		optionType         mechanisms.OptionType
+		minDelta, maxDelta int64
+	}{
+		{mechanisms.OptionTypeCall, 0, 1},
+		{mechanisms.OptionTypePut, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.optionType), func(t *testing.T) {
+			option, err := blackscholes.NewOption(
+				"CONTRACT",
+				tt.optionType,
+				primitives.MustPrice(primitives.NewDecimal(100)),
+				primitives.NewDecimalFromFloat(1.0),
+				primitives.MustPrice(primitives.NewDecimal(10)),
+				primitives.NewDecimalFromFloat(1.0),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create option: %v", err)
+			}
+			mechanismtest.VerifyDerivative(t, option, mechanismtest.DerivativeConfig{
+				Params:           params,
+				MinDelta:         primitives.NewDecimal(tt.minDelta),
+				MaxDelta:         primitives.NewDecimal(tt.maxDelta),
+				NonNegativeGamma: true,
+			})
+		})
+	}
+}
+
+// TestSettlePnL tests that settlement keeps the sign of the P&L.
+func TestSettlePnL(t *testing.T) {
+	tests := []struct {
+		name         string
+		optionType   mechanisms.OptionType
+		positionSize int64
+		finalPrice   int64
+		wantPnL      int64
+		wantPayoff   int64
+	}{
+		{"long ITM call", mechanisms.OptionTypeCall, 2, 115, 10, 30},
+		{"long OTM call", mechanisms.OptionTypeCall, 2, 90, -20, 0},
+		{"short ITM call", mechanisms.OptionTypeCall, -2, 125, -30, -50},
+		{"short OTM put", mechanisms.OptionTypePut, -1, 120, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Strike 100, premium 10
+			option, err := blackscholes.NewOption(
+				"TEST",
+				tt.optionType,
+				primitives.MustPrice(primitives.NewDecimal(100)),
+				primitives.One(),
+				primitives.MustPrice(primitives.NewDecimal(10)),
+				primitives.NewDecimal(tt.positionSize),
+			)
+			if err != nil {
+				t.Fatalf("Failed to create option: %v", err)
+			}
+			final := primitives.MustPrice(primitives.NewDecimal(tt.finalPrice))
+
+			payoff, err := option.Payoff(final)
+			if err != nil {
+				t.Fatalf("Payoff: %v", err)
+			}
+			if !payoff.Equal(primitives.NewDecimal(tt.wantPayoff)) {
+				t.Errorf("payoff = %s, want %d", payoff, tt.wantPayoff)
+			}
+			if option.IsSettled() {
+				t.Error("Payoff should not settle the option")
+			}
+
+			pnl, err := option.SettlePnL(final)
+			if err != nil {
+				t.Fatalf("SettlePnL: %v", err)
+			}
+			if !pnl.Equal(primitives.NewDecimal(tt.wantPnL)) {
+				t.Errorf("P&L = %s, want %d", pnl, tt.wantPnL)
+			}
+			if _, err := option.SettlePnL(final); err == nil {
+				t.Error("Expected error when settling already settled option")
+			}
+		})
+	}
+}
+
+func TestOptionPosition(t *testing.T) {
+	start := primitives.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	snapshot := func(price int64, at primitives.Time) strategy.MarketSnapshot {
+		return strategy.NewSimpleSnapshot(at, map[string]primitives.Price{
+			"ETH/USD": primitives.MustPrice(primitives.NewDecimal(price)),
+		})
+	}
+	// A covered call: short two calls, holding two ETH
+	position, err := blackscholes.NewOptionPosition(blackscholes.PositionConfig{
+		ID:         "covered-call",
+		Pair:       "ETH/USD",
+		Type:       mechanisms.OptionTypeCall,
+		Strike:     primitives.MustPrice(primitives.NewDecimal(2000)),
+		Expiration: start.Add(primitives.Hours(24 * 7)),
+		Quantity:   primitives.NewDecimal(-2),
+		Volatility: primitives.MustDecimalFromString("0.8"),
+		Cover:      primitives.NewDecimal(2),
+	})
+	if err != nil {
+		t.Fatalf("NewOptionPosition: %v", err)
+	}
+	if position.Underlying() != "ETH" || position.Type() != strategy.PositionTypeOption {
+		t.Errorf("unexpected underlying %q or type %s", position.Underlying(), position.Type())
+	}
+
+	value, err := position.Value(snapshot(2000, start))
+	if err != nil {
+		t.Fatalf("Value: %v", err)
+	}
+	// Below the cover, by the premium of the calls sold
+	if v := value.Decimal().Float64(); v >= 4000 || v < 3500 {
+		t.Errorf("value = %.2f, want just under 4000", v)
+	}
+	risk, err := position.Risk(snapshot(2000, start))
+	if err != nil {
+		t.Fatalf("Risk: %v", err)
+	}
+	if d := risk.Delta.Float64(); d <= 0 || d >= 2 {
+		t.Errorf("delta = %.4f, want between 0 and 2", d)
+	}
+	if !risk.Theta.IsPositive() || !risk.Vega.IsNegative() {
+		t.Errorf("short calls should earn theta and be short vega: %+v", risk)
+	}
+
+	// At expiry the cover is called away at the strike
+	expiry := start.Add(primitives.Hours(24 * 7))
+	for _, tt := range []struct {
+		price int64
+		want  int64
+	}{
+		{price: 1800, want: 3600},
+		{price: 2500, want: 4000},
+	} {
+		cash, err := position.Settle(snapshot(tt.price, expiry))
+		if err != nil {
+			t.Fatalf("Settle: %v", err)
+		}
+		if !cash.Equal(primitives.NewDecimal(tt.want)) {
+			t.Errorf("settled at %d for %s, want %d", tt.price, cash, tt.want)
+		}
+	}
+
+	t.Run("naked short settles for negative cash", func(t *testing.T) {
+		naked, err := blackscholes.NewOptionPosition(blackscholes.PositionConfig{
+			ID:         "naked",
+			Pair:       "ETH/USD",
+			Type:       mechanisms.OptionTypeCall,
+			Strike:     primitives.MustPrice(primitives.NewDecimal(2000)),
+			Expiration: expiry,
+			Quantity:   primitives.NewDecimal(-1),
+			Volatility: primitives.MustDecimalFromString("0.8"),
+			Collateral: primitives.NewDecimal(100),
+		})
+		if err != nil {
+			t.Fatalf("NewOptionPosition: %v", err)
+		}
+		value, err := naked.Value(snapshot(2500, expiry))
+		if err != nil || !value.IsZero() {
+			t.Errorf("value = %s, %v; want zero", value, err)
+		}
+		cash, err := naked.Settle(snapshot(2500, expiry))
+		if err != nil {
+			t.Fatalf("Settle: %v", err)
+		}
+		if !cash.Equal(primitives.NewDecimal(-400)) {
+			t.Errorf("settled for %s, want -400", cash)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		if _, err := blackscholes.NewOptionPosition(blackscholes.PositionConfig{ID: "x", Pair: "ETH/USD"}); err == nil {
+			t.Error("expected error for missing strike")
+		}
+		_, err := blackscholes.NewOptionPosition(blackscholes.PositionConfig{
+			ID:         "x",
+			Pair:       "ETH/USD",
+			Strike:     primitives.MustPrice(primitives.NewDecimal(2000)),
+			Collateral: primitives.NewDecimal(-1),
+		})
+		if err == nil {
+			t.Error("expected error for negative collateral")
+		}
+	})
+}
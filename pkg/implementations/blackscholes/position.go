@@ -0,0 +1,192 @@
+package blackscholes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// PositionConfig describes an OptionPosition.
+type PositionConfig struct {
+	// ID is the position's ID in the portfolio
+	ID string
+
+	// Pair is the underlying pair priced from the snapshot (e.g., "ETH/USD")
+	Pair string
+
+	Type       mechanisms.OptionType
+	Strike     primitives.Price
+	Expiration primitives.Time
+
+	// Quantity is the number of contracts, negative for a short position
+	Quantity primitives.Decimal
+
+	// Volatility prices the option when the snapshot has no implied
+	// volatility for Pair (see snapshotkeys.OptionImpliedVol)
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+
+	// Collateral is cash posted with the position, returned at
+	// settlement, and Cover units of the underlying held with it, sold at
+	// settlement; together they secure a short position, e.g. a covered
+	// call with Cover equal to the contracts sold
+	Collateral primitives.Decimal
+	Cover      primitives.Decimal
+}
+
+// OptionPosition holds European options on Pair as a strategy.Position,
+// priced with Black-Scholes at the snapshot's underlying price and
+// implied volatility. It is strategy.Expirable: at Expiration the
+// backtest engine settles it for the options' signed Payoff plus its
+// collateral and cover.
+//
+// Its Value is collateral, cover and the options' signed value, floored
+// at zero: a short position whose loss exceeds its collateral is worth
+// nothing, while its settlement debits the shortfall from cash.
+type OptionPosition struct {
+	config PositionConfig
+}
+
+// NewOptionPosition creates an option position.
+// Returns error if the ID or pair is empty, the strike is not positive, or
+// the collateral, cover or volatility is negative.
+func NewOptionPosition(config PositionConfig) (*OptionPosition, error) {
+	if config.ID == "" || config.Pair == "" {
+		return nil, errors.New("option position requires an ID and a pair")
+	}
+	if config.Strike.IsZero() {
+		return nil, ErrInvalidStrike
+	}
+	if config.Volatility.IsNegative() {
+		return nil, ErrInvalidVolatility
+	}
+	if config.Collateral.IsNegative() || config.Cover.IsNegative() {
+		return nil, fmt.Errorf("option position %s: collateral and cover must be non-negative", config.ID)
+	}
+	return &OptionPosition{config: config}, nil
+}
+
+// ID implements strategy.Position.
+func (p *OptionPosition) ID() string {
+	return p.config.ID
+}
+
+// Type implements strategy.Position.
+func (p *OptionPosition) Type() strategy.PositionType {
+	return strategy.PositionTypeOption
+}
+
+// Config returns the position's terms.
+func (p *OptionPosition) Config() PositionConfig {
+	return p.config
+}
+
+// Expiry implements strategy.Expirable.
+func (p *OptionPosition) Expiry() primitives.Time {
+	return p.config.Expiration
+}
+
+// Underlying implements strategy.UnderlyingPosition.
+func (p *OptionPosition) Underlying() string {
+	return primitives.MustPair(p.config.Pair).Base
+}
+
+// Value implements strategy.Position: collateral plus cover at spot plus
+// Quantity options at their Black-Scholes price, floored at zero. At and
+// after expiration the options are worth their intrinsic value.
+func (p *OptionPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	option, params, err := p.option(snapshot)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	price, err := option.Price(context.Background(), params)
+	if err != nil {
+		return primitives.ZeroAmount(), fmt.Errorf("failed to price %s: %w", p.config.ID, err)
+	}
+	value := p.secured(params.UnderlyingPrice).Add(p.config.Quantity.Mul(price.Decimal()))
+	if value.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(value)
+}
+
+// Risk implements strategy.PositionWithRisk: the options' Greeks times
+// Quantity, plus the cover's delta, with theta per day.
+func (p *OptionPosition) Risk(snapshot strategy.MarketSnapshot) (strategy.RiskMetrics, error) {
+	option, params, err := p.option(snapshot)
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	greeks, err := option.Greeks(context.Background(), params)
+	if err != nil {
+		return strategy.RiskMetrics{}, fmt.Errorf("failed to price %s: %w", p.config.ID, err)
+	}
+	// Option theta is per year, a position's per day
+	theta, err := greeks.Theta.Div(primitives.NewDecimal(365))
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	q := p.config.Quantity
+	return strategy.RiskMetrics{
+		Delta:    q.Mul(greeks.Delta).Add(p.config.Cover),
+		Gamma:    q.Mul(greeks.Gamma),
+		Vega:     q.Mul(greeks.Vega),
+		Theta:    q.Mul(theta),
+		Rho:      q.Mul(greeks.Rho),
+		Leverage: primitives.One(),
+	}, nil
+}
+
+// Settle implements strategy.Settleable: the options' signed Payoff at the
+// snapshot's underlying price plus the collateral and cover, negative
+// when a short position pays out more than secures it.
+func (p *OptionPosition) Settle(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	option, params, err := p.option(snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	payoff, err := option.Payoff(params.UnderlyingPrice)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("failed to settle %s: %w", p.config.ID, err)
+	}
+	return p.secured(params.UnderlyingPrice).Add(payoff), nil
+}
+
+// secured returns the collateral plus the cover at spot.
+func (p *OptionPosition) secured(spot primitives.Price) primitives.Decimal {
+	return p.config.Collateral.Add(p.config.Cover.Mul(spot.Decimal()))
+}
+
+// option returns the position's options and their pricing inputs at
+// snapshot.
+func (p *OptionPosition) option(snapshot strategy.MarketSnapshot) (*Option, mechanisms.PriceParams, error) {
+	spot, err := snapshot.Price(p.config.Pair)
+	if err != nil {
+		return nil, mechanisms.PriceParams{}, err
+	}
+	vol := p.config.Volatility
+	if implied, err := strategy.GetDecimal(snapshot, snapshotkeys.OptionImpliedVol(p.config.Pair)); err == nil {
+		vol = implied
+	}
+	years := primitives.Zero()
+	if now := snapshot.Time(); p.config.Expiration.After(now) {
+		years = primitives.NewDecimalFromFloat(p.config.Expiration.Sub(now).Hours() / primitives.Year.Hours())
+	}
+
+	// Entry price does not affect pricing; the strike stands in
+	option, err := NewOption(p.config.ID, p.config.Type, p.config.Strike, years, p.config.Strike, p.config.Quantity)
+	if err != nil {
+		return nil, mechanisms.PriceParams{}, err
+	}
+	return option, mechanisms.PriceParams{
+		UnderlyingPrice: spot,
+		TimeToExpiry:    years,
+		Volatility:      vol,
+		RiskFreeRate:    p.config.RiskFreeRate,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package concentrated_liquidity
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// AdversarialFlow models the fee income passive LPs lose to adversarial
+// flow: just-in-time liquidity that is minted ahead of a large swap and
+// burned after it, diluting the passive share of that swap's fee, and
+// MEV such as sandwiches and arbitrage against stale prices, which grows
+// with volatility. It is a haircut on the fees a position would otherwise
+// capture:
+//
+//	haircut = Base + SizeWeight × s / (s + SizeReference) + VolatilityWeight × σ
+//
+// clamped to [0, Max], where s is the average swap size over the snapshot
+// period and σ the annualized volatility. The size term saturates: swaps
+// much larger than SizeReference lose about SizeWeight to JIT liquidity.
+//
+// Set one on a Pool with SetAdversarialFlow so SnapshotFees reports fees
+// net of the haircut. The weights are assumptions to calibrate against a
+// pool's history, not estimates this package makes.
+type AdversarialFlow struct {
+	// Base is the haircut applied regardless of flow
+	Base primitives.Decimal
+
+	// SizeWeight is the haircut approached as the average swap size grows
+	// past SizeReference, in the currency of snapshotkeys.PoolVolume. The
+	// average swap size is PoolVolume over snapshotkeys.PoolSwapCount.
+	SizeWeight    primitives.Decimal
+	SizeReference primitives.Amount
+
+	// VolatilityWeight is the haircut per unit of annualized volatility,
+	// read from snapshot metadata at VolatilityKey (e.g.,
+	// analytics.RealizedVolatilityKey("ETH/USD"))
+	VolatilityWeight primitives.Decimal
+	VolatilityKey    string
+
+	// Max caps the haircut; zero means 1
+	Max primitives.Decimal
+}
+
+// Validate returns ErrInvalidPoolParams if a weight is negative, Max is
+// outside [0, 1], a size weight has no positive reference or a volatility
+// weight has no key.
+func (f AdversarialFlow) Validate() error {
+	for _, weight := range []struct {
+		name  string
+		value primitives.Decimal
+	}{{"base", f.Base}, {"size weight", f.SizeWeight}, {"volatility weight", f.VolatilityWeight}} {
+		if weight.value.IsNegative() {
+			return fmt.Errorf("%w: adversarial flow %s cannot be negative, got %s", ErrInvalidPoolParams, weight.name, weight.value)
+		}
+	}
+	if f.Max.IsNegative() || f.Max.GreaterThan(primitives.One()) {
+		return fmt.Errorf("%w: adversarial flow max must be in [0, 1], got %s", ErrInvalidPoolParams, f.Max)
+	}
+	if f.SizeWeight.IsPositive() && f.SizeReference.IsZero() {
+		return fmt.Errorf("%w: adversarial flow size weight needs a positive size reference", ErrInvalidPoolParams)
+	}
+	if f.VolatilityWeight.IsPositive() && f.VolatilityKey == "" {
+		return fmt.Errorf("%w: adversarial flow volatility weight needs a volatility key", ErrInvalidPoolParams)
+	}
+	return nil
+}
+
+// Haircut returns the fraction of fees lost at an average swap size and
+// annualized volatility.
+func (f AdversarialFlow) Haircut(averageSwap, volatility primitives.Decimal) primitives.Decimal {
+	haircut := f.Base.Add(f.VolatilityWeight.Mul(volatility))
+	if f.SizeWeight.IsPositive() && averageSwap.IsPositive() {
+		saturation, err := averageSwap.Div(averageSwap.Add(f.SizeReference.Decimal()))
+		if err == nil {
+			haircut = haircut.Add(f.SizeWeight.Mul(saturation))
+		}
+	}
+
+	limit := f.Max
+	if limit.IsZero() {
+		limit = primitives.One()
+	}
+	switch {
+	case haircut.IsNegative():
+		return primitives.Zero()
+	case haircut.GreaterThan(limit):
+		return limit
+	}
+	return haircut
+}
+
+// SnapshotHaircut returns the haircut at snapshot for a pool, reading the
+// pool's volume and swap count and the volatility at VolatilityKey. The
+// inputs of a zero weight are not read.
+func (f AdversarialFlow) SnapshotHaircut(poolID string, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	averageSwap := primitives.Zero()
+	if f.SizeWeight.IsPositive() {
+		volume, err := strategy.GetDecimal(snapshot, snapshotkeys.PoolVolume(poolID))
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("volume of pool %s: %w", poolID, err)
+		}
+		swaps, err := strategy.GetInt(snapshot, snapshotkeys.PoolSwapCount(poolID))
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("swap count of pool %s: %w", poolID, err)
+		}
+		if swaps > 0 {
+			if averageSwap, err = volume.Div(primitives.NewDecimal(int64(swaps))); err != nil {
+				return primitives.Zero(), err
+			}
+		}
+	}
+
+	volatility := primitives.Zero()
+	if f.VolatilityWeight.IsPositive() {
+		var err error
+		if volatility, err = strategy.GetDecimal(snapshot, f.VolatilityKey); err != nil {
+			return primitives.Zero(), fmt.Errorf("volatility for pool %s: %w", poolID, err)
+		}
+	}
+	return f.Haircut(averageSwap, volatility), nil
+}
+
+// SetAdversarialFlow sets the adversarial flow model SnapshotFees applies;
+// nil, the default, reports fees without a haircut. Returns
+// ErrInvalidPoolParams if the model does not validate.
+func (p *Pool) SetAdversarialFlow(flow *AdversarialFlow) error {
+	if flow != nil {
+		if err := flow.Validate(); err != nil {
+			return err
+		}
+	}
+	p.adversarial = flow
+	return nil
+}
+
+// AdversarialFlow returns the pool's adversarial flow model, or nil.
+func (p *Pool) AdversarialFlow() *AdversarialFlow {
+	return p.adversarial
+}
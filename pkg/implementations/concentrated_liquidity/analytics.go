@@ -0,0 +1,302 @@
+package concentrated_liquidity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PositionValue decomposes a position's value into its token legs.
+type PositionValue struct {
+	// AmountA and AmountB are the tokens the position would withdraw
+	AmountA primitives.Amount
+	AmountB primitives.Amount
+
+	// ValueA and ValueB are each leg valued at its price
+	ValueA primitives.Amount
+	ValueB primitives.Amount
+
+	// Total is ValueA + ValueB
+	Total primitives.Amount
+}
+
+// ShareA returns the fraction of the value held in token A, or zero for
+// an empty position.
+func (v PositionValue) ShareA() primitives.Decimal {
+	share, err := v.ValueA.Decimal().Div(v.Total.Decimal())
+	if err != nil {
+		return primitives.Zero()
+	}
+	return share
+}
+
+// Decompose splits a position's value into its token A and token B legs,
+// valuing each at its price in a common quote currency. The position needs
+// the metadata RemoveLiquidity requires; outside its range it is entirely
+// token A (below) or token B (above).
+func (p *Pool) Decompose(position mechanisms.PoolPosition, priceA, priceB primitives.Price) (PositionValue, error) {
+	amounts, err := p.rangeAmounts(position)
+	if err != nil {
+		return PositionValue{}, err
+	}
+	value := PositionValue{
+		AmountA: amounts.AmountA,
+		AmountB: amounts.AmountB,
+		ValueA:  amounts.AmountA.MulPrice(priceA),
+		ValueB:  amounts.AmountB.MulPrice(priceB),
+	}
+	value.Total = value.ValueA.Add(value.ValueB)
+	return value, nil
+}
+
+// PositionGreeks returns the sensitivities of a position's value, in
+// token B, to the price of token A in token B (human units of each token).
+// The position needs the metadata RemoveLiquidity requires.
+//
+// With liquidity L over sqrt prices [√Pa, √Pb] the position holds
+// x = L(1/√P - 1/√Pb) of token A while in range, so:
+//   - Delta = x: the token A held, L(1/√Pa - 1/√Pb) below the range and
+//     zero above it
+//   - Gamma = -L / (2 P^(3/2)) in range and zero outside it; an LP
+//     position is always short gamma
+//
+// Theta, Vega and Rho are zero: fee income is not a price sensitivity.
+// Gamma is computed in float64.
+func (p *Pool) PositionGreeks(position mechanisms.PoolPosition) (mechanisms.Greeks, error) {
+	amounts, err := p.rangeAmounts(position)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	liquidity, lower, upper, sqrtPrice, err := p.positionRange(position)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+
+	gamma := primitives.Zero()
+	if sqrtPrice > lower && sqrtPrice < upper {
+		// Raw units: d²V/dP² = -L / (2 P^(3/2)). Converting to human units
+		// scales by 10^(decimalsB - decimalsA) / 10^decimalsA.
+		decimalsA, decimalsB := float64(p.tokenA.Decimals()), float64(p.tokenB.Decimals())
+		raw := -liquidity / (2 * sqrtPrice * sqrtPrice * sqrtPrice)
+		gamma = primitives.NewDecimalFromFloat(raw * math.Pow(10, decimalsB-2*decimalsA))
+	}
+
+	return mechanisms.Greeks{
+		Delta: amounts.AmountA.Decimal(),
+		Gamma: gamma,
+		Theta: primitives.Zero(),
+		Vega:  primitives.Zero(),
+		Rho:   primitives.Zero(),
+	}, nil
+}
+
+// rangeAmounts returns the tokens a position holds, with the current price
+// clamped to the position's range so an out-of-range position holds only
+// one token.
+func (p *Pool) rangeAmounts(position mechanisms.PoolPosition) (mechanisms.TokenAmounts, error) {
+	tickLower, okLower := position.Metadata["tick_lower"].(int)
+	tickUpper, okUpper := position.Metadata["tick_upper"].(int)
+	sqrtPriceX96Str, okPrice := position.Metadata["sqrt_price_x96"].(string)
+	if !okLower || !okUpper || !okPrice {
+		return p.RemoveLiquidity(context.Background(), position)
+	}
+	sqrtPriceX96, ok := new(big.Int).SetString(sqrtPriceX96Str, 10)
+	if !ok {
+		return mechanisms.TokenAmounts{}, errors.New("invalid sqrt_price_x96 format")
+	}
+	sqrtLower, err := utils.GetSqrtRatioAtTick(tickLower)
+	if err != nil {
+		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid tickLower: %w", err)
+	}
+	sqrtUpper, err := utils.GetSqrtRatioAtTick(tickUpper)
+	if err != nil {
+		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid tickUpper: %w", err)
+	}
+
+	clamped := sqrtPriceX96
+	if clamped.Cmp(sqrtLower) < 0 {
+		clamped = sqrtLower
+	} else if clamped.Cmp(sqrtUpper) > 0 {
+		clamped = sqrtUpper
+	}
+	if clamped == sqrtPriceX96 {
+		return p.RemoveLiquidity(context.Background(), position)
+	}
+	metadata := make(map[string]interface{}, len(position.Metadata))
+	for k, v := range position.Metadata {
+		metadata[k] = v
+	}
+	metadata["sqrt_price_x96"] = clamped.String()
+	position.Metadata = metadata
+	return p.RemoveLiquidity(context.Background(), position)
+}
+
+// positionRange returns a position's liquidity and its range and current
+// sqrt prices (raw, not Q96-scaled) as float64.
+func (p *Pool) positionRange(position mechanisms.PoolPosition) (liquidity, lower, upper, current float64, err error) {
+	liquidityStr, _ := position.Metadata["liquidity"].(string)
+	tickLower, _ := position.Metadata["tick_lower"].(int)
+	tickUpper, _ := position.Metadata["tick_upper"].(int)
+	sqrtPriceX96Str, _ := position.Metadata["sqrt_price_x96"].(string)
+
+	l, ok := new(big.Float).SetString(liquidityStr)
+	if !ok {
+		return 0, 0, 0, 0, errors.New("invalid liquidity format")
+	}
+	sqrtPriceX96, ok := new(big.Float).SetString(sqrtPriceX96Str)
+	if !ok {
+		return 0, 0, 0, 0, errors.New("invalid sqrt_price_x96 format")
+	}
+	sqrtLower, err := utils.GetSqrtRatioAtTick(tickLower)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("invalid tickLower: %w", err)
+	}
+	sqrtUpper, err := utils.GetSqrtRatioAtTick(tickUpper)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("invalid tickUpper: %w", err)
+	}
+
+	liquidity, _ = l.Float64()
+	current, _ = new(big.Float).Quo(sqrtPriceX96, q96).Float64()
+	lower, _ = new(big.Float).Quo(new(big.Float).SetInt(sqrtLower), q96).Float64()
+	upper, _ = new(big.Float).Quo(new(big.Float).SetInt(sqrtUpper), q96).Float64()
+	return liquidity, lower, upper, current, nil
+}
+
+// InRange reports whether a position over [tickLower, tickUpper) earns
+// fees at currentTick.
+func InRange(tickLower, tickUpper, currentTick int) bool {
+	return currentTick >= tickLower && currentTick < tickUpper
+}
+
+// RangeTracker measures how long a tick range is in range, weighting each
+// observation by the time until the next one.
+type RangeTracker struct {
+	tickLower int
+	tickUpper int
+
+	started bool
+	last    primitives.Time
+	inRange bool
+
+	total primitives.Duration
+	in    primitives.Duration
+}
+
+// NewRangeTracker creates a tracker for the range [tickLower, tickUpper).
+// Returns ErrInvalidTickRange if tickLower is not below tickUpper.
+func NewRangeTracker(tickLower, tickUpper int) (*RangeTracker, error) {
+	if tickLower >= tickUpper {
+		return nil, ErrInvalidTickRange
+	}
+	return &RangeTracker{tickLower: tickLower, tickUpper: tickUpper}, nil
+}
+
+// Observe records the pool's tick at t; the state holds until the next
+// observation. Returns error if t precedes the previous observation.
+func (r *RangeTracker) Observe(t primitives.Time, currentTick int) error {
+	if r.started {
+		if t.Before(r.last) {
+			return fmt.Errorf("observation at %s precedes %s", t, r.last)
+		}
+		elapsed := t.Sub(r.last)
+		r.total = r.total.Add(elapsed)
+		if r.inRange {
+			r.in = r.in.Add(elapsed)
+		}
+	}
+	r.started, r.last, r.inRange = true, t, InRange(r.tickLower, r.tickUpper, currentTick)
+	return nil
+}
+
+// InRangeTime returns the time observed in range.
+func (r *RangeTracker) InRangeTime() primitives.Duration {
+	return r.in
+}
+
+// TotalTime returns the time between the first and last observations.
+func (r *RangeTracker) TotalTime() primitives.Duration {
+	return r.total
+}
+
+// InRangeFraction returns the share of observed time spent in range, or
+// zero before two observations.
+func (r *RangeTracker) InRangeFraction() primitives.Decimal {
+	if r.total.IsZero() {
+		return primitives.Zero()
+	}
+	return primitives.NewDecimalFromFloat(float64(r.in.Duration()) / float64(r.total.Duration()))
+}
+
+// VolumeAssumptions are the inputs to ExpectedFeeAPR.
+type VolumeAssumptions struct {
+	// DailyVolume is the pool's swap volume per day, in the currency the
+	// position is valued in
+	DailyVolume primitives.Amount
+
+	// PoolLiquidity is the pool's active liquidity at the current price,
+	// including the position, in the units of the position's liquidity
+	PoolLiquidity primitives.Amount
+
+	// InRange is the expected fraction of time the position is in range,
+	// e.g., a RangeTracker's InRangeFraction; 1 for always
+	InRange primitives.Decimal
+
+	// Haircut is the fraction of fees lost to adversarial flow, e.g., an
+	// AdversarialFlow's Haircut; zero for none
+	Haircut primitives.Decimal
+}
+
+// ExpectedFeeAPR returns the annual simple fee yield of a position worth
+// value under volume assumptions: the pool fee on daily volume, times the
+// position's share of active liquidity and the time it is in range,
+// annualized over primitives.Year and divided by value, less the Haircut.
+// Returns ErrInvalidPoolParams if value or the pool liquidity is not
+// positive or InRange or Haircut is outside [0, 1].
+func (p *Pool) ExpectedFeeAPR(position mechanisms.PoolPosition, value primitives.Amount, assumptions VolumeAssumptions) (primitives.Rate, error) {
+	if !value.Decimal().IsPositive() {
+		return primitives.Rate{}, fmt.Errorf("%w: position value must be positive", ErrInvalidPoolParams)
+	}
+	if !assumptions.PoolLiquidity.Decimal().IsPositive() {
+		return primitives.Rate{}, fmt.Errorf("%w: pool liquidity must be positive", ErrInvalidPoolParams)
+	}
+	if assumptions.InRange.IsNegative() || assumptions.InRange.GreaterThan(primitives.One()) {
+		return primitives.Rate{}, fmt.Errorf("%w: in-range fraction %s outside [0, 1]", ErrInvalidPoolParams, assumptions.InRange)
+	}
+	if assumptions.Haircut.IsNegative() || assumptions.Haircut.GreaterThan(primitives.One()) {
+		return primitives.Rate{}, fmt.Errorf("%w: haircut %s outside [0, 1]", ErrInvalidPoolParams, assumptions.Haircut)
+	}
+	liquidityStr, ok := position.Metadata["liquidity"].(string)
+	if !ok {
+		return primitives.Rate{}, errors.New("liquidity required in position metadata")
+	}
+	liquidity, err := primitives.NewDecimalFromString(liquidityStr)
+	if err != nil {
+		return primitives.Rate{}, fmt.Errorf("invalid liquidity format: %w", err)
+	}
+
+	share, err := liquidity.Div(assumptions.PoolLiquidity.Decimal())
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	feeRate, err := primitives.NewDecimal(int64(p.fee)).Div(primitives.NewDecimal(1_000_000))
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	dailyFees := assumptions.DailyVolume.Decimal().Mul(feeRate).Mul(share).Mul(assumptions.InRange).Mul(primitives.One().Sub(assumptions.Haircut))
+	dailyYield, err := dailyFees.Div(value.Decimal())
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	daily, err := primitives.NewRate(dailyYield, primitives.Hours(24), primitives.CompoundingSimple)
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	return daily.Annualized(), nil
+}
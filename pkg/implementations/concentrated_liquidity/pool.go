@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 
 	core "github.com/daoleno/uniswap-sdk-core/entities"
 	"github.com/daoleno/uniswapv3-sdk/constants"
@@ -12,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/tokens"
 )
 
 var (
@@ -25,6 +27,28 @@ var (
 	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
 )
 
+// q96 is 2^96, the Q64.96 fixed-point scale of sqrtPriceX96.
+var q96 = new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 96))
+
+// bigIntPool recycles the buffers used to parse metadata integers on the
+// Calculate and RemoveLiquidity hot paths.
+var bigIntPool = sync.Pool{New: func() interface{} { return new(big.Int) }}
+
+// parseBigInt parses a base-10 integer into a pooled buffer.
+// Callers must release the result with releaseBigInt.
+func parseBigInt(s string) (*big.Int, bool) {
+	n := bigIntPool.Get().(*big.Int)
+	if _, ok := n.SetString(s, 10); !ok {
+		releaseBigInt(n)
+		return nil, false
+	}
+	return n, true
+}
+
+func releaseBigInt(n *big.Int) {
+	bigIntPool.Put(n)
+}
+
 // Pool implements the LiquidityPool interface for Uniswap V3 style concentrated liquidity.
 // It wraps the daoleno/uniswapv3-sdk library to provide integration with our framework.
 //
@@ -36,9 +60,16 @@ type Pool struct {
 	tokenB      *core.Token
 	fee         constants.FeeAmount
 	tickSpacing int
+
+	// decimalAdjustment is 10^(tokenB.decimals - tokenA.decimals),
+	// precomputed for spot price conversion
+	decimalAdjustment *big.Float
+
+	// adversarial is the optional haircut SnapshotFees applies
+	adversarial *AdversarialFlow
 }
 
-// NewPool creates a new concentrated liquidity pool.
+// NewPool creates a new concentrated liquidity pool on Ethereum mainnet.
 //
 // Parameters:
 //   - poolID: Unique identifier for this pool
@@ -49,7 +80,8 @@ type Pool struct {
 //   - fee: Fee tier (500 for 0.05%, 3000 for 0.3%, 10000 for 1%)
 //
 // The pool uses Uniswap V3's concentrated liquidity model where liquidity providers
-// can specify price ranges for their capital.
+// can specify price ranges for their capital. NewPoolForTokens takes tokens
+// from a tokens.Registry instead, on any chain.
 func NewPool(
 	poolID string,
 	tokenAAddress common.Address,
@@ -58,31 +90,75 @@ func NewPool(
 	tokenBDecimals uint,
 	fee constants.FeeAmount,
 ) (*Pool, error) {
+	tokenA := core.NewToken(uint(tokens.Mainnet), tokenAAddress, tokenADecimals, "", "")
+	tokenB := core.NewToken(uint(tokens.Mainnet), tokenBAddress, tokenBDecimals, "", "")
+	return newPool(poolID, tokenA, tokenB, fee)
+}
+
+// NewPoolForTokens creates a concentrated liquidity pool between two
+// registered tokens, taking their chain, addresses, decimals and symbols
+// from the registry entries, e.g.:
+//
+//	weth, _ := tokens.Lookup(tokens.Arbitrum, "WETH")
+//	usdc, _ := tokens.Lookup(tokens.Arbitrum, "USDC")
+//	pool, err := NewPoolForTokens("weth-usdc", weth, usdc, constants.FeeMedium)
+//
+// Returns ErrInvalidPoolParams if the tokens are on different chains.
+func NewPoolForTokens(poolID string, tokenA, tokenB tokens.Token, fee constants.FeeAmount) (*Pool, error) {
+	if tokenA.ChainID != tokenB.ChainID {
+		return nil, fmt.Errorf("%w: %s and %s are on different chains", ErrInvalidPoolParams, tokenA, tokenB)
+	}
+	if tokenA.Decimals < 0 || tokenB.Decimals < 0 {
+		return nil, fmt.Errorf("%w: negative token decimals", ErrInvalidPoolParams)
+	}
+	return newPool(poolID,
+		core.NewToken(uint(tokenA.ChainID), tokenA.Address, uint(tokenA.Decimals), tokenA.Symbol, ""),
+		core.NewToken(uint(tokenB.ChainID), tokenB.Address, uint(tokenB.Decimals), tokenB.Symbol, ""),
+		fee)
+}
+
+func newPool(poolID string, tokenA, tokenB *core.Token, fee constants.FeeAmount) (*Pool, error) {
 	// Validate inputs
 	if poolID == "" {
 		return nil, errors.New("poolID cannot be empty")
 	}
 
-	// Create token instances using the SDK
-	// Chain ID is set to 1 (mainnet) but could be parameterized if needed
-	tokenA := core.NewToken(1, tokenAAddress, tokenADecimals, "", "")
-	tokenB := core.NewToken(1, tokenBAddress, tokenBDecimals, "", "")
-
 	// Get tick spacing for the fee tier
 	tickSpacing, ok := constants.TickSpacings[fee]
 	if !ok {
 		return nil, fmt.Errorf("invalid fee amount: %d", fee)
 	}
 
+	// Adjust for decimals: price * 10^(tokenB.decimals - tokenA.decimals)
+	decimalAdjustment := new(big.Int).Exp(
+		big.NewInt(10),
+		big.NewInt(int64(tokenB.Decimals())-int64(tokenA.Decimals())),
+		nil,
+	)
+
 	return &Pool{
-		poolID:      poolID,
-		tokenA:      tokenA,
-		tokenB:      tokenB,
-		fee:         fee,
-		tickSpacing: tickSpacing,
+		poolID:            poolID,
+		tokenA:            tokenA,
+		tokenB:            tokenB,
+		fee:               fee,
+		tickSpacing:       tickSpacing,
+		decimalAdjustment: new(big.Float).SetInt(decimalAdjustment),
 	}, nil
 }
 
+// ChainID returns the chain the pool's tokens are deployed on.
+func (p *Pool) ChainID() tokens.ChainID {
+	return tokens.ChainID(p.tokenA.ChainId())
+}
+
+// CurrencySpecs returns the denominations of token A and token B.
+// RemoveLiquidity converts the SDK's raw token units to human units with
+// these specs, and Calculate quantizes the spot price to token B's precision.
+func (p *Pool) CurrencySpecs() (primitives.CurrencySpec, primitives.CurrencySpec) {
+	return primitives.CurrencySpec{Symbol: p.tokenA.Symbol(), Decimals: int32(p.tokenA.Decimals())},
+		primitives.CurrencySpec{Symbol: p.tokenB.Symbol(), Decimals: int32(p.tokenB.Decimals())}
+}
+
 // Mechanism returns the mechanism type identifier.
 func (p *Pool) Mechanism() mechanisms.MechanismType {
 	return mechanisms.MechanismTypeLiquidityPool
@@ -119,35 +195,26 @@ func (p *Pool) Calculate(ctx context.Context, params mechanisms.PoolParams) (mec
 	}
 
 	// Parse sqrt price
-	sqrtPriceX96, ok := new(big.Int).SetString(sqrtPriceX96Str, 10)
+	sqrtPriceX96, ok := parseBigInt(sqrtPriceX96Str)
 	if !ok {
 		return mechanisms.PoolState{}, errors.New("invalid sqrt_price_x96 format")
 	}
+	defer releaseBigInt(sqrtPriceX96)
 
 	// Parse liquidity
-	liquidity, ok := new(big.Int).SetString(liquidityStr, 10)
+	liquidity, ok := parseBigInt(liquidityStr)
 	if !ok {
 		return mechanisms.PoolState{}, errors.New("invalid liquidity format")
 	}
+	defer releaseBigInt(liquidity)
 
 	// Calculate spot price from sqrt price
 	// price = (sqrtPriceX96 / 2^96)^2
-	q96 := new(big.Int).Exp(big.NewInt(2), big.NewInt(96), nil)
-	sqrtPrice := new(big.Float).Quo(
-		new(big.Float).SetInt(sqrtPriceX96),
-		new(big.Float).SetInt(q96),
-	)
+	sqrtPrice := new(big.Float).Quo(new(big.Float).SetInt(sqrtPriceX96), q96)
 
-	// Square to get price
+	// Square to get price, then adjust for token decimals
 	priceFloat := new(big.Float).Mul(sqrtPrice, sqrtPrice)
-
-	// Adjust for decimals: price * 10^(tokenB.decimals - tokenA.decimals)
-	decimalAdjustment := new(big.Int).Exp(
-		big.NewInt(10),
-		big.NewInt(int64(p.tokenB.Decimals())-int64(p.tokenA.Decimals())),
-		nil,
-	)
-	adjustedPrice := new(big.Float).Mul(priceFloat, new(big.Float).SetInt(decimalAdjustment))
+	adjustedPrice := new(big.Float).Mul(priceFloat, p.decimalAdjustment)
 
 	// Convert to primitives.Price
 	priceRat, _ := adjustedPrice.Rat(nil)
@@ -159,6 +226,8 @@ func (p *Pool) Calculate(ctx context.Context, params mechanisms.PoolParams) (mec
 	if err != nil {
 		return mechanisms.PoolState{}, fmt.Errorf("invalid spot price: %w", err)
 	}
+	_, specB := p.CurrencySpecs()
+	spotPrice = spotPrice.Round(specB.Decimals, primitives.RoundHalfEven)
 
 	// Convert liquidity to Amount
 	liquidityDec, err := primitives.NewDecimalFromString(liquidity.String())
@@ -209,7 +278,8 @@ func (p *Pool) AddLiquidity(ctx context.Context, amounts mechanisms.TokenAmounts
 
 // RemoveLiquidity simulates removing liquidity from the pool.
 //
-// Returns the token amounts that would be withdrawn for the given position.
+// Returns the token amounts that would be withdrawn for the given position,
+// in human units of each token (e.g., 1.5 WETH rather than 1.5e18 wei).
 func (p *Pool) RemoveLiquidity(ctx context.Context, position mechanisms.PoolPosition) (mechanisms.TokenAmounts, error) {
 	// Extract position information
 	liquidityStr, ok := position.Metadata["liquidity"].(string)
@@ -233,15 +303,17 @@ func (p *Pool) RemoveLiquidity(ctx context.Context, position mechanisms.PoolPosi
 	}
 
 	// Parse values
-	liquidity, ok := new(big.Int).SetString(liquidityStr, 10)
+	liquidity, ok := parseBigInt(liquidityStr)
 	if !ok {
 		return mechanisms.TokenAmounts{}, errors.New("invalid liquidity format")
 	}
+	defer releaseBigInt(liquidity)
 
-	sqrtPriceX96, ok := new(big.Int).SetString(sqrtPriceX96Str, 10)
+	sqrtPriceX96, ok := parseBigInt(sqrtPriceX96Str)
 	if !ok {
 		return mechanisms.TokenAmounts{}, errors.New("invalid sqrt_price_x96 format")
 	}
+	defer releaseBigInt(sqrtPriceX96)
 
 	// Calculate sqrt prices at tick boundaries
 	sqrtPriceLower, err := utils.GetSqrtRatioAtTick(tickLower)
@@ -271,24 +343,20 @@ func (p *Pool) RemoveLiquidity(ctx context.Context, position mechanisms.PoolPosi
 		false, // roundUp = false for removals
 	)
 
-	// Convert to our Amount types
-	amount0Dec, err := primitives.NewDecimalFromString(amount0.String())
-	if err != nil {
-		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid amount0 decimal: %w", err)
-	}
-	amountA, err := primitives.NewAmount(amount0Dec)
+	// Convert raw token units to human units. Removals round down, so
+	// quantizing to each token's precision never creates dust.
+	specA, specB := p.CurrencySpecs()
+	amountA, err := specA.FromRaw(amount0)
 	if err != nil {
 		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid amount0: %w", err)
 	}
+	amountA = specA.Quantize(amountA, primitives.RoundDown)
 
-	amount1Dec, err := primitives.NewDecimalFromString(amount1.String())
-	if err != nil {
-		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid amount1 decimal: %w", err)
-	}
-	amountB, err := primitives.NewAmount(amount1Dec)
+	amountB, err := specB.FromRaw(amount1)
 	if err != nil {
 		return mechanisms.TokenAmounts{}, fmt.Errorf("invalid amount1: %w", err)
 	}
+	amountB = specB.Quantize(amountB, primitives.RoundDown)
 
 	return mechanisms.TokenAmounts{
 		AmountA: amountA,
@@ -2,13 +2,23 @@ package concentrated_liquidity_test
 
 import (
 	"context"
+	"errors"
+	"math"
 	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/daoleno/uniswapv3-sdk/constants"
+	"github.com/daoleno/uniswapv3-sdk/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/concentrated_liquidity"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms/mechanismtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/tokens"
 )
 
 // Test tokens (USDC/WETH on mainnet)
@@ -171,6 +181,57 @@ func TestPoolCalculate(t *testing.T) {
 	}
 }
 
+// TestCurrencySpecs verifies token denominations are exposed for raw unit conversion.
+func TestCurrencySpecs(t *testing.T) {
+	pool, err := concentrated_liquidity.NewPool("usdc-weth-3000", usdcAddress, 6, wethAddress, 18, constants.FeeMedium)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	specA, specB := pool.CurrencySpecs()
+	if specA.Decimals != 6 || specB.Decimals != 18 {
+		t.Errorf("Expected decimals 6/18, got %d/%d", specA.Decimals, specB.Decimals)
+	}
+
+	raw := primitives.MustAmount(primitives.NewDecimal(2500000))
+	if got := specA.FromRawAmount(raw); got.String() != "2.5" {
+		t.Errorf("Expected 2.5 USDC, got %s", got)
+	}
+}
+
+// TestNewPoolForTokens verifies pools built from registered tokens carry
+// their chain, decimals and symbols.
+func TestNewPoolForTokens(t *testing.T) {
+	weth, err := tokens.Lookup(tokens.Arbitrum, "WETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usdc, err := tokens.Lookup(tokens.Arbitrum, "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := concentrated_liquidity.NewPoolForTokens("weth-usdc-500", weth, usdc, constants.FeeLow)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	if pool.ChainID() != tokens.Arbitrum {
+		t.Errorf("Expected chain %s, got %s", tokens.Arbitrum, pool.ChainID())
+	}
+	specA, specB := pool.CurrencySpecs()
+	if specA.String() != "WETH(18)" || specB.String() != "USDC(6)" {
+		t.Errorf("Expected WETH(18)/USDC(6), got %s/%s", specA, specB)
+	}
+
+	mainnetUSDC, err := tokens.Lookup(tokens.Mainnet, "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := concentrated_liquidity.NewPoolForTokens("cross-chain", weth, mainnetUSDC, constants.FeeLow); !errors.Is(err, concentrated_liquidity.ErrInvalidPoolParams) {
+		t.Errorf("Expected ErrInvalidPoolParams for tokens on different chains, got %v", err)
+	}
+}
+
 // TestRemoveLiquidity verifies that removing liquidity calculates correct token amounts.
 func TestRemoveLiquidity(t *testing.T) {
 	pool, err := concentrated_liquidity.NewPool(
@@ -215,6 +276,18 @@ func TestRemoveLiquidity(t *testing.T) {
 	if amounts.AmountA.IsZero() && amounts.AmountB.IsZero() {
 		t.Error("Expected at least one non-zero amount")
 	}
+
+	// Amounts are in human units, quantized to each token's precision
+	specA, specB := pool.CurrencySpecs()
+	if _, err := specA.ToRaw(amounts.AmountA); err != nil {
+		t.Errorf("AmountA %s finer than USDC precision: %v", amounts.AmountA, err)
+	}
+	if _, err := specB.ToRaw(amounts.AmountB); err != nil {
+		t.Errorf("AmountB %s finer than WETH precision: %v", amounts.AmountB, err)
+	}
+	if got := amounts.AmountB.String(); got != "21.950969765084031473" {
+		t.Errorf("Expected 21.950969765084031473 WETH, got %s", got)
+	}
 }
 
 // TestRemoveLiquidityErrors verifies error handling for invalid position data.
@@ -569,3 +642,298 @@ func BenchmarkRemoveLiquidity(b *testing.B) {
 		}
 	}
 }
+
+// TestLiquidityPoolContract runs the LiquidityPool property suite.
+// AddLiquidity is not yet supported, so the roundtrip property is skipped.
+func TestLiquidityPoolContract(t *testing.T) {
+	pool, err := concentrated_liquidity.NewPool("usdc-weth-3000", usdcAddress, 6, wethAddress, 18, constants.FeeMedium)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+
+	sqrtPriceAt := func(tick int) string {
+		sqrtPrice, err := utils.GetSqrtRatioAtTick(tick)
+		if err != nil {
+			t.Fatalf("GetSqrtRatioAtTick(%d): %v", tick, err)
+		}
+		return sqrtPrice.String()
+	}
+	liquidity := func(r *rand.Rand) string {
+		return new(big.Int).Mul(big.NewInt(r.Int63n(1_000_000)+1), big.NewInt(1_000_000_000_000)).String()
+	}
+
+	mechanismtest.VerifyLiquidityPool(t, pool, mechanismtest.LiquidityPoolConfig{
+		Params: func(r *rand.Rand) mechanisms.PoolParams {
+			tick := 80000 + r.Intn(10000)
+			return mechanisms.PoolParams{Metadata: map[string]interface{}{
+				"current_tick":   tick,
+				"sqrt_price_x96": sqrtPriceAt(tick),
+				"liquidity":      liquidity(r),
+			}}
+		},
+		Positions: func(r *rand.Rand) mechanisms.PoolPosition {
+			lower := 80000 + r.Intn(5000)
+			return mechanisms.PoolPosition{Metadata: map[string]interface{}{
+				"liquidity":      liquidity(r),
+				"tick_lower":     lower,
+				"tick_upper":     lower + 1 + r.Intn(5000),
+				"sqrt_price_x96": sqrtPriceAt(80000 + r.Intn(10000)),
+			}}
+		},
+	})
+}
+
+// TestPositionAnalytics verifies value decomposition, range Greeks, range
+// tracking and fee APR on an 18/18-decimal pool around price 1.
+func TestPositionAnalytics(t *testing.T) {
+	pool, err := concentrated_liquidity.NewPool("a-b-3000", usdcAddress, 18, wethAddress, 18, constants.FeeMedium)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	positionAt := func(tick int) mechanisms.PoolPosition {
+		sqrtPrice, err := utils.GetSqrtRatioAtTick(tick)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mechanisms.PoolPosition{Metadata: map[string]interface{}{
+			"liquidity":      "1000000000000000000000000", // 1e24
+			"tick_lower":     -6000,
+			"tick_upper":     6000,
+			"sqrt_price_x96": sqrtPrice.String(),
+		}}
+	}
+	priceAt := func(tick int) float64 {
+		return math.Pow(1.0001, float64(tick))
+	}
+
+	center := positionAt(0)
+	value, err := pool.Decompose(center, primitives.MustPrice(primitives.One()), primitives.MustPrice(primitives.NewDecimal(2)))
+	if err != nil {
+		t.Fatalf("Decompose: %v", err)
+	}
+	if !value.ValueB.Decimal().Equal(value.AmountB.Decimal().Mul(primitives.NewDecimal(2))) || !value.Total.Equal(value.ValueA.Add(value.ValueB)) {
+		t.Errorf("inconsistent decomposition %+v", value)
+	}
+	if share := value.ShareA().Float64(); share < 0.33 || share > 0.34 {
+		t.Errorf("ShareA = %f, want about 1/3 with token B at twice the price", share)
+	}
+
+	greeks, err := pool.PositionGreeks(center)
+	if err != nil {
+		t.Fatalf("PositionGreeks: %v", err)
+	}
+	if !greeks.Delta.Equal(value.AmountA.Decimal()) {
+		t.Errorf("delta %s, want the token A held %s", greeks.Delta, value.AmountA)
+	}
+	// Gamma matches the finite difference of delta
+	up, _ := pool.PositionGreeks(positionAt(10))
+	down, _ := pool.PositionGreeks(positionAt(-10))
+	numeric := up.Delta.Sub(down.Delta).Float64() / (priceAt(10) - priceAt(-10))
+	if got := greeks.Gamma.Float64(); got >= 0 || math.Abs(got-numeric)/math.Abs(numeric) > 1e-3 {
+		t.Errorf("gamma = %g, finite difference %g", got, numeric)
+	}
+	below, _ := pool.PositionGreeks(positionAt(-7000))
+	above, _ := pool.PositionGreeks(positionAt(7000))
+	if !below.Gamma.IsZero() || !above.Gamma.IsZero() || !above.Delta.IsZero() || !below.Delta.IsPositive() {
+		t.Errorf("out of range Greeks: below %+v, above %+v", below, above)
+	}
+
+	tracker, err := concentrated_liquidity.NewRangeTracker(-60, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := primitives.Unix(0, 0)
+	for _, obs := range []struct {
+		hours int64
+		tick  int
+	}{{0, 0}, {1, 100}, {4, 59}, {5, 60}} {
+		if err := tracker.Observe(start.Add(primitives.Hours(obs.hours)), obs.tick); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := tracker.InRangeFraction().String(); got != "0.4" || tracker.TotalTime() != primitives.Hours(5) {
+		t.Errorf("in range %s of %s, want 0.4 of 5h", got, tracker.TotalTime())
+	}
+	if err := tracker.Observe(start, 0); err == nil {
+		t.Error("expected an error for an out-of-order observation")
+	}
+	if _, err := concentrated_liquidity.NewRangeTracker(60, 60); err == nil {
+		t.Error("expected an error for an empty range")
+	}
+
+	// 0.3% of 1M daily volume × 10% of liquidity × half the time = 150 a day on 100k
+	apr, err := pool.ExpectedFeeAPR(center, primitives.MustAmount(primitives.NewDecimal(100000)), concentrated_liquidity.VolumeAssumptions{
+		DailyVolume:   primitives.MustAmount(primitives.NewDecimal(1000000)),
+		PoolLiquidity: primitives.MustAmount(primitives.MustDecimalFromString("10000000000000000000000000")),
+		InRange:       primitives.MustDecimalFromString("0.5"),
+	})
+	if err != nil {
+		t.Fatalf("ExpectedFeeAPR: %v", err)
+	}
+	if got := apr.Fraction().String(); got != "0.547875" {
+		t.Errorf("fee APR = %s, want 0.547875", got)
+	}
+	if _, err := pool.ExpectedFeeAPR(center, primitives.ZeroAmount(), concentrated_liquidity.VolumeAssumptions{}); err == nil {
+		t.Error("expected an error for a zero position value")
+	}
+}
+
+// TestTickLiquidityFeeShare verifies fee shares against a tick-indexed
+// liquidity distribution and the single-liquidity fallback.
+func TestTickLiquidityFeeShare(t *testing.T) {
+	pool, err := concentrated_liquidity.NewPool("a-b-3000", usdcAddress, 18, wethAddress, 18, constants.FeeMedium)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	d := primitives.NewDecimal
+
+	// A subgraph page: 100 over [-600, 600), 300 over [-60, 60) and our 100
+	// over [-120, 120)
+	ticks, err := concentrated_liquidity.DecodeSubgraphTicks(strings.NewReader(`{"data": {"ticks": [
+		{"tickIdx": "600", "liquidityNet": "-100"},
+		{"tickIdx": "-600", "liquidityNet": "100"},
+		{"tickIdx": "-60", "liquidityNet": "300"},
+		{"tickIdx": "60", "liquidityNet": "-300"},
+		{"tickIdx": "-120", "liquidityNet": "100"},
+		{"tickIdx": "120", "liquidityNet": "-100"}
+	]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	distribution, err := concentrated_liquidity.NewLiquidityDistribution(ticks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tick, want := range map[int]int64{-700: 0, -600: 100, -100: 200, 0: 500, 59: 500, 60: 200, 300: 100, 600: 0} {
+		if got := distribution.ActiveLiquidity(tick); !got.Equal(d(want)) {
+			t.Errorf("active liquidity at tick %d = %s, want %d", tick, got, want)
+		}
+	}
+	if got := distribution.Ticks(); len(got) != 6 || got[0].Tick != -600 || !got[2].LiquidityNet.Equal(d(300)) {
+		t.Errorf("Ticks() = %+v", got)
+	}
+
+	position := mechanisms.PoolPosition{Metadata: map[string]interface{}{
+		"liquidity":  "100",
+		"tick_lower": -120,
+		"tick_upper": 120,
+	}}
+	snapshot := strategy.NewSimpleSnapshot(primitives.Unix(0, 0), nil)
+	snapshot.Set(snapshotkeys.PoolTickLiquidity("a-b-3000"), distribution)
+	snapshot.Set(snapshotkeys.PoolVolume("a-b-3000"), d(1_000_000))
+	for _, tt := range []struct {
+		tick int
+		want string
+	}{{0, "0.2"}, {100, "0.5"}, {120, "0"}, {-121, "0"}} {
+		snapshot.Set(snapshotkeys.PoolCurrentTick("a-b-3000"), tt.tick)
+		share, err := pool.SnapshotFeeShare(position, snapshot)
+		if err != nil || !share.Equal(primitives.MustDecimalFromString(tt.want)) {
+			t.Errorf("fee share at tick %d = %s (%v), want %s", tt.tick, share, err, tt.want)
+		}
+	}
+
+	// Half the 0.3% fee on 1,000,000 of volume at tick 100
+	snapshot.Set(snapshotkeys.PoolCurrentTick("a-b-3000"), 100)
+	if fees, err := pool.SnapshotFees(position, snapshot); err != nil || !fees.Equal(d(1500)) {
+		t.Errorf("fees = %s (%v), want 1500", fees, err)
+	}
+
+	// Without a distribution the single pool liquidity applies everywhere
+	flat := strategy.NewSimpleSnapshot(primitives.Unix(0, 0), nil)
+	flat.Set(snapshotkeys.PoolLiquidity("a-b-3000"), "400")
+	if share, err := pool.SnapshotFeeShare(position, flat); err != nil || !share.Equal(primitives.MustDecimalFromString("0.25")) {
+		t.Errorf("flat fee share = %s (%v), want 0.25", share, err)
+	}
+
+	if _, err := concentrated_liquidity.NewLiquidityDistribution([]concentrated_liquidity.TickLiquidity{{Tick: 0, LiquidityNet: d(-1)}}); !errors.Is(err, concentrated_liquidity.ErrInvalidPoolParams) {
+		t.Errorf("expected ErrInvalidPoolParams for negative liquidity, got %v", err)
+	}
+	if _, err := concentrated_liquidity.NewLiquidityDistribution([]concentrated_liquidity.TickLiquidity{{Tick: 0, LiquidityNet: d(1)}, {Tick: 0, LiquidityNet: d(1)}}); !errors.Is(err, concentrated_liquidity.ErrInvalidPoolParams) {
+		t.Errorf("expected ErrInvalidPoolParams for a duplicate tick, got %v", err)
+	}
+	if _, err := concentrated_liquidity.DecodeSubgraphTicks(strings.NewReader(`[{"tickIdx": "x", "liquidityNet": "1"}]`)); err == nil {
+		t.Error("expected an error for a malformed tick")
+	}
+}
+
+// TestAdversarialFlow verifies the JIT/MEV haircut and its application to
+// snapshot fees.
+func TestAdversarialFlow(t *testing.T) {
+	dec := primitives.MustDecimalFromString
+	flow := concentrated_liquidity.AdversarialFlow{
+		Base:             dec("0.02"),
+		SizeWeight:       dec("0.3"),
+		SizeReference:    primitives.MustAmount(primitives.NewDecimal(50_000)),
+		VolatilityWeight: dec("0.1"),
+		VolatilityKey:    "volatility:ETH/USD:realized",
+		Max:              dec("0.5"),
+	}
+	for _, tt := range []struct {
+		swap, vol, want string
+	}{
+		{"0", "0", "0.02"},
+		{"50000", "0", "0.17"},     // half the size weight at the reference
+		{"50000", "0.8", "0.25"},   // plus 0.1 × 80% volatility
+		{"1000000000", "3", "0.5"}, // capped at Max
+	} {
+		if got := flow.Haircut(dec(tt.swap), dec(tt.vol)); !got.Round(6, primitives.RoundHalfEven).Equal(dec(tt.want)) {
+			t.Errorf("Haircut(%s, %s) = %s, want %s", tt.swap, tt.vol, got, tt.want)
+		}
+	}
+
+	pool, err := concentrated_liquidity.NewPool("a-b-3000", usdcAddress, 18, wethAddress, 18, constants.FeeMedium)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	position := mechanisms.PoolPosition{Metadata: map[string]interface{}{"liquidity": "100", "tick_lower": -120, "tick_upper": 120}}
+	snapshot := strategy.NewSimpleSnapshot(primitives.Unix(0, 0), nil)
+	snapshot.Set(snapshotkeys.PoolLiquidity("a-b-3000"), "200")
+	snapshot.Set(snapshotkeys.PoolVolume("a-b-3000"), primitives.NewDecimal(1_000_000))
+	snapshot.Set(snapshotkeys.PoolSwapCount("a-b-3000"), 20)
+	snapshot.Set("volatility:ETH/USD:realized", dec("0.8"))
+
+	// Half of 0.3% of 1,000,000, less 25% for 50,000 average swaps at 80% vol
+	if err := pool.SetAdversarialFlow(&flow); err != nil {
+		t.Fatal(err)
+	}
+	if fees, err := pool.SnapshotFees(position, snapshot); err != nil || !fees.Equal(dec("1125")) {
+		t.Errorf("fees net of haircut = %s (%v), want 1125", fees, err)
+	}
+	if err := pool.SetAdversarialFlow(nil); err != nil {
+		t.Fatal(err)
+	}
+	if fees, err := pool.SnapshotFees(position, snapshot); err != nil || !fees.Equal(dec("1500")) {
+		t.Errorf("fees without a model = %s (%v), want 1500", fees, err)
+	}
+
+	// The analytic estimate takes the same haircut
+	assumptions := concentrated_liquidity.VolumeAssumptions{
+		DailyVolume:   primitives.MustAmount(primitives.NewDecimal(1_000_000)),
+		PoolLiquidity: primitives.MustAmount(primitives.NewDecimal(200)),
+		InRange:       primitives.One(),
+	}
+	value := primitives.MustAmount(primitives.NewDecimal(100_000))
+	gross, err := pool.ExpectedFeeAPR(position, value, assumptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assumptions.Haircut = dec("0.25")
+	net, err := pool.ExpectedFeeAPR(position, value, assumptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !net.Fraction().Equal(gross.Fraction().Mul(dec("0.75"))) {
+		t.Errorf("net APR %s, want 75%% of %s", net.Fraction(), gross.Fraction())
+	}
+
+	for name, bad := range map[string]concentrated_liquidity.AdversarialFlow{
+		"negative base":     {Base: dec("-0.1")},
+		"max above 1":       {Max: dec("1.5")},
+		"no reference":      {SizeWeight: dec("0.1")},
+		"no volatility key": {VolatilityWeight: dec("0.1")},
+	} {
+		if err := pool.SetAdversarialFlow(&bad); !errors.Is(err, concentrated_liquidity.ErrInvalidPoolParams) {
+			t.Errorf("%s: expected ErrInvalidPoolParams, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package concentrated_liquidity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// TickLiquidity is an initialized tick of a pool: LiquidityNet is the
+// liquidity added when the price crosses the tick upward (and removed
+// crossing it downward), the sum over the positions with a lower bound at
+// Tick less those with an upper bound there.
+type TickLiquidity struct {
+	Tick         int
+	LiquidityNet primitives.Decimal
+}
+
+// LiquidityDistribution is a pool's liquidity by tick, built from its
+// initialized ticks as read from a subgraph (see DecodeSubgraphTicks) or
+// from the pool contract's ticks over RPC. The active liquidity at a tick
+// is the sum of LiquidityNet over initialized ticks at or below it.
+//
+// Store a distribution in snapshot metadata at
+// snapshotkeys.PoolTickLiquidity to have SnapshotFeeShare use it.
+//
+// Thread Safety: LiquidityDistribution is immutable and safe for
+// concurrent use.
+type LiquidityDistribution struct {
+	ticks []int
+
+	// active[i] is the liquidity in [ticks[i], ticks[i+1])
+	active []primitives.Decimal
+}
+
+// NewLiquidityDistribution creates a distribution from initialized ticks
+// in any order. Returns ErrInvalidPoolParams if a tick appears twice or
+// the liquidity is negative anywhere.
+func NewLiquidityDistribution(ticks []TickLiquidity) (*LiquidityDistribution, error) {
+	sorted := append([]TickLiquidity(nil), ticks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Tick < sorted[j].Tick })
+
+	d := &LiquidityDistribution{
+		ticks:  make([]int, len(sorted)),
+		active: make([]primitives.Decimal, len(sorted)),
+	}
+	liquidity := primitives.Zero()
+	for i, tick := range sorted {
+		if i > 0 && tick.Tick == sorted[i-1].Tick {
+			return nil, fmt.Errorf("%w: tick %d appears twice", ErrInvalidPoolParams, tick.Tick)
+		}
+		liquidity = liquidity.Add(tick.LiquidityNet)
+		if liquidity.IsNegative() {
+			return nil, fmt.Errorf("%w: liquidity above tick %d is negative (%s)", ErrInvalidPoolParams, tick.Tick, liquidity)
+		}
+		d.ticks[i], d.active[i] = tick.Tick, liquidity
+	}
+	return d, nil
+}
+
+// ActiveLiquidity returns the liquidity in range at tick, the liquidity
+// swaps at that price trade against.
+func (d *LiquidityDistribution) ActiveLiquidity(tick int) primitives.Decimal {
+	i := sort.SearchInts(d.ticks, tick+1) - 1
+	if i < 0 {
+		return primitives.Zero()
+	}
+	return d.active[i]
+}
+
+// Ticks returns the initialized ticks with their net liquidity, in
+// ascending order.
+func (d *LiquidityDistribution) Ticks() []TickLiquidity {
+	ticks := make([]TickLiquidity, len(d.ticks))
+	previous := primitives.Zero()
+	for i, tick := range d.ticks {
+		ticks[i] = TickLiquidity{Tick: tick, LiquidityNet: d.active[i].Sub(previous)}
+		previous = d.active[i]
+	}
+	return ticks
+}
+
+// FeeShare returns the fraction of swap fees at currentTick a position
+// earns: its liquidity over the active liquidity, capped at 1, and zero
+// while the position is out of range. The distribution should include the
+// position's own liquidity.
+//
+// Returns ErrInsufficientLiquidity if the position is in range where the
+// distribution has no liquidity.
+func (d *LiquidityDistribution) FeeShare(position mechanisms.PoolPosition, currentTick int) (primitives.Decimal, error) {
+	tickLower, okLower := position.Metadata["tick_lower"].(int)
+	tickUpper, okUpper := position.Metadata["tick_upper"].(int)
+	if !okLower || !okUpper {
+		return primitives.Zero(), fmt.Errorf("%w: tick_lower and tick_upper required in position metadata", ErrInvalidPoolParams)
+	}
+	if !InRange(tickLower, tickUpper, currentTick) {
+		return primitives.Zero(), nil
+	}
+	liquidity, err := positionLiquidity(position)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	active := d.ActiveLiquidity(currentTick)
+	if !active.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: no active liquidity at tick %d", ErrInsufficientLiquidity, currentTick)
+	}
+	share, err := liquidity.Div(active)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if share.GreaterThan(primitives.One()) {
+		share = primitives.One()
+	}
+	return share, nil
+}
+
+// SnapshotFeeShare returns a position's share of the pool's swap fees at
+// snapshot. With a LiquidityDistribution at
+// snapshotkeys.PoolTickLiquidity it is the position's liquidity over the
+// active liquidity at the tick at snapshotkeys.PoolCurrentTick, zero out
+// of range; without one it falls back to the single pool liquidity at
+// snapshotkeys.PoolLiquidity, which ignores the range. Keys use the pool's
+// ID.
+func (p *Pool) SnapshotFeeShare(position mechanisms.PoolPosition, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	if raw, ok := snapshot.Get(snapshotkeys.PoolTickLiquidity(p.poolID)); ok {
+		distribution, ok := raw.(*LiquidityDistribution)
+		if !ok {
+			return primitives.Zero(), fmt.Errorf("%w: tick liquidity for pool %s is a %T", ErrInvalidPoolParams, p.poolID, raw)
+		}
+		tick, err := strategy.GetInt(snapshot, snapshotkeys.PoolCurrentTick(p.poolID))
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("current tick of pool %s: %w", p.poolID, err)
+		}
+		return distribution.FeeShare(position, tick)
+	}
+
+	poolLiquidity, err := strategy.GetDecimal(snapshot, snapshotkeys.PoolLiquidity(p.poolID))
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("liquidity of pool %s: %w", p.poolID, err)
+	}
+	if !poolLiquidity.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: pool %s has liquidity %s", ErrInsufficientLiquidity, p.poolID, poolLiquidity)
+	}
+	liquidity, err := positionLiquidity(position)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	share, err := liquidity.Div(poolLiquidity)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if share.GreaterThan(primitives.One()) {
+		share = primitives.One()
+	}
+	return share, nil
+}
+
+// SnapshotFees returns the swap fees a position earned over the snapshot
+// period: the volume at snapshotkeys.PoolVolume times the pool fee and the
+// position's SnapshotFeeShare, in the currency the volume is measured in.
+// With an AdversarialFlow set, the fees are net of its haircut.
+func (p *Pool) SnapshotFees(position mechanisms.PoolPosition, snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	volume, err := strategy.GetDecimal(snapshot, snapshotkeys.PoolVolume(p.poolID))
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("volume of pool %s: %w", p.poolID, err)
+	}
+	share, err := p.SnapshotFeeShare(position, snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	feeRate, err := primitives.NewDecimal(int64(p.fee)).Div(primitives.NewDecimal(1_000_000))
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	fees := volume.Mul(feeRate).Mul(share)
+	if p.adversarial == nil || fees.IsZero() {
+		return fees, nil
+	}
+	haircut, err := p.adversarial.SnapshotHaircut(p.poolID, snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return fees.Mul(primitives.One().Sub(haircut)), nil
+}
+
+// positionLiquidity parses a position's liquidity metadata.
+func positionLiquidity(position mechanisms.PoolPosition) (primitives.Decimal, error) {
+	liquidityStr, ok := position.Metadata["liquidity"].(string)
+	if !ok {
+		return primitives.Zero(), fmt.Errorf("%w: liquidity required in position metadata", ErrInvalidPoolParams)
+	}
+	liquidity, err := primitives.NewDecimalFromString(liquidityStr)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("invalid liquidity format: %w", err)
+	}
+	return liquidity, nil
+}
+
+// subgraphTick is a tick as the Uniswap V3 subgraph returns it, with
+// numbers as strings.
+type subgraphTick struct {
+	TickIdx      string `json:"tickIdx"`
+	LiquidityNet string `json:"liquidityNet"`
+}
+
+// DecodeSubgraphTicks reads initialized ticks from a Uniswap V3 subgraph
+// query for ticks { tickIdx liquidityNet }: either the full GraphQL
+// response ({"data": {"ticks": [...]}}) or the bare array of ticks. Pass
+// the result to NewLiquidityDistribution. A pool with more ticks than one
+// query returns needs its pages decoded and concatenated.
+func DecodeSubgraphTicks(r io.Reader) ([]TickLiquidity, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode subgraph ticks: %w", err)
+	}
+	var rows []subgraphTick
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		var response struct {
+			Data struct {
+				Ticks []subgraphTick `json:"ticks"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &response); err != nil {
+			return nil, fmt.Errorf("failed to decode subgraph ticks: %w", err)
+		}
+		rows = response.Data.Ticks
+	}
+
+	ticks := make([]TickLiquidity, len(rows))
+	for i, row := range rows {
+		tick, err := strconv.Atoi(row.TickIdx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tickIdx %q: %w", row.TickIdx, err)
+		}
+		net, err := primitives.NewDecimalFromString(row.LiquidityNet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid liquidityNet %q at tick %d: %w", row.LiquidityNet, tick, err)
+		}
+		ticks[i] = TickLiquidity{Tick: tick, LiquidityNet: net}
+	}
+	return ticks, nil
+}
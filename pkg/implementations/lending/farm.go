@@ -0,0 +1,277 @@
+package lending
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ErrInvalidFarm is returned when a farm's configuration is out of range
+var ErrInvalidFarm = errors.New("invalid farm")
+
+// FarmConfig describes a leveraged LP farm on a two-token pool.
+//
+// Equity in TokenA is supplied as collateral and TokenB is borrowed against
+// it. Each loop borrows TokenB, swaps half into TokenA at oracle prices,
+// adds both legs to the pool, and supplies the LP tokens as collateral for
+// the next loop.
+type FarmConfig struct {
+	// ID identifies the farm's position in a portfolio
+	ID string
+
+	// TokenA is the equity asset; TokenB is the borrowed leg
+	TokenA string
+	TokenB string
+
+	// PairA and PairB are the snapshot price keys of TokenA and TokenB
+	PairA string
+	PairB string
+
+	// LP is the market reserve symbol of the pool's LP token, priced per
+	// unit of constant-product liquidity (sqrt(a × b))
+	LP string
+
+	// FeeRate is the pool's fee yield, compounding into the LP tokens
+	FeeRate primitives.Rate
+
+	// Utilization is the fraction of borrow capacity each loop uses, in
+	// (0, 1]; lower values leave a buffer above liquidation
+	Utilization primitives.Decimal
+
+	// Loops is the number of borrow-and-LP rounds
+	Loops int
+}
+
+// FarmPosition is an open leveraged LP farm. It implements
+// strategy.PositionWithRisk; debt interest and LP fees accrue from the time
+// the farm was opened to the snapshot it is valued at.
+type FarmPosition struct {
+	market  *Market
+	config  FarmConfig
+	account Account
+	opened  primitives.Time
+}
+
+// OpenFarm supplies equity units of config.TokenA to market and loops
+// borrowing and LPing at snapshot prices. The LP reserve and TokenA must
+// have collateral factors, and TokenB must be borrowable in market.
+func OpenFarm(market *Market, config FarmConfig, equity primitives.Decimal, snapshot strategy.MarketSnapshot) (*FarmPosition, error) {
+	switch {
+	case config.ID == "":
+		return nil, fmt.Errorf("%w: ID cannot be empty", ErrInvalidFarm)
+	case !config.Utilization.IsPositive() || config.Utilization.GreaterThan(primitives.One()):
+		return nil, fmt.Errorf("%w: utilization %s outside (0, 1]", ErrInvalidFarm, config.Utilization)
+	case config.Loops < 1:
+		return nil, fmt.Errorf("%w: loops must be at least 1", ErrInvalidFarm)
+	}
+	for _, symbol := range []string{config.TokenA, config.TokenB, config.LP} {
+		if _, ok := market.Reserve(symbol); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAsset, symbol)
+		}
+	}
+
+	farm := &FarmPosition{market: market, config: config, opened: snapshot.Time()}
+	prices, err := farm.prices(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := market.Supply(NewAccount(), config.TokenA, equity)
+	if err != nil {
+		return nil, err
+	}
+	for loop := 0; loop < config.Loops; loop++ {
+		capacity, err := market.BorrowCapacity(account, prices)
+		if err != nil {
+			return nil, err
+		}
+		borrow := capacity.Mul(config.Utilization)
+		if !borrow.IsPositive() {
+			break
+		}
+
+		// Borrow the B leg and LP its full value
+		amountB, err := borrow.Div(prices[config.TokenB].Decimal())
+		if err != nil {
+			return nil, err
+		}
+		if account, err = market.Borrow(account, config.TokenB, amountB, prices); err != nil {
+			return nil, err
+		}
+		liquidity, err := borrow.Div(prices[config.LP].Decimal())
+		if err != nil {
+			return nil, err
+		}
+		if account, err = market.Supply(account, config.LP, liquidity); err != nil {
+			return nil, err
+		}
+	}
+
+	farm.account = account
+	return farm, nil
+}
+
+// ID returns the configured farm ID.
+func (f *FarmPosition) ID() string {
+	return f.config.ID
+}
+
+// Type returns PositionTypeLiquidityPool.
+func (f *FarmPosition) Type() strategy.PositionType {
+	return strategy.PositionTypeLiquidityPool
+}
+
+// Account returns the farm's lending account with interest and fees
+// accrued to snapshot.
+func (f *FarmPosition) Account(snapshot strategy.MarketSnapshot) Account {
+	elapsed := primitives.NewDuration(0)
+	if snapshot.Time().After(f.opened) {
+		elapsed = snapshot.Time().Sub(f.opened)
+	}
+	account := f.market.Accrue(f.account, elapsed)
+	if lp, ok := account.Collateral[f.config.LP]; ok && f.config.FeeRate.Period().Duration() > 0 {
+		account.Collateral[f.config.LP] = lp.Mul(primitives.One().Add(f.config.FeeRate.Over(elapsed)))
+	}
+	return account
+}
+
+// Value returns collateral minus debt at snapshot prices: the equity a
+// full unwind would return. It is zero once debt exceeds collateral.
+func (f *FarmPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	collateral, debt, err := f.values(snapshot)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	equity := collateral.Sub(debt)
+	if equity.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(equity)
+}
+
+// Leverage returns collateral value divided by equity: the loop leverage.
+func (f *FarmPosition) Leverage(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	collateral, debt, err := f.values(snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	equity := collateral.Sub(debt)
+	if !equity.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: farm %s has no equity", ErrInsufficientCollateral, f.config.ID)
+	}
+	return collateral.Div(equity)
+}
+
+// HealthFactor returns the farm's health factor at snapshot.
+func (f *FarmPosition) HealthFactor(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	prices, err := f.prices(snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return f.market.HealthFactor(f.Account(snapshot), prices)
+}
+
+// Liquidatable reports whether the health factor is below 1 at snapshot.
+func (f *FarmPosition) Liquidatable(snapshot strategy.MarketSnapshot) (bool, error) {
+	hf, err := f.HealthFactor(snapshot)
+	if errors.Is(err, ErrNoDebt) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return hf.LessThan(primitives.One()), nil
+}
+
+// LiquidationPrice returns the price of the borrowed TokenB at which the
+// health factor reaches 1, holding TokenA's price at snapshot. It solves
+// d·p = cA + 2·L·tLP·√(pA·p) for p, where the LP term follows the
+// constant-product value of the pool.
+func (f *FarmPosition) LiquidationPrice(snapshot strategy.MarketSnapshot) (primitives.Price, error) {
+	prices, err := f.prices(snapshot)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	account := f.Account(snapshot)
+	debt := account.Debt[f.config.TokenB].Float64()
+	if debt <= 0 {
+		return primitives.ZeroPrice(), ErrNoDebt
+	}
+	reserveA, _ := f.market.Reserve(f.config.TokenA)
+	reserveLP, _ := f.market.Reserve(f.config.LP)
+
+	priceA := prices[f.config.TokenA].Decimal().Float64()
+	fixed := account.Collateral[f.config.TokenA].Float64() * priceA * reserveA.LiquidationThreshold.Float64()
+	b := 2 * account.Collateral[f.config.LP].Float64() * reserveLP.LiquidationThreshold.Float64() * math.Sqrt(priceA)
+
+	root := (b + math.Sqrt(b*b+4*debt*fixed)) / (2 * debt)
+	return primitives.NewPrice(primitives.NewDecimalFromFloat(root * root))
+}
+
+// Risk implements strategy.PositionWithRisk. Delta is the net exposure to
+// TokenA in units, Leverage the loop leverage and LiquidationPrice the
+// TokenB price at which the farm can be liquidated.
+func (f *FarmPosition) Risk(snapshot strategy.MarketSnapshot) (strategy.RiskMetrics, error) {
+	leverage, err := f.Leverage(snapshot)
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	liquidation, err := f.LiquidationPrice(snapshot)
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	prices, err := f.prices(snapshot)
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+
+	// Half the LP value sits in TokenA
+	account := f.Account(snapshot)
+	lpValue := account.Collateral[f.config.LP].Mul(prices[f.config.LP].Decimal())
+	lpA, err := lpValue.Div(primitives.NewDecimal(2).Mul(prices[f.config.TokenA].Decimal()))
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	return strategy.RiskMetrics{
+		Delta:            account.Collateral[f.config.TokenA].Add(lpA),
+		Leverage:         leverage,
+		LiquidationPrice: liquidation,
+	}, nil
+}
+
+// values returns accrued collateral and debt values at snapshot.
+func (f *FarmPosition) values(snapshot strategy.MarketSnapshot) (collateral, debt primitives.Decimal, err error) {
+	prices, err := f.prices(snapshot)
+	if err != nil {
+		return primitives.Zero(), primitives.Zero(), err
+	}
+	account := f.Account(snapshot)
+	if collateral, err = f.market.CollateralValue(account, prices); err != nil {
+		return primitives.Zero(), primitives.Zero(), err
+	}
+	if debt, err = f.market.DebtValue(account, prices); err != nil {
+		return primitives.Zero(), primitives.Zero(), err
+	}
+	return collateral, debt, nil
+}
+
+// prices reads both legs from snapshot and prices the LP token at the
+// constant-product value of one unit of liquidity, 2·√(pA·pB).
+func (f *FarmPosition) prices(snapshot strategy.MarketSnapshot) (Prices, error) {
+	priceA, err := snapshot.Price(f.config.PairA)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMissingPrice, f.config.PairA, err)
+	}
+	priceB, err := snapshot.Price(f.config.PairB)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMissingPrice, f.config.PairB, err)
+	}
+	lp, err := primitives.NewPrice(primitives.NewDecimal(2).Mul(priceA.Decimal().Mul(priceB.Decimal()).Sqrt()))
+	if err != nil {
+		return nil, err
+	}
+	return Prices{f.config.TokenA: priceA, f.config.TokenB: priceB, f.config.LP: lp}, nil
+}
@@ -0,0 +1,198 @@
+package lending_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/lending"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func price(s string) primitives.Price {
+	return primitives.MustPrice(dec(s))
+}
+
+// stableMarket lends USDT at 5% simple APR against USDC and USDC/USDT LP tokens.
+func stableMarket(t *testing.T) *lending.Market {
+	t.Helper()
+	market := lending.NewMarket("test-lend")
+	reserves := []lending.Reserve{
+		{Symbol: "USDC", CollateralFactor: dec("0.8"), LiquidationThreshold: dec("0.85")},
+		{Symbol: "USDT", CollateralFactor: dec("0.8"), LiquidationThreshold: dec("0.85"),
+			BorrowRate: primitives.NewAnnualRate(dec("0.05"), primitives.CompoundingSimple)},
+		{Symbol: "LP", CollateralFactor: dec("0.75"), LiquidationThreshold: dec("0.8")},
+	}
+	for _, r := range reserves {
+		if err := market.AddReserve(r); err != nil {
+			t.Fatalf("AddReserve(%s): %v", r.Symbol, err)
+		}
+	}
+	return market
+}
+
+func TestMarket(t *testing.T) {
+	market := stableMarket(t)
+	prices := lending.Prices{"USDC": price("1"), "USDT": price("1")}
+
+	t.Run("borrow within capacity", func(t *testing.T) {
+		acct, err := market.Supply(lending.NewAccount(), "USDC", dec("1000"))
+		if err != nil {
+			t.Fatalf("Supply: %v", err)
+		}
+		acct, err = market.Borrow(acct, "USDT", dec("500"), prices)
+		if err != nil {
+			t.Fatalf("Borrow: %v", err)
+		}
+		capacity, err := market.BorrowCapacity(acct, prices)
+		if err != nil || !capacity.Equal(dec("300")) {
+			t.Errorf("capacity = %s (%v), want 300", capacity, err)
+		}
+		hf, err := market.HealthFactor(acct, prices)
+		if err != nil || !hf.Equal(dec("1.7")) {
+			t.Errorf("health factor = %s (%v), want 1.7", hf, err)
+		}
+		if _, err := market.Borrow(acct, "USDT", dec("301"), prices); !errors.Is(err, lending.ErrInsufficientCollateral) {
+			t.Errorf("expected %v, got %v", lending.ErrInsufficientCollateral, err)
+		}
+
+		// Half a year at 5% simple
+		accrued := market.Accrue(acct, primitives.NewDuration(4383*time.Hour))
+		if !accrued.Debt["USDT"].Equal(dec("512.5")) || !acct.Debt["USDT"].Equal(dec("500")) {
+			t.Errorf("accrued debt = %s (original %s), want 512.5 (500)", accrued.Debt["USDT"], acct.Debt["USDT"])
+		}
+		repaid, err := market.Repay(acct, "USDT", dec("600"))
+		if err != nil || len(repaid.Debt) != 0 {
+			t.Errorf("repay: %v, debt %v", err, repaid.Debt)
+		}
+		if _, err := market.HealthFactor(repaid, prices); !errors.Is(err, lending.ErrNoDebt) {
+			t.Errorf("expected %v, got %v", lending.ErrNoDebt, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		invalid := []lending.Reserve{
+			{CollateralFactor: dec("0.5"), LiquidationThreshold: dec("0.6")},
+			{Symbol: "X", CollateralFactor: dec("1"), LiquidationThreshold: dec("1")},
+			{Symbol: "X", CollateralFactor: dec("0.8"), LiquidationThreshold: dec("0.7")},
+		}
+		for i, r := range invalid {
+			if err := market.AddReserve(r); !errors.Is(err, lending.ErrInvalidReserve) {
+				t.Errorf("reserve %d: expected %v, got %v", i, lending.ErrInvalidReserve, err)
+			}
+		}
+		if _, err := market.Supply(lending.NewAccount(), "DAI", dec("1")); !errors.Is(err, lending.ErrUnknownAsset) {
+			t.Errorf("expected %v, got %v", lending.ErrUnknownAsset, err)
+		}
+		if _, err := market.Supply(lending.NewAccount(), "USDC", primitives.Zero()); !errors.Is(err, lending.ErrInvalidAmount) {
+			t.Errorf("expected %v, got %v", lending.ErrInvalidAmount, err)
+		}
+		acct, _ := market.Supply(lending.NewAccount(), "USDC", dec("1"))
+		if _, err := market.Borrow(acct, "USDT", dec("0.1"), lending.Prices{"USDC": price("1")}); !errors.Is(err, lending.ErrMissingPrice) {
+			t.Errorf("expected %v, got %v", lending.ErrMissingPrice, err)
+		}
+		if market.Mechanism() != mechanisms.MechanismTypeLending || market.Venue() != "test-lend" {
+			t.Errorf("unexpected identity %s/%s", market.Mechanism(), market.Venue())
+		}
+	})
+}
+
+func stableSnapshot(hours int, usdt string) strategy.MarketSnapshot {
+	return strategy.NewSimpleSnapshot(
+		primitives.NewTime(start.Add(time.Duration(hours)*time.Hour)),
+		map[string]primitives.Price{"USDC/USD": price("1"), "USDT/USD": price(usdt)},
+	)
+}
+
+func farmConfig(loops int) lending.FarmConfig {
+	return lending.FarmConfig{
+		ID:          "farm:usdc-usdt",
+		TokenA:      "USDC",
+		TokenB:      "USDT",
+		PairA:       "USDC/USD",
+		PairB:       "USDT/USD",
+		LP:          "LP",
+		FeeRate:     primitives.NewAnnualRate(dec("0.1"), primitives.CompoundingSimple),
+		Utilization: dec("0.9"),
+		Loops:       loops,
+	}
+}
+
+func TestFarm(t *testing.T) {
+	market := stableMarket(t)
+	farm, err := lending.OpenFarm(market, farmConfig(3), dec("1000"), stableSnapshot(0, "1"))
+	if err != nil {
+		t.Fatalf("OpenFarm: %v", err)
+	}
+	open := stableSnapshot(0, "1")
+
+	t.Run("loop leverage", func(t *testing.T) {
+		// Each loop borrows 90% of the remaining capacity: 720, 558 and 432.45
+		account := farm.Account(open)
+		if !account.Debt["USDT"].Equal(dec("1710.45")) || !account.Collateral["LP"].Equal(dec("855.225")) {
+			t.Errorf("unexpected account %+v", account)
+		}
+		leverage, err := farm.Leverage(open)
+		if err != nil || !leverage.Equal(dec("2.71045")) {
+			t.Errorf("leverage = %s (%v), want 2.71045", leverage, err)
+		}
+		value, err := farm.Value(open)
+		if err != nil || !value.Decimal().Equal(dec("1000")) {
+			t.Errorf("value = %s (%v), want 1000", value, err)
+		}
+		hf, err := farm.HealthFactor(open)
+		if err != nil || hf.LessThan(dec("1.29")) || hf.GreaterThan(dec("1.30")) {
+			t.Errorf("health factor = %s (%v), want ~1.297", hf, err)
+		}
+	})
+
+	t.Run("fees outpace borrow cost", func(t *testing.T) {
+		// 10% fees on 1710.45 of LP less 5% interest on 1710.45 of debt
+		value, err := farm.Value(stableSnapshot(8766, "1"))
+		if err != nil || !value.Decimal().Equal(dec("1085.5225")) {
+			t.Errorf("value after a year = %s (%v), want 1085.5225", value, err)
+		}
+	})
+
+	t.Run("depeg liquidation", func(t *testing.T) {
+		liquidation, err := farm.LiquidationPrice(open)
+		if err != nil {
+			t.Fatalf("LiquidationPrice: %v", err)
+		}
+		if !liquidation.Decimal().GreaterThan(dec("1.3")) || !liquidation.Decimal().LessThan(dec("1.5")) {
+			t.Errorf("liquidation price = %s, want a USDT depeg between 1.3 and 1.5", liquidation)
+		}
+		above := liquidation.Decimal().Mul(dec("1.01")).Round(6, primitives.RoundUp).String()
+		below := liquidation.Decimal().Mul(dec("0.99")).Round(6, primitives.RoundDown).String()
+		if liquid, err := farm.Liquidatable(stableSnapshot(0, above)); err != nil || !liquid {
+			t.Errorf("expected liquidatable at %s (%v)", above, err)
+		}
+		if liquid, err := farm.Liquidatable(stableSnapshot(0, below)); err != nil || liquid {
+			t.Errorf("expected healthy at %s (%v)", below, err)
+		}
+		risk, err := farm.Risk(open)
+		if err != nil || !risk.LiquidationPrice.Equal(liquidation) || !risk.Leverage.Equal(dec("2.71045")) {
+			t.Errorf("unexpected risk %+v (%v)", risk, err)
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		config := farmConfig(0)
+		if _, err := lending.OpenFarm(market, config, dec("1000"), open); !errors.Is(err, lending.ErrInvalidFarm) {
+			t.Errorf("expected %v, got %v", lending.ErrInvalidFarm, err)
+		}
+		config = farmConfig(1)
+		config.LP = "CURVE-LP"
+		if _, err := lending.OpenFarm(market, config, dec("1000"), open); !errors.Is(err, lending.ErrUnknownAsset) {
+			t.Errorf("expected %v, got %v", lending.ErrUnknownAsset, err)
+		}
+	})
+}
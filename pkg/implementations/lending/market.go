@@ -0,0 +1,276 @@
+// Package lending implements an over-collateralized lending market and a
+// helper for leveraged LP farming built on it.
+//
+// The Market follows the Aave/Compound model: each reserve has a collateral
+// factor (the maximum loan-to-value when borrowing against it), a
+// liquidation threshold, and a borrow rate. An Account holds collateral and
+// debt per asset; its health factor is the threshold-weighted collateral
+// value divided by the debt value, and it can be liquidated below 1.
+//
+// Account operations return a new Account rather than modifying the
+// receiver, matching the framework's immutable-position convention.
+package lending
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInvalidReserve is returned when a reserve's parameters are out of range
+	ErrInvalidReserve = errors.New("invalid reserve")
+
+	// ErrUnknownAsset is returned for an asset the market has no reserve for
+	ErrUnknownAsset = errors.New("unknown asset")
+
+	// ErrInvalidAmount is returned for non-positive amounts
+	ErrInvalidAmount = errors.New("amount must be positive")
+
+	// ErrInsufficientCollateral is returned when a borrow exceeds the
+	// account's borrow capacity
+	ErrInsufficientCollateral = errors.New("insufficient collateral")
+
+	// ErrMissingPrice is returned when a price needed for valuation is absent
+	ErrMissingPrice = errors.New("missing price")
+
+	// ErrNoDebt is returned by HealthFactor for an account without debt,
+	// whose health factor is unbounded
+	ErrNoDebt = errors.New("account has no debt")
+)
+
+// Reserve configures one asset of a lending market.
+type Reserve struct {
+	// Symbol identifies the asset (e.g., "USDC")
+	Symbol string
+
+	// CollateralFactor is the maximum loan-to-value of borrowing against
+	// the asset, in [0, 1); zero means it cannot back borrows
+	CollateralFactor primitives.Decimal
+
+	// LiquidationThreshold is the loan-to-value at which collateral in the
+	// asset can be liquidated, in [CollateralFactor, 1]
+	LiquidationThreshold primitives.Decimal
+
+	// BorrowRate is the interest charged on debt in the asset
+	BorrowRate primitives.Rate
+}
+
+// Market is an over-collateralized lending market.
+//
+// Thread Safety: Market is safe for concurrent reads once all reserves are
+// added.
+type Market struct {
+	venue    string
+	reserves map[string]Reserve
+}
+
+// NewMarket creates a lending market identified by venue.
+func NewMarket(venue string) *Market {
+	return &Market{venue: venue, reserves: make(map[string]Reserve)}
+}
+
+// Mechanism implements mechanisms.MarketMechanism.
+func (m *Market) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeLending
+}
+
+// Venue implements mechanisms.MarketMechanism.
+func (m *Market) Venue() string {
+	return m.venue
+}
+
+// AddReserve adds or replaces the reserve for r.Symbol.
+// Returns ErrInvalidReserve if the symbol is empty, the collateral factor
+// is outside [0, 1), or the liquidation threshold is outside
+// [CollateralFactor, 1].
+func (m *Market) AddReserve(r Reserve) error {
+	switch {
+	case r.Symbol == "":
+		return fmt.Errorf("%w: symbol cannot be empty", ErrInvalidReserve)
+	case r.CollateralFactor.IsNegative() || !r.CollateralFactor.LessThan(primitives.One()):
+		return fmt.Errorf("%w: %s collateral factor %s outside [0, 1)", ErrInvalidReserve, r.Symbol, r.CollateralFactor)
+	case r.LiquidationThreshold.LessThan(r.CollateralFactor) || r.LiquidationThreshold.GreaterThan(primitives.One()):
+		return fmt.Errorf("%w: %s liquidation threshold %s outside [%s, 1]",
+			ErrInvalidReserve, r.Symbol, r.LiquidationThreshold, r.CollateralFactor)
+	}
+	m.reserves[r.Symbol] = r
+	return nil
+}
+
+// Reserve returns the reserve for symbol.
+func (m *Market) Reserve(symbol string) (Reserve, bool) {
+	r, ok := m.reserves[symbol]
+	return r, ok
+}
+
+// Account is a borrower's collateral and debt, in asset units.
+type Account struct {
+	Collateral map[string]primitives.Decimal
+	Debt       map[string]primitives.Decimal
+}
+
+// NewAccount creates an empty account.
+func NewAccount() Account {
+	return Account{
+		Collateral: make(map[string]primitives.Decimal),
+		Debt:       make(map[string]primitives.Decimal),
+	}
+}
+
+// clone returns a deep copy of a.
+func (a Account) clone() Account {
+	c := NewAccount()
+	for symbol, amount := range a.Collateral {
+		c.Collateral[symbol] = amount
+	}
+	for symbol, amount := range a.Debt {
+		c.Debt[symbol] = amount
+	}
+	return c
+}
+
+// Prices maps asset symbols to prices in the valuation currency.
+type Prices map[string]primitives.Price
+
+func (p Prices) of(symbol string) (primitives.Decimal, error) {
+	price, ok := p[symbol]
+	if !ok {
+		return primitives.Zero(), fmt.Errorf("%w: %s", ErrMissingPrice, symbol)
+	}
+	return price.Decimal(), nil
+}
+
+// Supply returns acct with amount of symbol added as collateral.
+func (m *Market) Supply(acct Account, symbol string, amount primitives.Decimal) (Account, error) {
+	if _, ok := m.reserves[symbol]; !ok {
+		return acct, fmt.Errorf("%w: %s", ErrUnknownAsset, symbol)
+	}
+	if !amount.IsPositive() {
+		return acct, fmt.Errorf("%w: supply %s %s", ErrInvalidAmount, amount, symbol)
+	}
+	next := acct.clone()
+	next.Collateral[symbol] = next.Collateral[symbol].Add(amount)
+	return next, nil
+}
+
+// Borrow returns acct with amount of symbol borrowed.
+// Returns ErrInsufficientCollateral if the debt would exceed the account's
+// borrow capacity at prices.
+func (m *Market) Borrow(acct Account, symbol string, amount primitives.Decimal, prices Prices) (Account, error) {
+	if _, ok := m.reserves[symbol]; !ok {
+		return acct, fmt.Errorf("%w: %s", ErrUnknownAsset, symbol)
+	}
+	if !amount.IsPositive() {
+		return acct, fmt.Errorf("%w: borrow %s %s", ErrInvalidAmount, amount, symbol)
+	}
+	capacity, err := m.BorrowCapacity(acct, prices)
+	if err != nil {
+		return acct, err
+	}
+	price, err := prices.of(symbol)
+	if err != nil {
+		return acct, err
+	}
+	if value := amount.Mul(price); value.GreaterThan(capacity) {
+		return acct, fmt.Errorf("%w: borrowing %s exceeds capacity %s", ErrInsufficientCollateral, value, capacity)
+	}
+	next := acct.clone()
+	next.Debt[symbol] = next.Debt[symbol].Add(amount)
+	return next, nil
+}
+
+// Repay returns acct with up to amount of symbol debt repaid.
+func (m *Market) Repay(acct Account, symbol string, amount primitives.Decimal) (Account, error) {
+	if !amount.IsPositive() {
+		return acct, fmt.Errorf("%w: repay %s %s", ErrInvalidAmount, amount, symbol)
+	}
+	next := acct.clone()
+	remaining := next.Debt[symbol].Sub(amount)
+	if remaining.IsPositive() {
+		next.Debt[symbol] = remaining
+	} else {
+		delete(next.Debt, symbol)
+	}
+	return next, nil
+}
+
+// Accrue returns acct with interest charged on every debt over elapsed.
+func (m *Market) Accrue(acct Account, elapsed primitives.Duration) Account {
+	next := acct.clone()
+	for symbol, debt := range next.Debt {
+		if r, ok := m.reserves[symbol]; ok && r.BorrowRate.Period().Duration() > 0 {
+			next.Debt[symbol] = debt.Mul(primitives.One().Add(r.BorrowRate.Over(elapsed)))
+		}
+	}
+	return next
+}
+
+// CollateralValue returns the value of acct's collateral at prices.
+func (m *Market) CollateralValue(acct Account, prices Prices) (primitives.Decimal, error) {
+	return m.weighted(acct.Collateral, prices, func(Reserve) primitives.Decimal { return primitives.One() })
+}
+
+// DebtValue returns the value of acct's debt at prices.
+func (m *Market) DebtValue(acct Account, prices Prices) (primitives.Decimal, error) {
+	return m.weighted(acct.Debt, prices, func(Reserve) primitives.Decimal { return primitives.One() })
+}
+
+// BorrowCapacity returns how much more value acct can borrow at prices:
+// collateral weighted by collateral factor, less existing debt. It is
+// negative when the account is over its limit.
+func (m *Market) BorrowCapacity(acct Account, prices Prices) (primitives.Decimal, error) {
+	limit, err := m.weighted(acct.Collateral, prices, func(r Reserve) primitives.Decimal { return r.CollateralFactor })
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	debt, err := m.DebtValue(acct, prices)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return limit.Sub(debt), nil
+}
+
+// HealthFactor returns collateral weighted by liquidation threshold divided
+// by debt, at prices. Below 1 the account can be liquidated.
+// Returns ErrNoDebt if the account has no debt.
+func (m *Market) HealthFactor(acct Account, prices Prices) (primitives.Decimal, error) {
+	debt, err := m.DebtValue(acct, prices)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if !debt.IsPositive() {
+		return primitives.Zero(), ErrNoDebt
+	}
+	threshold, err := m.weighted(acct.Collateral, prices, func(r Reserve) primitives.Decimal { return r.LiquidationThreshold })
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return threshold.Div(debt)
+}
+
+// weighted sums amount × price × weight(reserve) over assets.
+func (m *Market) weighted(amounts map[string]primitives.Decimal, prices Prices, weight func(Reserve) primitives.Decimal) (primitives.Decimal, error) {
+	symbols := make([]string, 0, len(amounts))
+	for symbol := range amounts {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	total := primitives.Zero()
+	for _, symbol := range symbols {
+		r, ok := m.reserves[symbol]
+		if !ok {
+			return primitives.Zero(), fmt.Errorf("%w: %s", ErrUnknownAsset, symbol)
+		}
+		price, err := prices.of(symbol)
+		if err != nil {
+			return primitives.Zero(), err
+		}
+		total = total.Add(amounts[symbol].Mul(price).Mul(weight(r)))
+	}
+	return total, nil
+}
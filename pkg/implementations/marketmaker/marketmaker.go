@@ -0,0 +1,531 @@
+// Package marketmaker simulates an options market maker: it quotes a
+// spread around Black-Scholes model prices across a strike ladder, fills
+// quotes with a probability that falls as the quote's edge grows, tracks
+// inventory per strike and expiry, and delta hedges the book with a
+// perpetual future.
+//
+// The dealer account is a single Book position holding margin, option
+// inventory and the perpetual hedge, valued as the equity a full unwind at
+// model prices would return. MarketMaker is a strategy.Strategy that
+// posts the margin, steps the book every snapshot and replaces it in the
+// portfolio, which makes vol-selling research a matter of configuration.
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/perpetual"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrInvalidConfig is returned when a market maker's configuration is
+	// missing fields or out of range
+	ErrInvalidConfig = errors.New("invalid market maker config")
+)
+
+// Contract identifies one option series.
+type Contract struct {
+	Type   mechanisms.OptionType
+	Strike primitives.Price
+	Expiry primitives.Time
+}
+
+// ID returns a stable identifier such as "call-2000-20240131".
+func (c Contract) ID() string {
+	return fmt.Sprintf("%s-%s-%s", c.Type, c.Strike, c.Expiry.Format("20060102"))
+}
+
+// Ladder returns every combination of types, strikes and expiries, ordered
+// by expiry, then strike, then type.
+func Ladder(types []mechanisms.OptionType, strikes []primitives.Price, expiries []primitives.Time) []Contract {
+	contracts := make([]Contract, 0, len(types)*len(strikes)*len(expiries))
+	for _, expiry := range expiries {
+		for _, strike := range strikes {
+			for _, optionType := range types {
+				contracts = append(contracts, Contract{Type: optionType, Strike: strike, Expiry: expiry})
+			}
+		}
+	}
+	return contracts
+}
+
+// FillModel gives the probability that a quote is filled during one
+// snapshot. Edge is the quote's distance from the model price as a
+// fraction of it, positive when the quote favours the market maker.
+type FillModel interface {
+	FillProbability(edge primitives.Decimal) primitives.Decimal
+}
+
+// ExponentialFill fills with probability Base·e^(−Decay·edge), capped at 1:
+// quotes at model fill with probability Base, wider quotes exponentially
+// less often.
+type ExponentialFill struct {
+	Base  primitives.Decimal
+	Decay primitives.Decimal
+}
+
+// FillProbability implements FillModel.
+func (f ExponentialFill) FillProbability(edge primitives.Decimal) primitives.Decimal {
+	p := f.Base.Float64() * math.Exp(-f.Decay.Float64()*edge.Float64())
+	return primitives.NewDecimalFromFloat(math.Max(0, math.Min(1, p)))
+}
+
+// Config configures a market maker.
+type Config struct {
+	// ID identifies the book position
+	ID string
+
+	// Underlying is the snapshot price key of the underlying, which is
+	// also the hedge perpetual's mark price
+	Underlying string
+
+	// Contracts is the ladder of series to quote
+	Contracts []Contract
+
+	// Volatility and RiskFreeRate are the annualized model inputs
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+
+	// HalfSpread is the distance of each quote from the model price, as a
+	// fraction of it
+	HalfSpread primitives.Decimal
+
+	// Skew shifts both quotes by this fraction of the model price per
+	// contract of inventory, so a long book quotes lower to sell down
+	Skew primitives.Decimal
+
+	// QuoteSize is the number of contracts per fill
+	QuoteSize primitives.Decimal
+
+	// MaxInventory caps the absolute inventory per contract; the side that
+	// would exceed it is not quoted
+	MaxInventory primitives.Decimal
+
+	// Fill models fill probability
+	Fill FillModel
+
+	// HedgeBand is the absolute net delta, in underlying units, tolerated
+	// before the hedge is reset to flat
+	HedgeBand primitives.Decimal
+
+	// Margin is the cash posted to the book when it opens
+	Margin primitives.Decimal
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.ID == "" || c.Underlying == "":
+		return fmt.Errorf("%w: ID and underlying are required", ErrInvalidConfig)
+	case len(c.Contracts) == 0:
+		return fmt.Errorf("%w: no contracts to quote", ErrInvalidConfig)
+	case !c.Volatility.IsPositive():
+		return fmt.Errorf("%w: volatility must be positive", ErrInvalidConfig)
+	case c.HalfSpread.IsNegative() || c.Skew.IsNegative() || c.HedgeBand.IsNegative():
+		return fmt.Errorf("%w: spread, skew and hedge band cannot be negative", ErrInvalidConfig)
+	case !c.QuoteSize.IsPositive() || c.MaxInventory.LessThan(c.QuoteSize):
+		return fmt.Errorf("%w: quote size must be positive and within max inventory", ErrInvalidConfig)
+	case c.Fill == nil:
+		return fmt.Errorf("%w: fill model is required", ErrInvalidConfig)
+	case !c.Margin.IsPositive():
+		return fmt.Errorf("%w: margin must be positive", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Quote is a two-sided market in one contract.
+type Quote struct {
+	Contract Contract
+
+	// Model is the Black-Scholes price; Bid and Ask straddle it
+	Model primitives.Price
+	Bid   primitives.Price
+	Ask   primitives.Price
+
+	// Delta is the model delta of one contract
+	Delta primitives.Decimal
+
+	// BidOpen and AskOpen report whether each side is quoted
+	BidOpen bool
+	AskOpen bool
+}
+
+// Fill is a quote hit by the market. Side is from the market maker's
+// perspective: buy means the market maker bought at the bid.
+type Fill struct {
+	Time     primitives.Time
+	Contract Contract
+	Side     mechanisms.OrderSide
+	Quantity primitives.Decimal
+	Price    primitives.Price
+	Edge     primitives.Decimal
+}
+
+// Book is the market maker's dealer account: cash (margin plus premium and
+// realized hedge P&L), option inventory and the perpetual hedge.
+//
+// Book is immutable; MarketMaker.Step returns the next book.
+type Book struct {
+	config    *Config
+	cash      primitives.Decimal
+	inventory map[Contract]primitives.Decimal
+	hedge     *perpetual.Future
+}
+
+// NewBook creates a book holding margin and no inventory.
+func NewBook(config Config) (*Book, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &Book{config: &config, cash: config.Margin, inventory: map[Contract]primitives.Decimal{}}, nil
+}
+
+// ID returns the configured book ID.
+func (b *Book) ID() string {
+	return b.config.ID
+}
+
+// Type returns PositionTypeOption.
+func (b *Book) Type() strategy.PositionType {
+	return strategy.PositionTypeOption
+}
+
+// Inventory returns the contracts held, long positive and short negative.
+func (b *Book) Inventory() map[Contract]primitives.Decimal {
+	inventory := make(map[Contract]primitives.Decimal, len(b.inventory))
+	for contract, quantity := range b.inventory {
+		inventory[contract] = quantity
+	}
+	return inventory
+}
+
+// Hedge returns the perpetual hedge size in underlying units, negative
+// for a short.
+func (b *Book) Hedge() primitives.Decimal {
+	if b.hedge == nil {
+		return primitives.Zero()
+	}
+	return b.hedge.PositionSize()
+}
+
+// Cash returns the book's cash.
+func (b *Book) Cash() primitives.Decimal {
+	return b.cash
+}
+
+// Value returns cash plus inventory at model prices plus hedge P&L: the
+// equity a full unwind would return. It is zero once the book is
+// insolvent.
+func (b *Book) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	underlying, err := snapshot.Price(b.config.Underlying)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	equity := b.cash
+	for _, contract := range b.contracts() {
+		model, _, err := b.config.price(contract, underlying, snapshot.Time())
+		if err != nil {
+			return primitives.ZeroAmount(), err
+		}
+		equity = equity.Add(b.inventory[contract].Mul(model.Decimal()))
+	}
+	if b.hedge != nil {
+		pnl, err := b.hedge.UnrealizedPnL(underlying)
+		if err != nil {
+			return primitives.ZeroAmount(), err
+		}
+		equity = equity.Add(pnl)
+	}
+	if equity.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(equity)
+}
+
+// Greeks returns the net Greeks of the inventory and hedge at snapshot.
+func (b *Book) Greeks(snapshot strategy.MarketSnapshot) (mechanisms.Greeks, error) {
+	underlying, err := snapshot.Price(b.config.Underlying)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	net := mechanisms.Greeks{Delta: b.Hedge()}
+	for _, contract := range b.contracts() {
+		_, greeks, err := b.config.price(contract, underlying, snapshot.Time())
+		if err != nil {
+			return mechanisms.Greeks{}, err
+		}
+		quantity := b.inventory[contract]
+		net.Delta = net.Delta.Add(quantity.Mul(greeks.Delta))
+		net.Gamma = net.Gamma.Add(quantity.Mul(greeks.Gamma))
+		net.Theta = net.Theta.Add(quantity.Mul(greeks.Theta))
+		net.Vega = net.Vega.Add(quantity.Mul(greeks.Vega))
+		net.Rho = net.Rho.Add(quantity.Mul(greeks.Rho))
+	}
+	return net, nil
+}
+
+// Risk implements strategy.PositionWithRisk with the book's net Greeks.
+func (b *Book) Risk(snapshot strategy.MarketSnapshot) (strategy.RiskMetrics, error) {
+	greeks, err := b.Greeks(snapshot)
+	if err != nil {
+		return strategy.RiskMetrics{}, err
+	}
+	return strategy.RiskMetrics{Delta: greeks.Delta, Gamma: greeks.Gamma, Vega: greeks.Vega, Theta: greeks.Theta}, nil
+}
+
+// contracts returns held contracts in a deterministic order.
+func (b *Book) contracts() []Contract {
+	contracts := make([]Contract, 0, len(b.inventory))
+	for contract := range b.inventory {
+		contracts = append(contracts, contract)
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].ID() < contracts[j].ID() })
+	return contracts
+}
+
+// clone returns a copy of b that can be modified.
+func (b *Book) clone() *Book {
+	next := *b
+	next.inventory = b.Inventory()
+	return &next
+}
+
+// price returns the model price and Greeks of one contract; expired
+// contracts are worth their intrinsic value.
+func (c *Config) price(contract Contract, underlying primitives.Price, now primitives.Time) (primitives.Price, mechanisms.Greeks, error) {
+	years := primitives.Zero()
+	if contract.Expiry.After(now) {
+		years = primitives.NewDecimalFromFloat(contract.Expiry.Sub(now).Hours() / primitives.Year.Hours())
+	}
+	// Entry price and size do not affect pricing; the strike stands in
+	option, err := blackscholes.NewOption(contract.ID(), contract.Type, contract.Strike, years, contract.Strike, primitives.One())
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	params := mechanisms.PriceParams{
+		UnderlyingPrice: underlying,
+		TimeToExpiry:    years,
+		Volatility:      c.Volatility,
+		RiskFreeRate:    c.RiskFreeRate,
+	}
+	ctx := context.Background()
+	model, err := option.Price(ctx, params)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	greeks, err := option.Greeks(ctx, params)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	return model, greeks, nil
+}
+
+// MarketMaker runs a Book as a strategy.
+//
+// Thread Safety: MarketMaker is not thread-safe, matching the backtest
+// engine's single-goroutine execution model.
+type MarketMaker struct {
+	config Config
+	rng    *rand.Rand
+	fills  []Fill
+}
+
+// NewMarketMaker creates a market maker. seed drives fill simulation, so
+// the same seed and snapshots always produce the same fills.
+func NewMarketMaker(config Config, seed int64) (*MarketMaker, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &MarketMaker{config: config, rng: rand.New(rand.NewSource(seed))}, nil
+}
+
+// Fills returns every fill so far, in order.
+func (m *MarketMaker) Fills() []Fill {
+	fills := make([]Fill, len(m.fills))
+	copy(fills, m.fills)
+	return fills
+}
+
+// Quotes returns the two-sided markets the book would show at snapshot,
+// skewed by its inventory. Expired contracts are not quoted.
+func (m *MarketMaker) Quotes(book *Book, snapshot strategy.MarketSnapshot) ([]Quote, error) {
+	underlying, err := snapshot.Price(m.config.Underlying)
+	if err != nil {
+		return nil, err
+	}
+	quotes := make([]Quote, 0, len(m.config.Contracts))
+	for _, contract := range m.config.Contracts {
+		if !contract.Expiry.After(snapshot.Time()) {
+			continue
+		}
+		model, greeks, err := m.config.price(contract, underlying, snapshot.Time())
+		if err != nil {
+			return nil, fmt.Errorf("failed to price %s: %w", contract.ID(), err)
+		}
+		if model.IsZero() {
+			continue
+		}
+
+		inventory := book.inventory[contract]
+		shift := m.config.Skew.Mul(inventory)
+		bid := model.Decimal().Mul(primitives.One().Sub(m.config.HalfSpread).Sub(shift))
+		ask := model.Decimal().Mul(primitives.One().Add(m.config.HalfSpread).Sub(shift))
+		quote := Quote{
+			Contract: contract,
+			Model:    model,
+			Delta:    greeks.Delta,
+			BidOpen:  bid.IsPositive() && !inventory.Add(m.config.QuoteSize).GreaterThan(m.config.MaxInventory),
+			AskOpen:  !inventory.Sub(m.config.QuoteSize).LessThan(m.config.MaxInventory.Neg()),
+		}
+		if quote.BidOpen {
+			quote.Bid = primitives.MustPrice(bid)
+		}
+		quote.Ask = primitives.MustPrice(ask)
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
+
+// Step advances book to snapshot: expired contracts settle at intrinsic
+// value, quotes are filled at random according to the fill model, and the
+// hedge is reset to flat if net delta leaves the hedge band. It returns the
+// next book and the fills.
+func (m *MarketMaker) Step(book *Book, snapshot strategy.MarketSnapshot) (*Book, []Fill, error) {
+	underlying, err := snapshot.Price(m.config.Underlying)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := snapshot.Time()
+	next := book.clone()
+
+	// Settle expired contracts at intrinsic value
+	for _, contract := range book.contracts() {
+		if contract.Expiry.After(now) {
+			continue
+		}
+		payoff, _, err := m.config.price(contract, underlying, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		next.cash = next.cash.Add(next.inventory[contract].Mul(payoff.Decimal()))
+		delete(next.inventory, contract)
+	}
+
+	quotes, err := m.Quotes(next, snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fills []Fill
+	size := m.config.QuoteSize
+	for _, quote := range quotes {
+		// Draw both sides so the random sequence does not depend on which
+		// sides are open
+		bidDraw, askDraw := m.rng.Float64(), m.rng.Float64()
+		if quote.BidOpen {
+			edge := m.edge(quote.Model.Decimal().Sub(quote.Bid.Decimal()), quote.Model)
+			if bidDraw < m.config.Fill.FillProbability(edge).Float64() {
+				next.inventory[quote.Contract] = next.inventory[quote.Contract].Add(size)
+				next.cash = next.cash.Sub(size.Mul(quote.Bid.Decimal()))
+				fills = append(fills, Fill{Time: now, Contract: quote.Contract, Side: mechanisms.OrderSideBuy, Quantity: size, Price: quote.Bid, Edge: edge})
+			}
+		}
+		if quote.AskOpen {
+			edge := m.edge(quote.Ask.Decimal().Sub(quote.Model.Decimal()), quote.Model)
+			if askDraw < m.config.Fill.FillProbability(edge).Float64() {
+				next.inventory[quote.Contract] = next.inventory[quote.Contract].Sub(size)
+				next.cash = next.cash.Add(size.Mul(quote.Ask.Decimal()))
+				fills = append(fills, Fill{Time: now, Contract: quote.Contract, Side: mechanisms.OrderSideSell, Quantity: size, Price: quote.Ask, Edge: edge})
+			}
+		}
+	}
+	for contract, quantity := range next.inventory {
+		if quantity.IsZero() {
+			delete(next.inventory, contract)
+		}
+	}
+
+	if err := m.rehedge(next, underlying, snapshot); err != nil {
+		return nil, nil, err
+	}
+	m.fills = append(m.fills, fills...)
+	return next, fills, nil
+}
+
+// edge returns diff as a fraction of the model price.
+func (m *MarketMaker) edge(diff primitives.Decimal, model primitives.Price) primitives.Decimal {
+	edge, err := diff.Div(model.Decimal())
+	if err != nil {
+		return primitives.Zero()
+	}
+	return edge
+}
+
+// rehedge resets the hedge to offset the options' delta when the book's
+// net delta is outside the band, realizing the old hedge's P&L.
+func (m *MarketMaker) rehedge(book *Book, underlying primitives.Price, snapshot strategy.MarketSnapshot) error {
+	greeks, err := book.Greeks(snapshot)
+	if err != nil {
+		return err
+	}
+	if !greeks.Delta.Abs().GreaterThan(m.config.HedgeBand) {
+		return nil
+	}
+
+	target := book.Hedge().Sub(greeks.Delta)
+	if book.hedge != nil {
+		pnl, err := book.hedge.UnrealizedPnL(underlying)
+		if err != nil {
+			return err
+		}
+		book.cash = book.cash.Add(pnl)
+		book.hedge = nil
+	}
+	if target.IsZero() {
+		return nil
+	}
+	hedge, err := perpetual.NewFuture(m.config.ID+"-hedge", m.config.Underlying, underlying, target, primitives.One(), 8*time.Hour)
+	if err != nil {
+		return fmt.Errorf("failed to open hedge: %w", err)
+	}
+	book.hedge = hedge
+	return nil
+}
+
+// Rebalance implements strategy.Strategy. The first call posts the margin
+// and opens the book; later calls step it and replace it in the portfolio.
+func (m *MarketMaker) Rebalance(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	position, err := portfolio.GetPosition(m.config.ID)
+	if err != nil {
+		book, err := NewBook(m.config)
+		if err != nil {
+			return nil, err
+		}
+		next, _, err := m.Step(book, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return []strategy.Action{
+			strategy.NewAdjustCashAction(m.config.Margin.Neg(), "post market maker margin"),
+			strategy.NewAddPositionAction(next),
+		}, nil
+	}
+
+	book, ok := position.(*Book)
+	if !ok {
+		return nil, fmt.Errorf("%w: position %s is %T, not a book", ErrInvalidConfig, m.config.ID, position)
+	}
+	next, _, err := m.Step(book, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return []strategy.Action{strategy.NewReplacePositionAction(book.ID(), next)}, nil
+}
@@ -0,0 +1,252 @@
+package marketmaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/marketmaker"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func price(s string) primitives.Price {
+	return primitives.MustPrice(dec(s))
+}
+
+func snapshot(hours int, eth string) strategy.MarketSnapshot {
+	return strategy.NewSimpleSnapshot(
+		primitives.NewTime(start.Add(time.Duration(hours)*time.Hour)),
+		map[string]primitives.Price{"ETH/USD": price(eth)},
+	)
+}
+
+// config quotes a ladder of 30-day calls and puts around 2000.
+func config() marketmaker.Config {
+	expiry := primitives.NewTime(start.Add(30 * 24 * time.Hour))
+	return marketmaker.Config{
+		ID:         "mm:eth",
+		Underlying: "ETH/USD",
+		Contracts: marketmaker.Ladder(
+			[]mechanisms.OptionType{mechanisms.OptionTypeCall, mechanisms.OptionTypePut},
+			[]primitives.Price{price("1800"), price("2000"), price("2200")},
+			[]primitives.Time{expiry},
+		),
+		Volatility:   dec("0.6"),
+		RiskFreeRate: dec("0.05"),
+		HalfSpread:   dec("0.02"),
+		Skew:         dec("0.005"),
+		QuoteSize:    primitives.One(),
+		MaxInventory: dec("3"),
+		Fill:         marketmaker.ExponentialFill{Base: dec("0.5"), Decay: dec("20")},
+		HedgeBand:    dec("0.1"),
+		Margin:       dec("10000"),
+	}
+}
+
+func TestExponentialFill(t *testing.T) {
+	fill := marketmaker.ExponentialFill{Base: dec("0.5"), Decay: dec("10")}
+	tests := []struct {
+		name     string
+		edge     string
+		min, max float64
+	}{
+		{"at model", "0", 0.5, 0.5},
+		{"wide quote", "0.1", 0.18, 0.19},
+		{"through model capped", "-1", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := fill.FillProbability(dec(tt.edge)).Float64()
+			if p < tt.min || p > tt.max {
+				t.Errorf("probability = %f, want [%f, %f]", p, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	mm, err := marketmaker.NewMarketMaker(config(), 1)
+	if err != nil {
+		t.Fatalf("NewMarketMaker: %v", err)
+	}
+	book, err := marketmaker.NewBook(config())
+	if err != nil {
+		t.Fatalf("NewBook: %v", err)
+	}
+	quotes, err := mm.Quotes(book, snapshot(0, "2000"))
+	if err != nil {
+		t.Fatalf("Quotes: %v", err)
+	}
+	if len(quotes) != 6 {
+		t.Fatalf("got %d quotes, want 6", len(quotes))
+	}
+	for _, q := range quotes {
+		m, _ := q.Bid.Decimal().Add(q.Ask.Decimal()).Div(primitives.NewDecimal(2))
+		if !m.Round(8, primitives.RoundHalfEven).Equal(q.Model.Decimal().Round(8, primitives.RoundHalfEven)) {
+			t.Errorf("%s: mid %s, want model %s with flat inventory", q.Contract.ID(), m, q.Model)
+		}
+		if !q.BidOpen || !q.AskOpen {
+			t.Errorf("%s: both sides should be open", q.Contract.ID())
+		}
+	}
+	if id := quotes[0].Contract.ID(); id != "call-1800-20240131" {
+		t.Errorf("first contract = %s, want call-1800-20240131", id)
+	}
+	if _, err := mm.Quotes(book, snapshot(30*24, "2000")); err != nil {
+		t.Errorf("Quotes at expiry: %v", err)
+	}
+}
+
+func TestStep(t *testing.T) {
+	cfg := config()
+	// Certain fills on both sides every snapshot
+	cfg.Fill = marketmaker.ExponentialFill{Base: dec("2"), Decay: primitives.Zero()}
+	mm, err := marketmaker.NewMarketMaker(cfg, 1)
+	if err != nil {
+		t.Fatalf("NewMarketMaker: %v", err)
+	}
+	book, _ := marketmaker.NewBook(cfg)
+
+	t.Run("round trip earns the spread", func(t *testing.T) {
+		next, fills, err := mm.Step(book, snapshot(0, "2000"))
+		if err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+		if len(fills) != 12 || len(next.Inventory()) != 0 {
+			t.Fatalf("got %d fills and inventory %v, want 12 and flat", len(fills), next.Inventory())
+		}
+		value, err := next.Value(snapshot(0, "2000"))
+		if err != nil || !value.Decimal().GreaterThan(cfg.Margin) {
+			t.Errorf("value = %s (%v), want above margin", value, err)
+		}
+		if !book.Cash().Equal(cfg.Margin) {
+			t.Errorf("original book cash changed to %s", book.Cash())
+		}
+	})
+}
+
+func TestDeltaHedge(t *testing.T) {
+	cfg := config()
+	cfg.Contracts = cfg.Contracts[:1] // 1800 call
+	// Sell only: bids are never hit
+	cfg.Fill = &sellOnly{}
+	cfg.MaxInventory = primitives.One()
+	mm, _ := marketmaker.NewMarketMaker(cfg, 1)
+	book, _ := marketmaker.NewBook(cfg)
+
+	next, fills, err := mm.Step(book, snapshot(0, "2000"))
+	if err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if len(fills) != 1 || fills[0].Side != mechanisms.OrderSideSell {
+		t.Fatalf("fills = %+v, want one sell", fills)
+	}
+	// Short an in-the-money call: the hedge is long its delta
+	if !next.Hedge().IsPositive() {
+		t.Errorf("hedge = %s, want long", next.Hedge())
+	}
+	greeks, _ := next.Greeks(snapshot(0, "2000"))
+	if !greeks.Delta.Abs().LessThan(dec("0.0001")) || !greeks.Gamma.IsNegative() {
+		t.Errorf("greeks = %+v, want flat delta and short gamma", greeks)
+	}
+	risk, err := next.Risk(snapshot(0, "2000"))
+	if err != nil || !risk.Gamma.Equal(greeks.Gamma) {
+		t.Errorf("risk = %+v (%v), want book Greeks", risk, err)
+	}
+
+	// The short is at the limit, so only the bid is quoted
+	quotes, err := mm.Quotes(next, snapshot(0, "2000"))
+	if err != nil || len(quotes) != 1 || !quotes[0].BidOpen || quotes[0].AskOpen {
+		t.Errorf("quotes = %+v (%v), want bid only", quotes, err)
+	}
+	if len(quotes) == 1 && !quotes[0].Bid.Decimal().GreaterThan(quotes[0].Model.Decimal().Mul(dec("0.98"))) {
+		t.Errorf("bid %s should skew up to buy back the short", quotes[0].Bid)
+	}
+
+	// At expiry the short call settles at intrinsic and the hedge unwinds
+	settled, _, err := mm.Step(next, snapshot(31*24, "2100"))
+	if err != nil {
+		t.Fatalf("Step at expiry: %v", err)
+	}
+	if len(settled.Inventory()) != 0 || !settled.Hedge().IsZero() {
+		t.Errorf("expired book should be flat, got %v and hedge %s", settled.Inventory(), settled.Hedge())
+	}
+}
+
+// sellOnly fills every ask and never fills bids. Step asks for the bid's
+// probability before the ask's, so every second call is an ask.
+type sellOnly struct{ calls int }
+
+func (s *sellOnly) FillProbability(primitives.Decimal) primitives.Decimal {
+	s.calls++
+	if s.calls%2 == 0 {
+		return primitives.One()
+	}
+	return primitives.Zero()
+}
+
+func TestBacktest(t *testing.T) {
+	var snapshots []strategy.MarketSnapshot
+	for day, eth := range []string{"2000", "2040", "1990", "2010", "2060", "2030"} {
+		snapshots = append(snapshots, snapshot(day*24, eth))
+	}
+	run := func() (*backtest.Result, *marketmaker.MarketMaker) {
+		mm, err := marketmaker.NewMarketMaker(config(), 42)
+		if err != nil {
+			t.Fatalf("NewMarketMaker: %v", err)
+		}
+		result, err := backtest.NewEngine(backtest.DefaultConfig()).Run(context.Background(), mm, snapshots)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return result, mm
+	}
+
+	first, mm := run()
+	second, _ := run()
+	if !first.FinalValue.Decimal().Equal(second.FinalValue.Decimal()) {
+		t.Errorf("same seed gave %s and %s", first.FinalValue, second.FinalValue)
+	}
+	if len(mm.Fills()) == 0 {
+		t.Error("expected fills over the backtest")
+	}
+	position, err := first.Portfolio.GetPosition("mm:eth")
+	if err != nil {
+		t.Fatalf("GetPosition: %v", err)
+	}
+	for contract, quantity := range position.(*marketmaker.Book).Inventory() {
+		if quantity.Abs().GreaterThan(dec("3")) {
+			t.Errorf("%s inventory %s exceeds limit", contract.ID(), quantity)
+		}
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	mutations := map[string]func(*marketmaker.Config){
+		"no contracts":   func(c *marketmaker.Config) { c.Contracts = nil },
+		"no volatility":  func(c *marketmaker.Config) { c.Volatility = primitives.Zero() },
+		"no fill model":  func(c *marketmaker.Config) { c.Fill = nil },
+		"size over max":  func(c *marketmaker.Config) { c.MaxInventory = dec("0.5") },
+		"negative skew":  func(c *marketmaker.Config) { c.Skew = dec("-0.1") },
+		"missing margin": func(c *marketmaker.Config) { c.Margin = primitives.Zero() },
+	}
+	for name, mutate := range mutations {
+		t.Run(name, func(t *testing.T) {
+			cfg := config()
+			mutate(&cfg)
+			if _, err := marketmaker.NewMarketMaker(cfg, 1); !errors.Is(err, marketmaker.ErrInvalidConfig) {
+				t.Errorf("expected %v, got %v", marketmaker.ErrInvalidConfig, err)
+			}
+		})
+	}
+}
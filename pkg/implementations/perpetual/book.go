@@ -0,0 +1,241 @@
+package perpetual
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// NetPosition is the net perpetual position a PerpBook holds in a symbol.
+type NetPosition struct {
+	Symbol string
+
+	// Size is the signed net size (negative for shorts, zero when flat)
+	Size primitives.Decimal
+
+	// EntryPrice is the volume-weighted average entry price of the open
+	// size; zero when flat
+	EntryPrice primitives.Price
+
+	// RealizedPnL is the price P&L realized by reducing fills, before
+	// funding
+	RealizedPnL primitives.Decimal
+
+	// Funding is the net funding paid across the life of the position, as
+	// Future.AccumulatedFunding: positive when paid, negative when
+	// received
+	Funding primitives.Decimal
+
+	// Fills is the number of fills merged into the position
+	Fills int
+}
+
+// Direction returns the direction of the net size; long when flat.
+func (p NetPosition) Direction() mechanisms.PositionDirection {
+	return directionOf(p.Size)
+}
+
+// IsFlat reports whether the net size is zero.
+func (p NetPosition) IsFlat() bool {
+	return p.Size.IsZero()
+}
+
+// UnrealizedPnL returns Size × (markPrice - EntryPrice).
+func (p NetPosition) UnrealizedPnL(markPrice primitives.Price) (primitives.Decimal, error) {
+	if markPrice.IsZero() {
+		return primitives.Zero(), ErrInvalidMarkPrice
+	}
+	if p.IsFlat() {
+		return primitives.Zero(), nil
+	}
+	return markPrice.Decimal().Sub(p.EntryPrice.Decimal()).Mul(p.Size), nil
+}
+
+// TotalPnL returns realized plus unrealized P&L less net funding paid.
+func (p NetPosition) TotalPnL(markPrice primitives.Price) (primitives.Decimal, error) {
+	unrealized, err := p.UnrealizedPnL(markPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return p.RealizedPnL.Add(unrealized).Sub(p.Funding), nil
+}
+
+// PerpBook nets perpetual fills into one position per symbol, so adding to
+// or trimming a position does not need a new Future with its own ID. Fills
+// on the side of the position increase it at a volume-weighted average
+// entry price; opposite fills reduce it, realizing P&L against that entry,
+// and a fill larger than the position flips it, opening the remainder at
+// the fill price. Funding accrues on the net size, and a position's
+// realized P&L and funding are kept when it goes flat and carried into
+// any later position in the symbol.
+//
+// Thread Safety: PerpBook is not thread-safe. Concurrent access should be
+// protected by the caller.
+type PerpBook struct {
+	fundingPeriod time.Duration
+	positions     map[string]*NetPosition
+}
+
+// NewPerpBook creates an empty book whose funding rates are per
+// fundingPeriod (typically 8 hours).
+func NewPerpBook(fundingPeriod time.Duration) (*PerpBook, error) {
+	if fundingPeriod <= 0 {
+		return nil, errors.New("funding period must be positive")
+	}
+	return &PerpBook{fundingPeriod: fundingPeriod, positions: make(map[string]*NetPosition)}, nil
+}
+
+// FundingPeriod returns the period funding rates apply to.
+func (b *PerpBook) FundingPeriod() time.Duration {
+	return b.fundingPeriod
+}
+
+// Fill merges a fill of size contracts (negative for sells) at price into
+// the symbol's net position and returns the P&L it realized.
+// Returns ErrInvalidPositionSize for a zero size and ErrInvalidMarkPrice
+// for a zero price.
+func (b *PerpBook) Fill(symbol string, size primitives.Decimal, price primitives.Price) (primitives.Decimal, error) {
+	if symbol == "" {
+		return primitives.Zero(), errors.New("symbol cannot be empty")
+	}
+	if size.IsZero() {
+		return primitives.Zero(), ErrInvalidPositionSize
+	}
+	if price.IsZero() {
+		return primitives.Zero(), ErrInvalidMarkPrice
+	}
+
+	position := b.positions[symbol]
+	if position == nil {
+		position = &NetPosition{Symbol: symbol, Size: primitives.Zero(), RealizedPnL: primitives.Zero(), Funding: primitives.Zero()}
+		b.positions[symbol] = position
+	}
+	position.Fills++
+
+	realized := primitives.Zero()
+	remaining := size
+	if !position.IsFlat() && position.Size.IsNegative() != size.IsNegative() {
+		// Reduce against the average entry
+		closed := size.Abs()
+		if closed.GreaterThan(position.Size.Abs()) {
+			closed = position.Size.Abs()
+		}
+		if position.Size.IsNegative() {
+			closed = closed.Neg()
+		}
+		realized = price.Decimal().Sub(position.EntryPrice.Decimal()).Mul(closed)
+		position.Size = position.Size.Sub(closed)
+		remaining = remaining.Add(closed)
+		position.RealizedPnL = position.RealizedPnL.Add(realized)
+		if position.IsFlat() {
+			position.EntryPrice = primitives.ZeroPrice()
+		}
+	}
+	if !remaining.IsZero() {
+		// Increase, or open the remainder of a flip, at the fill price
+		next := position.Size.Add(remaining)
+		weighted := position.Size.Mul(position.EntryPrice.Decimal()).Add(remaining.Mul(price.Decimal()))
+		entry, err := weighted.Div(next)
+		if err != nil {
+			return primitives.Zero(), err
+		}
+		if position.EntryPrice, err = primitives.NewPrice(entry); err != nil {
+			return primitives.Zero(), err
+		}
+		position.Size = next
+	}
+	return realized, nil
+}
+
+// ApplyFunding charges one funding period at fundingRate (per the book's
+// funding period) on the symbol's net size at markPrice and returns the
+// payment, positive when the position pays, as Future.ApplyFunding does.
+// A flat or unknown symbol pays nothing.
+func (b *PerpBook) ApplyFunding(symbol string, markPrice primitives.Price, fundingRate primitives.Decimal) (primitives.Decimal, error) {
+	if markPrice.IsZero() {
+		return primitives.Zero(), ErrInvalidMarkPrice
+	}
+	position := b.positions[symbol]
+	if position == nil || position.IsFlat() {
+		return primitives.Zero(), nil
+	}
+	payment := position.Size.Mul(markPrice.Decimal()).Mul(fundingRate)
+	position.Funding = position.Funding.Add(payment)
+	return payment, nil
+}
+
+// ApplyFundingRate applies one funding period using a typed Rate,
+// converted to the book's funding period first (see
+// Future.ApplyFundingRate).
+func (b *PerpBook) ApplyFundingRate(symbol string, markPrice primitives.Price, rate primitives.Rate) (primitives.Decimal, error) {
+	perPeriod, err := rate.Per(primitives.NewDuration(b.fundingPeriod))
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return b.ApplyFunding(symbol, markPrice, perPeriod.Fraction())
+}
+
+// Position returns a copy of the symbol's net position, including a flat
+// one, and whether the book has traded the symbol.
+func (b *PerpBook) Position(symbol string) (NetPosition, bool) {
+	position, ok := b.positions[symbol]
+	if !ok {
+		return NetPosition{}, false
+	}
+	return *position, true
+}
+
+// Positions returns copies of every net position, flat ones included,
+// sorted by symbol.
+func (b *PerpBook) Positions() []NetPosition {
+	positions := make([]NetPosition, 0, len(b.positions))
+	for _, position := range b.positions {
+		positions = append(positions, *position)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions
+}
+
+// RealizedPnL returns the P&L realized across all symbols, before funding.
+func (b *PerpBook) RealizedPnL() primitives.Decimal {
+	total := primitives.Zero()
+	for _, position := range b.positions {
+		total = total.Add(position.RealizedPnL)
+	}
+	return total
+}
+
+// Funding returns the net funding paid across all symbols.
+func (b *PerpBook) Funding() primitives.Decimal {
+	total := primitives.Zero()
+	for _, position := range b.positions {
+		total = total.Add(position.Funding)
+	}
+	return total
+}
+
+// UnrealizedPnL returns the unrealized P&L of every open position at
+// markPrices, keyed by symbol. Returns error if an open position's symbol
+// has no mark price.
+func (b *PerpBook) UnrealizedPnL(markPrices map[string]primitives.Price) (primitives.Decimal, error) {
+	total := primitives.Zero()
+	for symbol, position := range b.positions {
+		if position.IsFlat() {
+			continue
+		}
+		mark, ok := markPrices[symbol]
+		if !ok {
+			return primitives.Zero(), fmt.Errorf("no mark price for %s", symbol)
+		}
+		pnl, err := position.UnrealizedPnL(mark)
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("%s: %w", symbol, err)
+		}
+		total = total.Add(pnl)
+	}
+	return total, nil
+}
@@ -0,0 +1,119 @@
+package perpetual
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ErrNoBreakeven is returned when expected funding never pays back the
+// cost of entering a position.
+var ErrNoBreakeven = errors.New("funding carry never covers entry cost")
+
+// Carry analytics size funding-rate trades. Funding rates are given as
+// primitives.Rate values with any period, e.g. 0.0001 per 8 hours (simple),
+// and are converted to the contract's funding period before use. A
+// positive rate means longs pay shorts, so a short position earns it.
+
+// FundingCarry returns the mean funding the position earns per funding
+// period as a fraction of notional, given a funding-rate series: the mean
+// rate for a short and its negation for a long. Returns
+// ErrInvalidFundingRate if rates is empty.
+func (f *Future) FundingCarry(rates []primitives.Rate) (primitives.Rate, error) {
+	if len(rates) == 0 {
+		return primitives.Rate{}, fmt.Errorf("%w: no funding rates", ErrInvalidFundingRate)
+	}
+	period := primitives.NewDuration(f.fundingPeriod)
+	sum := primitives.Zero()
+	for _, rate := range rates {
+		perPeriod, err := rate.Per(period)
+		if err != nil {
+			return primitives.Rate{}, err
+		}
+		sum = sum.Add(perPeriod.Fraction())
+	}
+	mean, err := sum.Div(primitives.NewDecimal(int64(len(rates))))
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	if f.direction == mechanisms.PositionDirectionLong {
+		mean = mean.Neg()
+	}
+	return primitives.NewRate(mean, period, primitives.CompoundingSimple)
+}
+
+// CarryAPR returns the annualized simple carry of the position given a
+// funding-rate series (see FundingCarry), e.g. 0.01% per 8 hours earned
+// by a short is about 10.96% a year.
+func (f *Future) CarryAPR(rates []primitives.Rate) (primitives.Rate, error) {
+	carry, err := f.FundingCarry(rates)
+	if err != nil {
+		return primitives.Rate{}, err
+	}
+	return carry.Annualized(), nil
+}
+
+// FundingSensitivity returns the change in the position's P&L over horizon
+// if the funding rate rises by one basis point per funding period, at
+// markPrice. It is negative for a long, which pays the extra funding, and
+// positive for a short.
+func (f *Future) FundingSensitivity(markPrice primitives.Price, horizon time.Duration) (primitives.Decimal, error) {
+	if markPrice.IsZero() {
+		return primitives.Zero(), ErrInvalidMarkPrice
+	}
+	bp, err := primitives.NewRate(primitives.NewBasisPoints(primitives.One()).Fraction(), primitives.NewDuration(f.fundingPeriod), primitives.CompoundingSimple)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	notional := f.positionSize.Abs().Mul(markPrice.Decimal())
+	change := notional.Mul(bp.Over(primitives.NewDuration(horizon)))
+	if f.direction == mechanisms.PositionDirectionLong {
+		change = change.Neg()
+	}
+	return change, nil
+}
+
+// BreakevenHoldingPeriod returns how long the position must be held for
+// expected funding to pay back the cost of holding it instead of spot.
+// The cost is fees, a fraction of notional (e.g., round-trip fees on both
+// legs of a cash-and-carry trade), plus the basis paid at entry: a long
+// entered at markPrice above indexPrice pays the premium, while a short
+// receives it. Expected funding is the FundingCarry of rates.
+//
+// Returns zero if the cost is not positive, and ErrNoBreakeven if the
+// position does not earn funding.
+func (f *Future) BreakevenHoldingPeriod(markPrice, indexPrice primitives.Price, fees primitives.Decimal, rates []primitives.Rate) (time.Duration, error) {
+	if markPrice.IsZero() {
+		return 0, ErrInvalidMarkPrice
+	}
+	if indexPrice.IsZero() {
+		return 0, ErrInvalidIndexPrice
+	}
+	basis, err := markPrice.Decimal().Sub(indexPrice.Decimal()).Div(indexPrice.Decimal())
+	if err != nil {
+		return 0, err
+	}
+	if f.direction == mechanisms.PositionDirectionShort {
+		basis = basis.Neg()
+	}
+	cost := fees.Add(basis)
+	if !cost.IsPositive() {
+		return 0, nil
+	}
+
+	carry, err := f.FundingCarry(rates)
+	if err != nil {
+		return 0, err
+	}
+	if !carry.Fraction().IsPositive() {
+		return 0, fmt.Errorf("%w: carry %s against cost %s", ErrNoBreakeven, carry, cost)
+	}
+	periods, err := cost.Div(carry.Fraction())
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(periods.Float64() * float64(f.fundingPeriod)), nil
+}
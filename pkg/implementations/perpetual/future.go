@@ -1,6 +1,9 @@
 // Package perpetual implements perpetual futures contracts.
 // This package provides a reference implementation of the Derivative interface
-// for perpetual swap contracts with funding rate mechanics.
+// for perpetual swap contracts with funding rate mechanics (Future), a
+// pool-based perp DEX model with borrow fees and skew-driven price impact
+// (PoolMarket and PoolPerp), and a book netting fills into one position per
+// symbol (PerpBook).
 package perpetual
 
 import (
@@ -64,8 +67,12 @@ type Future struct {
 	// accumulatedFunding tracks the total funding payments made/received
 	accumulatedFunding primitives.Decimal
 
-	// lastFundingTime tracks when the last funding was applied
-	lastFundingTime time.Time
+	// lastFundingTime tracks when the last funding was applied; zero until
+	// funding is first applied
+	lastFundingTime primitives.Time
+
+	// clock stamps lastFundingTime; the wall clock unless SetClock is called
+	clock primitives.Clock
 
 	// settled indicates if the position has been closed
 	settled bool
@@ -81,6 +88,9 @@ type Future struct {
 //   - leverage: Leverage multiplier (e.g., 10 for 10x)
 //   - fundingPeriod: Time between funding payments (typically 8 hours)
 //
+// The contract stamps funding with the wall clock; call SetClock to run it
+// on simulated time, such as strategy.Clock(ctx) in a backtest.
+//
 // Returns error if any parameter is invalid.
 func NewFuture(
 	futureID string,
@@ -129,7 +139,7 @@ func NewFuture(
 		direction:          direction,
 		fundingPeriod:      fundingPeriod,
 		accumulatedFunding: primitives.Zero(),
-		lastFundingTime:    time.Now(),
+		clock:              primitives.SystemClock{},
 		settled:            false,
 	}, nil
 }
@@ -228,31 +238,38 @@ func (f *Future) Settle(ctx context.Context) (primitives.Amount, error) {
 	return primitives.ZeroAmount(), errors.New("settle requires final mark price in context metadata with key 'final_mark_price'")
 }
 
-// SettleWithPrice settles the position given a final mark price.
-// This is a helper method that calculates final P&L including funding payments.
+// SettleWithPrice settles the position given a final mark price and
+// returns the absolute value of its P&L including funding payments.
+//
+// Deprecated: the result does not say whether the position made or lost
+// money; use SettlePnL.
 func (f *Future) SettleWithPrice(finalMarkPrice primitives.Price) (primitives.Amount, error) {
-	if f.settled {
-		return primitives.ZeroAmount(), errors.New("position already settled")
+	pnl, err := f.SettlePnL(finalMarkPrice)
+	if err != nil {
+		return primitives.ZeroAmount(), err
 	}
+	return primitives.NewAmount(pnl.Abs())
+}
 
-	if finalMarkPrice.IsZero() {
-		return primitives.ZeroAmount(), ErrInvalidMarkPrice
+// SettlePnL settles the position given a final mark price and returns its
+// P&L including funding payments: (FinalPrice - EntryPrice) * PositionSize
+// - AccumulatedFunding, negative for a loss. It is the cash the position
+// pays out beyond its margin when closed, as strategy.Settleable.Settle
+// returns.
+// Returns error if the position is already settled.
+func (f *Future) SettlePnL(finalMarkPrice primitives.Price) (primitives.Decimal, error) {
+	if f.settled {
+		return primitives.Zero(), errors.New("position already settled")
 	}
 
-	// Calculate price P&L: (FinalPrice - EntryPrice) * PositionSize
-	priceDiff := finalMarkPrice.Decimal().Sub(f.entryPrice.Decimal())
-	pricePnl := priceDiff.Mul(f.positionSize)
-
-	// Subtract accumulated funding (funding is a cost/benefit separate from price movement)
-	totalPnl := pricePnl.Sub(f.accumulatedFunding)
+	pnl, err := f.UnrealizedPnL(finalMarkPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
 
 	f.settled = true
 
-	// Return absolute value as Amount (sign indicates profit/loss)
-	if totalPnl.IsNegative() {
-		return primitives.NewAmount(totalPnl.Neg())
-	}
-	return primitives.NewAmount(totalPnl)
+	return pnl, nil
 }
 
 // ApplyFunding applies funding rate payments to the position.
@@ -291,11 +308,22 @@ func (f *Future) ApplyFunding(markPrice primitives.Price, fundingRate primitives
 
 	// Accumulate funding
 	f.accumulatedFunding = f.accumulatedFunding.Add(payment)
-	f.lastFundingTime = time.Now()
+	f.lastFundingTime = f.clock.Now()
 
 	return payment, nil
 }
 
+// ApplyFundingRate applies a funding payment for one funding period using a
+// typed Rate. The rate is converted to the contract's funding period first,
+// so an 8-hour venue rate can be applied to a 1-hour contract and vice versa.
+func (f *Future) ApplyFundingRate(markPrice primitives.Price, rate primitives.Rate) (primitives.Decimal, error) {
+	perPeriod, err := rate.Per(primitives.NewDuration(f.fundingPeriod))
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return f.ApplyFunding(markPrice, perPeriod.Fraction())
+}
+
 // CalculateFundingRate calculates the funding rate based on mark and index prices.
 //
 // The funding rate is typically calculated as:
@@ -381,6 +409,22 @@ func (f *Future) LiquidationPrice() (primitives.Price, error) {
 	return primitives.NewPrice(liquidationPrice)
 }
 
+// SetClock sets the clock the contract stamps funding with, such as
+// strategy.Clock(ctx) or backtest.Config.Clock in a backtest. Nil
+// restores the wall clock.
+func (f *Future) SetClock(clock primitives.Clock) {
+	if clock == nil {
+		clock = primitives.SystemClock{}
+	}
+	f.clock = clock
+}
+
+// LastFundingTime returns when funding was last applied, or the zero time
+// if it has not been.
+func (f *Future) LastFundingTime() primitives.Time {
+	return f.lastFundingTime
+}
+
 // FutureID returns the future contract identifier.
 func (f *Future) FutureID() string {
 	return f.futureID
@@ -401,6 +445,11 @@ func (f *Future) PositionSize() primitives.Decimal {
 	return f.positionSize
 }
 
+// Size returns the signed position size (negative for shorts).
+func (f *Future) Size() primitives.Quantity {
+	return primitives.NewQuantity(f.positionSize)
+}
+
 // Leverage returns the leverage multiplier.
 func (f *Future) Leverage() primitives.Decimal {
 	return f.leverage
@@ -2,13 +2,17 @@ package perpetual_test
 
 import (
 	"context"
+	"errors"
 	"math"
+	"math/rand"
 	"testing"
 	"time"
 
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/perpetual"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms/mechanismtest"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
 )
 
 // Test constants
@@ -640,3 +644,475 @@ func TestMechanismInterface(t *testing.T) {
 		t.Errorf("Expected venue 'perpetual' but got %v", future.Venue())
 	}
 }
+
+// TestSignedSize tests the signed Quantity view of position size.
+func TestSignedSize(t *testing.T) {
+	short, err := perpetual.NewFuture(
+		"ETH-PERP-1",
+		"ETHUSDT",
+		primitives.MustPrice(primitives.NewDecimal(3000)),
+		primitives.NewDecimal(-2),
+		primitives.NewDecimal(5),
+		8*time.Hour,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create future: %v", err)
+	}
+
+	size := short.Size()
+	if !size.IsShort() || size.String() != "-2" {
+		t.Errorf("Expected short size -2 but got %s", size)
+	}
+	if !size.Abs().Equal(primitives.MustAmount(primitives.NewDecimal(2))) {
+		t.Errorf("Expected magnitude 2 but got %s", size.Abs())
+	}
+}
+
+// TestApplyFundingRate tests funding with a typed Rate on a different period.
+func TestApplyFundingRate(t *testing.T) {
+	future, err := perpetual.NewFuture(
+		"BTC-PERP-1",
+		"BTCUSDT",
+		primitives.MustPrice(primitives.NewDecimal(50000)),
+		primitives.NewDecimal(1),
+		primitives.NewDecimal(10),
+		time.Hour,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create future: %v", err)
+	}
+
+	// 0.08% per 8 hours is 0.01% per hourly funding period
+	rate, err := primitives.NewRate(primitives.MustDecimalFromString("0.0008"), primitives.Hours(8), primitives.CompoundingSimple)
+	if err != nil {
+		t.Fatalf("Failed to create rate: %v", err)
+	}
+	payment, err := future.ApplyFundingRate(primitives.MustPrice(primitives.NewDecimal(50000)), rate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !payment.Equal(primitives.NewDecimal(5)) {
+		t.Errorf("Expected payment 5 but got %s", payment)
+	}
+}
+
+// TestDerivativeContract runs the Derivative property suite for long and short positions.
+func TestDerivativeContract(t *testing.T) {
+	for _, size := range []float64{1.5, -1.5} {
+		future, err := perpetual.NewFuture(
+			"CONTRACT",
+			"BTCUSDT",
+			primitives.MustPrice(primitives.NewDecimal(50000)),
+			primitives.NewDecimalFromFloat(size),
+			primitives.NewDecimal(10),
+			8*time.Hour,
+		)
+		if err != nil {
+			t.Fatalf("Failed to create future: %v", err)
+		}
+		t.Run(string(future.Direction()), func(t *testing.T) {
+			mechanismtest.VerifyDerivative(t, future, mechanismtest.DerivativeConfig{
+				Params: func(r *rand.Rand) mechanisms.PriceParams {
+					return mechanisms.PriceParams{
+						MarkPrice:   primitives.MustPrice(primitives.NewDecimalFromFloat(40000 + r.Float64()*20000)),
+						FundingRate: primitives.NewDecimalFromFloat((r.Float64() - 0.5) * 0.001),
+					}
+				},
+				NonNegativeGamma: true,
+			})
+		})
+	}
+}
+
+// TestCarryAnalytics tests funding carry, sensitivity and breakeven for longs and shorts.
+func TestCarryAnalytics(t *testing.T) {
+	newFuture := func(size int64) *perpetual.Future {
+		future, err := perpetual.NewFuture("ETH-PERP", "ETHUSDT", primitives.MustPrice(primitives.NewDecimal(3000)),
+			primitives.NewDecimal(size), primitives.NewDecimal(2), 8*time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to create future: %v", err)
+		}
+		return future
+	}
+	rate := func(fraction string, period primitives.Duration) primitives.Rate {
+		r, err := primitives.NewRate(primitives.MustDecimalFromString(fraction), period, primitives.CompoundingSimple)
+		if err != nil {
+			t.Fatalf("Failed to create rate: %v", err)
+		}
+		return r
+	}
+	// Mean funding is 0.02% per 8 hours; the daily rate is converted first
+	rates := []primitives.Rate{rate("0.0001", primitives.Hours(8)), rate("0.0003", primitives.Hours(24)), rate("0.0004", primitives.Hours(8))}
+	short, long := newFuture(-2), newFuture(2)
+
+	apr, err := short.CarryAPR(rates)
+	if err != nil {
+		t.Fatalf("CarryAPR: %v", err)
+	}
+	if got := apr.Fraction().Float64(); math.Abs(got-0.0002*8766/8) > 1e-9 || apr.Period() != primitives.Year {
+		t.Errorf("short carry APR = %s, want 21.915%% per year", apr)
+	}
+	carry, err := long.FundingCarry(rates)
+	if err != nil {
+		t.Fatalf("FundingCarry: %v", err)
+	}
+	if !carry.Fraction().Equal(primitives.MustDecimalFromString("-0.0002")) {
+		t.Errorf("long carry = %s, want -0.02%% per 8h", carry)
+	}
+	if _, err := short.CarryAPR(nil); !errors.Is(err, perpetual.ErrInvalidFundingRate) {
+		t.Errorf("empty series: got %v, want ErrInvalidFundingRate", err)
+	}
+
+	// 6,000 notional × 1bp × 3 funding periods a day
+	mark := primitives.MustPrice(primitives.NewDecimal(3000))
+	for future, want := range map[*perpetual.Future]string{short: "1.8", long: "-1.8"} {
+		got, err := future.FundingSensitivity(mark, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("FundingSensitivity: %v", err)
+		}
+		if !got.Equal(primitives.MustDecimalFromString(want)) {
+			t.Errorf("sensitivity of size %s = %s, want %s", future.PositionSize(), got, want)
+		}
+	}
+
+	fees := primitives.MustDecimalFromString("0.001")
+	breakeven, err := short.BreakevenHoldingPeriod(mark, mark, fees, rates)
+	if err != nil {
+		t.Fatalf("BreakevenHoldingPeriod: %v", err)
+	}
+	if breakeven != 40*time.Hour {
+		t.Errorf("breakeven = %s, want 40h (5 funding periods)", breakeven)
+	}
+	premium := primitives.MustPrice(primitives.NewDecimal(3003))
+	if breakeven, err := short.BreakevenHoldingPeriod(premium, mark, fees, rates); err != nil || breakeven != 0 {
+		t.Errorf("basis covering fees: got %s, %v; want 0", breakeven, err)
+	}
+	if _, err := long.BreakevenHoldingPeriod(mark, mark, fees, rates); !errors.Is(err, perpetual.ErrNoBreakeven) {
+		t.Errorf("long paying funding: got %v, want ErrNoBreakeven", err)
+	}
+}
+
+// TestPoolMarket tests price impact, borrow and funding fees and position
+// lifecycle on a pool-based perp market.
+func TestPoolMarket(t *testing.T) {
+	d := primitives.MustDecimalFromString
+	mark := primitives.MustPrice(primitives.NewDecimal(2000))
+	hourly := func(fraction string) primitives.Rate {
+		rate, err := primitives.NewRate(d(fraction), primitives.Hours(1), primitives.CompoundingSimple)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rate
+	}
+	params := perpetual.PoolMarketParams{
+		PoolValue:         d("10000000"),
+		PositionFee:       d("0.0005"),
+		ImpactFactor:      d("0.000000001"),
+		BorrowRate:        hourly("0.01"),
+		FundingRate:       hourly("0.001"),
+		MaxLeverage:       d("50"),
+		MaintenanceMargin: d("0.01"),
+	}
+	newMarket := func(t *testing.T) *perpetual.PoolMarket {
+		market, err := perpetual.NewPoolMarket("ETH-USD", params)
+		if err != nil {
+			t.Fatalf("NewPoolMarket: %v", err)
+		}
+		return market
+	}
+	expect := func(t *testing.T, name string, got primitives.Decimal, want string) {
+		t.Helper()
+		if !got.Equal(d(want)) {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("lifecycle", func(t *testing.T) {
+		market := newMarket(t)
+		long, quote, err := market.Open("long", d("10"), mark, d("2000"), start)
+		if err != nil {
+			t.Fatalf("Open long: %v", err)
+		}
+		// Skew 0 -> 20000 costs 1e-9 * 20000^2
+		expect(t, "long impact", quote.Impact, "0.4")
+		expect(t, "long fee", quote.Fee, "10")
+		expect(t, "long entry", long.EntryPrice().Decimal(), "2000.04")
+		expect(t, "long collateral", long.Collateral(), "1990")
+
+		short, quote, err := market.Open("short", d("-2.5"), mark, d("1000"), start)
+		if err != nil {
+			t.Fatalf("Open short: %v", err)
+		}
+		// Reducing the skew from 20000 to 15000 earns a rebate and a
+		// better price for the seller
+		expect(t, "short impact", quote.Impact, "-0.175")
+		expect(t, "short entry", short.EntryPrice().Decimal(), "2000.07")
+
+		// Skew 15000 of 25000: longs pay 0.6 of the funding rate and shorts
+		// receive the same total on a quarter of the open interest
+		expect(t, "long funding", market.FundingRate(mechanisms.PositionDirectionLong).Fraction(), "0.0006")
+		expect(t, "short funding", market.FundingRate(mechanisms.PositionDirectionShort).Fraction(), "-0.0024")
+		expect(t, "long borrow", market.BorrowRate(mechanisms.PositionDirectionLong).Fraction(), "0.00002")
+		greeks, err := long.Greeks(context.Background(), mechanisms.PriceParams{MarkPrice: mark})
+		if err != nil {
+			t.Fatalf("Greeks: %v", err)
+		}
+		expect(t, "long theta", greeks.Theta, "-12.4")
+
+		later := start.Add(10 * time.Hour)
+		charged, err := long.Accrue(later)
+		if err != nil {
+			t.Fatalf("Accrue: %v", err)
+		}
+		expect(t, "long fees over 10h", charged, "124")
+		if charged, _ = short.Accrue(later); !charged.Equal(d("-119.75")) {
+			t.Errorf("short fees over 10h = %s, want -119.75", charged)
+		}
+		liquidation, err := long.LiquidationPrice()
+		if err != nil {
+			t.Fatalf("LiquidationPrice: %v", err)
+		}
+		expect(t, "long liquidation price", liquidation.Decimal(), "1833.44")
+
+		// Closing 21000 from skew 15000 to -6000 earns a 0.189 rebate
+		payout, err := long.Close(primitives.MustPrice(primitives.NewDecimal(2100)), later)
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		expect(t, "payout", payout, "2855.289")
+		longOI, shortOI := market.OpenInterest()
+		expect(t, "long OI", longOI, "0")
+		expect(t, "short OI", shortOI, "5000")
+		if _, err := long.Accrue(later); !errors.Is(err, perpetual.ErrPositionClosed) {
+			t.Errorf("expected ErrPositionClosed, got %v", err)
+		}
+	})
+
+	t.Run("hedge cost", func(t *testing.T) {
+		market := newMarket(t)
+		cost, err := market.HedgeCost(d("20000"), mark, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("HedgeCost: %v", err)
+		}
+		// Open 10 + 0.4, hold 20000 * (0.00048 borrow + 0.024 funding),
+		// close 10 - 0.4
+		expect(t, "hedge cost", cost, "509.6")
+		if long, short := market.OpenInterest(); !long.IsZero() || !short.IsZero() {
+			t.Errorf("HedgeCost left open interest %s/%s", long, short)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		market := newMarket(t)
+		if _, _, err := market.Open("big", d("100"), mark, d("2000"), start); !errors.Is(err, perpetual.ErrInsufficientCollateral) {
+			t.Errorf("expected ErrInsufficientCollateral, got %v", err)
+		}
+		if _, err := perpetual.NewPoolMarket("ETH-USD", perpetual.PoolMarketParams{}); !errors.Is(err, perpetual.ErrInvalidMarketParams) {
+			t.Errorf("expected ErrInvalidMarketParams, got %v", err)
+		}
+	})
+
+	t.Run("derivative contract", func(t *testing.T) {
+		position, _, err := newMarket(t).Open("contract", d("-1.5"), mark, d("1000"), start)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		mechanismtest.VerifyDerivative(t, position, mechanismtest.DerivativeConfig{
+			Params: func(r *rand.Rand) mechanisms.PriceParams {
+				return mechanisms.PriceParams{MarkPrice: primitives.MustPrice(primitives.NewDecimalFromFloat(1500 + r.Float64()*1000))}
+			},
+			NonNegativeGamma: true,
+		})
+	})
+}
+
+func TestFutureClock(t *testing.T) {
+	start := primitives.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	future, err := perpetual.NewFuture("eth-perp", "ETHUSDT", primitives.MustPrice(primitives.NewDecimal(2000)), primitives.NewDecimal(-1), primitives.One(), 8*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := primitives.NewManualClock(start)
+	future.SetClock(clock)
+	if !future.LastFundingTime().Time().IsZero() {
+		t.Errorf("LastFundingTime = %s before funding, want zero", future.LastFundingTime())
+	}
+	clock.Advance(primitives.FundingInterval)
+	if _, err := future.ApplyFunding(primitives.MustPrice(primitives.NewDecimal(2000)), primitives.MustDecimalFromString("0.0001")); err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(primitives.FundingInterval); !future.LastFundingTime().Equal(want) {
+		t.Errorf("LastFundingTime = %s after funding, want %s", future.LastFundingTime(), want)
+	}
+}
+
+func TestPerpBook(t *testing.T) {
+	d := primitives.MustDecimalFromString
+	px := func(s string) primitives.Price { return primitives.MustPrice(d(s)) }
+
+	if _, err := perpetual.NewPerpBook(0); err == nil {
+		t.Error("expected error for zero funding period")
+	}
+	book, err := perpetual.NewPerpBook(8 * time.Hour)
+	if err != nil {
+		t.Fatalf("NewPerpBook: %v", err)
+	}
+
+	steps := []struct {
+		name         string
+		size, price  string
+		wantRealized string
+		wantSize     string
+		wantEntry    string
+	}{
+		{"open long", "1", "2000", "0", "1", "2000"},
+		{"add to long at volume-weighted entry", "3", "2200", "0", "4", "2150"},
+		{"reduce realizes against the entry", "-1", "2350", "200", "3", "2150"},
+		{"flip closes and opens the remainder at the fill", "-5", "2050", "-300", "-2", "2050"},
+		{"add to short", "-2", "1950", "0", "-4", "2000"},
+		{"close flat", "4", "1900", "400", "0", "0"},
+	}
+	for _, step := range steps {
+		realized, err := book.Fill("ETH-PERP", d(step.size), px(step.price))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		position, _ := book.Position("ETH-PERP")
+		if realized.String() != step.wantRealized || position.Size.String() != step.wantSize ||
+			position.EntryPrice.String() != step.wantEntry {
+			t.Errorf("%s: realized %s, size %s @ %s; want %s, %s @ %s", step.name,
+				realized, position.Size, position.EntryPrice, step.wantRealized, step.wantSize, step.wantEntry)
+		}
+	}
+	position, ok := book.Position("ETH-PERP")
+	if !ok || !position.IsFlat() || position.Fills != len(steps) || position.RealizedPnL.String() != "300" {
+		t.Errorf("flat position = %+v", position)
+	}
+
+	// Funding accrues on the net size, signed as Future.ApplyFunding
+	if _, err := book.Fill("BTC-PERP", d("-0.5"), px("40000")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book.Fill("BTC-PERP", d("-0.5"), px("42000")); err != nil {
+		t.Fatal(err)
+	}
+	payment, err := book.ApplyFunding("BTC-PERP", px("41000"), d("0.0001"))
+	if err != nil || payment.String() != "-4.1" {
+		t.Errorf("short funding = %s (%v), want -4.1 received", payment, err)
+	}
+	rate, _ := primitives.NewRate(d("0.00005"), primitives.NewDuration(4*time.Hour), primitives.CompoundingSimple)
+	if payment, err = book.ApplyFundingRate("BTC-PERP", px("41000"), rate); err != nil || payment.String() != "-4.1" {
+		t.Errorf("rescaled funding = %s (%v), want -4.1", payment, err)
+	}
+	if payment, _ := book.ApplyFunding("ETH-PERP", px("1900"), d("0.0001")); !payment.IsZero() {
+		t.Errorf("flat position paid funding %s", payment)
+	}
+	btc, _ := book.Position("BTC-PERP")
+	if btc.Direction() != mechanisms.PositionDirectionShort || btc.Funding.String() != "-8.2" {
+		t.Errorf("BTC position = %+v", btc)
+	}
+	total, err := btc.TotalPnL(px("40000"))
+	if err != nil || total.String() != "1008.2" {
+		t.Errorf("total P&L = %s (%v), want 1008.2", total, err)
+	}
+
+	if got := book.RealizedPnL().String(); got != "300" {
+		t.Errorf("book realized = %s, want 300", got)
+	}
+	if got := book.Funding().String(); got != "-8.2" {
+		t.Errorf("book funding = %s, want -8.2", got)
+	}
+	unrealized, err := book.UnrealizedPnL(map[string]primitives.Price{"BTC-PERP": px("40000")})
+	if err != nil || unrealized.String() != "1000" {
+		t.Errorf("book unrealized = %s (%v), want 1000", unrealized, err)
+	}
+	if _, err := book.UnrealizedPnL(nil); err == nil {
+		t.Error("expected error without the BTC mark price")
+	}
+	if symbols := book.Positions(); len(symbols) != 2 || symbols[0].Symbol != "BTC-PERP" {
+		t.Errorf("positions = %+v", symbols)
+	}
+	if _, err := book.Fill("ETH-PERP", primitives.Zero(), px("1")); !errors.Is(err, perpetual.ErrInvalidPositionSize) {
+		t.Errorf("zero fill: err = %v", err)
+	}
+}
+
+// TestSettlePnL tests that settling at a loss returns a negative P&L.
+func TestSettlePnL(t *testing.T) {
+	future, err := perpetual.NewFuture(
+		"TEST",
+		"BTCUSDT",
+		primitives.MustPrice(primitives.NewDecimal(50000)),
+		primitives.NewDecimalFromFloat(1.0),
+		primitives.NewDecimal(10),
+		8*time.Hour,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create future: %v", err)
+	}
+	if _, err := future.ApplyFunding(primitives.MustPrice(primitives.NewDecimal(50000)), primitives.MustDecimalFromString("0.0001")); err != nil {
+		t.Fatalf("Failed to apply funding: %v", err)
+	}
+
+	// Expected: -1000 (price loss) - 5 (funding) = -1005
+	pnl, err := future.SettlePnL(primitives.MustPrice(primitives.NewDecimal(49000)))
+	if err != nil {
+		t.Fatalf("Failed to settle: %v", err)
+	}
+	if !pnl.Equal(primitives.NewDecimal(-1005)) {
+		t.Errorf("Settlement P&L = %s, want -1005", pnl)
+	}
+	if !future.IsSettled() {
+		t.Error("Future should be marked as settled")
+	}
+	if _, err := future.SettlePnL(primitives.MustPrice(primitives.NewDecimal(49000))); err == nil {
+		t.Error("Expected error when settling already settled position")
+	}
+}
+
+func TestFuturePosition(t *testing.T) {
+	future, err := perpetual.NewFuture("eth-perp", "ETHUSDT",
+		primitives.MustPrice(primitives.NewDecimal(2000)), primitives.NewDecimal(-1),
+		primitives.NewDecimal(5), 8*time.Hour)
+	if err != nil {
+		t.Fatalf("NewFuture: %v", err)
+	}
+	position, err := perpetual.NewFuturePosition("short", "ETH/USD", future, primitives.NewDecimal(400))
+	if err != nil {
+		t.Fatalf("NewFuturePosition: %v", err)
+	}
+	snapshot := func(price int64) strategy.MarketSnapshot {
+		return strategy.NewSimpleSnapshot(primitives.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+			map[string]primitives.Price{"ETH/USD": primitives.MustPrice(primitives.NewDecimal(price))})
+	}
+
+	value, err := position.Value(snapshot(1900))
+	if err != nil || !value.Decimal().Equal(primitives.NewDecimal(500)) {
+		t.Errorf("value = %s, %v; want 500", value, err)
+	}
+	// Losses beyond the margin floor the value and debit at settlement
+	value, err = position.Value(snapshot(2500))
+	if err != nil || !value.IsZero() {
+		t.Errorf("value = %s, %v; want zero", value, err)
+	}
+	cash, err := position.Settle(snapshot(2500))
+	if err != nil {
+		t.Fatalf("Settle: %v", err)
+	}
+	if !cash.Equal(primitives.NewDecimal(-100)) {
+		t.Errorf("settled for %s, want -100", cash)
+	}
+	// Settlement leaves the held contract open, so it can be retried
+	if future.IsSettled() {
+		t.Error("Settle should not settle the held future")
+	}
+	if cash, err := position.Settle(snapshot(1900)); err != nil || !cash.Equal(primitives.NewDecimal(500)) {
+		t.Errorf("retried settlement = %s, %v; want 500", cash, err)
+	}
+
+	if _, err := perpetual.NewFuturePosition("x", "ETH/USD", nil, primitives.Zero()); err == nil {
+		t.Error("expected error for nil future")
+	}
+}
@@ -0,0 +1,506 @@
+package perpetual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInvalidMarketParams is returned when pool market parameters are invalid
+	ErrInvalidMarketParams = errors.New("invalid pool market parameters")
+
+	// ErrInsufficientCollateral is returned when collateral cannot cover a
+	// position's fees or leverage limit
+	ErrInsufficientCollateral = errors.New("insufficient collateral")
+
+	// ErrPositionClosed is returned when operating on a closed position
+	ErrPositionClosed = errors.New("position already closed")
+)
+
+// PoolMarketParams configures a PoolMarket. Rates accrue on position
+// notional at entry and are converted to the elapsed time with Rate.Over.
+type PoolMarketParams struct {
+	// PoolValue is the value of the liquidity pool that takes the other
+	// side of every trade, in the quote currency
+	PoolValue primitives.Decimal
+
+	// PositionFee is the fee charged on notional opened or closed,
+	// e.g. 0.0005 for 5 bps
+	PositionFee primitives.Decimal
+
+	// ImpactFactor scales price impact. A trade moving the skew (long
+	// minus short open interest, in quote) from s to s' costs
+	// ImpactFactor * (s'^2 - s^2); trades that reduce the skew earn the
+	// negative cost as a rebate
+	ImpactFactor primitives.Decimal
+
+	// BorrowRate is the borrow fee at full utilization; each side pays
+	// BorrowRate scaled by its open interest over PoolValue
+	BorrowRate primitives.Rate
+
+	// FundingRate is the funding the larger side pays at full skew; it is
+	// scaled by skew over total open interest and received by the smaller
+	// side. The zero Rate disables funding
+	FundingRate primitives.Rate
+
+	// MaxLeverage bounds notional over collateral at open; zero for no limit
+	MaxLeverage primitives.Decimal
+
+	// MaintenanceMargin is the fraction of notional that collateral plus
+	// P&L must cover to avoid liquidation, e.g. 0.01
+	MaintenanceMargin primitives.Decimal
+}
+
+// TradeQuote is the cost of changing open interest on a PoolMarket.
+type TradeQuote struct {
+	// Notional is the signed notional traded at the mark price: positive
+	// buys (opening longs or closing shorts), negative sells
+	Notional primitives.Decimal
+
+	// Fee is the position fee
+	Fee primitives.Decimal
+
+	// Impact is the price impact cost, negative for a rebate
+	Impact primitives.Decimal
+
+	// ExecutionPrice is the mark price adjusted for impact
+	ExecutionPrice primitives.Price
+}
+
+// Cost returns the fee plus the price impact.
+func (q TradeQuote) Cost() primitives.Decimal {
+	return q.Fee.Add(q.Impact)
+}
+
+// PoolMarket models a perpetual market on a pool-based perp DEX such as
+// GMX. Unlike a Future on an order-book venue, traders face a liquidity
+// pool rather than each other, so costs depend on the pool's state:
+//   - Price impact grows with the open interest skew a trade creates
+//   - Each side pays a borrow fee proportional to its pool utilization
+//   - The larger side pays funding to the smaller side
+//
+// Positions opened with Open add to the market's open interest, and so
+// move the impact and fees every other position sees.
+//
+// Thread Safety: PoolMarket is not thread-safe.
+type PoolMarket struct {
+	symbol  string
+	params  PoolMarketParams
+	longOI  primitives.Decimal
+	shortOI primitives.Decimal
+}
+
+// NewPoolMarket creates a pool market with no open interest.
+// Returns ErrInvalidMarketParams if the pool value is not positive or any
+// fee, factor or limit is negative.
+func NewPoolMarket(symbol string, params PoolMarketParams) (*PoolMarket, error) {
+	if symbol == "" {
+		return nil, errors.New("symbol cannot be empty")
+	}
+	if !params.PoolValue.IsPositive() {
+		return nil, fmt.Errorf("%w: pool value must be positive", ErrInvalidMarketParams)
+	}
+	for name, value := range map[string]primitives.Decimal{
+		"position fee":       params.PositionFee,
+		"impact factor":      params.ImpactFactor,
+		"borrow rate":        params.BorrowRate.Fraction(),
+		"funding rate":       params.FundingRate.Fraction(),
+		"max leverage":       params.MaxLeverage,
+		"maintenance margin": params.MaintenanceMargin,
+	} {
+		if value.IsNegative() {
+			return nil, fmt.Errorf("%w: %s cannot be negative", ErrInvalidMarketParams, name)
+		}
+	}
+	return &PoolMarket{
+		symbol:  symbol,
+		params:  params,
+		longOI:  primitives.Zero(),
+		shortOI: primitives.Zero(),
+	}, nil
+}
+
+// Symbol returns the market symbol.
+func (m *PoolMarket) Symbol() string {
+	return m.symbol
+}
+
+// Params returns the market parameters.
+func (m *PoolMarket) Params() PoolMarketParams {
+	return m.params
+}
+
+// OpenInterest returns the long and short open interest in quote.
+func (m *PoolMarket) OpenInterest() (long, short primitives.Decimal) {
+	return m.longOI, m.shortOI
+}
+
+// Skew returns long minus short open interest.
+func (m *PoolMarket) Skew() primitives.Decimal {
+	return m.longOI.Sub(m.shortOI)
+}
+
+// Utilization returns the open interest of a side over the pool value.
+func (m *PoolMarket) Utilization(direction mechanisms.PositionDirection) primitives.Decimal {
+	utilization, err := m.sideOI(direction).Div(m.params.PoolValue)
+	if err != nil {
+		return primitives.Zero()
+	}
+	return utilization
+}
+
+// BorrowRate returns the borrow fee rate a side currently pays.
+func (m *PoolMarket) BorrowRate(direction mechanisms.PositionDirection) primitives.Rate {
+	return m.scaleRate(m.params.BorrowRate, m.Utilization(direction))
+}
+
+// FundingRate returns the funding rate a side currently pays; it is
+// negative for the smaller side, which receives the larger side's
+// payments in proportion to their open interest.
+func (m *PoolMarket) FundingRate(direction mechanisms.PositionDirection) primitives.Rate {
+	total := m.longOI.Add(m.shortOI)
+	skew := m.Skew()
+	if total.IsZero() || skew.IsZero() {
+		return m.scaleRate(m.params.FundingRate, primitives.Zero())
+	}
+	larger := mechanisms.PositionDirectionLong
+	if skew.IsNegative() {
+		larger = mechanisms.PositionDirectionShort
+	}
+	// Safe to ignore error: total is positive
+	paid, _ := skew.Abs().Div(total)
+	if direction == larger {
+		return m.scaleRate(m.params.FundingRate, paid)
+	}
+	received, err := paid.Mul(m.sideOI(larger)).Div(m.sideOI(direction))
+	if err != nil {
+		// No open interest on the receiving side
+		return m.scaleRate(m.params.FundingRate, primitives.Zero())
+	}
+	return m.scaleRate(m.params.FundingRate, received.Neg())
+}
+
+// PriceImpact returns the impact cost of trading notional (signed, in
+// quote) against the current skew; negative values are rebates.
+func (m *PoolMarket) PriceImpact(notional primitives.Decimal) primitives.Decimal {
+	before := m.Skew()
+	after := before.Add(notional)
+	return m.params.ImpactFactor.Mul(after.Mul(after).Sub(before.Mul(before)))
+}
+
+// Quote returns the fee, impact and execution price of trading notional
+// (signed, in quote) at markPrice.
+func (m *PoolMarket) Quote(notional primitives.Decimal, markPrice primitives.Price) (TradeQuote, error) {
+	if markPrice.IsZero() {
+		return TradeQuote{}, ErrInvalidMarkPrice
+	}
+	if notional.IsZero() {
+		return TradeQuote{}, ErrInvalidPositionSize
+	}
+	impact := m.PriceImpact(notional)
+	// Buyers pay the impact as a higher price, sellers as a lower one
+	slippage, err := impact.Div(notional)
+	if err != nil {
+		return TradeQuote{}, err
+	}
+	execution, err := primitives.NewPrice(markPrice.Decimal().Mul(primitives.One().Add(slippage)))
+	if err != nil {
+		return TradeQuote{}, fmt.Errorf("%w: impact %s exceeds notional %s", ErrInvalidMarketParams, impact, notional)
+	}
+	return TradeQuote{
+		Notional:       notional,
+		Fee:            notional.Abs().Mul(m.params.PositionFee),
+		Impact:         impact,
+		ExecutionPrice: execution,
+	}, nil
+}
+
+// HoldingCost returns the borrow and funding fees a position of notional
+// (signed, in quote) already in the market pays over horizon at current
+// rates; it is negative when funding received exceeds the borrow fee.
+func (m *PoolMarket) HoldingCost(notional primitives.Decimal, horizon time.Duration) primitives.Decimal {
+	direction := directionOf(notional)
+	elapsed := primitives.NewDuration(horizon)
+	rate := m.BorrowRate(direction).Over(elapsed).Add(m.FundingRate(direction).Over(elapsed))
+	return notional.Abs().Mul(rate)
+}
+
+// HedgeCost returns the total cost of opening a position of notional
+// (signed, in quote) at markPrice, holding it for horizon and closing it
+// at the same price with the rest of the market unchanged: position fees
+// and impact both ways plus the holding cost. Compare it with a Future's
+// fees and FundingCarry to choose between an order-book and a pool venue.
+func (m *PoolMarket) HedgeCost(notional primitives.Decimal, markPrice primitives.Price, horizon time.Duration) (primitives.Decimal, error) {
+	open, err := m.Quote(notional, markPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	direction := directionOf(notional)
+	m.adjustOI(direction, notional.Abs())
+	defer m.adjustOI(direction, notional.Abs().Neg())
+
+	holding := m.HoldingCost(notional, horizon)
+	closing, err := m.Quote(notional.Neg(), markPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return open.Cost().Add(holding).Add(closing.Cost()), nil
+}
+
+// Open opens a position of size (in base units, negative for shorts) at
+// markPrice with collateral in quote, at time at. The position fee is
+// deducted from the collateral and the entry price includes price impact.
+// Returns ErrInsufficientCollateral if the collateral does not cover the
+// fee or the position exceeds MaxLeverage.
+func (m *PoolMarket) Open(id string, size primitives.Decimal, markPrice primitives.Price, collateral primitives.Decimal, at time.Time) (*PoolPerp, TradeQuote, error) {
+	if id == "" {
+		return nil, TradeQuote{}, errors.New("position ID cannot be empty")
+	}
+	if size.IsZero() {
+		return nil, TradeQuote{}, ErrInvalidPositionSize
+	}
+	quote, err := m.Quote(size.Mul(markPrice.Decimal()), markPrice)
+	if err != nil {
+		return nil, TradeQuote{}, err
+	}
+	remaining := collateral.Sub(quote.Fee)
+	if !remaining.IsPositive() {
+		return nil, TradeQuote{}, fmt.Errorf("%w: collateral %s does not cover fee %s", ErrInsufficientCollateral, collateral, quote.Fee)
+	}
+	if m.params.MaxLeverage.IsPositive() && quote.Notional.Abs().GreaterThan(remaining.Mul(m.params.MaxLeverage)) {
+		return nil, TradeQuote{}, fmt.Errorf("%w: notional %s exceeds %sx collateral %s", ErrInsufficientCollateral, quote.Notional.Abs(), m.params.MaxLeverage, remaining)
+	}
+
+	m.adjustOI(directionOf(size), quote.Notional.Abs())
+	return &PoolPerp{
+		id:          id,
+		market:      m,
+		size:        size,
+		entryPrice:  quote.ExecutionPrice,
+		notional:    quote.Notional.Abs(),
+		collateral:  remaining,
+		direction:   directionOf(size),
+		feesPaid:    quote.Fee,
+		lastAccrual: at,
+	}, quote, nil
+}
+
+func (m *PoolMarket) sideOI(direction mechanisms.PositionDirection) primitives.Decimal {
+	if direction == mechanisms.PositionDirectionShort {
+		return m.shortOI
+	}
+	return m.longOI
+}
+
+// adjustOI adds delta, negative to remove, to a side's open interest.
+func (m *PoolMarket) adjustOI(direction mechanisms.PositionDirection, delta primitives.Decimal) {
+	if direction == mechanisms.PositionDirectionShort {
+		m.shortOI = m.shortOI.Add(delta)
+	} else {
+		m.longOI = m.longOI.Add(delta)
+	}
+}
+
+func (m *PoolMarket) scaleRate(rate primitives.Rate, scale primitives.Decimal) primitives.Rate {
+	period := rate.Period()
+	if period.Duration() <= 0 {
+		period = primitives.Hours(1)
+	}
+	// Safe to ignore error: period is positive
+	scaled, _ := primitives.NewRate(rate.Fraction().Mul(scale), period, rate.Compounding())
+	return scaled
+}
+
+func directionOf(size primitives.Decimal) mechanisms.PositionDirection {
+	if size.IsNegative() {
+		return mechanisms.PositionDirectionShort
+	}
+	return mechanisms.PositionDirectionLong
+}
+
+// PoolPerp is a position on a PoolMarket. Borrow and funding fees accrue
+// against its collateral with Accrue; Close realizes the P&L.
+//
+// Thread Safety: PoolPerp is not thread-safe.
+type PoolPerp struct {
+	id         string
+	market     *PoolMarket
+	size       primitives.Decimal
+	entryPrice primitives.Price
+
+	// notional is |size| at the mark price at open, the base for borrow
+	// and funding fees
+	notional primitives.Decimal
+
+	collateral  primitives.Decimal
+	direction   mechanisms.PositionDirection
+	feesPaid    primitives.Decimal
+	lastAccrual time.Time
+	closed      bool
+}
+
+// Mechanism returns the mechanism type identifier.
+func (p *PoolPerp) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeDerivative
+}
+
+// Venue returns the venue identifier.
+func (p *PoolPerp) Venue() string {
+	return "perp-dex"
+}
+
+// Price returns the mark price, as Future.Price does.
+func (p *PoolPerp) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
+	if params.MarkPrice.IsZero() {
+		return primitives.ZeroPrice(), ErrInvalidMarkPrice
+	}
+	return params.MarkPrice, nil
+}
+
+// Greeks returns a delta of 1 for longs and -1 for shorts and, as Theta,
+// the negated borrow and funding fees the position pays per hour at the
+// market's current rates. PriceParams.FundingRate is ignored: funding on a
+// pool market follows its skew.
+func (p *PoolPerp) Greeks(ctx context.Context, params mechanisms.PriceParams) (mechanisms.Greeks, error) {
+	delta := primitives.One()
+	if p.direction == mechanisms.PositionDirectionShort {
+		delta = delta.Neg()
+	}
+	return mechanisms.Greeks{
+		Delta: delta,
+		Gamma: primitives.Zero(),
+		Theta: p.market.HoldingCost(p.signedNotional(), time.Hour).Neg(),
+		Vega:  primitives.Zero(),
+		Rho:   primitives.Zero(),
+	}, nil
+}
+
+// Settle is not supported without a price; use Close.
+func (p *PoolPerp) Settle(ctx context.Context) (primitives.Amount, error) {
+	if p.closed {
+		return primitives.ZeroAmount(), ErrPositionClosed
+	}
+	return primitives.ZeroAmount(), errors.New("settle requires a mark price; use Close")
+}
+
+// Accrue charges the borrow and funding fees due since the last accrual
+// at the market's current rates, deducting them from the collateral, and
+// returns the amount charged (negative when funding received exceeds the
+// borrow fee).
+func (p *PoolPerp) Accrue(at time.Time) (primitives.Decimal, error) {
+	if p.closed {
+		return primitives.Zero(), ErrPositionClosed
+	}
+	if at.Before(p.lastAccrual) {
+		return primitives.Zero(), fmt.Errorf("accrual at %s precedes %s", at, p.lastAccrual)
+	}
+	charge := p.market.HoldingCost(p.signedNotional(), at.Sub(p.lastAccrual))
+	p.collateral = p.collateral.Sub(charge)
+	p.feesPaid = p.feesPaid.Add(charge)
+	p.lastAccrual = at
+	return charge, nil
+}
+
+// Close accrues fees to at, closes the position at markPrice, adjusted for
+// price impact, and removes it from the market's open interest. It returns
+// the collateral paid out: collateral plus P&L less the closing fee,
+// floored at zero.
+func (p *PoolPerp) Close(markPrice primitives.Price, at time.Time) (primitives.Decimal, error) {
+	if _, err := p.Accrue(at); err != nil {
+		return primitives.Zero(), err
+	}
+	// Quote while the position is still in the open interest, so the
+	// impact is measured on the skew change of closing it
+	quote, err := p.market.Quote(p.size.Neg().Mul(markPrice.Decimal()), markPrice)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	p.market.adjustOI(p.direction, p.notional.Neg())
+
+	pnl := quote.ExecutionPrice.Decimal().Sub(p.entryPrice.Decimal()).Mul(p.size)
+	payout := p.collateral.Add(pnl).Sub(quote.Fee)
+	p.feesPaid = p.feesPaid.Add(quote.Fee)
+	p.collateral = primitives.Zero()
+	p.closed = true
+	if payout.IsNegative() {
+		return primitives.Zero(), nil
+	}
+	return payout, nil
+}
+
+// UnrealizedPnL returns size * (markPrice - entryPrice), before fees.
+func (p *PoolPerp) UnrealizedPnL(markPrice primitives.Price) (primitives.Decimal, error) {
+	if markPrice.IsZero() {
+		return primitives.Zero(), ErrInvalidMarkPrice
+	}
+	return markPrice.Decimal().Sub(p.entryPrice.Decimal()).Mul(p.size), nil
+}
+
+// LiquidationPrice returns the mark price at which collateral plus P&L
+// falls to the maintenance margin on the entry notional, given the
+// collateral left after fees accrued so far.
+func (p *PoolPerp) LiquidationPrice() (primitives.Price, error) {
+	// collateral + size*(P - entry) = margin*notional
+	margin := p.market.params.MaintenanceMargin.Mul(p.notional)
+	move, err := margin.Sub(p.collateral).Div(p.size)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	price := p.entryPrice.Decimal().Add(move)
+	if price.IsNegative() {
+		return primitives.ZeroPrice(), nil
+	}
+	return primitives.NewPrice(price)
+}
+
+func (p *PoolPerp) signedNotional() primitives.Decimal {
+	if p.direction == mechanisms.PositionDirectionShort {
+		return p.notional.Neg()
+	}
+	return p.notional
+}
+
+// ID returns the position identifier.
+func (p *PoolPerp) ID() string {
+	return p.id
+}
+
+// Market returns the market the position trades on.
+func (p *PoolPerp) Market() *PoolMarket {
+	return p.market
+}
+
+// EntryPrice returns the execution price at open, including impact.
+func (p *PoolPerp) EntryPrice() primitives.Price {
+	return p.entryPrice
+}
+
+// Size returns the signed position size (negative for shorts).
+func (p *PoolPerp) Size() primitives.Quantity {
+	return primitives.NewQuantity(p.size)
+}
+
+// Direction returns the position direction.
+func (p *PoolPerp) Direction() mechanisms.PositionDirection {
+	return p.direction
+}
+
+// Collateral returns the collateral remaining after fees.
+func (p *PoolPerp) Collateral() primitives.Decimal {
+	return p.collateral
+}
+
+// FeesPaid returns the position, borrow and funding fees paid so far.
+func (p *PoolPerp) FeesPaid() primitives.Decimal {
+	return p.feesPaid
+}
+
+// IsClosed returns whether the position has been closed.
+func (p *PoolPerp) IsClosed() bool {
+	return p.closed
+}
@@ -0,0 +1,100 @@
+package perpetual
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// FuturePosition holds a perpetual Future as a strategy.Position, marked
+// at the snapshot's price for its pair. Closing it with
+// strategy.NewSettlePositionAction returns its margin plus the Future's
+// SettlePnL, which is negative when losses exceed the margin.
+//
+// Its Value is margin plus unrealized P&L, floored at zero: a position
+// whose losses exceed its margin is worth nothing, while its settlement
+// debits the shortfall from cash.
+type FuturePosition struct {
+	id     string
+	pair   string
+	margin primitives.Decimal
+	future *Future
+}
+
+// NewFuturePosition creates a position holding future on pair, secured by
+// margin posted from cash when the position is opened.
+// Returns error if the ID or pair is empty, future is nil, or margin is
+// negative.
+func NewFuturePosition(id, pair string, future *Future, margin primitives.Decimal) (*FuturePosition, error) {
+	if id == "" || pair == "" {
+		return nil, errors.New("future position requires an ID and a pair")
+	}
+	if future == nil {
+		return nil, errors.New("future position requires a future")
+	}
+	if margin.IsNegative() {
+		return nil, fmt.Errorf("future position %s: margin must be non-negative", id)
+	}
+	return &FuturePosition{id: id, pair: pair, margin: margin, future: future}, nil
+}
+
+// ID implements strategy.Position.
+func (p *FuturePosition) ID() string {
+	return p.id
+}
+
+// Type implements strategy.Position.
+func (p *FuturePosition) Type() strategy.PositionType {
+	return strategy.PositionTypePerpetual
+}
+
+// Future returns the held contract.
+func (p *FuturePosition) Future() *Future {
+	return p.future
+}
+
+// Margin returns the margin posted with the position.
+func (p *FuturePosition) Margin() primitives.Decimal {
+	return p.margin
+}
+
+// Underlying implements strategy.UnderlyingPosition.
+func (p *FuturePosition) Underlying() string {
+	return primitives.MustPair(p.pair).Base
+}
+
+// Value implements strategy.Position: margin plus unrealized P&L at the
+// snapshot's price, floored at zero.
+func (p *FuturePosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price(p.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	pnl, err := p.future.UnrealizedPnL(price)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	equity := p.margin.Add(pnl)
+	if equity.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(equity)
+}
+
+// Settle implements strategy.Settleable: margin plus the Future's
+// SettlePnL at the snapshot's price. It settles a copy of the contract,
+// so a settlement the portfolio rolls back can be retried.
+func (p *FuturePosition) Settle(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	price, err := snapshot.Price(p.pair)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	future := *p.future
+	pnl, err := future.SettlePnL(price)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("failed to settle %s: %w", p.id, err)
+	}
+	return p.margin.Add(pnl), nil
+}
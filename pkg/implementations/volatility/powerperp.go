@@ -0,0 +1,224 @@
+package volatility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInvalidPower is returned when a power perpetual's power is not
+	// greater than 1
+	ErrInvalidPower = errors.New("power must be greater than 1")
+
+	// ErrInvalidFundingPeriod is returned when the funding period is not positive
+	ErrInvalidFundingPeriod = errors.New("funding period must be positive")
+
+	// ErrNoFairPrice is returned when the carry over one funding period is
+	// too large for the perpetual to have a finite fair price
+	ErrNoFairPrice = errors.New("no finite fair price for these parameters")
+)
+
+// PowerPerp is a perpetual that tracks Scale × S^Power of the underlying
+// price S, e.g. squeeth with Power 2 and Scale 1/10,000.
+//
+// Longs pay shorts funding of (Mark − Index) per funding period, where
+// Index = Scale × S^Power, which anchors the mark near its fair value.
+// Because the index is convex in S, a long power perp is long gamma and
+// vega without expiry; funding is the price paid for that convexity.
+//
+// Thread Safety: PowerPerp is immutable and safe for concurrent use.
+type PowerPerp struct {
+	id            string
+	symbol        string
+	power         primitives.Decimal
+	scale         primitives.Decimal
+	fundingPeriod primitives.Decimal
+	entryPrice    primitives.Price
+	positionSize  primitives.Decimal
+}
+
+// NewPowerPerp creates a power perpetual position.
+//
+// Parameters:
+//   - id: Unique identifier
+//   - symbol: Underlying symbol (e.g., "ETH")
+//   - power: Exponent of the index, greater than 1 (2 for squeeth)
+//   - scale: Index normalization, positive (e.g., 0.0001)
+//   - fundingPeriod: Funding period in years (e.g., 17.5/365 for squeeth)
+//   - entryPrice: Mark price the position was entered at
+//   - positionSize: Contracts held (positive for long, negative for short)
+func NewPowerPerp(
+	id, symbol string,
+	power, scale, fundingPeriod primitives.Decimal,
+	entryPrice primitives.Price,
+	positionSize primitives.Decimal,
+) (*PowerPerp, error) {
+	switch {
+	case id == "":
+		return nil, errors.New("power perp ID cannot be empty")
+	case !power.GreaterThan(primitives.One()):
+		return nil, ErrInvalidPower
+	case !scale.IsPositive():
+		return nil, errors.New("scale must be positive")
+	case !fundingPeriod.IsPositive():
+		return nil, ErrInvalidFundingPeriod
+	case entryPrice.IsZero():
+		return nil, errors.New("entry price must be positive")
+	case positionSize.IsZero():
+		return nil, ErrInvalidNotional
+	}
+	return &PowerPerp{
+		id:            id,
+		symbol:        symbol,
+		power:         power,
+		scale:         scale,
+		fundingPeriod: fundingPeriod,
+		entryPrice:    entryPrice,
+		positionSize:  positionSize,
+	}, nil
+}
+
+// Mechanism returns MechanismTypeDerivative.
+func (p *PowerPerp) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeDerivative
+}
+
+// Venue returns "power-perp".
+func (p *PowerPerp) Venue() string {
+	return "power-perp"
+}
+
+// Index returns Scale × S^Power, the value the perpetual tracks.
+func (p *PowerPerp) Index(underlying primitives.Price) (primitives.Price, error) {
+	if underlying.IsZero() {
+		return primitives.ZeroPrice(), errors.New("underlying price must be positive")
+	}
+	return primitives.NewPrice(primitives.NewDecimalFromFloat(p.index(underlying.Decimal().Float64())))
+}
+
+func (p *PowerPerp) index(s float64) float64 {
+	return p.scale.Float64() * math.Pow(s, p.power.Float64())
+}
+
+// carry returns the growth rate k of E[S^p] under risk-neutral GBM,
+// (p−1)·(r + p·σ²/2), and the fair price multiplier 1/(1 − k·f).
+//
+// Funding every period f is equivalent to continuously rolling into
+// expiries exponentially distributed with mean f, so the fair mark is
+// ∫ (1/f)·e^(−τ/f)·Index·e^(kτ) dτ = Index / (1 − k·f), finite while k·f < 1.
+func (p *PowerPerp) carry(params mechanisms.PriceParams) (k, multiplier float64, err error) {
+	if params.Volatility.IsNegative() {
+		return 0, 0, ErrInvalidVolatility
+	}
+	power := p.power.Float64()
+	sigma := params.Volatility.Float64()
+	k = (power - 1) * (params.RiskFreeRate.Float64() + power*sigma*sigma/2)
+	kf := k * p.fundingPeriod.Float64()
+	if kf >= 1 {
+		return 0, 0, fmt.Errorf("%w: carry %.4f over the funding period", ErrNoFairPrice, kf)
+	}
+	return k, 1 / (1 - kf), nil
+}
+
+// Price returns the fair mark price per contract, Index / (1 − k·f).
+//
+// Required parameters:
+//   - UnderlyingPrice: Current price of the underlying (S)
+//   - Volatility: Implied volatility (σ)
+//   - RiskFreeRate: Risk-free rate (r)
+func (p *PowerPerp) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
+	if params.UnderlyingPrice.IsZero() {
+		return primitives.ZeroPrice(), errors.New("underlying price must be positive")
+	}
+	_, multiplier, err := p.carry(params)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	return primitives.NewPrice(primitives.NewDecimalFromFloat(p.index(params.UnderlyingPrice.Decimal().Float64()) * multiplier))
+}
+
+// Greeks returns per-contract sensitivities of the fair mark price.
+//
+//   - Delta: p·Mark/S
+//   - Gamma: p·(p−1)·Mark/S²
+//   - Theta: 0; the position does not decay, it pays funding instead
+//   - Vega: Change in mark per 1% change in volatility
+//   - Rho: Change in mark per 1% change in the risk-free rate
+func (p *PowerPerp) Greeks(ctx context.Context, params mechanisms.PriceParams) (mechanisms.Greeks, error) {
+	mark, err := p.Price(ctx, params)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	_, multiplier, err := p.carry(params)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	s := params.UnderlyingPrice.Decimal().Float64()
+	m := mark.Decimal().Float64()
+	power := p.power.Float64()
+	sigma := params.Volatility.Float64()
+	f := p.fundingPeriod.Float64()
+
+	// ∂M/∂k = M·f/(1 − k·f) = M·f·multiplier
+	dk := m * f * multiplier
+	return mechanisms.Greeks{
+		Delta: primitives.NewDecimalFromFloat(power * m / s),
+		Gamma: primitives.NewDecimalFromFloat(power * (power - 1) * m / (s * s)),
+		Theta: primitives.Zero(),
+		Vega:  primitives.NewDecimalFromFloat(dk * (power - 1) * power * sigma * 0.01),
+		Rho:   primitives.NewDecimalFromFloat(dk * (power - 1) * 0.01),
+	}, nil
+}
+
+// Funding returns the funding the position pays over elapsed years at
+// the given mark and underlying prices: PositionSize × (Mark − Index) ×
+// elapsed / FundingPeriod. Positive values are paid, negative received.
+func (p *PowerPerp) Funding(mark, underlying primitives.Price, elapsed primitives.Decimal) (primitives.Decimal, error) {
+	index, err := p.Index(underlying)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	periods, err := elapsed.Div(p.fundingPeriod)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return p.positionSize.Mul(mark.Decimal().Sub(index.Decimal())).Mul(periods), nil
+}
+
+// UnrealizedPnL returns (Mark − EntryPrice) × PositionSize, excluding funding.
+func (p *PowerPerp) UnrealizedPnL(mark primitives.Price) (primitives.Decimal, error) {
+	if mark.IsZero() {
+		return primitives.Zero(), errors.New("mark price must be positive")
+	}
+	return mark.Decimal().Sub(p.entryPrice.Decimal()).Mul(p.positionSize), nil
+}
+
+// Settle is not supported without a closing mark; use UnrealizedPnL.
+func (p *PowerPerp) Settle(ctx context.Context) (primitives.Amount, error) {
+	return primitives.ZeroAmount(), errors.New("settle requires the closing mark price; use UnrealizedPnL")
+}
+
+// PerpID returns the position identifier.
+func (p *PowerPerp) PerpID() string {
+	return p.id
+}
+
+// Symbol returns the underlying symbol.
+func (p *PowerPerp) Symbol() string {
+	return p.symbol
+}
+
+// Power returns the index exponent.
+func (p *PowerPerp) Power() primitives.Decimal {
+	return p.power
+}
+
+// PositionSize returns the contracts held, negative for a short.
+func (p *PowerPerp) PositionSize() primitives.Decimal {
+	return p.positionSize
+}
@@ -0,0 +1,383 @@
+// Package volatility implements volatility derivatives: variance and
+// volatility swaps, which pay the difference between realized volatility
+// and a strike, and power perpetuals, which track a power of the
+// underlying price (e.g. squeeth, the squared ETH perpetual).
+//
+// These instruments give pure or convex exposure to volatility that the
+// option and linear perpetual implementations cannot express.
+package volatility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// MetadataRealizedVariance is the PriceParams.Metadata key for the
+// annualized realized variance observed since a swap started (decimal or
+// float64). It is zero when absent, as at inception.
+const MetadataRealizedVariance = "realized_variance"
+
+var (
+	// ErrInvalidStrike is returned when a swap's volatility strike is not positive
+	ErrInvalidStrike = errors.New("volatility strike must be positive")
+
+	// ErrInvalidTenor is returned when a swap's tenor is not positive
+	ErrInvalidTenor = errors.New("tenor must be positive")
+
+	// ErrInvalidNotional is returned for a zero notional or position size
+	ErrInvalidNotional = errors.New("notional cannot be zero")
+
+	// ErrInvalidVolatility is returned when volatility is negative
+	ErrInvalidVolatility = errors.New("volatility must be non-negative")
+
+	// ErrInvalidTimeToExpiry is returned when time to expiry is outside
+	// [0, tenor]
+	ErrInvalidTimeToExpiry = errors.New("time to expiry must be within the tenor")
+
+	// ErrInsufficientPrices is returned when fewer than two prices are
+	// given to RealizedVariance
+	ErrInsufficientPrices = errors.New("at least two prices are required")
+)
+
+// RealizedVariance returns the annualized realized variance of prices:
+// the mean squared log return times periodsPerYear. This is the standard
+// variance swap definition, which assumes a zero mean return.
+func RealizedVariance(prices []primitives.Price, periodsPerYear int64) (primitives.Decimal, error) {
+	if len(prices) < 2 {
+		return primitives.Zero(), ErrInsufficientPrices
+	}
+	if periodsPerYear <= 0 {
+		return primitives.Zero(), fmt.Errorf("periods per year must be positive, got %d", periodsPerYear)
+	}
+	sum := 0.0
+	for i := 1; i < len(prices); i++ {
+		prev, curr := prices[i-1].Decimal().Float64(), prices[i].Decimal().Float64()
+		if prev <= 0 || curr <= 0 {
+			return primitives.Zero(), fmt.Errorf("price %d must be positive", i)
+		}
+		r := math.Log(curr / prev)
+		sum += r * r
+	}
+	return primitives.NewDecimalFromFloat(sum / float64(len(prices)-1) * float64(periodsPerYear)), nil
+}
+
+// swapTerms holds what variance and volatility swaps have in common.
+type swapTerms struct {
+	id        string
+	strikeVol primitives.Decimal
+	notional  primitives.Decimal
+	tenor     primitives.Decimal
+}
+
+func newSwapTerms(id string, strikeVol, notional, tenor primitives.Decimal) (swapTerms, error) {
+	switch {
+	case id == "":
+		return swapTerms{}, errors.New("swap ID cannot be empty")
+	case !strikeVol.IsPositive():
+		return swapTerms{}, ErrInvalidStrike
+	case notional.IsZero():
+		return swapTerms{}, ErrInvalidNotional
+	case !tenor.IsPositive():
+		return swapTerms{}, ErrInvalidTenor
+	}
+	return swapTerms{id: id, strikeVol: strikeVol, notional: notional, tenor: tenor}, nil
+}
+
+// expectation is the market state a swap is valued in, in float64 like
+// the Black-Scholes implementation.
+type expectation struct {
+	// variance is the expected annualized variance over the whole tenor
+	variance float64
+
+	// implied and realized are the inputs it blends
+	implied  float64
+	realized float64
+
+	// elapsed and remaining are fractions of the tenor
+	elapsed   float64
+	remaining float64
+
+	// discount is e^(−r·τ) to maturity
+	discount float64
+	tau      float64
+}
+
+// expect blends realized variance over the elapsed part of the tenor with
+// implied variance over the remainder:
+//
+//	E[σ²] = (t/T)·σ²_realized + ((T−t)/T)·σ²_implied
+//
+// Params.Volatility is the implied volatility for the remaining term and
+// TimeToExpiry the remaining term in years; zero TimeToExpiry means the
+// swap has just started.
+func (s swapTerms) expect(params mechanisms.PriceParams) (expectation, error) {
+	if params.Volatility.IsNegative() {
+		return expectation{}, ErrInvalidVolatility
+	}
+	tau := params.TimeToExpiry
+	if tau.IsZero() {
+		tau = s.tenor
+	}
+	if tau.IsNegative() || tau.GreaterThan(s.tenor) {
+		return expectation{}, ErrInvalidTimeToExpiry
+	}
+	realized, err := metadataDecimal(params.Metadata, MetadataRealizedVariance)
+	if err != nil {
+		return expectation{}, err
+	}
+
+	e := expectation{
+		implied:  params.Volatility.Float64(),
+		realized: realized.Float64(),
+		tau:      tau.Float64(),
+	}
+	e.remaining = e.tau / s.tenor.Float64()
+	e.elapsed = 1 - e.remaining
+	e.variance = e.elapsed*e.realized + e.remaining*e.implied*e.implied
+	e.discount = math.Exp(-params.RiskFreeRate.Float64() * e.tau)
+	return e, nil
+}
+
+// metadataDecimal reads an optional decimal or float64 metadata value.
+func metadataDecimal(metadata map[string]interface{}, key string) (primitives.Decimal, error) {
+	switch v := metadata[key].(type) {
+	case nil:
+		return primitives.Zero(), nil
+	case primitives.Decimal:
+		return v, nil
+	case float64:
+		return primitives.NewDecimalFromFloat(v), nil
+	default:
+		return primitives.Zero(), fmt.Errorf("metadata %s has type %T, want decimal or float64", key, v)
+	}
+}
+
+// VarianceSwap pays VarianceNotional × (σ²_realized − K²) at maturity,
+// where K is the volatility strike.
+//
+// Notional is quoted as vega notional, the P&L per volatility point near
+// the strike; the variance notional is vegaNotional / (2K). A negative
+// notional is a short variance position.
+//
+// Thread Safety: VarianceSwap is immutable and safe for concurrent use.
+type VarianceSwap struct {
+	swapTerms
+}
+
+// NewVarianceSwap creates a variance swap.
+//
+// Parameters:
+//   - id: Unique identifier
+//   - strikeVol: Volatility strike K, annualized (e.g., 0.6 for 60 vol)
+//   - vegaNotional: P&L per volatility point (positive long, negative short)
+//   - tenor: Term of the swap in years
+func NewVarianceSwap(id string, strikeVol, vegaNotional, tenor primitives.Decimal) (*VarianceSwap, error) {
+	terms, err := newSwapTerms(id, strikeVol, vegaNotional, tenor)
+	if err != nil {
+		return nil, err
+	}
+	return &VarianceSwap{swapTerms: terms}, nil
+}
+
+// Mechanism returns MechanismTypeDerivative.
+func (v *VarianceSwap) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeDerivative
+}
+
+// Venue returns "variance-swap".
+func (v *VarianceSwap) Venue() string {
+	return "variance-swap"
+}
+
+// SwapID returns the swap identifier.
+func (v *VarianceSwap) SwapID() string {
+	return v.id
+}
+
+// VarianceNotional returns vegaNotional / (2K), the P&L per unit of
+// annualized variance.
+func (v *VarianceSwap) VarianceNotional() primitives.Decimal {
+	return primitives.NewDecimalFromFloat(v.varianceNotional())
+}
+
+func (v *VarianceSwap) varianceNotional() float64 {
+	return v.notional.Float64() / (2 * v.strikeVol.Float64())
+}
+
+// Price returns the fair volatility strike √E[σ²]: the strike at which a
+// new swap on the same terms would be worth zero. Swaps are quoted in
+// volatility points, so this is the swap's market price.
+//
+// Required parameters:
+//   - Volatility: Implied volatility for the remaining term
+//   - TimeToExpiry: Remaining term in years (zero means the full tenor)
+//   - Metadata[MetadataRealizedVariance]: Realized variance so far, if any
+func (v *VarianceSwap) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
+	e, err := v.expect(params)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	return primitives.NewPrice(primitives.NewDecimalFromFloat(math.Sqrt(e.variance)))
+}
+
+// Value returns the discounted mark-to-market of the position,
+// VarianceNotional × (E[σ²] − K²) × e^(−rτ), positive when the holder is
+// in profit.
+func (v *VarianceSwap) Value(params mechanisms.PriceParams) (primitives.Decimal, error) {
+	e, err := v.expect(params)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	k := v.strikeVol.Float64()
+	return primitives.NewDecimalFromFloat(v.varianceNotional() * (e.variance - k*k) * e.discount), nil
+}
+
+// Greeks returns the sensitivities of the position's Value.
+//
+//   - Delta: 0; the swap's payoff does not depend on price direction
+//   - Gamma: Cash gamma of the replicating log contract, 2N/(T·S²), per
+//     unit of variance notional N over the remaining term
+//   - Theta: Change in value per year as elapsed time converts implied
+//     into realized variance, holding both fixed
+//   - Vega: Change in value per 1% change in implied volatility
+//   - Rho: Change in value per 1% change in the risk-free rate
+func (v *VarianceSwap) Greeks(ctx context.Context, params mechanisms.PriceParams) (mechanisms.Greeks, error) {
+	if params.UnderlyingPrice.IsZero() {
+		return mechanisms.Greeks{}, errors.New("underlying price must be positive")
+	}
+	e, err := v.expect(params)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	n := v.varianceNotional()
+	s := params.UnderlyingPrice.Decimal().Float64()
+	tenor := v.tenor.Float64()
+	k := v.strikeVol.Float64()
+
+	return mechanisms.Greeks{
+		Delta: primitives.Zero(),
+		Gamma: primitives.NewDecimalFromFloat(2 * n * e.remaining / (tenor * s * s) * e.discount),
+		Theta: primitives.NewDecimalFromFloat(n * (e.realized - e.implied*e.implied) / tenor * e.discount),
+		Vega:  primitives.NewDecimalFromFloat(n * 2 * e.implied * e.remaining * e.discount * 0.01),
+		Rho:   primitives.NewDecimalFromFloat(-e.tau * n * (e.variance - k*k) * e.discount * 0.01),
+	}, nil
+}
+
+// Settle is not supported without the realized variance; use
+// SettleWithVariance.
+func (v *VarianceSwap) Settle(ctx context.Context) (primitives.Amount, error) {
+	return primitives.ZeroAmount(), errors.New("settle requires the realized variance; use SettleWithVariance")
+}
+
+// SettleWithVariance returns the payoff VarianceNotional × (σ²_realized −
+// K²) for the annualized realized variance over the tenor, positive when
+// the holder receives it.
+func (v *VarianceSwap) SettleWithVariance(realized primitives.Decimal) (primitives.Decimal, error) {
+	if realized.IsNegative() {
+		return primitives.Zero(), fmt.Errorf("%w: realized variance %s", ErrInvalidVolatility, realized)
+	}
+	k := v.strikeVol.Float64()
+	return primitives.NewDecimalFromFloat(v.varianceNotional() * (realized.Float64() - k*k)), nil
+}
+
+// VolatilitySwap pays Notional × (σ_realized − K) at maturity.
+//
+// Its fair strike is E[σ], which by Jensen's inequality is below
+// √E[σ²]. Without a volatility-of-volatility model this implementation
+// uses √E[σ²], so Price is an upper bound on the fair strike and the
+// difference, the convexity adjustment, is left to the caller.
+//
+// Thread Safety: VolatilitySwap is immutable and safe for concurrent use.
+type VolatilitySwap struct {
+	swapTerms
+}
+
+// NewVolatilitySwap creates a volatility swap with the same parameters as
+// NewVarianceSwap; notional is the P&L per unit of volatility.
+func NewVolatilitySwap(id string, strikeVol, notional, tenor primitives.Decimal) (*VolatilitySwap, error) {
+	terms, err := newSwapTerms(id, strikeVol, notional, tenor)
+	if err != nil {
+		return nil, err
+	}
+	return &VolatilitySwap{swapTerms: terms}, nil
+}
+
+// Mechanism returns MechanismTypeDerivative.
+func (v *VolatilitySwap) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeDerivative
+}
+
+// Venue returns "volatility-swap".
+func (v *VolatilitySwap) Venue() string {
+	return "volatility-swap"
+}
+
+// SwapID returns the swap identifier.
+func (v *VolatilitySwap) SwapID() string {
+	return v.id
+}
+
+// Price returns the fair volatility strike √E[σ²] with the same inputs as
+// VarianceSwap.Price.
+func (v *VolatilitySwap) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
+	e, err := v.expect(params)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	return primitives.NewPrice(primitives.NewDecimalFromFloat(math.Sqrt(e.variance)))
+}
+
+// Value returns the discounted mark-to-market of the position,
+// Notional × (√E[σ²] − K) × e^(−rτ).
+func (v *VolatilitySwap) Value(params mechanisms.PriceParams) (primitives.Decimal, error) {
+	e, err := v.expect(params)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return primitives.NewDecimalFromFloat(v.notional.Float64() * (math.Sqrt(e.variance) - v.strikeVol.Float64()) * e.discount), nil
+}
+
+// Greeks returns the sensitivities of the position's Value. Delta and
+// Gamma are zero: unlike variance, volatility cannot be replicated
+// statically with options, so there is no model-free hedge to report.
+// Theta, Vega and Rho follow the VarianceSwap conventions.
+func (v *VolatilitySwap) Greeks(ctx context.Context, params mechanisms.PriceParams) (mechanisms.Greeks, error) {
+	e, err := v.expect(params)
+	if err != nil {
+		return mechanisms.Greeks{}, err
+	}
+	n := v.notional.Float64()
+	vol := math.Sqrt(e.variance)
+	if vol == 0 {
+		return mechanisms.Greeks{}, nil
+	}
+
+	// d√E = dE / (2√E)
+	return mechanisms.Greeks{
+		Delta: primitives.Zero(),
+		Gamma: primitives.Zero(),
+		Theta: primitives.NewDecimalFromFloat(n * (e.realized - e.implied*e.implied) / v.tenor.Float64() / (2 * vol) * e.discount),
+		Vega:  primitives.NewDecimalFromFloat(n * e.implied * e.remaining / vol * e.discount * 0.01),
+		Rho:   primitives.NewDecimalFromFloat(-e.tau * n * (vol - v.strikeVol.Float64()) * e.discount * 0.01),
+	}, nil
+}
+
+// Settle is not supported without the realized volatility; use
+// SettleWithVolatility.
+func (v *VolatilitySwap) Settle(ctx context.Context) (primitives.Amount, error) {
+	return primitives.ZeroAmount(), errors.New("settle requires the realized volatility; use SettleWithVolatility")
+}
+
+// SettleWithVolatility returns the payoff Notional × (σ_realized − K),
+// positive when the holder receives it.
+func (v *VolatilitySwap) SettleWithVolatility(realized primitives.Decimal) (primitives.Decimal, error) {
+	if realized.IsNegative() {
+		return primitives.Zero(), fmt.Errorf("%w: realized volatility %s", ErrInvalidVolatility, realized)
+	}
+	return v.notional.Mul(realized.Sub(v.strikeVol)), nil
+}
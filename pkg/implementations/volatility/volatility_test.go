@@ -0,0 +1,264 @@
+package volatility_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/volatility"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms/mechanismtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+const tolerance = 1e-6
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func price(s string) primitives.Price {
+	return primitives.MustPrice(dec(s))
+}
+
+func near(got primitives.Decimal, want, tol float64) bool {
+	return math.Abs(got.Float64()-want) <= tol*math.Max(1, math.Abs(want))
+}
+
+func TestRealizedVariance(t *testing.T) {
+	// Two ±9.53% log returns, annualized daily
+	variance, err := volatility.RealizedVariance([]primitives.Price{price("100"), price("110"), price("100")}, 365)
+	if err != nil {
+		t.Fatalf("RealizedVariance: %v", err)
+	}
+	want := math.Pow(math.Log(1.1), 2) * 365
+	if !near(variance, want, tolerance) {
+		t.Errorf("variance = %s, want %f", variance, want)
+	}
+	if _, err := volatility.RealizedVariance([]primitives.Price{price("100")}, 365); !errors.Is(err, volatility.ErrInsufficientPrices) {
+		t.Errorf("expected %v, got %v", volatility.ErrInsufficientPrices, err)
+	}
+}
+
+func TestVarianceSwap(t *testing.T) {
+	// Long 100,000 vega notional at 60 vol for a year: 83,333 per unit variance
+	swap, err := volatility.NewVarianceSwap("var-eth", dec("0.6"), dec("100000"), primitives.One())
+	if err != nil {
+		t.Fatalf("NewVarianceSwap: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		params    mechanisms.PriceParams
+		fairVol   float64
+		wantValue float64
+	}{
+		{
+			name:      "inception at 70 implied",
+			params:    mechanisms.PriceParams{Volatility: dec("0.7")},
+			fairVol:   0.7,
+			wantValue: 100000.0 / 1.2 * (0.49 - 0.36),
+		},
+		{
+			name: "halfway with 50 realized",
+			params: mechanisms.PriceParams{
+				Volatility:   dec("0.6"),
+				TimeToExpiry: dec("0.5"),
+				Metadata:     map[string]interface{}{volatility.MetadataRealizedVariance: dec("0.25")},
+			},
+			fairVol:   math.Sqrt(0.305),
+			wantValue: 100000.0 / 1.2 * (0.305 - 0.36),
+		},
+		{
+			name: "discounted",
+			params: mechanisms.PriceParams{
+				Volatility:   dec("0.7"),
+				RiskFreeRate: dec("0.05"),
+			},
+			fairVol:   0.7,
+			wantValue: 100000.0 / 1.2 * (0.49 - 0.36) * math.Exp(-0.05),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fair, err := swap.Price(ctx, tt.params)
+			if err != nil || !near(fair.Decimal(), tt.fairVol, tolerance) {
+				t.Errorf("fair vol = %s (%v), want %f", fair, err, tt.fairVol)
+			}
+			value, err := swap.Value(tt.params)
+			if err != nil || !near(value, tt.wantValue, tolerance) {
+				t.Errorf("value = %s (%v), want %f", value, err, tt.wantValue)
+			}
+		})
+	}
+
+	t.Run("vega matches finite difference", func(t *testing.T) {
+		params := mechanisms.PriceParams{UnderlyingPrice: price("2000"), Volatility: dec("0.7"), TimeToExpiry: dec("0.5")}
+		greeks, err := swap.Greeks(ctx, params)
+		if err != nil {
+			t.Fatalf("Greeks: %v", err)
+		}
+		up, down := params, params
+		up.Volatility, down.Volatility = dec("0.705"), dec("0.695")
+		vu, _ := swap.Value(up)
+		vd, _ := swap.Value(down)
+		// Per 1% change in vol
+		want := vu.Sub(vd).Float64()
+		if !near(greeks.Vega, want, 1e-3) || !greeks.Delta.IsZero() || !greeks.Gamma.IsPositive() {
+			t.Errorf("greeks = %+v, want vega %f, zero delta and positive gamma", greeks, want)
+		}
+	})
+
+	t.Run("settle", func(t *testing.T) {
+		payoff, err := swap.SettleWithVariance(dec("0.25"))
+		if err != nil || !near(payoff, 100000.0/1.2*(0.25-0.36), tolerance) {
+			t.Errorf("payoff = %s (%v)", payoff, err)
+		}
+		if _, err := swap.Settle(ctx); err == nil {
+			t.Error("expected Settle without realized variance to fail")
+		}
+	})
+
+	mechanismtest.VerifyDerivative(t, swap, mechanismtest.DerivativeConfig{
+		Params: func(r *rand.Rand) mechanisms.PriceParams {
+			return mechanisms.PriceParams{
+				UnderlyingPrice: primitives.MustPrice(primitives.NewDecimalFromFloat(1000 + r.Float64()*3000)),
+				Volatility:      primitives.NewDecimalFromFloat(0.2 + r.Float64()),
+				TimeToExpiry:    primitives.NewDecimalFromFloat(0.01 + r.Float64()*0.99),
+				Metadata:        map[string]interface{}{volatility.MetadataRealizedVariance: r.Float64()},
+			}
+		},
+		NonNegativeGamma: true,
+	})
+}
+
+func TestVolatilitySwap(t *testing.T) {
+	swap, err := volatility.NewVolatilitySwap("vol-eth", dec("0.6"), dec("1000"), primitives.One())
+	if err != nil {
+		t.Fatalf("NewVolatilitySwap: %v", err)
+	}
+	value, err := swap.Value(mechanisms.PriceParams{Volatility: dec("0.7")})
+	if err != nil || !near(value, 100, tolerance) {
+		t.Errorf("value = %s (%v), want 100", value, err)
+	}
+	payoff, err := swap.SettleWithVolatility(dec("0.55"))
+	if err != nil || !payoff.Equal(dec("-50")) {
+		t.Errorf("payoff = %s (%v), want -50", payoff, err)
+	}
+	greeks, err := swap.Greeks(context.Background(), mechanisms.PriceParams{Volatility: dec("0.7")})
+	if err != nil || !near(greeks.Vega, 10, tolerance) {
+		t.Errorf("vega = %s (%v), want 10 per vol point", greeks.Vega, err)
+	}
+}
+
+func TestSwapValidation(t *testing.T) {
+	tests := []struct {
+		name                    string
+		strike, notional, tenor string
+		want                    error
+	}{
+		{"zero strike", "0", "1", "1", volatility.ErrInvalidStrike},
+		{"zero notional", "0.5", "0", "1", volatility.ErrInvalidNotional},
+		{"zero tenor", "0.5", "1", "0", volatility.ErrInvalidTenor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := volatility.NewVarianceSwap("s", dec(tt.strike), dec(tt.notional), dec(tt.tenor)); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	swap, _ := volatility.NewVarianceSwap("s", dec("0.5"), dec("1"), dec("0.5"))
+	if _, err := swap.Value(mechanisms.PriceParams{Volatility: dec("0.5"), TimeToExpiry: dec("0.6")}); !errors.Is(err, volatility.ErrInvalidTimeToExpiry) {
+		t.Errorf("expected %v, got %v", volatility.ErrInvalidTimeToExpiry, err)
+	}
+	bad := mechanisms.PriceParams{Volatility: dec("0.5"), Metadata: map[string]interface{}{volatility.MetadataRealizedVariance: "high"}}
+	if _, err := swap.Value(bad); err == nil {
+		t.Error("expected an error for non-numeric realized variance")
+	}
+}
+
+// squeeth returns a long squared-ETH perpetual with 17.5-day funding.
+func squeeth(t *testing.T) *volatility.PowerPerp {
+	t.Helper()
+	perp, err := volatility.NewPowerPerp("osqth", "ETH", dec("2"), dec("0.0001"),
+		primitives.NewDecimalFromFloat(17.5/365), price("400"), primitives.One())
+	if err != nil {
+		t.Fatalf("NewPowerPerp: %v", err)
+	}
+	return perp
+}
+
+func TestPowerPerp(t *testing.T) {
+	perp := squeeth(t)
+	ctx := context.Background()
+	params := mechanisms.PriceParams{UnderlyingPrice: price("2000"), Volatility: dec("0.8")}
+
+	// k = σ² = 0.64, so the mark is 400 / (1 − 0.64·17.5/365)
+	multiplier := 1 / (1 - 0.64*17.5/365)
+	mark, err := perp.Price(ctx, params)
+	if err != nil || !near(mark.Decimal(), 400*multiplier, tolerance) {
+		t.Fatalf("mark = %s (%v), want %f", mark, err, 400*multiplier)
+	}
+
+	greeks, err := perp.Greeks(ctx, params)
+	if err != nil {
+		t.Fatalf("Greeks: %v", err)
+	}
+	if !near(greeks.Delta, 2*400*multiplier/2000, tolerance) || !near(greeks.Gamma, 2*400*multiplier/(2000*2000), tolerance) {
+		t.Errorf("greeks = %+v", greeks)
+	}
+
+	// Vega against a finite difference in volatility
+	up, down := params, params
+	up.Volatility, down.Volatility = dec("0.805"), dec("0.795")
+	mu, _ := perp.Price(ctx, up)
+	md, _ := perp.Price(ctx, down)
+	if want := mu.Decimal().Sub(md.Decimal()).Float64(); !near(greeks.Vega, want, 1e-3) {
+		t.Errorf("vega = %s, want %f", greeks.Vega, want)
+	}
+
+	t.Run("funding and pnl", func(t *testing.T) {
+		// Mark 420 over index 400 for one full funding period
+		funding, err := perp.Funding(price("420"), price("2000"), primitives.NewDecimalFromFloat(17.5/365))
+		if err != nil || !near(funding, 20, tolerance) {
+			t.Errorf("funding = %s (%v), want 20", funding, err)
+		}
+		pnl, err := perp.UnrealizedPnL(price("450"))
+		if err != nil || !pnl.Equal(dec("50")) {
+			t.Errorf("pnl = %s (%v), want 50", pnl, err)
+		}
+	})
+
+	t.Run("no fair price", func(t *testing.T) {
+		extreme := mechanisms.PriceParams{UnderlyingPrice: price("2000"), Volatility: dec("5")}
+		if _, err := perp.Price(ctx, extreme); !errors.Is(err, volatility.ErrNoFairPrice) {
+			t.Errorf("expected %v, got %v", volatility.ErrNoFairPrice, err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		if _, err := volatility.NewPowerPerp("p", "ETH", primitives.One(), dec("1"), dec("0.1"), price("1"), primitives.One()); !errors.Is(err, volatility.ErrInvalidPower) {
+			t.Errorf("expected %v, got %v", volatility.ErrInvalidPower, err)
+		}
+		if _, err := volatility.NewPowerPerp("p", "ETH", dec("2"), dec("1"), primitives.Zero(), price("1"), primitives.One()); !errors.Is(err, volatility.ErrInvalidFundingPeriod) {
+			t.Errorf("expected %v, got %v", volatility.ErrInvalidFundingPeriod, err)
+		}
+	})
+
+	mechanismtest.VerifyDerivative(t, perp, mechanismtest.DerivativeConfig{
+		Params: func(r *rand.Rand) mechanisms.PriceParams {
+			return mechanisms.PriceParams{
+				UnderlyingPrice: primitives.MustPrice(primitives.NewDecimalFromFloat(1000 + r.Float64()*3000)),
+				Volatility:      primitives.NewDecimalFromFloat(0.2 + r.Float64()),
+			}
+		},
+		MinDelta:         primitives.Zero(),
+		MaxDelta:         primitives.NewDecimal(10),
+		NonNegativeGamma: true,
+	})
+}
@@ -0,0 +1,91 @@
+package indicators
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Bar is a single high/low/close observation used by range-based indicators.
+type Bar struct {
+	High  primitives.Decimal
+	Low   primitives.Decimal
+	Close primitives.Decimal
+}
+
+// ATR is the Average True Range using Wilder's smoothing.
+//
+// TrueRange = max(High - Low, |High - PrevClose|, |Low - PrevClose|)
+//
+// When only close prices are available, Update treats each close as a bar
+// with High = Low = Close, so the true range reduces to |Close - PrevClose|.
+type ATR struct {
+	period    int
+	periodDec primitives.Decimal
+	prevClose primitives.Decimal
+	hasPrev   bool
+	count     int
+	value     primitives.Decimal
+	ready     bool
+}
+
+// NewATR creates an Average True Range with the given period (commonly 14).
+// Returns ErrInvalidPeriod if period is not positive.
+func NewATR(period int) (*ATR, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &ATR{
+		period:    period,
+		periodDec: primitives.NewDecimal(int64(period)),
+		value:     primitives.Zero(),
+	}, nil
+}
+
+// Update adds a close-only observation.
+func (a *ATR) Update(value primitives.Decimal) {
+	a.UpdateBar(Bar{High: value, Low: value, Close: value})
+}
+
+// UpdateBar adds the next high/low/close bar.
+func (a *ATR) UpdateBar(bar Bar) {
+	if !a.hasPrev {
+		// The first bar has no previous close; it only seeds the series
+		a.prevClose = bar.Close
+		a.hasPrev = true
+		return
+	}
+
+	trueRange := bar.High.Sub(bar.Low)
+	if hc := bar.High.Sub(a.prevClose).Abs(); hc.GreaterThan(trueRange) {
+		trueRange = hc
+	}
+	if lc := bar.Low.Sub(a.prevClose).Abs(); lc.GreaterThan(trueRange) {
+		trueRange = lc
+	}
+	a.prevClose = bar.Close
+
+	a.count++
+	if a.count <= a.period {
+		a.value = a.value.Add(trueRange)
+		if a.count == a.period {
+			// Safe to ignore error: period is positive
+			a.value, _ = a.value.Div(a.periodDec)
+			a.ready = true
+		}
+		return
+	}
+
+	a.value, _ = a.value.Mul(a.periodDec.Sub(primitives.One())).Add(trueRange).Div(a.periodDec)
+}
+
+// Value returns the current average true range.
+func (a *ATR) Value() (primitives.Decimal, error) {
+	if !a.ready {
+		return primitives.Zero(), ErrNotReady
+	}
+	return a.value, nil
+}
+
+// Ready reports whether period true ranges have been observed.
+func (a *ATR) Ready() bool {
+	return a.ready
+}
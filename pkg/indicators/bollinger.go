@@ -0,0 +1,73 @@
+package indicators
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// BollingerBands tracks a moving average with upper and lower bands placed
+// a number of standard deviations away.
+//
+// Middle = SMA(period)
+// Upper  = Middle + k * StdDev(period)
+// Lower  = Middle - k * StdDev(period)
+//
+// Value returns the middle band; use Bands for all three.
+type BollingerBands struct {
+	window *window
+	k      primitives.Decimal
+}
+
+// NewBollingerBands creates Bollinger Bands with the given period and band width
+// in standard deviations (commonly 20 and 2).
+// Returns ErrInvalidPeriod if period is not positive.
+func NewBollingerBands(period int, k primitives.Decimal) (*BollingerBands, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &BollingerBands{
+		window: newWindow(period),
+		k:      k,
+	}, nil
+}
+
+// Update adds the next observation.
+func (b *BollingerBands) Update(value primitives.Decimal) {
+	b.window.push(value)
+}
+
+// Value returns the middle band.
+func (b *BollingerBands) Value() (primitives.Decimal, error) {
+	_, middle, _, err := b.Bands()
+	return middle, err
+}
+
+// Bands returns the lower, middle and upper bands.
+func (b *BollingerBands) Bands() (lower, middle, upper primitives.Decimal, err error) {
+	if !b.Ready() {
+		return primitives.Zero(), primitives.Zero(), primitives.Zero(), ErrNotReady
+	}
+	mean, stdDev, err := meanStdDev(b.window.slice())
+	if err != nil {
+		return primitives.Zero(), primitives.Zero(), primitives.Zero(), err
+	}
+	width := stdDev.Mul(b.k)
+	return mean.Sub(width), mean, mean.Add(width), nil
+}
+
+// PercentB returns where the latest observation sits within the bands:
+// 0 at the lower band, 1 at the upper band.
+// Returns ErrDivisionByZero when the bands have zero width.
+func (b *BollingerBands) PercentB() (primitives.Decimal, error) {
+	lower, _, upper, err := b.Bands()
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	values := b.window.slice()
+	last := values[len(values)-1]
+	return last.Sub(lower).Div(upper.Sub(lower))
+}
+
+// Ready reports whether period observations have been seen.
+func (b *BollingerBands) Ready() bool {
+	return b.window.full()
+}
@@ -0,0 +1,99 @@
+// Package indicators provides streaming technical indicators that are updated
+// one observation at a time as market snapshots arrive. All indicators use
+// decimal arithmetic so that signal values can be compared and combined with
+// prices and amounts without floating-point drift.
+//
+// Indicators are stateful and designed to be owned by a single strategy.
+// Each call to Update appends one observation; Value reports the latest
+// indicator reading once enough history has been observed (see Ready).
+package indicators
+
+import (
+	"errors"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInvalidPeriod indicates an indicator was configured with a non-positive window
+	ErrInvalidPeriod = errors.New("indicator period must be positive")
+
+	// ErrNotReady indicates the indicator has not observed enough data to produce a value
+	ErrNotReady = errors.New("indicator not ready")
+
+	// ErrUnknownSignal indicates a signal name is not registered with a provider
+	ErrUnknownSignal = errors.New("unknown signal")
+)
+
+// Indicator is a streaming technical indicator fed one value per snapshot.
+//
+// Implementations must be deterministic: the same sequence of Update calls
+// always produces the same Value.
+//
+// Thread Safety: Implementations are not required to be thread-safe.
+type Indicator interface {
+	// Update feeds the next observation (typically a close price) into the indicator.
+	Update(value primitives.Decimal)
+
+	// Value returns the current indicator reading.
+	// Returns ErrNotReady until the indicator has seen enough observations.
+	Value() (primitives.Decimal, error)
+
+	// Ready reports whether Value will return a valid reading.
+	Ready() bool
+}
+
+// window is a fixed-capacity FIFO of decimals used by rolling indicators.
+type window struct {
+	values []primitives.Decimal
+	size   int
+	next   int
+	count  int
+}
+
+// newWindow creates a rolling window holding the last size values.
+func newWindow(size int) *window {
+	return &window{
+		values: make([]primitives.Decimal, size),
+		size:   size,
+	}
+}
+
+// push appends a value, returning the evicted value and true if the window was full.
+func (w *window) push(v primitives.Decimal) (primitives.Decimal, bool) {
+	evicted := w.values[w.next]
+	full := w.count == w.size
+	w.values[w.next] = v
+	w.next = (w.next + 1) % w.size
+	if !full {
+		w.count++
+	}
+	return evicted, full
+}
+
+// full reports whether the window holds size values.
+func (w *window) full() bool {
+	return w.count == w.size
+}
+
+// slice returns the window contents ordered from oldest to newest.
+func (w *window) slice() []primitives.Decimal {
+	out := make([]primitives.Decimal, 0, w.count)
+	start := 0
+	if w.full() {
+		start = w.next
+	}
+	for i := 0; i < w.count; i++ {
+		out = append(out, w.values[(start+i)%w.size])
+	}
+	return out
+}
+
+// meanStdDev returns the mean and population standard deviation of values.
+func meanStdDev(values []primitives.Decimal) (primitives.Decimal, primitives.Decimal, error) {
+	mean, variance, err := primitives.MeanVariance(values)
+	if err != nil {
+		return primitives.Zero(), primitives.Zero(), err
+	}
+	return mean, variance.Sqrt(), nil
+}
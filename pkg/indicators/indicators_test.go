@@ -0,0 +1,231 @@
+package indicators_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/indicators"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+const tolerance = 1e-6
+
+func dec(f float64) primitives.Decimal {
+	return primitives.NewDecimalFromFloat(f)
+}
+
+func feed(ind indicators.Indicator, values ...float64) {
+	for _, v := range values {
+		ind.Update(dec(v))
+	}
+}
+
+func assertClose(t *testing.T, got primitives.Decimal, want float64) {
+	t.Helper()
+	if math.Abs(got.Float64()-want) > tolerance {
+		t.Errorf("expected %f, got %s", want, got.String())
+	}
+}
+
+func TestInvalidPeriod(t *testing.T) {
+	constructors := map[string]func() error{
+		"SMA": func() error { _, err := indicators.NewSMA(0); return err },
+		"EMA": func() error { _, err := indicators.NewEMA(-1); return err },
+		"RSI": func() error { _, err := indicators.NewRSI(0); return err },
+		"ATR": func() error { _, err := indicators.NewATR(0); return err },
+		"Bollinger": func() error {
+			_, err := indicators.NewBollingerBands(0, primitives.NewDecimal(2))
+			return err
+		},
+		"RealizedVol": func() error { _, err := indicators.NewRealizedVolatility(5, 0); return err },
+		"ZScore":      func() error { _, err := indicators.NewZScore(0); return err },
+	}
+	for name, construct := range constructors {
+		t.Run(name, func(t *testing.T) {
+			if err := construct(); !errors.Is(err, indicators.ErrInvalidPeriod) {
+				t.Errorf("expected ErrInvalidPeriod, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSMA(t *testing.T) {
+	sma, _ := indicators.NewSMA(3)
+
+	feed(sma, 1, 2)
+	if sma.Ready() {
+		t.Fatal("SMA should not be ready after 2 of 3 values")
+	}
+	if _, err := sma.Value(); !errors.Is(err, indicators.ErrNotReady) {
+		t.Errorf("expected ErrNotReady, got %v", err)
+	}
+
+	feed(sma, 3)
+	v, err := sma.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, v, 2)
+
+	// Window rolls: (2 + 3 + 10) / 3
+	feed(sma, 10)
+	v, _ = sma.Value()
+	assertClose(t, v, 5)
+}
+
+func TestEMA(t *testing.T) {
+	ema, _ := indicators.NewEMA(3)
+
+	feed(ema, 1, 2, 3)
+	v, err := ema.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Seeded with SMA of first 3 values
+	assertClose(t, v, 2)
+
+	// alpha = 0.5: 2 + 0.5 * (4 - 2) = 3
+	feed(ema, 4)
+	v, _ = ema.Value()
+	assertClose(t, v, 3)
+}
+
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"all gains", []float64{1, 2, 3, 4}, 100},
+		{"all losses", []float64{4, 3, 2, 1}, 0},
+		{"flat", []float64{5, 5, 5, 5}, 50},
+		// Gains 2+1=3, losses 1 → avgGain 1, avgLoss 1/3 → RS 3 → RSI 75
+		{"mixed", []float64{10, 12, 11, 12}, 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsi, _ := indicators.NewRSI(3)
+			feed(rsi, tt.values...)
+			v, err := rsi.Value()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertClose(t, v, tt.want)
+		})
+	}
+}
+
+func TestBollingerBands(t *testing.T) {
+	bb, _ := indicators.NewBollingerBands(4, primitives.NewDecimal(2))
+	feed(bb, 2, 4, 4, 6)
+
+	lower, middle, upper, err := bb.Bands()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// mean 4, population stddev sqrt(2)
+	std := math.Sqrt(2)
+	assertClose(t, middle, 4)
+	assertClose(t, lower, 4-2*std)
+	assertClose(t, upper, 4+2*std)
+
+	pctB, err := bb.PercentB()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, pctB, (6-(4-2*std))/(4*std))
+}
+
+func TestATR(t *testing.T) {
+	atr, _ := indicators.NewATR(2)
+	atr.UpdateBar(indicators.Bar{High: dec(10), Low: dec(8), Close: dec(9)})
+	atr.UpdateBar(indicators.Bar{High: dec(11), Low: dec(9), Close: dec(10)}) // TR = 2
+	if atr.Ready() {
+		t.Fatal("ATR should not be ready after one true range")
+	}
+	atr.UpdateBar(indicators.Bar{High: dec(14), Low: dec(12), Close: dec(13)}) // TR = 4 (gap)
+
+	v, err := atr.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, v, 3)
+
+	// Wilder smoothing: (3 * 1 + 1) / 2 = 2, close-only update TR = |12 - 13|
+	atr.Update(dec(12))
+	v, _ = atr.Value()
+	assertClose(t, v, 2)
+}
+
+func TestRealizedVolatility(t *testing.T) {
+	vol, _ := indicators.NewRealizedVolatility(2, 1)
+	feed(vol, 100, 110, 99) // returns +10%, -10%
+
+	v, err := vol.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, v, 0.1)
+
+	annual, _ := indicators.NewRealizedVolatility(2, 365)
+	feed(annual, 100, 110, 99)
+	v, _ = annual.Value()
+	assertClose(t, v, 0.1*math.Sqrt(365))
+}
+
+func TestZScore(t *testing.T) {
+	z, _ := indicators.NewZScore(4)
+	feed(z, 2, 4, 4, 6)
+
+	v, err := z.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, v, 2/math.Sqrt(2))
+
+	flat, _ := indicators.NewZScore(2)
+	feed(flat, 5, 5)
+	v, _ = flat.Value()
+	assertClose(t, v, 0)
+}
+
+func TestSet(t *testing.T) {
+	set := indicators.NewSet()
+	sma, _ := indicators.NewSMA(2)
+	if err := set.Register("eth_sma", "ETH/USD", sma); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := set.Register("eth_sma", "ETH/USD", sma); err == nil {
+		t.Error("expected error registering duplicate name")
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, price := range []int64{100, 200} {
+		snap := strategy.NewSimpleSnapshot(
+			primitives.NewTime(base.Add(time.Duration(i)*time.Hour)),
+			map[string]primitives.Price{"ETH/USD": primitives.MustPrice(primitives.NewDecimal(price))},
+		)
+		if err := set.Update(snap); err != nil {
+			t.Fatalf("unexpected update error: %v", err)
+		}
+	}
+
+	var provider indicators.SignalProvider = set
+	v, err := provider.Signal("eth_sma")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertClose(t, v, 150)
+
+	if _, err := provider.Signal("missing"); !errors.Is(err, indicators.ErrUnknownSignal) {
+		t.Errorf("expected ErrUnknownSignal, got %v", err)
+	}
+
+	missing := strategy.NewSimpleSnapshot(primitives.NewTime(base), map[string]primitives.Price{})
+	if err := set.Update(missing); err == nil {
+		t.Error("expected error for missing price")
+	}
+}
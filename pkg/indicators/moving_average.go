@@ -0,0 +1,106 @@
+package indicators
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// SMA is a simple moving average over a fixed number of observations.
+type SMA struct {
+	period int
+	window *window
+	sum    primitives.Decimal
+}
+
+// NewSMA creates a simple moving average with the given period.
+// Returns ErrInvalidPeriod if period is not positive.
+func NewSMA(period int) (*SMA, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &SMA{
+		period: period,
+		window: newWindow(period),
+		sum:    primitives.Zero(),
+	}, nil
+}
+
+// Update adds the next observation to the average.
+func (s *SMA) Update(value primitives.Decimal) {
+	evicted, full := s.window.push(value)
+	if full {
+		s.sum = s.sum.Sub(evicted)
+	}
+	s.sum = s.sum.Add(value)
+}
+
+// Value returns the average of the last period observations.
+func (s *SMA) Value() (primitives.Decimal, error) {
+	if !s.Ready() {
+		return primitives.Zero(), ErrNotReady
+	}
+	return s.sum.Div(primitives.NewDecimal(int64(s.period)))
+}
+
+// Ready reports whether period observations have been seen.
+func (s *SMA) Ready() bool {
+	return s.window.full()
+}
+
+// EMA is an exponential moving average seeded with the simple average of
+// the first period observations.
+//
+// Smoothing factor: alpha = 2 / (period + 1)
+type EMA struct {
+	period int
+	alpha  primitives.Decimal
+	seed   *SMA
+	value  primitives.Decimal
+	ready  bool
+}
+
+// NewEMA creates an exponential moving average with the given period.
+// Returns ErrInvalidPeriod if period is not positive.
+func NewEMA(period int) (*EMA, error) {
+	seed, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+	alpha, err := primitives.NewDecimal(2).Div(primitives.NewDecimal(int64(period + 1)))
+	if err != nil {
+		return nil, err
+	}
+	return &EMA{
+		period: period,
+		alpha:  alpha,
+		seed:   seed,
+		value:  primitives.Zero(),
+	}, nil
+}
+
+// Update adds the next observation to the average.
+func (e *EMA) Update(value primitives.Decimal) {
+	if !e.ready {
+		e.seed.Update(value)
+		if e.seed.Ready() {
+			// Safe to ignore error: seed is ready
+			e.value, _ = e.seed.Value()
+			e.ready = true
+		}
+		return
+	}
+	// EMA = alpha * value + (1 - alpha) * previous
+	e.value = e.value.Add(value.Sub(e.value).Mul(e.alpha))
+}
+
+// Value returns the current exponential average.
+func (e *EMA) Value() (primitives.Decimal, error) {
+	if !e.ready {
+		return primitives.Zero(), ErrNotReady
+	}
+	return e.value, nil
+}
+
+// Ready reports whether the average has been seeded.
+func (e *EMA) Ready() bool {
+	return e.ready
+}
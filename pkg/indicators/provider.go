@@ -0,0 +1,103 @@
+package indicators
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// SignalProvider exposes named indicator readings to strategies.
+// Strategies depend on this interface rather than on concrete indicators,
+// so signal wiring can be configured outside the strategy.
+type SignalProvider interface {
+	// Signal returns the current value of the named signal.
+	// Returns ErrUnknownSignal if the name is not registered and
+	// ErrNotReady if the underlying indicator lacks history.
+	Signal(name string) (primitives.Decimal, error)
+}
+
+// binding associates an indicator with the price pair that feeds it.
+type binding struct {
+	pair      string
+	indicator Indicator
+}
+
+// Set is a collection of named indicators fed from market snapshots.
+// Call Update once per snapshot (typically at the start of Rebalance);
+// Set implements SignalProvider for querying the results.
+//
+// Thread Safety: Set is not thread-safe.
+type Set struct {
+	bindings map[string]binding
+}
+
+// NewSet creates an empty indicator set.
+func NewSet() *Set {
+	return &Set{bindings: make(map[string]binding)}
+}
+
+// Register adds an indicator under name, fed by the price of pair.
+// Returns error if the name is already registered or the indicator is nil.
+func (s *Set) Register(name, pair string, indicator Indicator) error {
+	if indicator == nil {
+		return fmt.Errorf("indicator %s cannot be nil", name)
+	}
+	if _, exists := s.bindings[name]; exists {
+		return fmt.Errorf("indicator %s already registered", name)
+	}
+	s.bindings[name] = binding{pair: pair, indicator: indicator}
+	return nil
+}
+
+// Update feeds the snapshot price for each registered pair into its indicators.
+// Indicators are updated in name order so results are deterministic.
+// Returns error if any required price is missing from the snapshot.
+func (s *Set) Update(snapshot strategy.MarketSnapshot) error {
+	for _, name := range s.Names() {
+		b := s.bindings[name]
+		price, err := snapshot.Price(b.pair)
+		if err != nil {
+			return fmt.Errorf("indicator %s: failed to get price for %s: %w", name, b.pair, err)
+		}
+		b.indicator.Update(price.Decimal())
+	}
+	return nil
+}
+
+// Signal returns the current value of the named indicator.
+func (s *Set) Signal(name string) (primitives.Decimal, error) {
+	b, ok := s.bindings[name]
+	if !ok {
+		return primitives.Zero(), fmt.Errorf("%w: %s", ErrUnknownSignal, name)
+	}
+	return b.indicator.Value()
+}
+
+// Indicator returns the named indicator for access to indicator-specific
+// methods (e.g., BollingerBands.Bands).
+func (s *Set) Indicator(name string) (Indicator, bool) {
+	b, ok := s.bindings[name]
+	return b.indicator, ok
+}
+
+// Ready reports whether every registered indicator is ready.
+func (s *Set) Ready() bool {
+	for _, b := range s.bindings {
+		if !b.indicator.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns the registered signal names in sorted order.
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.bindings))
+	for name := range s.bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
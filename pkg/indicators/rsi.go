@@ -0,0 +1,105 @@
+package indicators
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// RSI is the Relative Strength Index using Wilder's smoothing.
+//
+// RSI = 100 - 100 / (1 + AvgGain / AvgLoss)
+//
+// The first average gain/loss is the simple mean of the first period changes;
+// subsequent values use Wilder's recursive smoothing:
+// Avg = (PrevAvg * (period - 1) + Current) / period
+type RSI struct {
+	period    int
+	prev      primitives.Decimal
+	hasPrev   bool
+	changes   int
+	avgGain   primitives.Decimal
+	avgLoss   primitives.Decimal
+	ready     bool
+	periodDec primitives.Decimal
+}
+
+// NewRSI creates a Relative Strength Index with the given period (commonly 14).
+// Returns ErrInvalidPeriod if period is not positive.
+func NewRSI(period int) (*RSI, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &RSI{
+		period:    period,
+		avgGain:   primitives.Zero(),
+		avgLoss:   primitives.Zero(),
+		periodDec: primitives.NewDecimal(int64(period)),
+	}, nil
+}
+
+// Update adds the next observation.
+func (r *RSI) Update(value primitives.Decimal) {
+	if !r.hasPrev {
+		r.prev = value
+		r.hasPrev = true
+		return
+	}
+
+	change := value.Sub(r.prev)
+	r.prev = value
+
+	gain, loss := primitives.Zero(), primitives.Zero()
+	if change.IsPositive() {
+		gain = change
+	} else {
+		loss = change.Neg()
+	}
+
+	r.changes++
+	if r.changes <= r.period {
+		// Accumulate sums for the initial simple average
+		r.avgGain = r.avgGain.Add(gain)
+		r.avgLoss = r.avgLoss.Add(loss)
+		if r.changes == r.period {
+			// Safe to ignore errors: period is positive
+			r.avgGain, _ = r.avgGain.Div(r.periodDec)
+			r.avgLoss, _ = r.avgLoss.Div(r.periodDec)
+			r.ready = true
+		}
+		return
+	}
+
+	periodMinusOne := r.periodDec.Sub(primitives.One())
+	r.avgGain, _ = r.avgGain.Mul(periodMinusOne).Add(gain).Div(r.periodDec)
+	r.avgLoss, _ = r.avgLoss.Mul(periodMinusOne).Add(loss).Div(r.periodDec)
+}
+
+// Value returns the RSI in the range [0, 100].
+func (r *RSI) Value() (primitives.Decimal, error) {
+	if !r.ready {
+		return primitives.Zero(), ErrNotReady
+	}
+
+	hundred := primitives.NewDecimal(100)
+	if r.avgLoss.IsZero() {
+		if r.avgGain.IsZero() {
+			// No movement at all: neutral reading
+			return primitives.NewDecimal(50), nil
+		}
+		return hundred, nil
+	}
+
+	rs, err := r.avgGain.Div(r.avgLoss)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	ratio, err := hundred.Div(primitives.One().Add(rs))
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return hundred.Sub(ratio), nil
+}
+
+// Ready reports whether period price changes have been observed.
+func (r *RSI) Ready() bool {
+	return r.ready
+}
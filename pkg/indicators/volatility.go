@@ -0,0 +1,109 @@
+package indicators
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// RealizedVolatility is the rolling standard deviation of simple returns,
+// optionally annualized.
+//
+// Volatility = StdDev(returns over period) * sqrt(periodsPerYear)
+//
+// Pass periodsPerYear = 1 for the raw per-period volatility.
+type RealizedVolatility struct {
+	returns       *window
+	prev          primitives.Decimal
+	hasPrev       bool
+	annualization primitives.Decimal
+}
+
+// NewRealizedVolatility creates a realized volatility estimator over period returns.
+// periodsPerYear scales the result (e.g., 365 for daily snapshots, 8760 for hourly).
+// Returns ErrInvalidPeriod if period or periodsPerYear is not positive.
+func NewRealizedVolatility(period int, periodsPerYear int64) (*RealizedVolatility, error) {
+	if period <= 0 || periodsPerYear <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &RealizedVolatility{
+		returns:       newWindow(period),
+		annualization: primitives.NewDecimal(periodsPerYear).Sqrt(),
+	}, nil
+}
+
+// Update adds the next price observation.
+// Observations following a zero price are skipped to avoid undefined returns.
+func (v *RealizedVolatility) Update(value primitives.Decimal) {
+	if !v.hasPrev {
+		v.prev = value
+		v.hasPrev = true
+		return
+	}
+	ret, err := value.Sub(v.prev).Div(v.prev)
+	v.prev = value
+	if err != nil {
+		return
+	}
+	v.returns.push(ret)
+}
+
+// Value returns the (annualized) realized volatility.
+func (v *RealizedVolatility) Value() (primitives.Decimal, error) {
+	if !v.Ready() {
+		return primitives.Zero(), ErrNotReady
+	}
+	_, stdDev, err := meanStdDev(v.returns.slice())
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return stdDev.Mul(v.annualization), nil
+}
+
+// Ready reports whether period returns have been observed.
+func (v *RealizedVolatility) Ready() bool {
+	return v.returns.full()
+}
+
+// ZScore measures how many standard deviations the latest observation is
+// from the rolling mean.
+//
+// ZScore = (Value - Mean(period)) / StdDev(period)
+type ZScore struct {
+	window *window
+	last   primitives.Decimal
+}
+
+// NewZScore creates a rolling z-score over the given period.
+// Returns ErrInvalidPeriod if period is not positive.
+func NewZScore(period int) (*ZScore, error) {
+	if period <= 0 {
+		return nil, ErrInvalidPeriod
+	}
+	return &ZScore{window: newWindow(period)}, nil
+}
+
+// Update adds the next observation.
+func (z *ZScore) Update(value primitives.Decimal) {
+	z.window.push(value)
+	z.last = value
+}
+
+// Value returns the z-score of the latest observation.
+// Returns zero if the window has no dispersion.
+func (z *ZScore) Value() (primitives.Decimal, error) {
+	if !z.Ready() {
+		return primitives.Zero(), ErrNotReady
+	}
+	mean, stdDev, err := meanStdDev(z.window.slice())
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if stdDev.IsZero() {
+		return primitives.Zero(), nil
+	}
+	return z.last.Sub(mean).Div(stdDev)
+}
+
+// Ready reports whether period observations have been seen.
+func (z *ZScore) Ready() bool {
+	return z.window.full()
+}
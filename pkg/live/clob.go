@@ -0,0 +1,200 @@
+package live
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// CLOBConfig configures a CLOBExecutor.
+type CLOBConfig struct {
+	// Leverage sizes the margin fills move into PerpPosition positions;
+	// zero means 1 (fully collateralized)
+	Leverage primitives.Decimal
+
+	// Logger receives order records; nil disables logging
+	Logger *slog.Logger
+}
+
+// liveOrder is an order the executor placed and its fill state.
+type liveOrder struct {
+	order  strategy.Order
+	filled primitives.Decimal
+}
+
+// CLOBExecutor is an Executor for a central limit order book perp venue.
+// strategy.SubmitOrderAction and strategy.CancelOrderAction are sent to
+// the Venue; every other action is applied to the portfolio directly, as
+// paper bookkeeping. Reconcile polls the venue's fills and applies each
+// once, as a PerpFillAction on the PerpPosition named by the order's
+// PositionID, or PerpPositionID of its pair when that is empty. Fills of
+// orders placed outside the executor are applied to PerpPositionID.
+//
+// Thread Safety: CLOBExecutor is not thread-safe.
+type CLOBExecutor struct {
+	venue    Venue
+	leverage primitives.Decimal
+	log      *slog.Logger
+
+	// open maps venue order IDs to open orders, and venueIDs strategy
+	// order IDs to venue order IDs
+	open     map[string]*liveOrder
+	venueIDs map[string]string
+
+	// seen holds the trade IDs already applied; since is the time of the
+	// latest, from which the next Reconcile polls
+	seen  map[string]struct{}
+	since primitives.Time
+}
+
+// NewCLOBExecutor creates an executor trading on venue.
+func NewCLOBExecutor(venue Venue, config CLOBConfig) *CLOBExecutor {
+	return &CLOBExecutor{
+		venue:    venue,
+		leverage: config.Leverage,
+		log:      logging.For(config.Logger, logging.ComponentLive).With("venue", venue.Name()),
+		open:     make(map[string]*liveOrder),
+		venueIDs: make(map[string]string),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// Execute implements Executor. Actions are carried out in order and the
+// first failure is returned with the updates of the actions before it.
+func (e *CLOBExecutor) Execute(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, actions []strategy.Action) ([]strategy.OrderUpdate, error) {
+	var updates []strategy.OrderUpdate
+	for i, action := range actions {
+		var err error
+		switch a := action.(type) {
+		case *strategy.SubmitOrderAction:
+			var update strategy.OrderUpdate
+			update, err = e.submit(ctx, snapshot, a.Order)
+			if err == nil {
+				updates = append(updates, update)
+			}
+		case *strategy.CancelOrderAction:
+			var update strategy.OrderUpdate
+			update, err = e.cancel(ctx, snapshot, a.OrderID)
+			if err == nil {
+				updates = append(updates, update)
+			}
+		default:
+			err = action.Apply(portfolio)
+		}
+		if err != nil {
+			e.log.Warn("action failed", logging.KeyAction, action.String(), logging.KeyError, err)
+			return updates, fmt.Errorf("action %d (%s) failed: %w", i, action, err)
+		}
+	}
+	return updates, nil
+}
+
+func (e *CLOBExecutor) submit(ctx context.Context, snapshot strategy.MarketSnapshot, order strategy.Order) (strategy.OrderUpdate, error) {
+	if err := order.Validate(); err != nil {
+		return strategy.OrderUpdate{}, err
+	}
+	if _, exists := e.venueIDs[order.ID]; exists {
+		return strategy.OrderUpdate{}, fmt.Errorf("%w: order %s is already open", strategy.ErrInvalidAction, order.ID)
+	}
+	reference, err := snapshot.Price(order.Pair)
+	if err != nil {
+		return strategy.OrderUpdate{}, err
+	}
+	venueID, err := e.venue.PlaceOrder(ctx, order, reference)
+	if err != nil {
+		return strategy.OrderUpdate{}, err
+	}
+	e.open[venueID] = &liveOrder{order: order, filled: primitives.Zero()}
+	e.venueIDs[order.ID] = venueID
+	e.log.Info("order placed", logging.KeyOrder, order.ID, "venue_order_id", venueID, "order", order.String())
+	return strategy.OrderUpdate{Order: order, Status: strategy.OrderStatusOpen, Time: snapshot.Time(), Filled: primitives.Zero()}, nil
+}
+
+func (e *CLOBExecutor) cancel(ctx context.Context, snapshot strategy.MarketSnapshot, orderID string) (strategy.OrderUpdate, error) {
+	venueID, ok := e.venueIDs[orderID]
+	if !ok {
+		return strategy.OrderUpdate{}, fmt.Errorf("%w: %s", ErrUnknownOrder, orderID)
+	}
+	open := e.open[venueID]
+	if err := e.venue.CancelOrder(ctx, open.order.Pair, venueID); err != nil {
+		return strategy.OrderUpdate{}, err
+	}
+	e.close(venueID)
+	e.log.Info("order canceled", logging.KeyOrder, orderID, "venue_order_id", venueID)
+	return strategy.OrderUpdate{Order: open.order, Status: strategy.OrderStatusCanceled, Time: snapshot.Time(), Filled: open.filled}, nil
+}
+
+// Reconcile implements Executor.
+func (e *CLOBExecutor) Reconcile(ctx context.Context, portfolio *strategy.Portfolio) ([]strategy.OrderUpdate, error) {
+	fills, err := e.venue.Fills(ctx, e.since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch fills: %w", err)
+	}
+	var updates []strategy.OrderUpdate
+	for _, fill := range fills {
+		if _, applied := e.seen[fill.TradeID]; applied {
+			continue
+		}
+		open := e.open[fill.VenueOrderID]
+		positionID := PerpPositionID(fill.Pair)
+		fill.Fill.OrderID = fill.VenueOrderID
+		if open != nil {
+			positionID = open.order.PositionID
+			if positionID == "" {
+				positionID = PerpPositionID(open.order.Pair)
+			}
+			fill.Fill.OrderID = open.order.ID
+		}
+
+		action := &PerpFillAction{Fill: fill.Fill, PositionID: positionID, Fee: fill.Fee, Leverage: e.leverage}
+		if err := action.Apply(portfolio); err != nil {
+			return updates, fmt.Errorf("failed to apply fill %s: %w", fill.TradeID, err)
+		}
+		e.seen[fill.TradeID] = struct{}{}
+		if fill.Time.After(e.since) {
+			e.since = fill.Time
+		}
+		if e.log.Enabled(ctx, slog.LevelDebug) {
+			e.log.LogAttrs(ctx, slog.LevelDebug, "fill applied",
+				slog.String(logging.KeyOrder, fill.OrderID),
+				slog.String(logging.KeyPosition, positionID),
+				slog.String("trade_id", fill.TradeID),
+				slog.String("quantity", fill.Quantity.String()),
+				slog.String("price", fill.Price.String()))
+		}
+		if open == nil {
+			continue
+		}
+
+		open.filled = open.filled.Add(fill.Quantity)
+		update := strategy.OrderUpdate{Order: open.order, Status: strategy.OrderStatusOpen, Time: fill.Time, Filled: open.filled}
+		if !open.filled.LessThan(open.order.Size.Decimal()) {
+			update.Status = strategy.OrderStatusFilled
+			e.close(fill.VenueOrderID)
+		}
+		fillCopy := fill.Fill
+		update.Fill = &fillCopy
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// OpenOrders returns the IDs of the strategy orders still open.
+func (e *CLOBExecutor) OpenOrders() []string {
+	ids := make([]string, 0, len(e.venueIDs))
+	for id := range e.venueIDs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (e *CLOBExecutor) close(venueID string) {
+	if open, ok := e.open[venueID]; ok {
+		delete(e.venueIDs, open.order.ID)
+		delete(e.open, venueID)
+	}
+}
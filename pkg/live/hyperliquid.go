@@ -0,0 +1,633 @@
+package live
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// HyperliquidTestnetURL is the Hyperliquid testnet API.
+const HyperliquidTestnetURL = "https://api.hyperliquid-testnet.xyz"
+
+// strategy.Order Metadata keys for venue order flags (values are bool).
+const (
+	// MetadataReduceOnly marks an order that may only reduce a position
+	MetadataReduceOnly = "reduce_only"
+
+	// MetadataPostOnly marks a limit order that is canceled rather than
+	// taking liquidity
+	MetadataPostOnly = "post_only"
+)
+
+// HyperliquidVenue is a Venue for Hyperliquid perpetuals, by default on
+// the testnet. Orders trade the perp named by the base asset of the
+// order's pair ("ETH/USD" trades ETH). Hyperliquid has no native market
+// orders, so market orders are sent as immediate-or-cancel limits
+// Slippage through the reference price. Prices are rounded to five
+// significant figures and sizes to the asset's size decimals, as the
+// exchange requires. Stop orders, FOK and GTD are not supported; the
+// MetadataReduceOnly and MetadataPostOnly flags are.
+//
+// Exchange requests are signed with PrivateKey as Hyperliquid's L1
+// actions: an EIP-712 "Agent" message over the keccak hash of the
+// msgpack-encoded action.
+//
+// Thread Safety: HyperliquidVenue is safe for concurrent use once
+// configured.
+type HyperliquidVenue struct {
+	// URL is the API base URL (HyperliquidTestnetURL if empty)
+	URL string
+
+	// PrivateKey signs exchange requests; it is the account's key or an
+	// approved agent wallet's
+	PrivateKey *ecdsa.PrivateKey
+
+	// Account is the address whose fills are read; empty uses the
+	// address of PrivateKey (set it when PrivateKey is an agent wallet)
+	Account string
+
+	// Quote is the quote asset of the pairs fills are reported in
+	// (default "USD")
+	Quote string
+
+	// Slippage bounds market order prices as a fraction of the reference
+	// price (default 0.05)
+	Slippage primitives.Decimal
+
+	// Mainnet signs for mainnet rather than testnet; it must match URL
+	Mainnet bool
+
+	// Client is the HTTP client used (a client with a 30s timeout if nil)
+	Client *http.Client
+
+	// Clock stamps request nonces (the wall clock if nil)
+	Clock primitives.Clock
+
+	mu        sync.Mutex
+	assets    map[string]hyperliquidAsset
+	lastNonce int64
+}
+
+// hyperliquidAsset is a perp in the exchange's universe.
+type hyperliquidAsset struct {
+	index      int
+	szDecimals int32
+}
+
+// NewHyperliquidVenue creates a testnet venue signing with key.
+func NewHyperliquidVenue(key *ecdsa.PrivateKey) *HyperliquidVenue {
+	return &HyperliquidVenue{URL: HyperliquidTestnetURL, PrivateKey: key}
+}
+
+// Name returns "hyperliquid".
+func (v *HyperliquidVenue) Name() string {
+	return "hyperliquid"
+}
+
+// PlaceOrder implements Venue. It returns the exchange's order ID, for
+// resting and immediately filled orders alike.
+func (v *HyperliquidVenue) PlaceOrder(ctx context.Context, order strategy.Order, reference primitives.Price) (string, error) {
+	asset, err := v.asset(ctx, order.Pair)
+	if err != nil {
+		return "", err
+	}
+	isBuy := order.Side == mechanisms.OrderSideBuy
+
+	var price primitives.Decimal
+	tif := "Gtc"
+	switch order.Type {
+	case mechanisms.OrderTypeLimit:
+		price = order.Price.Decimal()
+	case mechanisms.OrderTypeMarket:
+		slippage := v.Slippage
+		if slippage.IsZero() {
+			slippage = primitives.NewDecimalFromFloat(0.05)
+		}
+		if isBuy {
+			price = reference.Decimal().Mul(primitives.One().Add(slippage))
+		} else {
+			price = reference.Decimal().Mul(primitives.One().Sub(slippage))
+		}
+		tif = "Ioc"
+	default:
+		return "", fmt.Errorf("%w: %s orders", ErrUnsupportedOrder, order.Type)
+	}
+	switch order.TimeInForce {
+	case "", mechanisms.TimeInForceGTC:
+	case mechanisms.TimeInForceIOC:
+		tif = "Ioc"
+	default:
+		return "", fmt.Errorf("%w: time in force %s", ErrUnsupportedOrder, order.TimeInForce)
+	}
+	if postOnly, _ := order.Metadata[MetadataPostOnly].(bool); postOnly {
+		tif = "Alo"
+	}
+	reduceOnly, _ := order.Metadata[MetadataReduceOnly].(bool)
+
+	size := order.Size.Decimal().Round(asset.szDecimals, primitives.RoundDown)
+	if !size.IsPositive() {
+		return "", fmt.Errorf("%w: size %s rounds to zero at %d decimals", ErrUnsupportedOrder, order.Size, asset.szDecimals)
+	}
+	wire := object{
+		{"a", asset.index},
+		{"b", isBuy},
+		{"p", hyperliquidPrice(price, asset.szDecimals, isBuy).String()},
+		{"s", size.String()},
+		{"r", reduceOnly},
+		{"t", object{{"limit", object{{"tif", tif}}}}},
+	}
+	action := object{
+		{"type", "order"},
+		{"orders", []interface{}{wire}},
+		{"grouping", "na"},
+	}
+
+	var status struct {
+		Resting *struct {
+			Oid int64 `json:"oid"`
+		} `json:"resting"`
+		Filled *struct {
+			Oid int64 `json:"oid"`
+		} `json:"filled"`
+		Error string `json:"error"`
+	}
+	if err := v.exchange(ctx, action, &status); err != nil {
+		return "", err
+	}
+	switch {
+	case status.Error != "":
+		return "", fmt.Errorf("%w: order %s rejected: %s", ErrVenue, order.ID, status.Error)
+	case status.Resting != nil:
+		return strconv.FormatInt(status.Resting.Oid, 10), nil
+	case status.Filled != nil:
+		return strconv.FormatInt(status.Filled.Oid, 10), nil
+	default:
+		return "", fmt.Errorf("%w: order %s has no status", ErrVenue, order.ID)
+	}
+}
+
+// CancelOrder implements Venue.
+func (v *HyperliquidVenue) CancelOrder(ctx context.Context, pair, venueOrderID string) error {
+	asset, err := v.asset(ctx, pair)
+	if err != nil {
+		return err
+	}
+	oid, err := strconv.ParseInt(venueOrderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad order ID %q", ErrUnknownOrder, venueOrderID)
+	}
+	action := object{
+		{"type", "cancel"},
+		{"cancels", []interface{}{object{{"a", asset.index}, {"o", oid}}}},
+	}
+
+	var status json.RawMessage
+	if err := v.exchange(ctx, action, &status); err != nil {
+		return err
+	}
+	var rejected struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(status, &rejected) == nil && rejected.Error != "" {
+		return fmt.Errorf("%w: cancel of %s rejected: %s", ErrVenue, venueOrderID, rejected.Error)
+	}
+	return nil
+}
+
+// Fills implements Venue.
+func (v *HyperliquidVenue) Fills(ctx context.Context, since primitives.Time) ([]VenueFill, error) {
+	account, err := v.account()
+	if err != nil {
+		return nil, err
+	}
+	var start int64
+	if !since.Time().IsZero() {
+		start = since.Time().UnixMilli()
+	}
+	request := object{
+		{"type", "userFillsByTime"},
+		{"user", account},
+		{"startTime", start},
+	}
+	var raw []struct {
+		Coin string `json:"coin"`
+		Px   string `json:"px"`
+		Sz   string `json:"sz"`
+		Side string `json:"side"`
+		Time int64  `json:"time"`
+		Oid  int64  `json:"oid"`
+		Tid  int64  `json:"tid"`
+		Fee  string `json:"fee"`
+	}
+	if err := v.info(ctx, request, &raw); err != nil {
+		return nil, fmt.Errorf("failed to read fills: %w", err)
+	}
+
+	quote := v.Quote
+	if quote == "" {
+		quote = "USD"
+	}
+	fills := make([]VenueFill, 0, len(raw))
+	for _, f := range raw {
+		price, err := primitives.NewDecimalFromString(f.Px)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fill %d price: %s", ErrVenue, f.Tid, err)
+		}
+		quantity, err := primitives.NewDecimalFromString(f.Sz)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fill %d size: %s", ErrVenue, f.Tid, err)
+		}
+		fee := primitives.Zero()
+		if f.Fee != "" {
+			if fee, err = primitives.NewDecimalFromString(f.Fee); err != nil {
+				return nil, fmt.Errorf("%w: fill %d fee: %s", ErrVenue, f.Tid, err)
+			}
+		}
+		fillPrice, err := primitives.NewPrice(price)
+		if err != nil {
+			return nil, fmt.Errorf("%w: fill %d price: %s", ErrVenue, f.Tid, err)
+		}
+		side := mechanisms.OrderSideBuy
+		if f.Side == "A" {
+			side = mechanisms.OrderSideSell
+		}
+		venueOrderID := strconv.FormatInt(f.Oid, 10)
+		fills = append(fills, VenueFill{
+			Fill: strategy.Fill{
+				OrderID:  venueOrderID,
+				Pair:     f.Coin + "/" + quote,
+				Side:     side,
+				Time:     primitives.NewTime(time.UnixMilli(f.Time)),
+				Quantity: quantity,
+				Price:    fillPrice,
+			},
+			VenueOrderID: venueOrderID,
+			TradeID:      strconv.FormatInt(f.Tid, 10),
+			Fee:          fee,
+		})
+	}
+	return fills, nil
+}
+
+// asset returns the perp traded by pair, loading the exchange's universe
+// on first use.
+func (v *HyperliquidVenue) asset(ctx context.Context, pair string) (hyperliquidAsset, error) {
+	parsed, err := primitives.ParsePair(pair)
+	if err != nil {
+		return hyperliquidAsset{}, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.assets == nil {
+		var meta struct {
+			Universe []struct {
+				Name       string `json:"name"`
+				SzDecimals int32  `json:"szDecimals"`
+			} `json:"universe"`
+		}
+		if err := v.info(ctx, object{{"type", "meta"}}, &meta); err != nil {
+			return hyperliquidAsset{}, fmt.Errorf("failed to read universe: %w", err)
+		}
+		assets := make(map[string]hyperliquidAsset, len(meta.Universe))
+		for i, a := range meta.Universe {
+			assets[strings.ToUpper(a.Name)] = hyperliquidAsset{index: i, szDecimals: a.SzDecimals}
+		}
+		v.assets = assets
+	}
+	asset, ok := v.assets[parsed.Base]
+	if !ok {
+		return hyperliquidAsset{}, fmt.Errorf("%w: no %s perp", ErrUnsupportedOrder, parsed.Base)
+	}
+	return asset, nil
+}
+
+func (v *HyperliquidVenue) account() (string, error) {
+	if v.Account != "" {
+		return strings.ToLower(v.Account), nil
+	}
+	if v.PrivateKey == nil {
+		return "", fmt.Errorf("%w: no account or private key", ErrVenue)
+	}
+	return strings.ToLower(crypto.PubkeyToAddress(v.PrivateKey.PublicKey).Hex()), nil
+}
+
+// nonce returns the current time in milliseconds, strictly increasing
+// across calls as the exchange requires.
+func (v *HyperliquidVenue) nonce() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var clock primitives.Clock = primitives.SystemClock{}
+	if v.Clock != nil {
+		clock = v.Clock
+	}
+	n := clock.Now().Time().UnixMilli()
+	if n <= v.lastNonce {
+		n = v.lastNonce + 1
+	}
+	v.lastNonce = n
+	return n
+}
+
+// info posts request to the info endpoint.
+func (v *HyperliquidVenue) info(ctx context.Context, request object, out interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return v.post(ctx, "/info", body, out)
+}
+
+// exchange signs and posts action to the exchange endpoint and decodes the
+// first entry of the response's statuses into status. Statuses are
+// objects, except the bare string "success" of a cancel.
+func (v *HyperliquidVenue) exchange(ctx context.Context, action object, status interface{}) error {
+	if v.PrivateKey == nil {
+		return fmt.Errorf("%w: no private key", ErrVenue)
+	}
+	nonce := v.nonce()
+	signature, err := signL1Action(v.PrivateKey, action, nonce, v.Mainnet)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(object{
+		{"action", action},
+		{"nonce", nonce},
+		{"signature", signature},
+		{"vaultAddress", nil},
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Status   string          `json:"status"`
+		Response json.RawMessage `json:"response"`
+	}
+	if err := v.post(ctx, "/exchange", body, &resp); err != nil {
+		return err
+	}
+	if resp.Status != "ok" {
+		return fmt.Errorf("%w: %s", ErrVenue, strings.Trim(string(resp.Response), `"`))
+	}
+	var result struct {
+		Data struct {
+			Statuses []json.RawMessage `json:"statuses"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Response, &result); err != nil {
+		return fmt.Errorf("%w: %s", ErrVenue, err)
+	}
+	if len(result.Data.Statuses) == 0 {
+		return fmt.Errorf("%w: empty statuses", ErrVenue)
+	}
+	if err := json.Unmarshal(result.Data.Statuses[0], status); err != nil {
+		return fmt.Errorf("%w: %s", ErrVenue, err)
+	}
+	return nil
+}
+
+func (v *HyperliquidVenue) post(ctx context.Context, path string, body []byte, out interface{}) error {
+	url := v.URL
+	if url == "" {
+		url = HyperliquidTestnetURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s: %s", ErrVenue, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: %s", ErrVenue, err)
+	}
+	return nil
+}
+
+// hyperliquidPrice rounds price to five significant figures and at most
+// 6 - szDecimals decimal places, away from the book (up for buys, down for
+// sells) so a marketable price stays marketable.
+func hyperliquidPrice(price primitives.Decimal, szDecimals int32, isBuy bool) primitives.Decimal {
+	places := 6 - szDecimals
+	if f := price.Float64(); f > 0 {
+		digits := int32(math.Floor(math.Log10(f))) + 1
+		places = min(places, 5-digits)
+	}
+	places = max(places, 0)
+	if isBuy {
+		return price.Round(places, primitives.RoundCeil)
+	}
+	return price.Round(places, primitives.RoundFloor)
+}
+
+// hyperliquidSignature is an ECDSA signature in the exchange's JSON form.
+type hyperliquidSignature struct {
+	R string `json:"r"`
+	S string `json:"s"`
+	V int    `json:"v"`
+}
+
+// signL1Action signs action at nonce as a Hyperliquid L1 action: the
+// EIP-712 Agent message {source, connectionId} where connectionId is the
+// keccak hash of the msgpack-encoded action, the big-endian nonce and a
+// zero byte for "no vault".
+func signL1Action(key *ecdsa.PrivateKey, action object, nonce int64, mainnet bool) (hyperliquidSignature, error) {
+	packed, err := msgpack(nil, action)
+	if err != nil {
+		return hyperliquidSignature{}, err
+	}
+	packed = binary.BigEndian.AppendUint64(packed, uint64(nonce))
+	packed = append(packed, 0)
+	connectionID := crypto.Keccak256(packed)
+
+	source := "b"
+	if mainnet {
+		source = "a"
+	}
+	digest := agentDigest(source, connectionID)
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		return hyperliquidSignature{}, err
+	}
+	return hyperliquidSignature{
+		R: "0x" + hex.EncodeToString(sig[:32]),
+		S: "0x" + hex.EncodeToString(sig[32:64]),
+		V: int(sig[64]) + 27,
+	}, nil
+}
+
+// agentDigest returns the EIP-712 digest of Agent{source, connectionId}
+// in the exchange's domain (name "Exchange", version "1", chain 1337, the
+// zero verifying contract).
+func agentDigest(source string, connectionID []byte) []byte {
+	domainType := crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	chainID := common.LeftPadBytes([]byte{0x05, 0x39}, 32)
+	domain := crypto.Keccak256(
+		domainType,
+		crypto.Keccak256([]byte("Exchange")),
+		crypto.Keccak256([]byte("1")),
+		chainID,
+		make([]byte, 32),
+	)
+	agentType := crypto.Keccak256([]byte("Agent(string source,bytes32 connectionId)"))
+	message := crypto.Keccak256(agentType, crypto.Keccak256([]byte(source)), connectionID)
+	return crypto.Keccak256([]byte{0x19, 0x01}, domain, message)
+}
+
+// field is a key and value of an object.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// object is a JSON/msgpack map that keeps its keys in order, since the
+// signed msgpack bytes depend on it.
+type object []field
+
+// MarshalJSON encodes the object with its keys in order.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// msgpack appends the msgpack encoding of v to b, using the smallest
+// encoding of each value as the reference Python packer does. Supported
+// values are nil, bool, int, int64, string, object and []interface{}.
+func msgpack(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int:
+		return msgpackInt(b, int64(v)), nil
+	case int64:
+		return msgpackInt(b, v), nil
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n < 1<<8:
+			b = append(b, 0xd9, byte(n))
+		case n < 1<<16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, v...), nil
+	case object:
+		b = msgpackHeader(b, len(v), 0x80, 0xde)
+		var err error
+		for _, f := range v {
+			if b, err = msgpack(b, f.key); err != nil {
+				return nil, err
+			}
+			if b, err = msgpack(b, f.value); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case []interface{}:
+		b = msgpackHeader(b, len(v), 0x90, 0xdc)
+		var err error
+		for _, e := range v {
+			if b, err = msgpack(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+func msgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(b, byte(v))
+	case v >= 0 && v < 1<<8:
+		return append(b, 0xcc, byte(v))
+	case v >= 0 && v < 1<<16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v >= 0 && v < 1<<32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+// msgpackHeader appends a map or array header: the fix form for fewer than
+// 16 entries, otherwise the 16- or 32-bit form (long + 1).
+func msgpackHeader(b []byte, n int, fix, long byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(b, long), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, long+1), uint32(n))
+	}
+}
@@ -0,0 +1,182 @@
+// Package live runs strategies against live venues for paper trading: the
+// same strategy.Strategy a backtest drives is fed live snapshots, and its
+// order actions are sent to a venue instead of the backtest engine's
+// simulated book.
+//
+// An Executor carries out a strategy's actions and reconciles what the
+// venue did back into the portfolio. CLOBExecutor implements it for
+// central limit order book perp venues behind the Venue interface, such as
+// the Hyperliquid testnet (HyperliquidVenue). Fills become PerpPosition
+// positions, so the portfolio mirrors the venue account. Session is the
+// loop tying a snapshot feed, a strategy and an executor together.
+package live
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrUnsupportedOrder is returned when a venue cannot express an order's
+	// type or time in force
+	ErrUnsupportedOrder = errors.New("order not supported by venue")
+
+	// ErrUnknownOrder is returned when canceling an order the executor did
+	// not place or that is no longer open
+	ErrUnknownOrder = errors.New("unknown order")
+
+	// ErrVenue is returned when a venue rejects a request or responds with
+	// something unexpected
+	ErrVenue = errors.New("venue error")
+)
+
+// Executor carries out a strategy's actions against a live or paper venue,
+// the live counterpart of the backtest engine's action handling.
+type Executor interface {
+	// Execute carries out actions returned at snapshot: order submissions
+	// and cancellations go to the venue, other actions are applied to
+	// portfolio. It returns an update for each order submitted or
+	// canceled.
+	Execute(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, actions []strategy.Action) ([]strategy.OrderUpdate, error)
+
+	// Reconcile applies fills the venue reported since the previous call
+	// to portfolio and returns an update for each.
+	Reconcile(ctx context.Context, portfolio *strategy.Portfolio) ([]strategy.OrderUpdate, error)
+}
+
+// Venue is a central limit order book venue's trading API.
+type Venue interface {
+	// Name returns the venue identifier.
+	Name() string
+
+	// PlaceOrder submits order and returns the venue's order ID. reference
+	// is the current price of the order's pair, which venues without
+	// native market orders use to bound the price of a market order.
+	PlaceOrder(ctx context.Context, order strategy.Order, reference primitives.Price) (string, error)
+
+	// CancelOrder cancels the open order with the venue's order ID.
+	CancelOrder(ctx context.Context, pair, venueOrderID string) error
+
+	// Fills returns the account's executions at or after since in time
+	// order. Fills may repeat across calls; TradeID identifies them.
+	Fills(ctx context.Context, since primitives.Time) ([]VenueFill, error)
+}
+
+// VenueFill is an execution reported by a Venue.
+type VenueFill struct {
+	strategy.Fill
+
+	// VenueOrderID is the venue's ID of the order that filled
+	VenueOrderID string
+
+	// TradeID uniquely identifies the execution at the venue
+	TradeID string
+
+	// Fee is the fee charged in the quote currency, negative for a rebate
+	Fee primitives.Decimal
+}
+
+// Session drives a strategy with live snapshots through an Executor.
+type Session struct {
+	// Executor carries out the strategy's actions
+	Executor Executor
+
+	// Logger receives session records; nil disables logging
+	Logger *slog.Logger
+
+	// Seed seeds the source of randomness strategies draw from with
+	// strategy.Rand, so a recorded feed replays with the same draws
+	Seed int64
+
+	// Clock is the time source strategies read with strategy.Clock; nil
+	// uses the wall clock
+	Clock primitives.Clock
+}
+
+// Run processes snapshots from feed until it is closed or ctx is done.
+// For each snapshot it reconciles fills into portfolio, notifies a
+// strategy.OrderListener of the resulting updates, rebalances the strategy
+// and executes its actions, as the backtest engine does for each
+// snapshot. Returns nil when feed is closed, ctx.Err() when ctx is done,
+// and otherwise the first error from the executor or strategy.
+func (s *Session) Run(ctx context.Context, strat strategy.Strategy, portfolio *strategy.Portfolio, feed <-chan strategy.MarketSnapshot) error {
+	if s.Executor == nil {
+		return errors.New("session needs an executor")
+	}
+	if strat == nil {
+		return fmt.Errorf("strategy cannot be nil")
+	}
+	if portfolio == nil {
+		return strategy.ErrNilPortfolio
+	}
+	log := logging.For(s.Logger, logging.ComponentLive)
+	listener, _ := strat.(strategy.OrderListener)
+	ctx = strategy.WithRand(ctx, strategy.NewRand(s.Seed))
+	clock := s.Clock
+	if clock == nil {
+		clock = primitives.SystemClock{}
+	}
+	ctx = strategy.WithClock(ctx, clock)
+
+	for i := 0; ; i++ {
+		var snapshot strategy.MarketSnapshot
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case next, ok := <-feed:
+			if !ok {
+				return nil
+			}
+			snapshot = next
+		}
+		portfolio.SetTime(snapshot.Time())
+
+		updates, err := s.Executor.Reconcile(ctx, portfolio)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile at snapshot %d: %w", i, err)
+		}
+		notify(listener, updates)
+
+		actions, err := strat.Rebalance(ctx, portfolio, snapshot)
+		if err != nil {
+			log.Error("strategy rebalance failed", logging.KeySnapshot, i, logging.KeyError, err)
+			return fmt.Errorf("strategy rebalance failed at snapshot %d: %w", i, err)
+		}
+		updates, err = s.Executor.Execute(ctx, portfolio, snapshot, actions)
+		notify(listener, updates)
+		if err != nil {
+			log.Error("execution failed", logging.KeySnapshot, i, logging.KeyError, err)
+			return fmt.Errorf("execution failed at snapshot %d: %w", i, err)
+		}
+		if log.Enabled(ctx, slog.LevelDebug) {
+			log.LogAttrs(ctx, slog.LevelDebug, "snapshot",
+				slog.Int(logging.KeySnapshot, i),
+				slog.Time(logging.KeySnapshotTime, snapshot.Time().Time()),
+				slog.Int("actions", len(actions)))
+		}
+	}
+}
+
+func notify(listener strategy.OrderListener, updates []strategy.OrderUpdate) {
+	if listener == nil {
+		return
+	}
+	for _, update := range updates {
+		listener.OnOrderUpdate(update)
+	}
+}
+
+// signedQuantity returns a fill's quantity, negative for sells.
+func signedQuantity(fill strategy.Fill) primitives.Decimal {
+	if fill.Side == mechanisms.OrderSideSell {
+		return fill.Quantity.Neg()
+	}
+	return fill.Quantity
+}
@@ -0,0 +1,335 @@
+package live_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/live"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func fill(side mechanisms.OrderSide, quantity, price string) strategy.Fill {
+	return strategy.Fill{OrderID: "o", Pair: "ETH/USD", Side: side, Quantity: dec(quantity), Price: primitives.MustPrice(dec(price))}
+}
+
+func perp(t *testing.T, portfolio *strategy.Portfolio) *live.PerpPosition {
+	t.Helper()
+	pos, err := portfolio.GetPosition(live.PerpPositionID("ETH/USD"))
+	if err != nil {
+		t.Fatalf("GetPosition: %v", err)
+	}
+	return pos.(*live.PerpPosition)
+}
+
+func TestPerpFillAction(t *testing.T) {
+	portfolio := strategy.NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000)))
+	apply := func(f strategy.Fill, fee string) {
+		t.Helper()
+		action := &live.PerpFillAction{Fill: f, PositionID: live.PerpPositionID("ETH/USD"), Fee: dec(fee), Leverage: primitives.NewDecimal(5)}
+		if err := action.Apply(portfolio); err != nil {
+			t.Fatalf("Apply %s: %v", action, err)
+		}
+	}
+
+	// Open 2 long at 2000 with 5x: margin 800, fee 1
+	apply(fill(mechanisms.OrderSideBuy, "2", "2000"), "1")
+	p := perp(t, portfolio)
+	if !p.Size().Equal(dec("2")) || !p.Margin().Equal(dec("800")) || !p.EntryPrice().Decimal().Equal(dec("2000")) {
+		t.Fatalf("open: size %s margin %s entry %s", p.Size(), p.Margin(), p.EntryPrice())
+	}
+	if !portfolio.Cash().Decimal().Equal(dec("9199")) {
+		t.Errorf("cash after open = %s, want 9199", portfolio.Cash())
+	}
+
+	// Reduce 1 at 2100: release 400 margin and realize 100
+	apply(fill(mechanisms.OrderSideSell, "1", "2100"), "0")
+	p = perp(t, portfolio)
+	if !p.Size().Equal(dec("1")) || !p.Margin().Equal(dec("400")) {
+		t.Fatalf("reduce: size %s margin %s", p.Size(), p.Margin())
+	}
+	if !portfolio.Cash().Decimal().Equal(dec("9699")) {
+		t.Errorf("cash after reduce = %s, want 9699", portfolio.Cash())
+	}
+
+	// Flip: sell 3 at 1900 closes 1 (realize -100, release 400) and opens
+	// 2 short at 1900 (margin 760)
+	apply(fill(mechanisms.OrderSideSell, "3", "1900"), "0")
+	p = perp(t, portfolio)
+	if !p.Size().Equal(dec("-2")) || !p.Margin().Equal(dec("760")) || !p.EntryPrice().Decimal().Equal(dec("1900")) {
+		t.Fatalf("flip: size %s margin %s entry %s", p.Size(), p.Margin(), p.EntryPrice())
+	}
+	if !portfolio.Cash().Decimal().Equal(dec("9239")) {
+		t.Errorf("cash after flip = %s, want 9239", portfolio.Cash())
+	}
+
+	// Valued at margin plus unrealized P&L: 760 + (-2)(1800-1900) = 960
+	snapshot := strategy.NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{"ETH/USD": primitives.MustPrice(dec("1800"))})
+	value, err := p.Value(snapshot)
+	if err != nil || !value.Decimal().Equal(dec("960")) {
+		t.Errorf("Value = %s, %v; want 960", value, err)
+	}
+
+	// Closing flat removes the position
+	apply(fill(mechanisms.OrderSideBuy, "2", "1800"), "0")
+	if _, err := portfolio.GetPosition(live.PerpPositionID("ETH/USD")); err == nil {
+		t.Error("a flat perp should be removed")
+	}
+	if !portfolio.Cash().Decimal().Equal(dec("10199")) {
+		t.Errorf("cash after close = %s, want 10199", portfolio.Cash())
+	}
+}
+
+// fakeVenue accepts every order and reports the fills queued on it.
+type fakeVenue struct {
+	placed   []strategy.Order
+	canceled []string
+	fills    []live.VenueFill
+}
+
+func (v *fakeVenue) Name() string { return "fake" }
+
+func (v *fakeVenue) PlaceOrder(ctx context.Context, order strategy.Order, reference primitives.Price) (string, error) {
+	v.placed = append(v.placed, order)
+	return "v-" + order.ID, nil
+}
+
+func (v *fakeVenue) CancelOrder(ctx context.Context, pair, venueOrderID string) error {
+	v.canceled = append(v.canceled, venueOrderID)
+	return nil
+}
+
+func (v *fakeVenue) Fills(ctx context.Context, since primitives.Time) ([]live.VenueFill, error) {
+	return v.fills, nil
+}
+
+// listeningStrategy submits its orders on the first snapshot and records
+// order updates.
+type listeningStrategy struct {
+	orders  []strategy.Order
+	updates []strategy.OrderUpdate
+	clock   primitives.Clock
+}
+
+func (s *listeningStrategy) Rebalance(ctx context.Context, portfolio *strategy.Portfolio, market strategy.MarketSnapshot) ([]strategy.Action, error) {
+	s.clock = strategy.Clock(ctx)
+	var actions []strategy.Action
+	for _, order := range s.orders {
+		actions = append(actions, strategy.NewSubmitOrderAction(order))
+	}
+	s.orders = nil
+	return actions, nil
+}
+
+func (s *listeningStrategy) OnOrderUpdate(update strategy.OrderUpdate) {
+	s.updates = append(s.updates, update)
+}
+
+func TestCLOBExecutor(t *testing.T) {
+	ctx := context.Background()
+	venue := &fakeVenue{}
+	executor := live.NewCLOBExecutor(venue, live.CLOBConfig{})
+	portfolio := strategy.NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000)))
+	start := primitives.Now()
+	price := map[string]primitives.Price{"ETH/USD": primitives.MustPrice(dec("2000"))}
+
+	strat := &listeningStrategy{orders: []strategy.Order{
+		strategy.NewLimitOrder("buy", "ETH/USD", mechanisms.OrderSideBuy, primitives.MustAmount(dec("2")), primitives.MustPrice(dec("1990"))),
+		strategy.NewLimitOrder("rest", "ETH/USD", mechanisms.OrderSideSell, primitives.MustAmount(dec("1")), primitives.MustPrice(dec("2200"))),
+	}}
+	feed := make(chan strategy.MarketSnapshot, 1)
+	feed <- strategy.NewSimpleSnapshot(start, price)
+	close(feed)
+	clock := primitives.NewManualClock(start)
+	session := &live.Session{Executor: executor, Clock: clock}
+	if err := session.Run(ctx, strat, portfolio, feed); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strat.clock != clock {
+		t.Errorf("strategy read clock %v, want the session's", strat.clock)
+	}
+	if len(venue.placed) != 2 || len(strat.updates) != 2 || strat.updates[0].Status != strategy.OrderStatusOpen {
+		t.Fatalf("placed %d orders, updates %+v", len(venue.placed), strat.updates)
+	}
+
+	// A partial fill, its repeat and the rest of the order
+	venue.fills = []live.VenueFill{
+		{Fill: fill(mechanisms.OrderSideBuy, "1.5", "1990"), VenueOrderID: "v-buy", TradeID: "t1", Fee: dec("0.5")},
+		{Fill: fill(mechanisms.OrderSideBuy, "1.5", "1990"), VenueOrderID: "v-buy", TradeID: "t1", Fee: dec("0.5")},
+		{Fill: fill(mechanisms.OrderSideBuy, "0.5", "1990"), VenueOrderID: "v-buy", TradeID: "t2"},
+	}
+	updates, err := executor.Reconcile(ctx, portfolio)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(updates) != 2 || updates[0].Status != strategy.OrderStatusOpen || updates[1].Status != strategy.OrderStatusFilled {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if updates[1].Fill == nil || updates[1].Fill.OrderID != "buy" || !updates[1].Filled.Equal(dec("2")) {
+		t.Errorf("final update = %+v", updates[1])
+	}
+	p := perp(t, portfolio)
+	if !p.Size().Equal(dec("2")) || !p.Margin().Equal(dec("3980")) {
+		t.Errorf("position size %s margin %s", p.Size(), p.Margin())
+	}
+	if !portfolio.Cash().Decimal().Equal(dec("6019.5")) {
+		t.Errorf("cash = %s, want 6019.5", portfolio.Cash())
+	}
+	if again, _ := executor.Reconcile(ctx, portfolio); len(again) != 0 {
+		t.Errorf("fills were applied twice: %+v", again)
+	}
+
+	snapshot := strategy.NewSimpleSnapshot(start.Add(primitives.Minutes(1)), price)
+	updates, err = executor.Execute(ctx, portfolio, snapshot, []strategy.Action{strategy.NewCancelOrderAction("rest")})
+	if err != nil || len(updates) != 1 || updates[0].Status != strategy.OrderStatusCanceled || venue.canceled[0] != "v-rest" {
+		t.Fatalf("cancel: %+v, %v", updates, err)
+	}
+	if _, err := executor.Execute(ctx, portfolio, snapshot, []strategy.Action{strategy.NewCancelOrderAction("buy")}); !errors.Is(err, live.ErrUnknownOrder) {
+		t.Errorf("canceling a filled order: expected ErrUnknownOrder, got %v", err)
+	}
+	if open := executor.OpenOrders(); len(open) != 0 {
+		t.Errorf("open orders = %v", open)
+	}
+}
+
+// hyperliquidServer fakes the info and exchange endpoints and records
+// exchange requests.
+func hyperliquidServer(t *testing.T, requests *[]map[string]json.RawMessage) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		switch r.URL.Path {
+		case "/info":
+			var kind string
+			json.Unmarshal(body["type"], &kind)
+			switch kind {
+			case "meta":
+				w.Write([]byte(`{"universe":[{"name":"BTC","szDecimals":5},{"name":"ETH","szDecimals":4}]}`))
+			case "userFillsByTime":
+				w.Write([]byte(`[{"coin":"ETH","px":"2001.5","sz":"0.5","side":"A","time":1700000000000,"oid":123,"tid":9,"fee":"0.25"}]`))
+			}
+		case "/exchange":
+			*requests = append(*requests, body)
+			var action struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(body["action"], &action)
+			if action.Type == "cancel" {
+				w.Write([]byte(`{"status":"ok","response":{"type":"cancel","data":{"statuses":["success"]}}}`))
+				return
+			}
+			w.Write([]byte(`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":123}}]}}}`))
+		}
+	}))
+}
+
+func TestHyperliquidVenue(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests []map[string]json.RawMessage
+	server := hyperliquidServer(t, &requests)
+	defer server.Close()
+	venue := live.NewHyperliquidVenue(key)
+	venue.URL = server.URL
+	venue.Clock = primitives.NewManualClock(primitives.Unix(1700000000, 0))
+
+	market := strategy.NewMarketOrder("m1", "ETH/USD", mechanisms.OrderSideBuy, primitives.MustAmount(dec("0.123456")))
+	market.Metadata = map[string]interface{}{live.MetadataReduceOnly: true}
+	oid, err := venue.PlaceOrder(ctx, market, primitives.MustPrice(dec("2000.123")))
+	if err != nil || oid != "123" {
+		t.Fatalf("PlaceOrder = %q, %v", oid, err)
+	}
+	// 2000.123 × 1.05 = 2100.12915, rounded up to five significant figures
+	want := `{"type":"order","orders":[{"a":1,"b":true,"p":"2100.2","s":"0.1234","r":true,"t":{"limit":{"tif":"Ioc"}}}],"grouping":"na"}`
+	if got := string(requests[0]["action"]); got != want {
+		t.Errorf("order action =\n%s\nwant\n%s", got, want)
+	}
+
+	stop := strategy.NewStopOrder("s1", "ETH/USD", mechanisms.OrderSideSell, primitives.MustAmount(dec("1")), primitives.MustPrice(dec("1900")))
+	if _, err := venue.PlaceOrder(ctx, stop, primitives.MustPrice(dec("2000"))); !errors.Is(err, live.ErrUnsupportedOrder) {
+		t.Errorf("stop order: expected ErrUnsupportedOrder, got %v", err)
+	}
+	doge := strategy.NewMarketOrder("d1", "DOGE/USD", mechanisms.OrderSideBuy, primitives.MustAmount(dec("1")))
+	if _, err := venue.PlaceOrder(ctx, doge, primitives.MustPrice(dec("0.1"))); !errors.Is(err, live.ErrUnsupportedOrder) {
+		t.Errorf("unlisted perp: expected ErrUnsupportedOrder, got %v", err)
+	}
+
+	if err := venue.CancelOrder(ctx, "ETH/USD", "123"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	cancel := requests[1]
+	var nonce uint64
+	if err := json.Unmarshal(cancel["nonce"], &nonce); err != nil {
+		t.Fatal(err)
+	}
+	// Nonces come from the venue's clock, kept strictly increasing
+	if nonce != 1700000000001 {
+		t.Errorf("cancel nonce = %d, want 1700000000001", nonce)
+	}
+	var sig struct {
+		R, S string
+		V    byte
+	}
+	if err := json.Unmarshal(cancel["signature"], &sig); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recompute the signed digest from the msgpack encoding of
+	// {"type":"cancel","cancels":[{"a":1,"o":123}]} and recover the signer
+	packed, _ := hex.DecodeString("82a474797065a663616e63656ca763616e63656c739182a16101a16f7b")
+	packed = binary.BigEndian.AppendUint64(packed, nonce)
+	connectionID := crypto.Keccak256(append(packed, 0))
+	domain := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("Exchange")),
+		crypto.Keccak256([]byte("1")),
+		common.LeftPadBytes([]byte{0x05, 0x39}, 32),
+		make([]byte, 32),
+	)
+	agent := crypto.Keccak256(
+		crypto.Keccak256([]byte("Agent(string source,bytes32 connectionId)")),
+		crypto.Keccak256([]byte("b")),
+		connectionID,
+	)
+	digest := crypto.Keccak256([]byte{0x19, 0x01}, domain, agent)
+	signature := append(append(common.FromHex(sig.R), common.FromHex(sig.S)...), sig.V-27)
+	pub, err := crypto.SigToPub(digest, signature)
+	if err != nil {
+		t.Fatalf("SigToPub: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Error("cancel signature does not recover to the signing key")
+	}
+
+	fills, err := venue.Fills(ctx, primitives.Time{})
+	if err != nil || len(fills) != 1 {
+		t.Fatalf("Fills = %+v, %v", fills, err)
+	}
+	f := fills[0]
+	if f.Pair != "ETH/USD" || f.Side != mechanisms.OrderSideSell || !f.Quantity.Equal(dec("0.5")) ||
+		f.VenueOrderID != "123" || f.TradeID != "9" || !f.Fee.Equal(dec("0.25")) || f.Time.Unix() != 1700000000 {
+		t.Errorf("fill = %+v", f)
+	}
+	if !strings.EqualFold(venue.Name(), "hyperliquid") {
+		t.Errorf("Name = %q", venue.Name())
+	}
+}
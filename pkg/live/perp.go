@@ -0,0 +1,217 @@
+package live
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// PerpPositionID returns the default PerpPosition ID for pair, "perp:<pair>".
+func PerpPositionID(pair string) string {
+	return "perp:" + pair
+}
+
+// PerpPosition is a position in a venue's perpetual contract built from
+// fills. Opening fills move margin, the notional over the leverage, from
+// cash into the position; reducing fills release margin and realize P&L.
+// The position is valued at its margin plus unrealized P&L at the snapshot
+// price of its pair, floored at zero.
+//
+// Thread Safety: PerpPosition is immutable and safe for concurrent use.
+type PerpPosition struct {
+	id         string
+	pair       string
+	size       primitives.Decimal
+	entryPrice primitives.Price
+	margin     primitives.Decimal
+}
+
+// NewPerpPosition creates a position of size contracts (negative for
+// shorts) of pair entered at entryPrice with margin.
+func NewPerpPosition(id, pair string, size primitives.Decimal, entryPrice primitives.Price, margin primitives.Decimal) *PerpPosition {
+	return &PerpPosition{id: id, pair: pair, size: size, entryPrice: entryPrice, margin: margin}
+}
+
+// ID returns the position ID.
+func (p *PerpPosition) ID() string {
+	return p.id
+}
+
+// Type returns PositionTypePerpetual.
+func (p *PerpPosition) Type() strategy.PositionType {
+	return strategy.PositionTypePerpetual
+}
+
+// Pair returns the pair the position is valued at.
+func (p *PerpPosition) Pair() string {
+	return p.pair
+}
+
+// Underlying implements strategy.UnderlyingPosition with the base asset of
+// Pair, or Pair itself if it does not parse.
+func (p *PerpPosition) Underlying() string {
+	pair, err := primitives.ParsePair(p.pair)
+	if err != nil {
+		return p.pair
+	}
+	return pair.Base
+}
+
+// Size returns the signed size (negative for shorts).
+func (p *PerpPosition) Size() primitives.Decimal {
+	return p.size
+}
+
+// EntryPrice returns the average entry price.
+func (p *PerpPosition) EntryPrice() primitives.Price {
+	return p.entryPrice
+}
+
+// Margin returns the margin held against the position.
+func (p *PerpPosition) Margin() primitives.Decimal {
+	return p.margin
+}
+
+// UnrealizedPnL returns Size × (price - EntryPrice).
+func (p *PerpPosition) UnrealizedPnL(price primitives.Price) primitives.Decimal {
+	return price.Decimal().Sub(p.entryPrice.Decimal()).Mul(p.size)
+}
+
+// Value returns margin plus unrealized P&L, floored at zero.
+func (p *PerpPosition) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price(p.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), fmt.Errorf("failed to value perp %s: %w", p.id, err)
+	}
+	equity := p.margin.Add(p.UnrealizedPnL(price))
+	if equity.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(equity)
+}
+
+// Liquidatable implements strategy.LiquidatablePosition: the position is
+// liquidated once losses exhaust its margin. It models no maintenance
+// margin of its own, so venues that liquidate earlier need a stricter
+// wrapper.
+func (p *PerpPosition) Liquidatable(snapshot strategy.MarketSnapshot) (bool, error) {
+	price, err := snapshot.Price(p.pair)
+	if err != nil {
+		return false, fmt.Errorf("failed to check perp %s: %w", p.id, err)
+	}
+	return !p.margin.Add(p.UnrealizedPnL(price)).IsPositive(), nil
+}
+
+// Risk implements strategy.PositionWithRisk: the delta is the size.
+func (p *PerpPosition) Risk(snapshot strategy.MarketSnapshot) (strategy.RiskMetrics, error) {
+	return strategy.RiskMetrics{Delta: p.size, Leverage: primitives.One()}, nil
+}
+
+// PerpFillAction applies a venue fill to the PerpPosition with ID
+// PositionID, creating it on the first fill and removing it when a fill
+// flattens it. Fee is charged to cash.
+type PerpFillAction struct {
+	Fill       strategy.Fill
+	PositionID string
+	Fee        primitives.Decimal
+
+	// Leverage sizes the margin moved into the position as notional over
+	// Leverage; zero means 1
+	Leverage primitives.Decimal
+}
+
+// Apply moves margin, realized P&L and the fee between cash and the
+// position. A fill larger than an opposite position closes it and opens
+// the remainder on the other side at the fill price. The margin becomes
+// the position's cost basis (see strategy.PnL).
+// Returns ErrInvalidAction if the position is not a PerpPosition of the
+// fill's pair.
+func (a *PerpFillAction) Apply(portfolio *strategy.Portfolio) error {
+	if portfolio == nil {
+		return strategy.ErrNilPortfolio
+	}
+	leverage := a.Leverage
+	if !leverage.IsPositive() {
+		leverage = primitives.One()
+	}
+	price := a.Fill.Price
+	quantity := signedQuantity(a.Fill)
+
+	size, margin := primitives.Zero(), primitives.Zero()
+	entry := price
+	existing, _ := portfolio.GetPosition(a.PositionID)
+	if existing != nil {
+		perp, ok := existing.(*PerpPosition)
+		if !ok || perp.pair != a.Fill.Pair {
+			return fmt.Errorf("%w: position %s is not a %s perp", strategy.ErrInvalidAction, a.PositionID, a.Fill.Pair)
+		}
+		size, entry, margin = perp.size, perp.entryPrice, perp.margin
+	}
+
+	cash := a.Fee.Neg()
+	if !size.IsZero() && size.IsNegative() != quantity.IsNegative() {
+		// Reduce: release margin pro rata and realize P&L on the closed part
+		closed := quantity.Abs()
+		if closed.GreaterThan(size.Abs()) {
+			closed = size.Abs()
+		}
+		released, err := margin.Mul(closed).Div(size.Abs())
+		if err != nil {
+			return err
+		}
+		pnl := price.Decimal().Sub(entry.Decimal()).Mul(closed)
+		if size.IsNegative() {
+			pnl = pnl.Neg()
+			quantity = quantity.Sub(closed)
+			size = size.Add(closed)
+		} else {
+			quantity = quantity.Add(closed)
+			size = size.Sub(closed)
+		}
+		margin = margin.Sub(released)
+		cash = cash.Add(released).Add(pnl)
+		if size.IsZero() {
+			entry = price
+		}
+	}
+	if !quantity.IsZero() {
+		// Increase, or open the remainder of a flip, at the fill price
+		added, err := quantity.Abs().Mul(price.Decimal()).Div(leverage)
+		if err != nil {
+			return err
+		}
+		next := size.Add(quantity)
+		weighted, err := size.Mul(entry.Decimal()).Add(quantity.Mul(price.Decimal())).Div(next)
+		if err != nil {
+			return err
+		}
+		if entry, err = primitives.NewPrice(weighted); err != nil {
+			return err
+		}
+		size, margin = next, margin.Add(added)
+		cash = cash.Sub(added)
+	}
+
+	var err error
+	switch {
+	case existing == nil && !size.IsZero():
+		err = portfolio.AddPosition(NewPerpPosition(a.PositionID, a.Fill.Pair, size, entry, margin))
+	case existing != nil && size.IsZero():
+		err = portfolio.RemovePosition(a.PositionID)
+	case existing != nil:
+		err = strategy.NewReplacePositionAction(a.PositionID, NewPerpPosition(a.PositionID, a.Fill.Pair, size, entry, margin)).Apply(portfolio)
+	}
+	if err == nil && !size.IsZero() {
+		err = portfolio.SetCostBasis(a.PositionID, margin)
+	}
+	if err != nil {
+		return err
+	}
+	return portfolio.AdjustCash(cash)
+}
+
+// String returns a description of this action.
+func (a *PerpFillAction) String() string {
+	return fmt.Sprintf("PerpFill(%s %s %s %s @ %s)", a.Fill.OrderID, a.Fill.Side, a.Fill.Quantity, a.Fill.Pair, a.Fill.Price)
+}
@@ -0,0 +1,161 @@
+// Package logging provides the conventions the toolkit uses with log/slog:
+// shared attribute keys so records from the engine, portfolio and order
+// book can be filtered and joined, component-scoped loggers, and a handler
+// that sets a separate minimum level per component.
+//
+// Components log through a *slog.Logger tagged with KeyComponent. A nil
+// logger disables logging at no cost beyond a level check:
+//
+//	handler := logging.NewLevelHandler(
+//		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+//		slog.LevelInfo,
+//		map[string]slog.Leveler{logging.ComponentOrders: slog.LevelDebug},
+//	)
+//	config.Logger = slog.New(handler)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Attribute keys shared by every component.
+const (
+	// KeyComponent names the component that logged a record
+	KeyComponent = "component"
+
+	// KeySnapshot is the index of the snapshot being processed
+	KeySnapshot = "snapshot"
+
+	// KeySnapshotTime is the market time of the snapshot being processed
+	KeySnapshotTime = "snapshot_time"
+
+	// KeyAction describes the action being applied
+	KeyAction = "action"
+
+	// KeyPosition is the ID of the position affected
+	KeyPosition = "position_id"
+
+	// KeyOrder is the ID of the order affected
+	KeyOrder = "order_id"
+
+	// KeyError is the error that caused the record
+	KeyError = "error"
+)
+
+// Components that log.
+const (
+	ComponentEngine    = "engine"
+	ComponentPortfolio = "portfolio"
+	ComponentOrders    = "orders"
+	ComponentLive      = "live"
+)
+
+// For returns logger tagged with component, or a logger that discards
+// everything when logger is nil.
+func For(logger *slog.Logger, component string) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	return logger.With(KeyComponent, component)
+}
+
+// Discard returns a logger whose handler is never enabled.
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// LevelHandler filters records by the minimum level of the component that
+// logged them before passing them to another handler. Components without
+// a level of their own use the fallback level. The wrapped handler's own
+// level still applies, so set it at or below the lowest component level.
+type LevelHandler struct {
+	inner     slog.Handler
+	fallback  slog.Leveler
+	levels    map[string]slog.Leveler
+	component string
+}
+
+// NewLevelHandler creates a handler passing records to inner when their
+// level reaches their component's level in levels, or fallback.
+func NewLevelHandler(inner slog.Handler, fallback slog.Leveler, levels map[string]slog.Leveler) *LevelHandler {
+	if fallback == nil {
+		fallback = slog.LevelInfo
+	}
+	return &LevelHandler{inner: inner, fallback: fallback, levels: levels}
+}
+
+// Enabled reports whether the component's level admits level.
+func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level().Level() && h.inner.Enabled(ctx, level)
+}
+
+// Handle passes the record to the wrapped handler.
+func (h *LevelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler for the attributes' component, if they
+// name one.
+func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.inner = h.inner.WithAttrs(attrs)
+	for _, attr := range attrs {
+		if attr.Key == KeyComponent {
+			next.component = attr.Value.String()
+		}
+	}
+	return &next
+}
+
+// WithGroup returns a handler that qualifies later attributes by name.
+func (h *LevelHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	next.inner = h.inner.WithGroup(name)
+	return &next
+}
+
+func (h *LevelHandler) level() slog.Leveler {
+	if level, ok := h.levels[h.component]; ok {
+		return level
+	}
+	return h.fallback
+}
+
+// ParseLevels parses a level spec such as "info,engine=debug,orders=warn":
+// an optional bare default level followed by component=level pairs. Level
+// names are those accepted by slog.Level.UnmarshalText. The default is
+// info when the spec does not set one.
+func ParseLevels(spec string) (slog.Level, map[string]slog.Leveler, error) {
+	fallback := slog.LevelInfo
+	levels := make(map[string]slog.Leveler)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		component, name, scoped := strings.Cut(part, "=")
+		if !scoped {
+			name = component
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return 0, nil, fmt.Errorf("invalid log level %q: %w", part, err)
+		}
+		if scoped {
+			levels[strings.TrimSpace(component)] = level
+		} else {
+			fallback = level
+		}
+	}
+	return fallback, levels, nil
+}
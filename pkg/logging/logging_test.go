@@ -0,0 +1,80 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
+)
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	inner := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(logging.NewLevelHandler(inner, slog.LevelWarn, map[string]slog.Leveler{
+		logging.ComponentOrders: slog.LevelDebug,
+	}))
+
+	logging.For(logger, logging.ComponentEngine).Info("engine info")
+	logging.For(logger, logging.ComponentEngine).Warn("engine warn")
+	logging.For(logger, logging.ComponentOrders).Debug("orders debug")
+	logging.For(logger, logging.ComponentOrders).WithGroup("fill").Debug("grouped debug")
+	logger.Info("untagged info")
+
+	out := buf.String()
+	for _, want := range []string{"engine warn", "orders debug", "grouped debug", "component=orders"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"engine info", "untagged info"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	for _, logger := range []*slog.Logger{logging.Discard(), logging.For(nil, logging.ComponentEngine)} {
+		if logger.Enabled(context.Background(), slog.LevelError) {
+			t.Error("discard logger is enabled")
+		}
+		logger.With("k", "v").WithGroup("g").Error("dropped")
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		spec     string
+		fallback slog.Level
+		levels   map[string]slog.Level
+		wantErr  bool
+	}{
+		{"", slog.LevelInfo, map[string]slog.Level{}, false},
+		{"debug", slog.LevelDebug, map[string]slog.Level{}, false},
+		{"warn, engine=debug ,orders=ERROR", slog.LevelWarn, map[string]slog.Level{"engine": slog.LevelDebug, "orders": slog.LevelError}, false},
+		{"portfolio=info+2", slog.LevelInfo, map[string]slog.Level{"portfolio": slog.LevelInfo + 2}, false},
+		{"engine=loud", 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			fallback, levels, err := logging.ParseLevels(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if fallback != tt.fallback || len(levels) != len(tt.levels) {
+				t.Fatalf("ParseLevels = %v, %v; want %v, %v", fallback, levels, tt.fallback, tt.levels)
+			}
+			for component, want := range tt.levels {
+				if got := levels[component]; got == nil || got.Level() != want {
+					t.Errorf("level of %s = %v, want %v", component, got, want)
+				}
+			}
+		})
+	}
+}
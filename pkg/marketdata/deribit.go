@@ -0,0 +1,341 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ErrDeribitResponse indicates the Deribit API returned an error or a
+// response that could not be decoded.
+var ErrDeribitResponse = errors.New("invalid deribit response")
+
+// DeribitEndpoint is the base URL of Deribit's production API.
+const DeribitEndpoint = "https://www.deribit.com"
+
+// deribitPageSize is the most trades Deribit returns per request.
+const deribitPageSize = 1000
+
+// DeribitOptions reads option chains for one currency from Deribit's public
+// API, which needs no API key: the live chain from the book summary, and
+// historical chains rebuilt from option trades.
+//
+// Deribit does not publish historical marks, so a historical chain quotes
+// each instrument at its latest trade: MarkIV is the traded IV, MarkPrice
+// the trade price and Underlying the index price at the trade. Greeks are
+// computed from the IV (see OptionQuote.Greeks) for live and historical
+// chains alike. Prices of Deribit's inverse options are in the base coin.
+type DeribitOptions struct {
+	// Endpoint is the API base URL (DeribitEndpoint, or
+	// https://test.deribit.com for the testnet)
+	Endpoint string
+
+	// Currency is the Deribit currency whose options are read (e.g., "ETH")
+	Currency string
+
+	// Pair is the underlying market the chains are keyed by in snapshots
+	// (e.g., ETH/USD)
+	Pair primitives.Pair
+
+	// MaxTrades bounds the trades read per HistoricalChains call (default
+	// 100000)
+	MaxTrades int
+
+	// Client is the HTTP client used (a client with a 30s timeout if nil)
+	Client *http.Client
+}
+
+// NewDeribitOptions creates a Deribit option chain reader.
+func NewDeribitOptions(endpoint, currency string, pair primitives.Pair) *DeribitOptions {
+	return &DeribitOptions{Endpoint: endpoint, Currency: currency, Pair: pair}
+}
+
+// deribitSummary is an entry of public/get_book_summary_by_currency.
+type deribitSummary struct {
+	InstrumentName         string      `json:"instrument_name"`
+	MarkIV                 json.Number `json:"mark_iv"`
+	MarkPrice              json.Number `json:"mark_price"`
+	UnderlyingPrice        json.Number `json:"underlying_price"`
+	EstimatedDeliveryPrice json.Number `json:"estimated_delivery_price"`
+	OpenInterest           json.Number `json:"open_interest"`
+	CreationTimestamp      int64       `json:"creation_timestamp"`
+}
+
+// deribitTrade is a trade of public/get_last_trades_by_currency_and_time.
+type deribitTrade struct {
+	TradeID        string      `json:"trade_id"`
+	InstrumentName string      `json:"instrument_name"`
+	Timestamp      int64       `json:"timestamp"`
+	Price          json.Number `json:"price"`
+	IV             json.Number `json:"iv"`
+	IndexPrice     json.Number `json:"index_price"`
+}
+
+// Chain returns the live option chain: every listed option with its mark
+// IV, mark price and open interest, stamped with the summary time.
+func (d *DeribitOptions) Chain(ctx context.Context) (*OptionChain, error) {
+	var resp struct {
+		Result []deribitSummary `json:"result"`
+	}
+	query := url.Values{"currency": {d.Currency}, "kind": {"option"}}
+	if err := d.get(ctx, "public/get_book_summary_by_currency", query, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Result) == 0 {
+		return nil, fmt.Errorf("%w: no %s options listed", ErrDeribitResponse, d.Currency)
+	}
+
+	chain := &OptionChain{Pair: d.Pair}
+	var latest int64
+	for _, row := range resp.Result {
+		quote, err := deribitQuote(row.InstrumentName, row.MarkIV, row.MarkPrice, row.UnderlyingPrice)
+		if err != nil {
+			return nil, err
+		}
+		if quote.OpenInterest, err = deribitDecimal(row.OpenInterest); err != nil {
+			return nil, err
+		}
+		chain.Quotes = append(chain.Quotes, quote)
+		if row.CreationTimestamp > latest {
+			latest = row.CreationTimestamp
+			index := row.EstimatedDeliveryPrice
+			if index == "" {
+				index = row.UnderlyingPrice
+			}
+			if chain.Underlying, err = deribitPrice(index); err != nil {
+				return nil, err
+			}
+		}
+	}
+	chain.Time = deribitTime(latest)
+	sortQuotes(chain.Quotes)
+	if err := computeGreeks(chain.Quotes, chain.Time); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// HistoricalChains rebuilds the chain at each grid time from the option
+// trades in the lookback window before it: each unexpired instrument
+// traded within lookback of the grid time is quoted at its latest trade,
+// and the chain's underlying is the index price at the latest of those
+// trades. Grid times with no such trades are skipped.
+//
+// Returns ErrInvalidStep if lookback is not positive and ErrUnsortedStream
+// if the grid is not in time order.
+func (d *DeribitOptions) HistoricalChains(ctx context.Context, grid []primitives.Time, lookback primitives.Duration) ([]*OptionChain, error) {
+	if lookback.Duration() <= 0 {
+		return nil, ErrInvalidStep
+	}
+	if len(grid) == 0 {
+		return nil, nil
+	}
+	for i := 1; i < len(grid); i++ {
+		if grid[i].Before(grid[i-1]) {
+			return nil, fmt.Errorf("%w: grid time %d at %s precedes grid time %d", ErrUnsortedStream, i, grid[i], i-1)
+		}
+	}
+
+	trades, err := d.trades(ctx, grid[0].Add(lookback.Mul(-1)), grid[len(grid)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]deribitTrade)
+	var chains []*OptionChain
+	next := 0
+	for _, t := range grid {
+		for next < len(trades) && !deribitTime(trades[next].Timestamp).After(t) {
+			latest[trades[next].InstrumentName] = trades[next]
+			next++
+		}
+
+		chain := &OptionChain{Pair: d.Pair, Time: t}
+		var newest int64
+		for name, trade := range latest {
+			if t.Sub(deribitTime(trade.Timestamp)).Duration() > lookback.Duration() {
+				delete(latest, name)
+				continue
+			}
+			quote, err := deribitQuote(name, trade.IV, trade.Price, trade.IndexPrice)
+			if err != nil {
+				return nil, err
+			}
+			if !quote.Expiry.After(t) {
+				delete(latest, name)
+				continue
+			}
+			chain.Quotes = append(chain.Quotes, quote)
+			if trade.Timestamp > newest || (trade.Timestamp == newest && quote.Underlying.Decimal().GreaterThan(chain.Underlying.Decimal())) {
+				newest, chain.Underlying = trade.Timestamp, quote.Underlying
+			}
+		}
+		if len(chain.Quotes) == 0 {
+			continue
+		}
+		sortQuotes(chain.Quotes)
+		if err := computeGreeks(chain.Quotes, t); err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+// trades reads the option trades in [from, to] in time order, paging
+// through Deribit's results.
+func (d *DeribitOptions) trades(ctx context.Context, from, to primitives.Time) ([]deribitTrade, error) {
+	limit := d.MaxTrades
+	if limit <= 0 {
+		limit = 100000
+	}
+	seen := make(map[string]bool)
+	var trades []deribitTrade
+	start := from.Time().UnixMilli()
+	end := to.Time().UnixMilli()
+	for {
+		var resp struct {
+			Result struct {
+				Trades  []deribitTrade `json:"trades"`
+				HasMore bool           `json:"has_more"`
+			} `json:"result"`
+		}
+		query := url.Values{
+			"currency":        {d.Currency},
+			"kind":            {"option"},
+			"start_timestamp": {strconv.FormatInt(start, 10)},
+			"end_timestamp":   {strconv.FormatInt(end, 10)},
+			"count":           {strconv.Itoa(deribitPageSize)},
+			"sorting":         {"asc"},
+		}
+		if err := d.get(ctx, "public/get_last_trades_by_currency_and_time", query, &resp); err != nil {
+			return nil, fmt.Errorf("failed to read trades from %s: %w", deribitTime(start), err)
+		}
+
+		added := 0
+		for _, trade := range resp.Result.Trades {
+			if seen[trade.TradeID] {
+				continue
+			}
+			seen[trade.TradeID] = true
+			trades = append(trades, trade)
+			added++
+			if len(trades) >= limit {
+				return trades, nil
+			}
+		}
+		// Pages overlap at their boundary millisecond; a page with nothing
+		// new cannot advance
+		if !resp.Result.HasMore || added == 0 {
+			return trades, nil
+		}
+		start = resp.Result.Trades[len(resp.Result.Trades)-1].Timestamp
+	}
+}
+
+// get calls a public API method and decodes its response into out.
+func (d *DeribitOptions) get(ctx context.Context, method string, query url.Values, out interface{}) error {
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = DeribitEndpoint
+	}
+	u := strings.TrimSuffix(endpoint, "/") + "/api/v2/" + method + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	return doJSONAs(d.Client, req, out, ErrDeribitResponse)
+}
+
+// deribitQuote builds a quote from an instrument name, an IV in percent
+// and the option and underlying prices.
+func deribitQuote(instrument string, iv, price, underlying json.Number) (OptionQuote, error) {
+	quote := OptionQuote{Instrument: instrument}
+	var err error
+	if quote.Type, quote.Strike, quote.Expiry, err = ParseDeribitInstrument(instrument); err != nil {
+		return OptionQuote{}, err
+	}
+	percent, err := deribitDecimal(iv)
+	if err != nil {
+		return OptionQuote{}, err
+	}
+	quote.MarkIV = percent.Mul(primitives.MustDecimalFromString("0.01"))
+	if quote.MarkPrice, err = deribitDecimal(price); err != nil {
+		return OptionQuote{}, err
+	}
+	if quote.Underlying, err = deribitPrice(underlying); err != nil {
+		return OptionQuote{}, err
+	}
+	return quote, nil
+}
+
+// ParseDeribitInstrument parses a Deribit option instrument name such as
+// "ETH-27DEC24-3000-C" or "XRP_USDC-30AUG24-0d625-P" (a "d" marks the
+// decimal point of a strike). Options expire at 08:00 UTC on their date.
+func ParseDeribitInstrument(name string) (mechanisms.OptionType, primitives.Price, primitives.Time, error) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 4 {
+		return "", primitives.Price{}, primitives.Time{}, fmt.Errorf("%w: %q is not an option instrument", ErrDeribitResponse, name)
+	}
+	date, err := time.Parse("2Jan06", parts[1])
+	if err != nil {
+		return "", primitives.Price{}, primitives.Time{}, fmt.Errorf("%w: bad expiry in %q: %s", ErrDeribitResponse, name, err)
+	}
+	strikeValue, err := primitives.NewDecimalFromString(strings.ReplaceAll(parts[2], "d", "."))
+	if err != nil {
+		return "", primitives.Price{}, primitives.Time{}, fmt.Errorf("%w: bad strike in %q: %s", ErrDeribitResponse, name, err)
+	}
+	strike, err := primitives.NewPrice(strikeValue)
+	if err != nil {
+		return "", primitives.Price{}, primitives.Time{}, fmt.Errorf("%w: bad strike in %q: %s", ErrDeribitResponse, name, err)
+	}
+	var optionType mechanisms.OptionType
+	switch parts[3] {
+	case "C":
+		optionType = mechanisms.OptionTypeCall
+	case "P":
+		optionType = mechanisms.OptionTypePut
+	default:
+		return "", primitives.Price{}, primitives.Time{}, fmt.Errorf("%w: bad option type in %q", ErrDeribitResponse, name)
+	}
+	return optionType, strike, primitives.NewTime(date.Add(8 * time.Hour)), nil
+}
+
+// deribitDecimal parses a JSON number, treating null as zero.
+func deribitDecimal(n json.Number) (primitives.Decimal, error) {
+	if n == "" {
+		return primitives.Zero(), nil
+	}
+	d, err := primitives.NewDecimalFromString(string(n))
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("%w: bad number %q", ErrDeribitResponse, n)
+	}
+	return d, nil
+}
+
+// deribitPrice parses a JSON number as a price.
+func deribitPrice(n json.Number) (primitives.Price, error) {
+	d, err := deribitDecimal(n)
+	if err != nil {
+		return primitives.Price{}, err
+	}
+	price, err := primitives.NewPrice(d)
+	if err != nil {
+		return primitives.Price{}, fmt.Errorf("%w: %s", ErrDeribitResponse, err)
+	}
+	return price, nil
+}
+
+// deribitTime converts a Deribit millisecond timestamp.
+func deribitTime(ms int64) primitives.Time {
+	return primitives.NewTime(time.UnixMilli(ms).UTC())
+}
@@ -0,0 +1,67 @@
+package marketdata
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Coalesce folds a tick-level event stream into one snapshot per step, for
+// strategies that only understand snapshots.
+//
+// Buckets are right-closed intervals (g-step, g] on a grid starting at the
+// first event's time, as in Downsample. Each bucket emits a
+// strategy.OHLCVSnapshot stamped with its grid time g, built by a
+// strategy.MarketState from every event up to g: bars aggregate the
+// bucket's trades, while prices and funding rates carry forward from
+// earlier buckets. Empty buckets are skipped.
+func Coalesce(events []strategy.Event, step primitives.Duration) ([]strategy.MarketSnapshot, error) {
+	if len(events) == 0 {
+		return nil, ErrEmptyStream
+	}
+	if step.Duration() <= 0 {
+		return nil, ErrInvalidStep
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Time().Before(events[i-1].Time()) {
+			return nil, fmt.Errorf("%w: event %d at %s precedes event %d",
+				ErrUnsortedStream, i, events[i].Time(), i-1)
+		}
+	}
+
+	state := strategy.NewMarketState()
+	out := make([]strategy.MarketSnapshot, 0)
+	bucketEnd := events[0].Time()
+	pending := false
+
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		snap, err := state.Snapshot(bucketEnd)
+		if err != nil {
+			return fmt.Errorf("failed to coalesce events at %s: %w", bucketEnd, err)
+		}
+		out = append(out, snap)
+		pending = false
+		return nil
+	}
+
+	for _, event := range events {
+		if event.Time().After(bucketEnd) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			// Advance to the first grid time at or after this event
+			steps := (event.Time().Sub(bucketEnd).Duration() + step.Duration() - 1) / step.Duration()
+			bucketEnd = bucketEnd.Add(step.Mul(int64(steps)))
+		}
+		state.Apply(event)
+		pending = true
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,830 @@
+package marketdata_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/marketdata"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var testStart = primitives.Unix(1700000000, 0)
+
+func px(s string) primitives.Price {
+	return primitives.MustPrice(primitives.MustDecimalFromString(s))
+}
+
+func snap(offset primitives.Duration, pair, price string) strategy.MarketSnapshot {
+	return strategy.NewSimpleSnapshot(testStart.Add(offset), map[string]primitives.Price{pair: px(price)})
+}
+
+func bar(offset primitives.Duration, open, high, low, close, volume string) strategy.MarketSnapshot {
+	s, err := strategy.NewOHLCVSnapshot(testStart.Add(offset), map[string]strategy.Bar{
+		"ETH/USD": {
+			Open:   px(open),
+			High:   px(high),
+			Low:    px(low),
+			Close:  px(close),
+			Volume: primitives.MustAmount(primitives.MustDecimalFromString(volume)),
+		},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func priceAt(t *testing.T, s strategy.MarketSnapshot, pair string) string {
+	t.Helper()
+	p, err := s.Price(pair)
+	if err != nil {
+		t.Fatalf("price %s: %v", pair, err)
+	}
+	return p.String()
+}
+
+func TestGrid(t *testing.T) {
+	grid, err := marketdata.Grid(testStart, testStart.Add(primitives.Hours(1)), primitives.Minutes(15))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(grid) != 5 {
+		t.Errorf("expected 5 grid points, got %d", len(grid))
+	}
+	if _, err := marketdata.Grid(testStart, testStart, primitives.Duration{}); !errors.Is(err, marketdata.ErrInvalidStep) {
+		t.Errorf("expected %v, got %v", marketdata.ErrInvalidStep, err)
+	}
+}
+
+func TestForwardFill(t *testing.T) {
+	stream := []strategy.MarketSnapshot{
+		snap(primitives.Minutes(5), "ETH/USD", "100"),
+		snap(primitives.Minutes(20), "ETH/USD", "110"),
+	}
+	stream[0].(*strategy.SimpleSnapshot).Set("k", "v")
+
+	grid, _ := marketdata.Grid(testStart, testStart.Add(primitives.Hours(1)), primitives.Minutes(15))
+
+	t.Run("fills from latest observation", func(t *testing.T) {
+		out, err := marketdata.ForwardFill(stream, grid, primitives.Duration{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Grid point at +0 precedes the first observation and is dropped
+		want := []string{"100", "110", "110", "110"}
+		if len(out) != len(want) {
+			t.Fatalf("expected %d snapshots, got %d", len(want), len(out))
+		}
+		for i, w := range want {
+			if got := priceAt(t, out[i], "ETH/USD"); got != w {
+				t.Errorf("snapshot %d: expected %s, got %s", i, w, got)
+			}
+			if !out[i].Time().Equal(grid[i+1]) {
+				t.Errorf("snapshot %d: expected time %s, got %s", i, grid[i+1], out[i].Time())
+			}
+		}
+		if v, ok := out[0].Get("k"); !ok || v != "v" {
+			t.Errorf("expected metadata to be carried, got %v, %v", v, ok)
+		}
+	})
+
+	t.Run("respects max staleness", func(t *testing.T) {
+		out, err := marketdata.ForwardFill(stream, grid, primitives.Minutes(20))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// +15, +30 are fresh; +45 and +60 are more than 20m after +20
+		if len(out) != 2 {
+			t.Errorf("expected 2 snapshots, got %d", len(out))
+		}
+	})
+
+	t.Run("rejects unsorted stream", func(t *testing.T) {
+		unsorted := []strategy.MarketSnapshot{stream[1], stream[0]}
+		if _, err := marketdata.ForwardFill(unsorted, grid, primitives.Duration{}); !errors.Is(err, marketdata.ErrUnsortedStream) {
+			t.Errorf("expected %v, got %v", marketdata.ErrUnsortedStream, err)
+		}
+	})
+}
+
+func TestUpsample(t *testing.T) {
+	stream := []strategy.MarketSnapshot{
+		snap(primitives.Duration{}, "ETH/USD", "100"),
+		snap(primitives.Hours(1), "ETH/USD", "120"),
+	}
+	out, err := marketdata.Upsample(stream, primitives.Minutes(15))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"100", "100", "100", "100", "120"}
+	if len(out) != len(want) {
+		t.Fatalf("expected %d snapshots, got %d", len(want), len(out))
+	}
+	for i, w := range want {
+		if got := priceAt(t, out[i], "ETH/USD"); got != w {
+			t.Errorf("snapshot %d: expected %s, got %s", i, w, got)
+		}
+	}
+	if _, err := marketdata.Upsample(nil, primitives.Minutes(1)); !errors.Is(err, marketdata.ErrEmptyStream) {
+		t.Errorf("expected %v, got %v", marketdata.ErrEmptyStream, err)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	t.Run("aggregates bars", func(t *testing.T) {
+		stream := []strategy.MarketSnapshot{
+			bar(primitives.Duration{}, "100", "101", "99", "100", "1"),
+			bar(primitives.Minutes(1), "100", "105", "98", "104", "2"),
+			bar(primitives.Minutes(2), "104", "106", "103", "103", "3"),
+			bar(primitives.Minutes(3), "103", "104", "90", "95", "4"),
+		}
+		out, err := marketdata.Downsample(stream, primitives.Minutes(2))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Buckets: [+0], (+0,+2], (+2,+4]
+		if len(out) != 3 {
+			t.Fatalf("expected 3 snapshots, got %d", len(out))
+		}
+		bs, ok := out[1].(strategy.BarSnapshot)
+		if !ok {
+			t.Fatal("expected aggregated snapshot to implement BarSnapshot")
+		}
+		b, err := bs.Bar("ETH/USD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := []string{b.Open.String(), b.High.String(), b.Low.String(), b.Close.String(), b.Volume.String()}
+		want := []string{"100", "106", "98", "103", "5"}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("field %d: expected %s, got %s", i, want[i], got[i])
+			}
+		}
+		if !out[2].Time().Equal(testStart.Add(primitives.Minutes(4))) {
+			t.Errorf("expected last bucket labelled +4m, got %s", out[2].Time())
+		}
+	})
+
+	t.Run("takes last close-only snapshot", func(t *testing.T) {
+		stream := []strategy.MarketSnapshot{
+			snap(primitives.Duration{}, "ETH/USD", "100"),
+			snap(primitives.Minutes(10), "ETH/USD", "101"),
+			snap(primitives.Minutes(50), "ETH/USD", "102"),
+			snap(primitives.Minutes(55), "ETH/USD", "103"),
+		}
+		out, err := marketdata.Downsample(stream, primitives.Minutes(30))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{"100", "101", "103"}
+		if len(out) != len(want) {
+			t.Fatalf("expected %d snapshots, got %d", len(want), len(out))
+		}
+		for i, w := range want {
+			if got := priceAt(t, out[i], "ETH/USD"); got != w {
+				t.Errorf("snapshot %d: expected %s, got %s", i, w, got)
+			}
+		}
+	})
+}
+
+func TestCoalesce(t *testing.T) {
+	trade := func(offset primitives.Duration, price, size string) strategy.Event {
+		return strategy.TradeEvent{At: testStart.Add(offset), Pair: "ETH/USD", Price: px(price),
+			Size: primitives.MustAmount(primitives.MustDecimalFromString(size))}
+	}
+	events := []strategy.Event{
+		trade(primitives.Duration{}, "100", "1"),
+		trade(primitives.Seconds(20), "103", "2"),
+		strategy.FundingEvent{At: testStart.Add(primitives.Seconds(30)), Symbol: "ETH-PERP", Rate: primitives.MustDecimalFromString("0.0001")},
+		trade(primitives.Seconds(50), "98", "1"),
+		trade(primitives.Seconds(60), "99", "3"),
+		trade(primitives.Minutes(3), "101", "1"),
+	}
+	out, err := marketdata.Coalesce(events, primitives.Minutes(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Buckets: [+0], (+0,+1m], (+2m,+3m]; (+1m,+2m] is empty
+	if len(out) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(out))
+	}
+	b, err := out[1].(strategy.BarSnapshot).Bar("ETH/USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := []string{b.Open.String(), b.High.String(), b.Low.String(), b.Close.String(), b.Volume.String()}
+	want := []string{"103", "103", "98", "99", "6"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+	if !out[2].Time().Equal(testStart.Add(primitives.Minutes(3))) || priceAt(t, out[2], "ETH/USD") != "101" {
+		t.Errorf("unexpected last snapshot at %s", out[2].Time())
+	}
+	if _, ok := out[2].Get(snapshotkeys.PerpFundingRate("ETH-PERP")); !ok {
+		t.Error("expected funding rate carried into later buckets")
+	}
+
+	if _, err := marketdata.Coalesce(nil, primitives.Minutes(1)); !errors.Is(err, marketdata.ErrEmptyStream) {
+		t.Errorf("expected ErrEmptyStream, got %v", err)
+	}
+	if _, err := marketdata.Coalesce([]strategy.Event{events[1], events[0]}, primitives.Minutes(1)); !errors.Is(err, marketdata.ErrUnsortedStream) {
+		t.Errorf("expected ErrUnsortedStream, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	spot := []strategy.MarketSnapshot{
+		snap(primitives.Duration{}, "ETH/USD", "100"),
+		snap(primitives.Minutes(1), "ETH/USD", "101"),
+		snap(primitives.Minutes(2), "ETH/USD", "102"),
+	}
+	funding := []strategy.MarketSnapshot{
+		snap(primitives.Minutes(1), "ETH-PERP", "100.5"),
+	}
+	funding[0].(*strategy.SimpleSnapshot).Set("perp:eth:funding_rate", "0.0001")
+
+	grid, _ := marketdata.Grid(testStart, testStart.Add(primitives.Minutes(2)), primitives.Minutes(1))
+	out, err := marketdata.Merge(grid, primitives.Duration{}, spot, funding)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// +0 is dropped: the funding stream has no data yet
+	if len(out) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(out))
+	}
+	if got := priceAt(t, out[1], "ETH/USD"); got != "102" {
+		t.Errorf("expected spot 102, got %s", got)
+	}
+	if got := priceAt(t, out[1], "ETH-PERP"); got != "100.5" {
+		t.Errorf("expected forward-filled perp 100.5, got %s", got)
+	}
+	if _, ok := out[1].Get("perp:eth:funding_rate"); !ok {
+		t.Error("expected funding metadata to be merged")
+	}
+}
+
+func TestOracleSnapshots(t *testing.T) {
+	eth, btc := primitives.MustPair("ETH/USD"), primitives.MustPair("BTC/USD")
+	updates := []marketdata.OracleUpdate{
+		{Pair: eth, Price: px("2090"), PublishTime: testStart.Add(primitives.Seconds(90)), Round: 2},
+		{Pair: eth, Price: px("2000"), Confidence: px("1.5"), PublishTime: testStart, Round: 1},
+		{Pair: btc, Price: px("40000"), PublishTime: testStart.Add(primitives.Seconds(10)), Round: 7},
+	}
+	grid := []primitives.Time{testStart.Add(primitives.Seconds(60)), testStart.Add(primitives.Seconds(120))}
+
+	snapshots, err := marketdata.OracleSnapshots(updates, grid, primitives.Seconds(100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	first := snapshots[0]
+	if priceAt(t, first, "ETH/USD") != "2000" || priceAt(t, first, "BTC/USD") != "40000" {
+		t.Errorf("unexpected prices %v", first.Prices())
+	}
+	if published, ok := first.(strategy.PriceTimestamper).PriceTime("ETH/USD"); !ok || !published.Equal(testStart) {
+		t.Errorf("expected ETH price time %s, got %s", testStart, published)
+	}
+	if conf, err := strategy.GetDecimal(first, snapshotkeys.OracleConfidence("ETH/USD")); err != nil || conf.String() != "1.5" {
+		t.Errorf("expected confidence 1.5, got %s (%v)", conf, err)
+	}
+	if round, ok := first.Get(snapshotkeys.OracleRound("BTC/USD")); !ok || round != uint64(7) {
+		t.Errorf("expected BTC round 7, got %v", round)
+	}
+
+	second := snapshots[1]
+	if priceAt(t, second, "ETH/USD") != "2090" {
+		t.Errorf("expected latest ETH update, got %v", second.Prices())
+	}
+	if _, err := second.Price("BTC/USD"); err == nil {
+		t.Error("expected stale BTC update to be dropped")
+	}
+
+	reversed := []primitives.Time{grid[1], grid[0]}
+	if _, err := marketdata.OracleSnapshots(updates, reversed, primitives.Duration{}); !errors.Is(err, marketdata.ErrUnsortedStream) {
+		t.Errorf("expected %v, got %v", marketdata.ErrUnsortedStream, err)
+	}
+}
+
+// word encodes v as a 32-byte ABI word in hex.
+func word(v *big.Int) string {
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return fmt.Sprintf("%064x", v)
+}
+
+func TestChainlinkFeed(t *testing.T) {
+	phase := new(big.Int).Lsh(big.NewInt(1), 64)
+	roundID := func(n int64) *big.Int { return new(big.Int).Add(phase, big.NewInt(n)) }
+	round := func(n int64) string {
+		answer := big.NewInt(2000 + n)
+		answer.Mul(answer, big.NewInt(100000000))
+		updated := big.NewInt(testStart.Add(primitives.Hours(n)).Unix())
+		return "0x" + word(roundID(n)) + word(answer) + word(updated) + word(updated) + word(roundID(n))
+	}
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req struct {
+			Method string `json:"method"`
+			Params []json.RawMessage
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_call" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		_ = json.Unmarshal(req.Params[0], &call)
+
+		var result string
+		switch {
+		case call.Data == "0x313ce567":
+			result = "0x" + word(big.NewInt(8))
+		case call.Data == "0xfeaf968c":
+			result = round(5)
+		case strings.HasPrefix(call.Data, "0x9a6fc8f5"):
+			id, _ := new(big.Int).SetString(call.Data[10:], 16)
+			result = round(new(big.Int).Sub(id, phase).Int64())
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": -32000, "message": "execution reverted"},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	defer server.Close()
+
+	feed := marketdata.NewChainlinkFeed(server.URL, "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419", primitives.MustPair("ETH/USD"))
+	updates, err := feed.Updates(context.Background(), testStart.Add(primitives.Hours(2)), testStart.Add(primitives.Hours(4)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("expected rounds 2-4, got %d updates", len(updates))
+	}
+	for i, u := range updates {
+		n := int64(i + 2)
+		if u.Price.String() != strconv.FormatInt(2000+n, 10) || u.Round != uint64(n) ||
+			!u.PublishTime.Equal(testStart.Add(primitives.Hours(n))) {
+			t.Errorf("update %d = %+v", i, u)
+		}
+	}
+	// decimals, latest round and rounds 4..1
+	if calls != 6 {
+		t.Errorf("expected 6 RPC calls, got %d", calls)
+	}
+
+	t.Run("http error", func(t *testing.T) {
+		missing := httptest.NewServer(http.NotFoundHandler())
+		defer missing.Close()
+		feed := marketdata.NewChainlinkFeed(missing.URL, "0x0", primitives.MustPair("ETH/USD"))
+		if _, err := feed.Updates(context.Background(), testStart, testStart); !errors.Is(err, marketdata.ErrOracleResponse) {
+			t.Errorf("expected %v, got %v", marketdata.ErrOracleResponse, err)
+		}
+	})
+}
+
+func TestPythFeed(t *testing.T) {
+	const id = "ff61491a931112ddf1bd8147cd1b641375f79f5825126d665480874634fd0ace"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/v2/updates/price/"), 10, 64)
+		if err != nil || r.URL.Query().Get("ids[]") != id {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Updates land on 20-minute boundaries, so a 10-minute sample can
+		// resolve to the same update as the one before it
+		published := testStart.Unix() + (ts-testStart.Unix()+1199)/1200*1200
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"parsed": []map[string]interface{}{{
+				"id": id,
+				"price": map[string]interface{}{
+					"price": strconv.FormatInt(200000000000+published-testStart.Unix(), 10), "conf": "150000000",
+					"expo": -8, "publish_time": published,
+				},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	feed := marketdata.NewPythFeed(server.URL, id, primitives.MustPair("ETH/USD"), primitives.Minutes(10))
+	updates, err := feed.Updates(context.Background(), testStart, testStart.Add(primitives.Hours(1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var published []int64
+	for _, u := range updates {
+		published = append(published, u.PublishTime.Unix()-testStart.Unix())
+		if u.Confidence.String() != "1.5" {
+			t.Errorf("expected confidence 1.5, got %s", u.Confidence)
+		}
+	}
+	if fmt.Sprint(published) != "[0 1200 2400 3600]" {
+		t.Errorf("expected one update per 20 minutes, got offsets %v", published)
+	}
+	if updates[1].Price.String() != "2000.000012" {
+		t.Errorf("expected price 2000.000012, got %s", updates[1].Price)
+	}
+
+	feed.ID = "00"
+	if _, err := feed.Updates(context.Background(), testStart, testStart); !errors.Is(err, marketdata.ErrOracleResponse) {
+		t.Errorf("expected %v, got %v", marketdata.ErrOracleResponse, err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	depthSnap := strategy.NewSimpleDepthSnapshot(testStart, map[string]primitives.Price{"ETH/USD": px("2000.5")})
+	depthSnap.SetPriceTime("ETH/USD", testStart.Add(primitives.Seconds(-1)))
+	depthSnap.Set("source", "binance")
+	depthSnap.Set("block", 42)
+	depthSnap.Set("funding", primitives.MustDecimalFromString("0.0001"))
+	depthSnap.Set("basis", 0.25)
+	if err := depthSnap.SetDepth("ETH/USD", mechanisms.OrderBookDepth{
+		Bids: []mechanisms.PriceLevel{{Price: px("2000"), Size: primitives.MustAmount(primitives.NewDecimal(3))}},
+		Asks: []mechanisms.PriceLevel{{Price: px("2001"), Size: primitives.MustAmount(primitives.NewDecimal(2))}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	feed := make(chan strategy.MarketSnapshot, 2)
+	feed <- depthSnap
+	feed <- snap(primitives.Minutes(1), "ETH/USD", "2010")
+	close(feed)
+
+	var buf bytes.Buffer
+	recorder := marketdata.NewRecorder(&buf)
+	var forwarded int
+	for range recorder.Tee(context.Background(), feed) {
+		forwarded++
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if forwarded != 2 || recorder.Count() != 2 || recorder.Err() != nil {
+		t.Fatalf("forwarded %d, recorded %d (%v), want 2", forwarded, recorder.Count(), recorder.Err())
+	}
+
+	snapshots, err := marketdata.ReadSnapshots(&buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshots: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("replayed %d snapshots, want 2", len(snapshots))
+	}
+
+	first := snapshots[0]
+	if !first.Time().Equal(testStart) {
+		t.Errorf("time %s, want %s", first.Time(), testStart)
+	}
+	if price, err := first.Price("ETH/USD"); err != nil || !price.Equal(px("2000.5")) {
+		t.Errorf("price %s (%v), want 2000.5", price, err)
+	}
+	if at, ok := first.(strategy.PriceTimestamper).PriceTime("ETH/USD"); !ok || !at.Equal(testStart.Add(primitives.Seconds(-1))) {
+		t.Errorf("price time %s (%v), want a second before the snapshot", at, ok)
+	}
+	depths, ok := first.(strategy.DepthSnapshot)
+	if !ok {
+		t.Fatalf("expected depth on the replayed snapshot, got %T", first)
+	}
+	if depth, err := depths.Depth("ETH/USD"); err != nil || len(depth.Bids) != 1 || !depth.Asks[0].Price.Equal(px("2001")) {
+		t.Errorf("depth %+v (%v)", depth, err)
+	}
+	if source, err := strategy.GetString(first, "source"); err != nil || source != "binance" {
+		t.Errorf("source %q (%v)", source, err)
+	}
+	if block, err := strategy.GetInt(first, "block"); err != nil || block != 42 {
+		t.Errorf("block %d (%v)", block, err)
+	}
+	for key, want := range map[string]string{"funding": "0.0001", "basis": "0.25"} {
+		if got, err := strategy.GetDecimal(first, key); err != nil || !got.Equal(primitives.MustDecimalFromString(want)) {
+			t.Errorf("%s = %s (%v), want %s", key, got, err, want)
+		}
+	}
+
+	if _, ok := snapshots[1].(strategy.DepthSnapshot); ok {
+		t.Errorf("expected no depth on a snapshot recorded without it")
+	}
+	if price, err := snapshots[1].Price("ETH/USD"); err != nil || !price.Equal(px("2010")) {
+		t.Errorf("second price %s (%v), want 2010", price, err)
+	}
+
+	if _, err := marketdata.NewReplayer(strings.NewReader("not gzip")); err == nil {
+		t.Errorf("expected an error opening an uncompressed stream")
+	}
+}
+
+func TestDeribitOptions(t *testing.T) {
+	ms := func(offset primitives.Duration) int64 { return testStart.Add(offset).Time().UnixMilli() }
+	trade := func(id, instrument string, offset primitives.Duration, iv, index float64) map[string]interface{} {
+		return map[string]interface{}{
+			"trade_id": id, "instrument_name": instrument, "timestamp": ms(offset),
+			"price": 0.05, "iv": iv, "index_price": index, "amount": 1,
+		}
+	}
+	from := ms(primitives.Duration{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("currency") != "ETH" || q.Get("kind") != "option" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/public/get_book_summary_by_currency":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": []map[string]interface{}{
+				{"instrument_name": "ETH-29DEC23-2200-C", "mark_iv": 60, "mark_price": 0.04, "underlying_price": 2005,
+					"estimated_delivery_price": 2000, "open_interest": 12, "creation_timestamp": ms(primitives.Duration{})},
+				{"instrument_name": "ETH-1DEC23-2000-P", "mark_iv": 50, "mark_price": 0.03, "underlying_price": 2001,
+					"estimated_delivery_price": 2000, "open_interest": 7, "creation_timestamp": ms(primitives.Duration{})},
+				{"instrument_name": "ETH-1DEC23-2000-C", "mark_iv": 50, "mark_price": 0.03, "underlying_price": 2001,
+					"estimated_delivery_price": 2000, "open_interest": 3, "creation_timestamp": ms(primitives.Duration{})},
+			}})
+		case "/api/v2/public/get_last_trades_by_currency_and_time":
+			// The second page repeats the first page's boundary trade
+			result := map[string]interface{}{"has_more": false, "trades": []interface{}{
+				trade("2", "ETH-29DEC23-2200-C", primitives.Minutes(30), 60, 2010),
+				trade("3", "ETH-1DEC23-2000-P", primitives.Minutes(150), 55, 2020),
+			}}
+			if q.Get("start_timestamp") == strconv.FormatInt(from, 10) {
+				result = map[string]interface{}{"has_more": true, "trades": []interface{}{
+					trade("1", "ETH-1DEC23-2000-C", primitives.Duration{}, 50, 2000),
+					trade("2", "ETH-29DEC23-2200-C", primitives.Minutes(30), 60, 2010),
+				}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pair := primitives.MustPair("ETH/USD")
+	deribit := marketdata.NewDeribitOptions(server.URL, "ETH", pair)
+	chain, err := deribit.Chain(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, q := range chain.Quotes {
+		names = append(names, q.Instrument)
+	}
+	if fmt.Sprint(names) != "[ETH-1DEC23-2000-C ETH-1DEC23-2000-P ETH-29DEC23-2200-C]" {
+		t.Errorf("expected quotes by expiry, strike and type, got %v", names)
+	}
+	call := chain.Quotes[0]
+	if call.Type != mechanisms.OptionTypeCall || call.Strike.String() != "2000" || call.MarkIV.String() != "0.5" {
+		t.Errorf("unexpected quote %+v", call)
+	}
+	if got := call.Expiry.Time().Format("2006-01-02T15:04"); got != "2023-12-01T08:00" {
+		t.Errorf("expected expiry at 08:00 UTC, got %s", got)
+	}
+	if chain.Underlying.String() != "2000" || !chain.Time.Equal(testStart) {
+		t.Errorf("expected chain at index 2000 and %s, got %s at %s", testStart, chain.Underlying, chain.Time)
+	}
+	// An at-the-money call and put have deltas one apart
+	if d := call.Greeks.Delta.Sub(chain.Quotes[1].Greeks.Delta).Float64(); d < 0.999 || d > 1.001 {
+		t.Errorf("expected call delta - put delta = 1, got %v", d)
+	}
+	if chain.Quotes[2].OpenInterest.String() != "12" {
+		t.Errorf("expected open interest 12, got %s", chain.Quotes[2].OpenInterest)
+	}
+
+	grid := []primitives.Time{testStart.Add(primitives.Hours(1)), testStart.Add(primitives.Hours(2)), testStart.Add(primitives.Hours(3))}
+	chains, err := deribit.HistoricalChains(context.Background(), grid, primitives.Hours(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chains) != 2 {
+		t.Fatalf("expected the grid time without recent trades skipped, got %d chains", len(chains))
+	}
+	if len(chains[0].Quotes) != 2 || chains[0].Underlying.String() != "2010" {
+		t.Errorf("expected two quotes at index 2010 after one hour, got %d at %s", len(chains[0].Quotes), chains[0].Underlying)
+	}
+	if len(chains[1].Quotes) != 1 || chains[1].Quotes[0].MarkIV.String() != "0.55" || !chains[1].Time.Equal(grid[2]) {
+		t.Errorf("expected only the put traded within the hour, got %+v", chains[1].Quotes)
+	}
+
+	snapshots, err := marketdata.OptionChainSnapshots(chains)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if priceAt(t, snapshots[0], "ETH/USD") != "2010" {
+		t.Errorf("expected the snapshot priced at the chain's underlying")
+	}
+	if vol, err := strategy.GetDecimal(snapshots[1], snapshotkeys.OptionImpliedVol("ETH/USD")); err != nil || vol.String() != "0.55" {
+		t.Errorf("expected at-the-money vol 0.55, got %v (%v)", vol, err)
+	}
+	if _, ok := snapshots[0].Get(snapshotkeys.OptionSurface("ETH/USD")); !ok {
+		t.Error("expected the surface in snapshot metadata")
+	}
+
+	if _, err := deribit.HistoricalChains(context.Background(), grid, primitives.Duration{}); !errors.Is(err, marketdata.ErrInvalidStep) {
+		t.Errorf("expected %v, got %v", marketdata.ErrInvalidStep, err)
+	}
+	deribit.Currency = "XRP"
+	if _, err := deribit.Chain(context.Background()); !errors.Is(err, marketdata.ErrDeribitResponse) {
+		t.Errorf("expected %v, got %v", marketdata.ErrDeribitResponse, err)
+	}
+}
+
+func TestParseDeribitInstrument(t *testing.T) {
+	typ, strike, expiry, err := marketdata.ParseDeribitInstrument("XRP_USDC-5JAN24-0d625-P")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if typ != mechanisms.OptionTypePut || strike.String() != "0.625" || expiry.Time().Format(time.RFC3339) != "2024-01-05T08:00:00Z" {
+		t.Errorf("unexpected parse %s %s %s", typ, strike, expiry)
+	}
+	for _, name := range []string{"ETH-PERPETUAL", "ETH-32DEC23-2000-C", "ETH-1DEC23-2000-X"} {
+		if _, _, _, err := marketdata.ParseDeribitInstrument(name); !errors.Is(err, marketdata.ErrDeribitResponse) {
+			t.Errorf("%s: expected %v, got %v", name, marketdata.ErrDeribitResponse, err)
+		}
+	}
+}
+
+func TestVolSurface(t *testing.T) {
+	near := testStart.Add(primitives.Days(30))
+	far := testStart.Add(primitives.Days(60))
+	surface, err := marketdata.NewVolSurface(testStart, []marketdata.VolPoint{
+		{Expiry: near, Strike: px("1000"), Vol: primitives.MustDecimalFromString("0.5")},
+		{Expiry: near, Strike: px("2000"), Vol: primitives.MustDecimalFromString("0.7")},
+		{Expiry: far, Strike: px("1000"), Vol: primitives.MustDecimalFromString("0.3")},
+		{Expiry: far, Strike: px("1000"), Vol: primitives.MustDecimalFromString("0.5")},
+		{Expiry: testStart, Strike: px("1000"), Vol: primitives.MustDecimalFromString("9")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(surface.Expiries()) != 2 {
+		t.Errorf("expected the expired point ignored, got expiries %v", surface.Expiries())
+	}
+
+	tests := []struct {
+		strike string
+		expiry primitives.Time
+		want   float64
+	}{
+		{"1500", near, 0.6},
+		{"500", near, 0.5},
+		{"3000", near, 0.7},
+		// Duplicates average to 0.4; total variances of 0.25·30 and
+		// 0.16·60 days interpolate to 0.19·45 days
+		{"1000", testStart.Add(primitives.Days(45)), 0.43589},
+		{"1000", testStart.Add(primitives.Days(10)), 0.5},
+		{"1000", testStart.Add(primitives.Days(90)), 0.4},
+	}
+	for _, tt := range tests {
+		vol, err := surface.Vol(px(tt.strike), tt.expiry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if d := vol.Float64() - tt.want; d < -1e-5 || d > 1e-5 {
+			t.Errorf("Vol(%s, %s) = %s, want %v", tt.strike, tt.expiry, vol, tt.want)
+		}
+	}
+
+	if _, err := surface.Vol(px("1000"), testStart); err == nil {
+		t.Error("expected an error for an expiry at the surface time")
+	}
+	if _, err := marketdata.NewVolSurface(testStart, nil); !errors.Is(err, marketdata.ErrEmptySurface) {
+		t.Errorf("expected %v, got %v", marketdata.ErrEmptySurface, err)
+	}
+}
+
+func TestSymbolMaps(t *testing.T) {
+	tests := []struct {
+		symbols *marketdata.SymbolMap
+		symbol  string
+		unified string
+		inverse bool
+	}{
+		{marketdata.BinanceSpotSymbols(), "ETHUSDT", "ETH/USDT", false},
+		{marketdata.BinanceFuturesSymbols(), "ETHUSDT", "ETH/USDT:USDT", false},
+		{marketdata.BinanceFuturesSymbols(), "ETHUSD_PERP", "ETH/USD:ETH", true},
+		{marketdata.CoinbaseSymbols(), "ETH-USD", "ETH/USD", false},
+		{marketdata.KrakenSymbols(), "XXBTZUSD", "BTC/USD", false},
+		{marketdata.KrakenSymbols(), "XBTUSD", "BTC/USD", false},
+		{marketdata.KrakenSymbols(), "XBT/EUR", "BTC/EUR", false},
+		{marketdata.KrakenSymbols(), "PF_XBTUSD", "BTC/USD:USD", false},
+		{marketdata.KrakenSymbols(), "PI_XBTUSD", "BTC/USD:BTC", true},
+		{marketdata.OKXSymbols(), "ETH-USDT-SWAP", "ETH/USDT:USDT", false},
+		{marketdata.OKXSymbols(), "ETH-USD-SWAP", "ETH/USD:ETH", true},
+		{marketdata.DeribitSymbols(), "ETH-PERPETUAL", "ETH/USD:ETH", true},
+		{marketdata.DeribitSymbols(), "ETH_USDC-PERPETUAL", "ETH/USDC:USDC", false},
+		{marketdata.HyperliquidSymbols(), "ETH", "ETH/USD:USDC", false},
+		{marketdata.HyperliquidSymbols(), "PURR/USDC", "PURR/USDC", false},
+	}
+	for _, tt := range tests {
+		market, err := tt.symbols.Market(tt.symbol)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", tt.symbols.Exchange, tt.symbol, err)
+			continue
+		}
+		if market.Unified() != tt.unified || market.Inverse() != tt.inverse || market.Symbol != tt.symbol {
+			t.Errorf("%s %s: got %s (inverse %v), want %s (inverse %v)",
+				tt.symbols.Exchange, tt.symbol, market.Unified(), market.Inverse(), tt.unified, tt.inverse)
+		}
+	}
+
+	for _, symbol := range []string{"ETH-27DEC24-3000-C", "BTC-PERPETUAL-X"} {
+		if _, err := marketdata.DeribitSymbols().Market(symbol); !errors.Is(err, marketdata.ErrUnknownSymbol) {
+			t.Errorf("%s: expected %v, got %v", symbol, marketdata.ErrUnknownSymbol, err)
+		}
+	}
+
+	// Aliases and overrides unify markets across exchanges
+	binance := marketdata.BinanceFuturesSymbols()
+	binance.Aliases = primitives.AssetAliases{"USDT": "USD"}
+	hyperliquid := marketdata.HyperliquidSymbols()
+	hyperliquid.Overrides = map[string]marketdata.Market{"@1": {Pair: primitives.MustPair("HFUN/USDC"), Type: marketdata.MarketSpot}}
+	a, _ := binance.Market("ETHUSDT")
+	b, _ := hyperliquid.Market("ETH")
+	if a.Key() != "ETH/USD" || a.Key() != b.Key() {
+		t.Errorf("expected both perps keyed ETH/USD, got %s and %s", a.Key(), b.Key())
+	}
+	if m, err := hyperliquid.Market("@1"); err != nil || m.Unified() != "HFUN/USDC" || m.Exchange != "hyperliquid" {
+		t.Errorf("expected the override, got %+v (%v)", m, err)
+	}
+}
+
+func TestCandleSnapshots(t *testing.T) {
+	binance := marketdata.BinanceFuturesSymbols()
+	binance.Aliases = primitives.AssetAliases{"USDT": "USD"}
+	hyperliquid := marketdata.HyperliquidSymbols()
+	hour := primitives.Hours(1)
+
+	flat := func(price string) strategy.Bar {
+		return strategy.Bar{Open: px(price), High: px(price), Low: px(price), Close: px(price), Volume: primitives.MustAmount(primitives.MustDecimalFromString("1"))}
+	}
+	c1, err := binance.Candle("ETHUSDT", testStart, hour, flat("2000"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c2, _ := binance.Candle("BTCUSDT", testStart, hour, flat("40000"))
+	c3, _ := hyperliquid.Candle("ETH", testStart.Add(hour), hour, flat("2010"))
+
+	// Binance funds every 8 hours, Hyperliquid hourly
+	f1, err := binance.Funding("ETHUSDT", testStart, primitives.MustDecimalFromString("0.0001"), primitives.Hours(8))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f2, _ := hyperliquid.Funding("ETH", testStart.Add(primitives.Minutes(90)), primitives.MustDecimalFromString("0.00002"), hour)
+	f2.MarkPrice = px("2011")
+	if f2.Rate.String() != "0.00016" {
+		t.Errorf("expected the hourly rate scaled to 0.00016 per 8h, got %s", f2.Rate)
+	}
+
+	snapshots, err := marketdata.CandleSnapshots([]marketdata.Candle{c3, c1, c2}, []marketdata.Funding{f2, f1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 || !snapshots[0].Time().Equal(testStart.Add(hour)) {
+		t.Fatalf("expected two snapshots stamped at candle close, got %d", len(snapshots))
+	}
+	if priceAt(t, snapshots[0], "BTC/USD") != "40000" || priceAt(t, snapshots[1], "ETH/USD") != "2010" {
+		t.Error("expected bars keyed by unified pair")
+	}
+	for i, want := range []string{"0.0001", "0.00016"} {
+		if rate, err := strategy.GetDecimal(snapshots[i], snapshotkeys.PerpFundingRate("ETH/USD")); err != nil || rate.String() != want {
+			t.Errorf("snapshot %d: expected funding %s, got %v (%v)", i, want, rate, err)
+		}
+	}
+	if _, ok := snapshots[0].Get(snapshotkeys.PerpMarkPrice("ETH/USD")); ok {
+		t.Error("expected no mark price before it is known")
+	}
+	if ev := f2.Event(); ev.Symbol != "ETH/USD" || !ev.MarkPrice.Equal(px("2011")) {
+		t.Errorf("unexpected funding event %v", ev)
+	}
+
+	spot, _ := marketdata.BinanceSpotSymbols().Candle("ETHUSD", testStart, hour, flat("2000"))
+	if _, err := marketdata.CandleSnapshots([]marketdata.Candle{c1, spot}, nil); !errors.Is(err, strategy.ErrInvalidMarketData) {
+		t.Errorf("expected %v, got %v", strategy.ErrInvalidMarketData, err)
+	}
+	if _, err := marketdata.CoinbaseSymbols().Funding("ETH-USD", testStart, primitives.Zero(), hour); !errors.Is(err, marketdata.ErrUnknownSymbol) {
+		t.Errorf("expected %v for a spot market, got %v", marketdata.ErrUnknownSymbol, err)
+	}
+}
@@ -0,0 +1,386 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ErrUnknownSymbol indicates an exchange symbol that cannot be mapped to a
+// unified market.
+var ErrUnknownSymbol = errors.New("unknown exchange symbol")
+
+// MarketType is the kind of instrument a market trades.
+type MarketType string
+
+const (
+	// MarketSpot is a spot market
+	MarketSpot MarketType = "spot"
+
+	// MarketPerp is a perpetual swap
+	MarketPerp MarketType = "perp"
+)
+
+// Market is an exchange's market in unified form.
+type Market struct {
+	// Exchange is the source exchange (e.g., "binance")
+	Exchange string
+
+	// Symbol is the exchange's own symbol (e.g., "ETHUSDT", "ETH-PERPETUAL")
+	Symbol string
+
+	// Pair is the unified base and quote, after asset aliases
+	Pair primitives.Pair
+
+	Type MarketType
+
+	// Settle is the asset a perp settles in: the quote for linear perps,
+	// the base for inverse perps. Empty for spot markets.
+	Settle string
+}
+
+// Key returns the snapshot key of the market: its pair in canonical
+// "BASE/QUOTE" form. Prices, bars and perp metadata are all keyed by it,
+// so the same market reads the same from every exchange.
+func (m Market) Key() string {
+	return m.Pair.String()
+}
+
+// Unified returns the market's CCXT-style unified symbol: "ETH/USDT" for
+// spot and "ETH/USDT:USDT" for a perp settling in USDT.
+func (m Market) Unified() string {
+	if m.Type == MarketPerp {
+		return m.Pair.String() + ":" + m.Settle
+	}
+	return m.Pair.String()
+}
+
+// Inverse reports whether the market is an inverse perp, margined and
+// settled in its base asset.
+func (m Market) Inverse() bool {
+	return m.Type == MarketPerp && m.Settle == m.Pair.Base
+}
+
+// SymbolMap maps one exchange's symbols to unified markets, and builds
+// unified candles and funding rates from the exchange's raw data.
+//
+// Symbols are parsed by the exchange's grammar, venue-specific asset codes
+// (such as Kraken's XBT) are translated, and Aliases are then applied to
+// both assets, so that e.g. {"USDT": "USD"} can make Binance's ETHUSDT and
+// Coinbase's ETH-USD the same market. Overrides take precedence over
+// parsing for symbols the grammar cannot handle.
+//
+// Thread Safety: SymbolMap is safe for concurrent reads once configured.
+type SymbolMap struct {
+	// Exchange names the exchange
+	Exchange string
+
+	// Aliases are applied to both assets of every parsed market
+	Aliases primitives.AssetAliases
+
+	// Overrides maps symbols directly to markets; Exchange and Symbol are
+	// filled in and Aliases are still applied
+	Overrides map[string]Market
+
+	venueAliases primitives.AssetAliases
+	parse        func(symbol string) (Market, error)
+}
+
+// NewSymbolMap creates a symbol map for an exchange whose symbols parse
+// with parse. The parsed market's Exchange and Symbol are filled in.
+func NewSymbolMap(exchange string, parse func(symbol string) (Market, error)) *SymbolMap {
+	return &SymbolMap{Exchange: exchange, parse: parse}
+}
+
+// Market returns the unified market of symbol. Returns ErrUnknownSymbol if
+// symbol cannot be parsed.
+func (s *SymbolMap) Market(symbol string) (Market, error) {
+	market, ok := s.Overrides[symbol]
+	if !ok {
+		if s.parse == nil {
+			return Market{}, fmt.Errorf("%w: %s has no symbol %q", ErrUnknownSymbol, s.Exchange, symbol)
+		}
+		var err error
+		if market, err = s.parse(symbol); err != nil {
+			return Market{}, fmt.Errorf("%w: %s symbol %q: %s", ErrUnknownSymbol, s.Exchange, symbol, err)
+		}
+	}
+	market.Exchange = s.Exchange
+	market.Symbol = symbol
+	market.Pair = s.Aliases.Pair(s.venueAliases.Pair(market.Pair))
+	if market.Settle != "" {
+		market.Settle = s.Aliases.Asset(s.venueAliases.Asset(market.Settle))
+	}
+	if market.Pair.Base == market.Pair.Quote {
+		return Market{}, fmt.Errorf("%w: %s symbol %q aliases to %s", ErrUnknownSymbol, s.Exchange, symbol, market.Pair)
+	}
+	return market, nil
+}
+
+// Candle is an OHLCV candle in unified form.
+type Candle struct {
+	Market Market
+
+	// Open is the time the candle opens; it closes at Open + Interval
+	Open     primitives.Time
+	Interval primitives.Duration
+
+	// Bar holds the prices in the quote asset and the volume in the base
+	// asset
+	Bar strategy.Bar
+}
+
+// Close returns the time the candle closes, when its data is known.
+func (c Candle) Close() primitives.Time {
+	return c.Open.Add(c.Interval)
+}
+
+// Candle builds a unified candle for symbol. Exchanges stamp candles with
+// their open time; pass that time as open. Returns error if the symbol is
+// unknown, interval is not positive or the bar is inconsistent.
+func (s *SymbolMap) Candle(symbol string, open primitives.Time, interval primitives.Duration, bar strategy.Bar) (Candle, error) {
+	market, err := s.Market(symbol)
+	if err != nil {
+		return Candle{}, err
+	}
+	if interval.Duration() <= 0 {
+		return Candle{}, fmt.Errorf("%w: candle interval %s must be positive", ErrInvalidStep, interval)
+	}
+	if err := bar.Validate(); err != nil {
+		return Candle{}, fmt.Errorf("%s candle at %s: %w", market.Unified(), open, err)
+	}
+	return Candle{Market: market, Open: open, Interval: interval, Bar: bar}, nil
+}
+
+// Funding is a perp funding rate in unified form.
+type Funding struct {
+	Market Market
+
+	// Time is when the rate takes effect
+	Time primitives.Time
+
+	// Rate is the funding rate per primitives.FundingInterval, whatever
+	// the exchange's own funding period
+	Rate primitives.Decimal
+
+	// MarkPrice and IndexPrice are the prices at the funding time; zero if
+	// unknown
+	MarkPrice  primitives.Price
+	IndexPrice primitives.Price
+}
+
+// Funding builds a unified funding rate for symbol from the exchange's
+// rate per period, rescaling it to primitives.FundingInterval (so an
+// hourly rate is multiplied by 8). Returns error if the symbol is unknown
+// or not a perp, or period is not positive.
+func (s *SymbolMap) Funding(symbol string, at primitives.Time, rate primitives.Decimal, period primitives.Duration) (Funding, error) {
+	market, err := s.Market(symbol)
+	if err != nil {
+		return Funding{}, err
+	}
+	if market.Type != MarketPerp {
+		return Funding{}, fmt.Errorf("%w: %s symbol %q is not a perp", ErrUnknownSymbol, s.Exchange, symbol)
+	}
+	if period.Duration() <= 0 {
+		return Funding{}, fmt.Errorf("%w: funding period %s must be positive", ErrInvalidStep, period)
+	}
+	scaled, err := rate.Mul(primitives.NewDecimal(int64(primitives.FundingInterval.Duration()))).
+		Div(primitives.NewDecimal(int64(period.Duration())))
+	if err != nil {
+		return Funding{}, err
+	}
+	return Funding{Market: market, Time: at, Rate: scaled}, nil
+}
+
+// Event returns the funding as a strategy.FundingEvent keyed by the
+// market's Key.
+func (f Funding) Event() strategy.FundingEvent {
+	return strategy.FundingEvent{At: f.Time, Symbol: f.Market.Key(), Rate: f.Rate, MarkPrice: f.MarkPrice}
+}
+
+// CandleSnapshots builds one OHLCV snapshot per candle close time, with
+// each candle's bar keyed by its market's Key. Snapshots are stamped at
+// the close, as a candle is not known before it. Each snapshot also
+// carries, for every perp, the latest funding at or before its time under
+// the snapshotkeys PerpFundingRate, PerpMarkPrice and PerpIndexPrice
+// (the prices when known).
+//
+// Returns strategy.ErrInvalidMarketData if two candles of the same key
+// close at the same time, e.g. the spot and perp of a pair or the same
+// market from two exchanges; merge those into separate series.
+func CandleSnapshots(candles []Candle, funding []Funding) ([]strategy.MarketSnapshot, error) {
+	sortedCandles := make([]Candle, len(candles))
+	copy(sortedCandles, candles)
+	sort.SliceStable(sortedCandles, func(i, j int) bool {
+		return sortedCandles[i].Close().Before(sortedCandles[j].Close())
+	})
+	sortedFunding := make([]Funding, len(funding))
+	copy(sortedFunding, funding)
+	sort.SliceStable(sortedFunding, func(i, j int) bool {
+		return sortedFunding[i].Time.Before(sortedFunding[j].Time)
+	})
+
+	latest := make(map[string]Funding)
+	var snapshots []strategy.MarketSnapshot
+	next := 0
+	for start := 0; start < len(sortedCandles); {
+		at := sortedCandles[start].Close()
+		bars := make(map[string]strategy.Bar)
+		end := start
+		for ; end < len(sortedCandles) && sortedCandles[end].Close().Equal(at); end++ {
+			key := sortedCandles[end].Market.Key()
+			if _, ok := bars[key]; ok {
+				return nil, fmt.Errorf("%w: two candles for %s close at %s", strategy.ErrInvalidMarketData, key, at)
+			}
+			bars[key] = sortedCandles[end].Bar
+		}
+		start = end
+
+		for next < len(sortedFunding) && !sortedFunding[next].Time.After(at) {
+			latest[sortedFunding[next].Market.Key()] = sortedFunding[next]
+			next++
+		}
+		snapshot, err := strategy.NewOHLCVSnapshot(at, bars, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, f := range latest {
+			snapshot.Set(snapshotkeys.PerpFundingRate(key), f.Rate)
+			if !f.MarkPrice.IsZero() {
+				snapshot.Set(snapshotkeys.PerpMarkPrice(key), f.MarkPrice.Decimal())
+			}
+			if !f.IndexPrice.IsZero() {
+				snapshot.Set(snapshotkeys.PerpIndexPrice(key), f.IndexPrice.Decimal())
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// BinanceSpotSymbols maps Binance spot symbols such as "ETHUSDT".
+func BinanceSpotSymbols() *SymbolMap {
+	return NewSymbolMap("binance", func(symbol string) (Market, error) {
+		pair, err := primitives.ParsePair(symbol)
+		return Market{Pair: pair, Type: MarketSpot}, err
+	})
+}
+
+// BinanceFuturesSymbols maps Binance futures symbols: USDⓈ-M perps such as
+// "ETHUSDT" and COIN-M perps such as "ETHUSD_PERP".
+func BinanceFuturesSymbols() *SymbolMap {
+	return NewSymbolMap("binance", func(symbol string) (Market, error) {
+		if base, ok := strings.CutSuffix(symbol, "_PERP"); ok {
+			pair, err := primitives.ParsePair(base)
+			return Market{Pair: pair, Type: MarketPerp, Settle: pair.Base}, err
+		}
+		if strings.Contains(symbol, "_") {
+			return Market{}, errors.New("dated futures are not supported")
+		}
+		pair, err := primitives.ParsePair(symbol)
+		return Market{Pair: pair, Type: MarketPerp, Settle: pair.Quote}, err
+	})
+}
+
+// CoinbaseSymbols maps Coinbase spot symbols such as "ETH-USD".
+func CoinbaseSymbols() *SymbolMap {
+	return NewSymbolMap("coinbase", func(symbol string) (Market, error) {
+		pair, err := primitives.ParsePair(symbol)
+		return Market{Pair: pair, Type: MarketSpot}, err
+	})
+}
+
+// KrakenSymbols maps Kraken spot symbols in REST form ("XBTUSD", legacy
+// "XXBTZUSD") or websocket form ("XBT/USD"), and Kraken Futures perps such
+// as "PF_XBTUSD" (linear, multi-collateral) and "PI_XBTUSD" (inverse).
+// XBT and XDG read as BTC and DOGE.
+func KrakenSymbols() *SymbolMap {
+	m := NewSymbolMap("kraken", func(symbol string) (Market, error) {
+		if rest, ok := strings.CutPrefix(symbol, "PF_"); ok {
+			pair, err := krakenPair(rest)
+			return Market{Pair: pair, Type: MarketPerp, Settle: pair.Quote}, err
+		}
+		if rest, ok := strings.CutPrefix(symbol, "PI_"); ok {
+			pair, err := krakenPair(rest)
+			return Market{Pair: pair, Type: MarketPerp, Settle: pair.Base}, err
+		}
+		// Legacy four-letter asset codes: X for crypto, Z for fiat
+		if len(symbol) == 8 && strings.ContainsRune("XZ", rune(symbol[0])) && strings.ContainsRune("XZ", rune(symbol[4])) {
+			pair, err := primitives.NewPair(symbol[1:4], symbol[5:8])
+			return Market{Pair: pair, Type: MarketSpot}, err
+		}
+		pair, err := krakenPair(symbol)
+		return Market{Pair: pair, Type: MarketSpot}, err
+	})
+	m.venueAliases = primitives.AssetAliases{"XBT": "BTC", "XDG": "DOGE"}
+	return m
+}
+
+// krakenPair parses a Kraken pair, splitting six-letter concatenated
+// symbols in half so "XBTUSD" does not read as XB/TUSD.
+func krakenPair(symbol string) (primitives.Pair, error) {
+	if len(symbol) == 6 && !strings.ContainsAny(symbol, "/-_:") {
+		return primitives.NewPair(symbol[:3], symbol[3:])
+	}
+	return primitives.ParsePair(symbol)
+}
+
+// OKXSymbols maps OKX instrument IDs: spot such as "ETH-USDT" and perps
+// such as "ETH-USDT-SWAP" (linear) and "ETH-USD-SWAP" (inverse).
+func OKXSymbols() *SymbolMap {
+	return NewSymbolMap("okx", func(symbol string) (Market, error) {
+		if rest, ok := strings.CutSuffix(symbol, "-SWAP"); ok {
+			pair, err := primitives.ParsePair(rest)
+			settle := pair.Quote
+			if pair.Quote == "USD" {
+				settle = pair.Base
+			}
+			return Market{Pair: pair, Type: MarketPerp, Settle: settle}, err
+		}
+		pair, err := primitives.ParsePair(symbol)
+		return Market{Pair: pair, Type: MarketSpot}, err
+	})
+}
+
+// DeribitSymbols maps Deribit instruments: inverse perps such as
+// "ETH-PERPETUAL" (ETH/USD settled in ETH), linear perps such as
+// "ETH_USDC-PERPETUAL" and spot such as "ETH_USDC".
+func DeribitSymbols() *SymbolMap {
+	return NewSymbolMap("deribit", func(symbol string) (Market, error) {
+		if rest, ok := strings.CutSuffix(symbol, "-PERPETUAL"); ok {
+			if !strings.Contains(rest, "_") {
+				pair, err := primitives.NewPair(rest, "USD")
+				return Market{Pair: pair, Type: MarketPerp, Settle: pair.Base}, err
+			}
+			pair, err := primitives.ParsePair(rest)
+			return Market{Pair: pair, Type: MarketPerp, Settle: pair.Quote}, err
+		}
+		if strings.Count(symbol, "-") > 0 {
+			return Market{}, errors.New("futures and options are not supported")
+		}
+		pair, err := primitives.ParsePair(symbol)
+		return Market{Pair: pair, Type: MarketSpot}, err
+	})
+}
+
+// HyperliquidSymbols maps Hyperliquid coins, which name perps quoted in
+// USD and settled in USDC (such as "ETH"), and spot pairs such as
+// "PURR/USDC".
+func HyperliquidSymbols() *SymbolMap {
+	return NewSymbolMap("hyperliquid", func(symbol string) (Market, error) {
+		if strings.Contains(symbol, "/") {
+			pair, err := primitives.ParsePair(symbol)
+			return Market{Pair: pair, Type: MarketSpot}, err
+		}
+		if strings.HasPrefix(symbol, "@") {
+			return Market{}, errors.New("spot index symbols need an override")
+		}
+		pair, err := primitives.NewPair(symbol, "USD")
+		return Market{Pair: pair, Type: MarketPerp, Settle: "USDC"}, err
+	})
+}
@@ -0,0 +1,312 @@
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ErrEmptySurface indicates a volatility surface has no usable points.
+var ErrEmptySurface = errors.New("volatility surface has no points")
+
+// OptionQuote is one option of a chain at a point in time.
+type OptionQuote struct {
+	// Instrument is the venue's instrument name (e.g., "ETH-27DEC24-3000-C")
+	Instrument string
+
+	Type   mechanisms.OptionType
+	Strike primitives.Price
+	Expiry primitives.Time
+
+	// MarkIV is the annualized implied volatility as a fraction (0.65 for
+	// 65%)
+	MarkIV primitives.Decimal
+
+	// MarkPrice is the option price in the venue's premium currency; for
+	// Deribit's inverse options that is the base coin, not USD
+	MarkPrice primitives.Decimal
+
+	// Underlying is the underlying price the option is marked against
+	Underlying primitives.Price
+
+	// Greeks are Black-Scholes Greeks at MarkIV and Underlying with a zero
+	// rate, in the units of blackscholes.Option.Greeks
+	Greeks mechanisms.Greeks
+
+	// OpenInterest is the open interest in contracts, when known
+	OpenInterest primitives.Decimal
+}
+
+// OptionChain is the options listed on an underlying at a point in time.
+type OptionChain struct {
+	// Pair is the underlying market, e.g. ETH/USD
+	Pair primitives.Pair
+
+	Time primitives.Time
+
+	// Underlying is the underlying's index price
+	Underlying primitives.Price
+
+	// Quotes are sorted by expiry, strike, then calls before puts
+	Quotes []OptionQuote
+}
+
+// Expiries returns the chain's distinct expiries in ascending order.
+func (c *OptionChain) Expiries() []primitives.Time {
+	var expiries []primitives.Time
+	for _, quote := range c.Quotes {
+		if n := len(expiries); n == 0 || !expiries[n-1].Equal(quote.Expiry) {
+			expiries = append(expiries, quote.Expiry)
+		}
+	}
+	return expiries
+}
+
+// Surface builds the chain's implied volatility surface from the quotes'
+// MarkIV. Returns ErrEmptySurface if no quote has a positive IV and a
+// future expiry.
+func (c *OptionChain) Surface() (*VolSurface, error) {
+	points := make([]VolPoint, 0, len(c.Quotes))
+	for _, quote := range c.Quotes {
+		points = append(points, VolPoint{Expiry: quote.Expiry, Strike: quote.Strike, Vol: quote.MarkIV})
+	}
+	return NewVolSurface(c.Time, points)
+}
+
+// Attach stores the chain in snapshot: the chain itself at
+// snapshotkeys.OptionChain, its surface at snapshotkeys.OptionSurface and
+// the at-the-money volatility of the nearest expiry at
+// snapshotkeys.OptionImpliedVol, all keyed by the chain's pair, so
+// consumers of a single volatility (such as structured products) price
+// from the chain too.
+func (c *OptionChain) Attach(snapshot *strategy.SimpleSnapshot) error {
+	surface, err := c.Surface()
+	if err != nil {
+		return fmt.Errorf("chain for %s at %s: %w", c.Pair, c.Time, err)
+	}
+	atm, err := surface.Vol(c.Underlying, surface.Expiries()[0])
+	if err != nil {
+		return err
+	}
+	key := c.Pair.String()
+	snapshot.Set(snapshotkeys.OptionChain(key), c)
+	snapshot.Set(snapshotkeys.OptionSurface(key), surface)
+	snapshot.Set(snapshotkeys.OptionImpliedVol(key), atm)
+	return nil
+}
+
+// OptionChainSnapshots builds one snapshot per chain, priced at the
+// chain's underlying and carrying it as Attach does. Chains must be in
+// time order.
+func OptionChainSnapshots(chains []*OptionChain) ([]strategy.MarketSnapshot, error) {
+	snapshots := make([]strategy.MarketSnapshot, 0, len(chains))
+	for i, chain := range chains {
+		if i > 0 && chain.Time.Before(chains[i-1].Time) {
+			return nil, fmt.Errorf("%w: chain %d at %s precedes chain %d", ErrUnsortedStream, i, chain.Time, i-1)
+		}
+		snapshot := strategy.NewSimpleSnapshot(chain.Time, map[string]primitives.Price{chain.Pair.String(): chain.Underlying})
+		if err := chain.Attach(snapshot); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// sortQuotes orders quotes by expiry, strike, then calls before puts.
+func sortQuotes(quotes []OptionQuote) {
+	sort.SliceStable(quotes, func(i, j int) bool {
+		a, b := quotes[i], quotes[j]
+		if !a.Expiry.Equal(b.Expiry) {
+			return a.Expiry.Before(b.Expiry)
+		}
+		if !a.Strike.Decimal().Equal(b.Strike.Decimal()) {
+			return a.Strike.Decimal().LessThan(b.Strike.Decimal())
+		}
+		return a.Type == mechanisms.OptionTypeCall && b.Type != mechanisms.OptionTypeCall
+	})
+}
+
+// computeGreeks fills in the Greeks of quotes at time now from their
+// MarkIV and Underlying. Expired quotes and quotes without an IV or
+// underlying are left without Greeks.
+func computeGreeks(quotes []OptionQuote, now primitives.Time) error {
+	var params []blackscholes.PriceParams
+	var index []int
+	for i, quote := range quotes {
+		years := float64(quote.Expiry.Sub(now).Duration()) / float64(primitives.Year.Duration())
+		if years <= 0 || !quote.MarkIV.IsPositive() || quote.Underlying.IsZero() {
+			continue
+		}
+		params = append(params, blackscholes.PriceParams{
+			Type:         quote.Type,
+			Underlying:   quote.Underlying,
+			Strike:       quote.Strike,
+			TimeToExpiry: primitives.NewDecimalFromFloat(years),
+			Volatility:   quote.MarkIV,
+		})
+		index = append(index, i)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	valuations, err := blackscholes.PriceMany(params)
+	if err != nil {
+		return fmt.Errorf("failed to compute option greeks: %w", err)
+	}
+	for n, i := range index {
+		quotes[i].Greeks = valuations[n].Greeks
+	}
+	return nil
+}
+
+// VolPoint is an implied volatility observed at a strike and expiry.
+type VolPoint struct {
+	Expiry primitives.Time
+	Strike primitives.Price
+
+	// Vol is the annualized implied volatility as a fraction
+	Vol primitives.Decimal
+}
+
+// VolSurface is an implied volatility surface by strike and expiry, built
+// from the points of an option chain.
+//
+// Within an expiry volatility is interpolated linearly in strike and held
+// flat beyond the outermost strikes. Across expiries total variance σ²T
+// is interpolated linearly in time at the strike; before the first expiry
+// and after the last the nearest expiry's volatility is used. The surface
+// works in float64.
+//
+// Thread Safety: VolSurface is immutable and safe for concurrent use.
+type VolSurface struct {
+	time   primitives.Time
+	slices []volSlice
+}
+
+// volSlice is the smile of one expiry.
+type volSlice struct {
+	expiry  primitives.Time
+	years   float64
+	strikes []float64
+	vols    []float64
+}
+
+// NewVolSurface builds a surface as of time at from points. Points with a
+// non-positive volatility or an expiry at or before at are ignored, and
+// several points at the same strike and expiry (such as a call and a put)
+// are averaged. Returns ErrEmptySurface if no point remains.
+func NewVolSurface(at primitives.Time, points []VolPoint) (*VolSurface, error) {
+	type key struct {
+		expiry int64
+		strike string
+	}
+	type sum struct {
+		expiry primitives.Time
+		strike float64
+		total  float64
+		count  int
+	}
+	sums := make(map[key]*sum)
+	for _, point := range points {
+		if !point.Vol.IsPositive() || !point.Expiry.After(at) {
+			continue
+		}
+		k := key{expiry: point.Expiry.UnixNano(), strike: point.Strike.Decimal().String()}
+		s, ok := sums[k]
+		if !ok {
+			s = &sum{expiry: point.Expiry, strike: point.Strike.Decimal().Float64()}
+			sums[k] = s
+		}
+		s.total += point.Vol.Float64()
+		s.count++
+	}
+	if len(sums) == 0 {
+		return nil, ErrEmptySurface
+	}
+
+	averaged := make([]*sum, 0, len(sums))
+	for _, s := range sums {
+		averaged = append(averaged, s)
+	}
+	sort.Slice(averaged, func(i, j int) bool {
+		if !averaged[i].expiry.Equal(averaged[j].expiry) {
+			return averaged[i].expiry.Before(averaged[j].expiry)
+		}
+		return averaged[i].strike < averaged[j].strike
+	})
+
+	surface := &VolSurface{time: at}
+	for _, s := range averaged {
+		n := len(surface.slices)
+		if n == 0 || !surface.slices[n-1].expiry.Equal(s.expiry) {
+			surface.slices = append(surface.slices, volSlice{
+				expiry: s.expiry,
+				years:  float64(s.expiry.Sub(at).Duration()) / float64(primitives.Year.Duration()),
+			})
+			n++
+		}
+		slice := &surface.slices[n-1]
+		slice.strikes = append(slice.strikes, s.strike)
+		slice.vols = append(slice.vols, s.total/float64(s.count))
+	}
+	return surface, nil
+}
+
+// Time returns the time the surface is as of.
+func (s *VolSurface) Time() primitives.Time {
+	return s.time
+}
+
+// Expiries returns the surface's expiries in ascending order.
+func (s *VolSurface) Expiries() []primitives.Time {
+	expiries := make([]primitives.Time, len(s.slices))
+	for i, slice := range s.slices {
+		expiries[i] = slice.expiry
+	}
+	return expiries
+}
+
+// Vol returns the implied volatility at strike for an option expiring at
+// expiry. Returns an error if expiry is not after the surface time.
+func (s *VolSurface) Vol(strike primitives.Price, expiry primitives.Time) (primitives.Decimal, error) {
+	if !expiry.After(s.time) {
+		return primitives.Zero(), fmt.Errorf("expiry %s is not after the surface time %s", expiry, s.time)
+	}
+	k := strike.Decimal().Float64()
+	years := float64(expiry.Sub(s.time).Duration()) / float64(primitives.Year.Duration())
+
+	i := sort.Search(len(s.slices), func(i int) bool { return s.slices[i].years >= years })
+	switch {
+	case i == 0:
+		return primitives.NewDecimalFromFloat(s.slices[0].vol(k)), nil
+	case i == len(s.slices):
+		return primitives.NewDecimalFromFloat(s.slices[i-1].vol(k)), nil
+	}
+	before, after := s.slices[i-1], s.slices[i]
+	varBefore := before.vol(k) * before.vol(k) * before.years
+	varAfter := after.vol(k) * after.vol(k) * after.years
+	w := (years - before.years) / (after.years - before.years)
+	variance := varBefore + w*(varAfter-varBefore)
+	return primitives.NewDecimalFromFloat(math.Sqrt(variance / years)), nil
+}
+
+// vol interpolates the smile linearly in strike, flat beyond its ends.
+func (s volSlice) vol(strike float64) float64 {
+	i := sort.SearchFloat64s(s.strikes, strike)
+	switch {
+	case i == 0:
+		return s.vols[0]
+	case i == len(s.strikes):
+		return s.vols[len(s.vols)-1]
+	}
+	w := (strike - s.strikes[i-1]) / (s.strikes[i] - s.strikes[i-1])
+	return s.vols[i-1] + w*(s.vols[i]-s.vols[i-1])
+}
@@ -0,0 +1,424 @@
+package marketdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// ErrOracleResponse indicates an oracle endpoint returned an error or a
+// response that could not be decoded.
+var ErrOracleResponse = errors.New("invalid oracle response")
+
+// OracleUpdate is a single price published by an on-chain oracle.
+type OracleUpdate struct {
+	// Pair is the market the price is quoted for
+	Pair primitives.Pair
+
+	// Price is the published price
+	Price primitives.Price
+
+	// Confidence is the half-width of the oracle's confidence interval
+	// around Price (zero for feeds that do not publish one, e.g. Chainlink)
+	Confidence primitives.Price
+
+	// PublishTime is when the oracle published the price, which may lag
+	// the time a snapshot built from it is stamped with
+	PublishTime primitives.Time
+
+	// Round is the Chainlink aggregator round (the round ID without its
+	// phase bits) or, for Pyth, the publish time in Unix seconds
+	Round uint64
+}
+
+// OracleSource supplies historical oracle updates.
+type OracleSource interface {
+	// Updates returns updates published in [from, to], sorted by publish time.
+	Updates(ctx context.Context, from, to primitives.Time) ([]OracleUpdate, error)
+}
+
+// OracleSnapshots builds one snapshot per grid time from oracle updates.
+//
+// Each snapshot holds, for every pair, the latest update published at or
+// before the grid time, so oracle latency is modeled rather than hidden:
+// the price time (strategy.PriceTimestamper) and the snapshotkeys
+// OraclePublishTime, OracleConfidence and OracleRound metadata describe
+// the update behind each price. Updates older than maxStaleness (when
+// positive) are left out, and grid times with no pair priced are skipped.
+func OracleSnapshots(
+	updates []OracleUpdate,
+	grid []primitives.Time,
+	maxStaleness primitives.Duration,
+) ([]strategy.MarketSnapshot, error) {
+	sorted := make([]OracleUpdate, len(updates))
+	copy(sorted, updates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PublishTime.Before(sorted[j].PublishTime)
+	})
+
+	latest := make(map[primitives.Pair]OracleUpdate)
+	out := make([]strategy.MarketSnapshot, 0, len(grid))
+	next := 0
+	for i, t := range grid {
+		if i > 0 && t.Before(grid[i-1]) {
+			return nil, fmt.Errorf("%w: grid time %d at %s precedes grid time %d", ErrUnsortedStream, i, t, i-1)
+		}
+		for next < len(sorted) && !sorted[next].PublishTime.After(t) {
+			latest[sorted[next].Pair] = sorted[next]
+			next++
+		}
+
+		prices := make(map[string]primitives.Price, len(latest))
+		snapshot := strategy.NewSimpleSnapshot(t, prices)
+		for pair, update := range latest {
+			if maxStaleness.Duration() > 0 && t.Sub(update.PublishTime).Duration() > maxStaleness.Duration() {
+				continue
+			}
+			key := pair.String()
+			prices[key] = update.Price
+			snapshot.SetPriceTime(key, update.PublishTime)
+			snapshot.Set(snapshotkeys.OraclePublishTime(key), update.PublishTime)
+			snapshot.Set(snapshotkeys.OracleConfidence(key), update.Confidence.Decimal())
+			snapshot.Set(snapshotkeys.OracleRound(key), update.Round)
+		}
+		if len(prices) == 0 {
+			continue
+		}
+		out = append(out, snapshot)
+	}
+	return out, nil
+}
+
+// Selectors of the Chainlink AggregatorV3Interface functions used below.
+const (
+	selectorDecimals        = "0x313ce567" // decimals()
+	selectorLatestRoundData = "0xfeaf968c" // latestRoundData()
+	selectorGetRoundData    = "0x9a6fc8f5" // getRoundData(uint80)
+)
+
+// ChainlinkFeed reads historical rounds from a Chainlink price feed
+// (AggregatorV3Interface) over Ethereum JSON-RPC.
+//
+// Rounds are walked backwards from the latest one with getRoundData, which
+// reads history from current contract state, so no archive node is needed.
+// Round IDs encode the aggregator phase in their top bits; the walk stops
+// at the first round of the current phase, at a round updated before from,
+// or after MaxRounds.
+type ChainlinkFeed struct {
+	// Endpoint is the JSON-RPC URL
+	Endpoint string
+
+	// Address is the feed (proxy) contract address
+	Address string
+
+	// Pair is the market the feed prices
+	Pair primitives.Pair
+
+	// MaxRounds bounds the number of rounds read per call (default 10000)
+	MaxRounds int
+
+	// Client is the HTTP client used (a client with a 30s timeout if nil)
+	Client *http.Client
+}
+
+// NewChainlinkFeed creates a Chainlink feed reader.
+func NewChainlinkFeed(endpoint, address string, pair primitives.Pair) *ChainlinkFeed {
+	return &ChainlinkFeed{Endpoint: endpoint, Address: address, Pair: pair, MaxRounds: 10000}
+}
+
+// chainlinkRound is the decoded result of latestRoundData/getRoundData.
+type chainlinkRound struct {
+	id        *big.Int
+	answer    *big.Int
+	updatedAt int64
+}
+
+// Updates implements OracleSource.
+func (f *ChainlinkFeed) Updates(ctx context.Context, from, to primitives.Time) ([]OracleUpdate, error) {
+	words, err := f.call(ctx, selectorDecimals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decimals: %w", err)
+	}
+	if len(words) < 1 {
+		return nil, fmt.Errorf("%w: empty decimals result", ErrOracleResponse)
+	}
+	decimals := int32(words[0].Int64())
+
+	round, err := f.round(ctx, selectorLatestRoundData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest round: %w", err)
+	}
+
+	maxRounds := f.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = 10000
+	}
+	phaseStart := new(big.Int).Lsh(new(big.Int).Rsh(round.id, 64), 64)
+
+	var updates []OracleUpdate
+	for n := 0; n < maxRounds; n++ {
+		published := primitives.Unix(round.updatedAt, 0)
+		if published.Before(from) {
+			break
+		}
+		if round.updatedAt > 0 && !published.After(to) {
+			if round.answer.Sign() < 0 {
+				return nil, fmt.Errorf("%w: negative answer in round %s", ErrOracleResponse, round.id)
+			}
+			updates = append(updates, OracleUpdate{
+				Pair:        f.Pair,
+				Price:       primitives.MustPrice(primitives.NewDecimalFromFixed(round.answer, decimals)),
+				PublishTime: published,
+				Round:       round.id.Uint64(),
+			})
+		}
+
+		prev := new(big.Int).Sub(round.id, big.NewInt(1))
+		if prev.Cmp(phaseStart) <= 0 {
+			break
+		}
+		round, err = f.round(ctx, selectorGetRoundData+fmt.Sprintf("%064x", prev))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read round %s: %w", prev, err)
+		}
+	}
+
+	// Rounds were read newest first
+	for i, j := 0, len(updates)-1; i < j; i, j = i+1, j-1 {
+		updates[i], updates[j] = updates[j], updates[i]
+	}
+	return updates, nil
+}
+
+// round calls a function returning (roundId, answer, startedAt, updatedAt, answeredInRound).
+func (f *ChainlinkFeed) round(ctx context.Context, data string) (chainlinkRound, error) {
+	words, err := f.call(ctx, data)
+	if err != nil {
+		return chainlinkRound{}, err
+	}
+	if len(words) < 5 {
+		return chainlinkRound{}, fmt.Errorf("%w: expected 5 words, got %d", ErrOracleResponse, len(words))
+	}
+	return chainlinkRound{id: words[0], answer: signedWord(words[1]), updatedAt: words[3].Int64()}, nil
+}
+
+// call performs eth_call against the feed and splits the result into 32-byte words.
+func (f *ChainlinkFeed) call(ctx context.Context, data string) ([]*big.Int, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_call",
+		"params":  []interface{}{map[string]string{"to": f.Address, "data": data}, "latest"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := postJSON(ctx, f.Client, f.Endpoint, body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%w: rpc error %d: %s", ErrOracleResponse, resp.Error.Code, resp.Error.Message)
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(resp.Result, "0x"))
+	if err != nil || len(raw)%32 != 0 {
+		return nil, fmt.Errorf("%w: malformed eth_call result %q", ErrOracleResponse, resp.Result)
+	}
+	words := make([]*big.Int, len(raw)/32)
+	for i := range words {
+		words[i] = new(big.Int).SetBytes(raw[i*32 : (i+1)*32])
+	}
+	return words, nil
+}
+
+// signedWord interprets a 256-bit word as a two's complement int256.
+func signedWord(w *big.Int) *big.Int {
+	if w.Bit(255) == 0 {
+		return w
+	}
+	return new(big.Int).Sub(w, new(big.Int).Lsh(big.NewInt(1), 256))
+}
+
+// PythFeed reads historical Pyth price updates from a Hermes endpoint
+// (e.g. https://hermes.pyth.network), sampling one update per Interval.
+type PythFeed struct {
+	// Endpoint is the Hermes base URL
+	Endpoint string
+
+	// ID is the hex price feed ID
+	ID string
+
+	// Pair is the market the feed prices
+	Pair primitives.Pair
+
+	// Interval is the spacing between sampled updates
+	Interval primitives.Duration
+
+	// Client is the HTTP client used (a client with a 30s timeout if nil)
+	Client *http.Client
+}
+
+// NewPythFeed creates a Pyth feed reader sampling every interval.
+func NewPythFeed(endpoint, id string, pair primitives.Pair, interval primitives.Duration) *PythFeed {
+	return &PythFeed{Endpoint: endpoint, ID: id, Pair: pair, Interval: interval}
+}
+
+// pythPrice is a Hermes price object; integer fields are decimal strings.
+type pythPrice struct {
+	Price       string `json:"price"`
+	Conf        string `json:"conf"`
+	Expo        int32  `json:"expo"`
+	PublishTime int64  `json:"publish_time"`
+}
+
+// Updates implements OracleSource. Each sample requests the update
+// published at or just after the sample time; samples that resolve to the
+// same publish time are reported once.
+func (f *PythFeed) Updates(ctx context.Context, from, to primitives.Time) ([]OracleUpdate, error) {
+	if f.Interval.Duration() <= 0 {
+		return nil, ErrInvalidStep
+	}
+
+	var updates []OracleUpdate
+	for t := from; !t.After(to); t = t.Add(f.Interval) {
+		update, err := f.updateAt(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read update at %s: %w", t, err)
+		}
+		if update.PublishTime.After(to) {
+			break
+		}
+		if n := len(updates); n > 0 && !update.PublishTime.After(updates[n-1].PublishTime) {
+			continue
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+func (f *PythFeed) updateAt(ctx context.Context, t primitives.Time) (OracleUpdate, error) {
+	u := strings.TrimSuffix(f.Endpoint, "/") + "/v2/updates/price/" + strconv.FormatInt(t.Unix(), 10) +
+		"?parsed=true&encoding=hex&ids[]=" + url.QueryEscape(f.ID)
+
+	var resp struct {
+		Parsed []struct {
+			ID    string    `json:"id"`
+			Price pythPrice `json:"price"`
+		} `json:"parsed"`
+	}
+	if err := getJSON(ctx, f.Client, u, &resp); err != nil {
+		return OracleUpdate{}, err
+	}
+	want := strings.TrimPrefix(strings.ToLower(f.ID), "0x")
+	for _, p := range resp.Parsed {
+		if strings.TrimPrefix(strings.ToLower(p.ID), "0x") != want {
+			continue
+		}
+		price, err := pythDecimal(p.Price.Price, p.Price.Expo)
+		if err != nil {
+			return OracleUpdate{}, err
+		}
+		conf, err := pythDecimal(p.Price.Conf, p.Price.Expo)
+		if err != nil {
+			return OracleUpdate{}, err
+		}
+		priceValue, err := primitives.NewPrice(price)
+		if err != nil {
+			return OracleUpdate{}, fmt.Errorf("%w: %s", ErrOracleResponse, err)
+		}
+		confValue, err := primitives.NewPrice(conf)
+		if err != nil {
+			return OracleUpdate{}, fmt.Errorf("%w: %s", ErrOracleResponse, err)
+		}
+		return OracleUpdate{
+			Pair:        f.Pair,
+			Price:       priceValue,
+			Confidence:  confValue,
+			PublishTime: primitives.Unix(p.Price.PublishTime, 0),
+			Round:       uint64(p.Price.PublishTime),
+		}, nil
+	}
+	return OracleUpdate{}, fmt.Errorf("%w: feed %s missing from response", ErrOracleResponse, f.ID)
+}
+
+// pythDecimal converts a Pyth integer string and exponent to a Decimal.
+func pythDecimal(value string, expo int32) (primitives.Decimal, error) {
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return primitives.Decimal{}, fmt.Errorf("%w: bad integer %q", ErrOracleResponse, value)
+	}
+	return primitives.NewDecimalFromFixed(v, -expo), nil
+}
+
+// httpTimeout bounds oracle requests made with the default client.
+const httpTimeout = 30 * time.Second
+
+func httpClient(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return &http.Client{Timeout: httpTimeout}
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(client, req, out)
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(client, req, out)
+}
+
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	return doJSONAs(client, req, out, ErrOracleResponse)
+}
+
+// doJSONAs performs req and decodes its JSON body into out, wrapping
+// error responses and undecodable bodies in sentinel.
+func doJSONAs(client *http.Client, req *http.Request, out interface{}, sentinel error) error {
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s: %s", sentinel, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: %s", sentinel, err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package marketdata
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// snapshotRecord is the recorded form of a snapshot, one JSON line each.
+type snapshotRecord struct {
+	Time       primitives.Time                      `json:"time"`
+	Prices     map[string]primitives.Price          `json:"prices"`
+	PriceTimes map[string]primitives.Time           `json:"price_times,omitempty"`
+	Depth      map[string]mechanisms.OrderBookDepth `json:"depth,omitempty"`
+	Metadata   map[string]json.RawMessage           `json:"metadata,omitempty"`
+}
+
+// Recorder persists snapshots as gzip-compressed JSON lines, so a live
+// session's market data can be replayed offline with Replayer. Each line
+// holds a snapshot's time and prices, the observation time of each price
+// (strategy.PriceTimestamper), the order book depth of each priced pair
+// (strategy.DepthSnapshot) and its metadata (strategy.MetadataLister).
+//
+// Metadata values are stored as JSON. Strings, integers and decimal types
+// replay as values strategy.GetString, GetInt and GetDecimal read back;
+// other values replay as their generic JSON decoding. OHLCV bars and quotes
+// are not recorded.
+//
+// Recorder is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	gz      *gzip.Writer
+	encoder *json.Encoder
+	count   int
+	err     error
+}
+
+// NewRecorder creates a recorder writing to w. Call Close to flush the
+// compressed stream.
+func NewRecorder(w io.Writer) *Recorder {
+	gz := gzip.NewWriter(w)
+	return &Recorder{gz: gz, encoder: json.NewEncoder(gz)}
+}
+
+// Record appends snapshot to the recording. Returns error if a metadata
+// value cannot be encoded or the write fails.
+func (r *Recorder) Record(snapshot strategy.MarketSnapshot) error {
+	record, err := newSnapshotRecord(snapshot)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to record snapshot at %s: %w", snapshot.Time(), err)
+	}
+	r.count++
+	return nil
+}
+
+// Count returns the number of snapshots recorded.
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Tee records each snapshot from feed and passes it on, for recording a
+// live.Session's feed as it runs. The returned channel closes when feed
+// closes, ctx is done or a snapshot fails to record; Err reports the
+// failure.
+func (r *Recorder) Tee(ctx context.Context, feed <-chan strategy.MarketSnapshot) <-chan strategy.MarketSnapshot {
+	out := make(chan strategy.MarketSnapshot)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case snapshot, ok := <-feed:
+				if !ok {
+					return
+				}
+				if err := r.Record(snapshot); err != nil {
+					r.mu.Lock()
+					r.err = err
+					r.mu.Unlock()
+					return
+				}
+				select {
+				case out <- snapshot:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Err returns the error that stopped a Tee, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close flushes the compressed stream. It does not close the underlying
+// writer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.gz.Close()
+}
+
+// MarshalSnapshot encodes one snapshot as JSON in the form a Recorder
+// writes, for storing snapshots outside a recording.
+func MarshalSnapshot(snapshot strategy.MarketSnapshot) ([]byte, error) {
+	record, err := newSnapshotRecord(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(record)
+}
+
+// UnmarshalSnapshot decodes a snapshot encoded by MarshalSnapshot, as
+// Replayer.Next does.
+func UnmarshalSnapshot(data []byte) (strategy.MarketSnapshot, error) {
+	var record snapshotRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return record.snapshot()
+}
+
+func newSnapshotRecord(snapshot strategy.MarketSnapshot) (snapshotRecord, error) {
+	record := snapshotRecord{Time: snapshot.Time(), Prices: snapshot.Prices()}
+	if timestamps, ok := snapshot.(strategy.PriceTimestamper); ok {
+		for pair := range record.Prices {
+			if t, ok := timestamps.PriceTime(pair); ok {
+				if record.PriceTimes == nil {
+					record.PriceTimes = make(map[string]primitives.Time)
+				}
+				record.PriceTimes[pair] = t
+			}
+		}
+	}
+	if depths, ok := snapshot.(strategy.DepthSnapshot); ok {
+		for pair := range record.Prices {
+			if depth, err := depths.Depth(pair); err == nil {
+				if record.Depth == nil {
+					record.Depth = make(map[string]mechanisms.OrderBookDepth)
+				}
+				record.Depth[pair] = depth
+			}
+		}
+	}
+	if lister, ok := snapshot.(strategy.MetadataLister); ok {
+		for _, key := range lister.MetadataKeys() {
+			value, _ := snapshot.Get(key)
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return snapshotRecord{}, fmt.Errorf("failed to record metadata %s at %s: %w", key, snapshot.Time(), err)
+			}
+			if record.Metadata == nil {
+				record.Metadata = make(map[string]json.RawMessage)
+			}
+			record.Metadata[key] = encoded
+		}
+	}
+	return record, nil
+}
+
+// Replayer reads back snapshots written by a Recorder, in recorded order.
+type Replayer struct {
+	gz      *gzip.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+// maxRecordSize bounds the length of one recorded snapshot line.
+const maxRecordSize = 64 << 20
+
+// NewReplayer creates a replayer reading from r. Returns error if r is not
+// a gzip stream.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(nil, maxRecordSize)
+	return &Replayer{gz: gz, scanner: scanner}, nil
+}
+
+// Next returns the next recorded snapshot, a strategy.SimpleSnapshot (or
+// strategy.SimpleDepthSnapshot if depth was recorded). Returns io.EOF
+// after the last one.
+func (p *Replayer) Next() (strategy.MarketSnapshot, error) {
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read recording after line %d: %w", p.line, err)
+		}
+		return nil, io.EOF
+	}
+	p.line++
+	var record snapshotRecord
+	if err := json.Unmarshal(p.scanner.Bytes(), &record); err != nil {
+		return nil, fmt.Errorf("invalid record on line %d: %w", p.line, err)
+	}
+	snapshot, err := record.snapshot()
+	if err != nil {
+		return nil, fmt.Errorf("invalid record on line %d: %w", p.line, err)
+	}
+	return snapshot, nil
+}
+
+// Close releases the decompressor. It does not close the underlying reader.
+func (p *Replayer) Close() error {
+	return p.gz.Close()
+}
+
+// ReadSnapshots reads a whole recording, for replay through
+// backtest.Engine.Run.
+func ReadSnapshots(r io.Reader) ([]strategy.MarketSnapshot, error) {
+	replayer, err := NewReplayer(r)
+	if err != nil {
+		return nil, err
+	}
+	defer replayer.Close()
+	var snapshots []strategy.MarketSnapshot
+	for {
+		snapshot, err := replayer.Next()
+		if errors.Is(err, io.EOF) {
+			return snapshots, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+}
+
+func (r snapshotRecord) snapshot() (strategy.MarketSnapshot, error) {
+	if r.Prices == nil {
+		r.Prices = make(map[string]primitives.Price)
+	}
+	var snapshot strategy.MarketSnapshot
+	var simple *strategy.SimpleSnapshot
+	if len(r.Depth) == 0 {
+		simple = strategy.NewSimpleSnapshot(r.Time, r.Prices)
+		snapshot = simple
+	} else {
+		depths := strategy.NewSimpleDepthSnapshot(r.Time, r.Prices)
+		simple = depths.SimpleSnapshot
+		for pair, depth := range r.Depth {
+			if err := depths.SetDepth(pair, depth); err != nil {
+				return nil, err
+			}
+		}
+		snapshot = depths
+	}
+	for pair, t := range r.PriceTimes {
+		simple.SetPriceTime(pair, t)
+	}
+	for key, encoded := range r.Metadata {
+		value, err := metadataValue(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %s: %w", key, err)
+		}
+		simple.Set(key, value)
+	}
+	return snapshot, nil
+}
+
+// metadataValue decodes a recorded metadata value: integers as int, other
+// numbers as primitives.Decimal from their literal, and anything else by
+// its generic JSON decoding.
+func metadataValue(encoded json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return value, nil
+	}
+	if n, err := strconv.Atoi(number.String()); err == nil {
+		return n, nil
+	}
+	return primitives.NewDecimalFromString(number.String())
+}
@@ -0,0 +1,299 @@
+// Package marketdata provides utilities for preparing market snapshot series
+// before they are fed to the backtest engine: merging streams from different
+// sources, resampling between granularities and aligning on a common grid.
+//
+// All utilities are look-ahead free: a snapshot emitted at time t only
+// contains data observed at or before t.
+package marketdata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrUnsortedStream indicates snapshots are not in chronological order
+	ErrUnsortedStream = errors.New("snapshot stream is not sorted by time")
+
+	// ErrInvalidStep indicates a non-positive resampling step
+	ErrInvalidStep = errors.New("resampling step must be positive")
+
+	// ErrEmptyStream indicates an operation received no snapshots
+	ErrEmptyStream = errors.New("snapshot stream is empty")
+)
+
+// Grid returns the times start, start+step, ... up to and including end.
+func Grid(start, end primitives.Time, step primitives.Duration) ([]primitives.Time, error) {
+	if step.Duration() <= 0 {
+		return nil, ErrInvalidStep
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("grid end %s before start %s", end, start)
+	}
+	grid := make([]primitives.Time, 0, int(end.Sub(start).Duration()/step.Duration())+1)
+	for t := start; !t.After(end); t = t.Add(step) {
+		grid = append(grid, t)
+	}
+	return grid, nil
+}
+
+// ForwardFill aligns a stream onto grid. Each grid time receives the most
+// recent snapshot at or before it, re-stamped with the grid time.
+//
+// Grid times before the first snapshot are omitted. If maxStaleness is
+// positive, grid times whose latest snapshot is older than maxStaleness
+// are also omitted rather than filled with stale data.
+func ForwardFill(
+	stream []strategy.MarketSnapshot,
+	grid []primitives.Time,
+	maxStaleness primitives.Duration,
+) ([]strategy.MarketSnapshot, error) {
+	if err := validateStream(stream); err != nil {
+		return nil, err
+	}
+
+	out := make([]strategy.MarketSnapshot, 0, len(grid))
+	cursor := newCursor(stream)
+	for _, t := range grid {
+		latest, ok := cursor.latestAt(t, maxStaleness)
+		if !ok {
+			continue
+		}
+		out = append(out, restamp(t, latest))
+	}
+	return out, nil
+}
+
+// Upsample fills a sparse stream onto a finer regular grid from its first
+// to its last snapshot, forward-filling between observations.
+func Upsample(stream []strategy.MarketSnapshot, step primitives.Duration) ([]strategy.MarketSnapshot, error) {
+	if len(stream) == 0 {
+		return nil, ErrEmptyStream
+	}
+	grid, err := Grid(stream[0].Time(), stream[len(stream)-1].Time(), step)
+	if err != nil {
+		return nil, err
+	}
+	return ForwardFill(stream, grid, primitives.Duration{})
+}
+
+// Downsample reduces a dense stream to one snapshot per step.
+//
+// Buckets are right-closed intervals (g-step, g] on a grid starting at the
+// first snapshot's time; each bucket emits the last snapshot it contains,
+// stamped with the bucket's grid time g. When every snapshot in a bucket
+// implements strategy.BarSnapshot, bars are aggregated (first open, max high,
+// min low, last close, summed volume) into a strategy.OHLCVSnapshot.
+// Empty buckets are skipped.
+func Downsample(stream []strategy.MarketSnapshot, step primitives.Duration) ([]strategy.MarketSnapshot, error) {
+	if len(stream) == 0 {
+		return nil, ErrEmptyStream
+	}
+	if step.Duration() <= 0 {
+		return nil, ErrInvalidStep
+	}
+	if err := validateStream(stream); err != nil {
+		return nil, err
+	}
+
+	origin := stream[0].Time()
+	out := make([]strategy.MarketSnapshot, 0)
+	var bucket []strategy.MarketSnapshot
+	bucketEnd := origin
+
+	flush := func() error {
+		if len(bucket) == 0 {
+			return nil
+		}
+		snap, err := aggregate(bucketEnd, bucket)
+		if err != nil {
+			return err
+		}
+		out = append(out, snap)
+		bucket = bucket[:0]
+		return nil
+	}
+
+	for _, snap := range stream {
+		if snap.Time().After(bucketEnd) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			// Advance to the first grid time at or after this snapshot
+			steps := (snap.Time().Sub(bucketEnd).Duration() + step.Duration() - 1) / step.Duration()
+			bucketEnd = bucketEnd.Add(step.Mul(int64(steps)))
+		}
+		bucket = append(bucket, snap)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Merge aligns several streams onto grid and combines them into a single
+// snapshot per grid time. Prices and metadata are unioned; when streams
+// share a pair or key, later streams take precedence.
+//
+// A grid time is emitted only when every stream has data for it (subject to
+// maxStaleness, see ForwardFill), so the merged series is coherent.
+func Merge(
+	grid []primitives.Time,
+	maxStaleness primitives.Duration,
+	streams ...[]strategy.MarketSnapshot,
+) ([]strategy.MarketSnapshot, error) {
+	cursors := make([]*cursor, len(streams))
+	for i, stream := range streams {
+		if err := validateStream(stream); err != nil {
+			return nil, fmt.Errorf("stream %d: %w", i, err)
+		}
+		cursors[i] = newCursor(stream)
+	}
+
+	out := make([]strategy.MarketSnapshot, 0, len(grid))
+	sources := make([]strategy.MarketSnapshot, len(streams))
+	for _, t := range grid {
+		complete := true
+		for i, c := range cursors {
+			latest, ok := c.latestAt(t, maxStaleness)
+			if !ok {
+				complete = false
+			}
+			sources[i] = latest
+		}
+		if !complete {
+			continue
+		}
+		out = append(out, restamp(t, sources...))
+	}
+	return out, nil
+}
+
+// validateStream checks that snapshots are in non-decreasing time order.
+func validateStream(stream []strategy.MarketSnapshot) error {
+	for i := 1; i < len(stream); i++ {
+		if stream[i].Time().Before(stream[i-1].Time()) {
+			return fmt.Errorf("%w: snapshot %d at %s precedes snapshot %d",
+				ErrUnsortedStream, i, stream[i].Time(), i-1)
+		}
+	}
+	return nil
+}
+
+// cursor walks a sorted stream to find the latest snapshot at or before a time.
+// Queries must be made in non-decreasing time order.
+type cursor struct {
+	stream []strategy.MarketSnapshot
+	next   int
+}
+
+func newCursor(stream []strategy.MarketSnapshot) *cursor {
+	return &cursor{stream: stream}
+}
+
+// latestAt returns the latest snapshot at or before t, honouring maxStaleness when positive.
+func (c *cursor) latestAt(t primitives.Time, maxStaleness primitives.Duration) (strategy.MarketSnapshot, bool) {
+	for c.next < len(c.stream) && !c.stream[c.next].Time().After(t) {
+		c.next++
+	}
+	if c.next == 0 {
+		return nil, false
+	}
+	latest := c.stream[c.next-1]
+	if maxStaleness.Duration() > 0 && t.Sub(latest.Time()).GreaterThan(maxStaleness) {
+		return nil, false
+	}
+	return latest, true
+}
+
+// restamp builds a SimpleSnapshot at t combining prices and metadata from sources.
+// Later sources override earlier ones.
+func restamp(t primitives.Time, sources ...strategy.MarketSnapshot) *strategy.SimpleSnapshot {
+	prices := make(map[string]primitives.Price)
+	for _, src := range sources {
+		for pair, price := range src.Prices() {
+			prices[pair] = price
+		}
+	}
+	snap := strategy.NewSimpleSnapshot(t, prices)
+	for _, src := range sources {
+		copyMetadata(snap, src)
+	}
+	return snap
+}
+
+// copyMetadata copies metadata from src into dst when src can enumerate its keys.
+func copyMetadata(dst *strategy.SimpleSnapshot, src strategy.MarketSnapshot) {
+	lister, ok := src.(strategy.MetadataLister)
+	if !ok {
+		return
+	}
+	for _, key := range lister.MetadataKeys() {
+		if v, ok := src.Get(key); ok {
+			dst.Set(key, v)
+		}
+	}
+}
+
+// aggregate collapses a bucket into a single snapshot stamped at t.
+func aggregate(t primitives.Time, bucket []strategy.MarketSnapshot) (strategy.MarketSnapshot, error) {
+	last := bucket[len(bucket)-1]
+
+	barSnaps := make([]strategy.BarSnapshot, 0, len(bucket))
+	for _, snap := range bucket {
+		bs, ok := snap.(strategy.BarSnapshot)
+		if !ok {
+			return restamp(t, last), nil
+		}
+		barSnaps = append(barSnaps, bs)
+	}
+
+	bars := make(map[string]strategy.Bar)
+	for _, bs := range barSnaps {
+		for pair := range bs.Prices() {
+			bar, err := bs.Bar(pair)
+			if err != nil {
+				continue
+			}
+			agg, seen := bars[pair]
+			if !seen {
+				bars[pair] = bar
+				continue
+			}
+			if bar.High.GreaterThan(agg.High) {
+				agg.High = bar.High
+			}
+			if bar.Low.LessThan(agg.Low) {
+				agg.Low = bar.Low
+			}
+			agg.Close = bar.Close
+			agg.Volume = agg.Volume.Add(bar.Volume)
+			bars[pair] = agg
+		}
+	}
+
+	quotes := make(map[string]strategy.Quote)
+	if qs, ok := last.(strategy.QuoteSnapshot); ok {
+		for pair := range last.Prices() {
+			if q, err := qs.Quote(pair); err == nil {
+				quotes[pair] = q
+			}
+		}
+	}
+
+	snap, err := strategy.NewOHLCVSnapshot(t, bars, quotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate bars at %s: %w", t, err)
+	}
+	if lister, ok := last.(strategy.MetadataLister); ok {
+		for _, key := range lister.MetadataKeys() {
+			if v, ok := last.Get(key); ok {
+				snap.Set(key, v)
+			}
+		}
+	}
+	return snap, nil
+}
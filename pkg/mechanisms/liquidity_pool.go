@@ -63,6 +63,17 @@ type LiquidityPool interface {
 	RemoveLiquidity(ctx context.Context, position PoolPosition) (TokenAmounts, error)
 }
 
+// SwapQuoter is an optional LiquidityPool extension for pools that can
+// quote a swap against given parameters. Routers such as the aggregator
+// implementation use it when available and fall back to constant-product
+// math over ReserveA/ReserveB and FeeRate otherwise.
+type SwapQuoter interface {
+	// QuoteSwap returns the output of swapping amountIn through the pool
+	// without modifying pool state. A buy pays token B for token A
+	// (amountIn in B, output in A); a sell pays token A for token B.
+	QuoteSwap(ctx context.Context, params PoolParams, side OrderSide, amountIn primitives.Amount) (primitives.Amount, error)
+}
+
 // PoolParams contains pool-specific parameters for calculations.
 // Different pool types use different subsets of these parameters.
 //
@@ -22,6 +22,14 @@ const (
 	// (e.g., CEX-style limit order books)
 	MechanismTypeOrderBook MechanismType = "orderbook"
 
+	// MechanismTypeAggregator represents routers that split execution
+	// across other mechanisms (e.g., 1inch, 0x, CowSwap)
+	MechanismTypeAggregator MechanismType = "aggregator"
+
+	// MechanismTypeLending represents over-collateralized lending markets
+	// (e.g., Aave, Compound, Morpho)
+	MechanismTypeLending MechanismType = "lending"
+
 	// Additional types can be defined as needed:
 	// MechanismTypeBatchAuction, MechanismTypeFlashLoan,
 	// MechanismTypeIntentPool, MechanismTypeBridge, etc.
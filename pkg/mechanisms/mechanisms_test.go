@@ -1,157 +1,292 @@
 package mechanisms_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms/mechanismtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 )
 
-// This file provides a framework for property-based testing of mechanism interfaces.
-// As implementations are added, they should be tested against these interface contracts
-// to ensure substitutability and correct behavior.
-//
-// Testing Strategy:
-//   1. Interface Compliance: Verify implementations satisfy interface contracts
-//   2. Property-Based Tests: Validate invariants hold for all implementations
-//   3. Error Handling: Ensure implementations return errors for invalid inputs
-//   4. Edge Cases: Test boundary conditions and special values
-
-// TestMarketMechanismInterface is a placeholder for interface contract tests.
-// When implementations are added, this test should verify that all implementations
-// correctly implement the MarketMechanism interface.
-func TestMarketMechanismInterface(t *testing.T) {
-	t.Skip("Placeholder: implement when concrete mechanisms are available")
-
-	// Future test structure:
-	// 1. Instantiate each mechanism implementation
-	// 2. Verify Mechanism() returns expected type
-	// 3. Verify Venue() returns valid string
-	// 4. Verify interface methods can be called without panic
-}
-
-// TestLiquidityPoolInterface is a placeholder for LiquidityPool contract tests.
-// Property-based tests should verify:
-//   - Calculate is deterministic (same params = same result)
-//   - AddLiquidity + RemoveLiquidity roundtrip preserves value (minus fees)
-//   - Token amounts are never negative
-//   - Pool state values are internally consistent
-func TestLiquidityPoolInterface(t *testing.T) {
-	t.Skip("Placeholder: implement when LiquidityPool implementations are available")
-
-	// Future property-based tests:
-	// Property 1: Calculate is pure (no side effects)
-	// Property 2: AddLiquidity returns valid position
-	// Property 3: RemoveLiquidity(AddLiquidity(x)) ≈ x (within fee tolerance)
-	// Property 4: Pool state prices are positive
-	// Property 5: Invalid inputs return errors, not panics
-}
-
-// TestDerivativeInterface is a placeholder for Derivative contract tests.
-// Property-based tests should verify:
-//   - Price is always non-negative
-//   - Greeks are within expected ranges (e.g., delta in [-1, 1] for options)
-//   - Price approaches intrinsic value as expiry approaches
-//   - Greeks change smoothly with parameters (no discontinuities)
-func TestDerivativeInterface(t *testing.T) {
-	t.Skip("Placeholder: implement when Derivative implementations are available")
-
-	// Future property-based tests:
-	// Property 1: Price >= 0 for all valid params
-	// Property 2: Call delta in [0, 1], put delta in [-1, 0]
-	// Property 3: Gamma >= 0 for long options
-	// Property 4: Price converges to intrinsic value at expiry
-	// Property 5: Greeks calculations are numerically stable
-}
-
-// TestOrderBookInterface is a placeholder for OrderBook contract tests.
-// Property-based tests should verify:
-//   - BestBid <= BestAsk (no crossed book)
-//   - PlaceOrder returns unique IDs
-//   - CancelOrder works for valid IDs
-//   - Order validation prevents invalid orders
-func TestOrderBookInterface(t *testing.T) {
-	t.Skip("Placeholder: implement when OrderBook implementations are available")
-
-	// Future property-based tests:
-	// Property 1: BestBid <= BestAsk always
-	// Property 2: PlaceOrder returns unique OrderID
-	// Property 3: CancelOrder(PlaceOrder(x)) succeeds
-	// Property 4: Invalid orders return errors
-	// Property 5: Depth levels are sorted correctly
-}
-
-// testMechanismContract is a helper that will be used to test any MarketMechanism.
-// This ensures all mechanisms implement the base interface correctly.
-func testMechanismContract(t *testing.T, m mechanisms.MarketMechanism, expectedType mechanisms.MechanismType) {
-	t.Helper()
-
-	// Verify mechanism type
-	if m.Mechanism() != expectedType {
-		t.Errorf("expected mechanism type %s, got %s", expectedType, m.Mechanism())
-	}
-
-	// Verify venue returns something (even if empty string is valid)
-	venue := m.Venue()
-	_ = venue // venue can be empty, but shouldn't panic
-}
-
-// testLiquidityPoolContract is a helper that will test LiquidityPool implementations.
-// This will be called by implementation-specific tests to verify interface contracts.
-func testLiquidityPoolContract(t *testing.T, pool mechanisms.LiquidityPool) {
-	t.Helper()
-
-	// Verify base mechanism contract
-	testMechanismContract(t, pool, mechanisms.MechanismTypeLiquidityPool)
-
-	// TODO: Add property-based tests when implementations exist
-	// Examples:
-	// - Test Calculate with various parameters
-	// - Test AddLiquidity/RemoveLiquidity roundtrip
-	// - Test error handling for invalid inputs
-}
-
-// testDerivativeContract is a helper that will test Derivative implementations.
-func testDerivativeContract(t *testing.T, deriv mechanisms.Derivative) {
-	t.Helper()
+// The property suites live in package mechanismtest so implementations can
+// run them from their own tests. These tests exercise each suite against a
+// minimal reference implementation of the interface.
 
-	// Verify base mechanism contract
-	testMechanismContract(t, deriv, mechanisms.MechanismTypeDerivative)
+func randomAmount(r *rand.Rand, max int64) primitives.Amount {
+	return primitives.MustAmount(primitives.NewDecimal(r.Int63n(max) + 1))
+}
 
-	// TODO: Add property-based tests when implementations exist
-	// Examples:
-	// - Test Price with various market conditions
-	// - Test Greeks ranges and relationships
-	// - Test Settle calculations
+// constantProductPool prices by reserve ratio and charges a fee on withdrawal.
+type constantProductPool struct {
+	fee primitives.Decimal
 }
 
-// testOrderBookContract is a helper that will test OrderBook implementations.
-func testOrderBookContract(t *testing.T, book mechanisms.OrderBook) {
-	t.Helper()
+func (p *constantProductPool) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeLiquidityPool
+}
 
-	// Verify base mechanism contract
-	testMechanismContract(t, book, mechanisms.MechanismTypeOrderBook)
+func (p *constantProductPool) Venue() string { return "reference" }
 
-	// TODO: Add property-based tests when implementations exist
-	// Examples:
-	// - Test BestBid/BestAsk ordering
-	// - Test PlaceOrder/CancelOrder lifecycle
-	// - Test Depth ordering and consistency
+func (p *constantProductPool) Calculate(ctx context.Context, params mechanisms.PoolParams) (mechanisms.PoolState, error) {
+	if params.ReserveA.IsZero() {
+		return mechanisms.PoolState{}, errors.New("reserve A required")
+	}
+	price, err := params.ReserveB.DivPrice(primitives.MustPrice(params.ReserveA.Decimal()))
+	if err != nil {
+		return mechanisms.PoolState{}, err
+	}
+	return mechanisms.PoolState{
+		SpotPrice:          primitives.MustPrice(price.Decimal()),
+		Liquidity:          params.ReserveA,
+		EffectiveLiquidity: params.ReserveA,
+	}, nil
 }
 
-// Exported test helpers that implementations should use:
+func (p *constantProductPool) AddLiquidity(ctx context.Context, amounts mechanisms.TokenAmounts) (mechanisms.PoolPosition, error) {
+	return mechanisms.PoolPosition{PoolID: "reference", Liquidity: amounts.AmountA, TokensDeposited: amounts}, nil
+}
 
-// VerifyLiquidityPoolContract should be called by any LiquidityPool implementation test.
-// It verifies the implementation satisfies the interface contract.
-func VerifyLiquidityPoolContract(t *testing.T, pool mechanisms.LiquidityPool) {
-	testLiquidityPoolContract(t, pool)
+func (p *constantProductPool) RemoveLiquidity(ctx context.Context, position mechanisms.PoolPosition) (mechanisms.TokenAmounts, error) {
+	if position.PoolID != "reference" {
+		return mechanisms.TokenAmounts{}, errors.New("unknown position")
+	}
+	keep := primitives.One().Sub(p.fee)
+	return mechanisms.TokenAmounts{
+		AmountA: position.TokensDeposited.AmountA.Mul(keep),
+		AmountB: position.TokensDeposited.AmountB.Mul(keep),
+	}, nil
 }
 
-// VerifyDerivativeContract should be called by any Derivative implementation test.
-func VerifyDerivativeContract(t *testing.T, deriv mechanisms.Derivative) {
-	testDerivativeContract(t, deriv)
+// quotingPool adds constant-product swap quotes to constantProductPool.
+type quotingPool struct {
+	constantProductPool
 }
 
-// VerifyOrderBookContract should be called by any OrderBook implementation test.
-func VerifyOrderBookContract(t *testing.T, book mechanisms.OrderBook) {
-	testOrderBookContract(t, book)
+func (p *quotingPool) QuoteSwap(ctx context.Context, params mechanisms.PoolParams, side mechanisms.OrderSide, amountIn primitives.Amount) (primitives.Amount, error) {
+	reserveIn, reserveOut := params.ReserveB, params.ReserveA
+	if side == mechanisms.OrderSideSell {
+		reserveIn, reserveOut = params.ReserveA, params.ReserveB
+	}
+	in := amountIn.Decimal().Mul(primitives.One().Sub(p.fee))
+	out, err := reserveOut.Decimal().Mul(in).Div(reserveIn.Decimal().Add(in))
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	return primitives.NewAmount(out)
+}
+
+// linearDerivative is a delta-one instrument priced at the mark.
+type linearDerivative struct{}
+
+func (d linearDerivative) Mechanism() mechanisms.MechanismType {
+	return mechanisms.MechanismTypeDerivative
+}
+
+func (d linearDerivative) Venue() string { return "reference" }
+
+func (d linearDerivative) Price(ctx context.Context, params mechanisms.PriceParams) (primitives.Price, error) {
+	if params.MarkPrice.IsZero() {
+		return primitives.Price{}, errors.New("mark price required")
+	}
+	return params.MarkPrice, nil
+}
+
+func (d linearDerivative) Greeks(ctx context.Context, params mechanisms.PriceParams) (mechanisms.Greeks, error) {
+	return mechanisms.Greeks{Delta: primitives.One()}, nil
+}
+
+func (d linearDerivative) Settle(ctx context.Context) (primitives.Amount, error) {
+	return primitives.ZeroAmount(), nil
+}
+
+// memoryBook is an in-memory limit order book without matching.
+type memoryBook struct {
+	next   int
+	orders map[mechanisms.OrderID]mechanisms.Order
+}
+
+func (b *memoryBook) Mechanism() mechanisms.MechanismType { return mechanisms.MechanismTypeOrderBook }
+
+func (b *memoryBook) Venue() string { return "reference" }
+
+func (b *memoryBook) best(side mechanisms.OrderSide) (primitives.Price, primitives.Amount, error) {
+	levels := b.levels(side)
+	if len(levels) == 0 {
+		return primitives.Price{}, primitives.Amount{}, fmt.Errorf("no %s orders", side)
+	}
+	return levels[0].Price, levels[0].Size, nil
+}
+
+func (b *memoryBook) BestBid(ctx context.Context) (primitives.Price, primitives.Amount, error) {
+	return b.best(mechanisms.OrderSideBuy)
+}
+
+func (b *memoryBook) BestAsk(ctx context.Context) (primitives.Price, primitives.Amount, error) {
+	return b.best(mechanisms.OrderSideSell)
+}
+
+func (b *memoryBook) PlaceOrder(ctx context.Context, order mechanisms.Order) (mechanisms.OrderID, error) {
+	b.next++
+	id := mechanisms.OrderID(fmt.Sprintf("order-%d", b.next))
+	b.orders[id] = order
+	return id, nil
+}
+
+func (b *memoryBook) CancelOrder(ctx context.Context, id mechanisms.OrderID) error {
+	if _, ok := b.orders[id]; !ok {
+		return fmt.Errorf("order %s not found", id)
+	}
+	delete(b.orders, id)
+	return nil
+}
+
+func (b *memoryBook) Depth(ctx context.Context, levels int) (mechanisms.OrderBookDepth, error) {
+	depth := mechanisms.OrderBookDepth{Bids: b.levels(mechanisms.OrderSideBuy), Asks: b.levels(mechanisms.OrderSideSell)}
+	if len(depth.Bids) > levels {
+		depth.Bids = depth.Bids[:levels]
+	}
+	if len(depth.Asks) > levels {
+		depth.Asks = depth.Asks[:levels]
+	}
+	return depth, nil
+}
+
+// levels aggregates resting orders by price, best first.
+func (b *memoryBook) levels(side mechanisms.OrderSide) []mechanisms.PriceLevel {
+	byPrice := make(map[string]*mechanisms.PriceLevel)
+	for _, order := range b.orders {
+		if order.Side != side {
+			continue
+		}
+		key := order.Price.String()
+		level, ok := byPrice[key]
+		if !ok {
+			level = &mechanisms.PriceLevel{Price: order.Price, Size: primitives.ZeroAmount()}
+			byPrice[key] = level
+		}
+		level.Size = level.Size.Add(order.Size)
+		level.OrderCount++
+	}
+	levels := make([]mechanisms.PriceLevel, 0, len(byPrice))
+	for _, level := range byPrice {
+		levels = append(levels, *level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if side == mechanisms.OrderSideBuy {
+			return levels[i].Price.GreaterThan(levels[j].Price)
+		}
+		return levels[i].Price.LessThan(levels[j].Price)
+	})
+	return levels
+}
+
+func TestLiquidityPoolContract(t *testing.T) {
+	mechanismtest.VerifyLiquidityPool(t, &constantProductPool{fee: primitives.MustDecimalFromString("0.003")}, mechanismtest.LiquidityPoolConfig{
+		Params: func(r *rand.Rand) mechanisms.PoolParams {
+			return mechanisms.PoolParams{ReserveA: randomAmount(r, 1_000_000), ReserveB: randomAmount(r, 1_000_000)}
+		},
+		Deposits: func(r *rand.Rand) mechanisms.TokenAmounts {
+			return mechanisms.TokenAmounts{AmountA: randomAmount(r, 1000), AmountB: randomAmount(r, 1000)}
+		},
+		Positions: func(r *rand.Rand) mechanisms.PoolPosition {
+			return mechanisms.PoolPosition{
+				PoolID:          "reference",
+				TokensDeposited: mechanisms.TokenAmounts{AmountA: randomAmount(r, 1000), AmountB: randomAmount(r, 1000)},
+			}
+		},
+		Tolerance: primitives.MustDecimalFromString("0.003"),
+	})
+}
+
+func TestDerivativeContract(t *testing.T) {
+	mechanismtest.VerifyDerivative(t, linearDerivative{}, mechanismtest.DerivativeConfig{
+		Params: func(r *rand.Rand) mechanisms.PriceParams {
+			return mechanisms.PriceParams{MarkPrice: primitives.MustPrice(primitives.NewDecimal(r.Int63n(100000) + 1))}
+		},
+		NonNegativeGamma: true,
+	})
+}
+
+func TestOrderBookContract(t *testing.T) {
+	book := &memoryBook{orders: make(map[mechanisms.OrderID]mechanisms.Order)}
+	mechanismtest.VerifyOrderBook(t, book, mechanismtest.OrderBookConfig{
+		Orders: func(r *rand.Rand) mechanisms.Order {
+			// Bids rest below 100 and asks above, so the book never crosses
+			order := mechanisms.Order{Side: mechanisms.OrderSideBuy, Type: mechanisms.OrderTypeLimit, Size: randomAmount(r, 10)}
+			offset := r.Int63n(50) + 1
+			order.Price = primitives.MustPrice(primitives.NewDecimal(100 - offset))
+			if r.Intn(2) == 0 {
+				order.Side = mechanisms.OrderSideSell
+				order.Price = primitives.MustPrice(primitives.NewDecimal(100 + offset))
+			}
+			return order
+		},
+		DepthLevels: 5,
+	})
+}
+
+func TestConformance(t *testing.T) {
+	fee := primitives.MustDecimalFromString("0.003")
+	poolConfig := mechanismtest.LiquidityPoolConfig{
+		Params: func(r *rand.Rand) mechanisms.PoolParams {
+			return mechanisms.PoolParams{ReserveA: randomAmount(r, 1_000_000), ReserveB: randomAmount(r, 1_000_000)}
+		},
+		Iterations: 20,
+	}
+	report := mechanismtest.Conformance(t, mechanismtest.Suite{
+		Pools: []mechanismtest.PoolSubject{
+			{Name: "constant-product", Pool: &constantProductPool{fee: fee}, Config: poolConfig},
+			{Name: "quoting", Pool: &quotingPool{constantProductPool{fee: fee}}, Config: poolConfig},
+		},
+		Derivatives: []mechanismtest.DerivativeSubject{{
+			Name:       "linear",
+			Derivative: linearDerivative{},
+			Config: mechanismtest.DerivativeConfig{
+				Params: func(r *rand.Rand) mechanisms.PriceParams {
+					return mechanisms.PriceParams{MarkPrice: primitives.MustPrice(primitives.NewDecimal(r.Int63n(100000) + 1))}
+				},
+			},
+		}},
+		OrderBooks: []mechanismtest.OrderBookSubject{{
+			Name: "memory",
+			New: func() mechanisms.OrderBook {
+				return &memoryBook{orders: make(map[mechanisms.OrderID]mechanisms.Order)}
+			},
+			Config: mechanismtest.OrderBookConfig{
+				Orders: func(r *rand.Rand) mechanisms.Order {
+					return mechanisms.Order{Side: mechanisms.OrderSideBuy, Type: mechanisms.OrderTypeLimit, Size: randomAmount(r, 10),
+						Price: primitives.MustPrice(primitives.NewDecimal(r.Int63n(99) + 1))}
+				},
+			},
+		}},
+	})
+
+	if !report.Passed() {
+		t.Fatalf("reference implementations failed conformance:\n%s", report.Markdown())
+	}
+	status := make(map[string]mechanismtest.Status)
+	for _, result := range report.Results {
+		status[result.Implementation+"/"+result.Check] = result.Status
+	}
+	for key, want := range map[string]mechanismtest.Status{
+		"constant-product/mechanism":            mechanismtest.StatusPass,
+		"constant-product/quote swap":           mechanismtest.StatusSkip,
+		"constant-product/add remove roundtrip": mechanismtest.StatusSkip,
+		"quoting/quote swap":                    mechanismtest.StatusPass,
+		"linear/greeks":                         mechanismtest.StatusPass,
+		"memory/orders":                         mechanismtest.StatusPass,
+	} {
+		if status[key] != want {
+			t.Errorf("%s = %q, want %q", key, status[key], want)
+		}
+	}
+	if markdown := report.Markdown(); !strings.Contains(markdown, "| quoting | liquidity_pool | quote swap | pass |") || !strings.Contains(markdown, "skipped") {
+		t.Errorf("unexpected report:\n%s", markdown)
+	}
 }
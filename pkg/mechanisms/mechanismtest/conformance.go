@@ -0,0 +1,201 @@
+package mechanismtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+)
+
+// Status is the outcome of a conformance check.
+type Status string
+
+const (
+	// StatusPass is a check every generated case satisfied
+	StatusPass Status = "pass"
+
+	// StatusFail is a check with a counterexample or error
+	StatusFail Status = "fail"
+
+	// StatusSkip is a check not run because its generator was not
+	// configured or the implementation lacks the optional interface
+	StatusSkip Status = "skip"
+)
+
+// CheckResult is the outcome of one check against one implementation.
+type CheckResult struct {
+	Implementation string
+	Mechanism      mechanisms.MechanismType
+	Check          string
+	Status         Status
+
+	// Detail is the failure or the reason for skipping
+	Detail string
+}
+
+// Report is the compliance report of a Conformance run.
+type Report struct {
+	Results []CheckResult
+}
+
+// Passed reports whether no check failed.
+func (r *Report) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the failed checks.
+func (r *Report) Failures() []CheckResult {
+	var failures []CheckResult
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Markdown renders the report as a Markdown table with one row per check,
+// followed by totals.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Implementation | Mechanism | Check | Status | Detail |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	counts := make(map[Status]int)
+	for _, result := range r.Results {
+		counts[result.Status]++
+		detail := strings.ReplaceAll(result.Detail, "|", "\\|")
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+			result.Implementation, result.Mechanism, result.Check, result.Status, detail)
+	}
+	fmt.Fprintf(&b, "\n%d passed, %d failed, %d skipped\n", counts[StatusPass], counts[StatusFail], counts[StatusSkip])
+	return b.String()
+}
+
+// PoolSubject is a liquidity pool implementation under conformance test.
+type PoolSubject struct {
+	Name   string
+	Pool   mechanisms.LiquidityPool
+	Config LiquidityPoolConfig
+}
+
+// DerivativeSubject is a derivative implementation under conformance test.
+type DerivativeSubject struct {
+	Name       string
+	Derivative mechanisms.Derivative
+	Config     DerivativeConfig
+}
+
+// OrderBookSubject is an order book implementation under conformance
+// test. New returns a fresh, empty book, as the checks mutate it.
+type OrderBookSubject struct {
+	Name   string
+	New    func() mechanisms.OrderBook
+	Config OrderBookConfig
+}
+
+// Suite lists the implementations a Conformance run checks.
+type Suite struct {
+	Pools       []PoolSubject
+	Derivatives []DerivativeSubject
+	OrderBooks  []OrderBookSubject
+}
+
+// Conformance runs the contract checks of VerifyLiquidityPool,
+// VerifyDerivative and VerifyOrderBook against every implementation in
+// suite, each as a subtest named after its mechanism and Name, and returns
+// the compliance report. Failures fail t as the Verify functions do; the
+// report is also logged, and can be written out for publishing alongside
+// an implementation:
+//
+//	func TestConformance(t *testing.T) {
+//	    report := mechanismtest.Conformance(t, mechanismtest.Suite{
+//	        Pools: []mechanismtest.PoolSubject{{Name: "my-amm", Pool: pool, Config: cfg}},
+//	    })
+//	    _ = os.WriteFile("conformance.md", []byte(report.Markdown()), 0o644)
+//	}
+//
+// and run with go test ./... -run Conformance.
+func Conformance(t *testing.T, suite Suite) *Report {
+	t.Helper()
+	report := &Report{}
+	for _, subject := range suite.Pools {
+		s := &session{report: report, implementation: subject.Name, mechanism: mechanisms.MechanismTypeLiquidityPool}
+		t.Run(subjectName(s.mechanism, subject.Name), func(t *testing.T) {
+			verifyLiquidityPool(t, s, subject.Pool, subject.Config)
+		})
+	}
+	for _, subject := range suite.Derivatives {
+		s := &session{report: report, implementation: subject.Name, mechanism: mechanisms.MechanismTypeDerivative}
+		t.Run(subjectName(s.mechanism, subject.Name), func(t *testing.T) {
+			verifyDerivative(t, s, subject.Derivative, subject.Config)
+		})
+	}
+	for _, subject := range suite.OrderBooks {
+		s := &session{report: report, implementation: subject.Name, mechanism: mechanisms.MechanismTypeOrderBook}
+		t.Run(subjectName(s.mechanism, subject.Name), func(t *testing.T) {
+			if subject.New == nil {
+				t.Fatal("mechanismtest: OrderBookSubject.New is required")
+			}
+			verifyOrderBook(t, s, subject.New(), subject.Config)
+		})
+	}
+	t.Logf("mechanism conformance report:\n%s", report.Markdown())
+	return report
+}
+
+// subjectName returns the subtest name of an implementation.
+func subjectName(mechanism mechanisms.MechanismType, name string) string {
+	return string(mechanism) + "/" + name
+}
+
+// session runs the checks of one implementation, recording their outcomes
+// in report when there is one.
+type session struct {
+	report         *Report
+	implementation string
+	mechanism      mechanisms.MechanismType
+}
+
+// check runs fn as a subtest named name, failing it with fn's error.
+func (s *session) check(t *testing.T, name string, fn func() error) {
+	t.Helper()
+	t.Run(name, func(t *testing.T) {
+		t.Helper()
+		err := fn()
+		if err != nil {
+			s.record(name, StatusFail, err.Error())
+			t.Fatal(err)
+		}
+		s.record(name, StatusPass, "")
+	})
+}
+
+// skip records a check that was not run.
+func (s *session) skip(name, reason string) {
+	s.record(name, StatusSkip, reason)
+}
+
+func (s *session) record(name string, status Status, detail string) {
+	if s.report == nil {
+		return
+	}
+	s.report.Results = append(s.report.Results, CheckResult{
+		Implementation: s.implementation,
+		Mechanism:      s.mechanism,
+		Check:          name,
+		Status:         status,
+		Detail:         detail,
+	})
+}
+
+// verifyMechanism checks the mechanism type and that Venue does not panic.
+func (s *session) verifyMechanism(t *testing.T, m mechanisms.MarketMechanism, want mechanisms.MechanismType) {
+	t.Helper()
+	s.check(t, "mechanism", func() error {
+		if got := m.Mechanism(); got != want {
+			return fmt.Errorf("Mechanism() = %s, want %s", got, want)
+		}
+		return noPanic("Venue", func() { _ = m.Venue() })
+	})
+}
@@ -0,0 +1,408 @@
+// Package mechanismtest provides property-based contract suites for
+// mechanism implementations. An implementation's tests supply generators
+// for valid inputs and the suite checks interface invariants over many
+// randomly generated cases:
+//
+//	func TestContract(t *testing.T) {
+//	    mechanismtest.VerifyDerivative(t, option, mechanismtest.DerivativeConfig{
+//	        Params: func(r *rand.Rand) mechanisms.PriceParams { ... },
+//	    })
+//	}
+//
+// Runs are reproducible: generators receive a *rand.Rand seeded from the
+// config, and failures report the seed and iteration that produced them.
+package mechanismtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DefaultIterations is the number of generated cases per property when a
+// config leaves Iterations unset.
+const DefaultIterations = 100
+
+// LiquidityPoolConfig configures VerifyLiquidityPool.
+type LiquidityPoolConfig struct {
+	// Params generates valid Calculate inputs (required)
+	Params func(r *rand.Rand) mechanisms.PoolParams
+
+	// Deposits generates AddLiquidity inputs; nil skips the add/remove
+	// roundtrip property (e.g., when AddLiquidity is unsupported)
+	Deposits func(r *rand.Rand) mechanisms.TokenAmounts
+
+	// Positions generates existing positions for RemoveLiquidity; nil skips
+	// the removal properties
+	Positions func(r *rand.Rand) mechanisms.PoolPosition
+
+	// Tolerance is the relative amount the add/remove roundtrip may lose to
+	// fees and rounding (e.g., 0.003 for a 0.3% fee)
+	Tolerance primitives.Decimal
+
+	Iterations int
+	Seed       int64
+}
+
+// DerivativeConfig configures VerifyDerivative.
+type DerivativeConfig struct {
+	// Params generates valid Price and Greeks inputs (required)
+	Params func(r *rand.Rand) mechanisms.PriceParams
+
+	// MinDelta and MaxDelta bound Delta; both zero means [-1, 1]
+	MinDelta primitives.Decimal
+	MaxDelta primitives.Decimal
+
+	// NonNegativeGamma asserts Gamma >= 0 (long option convexity)
+	NonNegativeGamma bool
+
+	Iterations int
+	Seed       int64
+}
+
+// OrderBookConfig configures VerifyOrderBook.
+type OrderBookConfig struct {
+	// Orders generates valid orders that the book accepts (required)
+	Orders func(r *rand.Rand) mechanisms.Order
+
+	// DepthLevels is the number of levels requested from Depth (default 10)
+	DepthLevels int
+
+	Iterations int
+	Seed       int64
+}
+
+// VerifyLiquidityPool checks the LiquidityPool contract:
+//   - Mechanism reports MechanismTypeLiquidityPool
+//   - Calculate is deterministic and never reports more effective
+//     liquidity than total liquidity
+//   - Calculate and RemoveLiquidity return errors, not panics, for empty input
+//   - RemoveLiquidity is deterministic
+//   - RemoveLiquidity(AddLiquidity(x)) returns no more than x and no less
+//     than the deposited amounts minus Tolerance
+//   - for pools implementing SwapQuoter, QuoteSwap is deterministic,
+//     non-negative and never quotes less output for more input
+func VerifyLiquidityPool(t *testing.T, pool mechanisms.LiquidityPool, cfg LiquidityPoolConfig) {
+	t.Helper()
+	verifyLiquidityPool(t, &session{}, pool, cfg)
+}
+
+func verifyLiquidityPool(t *testing.T, s *session, pool mechanisms.LiquidityPool, cfg LiquidityPoolConfig) {
+	t.Helper()
+	if cfg.Params == nil {
+		t.Fatal("mechanismtest: LiquidityPoolConfig.Params is required")
+	}
+	ctx := context.Background()
+	s.verifyMechanism(t, pool, mechanisms.MechanismTypeLiquidityPool)
+
+	s.check(t, "empty input does not panic", func() error {
+		if err := noPanic("Calculate", func() { _, _ = pool.Calculate(ctx, mechanisms.PoolParams{}) }); err != nil {
+			return err
+		}
+		return noPanic("RemoveLiquidity", func() { _, _ = pool.RemoveLiquidity(ctx, mechanisms.PoolPosition{}) })
+	})
+
+	s.check(t, "calculate", func() error {
+		return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+			params := cfg.Params(r)
+			first, err := pool.Calculate(ctx, params)
+			if err != nil {
+				return fmt.Errorf("Calculate(valid params): %w", err)
+			}
+			second, err := pool.Calculate(ctx, params)
+			if err != nil {
+				return fmt.Errorf("repeated Calculate: %w", err)
+			}
+			if !first.SpotPrice.Equal(second.SpotPrice) || !first.Liquidity.Equal(second.Liquidity) {
+				return fmt.Errorf("Calculate not deterministic: %s/%s then %s/%s",
+					first.SpotPrice, first.Liquidity, second.SpotPrice, second.Liquidity)
+			}
+			if first.EffectiveLiquidity.GreaterThan(first.Liquidity) {
+				return fmt.Errorf("effective liquidity %s exceeds liquidity %s",
+					first.EffectiveLiquidity, first.Liquidity)
+			}
+			return nil
+		})
+	})
+
+	if cfg.Positions == nil {
+		s.skip("remove liquidity", "no Positions generator")
+	} else {
+		s.check(t, "remove liquidity", func() error {
+			return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+				position := cfg.Positions(r)
+				first, err := pool.RemoveLiquidity(ctx, position)
+				if err != nil {
+					return fmt.Errorf("RemoveLiquidity(valid position): %w", err)
+				}
+				second, err := pool.RemoveLiquidity(ctx, position)
+				if err != nil {
+					return fmt.Errorf("repeated RemoveLiquidity: %w", err)
+				}
+				if !first.AmountA.Equal(second.AmountA) || !first.AmountB.Equal(second.AmountB) {
+					return fmt.Errorf("RemoveLiquidity not deterministic")
+				}
+				return nil
+			})
+		})
+	}
+
+	if cfg.Deposits == nil {
+		s.skip("add remove roundtrip", "no Deposits generator")
+	} else {
+		s.check(t, "add remove roundtrip", func() error {
+			return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+				deposit := cfg.Deposits(r)
+				position, err := pool.AddLiquidity(ctx, deposit)
+				if err != nil {
+					return fmt.Errorf("AddLiquidity: %w", err)
+				}
+				out, err := pool.RemoveLiquidity(ctx, position)
+				if err != nil {
+					return fmt.Errorf("RemoveLiquidity: %w", err)
+				}
+				if err := withinRoundtrip("A", deposit.AmountA, position.TokensDeposited.AmountA, out.AmountA, cfg.Tolerance); err != nil {
+					return err
+				}
+				return withinRoundtrip("B", deposit.AmountB, position.TokensDeposited.AmountB, out.AmountB, cfg.Tolerance)
+			})
+		})
+	}
+
+	quoter, ok := pool.(mechanisms.SwapQuoter)
+	if !ok {
+		s.skip("quote swap", "pool does not implement SwapQuoter")
+		return
+	}
+	s.check(t, "quote swap", func() error {
+		if err := noPanic("QuoteSwap", func() {
+			_, _ = quoter.QuoteSwap(ctx, mechanisms.PoolParams{}, mechanisms.OrderSideBuy, primitives.ZeroAmount())
+		}); err != nil {
+			return err
+		}
+		return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+			params := cfg.Params(r)
+			side := mechanisms.OrderSideBuy
+			if r.Intn(2) == 0 {
+				side = mechanisms.OrderSideSell
+			}
+			small := primitives.MustAmount(primitives.NewDecimal(r.Int63n(1000) + 1))
+			large := small.Add(primitives.MustAmount(primitives.NewDecimal(r.Int63n(1000) + 1)))
+
+			first, err := quoter.QuoteSwap(ctx, params, side, small)
+			if err != nil {
+				return fmt.Errorf("QuoteSwap(%s %s): %w", side, small, err)
+			}
+			second, err := quoter.QuoteSwap(ctx, params, side, small)
+			if err != nil || !first.Equal(second) {
+				return fmt.Errorf("QuoteSwap not deterministic: %s then %s (%v)", first, second, err)
+			}
+			if first.Decimal().IsNegative() {
+				return fmt.Errorf("negative quote %s for %s %s", first, side, small)
+			}
+			more, err := quoter.QuoteSwap(ctx, params, side, large)
+			if err != nil {
+				return fmt.Errorf("QuoteSwap(%s %s): %w", side, large, err)
+			}
+			if more.LessThan(first) {
+				return fmt.Errorf("%s of %s quoted %s, less than %s for %s", side, large, more, first, small)
+			}
+			return nil
+		})
+	})
+}
+
+// VerifyDerivative checks the Derivative contract:
+//   - Mechanism reports MechanismTypeDerivative
+//   - Price and Greeks succeed and are deterministic for valid params
+//   - Delta lies within [MinDelta, MaxDelta]; Gamma >= 0 when configured
+//   - Price and Greeks return errors, not panics, for empty params
+func VerifyDerivative(t *testing.T, deriv mechanisms.Derivative, cfg DerivativeConfig) {
+	t.Helper()
+	verifyDerivative(t, &session{}, deriv, cfg)
+}
+
+func verifyDerivative(t *testing.T, s *session, deriv mechanisms.Derivative, cfg DerivativeConfig) {
+	t.Helper()
+	if cfg.Params == nil {
+		t.Fatal("mechanismtest: DerivativeConfig.Params is required")
+	}
+	ctx := context.Background()
+	s.verifyMechanism(t, deriv, mechanisms.MechanismTypeDerivative)
+
+	minDelta, maxDelta := cfg.MinDelta, cfg.MaxDelta
+	if minDelta.IsZero() && maxDelta.IsZero() {
+		minDelta, maxDelta = primitives.NewDecimal(-1), primitives.NewDecimal(1)
+	}
+
+	s.check(t, "empty input does not panic", func() error {
+		if err := noPanic("Price", func() { _, _ = deriv.Price(ctx, mechanisms.PriceParams{}) }); err != nil {
+			return err
+		}
+		return noPanic("Greeks", func() { _, _ = deriv.Greeks(ctx, mechanisms.PriceParams{}) })
+	})
+
+	s.check(t, "price", func() error {
+		return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+			params := cfg.Params(r)
+			first, err := deriv.Price(ctx, params)
+			if err != nil {
+				return fmt.Errorf("Price(valid params): %w", err)
+			}
+			if first.Decimal().IsNegative() {
+				return fmt.Errorf("negative price %s", first)
+			}
+			second, err := deriv.Price(ctx, params)
+			if err != nil || !first.Equal(second) {
+				return fmt.Errorf("Price not deterministic: %s then %s (%v)", first, second, err)
+			}
+			return nil
+		})
+	})
+
+	s.check(t, "greeks", func() error {
+		return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+			params := cfg.Params(r)
+			greeks, err := deriv.Greeks(ctx, params)
+			if err != nil {
+				return fmt.Errorf("Greeks(valid params): %w", err)
+			}
+			if greeks.Delta.LessThan(minDelta) || greeks.Delta.GreaterThan(maxDelta) {
+				return fmt.Errorf("delta %s outside [%s, %s]", greeks.Delta, minDelta, maxDelta)
+			}
+			if cfg.NonNegativeGamma && greeks.Gamma.IsNegative() {
+				return fmt.Errorf("negative gamma %s", greeks.Gamma)
+			}
+			again, err := deriv.Greeks(ctx, params)
+			if err != nil || !again.Delta.Equal(greeks.Delta) || !again.Gamma.Equal(greeks.Gamma) {
+				return fmt.Errorf("Greeks not deterministic (%v)", err)
+			}
+			return nil
+		})
+	})
+}
+
+// VerifyOrderBook checks the OrderBook contract:
+//   - Mechanism reports MechanismTypeOrderBook
+//   - PlaceOrder returns unique IDs and CancelOrder succeeds exactly once
+//   - BestBid <= BestAsk whenever both sides are quoted
+//   - Depth returns at most the requested levels, bids descending and
+//     asks ascending
+//
+// The book is mutated; pass a fresh instance.
+func VerifyOrderBook(t *testing.T, book mechanisms.OrderBook, cfg OrderBookConfig) {
+	t.Helper()
+	verifyOrderBook(t, &session{}, book, cfg)
+}
+
+func verifyOrderBook(t *testing.T, s *session, book mechanisms.OrderBook, cfg OrderBookConfig) {
+	t.Helper()
+	if cfg.Orders == nil {
+		t.Fatal("mechanismtest: OrderBookConfig.Orders is required")
+	}
+	ctx := context.Background()
+	s.verifyMechanism(t, book, mechanisms.MechanismTypeOrderBook)
+
+	levels := cfg.DepthLevels
+	if levels <= 0 {
+		levels = 10
+	}
+	seen := make(map[mechanisms.OrderID]bool)
+
+	s.check(t, "orders", func() error {
+		return forAll(cfg.Iterations, cfg.Seed, func(r *rand.Rand) error {
+			id, err := book.PlaceOrder(ctx, cfg.Orders(r))
+			if err != nil {
+				return fmt.Errorf("PlaceOrder(valid order): %w", err)
+			}
+			if seen[id] {
+				return fmt.Errorf("duplicate order ID %s", id)
+			}
+			seen[id] = true
+
+			if r.Intn(4) == 0 {
+				if err := book.CancelOrder(ctx, id); err != nil {
+					return fmt.Errorf("CancelOrder(%s): %w", id, err)
+				}
+				if err := book.CancelOrder(ctx, id); err == nil {
+					return fmt.Errorf("second CancelOrder(%s) succeeded", id)
+				}
+			}
+
+			bid, _, bidErr := book.BestBid(ctx)
+			ask, _, askErr := book.BestAsk(ctx)
+			if bidErr == nil && askErr == nil && bid.GreaterThan(ask) {
+				return fmt.Errorf("crossed book: bid %s > ask %s", bid, ask)
+			}
+
+			depth, err := book.Depth(ctx, levels)
+			if err != nil {
+				return fmt.Errorf("Depth: %w", err)
+			}
+			return checkDepth(depth, levels)
+		})
+	})
+}
+
+// forAll runs property against iterations generated cases, returning the
+// seed and iteration of the first counterexample.
+func forAll(iterations int, seed int64, property func(r *rand.Rand) error) error {
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+	if seed == 0 {
+		seed = 1
+	}
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < iterations; i++ {
+		if err := property(r); err != nil {
+			return fmt.Errorf("property failed at iteration %d (seed %d): %w", i, seed, err)
+		}
+	}
+	return nil
+}
+
+// noPanic runs fn, returning an error if it panics.
+func noPanic(name string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", name, r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// withinRoundtrip checks deposited*(1-tol) <= out <= offered*(1+tol).
+func withinRoundtrip(token string, offered, deposited, out primitives.Amount, tol primitives.Decimal) error {
+	upper := offered.Decimal().Mul(primitives.One().Add(tol))
+	lower := deposited.Decimal().Mul(primitives.One().Sub(tol))
+	if out.Decimal().GreaterThan(upper) {
+		return fmt.Errorf("token %s: withdrew %s, more than the %s offered", token, out, offered)
+	}
+	if out.Decimal().LessThan(lower) {
+		return fmt.Errorf("token %s: withdrew %s, less than %s deposited minus tolerance", token, out, deposited)
+	}
+	return nil
+}
+
+func checkDepth(depth mechanisms.OrderBookDepth, levels int) error {
+	if len(depth.Bids) > levels || len(depth.Asks) > levels {
+		return fmt.Errorf("depth returned %d bids and %d asks for %d levels", len(depth.Bids), len(depth.Asks), levels)
+	}
+	for i := 1; i < len(depth.Bids); i++ {
+		if depth.Bids[i].Price.GreaterThan(depth.Bids[i-1].Price) {
+			return fmt.Errorf("bids not descending at level %d", i)
+		}
+	}
+	for i := 1; i < len(depth.Asks); i++ {
+		if depth.Asks[i].Price.LessThan(depth.Asks[i-1].Price) {
+			return fmt.Errorf("asks not ascending at level %d", i)
+		}
+	}
+	return nil
+}
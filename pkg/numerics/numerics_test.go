@@ -0,0 +1,78 @@
+package numerics_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+)
+
+func TestNormalCDF(t *testing.T) {
+	// Reference values of N(x)
+	cases := []struct {
+		x, want float64
+	}{
+		{0, 0.5},
+		{1, 0.8413447460685429},
+		{-1.96, 0.024997895148220435},
+		{-5, 2.866515718791939e-07},
+		{-8, 6.220960574271784e-16},
+	}
+	for _, c := range cases {
+		got := numerics.NormalCDF(c.x)
+		if math.Abs(got-c.want) > 1e-14*c.want {
+			t.Errorf("NormalCDF(%g) = %g, want %g", c.x, got, c.want)
+		}
+		approx := numerics.NormalCDFAbramowitzStegun(c.x)
+		if math.Abs(approx-c.want) > 1e-7 {
+			t.Errorf("NormalCDFAbramowitzStegun(%g) = %g, want %g", c.x, approx, c.want)
+		}
+	}
+
+	// The approximation's relative error grows in the tail; erf's does not
+	if rel := math.Abs(numerics.NormalCDFAbramowitzStegun(-8)/6.220960574271784e-16 - 1); rel < 1e-3 {
+		t.Errorf("expected the approximation to lose relative precision at -8, got %g", rel)
+	}
+}
+
+func TestBrent(t *testing.T) {
+	root, err := numerics.Brent(func(x float64) float64 { return x*x*x - 2*x - 5 }, 2, 3, 1e-12, 100)
+	if err != nil {
+		t.Fatalf("Brent: %v", err)
+	}
+	if want := 2.0945514815423265; math.Abs(root-want) > 1e-11 {
+		t.Errorf("root = %.16g, want %.16g", root, want)
+	}
+
+	root, err = numerics.Brent(math.Cos, 0, 3, 1e-12, 100)
+	if err != nil || math.Abs(root-math.Pi/2) > 1e-11 {
+		t.Errorf("cos root = %g (%v), want π/2", root, err)
+	}
+
+	if _, err := numerics.Brent(func(x float64) float64 { return x*x + 1 }, -1, 1, 1e-12, 100); !errors.Is(err, numerics.ErrNoBracket) {
+		t.Errorf("expected ErrNoBracket, got %v", err)
+	}
+	if _, err := numerics.Brent(math.Cbrt, -1, 2, 1e-15, 3); !errors.Is(err, numerics.ErrNoConvergence) {
+		t.Errorf("expected ErrNoConvergence, got %v", err)
+	}
+}
+
+func TestIntegrate(t *testing.T) {
+	got, err := numerics.Integrate(numerics.NormalPDF, -1.5, 2, 1e-12)
+	if err != nil {
+		t.Fatalf("Integrate: %v", err)
+	}
+	if want := numerics.NormalCDF(2) - numerics.NormalCDF(-1.5); math.Abs(got-want) > 1e-11 {
+		t.Errorf("∫φ = %.15g, want %.15g", got, want)
+	}
+
+	got, err = numerics.Integrate(math.Sqrt, 0, 1, 1e-10)
+	if err != nil || math.Abs(got-2.0/3) > 1e-9 {
+		t.Errorf("∫√x = %g (%v), want 2/3", got, err)
+	}
+
+	if _, err := numerics.Integrate(func(x float64) float64 { return 1 / x }, 0, 1, 1e-10); !errors.Is(err, numerics.ErrNoConvergence) {
+		t.Errorf("expected ErrNoConvergence at a singularity, got %v", err)
+	}
+}
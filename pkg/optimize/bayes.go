@@ -0,0 +1,182 @@
+package optimize
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/numerics"
+)
+
+// Gaussian process hyperparameters tried by bayes.fit. The kernel's
+// length scale and the noise variance are those maximizing the marginal
+// likelihood of the scores, which are standardized first.
+var (
+	gpLengthScales = []float64{0.05, 0.1, 0.2, 0.4, 0.8}
+	gpNoises       = []float64{1e-6, 1e-3, 1e-1}
+)
+
+// bayes is Bayesian optimization with a Gaussian process surrogate (a
+// squared exponential kernel over the unit cube) and the expected
+// improvement acquisition function, maximized over random candidates.
+type bayes struct {
+	n          int
+	initial    int
+	candidates int
+
+	points [][]float64
+	scores []float64
+}
+
+func newBayes(n, initial, candidates int) *bayes {
+	if initial == 0 {
+		initial = 2 * n
+		if initial < 5 {
+			initial = 5
+		}
+	}
+	if candidates == 0 {
+		candidates = 1000
+	}
+	return &bayes{n: n, initial: initial, candidates: candidates}
+}
+
+// gp is a Gaussian process fitted to standardized scores.
+type gp struct {
+	points      [][]float64
+	lengthScale float64
+	chol        [][]float64
+	alpha       []float64
+}
+
+func (g *gp) kernel(a, b []float64) float64 {
+	d := 0.0
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Exp(-d / (2 * g.lengthScale * g.lengthScale))
+}
+
+// predict returns the posterior mean and standard deviation at x.
+func (g *gp) predict(x []float64) (float64, float64) {
+	k := make([]float64, len(g.points))
+	mean := 0.0
+	for i, point := range g.points {
+		k[i] = g.kernel(x, point)
+		mean += k[i] * g.alpha[i]
+	}
+	v := forwardSolve(g.chol, k)
+	variance := 1.0
+	for _, vi := range v {
+		variance -= vi * vi
+	}
+	return mean, math.Sqrt(math.Max(variance, 1e-12))
+}
+
+// fit fits the process to y with the hyperparameters of highest marginal
+// likelihood, or returns nil if no covariance matrix could be factored.
+func (b *bayes) fit(y []float64) *gp {
+	var best *gp
+	bestLikelihood := math.Inf(-1)
+	for _, lengthScale := range gpLengthScales {
+		for _, noise := range gpNoises {
+			g := &gp{points: b.points, lengthScale: lengthScale}
+			k := make([][]float64, len(b.points))
+			for i := range k {
+				k[i] = make([]float64, len(b.points))
+				for j := range k[i] {
+					k[i][j] = g.kernel(b.points[i], b.points[j])
+				}
+				k[i][i] += noise
+			}
+			chol, err := cholesky(k)
+			if err != nil {
+				continue
+			}
+			g.chol = chol
+			g.alpha = choleskySolve(chol, y)
+			// log p(y) = -½·yᵀα - Σ log Lᵢᵢ - (n/2)·log 2π; the constant
+			// is the same for every candidate
+			likelihood := 0.0
+			for i := range y {
+				likelihood -= 0.5*y[i]*g.alpha[i] + math.Log(chol[i][i])
+			}
+			if likelihood > bestLikelihood {
+				best, bestLikelihood = g, likelihood
+			}
+		}
+	}
+	return best
+}
+
+// ask returns a uniformly random point for the first trials, then the
+// candidate with the highest expected improvement over the best score.
+func (b *bayes) ask(rng *rand.Rand) [][]float64 {
+	random := func() []float64 {
+		point := make([]float64, b.n)
+		for i := range point {
+			point[i] = rng.Float64()
+		}
+		return point
+	}
+	if len(b.points) < b.initial {
+		return [][]float64{random()}
+	}
+
+	mean, std := 0.0, 0.0
+	for _, score := range b.scores {
+		mean += score
+	}
+	mean /= float64(len(b.scores))
+	for _, score := range b.scores {
+		std += (score - mean) * (score - mean)
+	}
+	std = math.Sqrt(std / float64(len(b.scores)))
+	if std == 0 {
+		std = 1
+	}
+	y := make([]float64, len(b.scores))
+	incumbent, best := math.Inf(-1), 0
+	for i, score := range b.scores {
+		y[i] = (score - mean) / std
+		if y[i] > incumbent {
+			incumbent, best = y[i], i
+		}
+	}
+	g := b.fit(y)
+	if g == nil {
+		return [][]float64{random()}
+	}
+
+	// Half the candidates explore the whole cube, half refine around the
+	// best point so far
+	const xi = 0.01
+	var next []float64
+	bestEI := math.Inf(-1)
+	for c := 0; c < b.candidates; c++ {
+		var candidate []float64
+		if c%2 == 0 {
+			candidate = random()
+		} else {
+			candidate = make([]float64, b.n)
+			for i := range candidate {
+				candidate[i] = math.Min(math.Max(b.points[best][i]+0.05*rng.NormFloat64(), 0), 1)
+			}
+		}
+		mu, sigma := g.predict(candidate)
+		z := (mu - incumbent - xi) / sigma
+		ei := (mu-incumbent-xi)*numerics.NormalCDF(z) + sigma*numerics.NormalPDF(z)
+		if ei > bestEI {
+			next, bestEI = candidate, ei
+		}
+	}
+	return [][]float64{next}
+}
+
+// tell records the observations. A stopped trial's score is its mean over
+// the folds it ran, the best estimate there is of its full score.
+func (b *bayes) tell(observations []observation) {
+	for _, o := range observations {
+		b.points = append(b.points, o.point)
+		b.scores = append(b.scores, o.score)
+	}
+}
@@ -0,0 +1,186 @@
+package optimize
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// cmaes is the (μ/μ_w, λ)-CMA-ES of Hansen's tutorial ("The CMA Evolution
+// Strategy: A Tutorial", 2016), maximizing over the unit cube. Samples
+// outside the cube are projected onto it, and the projected points drive
+// the update.
+type cmaes struct {
+	n, lambda, mu int
+	weights       []float64
+	mueff         float64
+
+	cc, cs, c1, cmu, damps, chiN float64
+
+	mean  []float64
+	sigma float64
+	c     [][]float64
+	pc    []float64
+	ps    []float64
+
+	// b and d are the eigenvectors and the square roots of the eigenvalues
+	// of c, refreshed by ask
+	b [][]float64
+	d []float64
+
+	generation int
+}
+
+func newCMAES(n, lambda int, sigma float64) *cmaes {
+	if lambda == 0 {
+		lambda = 4 + int(3*math.Log(float64(n)))
+	}
+	if lambda < 2 {
+		lambda = 2
+	}
+	if sigma == 0 {
+		sigma = 0.3
+	}
+	mu := lambda / 2
+	weights := make([]float64, mu)
+	sum, sumSq := 0.0, 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+0.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+		sumSq += weights[i] * weights[i]
+	}
+	mueff := 1 / sumSq
+	nf := float64(n)
+
+	e := &cmaes{
+		n: n, lambda: lambda, mu: mu, weights: weights, mueff: mueff,
+		cc:    (4 + mueff/nf) / (nf + 4 + 2*mueff/nf),
+		cs:    (mueff + 2) / (nf + mueff + 5),
+		c1:    2 / ((nf+1.3)*(nf+1.3) + mueff),
+		chiN:  math.Sqrt(nf) * (1 - 1/(4*nf) + 1/(21*nf*nf)),
+		sigma: sigma,
+		mean:  make([]float64, n),
+		pc:    make([]float64, n),
+		ps:    make([]float64, n),
+		c:     make([][]float64, n),
+	}
+	e.cmu = math.Min(1-e.c1, 2*(mueff-2+1/mueff)/((nf+2)*(nf+2)+mueff))
+	e.damps = 1 + 2*math.Max(0, math.Sqrt((mueff-1)/(nf+1))-1) + e.cs
+	for i := range e.mean {
+		e.mean[i] = 0.5
+		e.c[i] = make([]float64, n)
+		e.c[i][i] = 1
+	}
+	return e
+}
+
+// ask samples a generation of λ points from N(mean, σ²C).
+func (e *cmaes) ask(rng *rand.Rand) [][]float64 {
+	values, vectors := symmetricEigen(e.c)
+	e.b = vectors
+	e.d = make([]float64, e.n)
+	for i, value := range values {
+		e.d[i] = math.Sqrt(math.Max(value, 1e-20))
+	}
+	points := make([][]float64, e.lambda)
+	for k := range points {
+		z := make([]float64, e.n)
+		for i := range z {
+			z[i] = e.d[i] * rng.NormFloat64()
+		}
+		point := make([]float64, e.n)
+		for i := range point {
+			y := 0.0
+			for j := range z {
+				y += e.b[i][j] * z[j]
+			}
+			point[i] = math.Min(math.Max(e.mean[i]+e.sigma*y, 0), 1)
+		}
+		points[k] = point
+	}
+	return points
+}
+
+// tell updates the distribution from a generation's observations. Stopped
+// trials rank below finished ones. A generation cut short by the trial
+// budget is the last, so it is not learned from.
+func (e *cmaes) tell(observations []observation) {
+	if len(observations) < e.lambda {
+		return
+	}
+	ranked := append([]observation(nil), observations...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].pruned != ranked[j].pruned {
+			return !ranked[i].pruned
+		}
+		return ranked[i].score > ranked[j].score
+	})
+
+	old := append([]float64(nil), e.mean...)
+	for i := range e.mean {
+		e.mean[i] = 0
+		for k := 0; k < e.mu; k++ {
+			e.mean[i] += e.weights[k] * ranked[k].point[i]
+		}
+	}
+	yw := make([]float64, e.n)
+	for i := range yw {
+		yw[i] = (e.mean[i] - old[i]) / e.sigma
+	}
+
+	// C^(-1/2)·yw = B·D⁻¹·Bᵀ·yw
+	bty := make([]float64, e.n)
+	for j := range bty {
+		for i := range yw {
+			bty[j] += e.b[i][j] * yw[i]
+		}
+		bty[j] /= e.d[j]
+	}
+	invSqrt := make([]float64, e.n)
+	for i := range invSqrt {
+		for j := range bty {
+			invSqrt[i] += e.b[i][j] * bty[j]
+		}
+	}
+	norm := 0.0
+	for i := range e.ps {
+		e.ps[i] = (1-e.cs)*e.ps[i] + math.Sqrt(e.cs*(2-e.cs)*e.mueff)*invSqrt[i]
+		norm += e.ps[i] * e.ps[i]
+	}
+	norm = math.Sqrt(norm)
+
+	e.generation++
+	hsig := 0.0
+	if norm/math.Sqrt(1-math.Pow(1-e.cs, float64(2*e.generation)))/e.chiN < 1.4+2/float64(e.n+1) {
+		hsig = 1
+	}
+	for i := range e.pc {
+		e.pc[i] = (1-e.cc)*e.pc[i] + hsig*math.Sqrt(e.cc*(2-e.cc)*e.mueff)*yw[i]
+	}
+
+	steps := make([][]float64, e.mu)
+	for k := range steps {
+		steps[k] = make([]float64, e.n)
+		for i := range steps[k] {
+			steps[k][i] = (ranked[k].point[i] - old[i]) / e.sigma
+		}
+	}
+	for i := 0; i < e.n; i++ {
+		for j := 0; j < e.n; j++ {
+			rankMu := 0.0
+			for k := range steps {
+				rankMu += e.weights[k] * steps[k][i] * steps[k][j]
+			}
+			e.c[i][j] = (1-e.c1-e.cmu)*e.c[i][j] +
+				e.c1*(e.pc[i]*e.pc[j]+(1-hsig)*e.cc*(2-e.cc)*e.c[i][j]) +
+				e.cmu*rankMu
+		}
+	}
+
+	e.sigma *= math.Exp((e.cs / e.damps) * (norm/e.chiN - 1))
+	// Keep the step size meaningful on the unit cube
+	e.sigma = math.Min(math.Max(e.sigma, 1e-8), 1)
+}
@@ -0,0 +1,448 @@
+// Package optimize searches continuous strategy parameters, such as a
+// range width, hedge ratio or rebalance threshold, for the values that
+// score best out of sample. Where a grid search spends its budget evenly,
+// CMA-ES and Bayesian optimization spend it where the scores are good:
+//
+//	splits, err := optimize.WalkForward(snapshots, optimize.WalkForwardConfig{Folds: 4, Train: 500})
+//	objective := optimize.Backtest(backtest.DefaultConfig(), factory, splits, optimize.SharpeMetric)
+//	report, err := optimize.Optimize(ctx, optimize.Config{
+//		Space: []optimize.Param{
+//			{Name: "width", Min: 0.01, Max: 0.5, Log: true},
+//			{Name: "hedge_ratio", Min: 0, Max: 1},
+//		},
+//		Method:        optimize.CMAES,
+//		Trials:        200,
+//		Folds:         len(splits),
+//		EarlyStopping: optimize.EarlyStopping{StartupTrials: 10},
+//	}, objective)
+//
+// Each trial is scored on every fold of a walk-forward cross-validation,
+// and a trial whose folds so far score below most finished trials is
+// stopped early instead of running its remaining folds. The report
+// deflates the best trial's Sharpe ratio for the search that found it and
+// estimates the probability that the selection is overfit (see
+// Overfitting), so parameters that only won by luck are not shipped.
+package optimize
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Param is one continuous dimension of the search space.
+type Param struct {
+	// Name is the key of the parameter in Values and strategy.Params
+	Name string
+
+	// Min and Max bound the parameter, inclusive
+	Min float64
+	Max float64
+
+	// Log searches the parameter on a log scale, for parameters spanning
+	// orders of magnitude; Min must be positive
+	Log bool
+
+	// Integer rounds the parameter to the nearest integer
+	Integer bool
+}
+
+// value maps the unit coordinate u to the parameter's range.
+func (p Param) value(u float64) float64 {
+	u = math.Min(math.Max(u, 0), 1)
+	var v float64
+	if p.Log {
+		v = math.Exp(math.Log(p.Min) + u*(math.Log(p.Max)-math.Log(p.Min)))
+	} else {
+		v = p.Min + u*(p.Max-p.Min)
+	}
+	if p.Integer {
+		v = math.Round(v)
+	}
+	return math.Min(math.Max(v, p.Min), p.Max)
+}
+
+func (p Param) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("parameter name is required")
+	}
+	if math.IsNaN(p.Min) || math.IsNaN(p.Max) || math.IsInf(p.Min, 0) || math.IsInf(p.Max, 0) {
+		return fmt.Errorf("parameter %q must have finite bounds", p.Name)
+	}
+	if p.Min >= p.Max {
+		return fmt.Errorf("parameter %q must have Min below Max, got [%g, %g]", p.Name, p.Min, p.Max)
+	}
+	if p.Log && p.Min <= 0 {
+		return fmt.Errorf("log-scale parameter %q must have a positive Min, got %g", p.Name, p.Min)
+	}
+	return nil
+}
+
+// Values are the parameter values of a trial, by parameter name.
+type Values map[string]float64
+
+// Params returns the values as strategy parameters, for a
+// strategy.Factory. Integral values are stored as int so Params.Int
+// accepts them.
+func (v Values) Params() strategy.Params {
+	params := make(strategy.Params, len(v))
+	for name, value := range v {
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			params[name] = int(value)
+		} else {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// Method selects the search algorithm.
+type Method int
+
+const (
+	// CMAES is the covariance matrix adaptation evolution strategy: each
+	// generation samples a population from a multivariate normal whose
+	// mean, step size and covariance adapt toward the best trials. It is
+	// robust on noisy, rugged objectives and needs no tuning, but spends
+	// trials by the generation.
+	CMAES Method = iota
+
+	// Bayesian fits a Gaussian process to the scores so far and runs the
+	// trial with the highest expected improvement. It finds good values in
+	// fewer trials than CMA-ES, for objectives expensive enough that the
+	// model's cost (cubic in the number of trials) does not matter.
+	Bayesian
+)
+
+// String returns the method name.
+func (m Method) String() string {
+	switch m {
+	case CMAES:
+		return "cma-es"
+	case Bayesian:
+		return "bayesian"
+	default:
+		return fmt.Sprintf("Method(%d)", int(m))
+	}
+}
+
+// Objective scores the parameter values on fold of the cross-validation;
+// higher is better. Folds are evaluated in order from zero, and a trial
+// stopped early is not evaluated on its remaining folds. An error aborts
+// the optimization.
+type Objective func(ctx context.Context, values Values, fold int) (float64, error)
+
+// EarlyStopping stops trials that score poorly on their first folds. After
+// each fold, a trial's mean score so far is compared with the mean scores
+// of the finished trials over the same folds, and the trial is stopped if
+// it is below their Quantile. The zero value never stops a trial.
+type EarlyStopping struct {
+	// StartupTrials is the number of trials that must finish before any is
+	// stopped; zero disables early stopping
+	StartupTrials int
+
+	// MinFolds is the number of folds a trial runs before it can be
+	// stopped (default 1)
+	MinFolds int
+
+	// Quantile of the finished trials' scores below which a trial is
+	// stopped, in (0, 1) (default 0.5, the median)
+	Quantile float64
+}
+
+// Config configures Optimize.
+type Config struct {
+	Space  []Param
+	Method Method
+
+	// Trials is the number of trials to run
+	Trials int
+
+	// Folds is the number of cross-validation folds each trial is scored
+	// on, typically the number of walk-forward splits (default 1)
+	Folds int
+
+	// Seed seeds the search, so the same configuration and objective
+	// always run the same trials
+	Seed int64
+
+	EarlyStopping EarlyStopping
+
+	// PopulationSize is the number of trials per CMA-ES generation
+	// (default 4 + ⌊3·ln n⌋ for n parameters)
+	PopulationSize int
+
+	// Sigma is CMA-ES's initial step size, as a fraction of each
+	// parameter's range (default 0.3)
+	Sigma float64
+
+	// InitialTrials is the number of uniformly random trials Bayesian
+	// optimization runs before fitting its model (default max(5, 2n))
+	InitialTrials int
+
+	// Candidates is the number of points at which Bayesian optimization
+	// evaluates the expected improvement to choose each trial (default
+	// 1000)
+	Candidates int
+
+	// Blocks is the number of blocks the trials' returns are cut into for
+	// the probability of backtest overfitting (see Overfitting); an even
+	// number, by default the largest up to 16 leaving two returns a block
+	Blocks int
+}
+
+// Trial is one evaluated point of the search.
+type Trial struct {
+	// Number is the trial's position in Report.Trials
+	Number int
+	Values Values
+
+	// Scores are the trial's scores on the folds it was evaluated on, in
+	// fold order; fewer than Config.Folds if it was stopped early
+	Scores []float64
+
+	// Score is the mean of Scores
+	Score float64
+
+	// Pruned reports whether the trial was stopped early
+	Pruned bool
+
+	// Returns are the periodic returns the objective recorded with
+	// RecordReturns, over the folds the trial ran in fold order
+	Returns []float64
+}
+
+// Report is the outcome of Optimize.
+type Report struct {
+	Method Method
+	Folds  int
+
+	// Trials are all trials in the order they ran
+	Trials []Trial
+
+	// Best is the finished trial with the highest Score
+	Best Trial
+
+	// Pruned is the number of trials stopped early
+	Pruned int
+
+	// Overfitting diagnoses the selection of Best, when the objective
+	// recorded returns (as Backtest's does); nil otherwise
+	Overfitting *Overfitting
+}
+
+// Summary returns a human-readable summary of the optimization.
+func (r *Report) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Optimization Results (%s):\n", r.Method)
+	fmt.Fprintf(&b, "  Trials: %d (%d stopped early)\n", len(r.Trials), r.Pruned)
+	fmt.Fprintf(&b, "  Folds: %d\n", r.Folds)
+	fmt.Fprintf(&b, "  Best Trial: #%d, score %.4f\n", r.Best.Number, r.Best.Score)
+	names := make([]string, 0, len(r.Best.Values))
+	for name := range r.Best.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "    %s = %g\n", name, r.Best.Values[name])
+	}
+	if o := r.Overfitting; o != nil {
+		dsr := o.DeflatedSharpe
+		fmt.Fprintf(&b, "  Deflated Sharpe: %.2f%% (Sharpe %.4f vs %.4f expected from %d trials)\n",
+			dsr.Probability.Float64()*100, dsr.Sharpe.Float64(), dsr.ExpectedMax.Float64(), dsr.Trials)
+		if o.PBO != nil {
+			fmt.Fprintf(&b, "  PBO: %.2f%% over %d combinations (loss out of sample %.2f%%)\n",
+				o.PBO.Probability.Float64()*100, o.PBO.Combinations, o.PBO.ProbabilityOfLoss.Float64()*100)
+		}
+		if o.Likely() {
+			b.WriteString("  Warning: the best trial is likely overfit\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// observation is a trial as the search algorithms see it: its point in
+// the unit cube and its score.
+type observation struct {
+	point  []float64
+	score  float64
+	pruned bool
+}
+
+// searcher proposes the points of the next trials and learns from their
+// scores. Points are in the unit cube, one coordinate per parameter.
+type searcher interface {
+	ask(rng *rand.Rand) [][]float64
+	tell(observations []observation)
+}
+
+// Optimize searches config.Space for the values maximizing the mean score
+// of objective over config.Folds folds, running config.Trials trials.
+// Returns error if the configuration is invalid, the objective fails or
+// returns a non-finite score, or ctx is canceled.
+func Optimize(ctx context.Context, config Config, objective Objective) (*Report, error) {
+	if objective == nil {
+		return nil, fmt.Errorf("objective cannot be nil")
+	}
+	if len(config.Space) == 0 {
+		return nil, fmt.Errorf("search space cannot be empty")
+	}
+	names := make(map[string]bool, len(config.Space))
+	for _, param := range config.Space {
+		if err := param.validate(); err != nil {
+			return nil, err
+		}
+		if names[param.Name] {
+			return nil, fmt.Errorf("duplicate parameter %q", param.Name)
+		}
+		names[param.Name] = true
+	}
+	if config.Trials <= 0 {
+		return nil, fmt.Errorf("trials must be positive, got %d", config.Trials)
+	}
+	if config.Folds < 0 || config.PopulationSize < 0 || config.InitialTrials < 0 || config.Candidates < 0 {
+		return nil, fmt.Errorf("folds, population size, initial trials and candidates cannot be negative")
+	}
+	if config.Blocks < 0 || config.Blocks%2 != 0 {
+		return nil, fmt.Errorf("blocks must be even, got %d", config.Blocks)
+	}
+	if config.Folds == 0 {
+		config.Folds = 1
+	}
+	stopping := config.EarlyStopping
+	if stopping.StartupTrials < 0 || stopping.MinFolds < 0 {
+		return nil, fmt.Errorf("early stopping trials and folds cannot be negative")
+	}
+	if stopping.Quantile < 0 || stopping.Quantile >= 1 {
+		return nil, fmt.Errorf("early stopping quantile must be in (0, 1), got %g", stopping.Quantile)
+	}
+	if stopping.MinFolds == 0 {
+		stopping.MinFolds = 1
+	}
+	if stopping.Quantile == 0 {
+		stopping.Quantile = 0.5
+	}
+
+	var search searcher
+	switch config.Method {
+	case CMAES:
+		if config.Sigma < 0 || config.Sigma > 1 {
+			return nil, fmt.Errorf("sigma must be in (0, 1], got %g", config.Sigma)
+		}
+		search = newCMAES(len(config.Space), config.PopulationSize, config.Sigma)
+	case Bayesian:
+		search = newBayes(len(config.Space), config.InitialTrials, config.Candidates)
+	default:
+		return nil, fmt.Errorf("unknown method %s", config.Method)
+	}
+
+	o := &optimizer{config: config, stopping: stopping, objective: objective}
+	rng := rand.New(rand.NewSource(config.Seed))
+	report := &Report{Method: config.Method, Folds: config.Folds}
+	for len(report.Trials) < config.Trials {
+		points := search.ask(rng)
+		if remaining := config.Trials - len(report.Trials); len(points) > remaining {
+			points = points[:remaining]
+		}
+		observations := make([]observation, 0, len(points))
+		for _, point := range points {
+			trial, err := o.evaluate(ctx, len(report.Trials), point)
+			if err != nil {
+				return nil, err
+			}
+			report.Trials = append(report.Trials, trial)
+			if trial.Pruned {
+				report.Pruned++
+			}
+			observations = append(observations, observation{point: point, score: trial.Score, pruned: trial.Pruned})
+		}
+		search.tell(observations)
+	}
+
+	best := -1
+	for i, trial := range report.Trials {
+		if !trial.Pruned && (best < 0 || trial.Score > report.Trials[best].Score) {
+			best = i
+		}
+	}
+	report.Best = report.Trials[best]
+	report.Overfitting = diagnose(report, config.Blocks)
+	return report, nil
+}
+
+// optimizer evaluates trials, stopping poor ones early.
+type optimizer struct {
+	config    Config
+	stopping  EarlyStopping
+	objective Objective
+
+	// finished holds, for each finished trial, its running mean score
+	// after each fold
+	finished [][]float64
+}
+
+// evaluate runs trial number on its folds.
+func (o *optimizer) evaluate(ctx context.Context, number int, point []float64) (Trial, error) {
+	values := make(Values, len(o.config.Space))
+	for i, param := range o.config.Space {
+		values[param.Name] = param.value(point[i])
+	}
+	trial := Trial{Number: number, Values: values}
+	recorder := &returnsRecorder{}
+	ctx = context.WithValue(ctx, returnsKey{}, recorder)
+	means := make([]float64, 0, o.config.Folds)
+	sum := 0.0
+	for fold := 0; fold < o.config.Folds; fold++ {
+		if err := ctx.Err(); err != nil {
+			return Trial{}, err
+		}
+		score, err := o.objective(ctx, values, fold)
+		if err != nil {
+			return Trial{}, fmt.Errorf("trial %d failed on fold %d: %w", number, fold, err)
+		}
+		if math.IsNaN(score) || math.IsInf(score, 0) {
+			return Trial{}, fmt.Errorf("trial %d scored %g on fold %d", number, score, fold)
+		}
+		trial.Scores = append(trial.Scores, score)
+		sum += score
+		means = append(means, sum/float64(len(trial.Scores)))
+		trial.Score = means[fold]
+		trial.Returns = recorder.returns
+		if fold+1 < o.config.Folds && o.stop(fold, trial.Score) {
+			trial.Pruned = true
+			return trial, nil
+		}
+	}
+	o.finished = append(o.finished, means)
+	return trial, nil
+}
+
+// stop reports whether a trial whose mean score through fold is mean
+// should be stopped.
+func (o *optimizer) stop(fold int, mean float64) bool {
+	if o.stopping.StartupTrials == 0 || len(o.finished) < o.stopping.StartupTrials || fold+1 < o.stopping.MinFolds {
+		return false
+	}
+	scores := make([]float64, len(o.finished))
+	for i, means := range o.finished {
+		scores[i] = means[fold]
+	}
+	return mean < quantile(scores, o.stopping.Quantile)
+}
+
+// quantile returns the q-quantile of values by linear interpolation.
+func quantile(values []float64, q float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
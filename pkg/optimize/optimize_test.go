@@ -0,0 +1,319 @@
+package optimize_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/optimize"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// bowl peaks at width 0.3, hedge_ratio 0.7.
+func bowl(_ context.Context, values optimize.Values, _ int) (float64, error) {
+	dx := values["width"] - 0.3
+	dy := values["hedge_ratio"] - 0.7
+	return -(dx*dx + dy*dy), nil
+}
+
+var bowlSpace = []optimize.Param{
+	{Name: "width", Min: 0, Max: 1},
+	{Name: "hedge_ratio", Min: -1, Max: 2},
+}
+
+func TestOptimizeCMAES(t *testing.T) {
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space:  bowlSpace,
+		Method: optimize.CMAES,
+		Trials: 300,
+		Seed:   1,
+	}, bowl)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if len(report.Trials) != 300 {
+		t.Fatalf("expected 300 trials, got %d", len(report.Trials))
+	}
+	best := report.Best.Values
+	if math.Abs(best["width"]-0.3) > 0.01 || math.Abs(best["hedge_ratio"]-0.7) > 0.01 {
+		t.Errorf("expected best near (0.3, 0.7), got %v", best)
+	}
+	for _, trial := range report.Trials {
+		for _, param := range bowlSpace {
+			if v := trial.Values[param.Name]; v < param.Min || v > param.Max {
+				t.Fatalf("trial %d has %s = %g outside [%g, %g]", trial.Number, param.Name, v, param.Min, param.Max)
+			}
+		}
+	}
+}
+
+func TestOptimizeBayesian(t *testing.T) {
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space:  bowlSpace,
+		Method: optimize.Bayesian,
+		Trials: 40,
+		Seed:   1,
+	}, bowl)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	best := report.Best.Values
+	if math.Abs(best["width"]-0.3) > 0.05 || math.Abs(best["hedge_ratio"]-0.7) > 0.1 {
+		t.Errorf("expected best near (0.3, 0.7), got %v", best)
+	}
+	if !strings.Contains(report.Summary(), "hedge_ratio = ") {
+		t.Errorf("summary missing best values:\n%s", report.Summary())
+	}
+}
+
+func TestOptimizeDeterministic(t *testing.T) {
+	for _, method := range []optimize.Method{optimize.CMAES, optimize.Bayesian} {
+		config := optimize.Config{Space: bowlSpace, Method: method, Trials: 20, Seed: 7}
+		first, err := optimize.Optimize(context.Background(), config, bowl)
+		if err != nil {
+			t.Fatalf("%s: Optimize failed: %v", method, err)
+		}
+		second, err := optimize.Optimize(context.Background(), config, bowl)
+		if err != nil {
+			t.Fatalf("%s: Optimize failed: %v", method, err)
+		}
+		if !reflect.DeepEqual(first.Trials, second.Trials) {
+			t.Errorf("%s: same seed ran different trials", method)
+		}
+	}
+}
+
+func TestOptimizeEarlyStopping(t *testing.T) {
+	evaluations := 0
+	objective := func(ctx context.Context, values optimize.Values, fold int) (float64, error) {
+		evaluations++
+		return bowl(ctx, values, fold)
+	}
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space:         bowlSpace,
+		Method:        optimize.CMAES,
+		Trials:        60,
+		Folds:         4,
+		Seed:          3,
+		EarlyStopping: optimize.EarlyStopping{StartupTrials: 5},
+	}, objective)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if report.Pruned == 0 {
+		t.Fatal("expected some trials to stop early")
+	}
+	if evaluations >= 60*4 {
+		t.Errorf("expected stopped trials to skip folds, ran %d evaluations", evaluations)
+	}
+	for _, trial := range report.Trials {
+		if trial.Pruned != (len(trial.Scores) < 4) {
+			t.Errorf("trial %d: pruned %v with %d scores", trial.Number, trial.Pruned, len(trial.Scores))
+		}
+	}
+	if report.Best.Pruned {
+		t.Error("best trial must have finished")
+	}
+}
+
+func TestOptimizeParamScales(t *testing.T) {
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space: []optimize.Param{
+			{Name: "threshold", Min: 0.001, Max: 1, Log: true},
+			{Name: "lookback", Min: 5, Max: 50, Integer: true},
+		},
+		Method: optimize.Bayesian,
+		Trials: 10,
+	}, func(_ context.Context, values optimize.Values, _ int) (float64, error) {
+		return values["threshold"], nil
+	})
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	for _, trial := range report.Trials {
+		params := trial.Values.Params()
+		lookback, err := params.Int("lookback", 0)
+		if err != nil || lookback < 5 || lookback > 50 {
+			t.Errorf("trial %d: lookback %v (%v) is not an integer in [5, 50]", trial.Number, params["lookback"], err)
+		}
+		if threshold := trial.Values["threshold"]; threshold < 0.001 || threshold > 1 {
+			t.Errorf("trial %d: threshold %g outside [0.001, 1]", trial.Number, threshold)
+		}
+	}
+}
+
+func TestOptimizeErrors(t *testing.T) {
+	ctx := context.Background()
+	invalid := []optimize.Config{
+		{Method: optimize.CMAES, Trials: 10},
+		{Space: bowlSpace, Trials: 0},
+		{Space: []optimize.Param{{Name: "x", Min: 1, Max: 1}}, Trials: 10},
+		{Space: []optimize.Param{{Name: "x", Min: 0, Max: 1, Log: true}}, Trials: 10},
+		{Space: []optimize.Param{{Name: "x", Min: 0, Max: 1}, {Name: "x", Min: 0, Max: 1}}, Trials: 10},
+		{Space: bowlSpace, Trials: 10, EarlyStopping: optimize.EarlyStopping{Quantile: 1}},
+		{Space: bowlSpace, Trials: 10, Method: optimize.Method(9)},
+	}
+	for i, config := range invalid {
+		if _, err := optimize.Optimize(ctx, config, bowl); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+
+	failure := errors.New("backtest failed")
+	_, err := optimize.Optimize(ctx, optimize.Config{Space: bowlSpace, Trials: 5},
+		func(context.Context, optimize.Values, int) (float64, error) { return 0, failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("expected objective error, got %v", err)
+	}
+	_, err = optimize.Optimize(ctx, optimize.Config{Space: bowlSpace, Trials: 5},
+		func(context.Context, optimize.Values, int) (float64, error) { return math.NaN(), nil })
+	if err == nil {
+		t.Error("expected error for NaN score")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := optimize.Optimize(canceled, optimize.Config{Space: bowlSpace, Trials: 5}, bowl); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// risingSnapshots returns n hourly ETH/USD snapshots rising by 1 each.
+func risingSnapshots(n int) []strategy.MarketSnapshot {
+	snapshots := make([]strategy.MarketSnapshot, n)
+	start := primitives.Unix(1700000000, 0)
+	for i := range snapshots {
+		price := primitives.MustPrice(primitives.NewDecimal(int64(100 + i)))
+		snapshots[i] = strategy.NewSimpleSnapshot(start.Add(primitives.Hours(int64(i))), map[string]primitives.Price{"ETH/USD": price})
+	}
+	return snapshots
+}
+
+func TestWalkForward(t *testing.T) {
+	snapshots := risingSnapshots(101)
+	splits, err := optimize.WalkForward(snapshots, optimize.WalkForwardConfig{Folds: 3, Train: 40})
+	if err != nil {
+		t.Fatalf("WalkForward failed: %v", err)
+	}
+	if len(splits) != 3 {
+		t.Fatalf("expected 3 splits, got %d", len(splits))
+	}
+	for i, split := range splits {
+		if len(split.Train) != 40 {
+			t.Errorf("split %d: expected 40 training snapshots, got %d", i, len(split.Train))
+		}
+		if !split.Train[len(split.Train)-1].Time().Before(split.Test[0].Time()) {
+			t.Errorf("split %d: training window does not precede test window", i)
+		}
+	}
+	if len(splits[0].Test) != 20 || len(splits[2].Test) != 21 {
+		t.Errorf("expected test windows of 20, 20 and 21, got %d, %d and %d",
+			len(splits[0].Test), len(splits[1].Test), len(splits[2].Test))
+	}
+
+	anchored, err := optimize.WalkForward(snapshots, optimize.WalkForwardConfig{Folds: 3, Train: 40, Anchored: true})
+	if err != nil {
+		t.Fatalf("WalkForward failed: %v", err)
+	}
+	if len(anchored[2].Train) != 80 || anchored[2].Train[0] != snapshots[0] {
+		t.Errorf("expected anchored training window of 80 from the start, got %d", len(anchored[2].Train))
+	}
+
+	if _, err := optimize.WalkForward(snapshots, optimize.WalkForwardConfig{Folds: 40, Train: 40}); err == nil {
+		t.Error("expected error for folds shorter than 2 snapshots")
+	}
+}
+
+func TestBacktestObjective(t *testing.T) {
+	splits, err := optimize.WalkForward(risingSnapshots(60), optimize.WalkForwardConfig{Folds: 2, Train: 10})
+	if err != nil {
+		t.Fatalf("WalkForward failed: %v", err)
+	}
+	factory := func(params strategy.Params) (strategy.Strategy, error) {
+		weight, err := params.Decimal("weight", primitives.One())
+		if err != nil {
+			return nil, err
+		}
+		return strategy.NewBuyAndHold("ETH/USD", weight)
+	}
+	objective := optimize.Backtest(backtest.DefaultConfig(), factory, splits, optimize.ReturnMetric)
+
+	// On a rising market, the more invested the better
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space:  []optimize.Param{{Name: "weight", Min: 0.05, Max: 1}},
+		Method: optimize.CMAES,
+		Trials: 24,
+		Folds:  len(splits),
+	}, objective)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if weight := report.Best.Values["weight"]; weight < 0.8 {
+		t.Errorf("expected best weight near 1, got %g", weight)
+	}
+	if len(report.Best.Scores) != 2 || report.Best.Scores[0] <= 0 {
+		t.Errorf("expected positive returns on both folds, got %v", report.Best.Scores)
+	}
+
+	if o := report.Overfitting; o == nil || o.DeflatedSharpe.Observations != 48 || o.PBO == nil {
+		t.Errorf("expected diagnostics over both test windows' 48 returns, got %+v", o)
+	}
+
+	if _, err := objective(context.Background(), optimize.Values{"weight": 0.5}, 2); err == nil {
+		t.Error("expected error for fold out of range")
+	}
+}
+
+func TestOptimizeOverfitting(t *testing.T) {
+	// Every trial's returns are noise, so whatever is best got lucky
+	objective := func(ctx context.Context, values optimize.Values, fold int) (float64, error) {
+		rng := rand.New(rand.NewSource(int64(values["x"]*1e9) + int64(fold)))
+		returns := make([]float64, 100)
+		sum := 0.0
+		for i := range returns {
+			returns[i] = 0.01 * rng.NormFloat64()
+			sum += returns[i]
+		}
+		optimize.RecordReturns(ctx, returns)
+		return sum, nil
+	}
+	report, err := optimize.Optimize(context.Background(), optimize.Config{
+		Space:  []optimize.Param{{Name: "x", Min: 0, Max: 1}},
+		Method: optimize.CMAES,
+		Trials: 60,
+		Folds:  2,
+		Seed:   5,
+	}, objective)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	o := report.Overfitting
+	if o == nil || o.PBO == nil {
+		t.Fatalf("expected overfitting diagnostics, got %+v", o)
+	}
+	if len(report.Best.Returns) != 200 || o.DeflatedSharpe.Trials != 60 || o.PBO.Blocks != 16 {
+		t.Errorf("expected 200 returns, 60 trials and 16 blocks, got %d, %d and %d",
+			len(report.Best.Returns), o.DeflatedSharpe.Trials, o.PBO.Blocks)
+	}
+	if !o.Likely() {
+		t.Errorf("expected noise to be flagged as overfit: DSR %s, PBO %s", o.DeflatedSharpe.Probability, o.PBO.Probability)
+	}
+	if summary := report.Summary(); !strings.Contains(summary, "PBO: ") || !strings.Contains(summary, "likely overfit") {
+		t.Errorf("summary missing diagnostics:\n%s", summary)
+	}
+
+	// Objectives that record no returns get no diagnostics
+	plain, err := optimize.Optimize(context.Background(), optimize.Config{Space: bowlSpace, Trials: 10}, bowl)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if plain.Overfitting != nil {
+		t.Errorf("expected no diagnostics without returns, got %+v", plain.Overfitting)
+	}
+}
@@ -0,0 +1,124 @@
+package optimize
+
+import (
+	"context"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// returnsKey is the context key of the trial's returnsRecorder.
+type returnsKey struct{}
+
+// returnsRecorder collects the returns an objective records for a trial.
+type returnsRecorder struct {
+	returns []float64
+}
+
+// RecordReturns records the periodic returns of the fold an objective is
+// evaluating, for the overfitting diagnostics of Report.Overfitting.
+// Objectives built by Backtest record their runs' returns; others call it
+// with ctx as passed to the objective. Outside Optimize it does nothing.
+func RecordReturns(ctx context.Context, returns []float64) {
+	if recorder, ok := ctx.Value(returnsKey{}).(*returnsRecorder); ok {
+		recorder.returns = append(recorder.returns, returns...)
+	}
+}
+
+// Overfitting diagnoses whether the best of an optimization's trials owes
+// its score to the search rather than to skill. The more trials a search
+// runs, the higher the best score luck alone produces, so a high score is
+// only evidence of skill once deflated for the search that found it.
+type Overfitting struct {
+	// DeflatedSharpe deflates the best trial's Sharpe ratio for the
+	// number of trials and the dispersion of their Sharpe ratios
+	DeflatedSharpe analytics.DeflatedSharpeRatio
+
+	// PBO is the probability of backtest overfitting of the selection
+	// among the finished trials, by combinatorially symmetric
+	// cross-validation over their returns. Trials stopped early are left
+	// out, having no returns for the later folds. Nil when fewer than two
+	// trials finished or their returns are too short to cut into blocks.
+	PBO *analytics.BacktestOverfitting
+}
+
+// Likely reports whether the best trial is likely overfit: its deflated
+// Sharpe ratio is below 0.95, or its PBO is 0.5 or above.
+func (o *Overfitting) Likely() bool {
+	if o.DeflatedSharpe.Probability.LessThan(primitives.MustDecimalFromString("0.95")) {
+		return true
+	}
+	return o.PBO != nil && !o.PBO.Probability.LessThan(primitives.MustDecimalFromString("0.5"))
+}
+
+// diagnose computes the overfitting diagnostics of report, or returns nil
+// if the best trial has too few returns for them.
+func diagnose(report *Report, blocks int) *Overfitting {
+	best := report.Best.Returns
+	sharpes := make([]primitives.Decimal, 0, len(report.Trials))
+	for _, trial := range report.Trials {
+		if sharpe, ok := periodSharpe(trial.Returns); ok {
+			sharpes = append(sharpes, primitives.NewDecimalFromFloat(sharpe))
+		}
+	}
+	dsr, err := analytics.DeflatedSharpe(analytics.DeflatedSharpeConfig{
+		Returns:      decimals(best),
+		TrialSharpes: sharpes,
+		Trials:       len(report.Trials),
+	})
+	if err != nil {
+		return nil
+	}
+	overfitting := &Overfitting{DeflatedSharpe: dsr}
+
+	var series [][]primitives.Decimal
+	for _, trial := range report.Trials {
+		if !trial.Pruned && len(trial.Returns) == len(best) {
+			series = append(series, decimals(trial.Returns))
+		}
+	}
+	if blocks == 0 {
+		blocks = len(best) / 2
+		if blocks > analytics.DefaultOverfittingBlocks {
+			blocks = analytics.DefaultOverfittingBlocks
+		}
+		blocks -= blocks % 2
+	}
+	if blocks >= 2 {
+		if pbo, err := analytics.ProbabilityOfBacktestOverfitting(series, blocks); err == nil {
+			overfitting.PBO = &pbo
+		}
+	}
+	return overfitting
+}
+
+// periodSharpe returns the per-period Sharpe ratio of returns, and false
+// if it is undefined.
+func periodSharpe(returns []float64) (float64, bool) {
+	if len(returns) < 2 {
+		return 0, false
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	if variance == 0 {
+		return 0, false
+	}
+	return mean / math.Sqrt(variance), true
+}
+
+func decimals(values []float64) []primitives.Decimal {
+	out := make([]primitives.Decimal, len(values))
+	for i, v := range values {
+		out[i] = primitives.NewDecimalFromFloat(v)
+	}
+	return out
+}
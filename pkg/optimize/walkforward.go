@@ -0,0 +1,119 @@
+package optimize
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Split is one fold of a walk-forward cross-validation: a test window and
+// the history before it.
+type Split struct {
+	Train []strategy.MarketSnapshot
+	Test  []strategy.MarketSnapshot
+}
+
+// WalkForwardConfig configures WalkForward.
+type WalkForwardConfig struct {
+	// Folds is the number of test windows
+	Folds int
+
+	// Train is the number of snapshots before the first test window, and
+	// of each split's training window unless Anchored
+	Train int
+
+	// Anchored starts every training window at the first snapshot, so
+	// later folds train on all history before their test window
+	Anchored bool
+}
+
+// WalkForward splits snapshots into config.Folds consecutive test
+// windows following the first config.Train snapshots, each paired with
+// the training window just before it. Test windows are of equal length,
+// the last taking any remainder, so no fold tests on data another fold
+// trains after. Returns error if a test window would have fewer than two
+// snapshots.
+func WalkForward(snapshots []strategy.MarketSnapshot, config WalkForwardConfig) ([]Split, error) {
+	if config.Folds <= 0 {
+		return nil, fmt.Errorf("folds must be positive, got %d", config.Folds)
+	}
+	if config.Train <= 0 {
+		return nil, fmt.Errorf("training window must be positive, got %d snapshots", config.Train)
+	}
+	size := (len(snapshots) - config.Train) / config.Folds
+	if size < 2 {
+		return nil, fmt.Errorf("%d snapshots leave fewer than 2 per fold for %d folds after a %d-snapshot training window",
+			len(snapshots), config.Folds, config.Train)
+	}
+	splits := make([]Split, config.Folds)
+	for i := range splits {
+		start := config.Train + i*size
+		end := start + size
+		if i == config.Folds-1 {
+			end = len(snapshots)
+		}
+		trainStart := start - config.Train
+		if config.Anchored {
+			trainStart = 0
+		}
+		splits[i] = Split{Train: snapshots[trainStart:start], Test: snapshots[start:end]}
+	}
+	return splits, nil
+}
+
+// Metric scores a backtest; higher is better.
+type Metric func(result *backtest.Result) float64
+
+// SharpeMetric scores a backtest by its Sharpe ratio.
+func SharpeMetric(result *backtest.Result) float64 {
+	return result.Sharpe.Float64()
+}
+
+// ReturnMetric scores a backtest by its total return.
+func ReturnMetric(result *backtest.Result) float64 {
+	return result.TotalReturn.Float64()
+}
+
+// Backtest returns an objective that scores fold i by backtesting the
+// strategy factory builds from the trial's values (see Values.Params) on
+// splits[i] and applying metric. Each run warms up on the split's
+// training window, replacing config.WarmUp and config.WarmUpDuration, so
+// the strategy's indicators start the test window with history and only
+// the test window is measured. The test window's point-to-point returns
+// are recorded for Report.Overfitting.
+func Backtest(config backtest.Config, factory strategy.Factory, splits []Split, metric Metric) Objective {
+	return func(ctx context.Context, values Values, fold int) (float64, error) {
+		if factory == nil || metric == nil {
+			return 0, fmt.Errorf("factory and metric are required")
+		}
+		if fold < 0 || fold >= len(splits) {
+			return 0, fmt.Errorf("fold %d out of range for %d splits", fold, len(splits))
+		}
+		split := splits[fold]
+		strat, err := factory(values.Params())
+		if err != nil {
+			return 0, fmt.Errorf("failed to build strategy: %w", err)
+		}
+		run := config
+		run.WarmUp = len(split.Train)
+		run.WarmUpDuration = primitives.Duration{}
+		snapshots := make([]strategy.MarketSnapshot, 0, len(split.Train)+len(split.Test))
+		snapshots = append(append(snapshots, split.Train...), split.Test...)
+		result, err := backtest.NewEngine(run).Run(ctx, strat, snapshots)
+		if err != nil {
+			return 0, err
+		}
+		history := result.History()
+		returns := make([]float64, 0, history.Len())
+		for i := 1; i < history.Len(); i++ {
+			if previous := history.Value(i - 1).Decimal().Float64(); previous != 0 {
+				returns = append(returns, history.Value(i).Decimal().Float64()/previous-1)
+			}
+		}
+		RecordReturns(ctx, returns)
+		return metric(result), nil
+	}
+}
@@ -0,0 +1,239 @@
+package primitives
+
+import (
+	"fmt"
+	"time"
+)
+
+// FundingInterval is the funding period used by most perpetual venues.
+var FundingInterval = Hours(8)
+
+// NextFundingTime returns the first funding timestamp strictly after t for
+// funding every interval, with timestamps aligned to 00:00 UTC (00:00,
+// 08:00 and 16:00 for the default eight hours). The result is in UTC.
+// Returns ErrInvalidDuration if interval is not positive or does not
+// divide a day evenly.
+func NextFundingTime(t Time, interval Duration) (Time, error) {
+	step := interval.Duration()
+	if step <= 0 || (24*time.Hour)%step != 0 {
+		return Time{}, fmt.Errorf("%w: funding interval %s must divide a day", ErrInvalidDuration, interval)
+	}
+	utc := t.value.UTC()
+	next := utc.Truncate(step)
+	if !next.After(utc) {
+		next = next.Add(step)
+	}
+	return Time{value: next}, nil
+}
+
+// FundingTimes returns the funding timestamps in (from, to], in order, for
+// accruing funding over a holding period. Returns ErrInvalidDuration under
+// the same conditions as NextFundingTime.
+func FundingTimes(from, to Time, interval Duration) ([]Time, error) {
+	next, err := NextFundingTime(from, interval)
+	if err != nil {
+		return nil, err
+	}
+	var times []Time
+	for !next.After(to) {
+		times = append(times, next)
+		next = next.Add(interval)
+	}
+	return times, nil
+}
+
+// ExpiryCalendar describes an options or futures venue's expiry
+// convention: contracts expire at Hour on Weekday in Location, weeklies
+// every such day, monthlies on the last such day of the month and
+// quarterlies on the last such day of March, June, September and December.
+type ExpiryCalendar struct {
+	Weekday  time.Weekday
+	Hour     int
+	Location *time.Location
+}
+
+// DeribitExpiry is Deribit's convention: 08:00 UTC on Fridays.
+var DeribitExpiry = ExpiryCalendar{Weekday: time.Friday, Hour: 8, Location: time.UTC}
+
+// NextDaily returns the first daily expiry (Hour on any day) strictly
+// after t.
+func (c ExpiryCalendar) NextDaily(t Time) Time {
+	local := t.value.In(c.location())
+	expiry := c.at(local.Year(), local.Month(), local.Day())
+	if !expiry.After(local) {
+		expiry = c.at(local.Year(), local.Month(), local.Day()+1)
+	}
+	return Time{value: expiry}
+}
+
+// NextWeekly returns the first weekly expiry strictly after t.
+func (c ExpiryCalendar) NextWeekly(t Time) Time {
+	local := t.value.In(c.location())
+	days := (int(c.Weekday) - int(local.Weekday()) + 7) % 7
+	expiry := c.at(local.Year(), local.Month(), local.Day()+days)
+	if !expiry.After(local) {
+		expiry = expiry.AddDate(0, 0, 7)
+	}
+	return Time{value: expiry}
+}
+
+// Monthly returns the monthly expiry of a calendar month: the last
+// Weekday of the month at Hour.
+func (c ExpiryCalendar) Monthly(year int, month time.Month) Time {
+	last := c.at(year, month+1, 0)
+	days := (int(last.Weekday()) - int(c.Weekday) + 7) % 7
+	return Time{value: last.AddDate(0, 0, -days)}
+}
+
+// NextMonthly returns the first monthly expiry strictly after t.
+func (c ExpiryCalendar) NextMonthly(t Time) Time {
+	local := t.value.In(c.location())
+	year, month := local.Year(), local.Month()
+	for {
+		if expiry := c.Monthly(year, month); expiry.value.After(local) {
+			return expiry
+		}
+		month++
+		if month > time.December {
+			year, month = year+1, time.January
+		}
+	}
+}
+
+// NextQuarterly returns the first quarterly expiry strictly after t.
+func (c ExpiryCalendar) NextQuarterly(t Time) Time {
+	local := t.value.In(c.location())
+	year, month := local.Year(), quarterEndMonth(local.Month())
+	for {
+		if expiry := c.Monthly(year, month); expiry.value.After(local) {
+			return expiry
+		}
+		month += 3
+		if month > time.December {
+			year, month = year+1, time.March
+		}
+	}
+}
+
+func (c ExpiryCalendar) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+func (c ExpiryCalendar) at(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, c.Hour, 0, 0, 0, c.location())
+}
+
+// MonthEnd returns midnight at the start of the last day of t's month, in
+// t's location.
+func MonthEnd(t Time) Time {
+	return Time{value: time.Date(t.value.Year(), t.value.Month()+1, 0, 0, 0, 0, 0, t.value.Location())}
+}
+
+// QuarterEnd returns midnight at the start of the last day of t's
+// calendar quarter, in t's location.
+func QuarterEnd(t Time) Time {
+	month := quarterEndMonth(t.value.Month())
+	return Time{value: time.Date(t.value.Year(), month+1, 0, 0, 0, 0, 0, t.value.Location())}
+}
+
+// NextMonthStart returns midnight on the first day of the month after
+// t's, in t's location: the usual time to roll a monthly position.
+func NextMonthStart(t Time) Time {
+	return Time{value: time.Date(t.value.Year(), t.value.Month()+1, 1, 0, 0, 0, 0, t.value.Location())}
+}
+
+// NextQuarterStart returns midnight on the first day of the quarter after
+// t's, in t's location.
+func NextQuarterStart(t Time) Time {
+	month := quarterEndMonth(t.value.Month())
+	return Time{value: time.Date(t.value.Year(), month+1, 1, 0, 0, 0, 0, t.value.Location())}
+}
+
+// quarterEndMonth returns the last month of month's quarter.
+func quarterEndMonth(month time.Month) time.Month {
+	return ((month-1)/3)*3 + 3
+}
+
+// BusinessCalendar treats Saturdays, Sundays and a set of holidays as
+// non-business days. Days are judged in the location of the time passed,
+// so a calendar works for any market's local time. The zero value has no
+// holidays.
+type BusinessCalendar struct {
+	holidays map[civilDate]bool
+}
+
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) civilDate {
+	return civilDate{t.Year(), t.Month(), t.Day()}
+}
+
+// NewBusinessCalendar creates a calendar with holidays, each given as any
+// time on the holiday's date in its own location.
+func NewBusinessCalendar(holidays ...Time) BusinessCalendar {
+	c := BusinessCalendar{holidays: make(map[civilDate]bool, len(holidays))}
+	for _, holiday := range holidays {
+		c.holidays[dateOf(holiday.value)] = true
+	}
+	return c
+}
+
+// IsBusinessDay reports whether t falls on a weekday that is not a holiday.
+func (c BusinessCalendar) IsBusinessDay(t Time) bool {
+	switch t.value.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	return !c.holidays[dateOf(t.value)]
+}
+
+// AddBusinessDays moves t forward by n business days (backward if n is
+// negative), keeping its clock time. Adding zero rolls a non-business day
+// forward to the next business day.
+func (c BusinessCalendar) AddBusinessDays(t Time, n int) Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	day := t.value
+	if n == 0 {
+		for !c.IsBusinessDay(Time{value: day}) {
+			day = day.AddDate(0, 0, 1)
+		}
+		return Time{value: day}
+	}
+	for n > 0 {
+		day = day.AddDate(0, 0, step)
+		if c.IsBusinessDay(Time{value: day}) {
+			n--
+		}
+	}
+	return Time{value: day}
+}
+
+// BusinessDaysBetween counts the business days after from's date up to and
+// including to's date, negated when to precedes from.
+func (c BusinessCalendar) BusinessDaysBetween(from, to Time) int {
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	start := dateOf(from.value)
+	end := dateOf(to.value.In(from.value.Location()))
+	day := time.Date(start.year, start.month, start.day, 12, 0, 0, 0, from.value.Location())
+	count := 0
+	for dateOf(day) != end {
+		day = day.AddDate(0, 0, 1)
+		if c.IsBusinessDay(Time{value: day}) {
+			count++
+		}
+	}
+	return sign * count
+}
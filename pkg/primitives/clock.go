@@ -0,0 +1,54 @@
+package primitives
+
+import "sync"
+
+// Clock is a source of the current time. Code that stamps or schedules
+// by the current time should take a Clock rather than calling time.Now,
+// so that a backtest can run it on snapshot time and reproduce it
+// exactly.
+type Clock interface {
+	Now() Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+// Now returns the current wall-clock time.
+func (SystemClock) Now() Time {
+	return Now()
+}
+
+// ManualClock is a Clock that only moves when set, as the backtest engine
+// moves it to each snapshot's time.
+//
+// Thread Safety: ManualClock is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.RWMutex
+	now Time
+}
+
+// NewManualClock creates a clock reading t.
+func NewManualClock(t Time) *ManualClock {
+	return &ManualClock{now: t}
+}
+
+// Now returns the time the clock was last set to.
+func (c *ManualClock) Now() Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *ManualClock) Set(t Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
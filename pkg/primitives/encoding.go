@@ -0,0 +1,615 @@
+package primitives
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Encoding
+//
+// Decimal-backed types (Decimal, Price, Amount, Quantity) encode as their
+// exact decimal string, e.g. "1999.50" in JSON, so no precision is lost to
+// float64. JSON decoding also accepts bare numbers, which are parsed from
+// their literal text rather than through float64.
+//
+// Time encodes as RFC 3339 with nanoseconds and Duration as a Go duration
+// string ("1h30m0s"). Pair encodes as its canonical "BASE/QUOTE" form and
+// the zero Pair as "".
+//
+// Percent and BasisPoints encode with their unit ("0.3%", "30bps"); decoding
+// also accepts a bare number in the type's unit. Their database value is the
+// bare decimal string. Rate encodes as "<fraction>/<period>/<compounding>"
+// (e.g. "0.0001/8h0m0s/simple") in text and SQL, and as an object with
+// fraction, period and compounding fields in JSON.
+//
+// All types implement encoding.TextMarshaler, json.Marshaler, sql.Scanner
+// and driver.Valuer (Pair: text only). Decoding into Price and Amount
+// rejects negative values. JSON null leaves the destination unchanged.
+
+var jsonNull = []byte("null")
+
+// unquoteJSON returns the text of a JSON string or number literal.
+func unquoteJSON(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+	return string(data), nil
+}
+
+// scanText converts a database value to text for parsing.
+func scanText(src interface{}) (string, error) {
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case int64:
+		return fmt.Sprintf("%d", v), nil
+	case float64:
+		return NewDecimalFromFloat(v).String(), nil
+	default:
+		return "", fmt.Errorf("cannot scan %T", src)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := NewDecimalFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a decimal string.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a decimal string or number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (d *Decimal) Scan(src interface{}) error {
+	if src == nil {
+		*d = Zero()
+		return nil
+	}
+	s, err := scanText(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer, storing the exact decimal string.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Price) MarshalText() ([]byte, error) {
+	return p.value.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Returns ErrNegativePrice for negative values.
+func (p *Price) UnmarshalText(text []byte) error {
+	var d Decimal
+	if err := d.UnmarshalText(text); err != nil {
+		return err
+	}
+	return p.set(d)
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a decimal string.
+func (p Price) MarshalJSON() ([]byte, error) {
+	return p.value.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Returns ErrNegativePrice for negative values.
+func (p *Price) UnmarshalJSON(data []byte) error {
+	d := p.value
+	if err := d.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	return p.set(d)
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (p *Price) Scan(src interface{}) error {
+	var d Decimal
+	if err := d.Scan(src); err != nil {
+		return err
+	}
+	return p.set(d)
+}
+
+// Value implements driver.Valuer, storing the exact decimal string.
+func (p Price) Value() (driver.Value, error) {
+	return p.value.Value()
+}
+
+func (p *Price) set(d Decimal) error {
+	price, err := NewPrice(d)
+	if err != nil {
+		return err
+	}
+	*p = price
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Amount) MarshalText() ([]byte, error) {
+	return a.value.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Returns ErrNegativeAmount for negative values.
+func (a *Amount) UnmarshalText(text []byte) error {
+	var d Decimal
+	if err := d.UnmarshalText(text); err != nil {
+		return err
+	}
+	return a.set(d)
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a decimal string.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return a.value.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Returns ErrNegativeAmount for negative values.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	d := a.value
+	if err := d.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	return a.set(d)
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (a *Amount) Scan(src interface{}) error {
+	var d Decimal
+	if err := d.Scan(src); err != nil {
+		return err
+	}
+	return a.set(d)
+}
+
+// Value implements driver.Valuer, storing the exact decimal string.
+func (a Amount) Value() (driver.Value, error) {
+	return a.value.Value()
+}
+
+func (a *Amount) set(d Decimal) error {
+	amount, err := NewAmount(d)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (q Quantity) MarshalText() ([]byte, error) {
+	return q.value.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (q *Quantity) UnmarshalText(text []byte) error {
+	return q.value.UnmarshalText(text)
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a decimal string.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return q.value.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	return q.value.UnmarshalJSON(data)
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (q *Quantity) Scan(src interface{}) error {
+	return q.value.Scan(src)
+}
+
+// Value implements driver.Valuer, storing the exact decimal string.
+func (q Quantity) Value() (driver.Value, error) {
+	return q.value.Value()
+}
+
+// MarshalText implements encoding.TextMarshaler using RFC 3339 with nanoseconds.
+func (t Time) MarshalText() ([]byte, error) {
+	return t.value.MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing RFC 3339.
+func (t *Time) UnmarshalText(text []byte) error {
+	return t.value.UnmarshalText(text)
+}
+
+// MarshalJSON implements json.Marshaler using RFC 3339 with nanoseconds.
+func (t Time) MarshalJSON() ([]byte, error) {
+	return t.value.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler, parsing RFC 3339.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	return t.value.UnmarshalJSON(data)
+}
+
+// Scan implements sql.Scanner, accepting time.Time or RFC 3339 text.
+// NULL scans as the zero Time.
+func (t *Time) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Time{}
+		return nil
+	case time.Time:
+		*t = NewTime(v)
+		return nil
+	case string:
+		return t.UnmarshalText([]byte(v))
+	case []byte:
+		return t.UnmarshalText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Time", src)
+	}
+}
+
+// Value implements driver.Valuer, storing a time.Time.
+func (t Time) Value() (driver.Value, error) {
+	return t.value, nil
+}
+
+// MarshalText implements encoding.TextMarshaler using Go duration syntax.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing Go duration
+// syntax (e.g., "90s", "1h30m").
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDuration, err)
+	}
+	*d = NewDuration(parsed)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a duration string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a duration string
+// or an integer number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, jsonNull) {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '"' {
+		var nanos int64
+		if err := json.Unmarshal(data, &nanos); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidDuration, err)
+		}
+		*d = NewDuration(time.Duration(nanos))
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDuration, err)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner, accepting integer nanoseconds or duration text.
+// NULL scans as zero.
+func (d *Duration) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Duration{}
+		return nil
+	case int64:
+		*d = NewDuration(time.Duration(v))
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidDuration, src)
+	}
+}
+
+// Value implements driver.Valuer, storing integer nanoseconds.
+func (d Duration) Value() (driver.Value, error) {
+	return int64(d.value), nil
+}
+
+// MarshalText implements encoding.TextMarshaler using the canonical form.
+// The zero Pair encodes as "".
+func (p Pair) MarshalText() ([]byte, error) {
+	if p.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParsePair.
+// Empty text decodes to the zero Pair.
+func (p *Pair) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = Pair{}
+		return nil
+	}
+	parsed, err := ParsePair(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// unitText strips an optional unit suffix and parses the remaining decimal.
+func unitText(text []byte, unit string) (Decimal, error) {
+	s := strings.TrimSpace(string(text))
+	s = strings.TrimSpace(strings.TrimSuffix(s, unit))
+	return NewDecimalFromString(s)
+}
+
+// MarshalText implements encoding.TextMarshaler, e.g. "0.3%".
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "0.3%" or "0.3".
+func (p *Percent) UnmarshalText(text []byte) error {
+	d, err := unitText(text, "%")
+	if err != nil {
+		return err
+	}
+	*p = NewPercent(d)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a string such as "0.3%".
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting "0.3%", "0.3" or 0.3.
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return p.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (p *Percent) Scan(src interface{}) error {
+	if src == nil {
+		*p = Percent{}
+		return nil
+	}
+	s, err := scanText(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return p.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer, storing the value in percent as a
+// decimal string without the unit.
+func (p Percent) Value() (driver.Value, error) {
+	return p.value.Value()
+}
+
+// MarshalText implements encoding.TextMarshaler, e.g. "30bps".
+func (b BasisPoints) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "30bps" or "30".
+func (b *BasisPoints) UnmarshalText(text []byte) error {
+	d, err := unitText(text, "bps")
+	if err != nil {
+		return err
+	}
+	*b = NewBasisPoints(d)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding as a string such as "30bps".
+func (b BasisPoints) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting "30bps", "30" or 30.
+func (b *BasisPoints) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		return nil
+	}
+	s, err := unquoteJSON(data)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner. NULL scans as zero.
+func (b *BasisPoints) Scan(src interface{}) error {
+	if src == nil {
+		*b = BasisPoints{}
+		return nil
+	}
+	s, err := scanText(src)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidDecimal, err)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer, storing the value in basis points as a
+// decimal string without the unit.
+func (b BasisPoints) Value() (driver.Value, error) {
+	return b.value.Value()
+}
+
+// MarshalText implements encoding.TextMarshaler using the convention's name.
+func (c Compounding) MarshalText() ([]byte, error) {
+	switch c {
+	case CompoundingSimple, CompoundingDiscrete, CompoundingContinuous:
+		return []byte(c.String()), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown compounding %d", ErrInvalidRate, int(c))
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting "simple",
+// "discrete" or "continuous" in any case.
+func (c *Compounding) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "simple":
+		*c = CompoundingSimple
+	case "discrete":
+		*c = CompoundingDiscrete
+	case "continuous":
+		*c = CompoundingContinuous
+	default:
+		return fmt.Errorf("%w: unknown compounding %q", ErrInvalidRate, text)
+	}
+	return nil
+}
+
+// rateJSON is the JSON form of a Rate.
+type rateJSON struct {
+	Fraction    Decimal     `json:"fraction"`
+	Period      Duration    `json:"period"`
+	Compounding Compounding `json:"compounding"`
+}
+
+// MarshalText implements encoding.TextMarshaler as
+// "<fraction>/<period>/<compounding>", e.g. "0.0001/8h0m0s/simple".
+func (r Rate) MarshalText() ([]byte, error) {
+	compounding, err := r.compounding.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(r.value.String() + "/" + r.period.String() + "/" + string(compounding)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Returns ErrInvalidRate for malformed text or a non-positive period.
+func (r *Rate) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: %q is not <fraction>/<period>/<compounding>", ErrInvalidRate, text)
+	}
+	var (
+		fraction    Decimal
+		period      Duration
+		compounding Compounding
+	)
+	if err := fraction.UnmarshalText([]byte(parts[0])); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRate, err)
+	}
+	if err := period.UnmarshalText([]byte(parts[1])); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidRate, err)
+	}
+	if err := compounding.UnmarshalText([]byte(parts[2])); err != nil {
+		return err
+	}
+	return r.set(fraction, period, compounding)
+}
+
+// MarshalJSON implements json.Marshaler, encoding as
+// {"fraction":"0.0001","period":"8h0m0s","compounding":"simple"}.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	if _, err := r.compounding.MarshalText(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rateJSON{Fraction: r.value, Period: r.period, Compounding: r.compounding})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A missing compounding field
+// decodes as simple. Returns ErrInvalidRate for a non-positive period.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+		return nil
+	}
+	var v rateJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return r.set(v.Fraction, v.Period, v.Compounding)
+}
+
+// Scan implements sql.Scanner, parsing the text form. NULL scans as the
+// zero Rate.
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		return r.UnmarshalText([]byte(v))
+	case []byte:
+		return r.UnmarshalText(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+}
+
+// Value implements driver.Valuer, storing the text form.
+func (r Rate) Value() (driver.Value, error) {
+	text, err := r.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+func (r *Rate) set(fraction Decimal, period Duration, compounding Compounding) error {
+	rate, err := NewRate(fraction, period, compounding)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
@@ -0,0 +1,191 @@
+package primitives
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// MathPrecision is the number of decimal places Sqrt, Exp, Ln and Pow
+// compute their results to. Like the division precision of the underlying
+// decimal library, it is package-wide: set it during initialization, not
+// concurrently with calculations.
+var MathPrecision int32 = 18
+
+// maxExpArgument bounds the argument of Exp. e^1000 has 435 integer
+// digits, far beyond any financial quantity.
+var maxExpArgument = decimal.NewFromInt(1000)
+
+var decimalOne = decimal.NewFromInt(1)
+
+// Sqrt returns the square root of d to MathPrecision places, or zero if d
+// is not positive. It is computed exactly in integer arithmetic and
+// rounded, so it is safe for monetary amounts.
+func (d Decimal) Sqrt() Decimal {
+	if !d.IsPositive() {
+		return Zero()
+	}
+	// √(d·10^2n) = √d·10^n, with a digit beyond places for rounding
+	digits := MathPrecision + 1
+	root := d.value.Shift(2 * digits).BigInt()
+	root.Sqrt(root)
+	return Decimal{value: decimal.NewFromBigInt(root, -digits).Round(MathPrecision)}
+}
+
+// Exp returns e^d to MathPrecision places. Returns ErrInvalidDecimal if d
+// exceeds 1000; below -1000 the result is zero at any practical
+// precision and Exp returns zero.
+func (d Decimal) Exp() (Decimal, error) {
+	if d.value.GreaterThan(maxExpArgument) {
+		return Zero(), fmt.Errorf("%w: exp(%s) overflows", ErrInvalidDecimal, d)
+	}
+	if d.value.LessThan(maxExpArgument.Neg()) {
+		return Zero(), nil
+	}
+	return Decimal{value: exp(d.value, MathPrecision)}, nil
+}
+
+// Ln returns the natural logarithm of d to MathPrecision places. Returns
+// ErrInvalidDecimal if d is not positive.
+func (d Decimal) Ln() (Decimal, error) {
+	if !d.IsPositive() {
+		return Zero(), fmt.Errorf("%w: ln(%s) is undefined", ErrInvalidDecimal, d)
+	}
+	ln, err := ln(d.value, MathPrecision)
+	if err != nil {
+		return Zero(), fmt.Errorf("%w: %v", ErrInvalidDecimal, err)
+	}
+	return Decimal{value: ln}, nil
+}
+
+// Pow returns d raised to exponent to MathPrecision places. Integer
+// exponents up to 64 in magnitude are computed by repeated multiplication;
+// others as e^(exponent·ln d). Returns ErrInvalidDecimal for 0 raised to a
+// non-positive exponent, a negative base with a fractional exponent, or a
+// result beyond the range of Exp.
+func (d Decimal) Pow(exponent Decimal) (Decimal, error) {
+	places := MathPrecision
+	if d.IsZero() || (exponent.value.IsInteger() && exponent.value.Abs().LessThanOrEqual(decimal.NewFromInt(64))) {
+		result, err := d.value.PowWithPrecision(exponent.value, places)
+		if err != nil {
+			return Zero(), fmt.Errorf("%w: %v", ErrInvalidDecimal, err)
+		}
+		return Decimal{value: result.Round(places)}, nil
+	}
+
+	if d.IsNegative() {
+		if !exponent.value.IsInteger() {
+			return Zero(), fmt.Errorf("%w: %s^%s is not real", ErrInvalidDecimal, d, exponent)
+		}
+		result, err := d.Neg().Pow(exponent)
+		if err != nil || exponent.value.BigInt().Bit(0) == 0 {
+			return result, err
+		}
+		return result.Neg(), nil
+	}
+
+	// The error in ln d is scaled by the exponent and the result, so carry
+	// enough guard digits to cover both
+	logBase, err := ln(d.value, places+10)
+	if err != nil {
+		return Zero(), fmt.Errorf("%w: %v", ErrInvalidDecimal, err)
+	}
+	power := logBase.Mul(exponent.value)
+	if guard := powerGuardDigits(power, exponent.value); guard > 10 {
+		if logBase, err = ln(d.value, places+guard); err != nil {
+			return Zero(), fmt.Errorf("%w: %v", ErrInvalidDecimal, err)
+		}
+		power = logBase.Mul(exponent.value)
+	}
+	result, err := (Decimal{value: power}).Exp()
+	if err != nil {
+		return Zero(), fmt.Errorf("%w: %s^%s overflows", ErrInvalidDecimal, d, exponent)
+	}
+	return result, nil
+}
+
+// powerGuardDigits returns the digits of precision lost to the magnitude
+// of e^power and of the exponent.
+func powerGuardDigits(power, exponent decimal.Decimal) int32 {
+	guard := int32(2 + exponent.Abs().Ceil().NumDigits())
+	if power.IsPositive() {
+		guard += int32(math.Ceil(power.InexactFloat64() / math.Ln10))
+	}
+	return guard
+}
+
+// exp returns e^x to places decimal places, for |x| at most
+// maxExpArgument, in binary fixed-point integer arithmetic. The argument
+// is halved below 10^-3, where a few Taylor terms suffice, and the result
+// squared back.
+func exp(x decimal.Decimal, places int32) decimal.Decimal {
+	magnitude := math.Abs(x.InexactFloat64())
+	halvings := 0
+	for m := magnitude; m > 1e-3; m /= 2 {
+		halvings++
+	}
+
+	// Each squaring doubles the relative error, and the result's integer
+	// digits consume precision, so work with guard bits for both
+	work := int(places)*4 + halvings + 16
+	if x.IsPositive() {
+		work += int(math.Ceil(magnitude / math.Ln2))
+	}
+	bits := uint(work)
+
+	// Values are integers in units of 2^-bits; the argument is scaled by
+	// 2^-halvings on conversion
+	reduced := new(big.Int).Lsh(x.Coefficient(), bits-uint(halvings))
+	if e := x.Exponent(); e < 0 {
+		reduced.Quo(reduced, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-e)), nil))
+	} else {
+		reduced.Mul(reduced, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(e)), nil))
+	}
+
+	sum := new(big.Int).Lsh(big.NewInt(1), bits)
+	term := new(big.Int).Set(sum)
+	product := new(big.Int)
+	n := new(big.Int)
+	for i := int64(1); term.Sign() != 0; i++ {
+		product.Mul(term, reduced)
+		term.Rsh(product, bits)
+		term.Quo(term, n.SetInt64(i))
+		sum.Add(sum, term)
+	}
+	for i := 0; i < halvings; i++ {
+		product.Mul(sum, sum)
+		sum.Rsh(product, bits)
+	}
+
+	// Back to decimal, with a digit beyond places for rounding
+	digits := places + 1
+	sum.Mul(sum, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+	sum.Rsh(sum, bits)
+	return decimal.NewFromBigInt(sum, -digits).Round(places)
+}
+
+// ln returns the natural logarithm of a positive x to places decimal
+// places by Newton's method on e^y = x, y ← y + x·e^(-y) - 1, from a
+// float64 estimate; each step doubles the correct digits. Values outside
+// the float64 range fall back to the decimal library's series.
+func ln(x decimal.Decimal, places int32) (decimal.Decimal, error) {
+	estimate := math.Log(x.InexactFloat64())
+	if math.IsInf(estimate, 0) || math.IsNaN(estimate) {
+		return x.Ln(places)
+	}
+	work := places + 4
+	tolerance := decimal.New(1, -work)
+	// e^(-y) is about 1/x, so it needs x's integer digits beyond work
+	expPlaces := work + int32(x.Ceil().NumDigits())
+	y := decimal.NewFromFloat(estimate)
+	for i := 0; i < 10; i++ {
+		step := x.Mul(exp(y.Neg(), expPlaces)).Sub(decimalOne)
+		y = y.Add(step)
+		if step.Abs().LessThanOrEqual(tolerance) {
+			break
+		}
+	}
+	return y.Round(places), nil
+}
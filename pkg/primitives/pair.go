@@ -0,0 +1,146 @@
+package primitives
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPair indicates a pair symbol that cannot be parsed
+var ErrInvalidPair = errors.New("invalid pair")
+
+// pairSeparators are accepted between base and quote when parsing.
+const pairSeparators = "/-_:"
+
+// KnownQuotes lists quote assets recognized when parsing concatenated
+// symbols such as "ETHUSDC". Longer symbols are tried first, so "USDC"
+// wins over "USD". Extend it at init time to support other venues.
+var KnownQuotes = []string{"USDT", "USDC", "BUSD", "TUSD", "FDUSD", "DAI", "USD", "EUR", "GBP", "BTC", "ETH", "BNB"}
+
+// Pair identifies a market by its base and quote assets. The canonical
+// string form is "BASE/QUOTE" in upper case, e.g. "ETH/USD". A price for
+// the pair is the number of quote units per base unit.
+type Pair struct {
+	Base  string
+	Quote string
+}
+
+// NewPair creates a Pair from base and quote symbols, normalizing case
+// and surrounding whitespace. Returns error if either symbol is empty or
+// base equals quote.
+func NewPair(base, quote string) (Pair, error) {
+	p := Pair{Base: normalizeAsset(base), Quote: normalizeAsset(quote)}
+	if p.Base == "" || p.Quote == "" {
+		return Pair{}, fmt.Errorf("%w: base and quote are required", ErrInvalidPair)
+	}
+	if p.Base == p.Quote {
+		return Pair{}, fmt.Errorf("%w: base and quote are both %s", ErrInvalidPair, p.Base)
+	}
+	return p, nil
+}
+
+// ParsePair parses a pair symbol in any common format: "ETH/USD",
+// "eth-usd", "ETH_USDC", "ETH:USDT" or concatenated "ETHUSDC" (using
+// KnownQuotes to find the split).
+func ParsePair(symbol string) (Pair, error) {
+	s := normalizeAsset(symbol)
+	if i := strings.IndexAny(s, pairSeparators); i >= 0 {
+		base, quote := s[:i], s[i+1:]
+		if strings.ContainsAny(quote, pairSeparators) {
+			return Pair{}, fmt.Errorf("%w: %q has more than one separator", ErrInvalidPair, symbol)
+		}
+		return NewPair(base, quote)
+	}
+
+	best := ""
+	for _, quote := range KnownQuotes {
+		if len(quote) > len(best) && len(s) > len(quote) && strings.HasSuffix(s, quote) {
+			best = quote
+		}
+	}
+	if best == "" {
+		return Pair{}, fmt.Errorf("%w: cannot split %q into base and quote", ErrInvalidPair, symbol)
+	}
+	return NewPair(strings.TrimSuffix(s, best), best)
+}
+
+// MustPair parses a pair symbol, panicking on error.
+// Only use for known-valid constants in tests or initialization.
+func MustPair(symbol string) Pair {
+	p, err := ParsePair(symbol)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the canonical "BASE/QUOTE" form.
+func (p Pair) String() string {
+	return p.Base + "/" + p.Quote
+}
+
+// Inverse returns the pair with base and quote swapped (ETH/USD -> USD/ETH).
+func (p Pair) Inverse() Pair {
+	return Pair{Base: p.Quote, Quote: p.Base}
+}
+
+// InvertPrice converts a price quoted for p into a price for p.Inverse().
+// Returns ErrDivisionByZero if price is zero.
+func (p Pair) InvertPrice(price Price) (Price, error) {
+	if price.IsZero() {
+		return Price{}, ErrDivisionByZero
+	}
+	inv, err := One().Div(price.Decimal())
+	if err != nil {
+		return Price{}, err
+	}
+	return NewPrice(inv)
+}
+
+// IsZero returns true for the zero Pair.
+func (p Pair) IsZero() bool {
+	return p.Base == "" && p.Quote == ""
+}
+
+// Contains returns true if asset is the pair's base or quote.
+func (p Pair) Contains(asset string) bool {
+	asset = normalizeAsset(asset)
+	return p.Base == asset || p.Quote == asset
+}
+
+// AssetAliases maps alternative asset symbols to a canonical symbol, so
+// pairs quoted for wrapped or bridged assets match their underlying, e.g.
+// {"WETH": "ETH"} makes "WETH/USDC" and "ETH/USDC" the same market.
+// Keys are upper-case symbols; values are normalized on lookup.
+type AssetAliases map[string]string
+
+// CommonAssetAliases maps widely used wrapped tokens to their underlying
+// asset. Pass it (or a copy extended for your venues) where aliases are
+// accepted; no aliases are applied unless configured.
+var CommonAssetAliases = AssetAliases{
+	"WETH":   "ETH",
+	"WBTC":   "BTC",
+	"WBNB":   "BNB",
+	"WMATIC": "MATIC",
+}
+
+// Asset returns the canonical symbol for asset.
+func (a AssetAliases) Asset(asset string) string {
+	asset = normalizeAsset(asset)
+	if canonical, ok := a[asset]; ok {
+		return normalizeAsset(canonical)
+	}
+	return asset
+}
+
+// Pair returns p with both assets replaced by their canonical symbols.
+func (a AssetAliases) Pair(p Pair) Pair {
+	if len(a) == 0 {
+		return p
+	}
+	return Pair{Base: a.Asset(p.Base), Quote: a.Asset(p.Quote)}
+}
+
+func normalizeAsset(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
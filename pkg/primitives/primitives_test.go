@@ -1,6 +1,10 @@
 package primitives
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
 	"testing"
 	"time"
 )
@@ -117,6 +121,51 @@ func TestDecimal(t *testing.T) {
 			t.Error("-(10) should equal -10")
 		}
 	})
+
+	t.Run("floor", func(t *testing.T) {
+		if got := MustDecimalFromString("2.7").Floor(); got.String() != "2" {
+			t.Errorf("floor(2.7) should be 2, got %s", got.String())
+		}
+		if got := MustDecimalFromString("-2.3").Floor(); got.String() != "-3" {
+			t.Errorf("floor(-2.3) should be -3, got %s", got.String())
+		}
+	})
+
+	t.Run("statistics", func(t *testing.T) {
+		if got := NewDecimal(16).Sqrt(); !got.Equal(NewDecimal(4)) {
+			t.Errorf("sqrt(16) should be 4, got %s", got)
+		}
+		if got := NewDecimal(-4).Sqrt(); !got.IsZero() {
+			t.Errorf("sqrt(-4) should be 0, got %s", got)
+		}
+		mean, variance, err := MeanVariance([]Decimal{NewDecimal(2), NewDecimal(4), NewDecimal(6)})
+		if err != nil {
+			t.Fatalf("MeanVariance() error: %v", err)
+		}
+		if !mean.Equal(NewDecimal(4)) || variance.String() != "2.6666666666666667" {
+			t.Errorf("MeanVariance() = %s, %s; want 4, 2.6666666666666667", mean, variance)
+		}
+		if _, _, err := MeanVariance(nil); !errors.Is(err, ErrDivisionByZero) {
+			t.Errorf("MeanVariance(nil) error = %v, want ErrDivisionByZero", err)
+		}
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		for _, s := range []string{"0", "1999.50", "-0.000000000000000001", "10125.283414719971319367",
+			"123456789012345678901234567890.5"} {
+			d := MustDecimalFromString(s)
+			v, ok := d.Fixed(18)
+			if !ok {
+				t.Fatalf("%s should be exact at 18 places", s)
+			}
+			if got := NewDecimalFromFixed(v, 18); !got.Equal(d) || got.String() != d.String() {
+				t.Errorf("fixed roundtrip of %s = %s", s, got)
+			}
+		}
+		if _, ok := MustDecimalFromString("0.0000000000000000001").Fixed(18); ok {
+			t.Error("19 fractional digits should not be exact at 18 places")
+		}
+	})
 }
 
 // TestPrice tests Price type operations
@@ -339,6 +388,466 @@ func TestAmount(t *testing.T) {
 
 // TestTypeSafety validates that invalid type operations cause compile errors
 // Note: These are commented out to show what SHOULD NOT compile
+func TestQuantity(t *testing.T) {
+	t.Run("signed arithmetic", func(t *testing.T) {
+		long := NewQuantity(NewDecimal(3))
+		short := NewQuantity(NewDecimal(-5))
+
+		net := long.Add(short)
+		if net.String() != "-2" || !net.IsShort() || net.Sign() != -1 {
+			t.Errorf("expected -2 short, got %s", net.String())
+		}
+		if diff := long.Sub(short); diff.String() != "8" || !diff.IsLong() {
+			t.Errorf("expected 8, got %s", diff.String())
+		}
+		if neg := short.Neg(); neg.String() != "5" {
+			t.Errorf("expected 5, got %s", neg.String())
+		}
+		if notional := short.MulPrice(MustPrice(NewDecimal(2000))); notional.String() != "-10000" {
+			t.Errorf("expected -10000, got %s", notional.String())
+		}
+		if half, _ := short.Div(NewDecimal(2)); half.String() != "-2.5" {
+			t.Errorf("expected -2.5, got %s", half.String())
+		}
+		if _, err := short.Div(Zero()); err != ErrDivisionByZero {
+			t.Errorf("expected ErrDivisionByZero, got %v", err)
+		}
+		if !short.LessThan(long) || !long.GreaterThan(short) || !ZeroQuantity().IsZero() || ZeroQuantity().Sign() != 0 {
+			t.Error("comparison mismatch")
+		}
+	})
+
+	t.Run("conversions", func(t *testing.T) {
+		short := NewQuantity(NewDecimal(-5))
+		if _, err := short.Amount(); err != ErrNegativeAmount {
+			t.Errorf("expected ErrNegativeAmount, got %v", err)
+		}
+		if abs := short.Abs(); !abs.Equal(MustAmount(NewDecimal(5))) {
+			t.Errorf("expected |q| = 5, got %s", abs.String())
+		}
+
+		amount := MustAmount(NewDecimal(7))
+		back, err := amount.Quantity().Amount()
+		if err != nil || !back.Equal(amount) {
+			t.Errorf("round trip failed: %s, %v", back.String(), err)
+		}
+		if !amount.Quantity().Equal(NewQuantity(NewDecimal(7))) {
+			t.Error("Amount.Quantity should preserve value")
+		}
+	})
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		value string
+		mode  RoundingMode
+		want  string
+	}{
+		{"2.345", RoundHalfUp, "2.35"},
+		{"-2.345", RoundHalfUp, "-2.35"},
+		{"2.345", RoundHalfEven, "2.34"},
+		{"2.355", RoundHalfEven, "2.36"},
+		{"2.349", RoundDown, "2.34"},
+		{"-2.349", RoundDown, "-2.34"},
+		{"2.341", RoundUp, "2.35"},
+		{"-2.341", RoundUp, "-2.35"},
+		{"-2.341", RoundFloor, "-2.35"},
+		{"-2.349", RoundCeil, "-2.34"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+"/"+tt.value, func(t *testing.T) {
+			got := MustDecimalFromString(tt.value).Round(2, tt.mode)
+			if got.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got.String())
+			}
+		})
+	}
+
+	t.Run("quantize to tick", func(t *testing.T) {
+		got, err := MustDecimalFromString("101.37").Quantize(MustDecimalFromString("0.25"), RoundDown)
+		if err != nil || got.String() != "101.25" {
+			t.Errorf("expected 101.25, got %s (%v)", got.String(), err)
+		}
+		if _, err := One().Quantize(Zero(), RoundDown); err == nil {
+			t.Error("expected error for zero step")
+		}
+	})
+
+	t.Run("price and amount", func(t *testing.T) {
+		if p := MustPrice(MustDecimalFromString("1999.999")).Round(2, RoundHalfUp); p.String() != "2000" {
+			t.Errorf("expected 2000, got %s", p.String())
+		}
+		if a := MustAmount(MustDecimalFromString("0.123456789")).Round(6, RoundDown); a.String() != "0.123456" {
+			t.Errorf("expected 0.123456, got %s", a.String())
+		}
+	})
+}
+
+func TestCurrencySpec(t *testing.T) {
+	usdc, err := NewCurrencySpec("USDC", 6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewCurrencySpec("BAD", -1); !errors.Is(err, ErrInvalidCurrencySpec) {
+		t.Errorf("expected ErrInvalidCurrencySpec, got %v", err)
+	}
+	if usdc.Unit().String() != "0.000001" || usdc.String() != "USDC(6)" {
+		t.Errorf("unexpected unit %s / string %s", usdc.Unit(), usdc)
+	}
+
+	t.Run("to raw", func(t *testing.T) {
+		raw, err := usdc.ToRaw(MustAmount(MustDecimalFromString("1.50")))
+		if err != nil || raw.String() != "1500000" {
+			t.Errorf("expected 1500000, got %v (%v)", raw, err)
+		}
+		dust := MustAmount(MustDecimalFromString("1.0000005"))
+		if _, err := usdc.ToRaw(dust); !errors.Is(err, ErrPrecisionLoss) {
+			t.Errorf("expected ErrPrecisionLoss, got %v", err)
+		}
+		if raw := usdc.ToRawRounded(dust, RoundDown); raw.String() != "1000000" {
+			t.Errorf("expected 1000000, got %s", raw)
+		}
+		if raw := usdc.ToRawRounded(dust, RoundUp); raw.String() != "1000001" {
+			t.Errorf("expected 1000001, got %s", raw)
+		}
+	})
+
+	t.Run("from raw", func(t *testing.T) {
+		weth, _ := NewCurrencySpec("WETH", 18)
+		raw, _ := new(big.Int).SetString("1500000000000000000", 10)
+		amount, err := weth.FromRaw(raw)
+		if err != nil || amount.String() != "1.5" {
+			t.Errorf("expected 1.5, got %s (%v)", amount, err)
+		}
+		if _, err := weth.FromRaw(big.NewInt(-1)); err != ErrNegativeAmount {
+			t.Errorf("expected ErrNegativeAmount, got %v", err)
+		}
+		if got := usdc.FromRawAmount(MustAmount(NewDecimal(2500000))); got.String() != "2.5" {
+			t.Errorf("expected 2.5, got %s", got)
+		}
+		if got := usdc.Quantize(MustAmount(MustDecimalFromString("0.0000019")), RoundHalfUp); got.String() != "0.000002" {
+			t.Errorf("expected 0.000002, got %s", got)
+		}
+	})
+}
+
+func TestPercentAndBasisPoints(t *testing.T) {
+	fee := NewPercent(MustDecimalFromString("0.3"))
+	if fee.Fraction().String() != "0.003" {
+		t.Errorf("expected fraction 0.003, got %s", fee.Fraction())
+	}
+	if fee.BasisPoints().String() != "30bps" || fee.String() != "0.3%" {
+		t.Errorf("unexpected conversions %s / %s", fee.BasisPoints(), fee)
+	}
+
+	bps := NewBasisPoints(NewDecimal(5))
+	if bps.Fraction().String() != "0.0005" || bps.Percent().String() != "0.05%" {
+		t.Errorf("unexpected conversions %s / %s", bps.Fraction(), bps.Percent())
+	}
+	if got := PercentFromFraction(MustDecimalFromString("0.15")); got.String() != "15%" {
+		t.Errorf("expected 15%%, got %s", got)
+	}
+	if got := BasisPointsFromFraction(MustDecimalFromString("0.0001")); !got.Decimal().Equal(One()) {
+		t.Errorf("expected 1bps, got %s", got)
+	}
+}
+
+func TestRate(t *testing.T) {
+	if _, err := NewRate(One(), Duration{}, CompoundingSimple); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("expected ErrInvalidRate, got %v", err)
+	}
+
+	t.Run("simple funding", func(t *testing.T) {
+		funding, err := NewRate(MustDecimalFromString("0.0001"), Hours(8), CompoundingSimple)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := funding.Over(Hours(24)); got.String() != "0.0003" {
+			t.Errorf("expected 0.0003 per day, got %s", got)
+		}
+		hourly, _ := funding.Per(Hours(1))
+		if hourly.Fraction().Round(10, RoundHalfUp).String() != "0.0000125" {
+			t.Errorf("expected 0.0000125 per hour, got %s", hourly.Fraction())
+		}
+		// 0.0001 * 3 * 365.25
+		if got := funding.Annualized().Fraction(); got.String() != "0.109575" {
+			t.Errorf("expected 0.109575 annualized, got %s", got)
+		}
+	})
+
+	t.Run("discrete compounding", func(t *testing.T) {
+		monthly, _ := NewRate(MustDecimalFromString("0.01"), Days(30), CompoundingDiscrete)
+		twoMonths := monthly.Over(Days(60)).Float64()
+		if math.Abs(twoMonths-0.0201) > 1e-9 {
+			t.Errorf("expected 0.0201, got %f", twoMonths)
+		}
+		apy := NewAnnualRate(MustDecimalFromString("0.1"), CompoundingDiscrete)
+		back, _ := apy.Per(Days(1))
+		if math.Abs(back.Annualized().Fraction().Float64()-0.1) > 1e-9 {
+			t.Errorf("round trip mismatch: %s", back.Annualized().Fraction())
+		}
+	})
+
+	t.Run("continuous compounding", func(t *testing.T) {
+		r := NewAnnualRate(MustDecimalFromString("0.05"), CompoundingContinuous)
+		if got := r.Over(Year).Float64(); math.Abs(got-(math.Exp(0.05)-1)) > 1e-9 {
+			t.Errorf("expected e^0.05-1, got %f", got)
+		}
+		daily, _ := r.Per(Days(1))
+		if math.Abs(daily.Fraction().Float64()-0.05/365.25) > 1e-12 {
+			t.Errorf("expected linear log-rate scaling, got %s", daily.Fraction())
+		}
+	})
+}
+
+func TestPair(t *testing.T) {
+	tests := []struct {
+		symbol  string
+		want    string
+		wantErr bool
+	}{
+		{"ETH/USD", "ETH/USD", false},
+		{" eth-usdc ", "ETH/USDC", false},
+		{"btc_usdt", "BTC/USDT", false},
+		{"SOL:EUR", "SOL/EUR", false},
+		{"ETHUSDC", "ETH/USDC", false},
+		{"WBTCUSD", "WBTC/USD", false},
+		{"ETHBTC", "ETH/BTC", false},
+		{"USDC", "", true},
+		{"ETH/", "", true},
+		{"ETH/ETH", "", true},
+		{"A/B/C", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			p, err := ParsePair(tt.symbol)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPair) {
+					t.Errorf("expected ErrInvalidPair, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.String() != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, p)
+			}
+		})
+	}
+
+	t.Run("inverse", func(t *testing.T) {
+		p := MustPair("ETH/USD")
+		if p.Inverse().String() != "USD/ETH" || p.Inverse().Inverse() != p {
+			t.Errorf("unexpected inverse %s", p.Inverse())
+		}
+		inv, err := p.InvertPrice(MustPrice(NewDecimal(2000)))
+		if err != nil || inv.String() != "0.0005" {
+			t.Errorf("expected 0.0005, got %s (%v)", inv, err)
+		}
+		if _, err := p.InvertPrice(ZeroPrice()); err != ErrDivisionByZero {
+			t.Errorf("expected ErrDivisionByZero, got %v", err)
+		}
+		if !p.Contains("eth") || p.Contains("BTC") || p.IsZero() {
+			t.Error("unexpected Contains/IsZero result")
+		}
+	})
+}
+
+// TestEncoding tests JSON, text and SQL round-trips
+func TestAssetAliases(t *testing.T) {
+	aliases := CommonAssetAliases
+	if got := aliases.Asset("weth"); got != "ETH" {
+		t.Errorf("Asset(weth) = %s, want ETH", got)
+	}
+	if got := aliases.Asset("USDC"); got != "USDC" {
+		t.Errorf("Asset(USDC) = %s, want USDC", got)
+	}
+	if got := aliases.Pair(MustPair("WBTC/WETH")); got != MustPair("BTC/ETH") {
+		t.Errorf("Pair(WBTC/WETH) = %s, want BTC/ETH", got)
+	}
+	var none AssetAliases
+	if got := none.Pair(MustPair("WETH/USDC")); got != MustPair("WETH/USDC") {
+		t.Errorf("nil aliases should leave pairs unchanged, got %s", got)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	type record struct {
+		Decimal  Decimal
+		Price    Price
+		Amount   Amount
+		Quantity Quantity
+		Time     Time
+		Duration Duration
+		Pair     Pair
+	}
+	in := record{
+		Decimal:  MustDecimalFromString("0.1000000000000000000000000001"),
+		Price:    MustPrice(MustDecimalFromString("1999.50")),
+		Amount:   MustAmount(MustDecimalFromString("123456789012345678.9")),
+		Quantity: NewQuantity(MustDecimalFromString("-2.5")),
+		Time:     NewTime(time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)),
+		Duration: Hours(1).Add(Minutes(30)),
+		Pair:     MustPair("eth-usdc"),
+	}
+
+	raw, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"Decimal":"0.1000000000000000000000000001","Price":"1999.5","Amount":"123456789012345678.9",` +
+		`"Quantity":"-2.5","Time":"2024-01-02T03:04:05.000000006Z","Duration":"1h30m0s","Pair":"ETH/USDC"}`
+	if string(raw) != want {
+		t.Errorf("json = %s, want %s", raw, want)
+	}
+
+	var out record
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !out.Decimal.Equal(in.Decimal) || !out.Price.Equal(in.Price) || !out.Amount.Equal(in.Amount) ||
+		!out.Quantity.Equal(in.Quantity) || !out.Time.Equal(in.Time) || out.Duration != in.Duration || out.Pair != in.Pair {
+		t.Errorf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+
+	t.Run("json numbers", func(t *testing.T) {
+		var r record
+		if err := json.Unmarshal([]byte(`{"Price":1999.123456789123456789,"Duration":1000000000}`), &r); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if r.Price.String() != "1999.123456789123456789" || r.Duration != Seconds(1) {
+			t.Errorf("got price %s duration %s", r.Price, r.Duration)
+		}
+	})
+
+	t.Run("json errors", func(t *testing.T) {
+		var r record
+		if err := json.Unmarshal([]byte(`{"Price":"-1"}`), &r); !errors.Is(err, ErrNegativePrice) {
+			t.Errorf("expected ErrNegativePrice, got %v", err)
+		}
+		if err := json.Unmarshal([]byte(`{"Amount":"-1"}`), &r); !errors.Is(err, ErrNegativeAmount) {
+			t.Errorf("expected ErrNegativeAmount, got %v", err)
+		}
+		if err := json.Unmarshal([]byte(`{"Decimal":"abc"}`), &r); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("expected ErrInvalidDecimal, got %v", err)
+		}
+		if err := json.Unmarshal([]byte(`{"Duration":"soon"}`), &r); !errors.Is(err, ErrInvalidDuration) {
+			t.Errorf("expected ErrInvalidDuration, got %v", err)
+		}
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		var d Decimal
+		for _, src := range []interface{}{"1.25", []byte("1.25"), 1.25} {
+			if err := d.Scan(src); err != nil || d.String() != "1.25" {
+				t.Errorf("Scan(%v) = %s, %v", src, d, err)
+			}
+		}
+		if v, _ := in.Amount.Value(); v != "123456789012345678.9" {
+			t.Errorf("Amount.Value() = %v", v)
+		}
+		var p Price
+		if err := p.Scan(int64(-3)); !errors.Is(err, ErrNegativePrice) {
+			t.Errorf("expected ErrNegativePrice, got %v", err)
+		}
+
+		v, _ := in.Time.Value()
+		var tm Time
+		if err := tm.Scan(v); err != nil || !tm.Equal(in.Time) {
+			t.Errorf("Time round trip = %s, %v", tm, err)
+		}
+		v, _ = in.Duration.Value()
+		var dur Duration
+		if err := dur.Scan(v); err != nil || dur != in.Duration {
+			t.Errorf("Duration round trip = %s, %v", dur, err)
+		}
+		if err := dur.Scan(nil); err != nil || dur.Duration() != 0 {
+			t.Errorf("Scan(nil) = %s, %v", dur, err)
+		}
+	})
+
+	t.Run("zero pair", func(t *testing.T) {
+		var r struct{ Pair Pair }
+		raw, err := json.Marshal(r)
+		if err != nil || string(raw) != `{"Pair":""}` {
+			t.Fatalf("marshal = %s, %v", raw, err)
+		}
+		r.Pair = MustPair("ETH/USDC")
+		if err := json.Unmarshal(raw, &r); err != nil || !r.Pair.IsZero() {
+			t.Errorf("round trip = %+v, %v", r.Pair, err)
+		}
+	})
+
+	t.Run("rates", func(t *testing.T) {
+		type rates struct {
+			Fee     Percent
+			Spread  BasisPoints
+			Funding Rate
+			APY     Rate
+		}
+		funding, err := NewRate(MustDecimalFromString("0.0001"), Hours(8), CompoundingSimple)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := rates{
+			Fee:     NewPercent(MustDecimalFromString("0.3")),
+			Spread:  NewBasisPoints(NewDecimal(30)),
+			Funding: funding,
+			APY:     NewAnnualRate(MustDecimalFromString("0.05"), CompoundingContinuous),
+		}
+		raw, err := json.Marshal(in)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		want := `{"Fee":"0.3%","Spread":"30bps",` +
+			`"Funding":{"fraction":"0.0001","period":"8h0m0s","compounding":"simple"},` +
+			`"APY":{"fraction":"0.05","period":"8766h0m0s","compounding":"continuous"}}`
+		if string(raw) != want {
+			t.Errorf("json = %s, want %s", raw, want)
+		}
+		var out rates
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if !out.Fee.Decimal().Equal(in.Fee.Decimal()) || !out.Spread.Decimal().Equal(in.Spread.Decimal()) ||
+			out.Funding.String() != in.Funding.String() || out.APY.String() != in.APY.String() {
+			t.Errorf("round trip mismatch: got %+v, want %+v", out, in)
+		}
+
+		if err := json.Unmarshal([]byte(`{"Fee":0.3,"Spread":"30"}`), &out); err != nil ||
+			out.Fee.String() != "0.3%" || out.Spread.String() != "30bps" {
+			t.Errorf("bare numbers = %s %s, %v", out.Fee, out.Spread, err)
+		}
+
+		text, _ := in.Funding.MarshalText()
+		if string(text) != "0.0001/8h0m0s/simple" {
+			t.Errorf("rate text = %s", text)
+		}
+		v, _ := in.APY.Value()
+		var rate Rate
+		if err := rate.Scan(v); err != nil || rate.String() != in.APY.String() || rate.Compounding() != CompoundingContinuous {
+			t.Errorf("Rate SQL round trip = %s, %v", rate, err)
+		}
+		v, _ = in.Fee.Value()
+		var fee Percent
+		if err := fee.Scan(v); err != nil || !fee.Decimal().Equal(in.Fee.Decimal()) {
+			t.Errorf("Percent SQL round trip = %s, %v", fee, err)
+		}
+		v, _ = in.Spread.Value()
+		var spread BasisPoints
+		if err := spread.Scan(v); err != nil || !spread.Decimal().Equal(in.Spread.Decimal()) {
+			t.Errorf("BasisPoints SQL round trip = %s, %v", spread, err)
+		}
+
+		for _, bad := range []string{"0.01/8h", "0.01/0s/simple", "0.01/8h/monthly"} {
+			if err := rate.UnmarshalText([]byte(bad)); !errors.Is(err, ErrInvalidRate) {
+				t.Errorf("UnmarshalText(%q) = %v, want ErrInvalidRate", bad, err)
+			}
+		}
+	})
+}
+
 func TestTypeSafety(t *testing.T) {
 	// The following would cause compile errors (intentionally):
 	// price := MustPrice(NewDecimal(100))
@@ -557,3 +1066,171 @@ func TestMustFunctions(t *testing.T) {
 		}
 	})
 }
+
+func TestCalendar(t *testing.T) {
+	at := func(layout string) Time {
+		parsed, err := time.Parse("2006-01-02 15:04", layout)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", layout, err)
+		}
+		return NewTime(parsed)
+	}
+	check := func(name string, got, want Time) {
+		t.Helper()
+		if !got.Equal(want) {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+
+	t.Run("funding", func(t *testing.T) {
+		next, err := NextFundingTime(at("2024-03-01 07:59"), FundingInterval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check("next funding", next, at("2024-03-01 08:00"))
+		next, _ = NextFundingTime(at("2024-03-01 16:00"), FundingInterval)
+		check("next funding on a boundary", next, at("2024-03-02 00:00"))
+
+		// A time in another zone is aligned to UTC boundaries
+		tokyo := NewTime(time.Date(2024, 3, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*3600)))
+		next, _ = NextFundingTime(tokyo, FundingInterval)
+		check("next funding from JST", next, at("2024-03-01 08:00"))
+
+		times, err := FundingTimes(at("2024-03-01 00:00"), at("2024-03-02 00:00"), FundingInterval)
+		if err != nil || len(times) != 3 || !times[2].Equal(at("2024-03-02 00:00")) {
+			t.Errorf("FundingTimes = %v, %v; want 08:00, 16:00 and midnight", times, err)
+		}
+		if _, err := NextFundingTime(at("2024-03-01 00:00"), Hours(7)); !errors.Is(err, ErrInvalidDuration) {
+			t.Errorf("7h interval: got %v, want ErrInvalidDuration", err)
+		}
+	})
+
+	t.Run("expiries", func(t *testing.T) {
+		// 2024-03-29 is the last Friday of March, and of Q1
+		check("next daily", DeribitExpiry.NextDaily(at("2024-03-27 08:00")), at("2024-03-28 08:00"))
+		check("next weekly", DeribitExpiry.NextWeekly(at("2024-03-20 12:00")), at("2024-03-22 08:00"))
+		check("next weekly on expiry", DeribitExpiry.NextWeekly(at("2024-03-22 08:00")), at("2024-03-29 08:00"))
+		check("monthly", DeribitExpiry.Monthly(2024, time.March), at("2024-03-29 08:00"))
+		check("monthly ending on a Friday", DeribitExpiry.Monthly(2024, time.May), at("2024-05-31 08:00"))
+		check("next monthly after expiry", DeribitExpiry.NextMonthly(at("2024-03-29 09:00")), at("2024-04-26 08:00"))
+		check("next quarterly", DeribitExpiry.NextQuarterly(at("2024-01-10 00:00")), at("2024-03-29 08:00"))
+		check("next quarterly after expiry", DeribitExpiry.NextQuarterly(at("2024-12-27 08:00")), at("2025-03-28 08:00"))
+	})
+
+	t.Run("period ends", func(t *testing.T) {
+		check("month end", MonthEnd(at("2024-02-10 15:00")), at("2024-02-29 00:00"))
+		check("quarter end", QuarterEnd(at("2024-05-10 15:00")), at("2024-06-30 00:00"))
+		check("next month start", NextMonthStart(at("2024-12-10 15:00")), at("2025-01-01 00:00"))
+		check("next quarter start", NextQuarterStart(at("2024-11-10 15:00")), at("2025-01-01 00:00"))
+	})
+
+	t.Run("business days", func(t *testing.T) {
+		// 2024-12-25 (Wednesday) is a holiday
+		calendar := NewBusinessCalendar(at("2024-12-25 00:00"))
+		if calendar.IsBusinessDay(at("2024-12-25 10:00")) || calendar.IsBusinessDay(at("2024-12-28 10:00")) {
+			t.Error("holiday or weekend counted as a business day")
+		}
+		if !calendar.IsBusinessDay(at("2024-12-24 10:00")) {
+			t.Error("Tuesday not counted as a business day")
+		}
+		check("add 2 over a holiday", calendar.AddBusinessDays(at("2024-12-24 10:00"), 2), at("2024-12-27 10:00"))
+		check("add 1 over a weekend", calendar.AddBusinessDays(at("2024-12-27 10:00"), 1), at("2024-12-30 10:00"))
+		check("subtract 1 over a holiday", calendar.AddBusinessDays(at("2024-12-26 10:00"), -1), at("2024-12-24 10:00"))
+		check("roll a weekend forward", calendar.AddBusinessDays(at("2024-12-28 10:00"), 0), at("2024-12-30 10:00"))
+
+		if got := calendar.BusinessDaysBetween(at("2024-12-23 18:00"), at("2024-12-30 09:00")); got != 4 {
+			t.Errorf("business days between = %d, want 4", got)
+		}
+		if got := calendar.BusinessDaysBetween(at("2024-12-30 09:00"), at("2024-12-23 18:00")); got != -4 {
+			t.Errorf("reversed business days between = %d, want -4", got)
+		}
+		var none BusinessCalendar
+		if !none.IsBusinessDay(at("2024-12-25 10:00")) {
+			t.Error("zero calendar has holidays")
+		}
+	})
+}
+
+func TestDecimalMath(t *testing.T) {
+	t.Run("reference values", func(t *testing.T) {
+		exp, err := NewDecimal(-3).Exp()
+		if err != nil || exp.String() != "0.049787068367863943" {
+			t.Errorf("exp(-3) = %s (%v), want 0.049787068367863943", exp, err)
+		}
+		ln, err := MustDecimalFromString("123456.789").Ln()
+		if err != nil || ln.String() != "11.723646487185880981" {
+			t.Errorf("ln(123456.789) = %s (%v), want 11.723646487185880981", ln, err)
+		}
+		if got := NewDecimal(2).Sqrt(); got.String() != "1.414213562373095049" {
+			t.Errorf("sqrt(2) = %s, want 1.414213562373095049", got)
+		}
+
+		// Fractional and large integer exponents go through exp and ln
+		pow, err := MustDecimalFromString("1.37").Pow(MustDecimalFromString("43.8292"))
+		if err != nil || pow.String() != "982546.429990534397167622" {
+			t.Errorf("1.37^43.8292 = %s (%v), want 982546.429990534397167622", pow, err)
+		}
+		pow, err = MustDecimalFromString("1.05").Pow(NewDecimal(365))
+		if err != nil || pow.String() != "54211841.577839524993033544" {
+			t.Errorf("1.05^365 = %s (%v), want 54211841.577839524993033544", pow, err)
+		}
+		pow, err = NewDecimal(-2).Pow(NewDecimal(3))
+		if err != nil || !pow.Equal(NewDecimal(-8)) {
+			t.Errorf("(-2)^3 = %s (%v), want -8", pow, err)
+		}
+	})
+
+	t.Run("precision", func(t *testing.T) {
+		defer func(places int32) { MathPrecision = places }(MathPrecision)
+		MathPrecision = 40
+		if got := NewDecimal(2).Sqrt(); got.String() != "1.4142135623730950488016887242096980785697" {
+			t.Errorf("sqrt(2) to 40 places = %s", got)
+		}
+		ln, err := MustDecimalFromString("0.5").Ln()
+		if err != nil || ln.String() != "-0.6931471805599453094172321214581765680755" {
+			t.Errorf("ln(0.5) to 40 places = %s (%v)", ln, err)
+		}
+	})
+
+	t.Run("domain errors", func(t *testing.T) {
+		if _, err := Zero().Ln(); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("ln(0) error = %v, want ErrInvalidDecimal", err)
+		}
+		if _, err := NewDecimal(1001).Exp(); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("exp(1001) error = %v, want ErrInvalidDecimal", err)
+		}
+		if got, err := NewDecimal(-1001).Exp(); err != nil || !got.IsZero() {
+			t.Errorf("exp(-1001) = %s (%v), want 0", got, err)
+		}
+		if _, err := NewDecimal(-2).Pow(MustDecimalFromString("0.5")); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("(-2)^0.5 error = %v, want ErrInvalidDecimal", err)
+		}
+		if _, err := Zero().Pow(NewDecimal(-1)); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("0^-1 error = %v, want ErrInvalidDecimal", err)
+		}
+		if _, err := NewDecimal(10).Pow(NewDecimal(1000)); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("10^1000 error = %v, want ErrInvalidDecimal", err)
+		}
+	})
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(NewTime(start))
+	if !clock.Now().Time().Equal(start) {
+		t.Fatalf("Now = %s, want %s", clock.Now(), start)
+	}
+	clock.Advance(Hours(8))
+	if got := clock.Now().Time(); !got.Equal(start.Add(8 * time.Hour)) {
+		t.Errorf("after Advance, Now = %s", got)
+	}
+	clock.Set(NewTime(start))
+	if got := clock.Now().Time(); !got.Equal(start) {
+		t.Errorf("after Set, Now = %s", got)
+	}
+
+	var _ Clock = SystemClock{}
+	if before, now := time.Now(), (SystemClock{}).Now().Time(); now.Before(before) {
+		t.Errorf("SystemClock read %s before %s", now, before)
+	}
+}
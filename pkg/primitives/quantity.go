@@ -0,0 +1,125 @@
+package primitives
+
+// Quantity is a signed amount of an asset: positive for long exposure or
+// credits, negative for short exposure or debits.
+//
+// Amount remains the type for values that cannot be negative (balances,
+// order sizes, reserves). Use Quantity where a sign is meaningful and
+// convert explicitly at the boundary with Amount.Quantity and
+// Quantity.Amount / Quantity.Abs.
+type Quantity struct {
+	value Decimal
+}
+
+// NewQuantity creates a Quantity from a signed Decimal value.
+func NewQuantity(value Decimal) Quantity {
+	return Quantity{value: value}
+}
+
+// ZeroQuantity returns a Quantity representing zero.
+func ZeroQuantity() Quantity {
+	return Quantity{value: Zero()}
+}
+
+// Quantity converts a non-negative Amount to a Quantity.
+func (a Amount) Quantity() Quantity {
+	return Quantity{value: a.value}
+}
+
+// Decimal returns the underlying signed Decimal value.
+func (q Quantity) Decimal() Decimal {
+	return q.value
+}
+
+// Amount converts the Quantity to an Amount.
+// Returns ErrNegativeAmount if the Quantity is negative.
+func (q Quantity) Amount() (Amount, error) {
+	return NewAmount(q.value)
+}
+
+// Abs returns the magnitude of the Quantity as an Amount.
+func (q Quantity) Abs() Amount {
+	return Amount{value: q.value.Abs()}
+}
+
+// Add returns the sum of two Quantities.
+func (q Quantity) Add(other Quantity) Quantity {
+	return Quantity{value: q.value.Add(other.value)}
+}
+
+// Sub returns the difference of two Quantities. The result may be negative.
+func (q Quantity) Sub(other Quantity) Quantity {
+	return Quantity{value: q.value.Sub(other.value)}
+}
+
+// Neg returns the Quantity with its sign flipped.
+func (q Quantity) Neg() Quantity {
+	return Quantity{value: q.value.Neg()}
+}
+
+// Mul returns the product of a Quantity and a Decimal.
+func (q Quantity) Mul(factor Decimal) Quantity {
+	return Quantity{value: q.value.Mul(factor)}
+}
+
+// Div returns the quotient of a Quantity and a Decimal.
+// Returns error if dividing by zero.
+func (q Quantity) Div(divisor Decimal) (Quantity, error) {
+	result, err := q.value.Div(divisor)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{value: result}, nil
+}
+
+// MulPrice returns the signed notional value of the Quantity at price.
+func (q Quantity) MulPrice(price Price) Quantity {
+	return Quantity{value: q.value.Mul(price.value)}
+}
+
+// Sign returns -1, 0 or 1 according to the sign of the Quantity.
+func (q Quantity) Sign() int {
+	switch {
+	case q.value.IsNegative():
+		return -1
+	case q.value.IsPositive():
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsLong returns true if the Quantity is positive.
+func (q Quantity) IsLong() bool {
+	return q.value.IsPositive()
+}
+
+// IsShort returns true if the Quantity is negative.
+func (q Quantity) IsShort() bool {
+	return q.value.IsNegative()
+}
+
+// IsZero returns true if the Quantity is zero.
+func (q Quantity) IsZero() bool {
+	return q.value.IsZero()
+}
+
+// GreaterThan returns true if q > other.
+func (q Quantity) GreaterThan(other Quantity) bool {
+	return q.value.GreaterThan(other.value)
+}
+
+// LessThan returns true if q < other.
+func (q Quantity) LessThan(other Quantity) bool {
+	return q.value.LessThan(other.value)
+}
+
+// Equal returns true if q == other.
+func (q Quantity) Equal(other Quantity) bool {
+	return q.value.Equal(other.value)
+}
+
+// String returns the string representation of the Quantity.
+func (q Quantity) String() string {
+	return q.value.String()
+}
@@ -0,0 +1,220 @@
+package primitives
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidRate indicates an invalid rate definition (e.g., non-positive period)
+var ErrInvalidRate = errors.New("invalid rate")
+
+// hundred and tenThousand convert between fractions, percent and basis points.
+var (
+	hundred     = NewDecimal(100)
+	tenThousand = NewDecimal(10000)
+)
+
+// Percent is a ratio expressed in percent: NewPercent(0.3) is 0.3%,
+// i.e., a fraction of 0.003. Using Percent instead of a raw Decimal makes
+// the unit explicit and prevents 0.003-vs-0.3 mistakes.
+type Percent struct {
+	value Decimal
+}
+
+// NewPercent creates a Percent from a value in percent (0.3 for 0.3%).
+func NewPercent(value Decimal) Percent {
+	return Percent{value: value}
+}
+
+// PercentFromFraction creates a Percent from a fraction (0.003 for 0.3%).
+func PercentFromFraction(fraction Decimal) Percent {
+	return Percent{value: fraction.Mul(hundred)}
+}
+
+// Decimal returns the value in percent.
+func (p Percent) Decimal() Decimal {
+	return p.value
+}
+
+// Fraction returns the value as a fraction (0.3% -> 0.003).
+func (p Percent) Fraction() Decimal {
+	// Safe to ignore error: divisor is non-zero
+	f, _ := p.value.Div(hundred)
+	return f
+}
+
+// BasisPoints converts to basis points (0.3% -> 30 bps).
+func (p Percent) BasisPoints() BasisPoints {
+	return BasisPoints{value: p.value.Mul(hundred)}
+}
+
+// String returns the value with a percent sign, e.g. "0.3%".
+func (p Percent) String() string {
+	return p.value.String() + "%"
+}
+
+// BasisPoints is a ratio expressed in basis points: NewBasisPoints(30) is
+// 30 bps, i.e., 0.3% or a fraction of 0.003.
+type BasisPoints struct {
+	value Decimal
+}
+
+// NewBasisPoints creates a BasisPoints value (30 for 30 bps).
+func NewBasisPoints(value Decimal) BasisPoints {
+	return BasisPoints{value: value}
+}
+
+// BasisPointsFromFraction creates BasisPoints from a fraction (0.003 -> 30 bps).
+func BasisPointsFromFraction(fraction Decimal) BasisPoints {
+	return BasisPoints{value: fraction.Mul(tenThousand)}
+}
+
+// Decimal returns the value in basis points.
+func (b BasisPoints) Decimal() Decimal {
+	return b.value
+}
+
+// Fraction returns the value as a fraction (30 bps -> 0.003).
+func (b BasisPoints) Fraction() Decimal {
+	// Safe to ignore error: divisor is non-zero
+	f, _ := b.value.Div(tenThousand)
+	return f
+}
+
+// Percent converts to percent (30 bps -> 0.3%).
+func (b BasisPoints) Percent() Percent {
+	// Safe to ignore error: divisor is non-zero
+	p, _ := b.value.Div(hundred)
+	return Percent{value: p}
+}
+
+// String returns the value with a unit suffix, e.g. "30bps".
+func (b BasisPoints) String() string {
+	return b.value.String() + "bps"
+}
+
+// Compounding is the convention used to convert a rate between periods.
+type Compounding int
+
+const (
+	// CompoundingSimple scales linearly with time: r_T = r * T/period
+	// (funding rates, fee accrual)
+	CompoundingSimple Compounding = iota
+
+	// CompoundingDiscrete compounds once per period: 1+r_T = (1+r)^(T/period)
+	// (periodic returns, APY)
+	CompoundingDiscrete
+
+	// CompoundingContinuous treats the rate as a log rate: 1+r_T = e^(r * T/period)
+	CompoundingContinuous
+)
+
+// String returns the name of the compounding convention.
+func (c Compounding) String() string {
+	switch c {
+	case CompoundingSimple:
+		return "simple"
+	case CompoundingDiscrete:
+		return "discrete"
+	case CompoundingContinuous:
+		return "continuous"
+	default:
+		return fmt.Sprintf("Compounding(%d)", int(c))
+	}
+}
+
+// Year is the period used for annualized rates (365.25 days).
+var Year = NewDuration(8766 * time.Hour)
+
+// Rate is a fractional rate earned or paid per period under a compounding
+// convention, e.g. a funding rate of 0.0001 per 8 hours (simple) or an
+// APY of 0.05 per year (discrete).
+type Rate struct {
+	value       Decimal
+	period      Duration
+	compounding Compounding
+}
+
+// NewRate creates a Rate of fraction per period.
+// Returns error if period is not positive.
+func NewRate(fraction Decimal, period Duration, compounding Compounding) (Rate, error) {
+	if period.Duration() <= 0 {
+		return Rate{}, fmt.Errorf("%w: period must be positive, got %s", ErrInvalidRate, period)
+	}
+	return Rate{value: fraction, period: period, compounding: compounding}, nil
+}
+
+// NewAnnualRate creates a Rate of fraction per year.
+func NewAnnualRate(fraction Decimal, compounding Compounding) Rate {
+	return Rate{value: fraction, period: Year, compounding: compounding}
+}
+
+// Fraction returns the rate per period as a fraction.
+func (r Rate) Fraction() Decimal {
+	return r.value
+}
+
+// Period returns the period the rate applies to.
+func (r Rate) Period() Duration {
+	return r.period
+}
+
+// Compounding returns the rate's compounding convention.
+func (r Rate) Compounding() Compounding {
+	return r.compounding
+}
+
+// Over returns the fractional change accrued over elapsed under the rate's
+// compounding convention (e.g., 0.0001 per 8h over 24h simple -> 0.0003).
+func (r Rate) Over(elapsed Duration) Decimal {
+	periods := float64(elapsed.Duration()) / float64(r.period.Duration())
+	switch r.compounding {
+	case CompoundingDiscrete:
+		// Convert to float64 for fractional exponent (acceptable for rate conversion)
+		return NewDecimalFromFloat(math.Pow(1+r.value.Float64(), periods) - 1)
+	case CompoundingContinuous:
+		return NewDecimalFromFloat(math.Expm1(r.value.Float64() * periods))
+	default:
+		ratio, err := NewDecimal(int64(elapsed.Duration())).Div(NewDecimal(int64(r.period.Duration())))
+		if err != nil {
+			return Zero()
+		}
+		return r.value.Mul(ratio)
+	}
+}
+
+// Per returns the equivalent rate for a different period under the same
+// compounding convention. Continuous and simple rates scale linearly.
+func (r Rate) Per(period Duration) (Rate, error) {
+	if period.Duration() <= 0 {
+		return Rate{}, fmt.Errorf("%w: period must be positive, got %s", ErrInvalidRate, period)
+	}
+	value := r.Over(period)
+	if r.compounding == CompoundingContinuous {
+		ratio, err := NewDecimal(int64(period.Duration())).Div(NewDecimal(int64(r.period.Duration())))
+		if err != nil {
+			return Rate{}, err
+		}
+		value = r.value.Mul(ratio)
+	}
+	return Rate{value: value, period: period, compounding: r.compounding}, nil
+}
+
+// Annualized returns the equivalent rate per year.
+func (r Rate) Annualized() Rate {
+	// Safe to ignore error: Year is positive
+	annual, _ := r.Per(Year)
+	return annual
+}
+
+// Percent returns the rate per period in percent.
+func (r Rate) Percent() Percent {
+	return PercentFromFraction(r.value)
+}
+
+// String returns e.g. "0.01% per 8h0m0s (simple)".
+func (r Rate) String() string {
+	return fmt.Sprintf("%s per %s (%s)", r.Percent(), r.period, r.compounding)
+}
@@ -0,0 +1,177 @@
+package primitives
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrInvalidCurrencySpec indicates a CurrencySpec with invalid decimals
+	ErrInvalidCurrencySpec = errors.New("invalid currency spec")
+
+	// ErrPrecisionLoss indicates a value has more precision than its currency allows
+	ErrPrecisionLoss = errors.New("value exceeds currency precision")
+)
+
+// RoundingMode selects how values are rounded to a fixed number of places.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to nearest, ties away from zero (2.5 -> 3, -2.5 -> -3)
+	RoundHalfUp RoundingMode = iota
+
+	// RoundHalfEven rounds to nearest, ties to even (2.5 -> 2, 3.5 -> 4)
+	RoundHalfEven
+
+	// RoundDown rounds toward zero (truncation)
+	RoundDown
+
+	// RoundUp rounds away from zero
+	RoundUp
+
+	// RoundFloor rounds toward negative infinity
+	RoundFloor
+
+	// RoundCeil rounds toward positive infinity
+	RoundCeil
+)
+
+// String returns the name of the rounding mode.
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundHalfUp:
+		return "half_up"
+	case RoundHalfEven:
+		return "half_even"
+	case RoundDown:
+		return "down"
+	case RoundUp:
+		return "up"
+	case RoundFloor:
+		return "floor"
+	case RoundCeil:
+		return "ceil"
+	default:
+		return fmt.Sprintf("RoundingMode(%d)", int(m))
+	}
+}
+
+// Round returns d rounded to places decimal places using mode.
+// Negative places round to the left of the decimal point (e.g., -2 rounds to hundreds).
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	return Decimal{value: roundDecimal(d.value, places, mode)}
+}
+
+// Quantize returns d rounded to a multiple of step using mode
+// (e.g., a step of 0.05 for a tick size). Returns error if step is not positive.
+func (d Decimal) Quantize(step Decimal, mode RoundingMode) (Decimal, error) {
+	if !step.IsPositive() {
+		return Decimal{}, fmt.Errorf("%w: quantize step must be positive", ErrInvalidDecimal)
+	}
+	steps, err := d.Div(step)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return steps.Round(0, mode).Mul(step), nil
+}
+
+// Round returns p rounded to places decimal places using mode.
+func (p Price) Round(places int32, mode RoundingMode) Price {
+	return Price{value: p.value.Round(places, mode)}
+}
+
+// Round returns a rounded to places decimal places using mode.
+func (a Amount) Round(places int32, mode RoundingMode) Amount {
+	return Amount{value: a.value.Round(places, mode)}
+}
+
+func roundDecimal(v decimal.Decimal, places int32, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return v.RoundBank(places)
+	case RoundDown:
+		return v.RoundDown(places)
+	case RoundUp:
+		return v.RoundUp(places)
+	case RoundFloor:
+		return v.RoundFloor(places)
+	case RoundCeil:
+		return v.RoundCeil(places)
+	default:
+		return v.Round(places)
+	}
+}
+
+// CurrencySpec describes how an asset is denominated on-chain or at a venue:
+// its symbol and the number of decimals in one whole unit (18 for ETH,
+// 6 for USDC, 8 for BTC).
+//
+// Use it at the boundary between human units (1.5 ETH) and raw integer
+// units (1500000000000000000 wei) so amounts never carry dust below the
+// asset's smallest unit.
+type CurrencySpec struct {
+	Symbol   string
+	Decimals int32
+}
+
+// maxCurrencyDecimals bounds Decimals to keep raw conversions reasonable.
+const maxCurrencyDecimals = 77
+
+// NewCurrencySpec creates a CurrencySpec.
+// Returns error if decimals is negative or unreasonably large.
+func NewCurrencySpec(symbol string, decimals int32) (CurrencySpec, error) {
+	if decimals < 0 || decimals > maxCurrencyDecimals {
+		return CurrencySpec{}, fmt.Errorf("%w: %s decimals %d out of range [0, %d]",
+			ErrInvalidCurrencySpec, symbol, decimals, maxCurrencyDecimals)
+	}
+	return CurrencySpec{Symbol: symbol, Decimals: decimals}, nil
+}
+
+// Unit returns the smallest representable amount (10^-Decimals).
+func (c CurrencySpec) Unit() Decimal {
+	return Decimal{value: decimal.New(1, -c.Decimals)}
+}
+
+// Quantize rounds an amount to the currency's precision using mode.
+func (c CurrencySpec) Quantize(amount Amount, mode RoundingMode) Amount {
+	return amount.Round(c.Decimals, mode)
+}
+
+// ToRaw converts a human-unit amount to raw integer units.
+// Returns ErrPrecisionLoss if the amount is finer than the currency's
+// smallest unit; use ToRawRounded to round explicitly instead.
+func (c CurrencySpec) ToRaw(amount Amount) (*big.Int, error) {
+	v := amount.value.value
+	if !v.Equal(v.RoundDown(c.Decimals)) {
+		return nil, fmt.Errorf("%w: %s has %d decimals, got %s", ErrPrecisionLoss, c.Symbol, c.Decimals, amount)
+	}
+	return v.Shift(c.Decimals).BigInt(), nil
+}
+
+// ToRawRounded converts a human-unit amount to raw integer units, rounding
+// to the currency's precision using mode.
+func (c CurrencySpec) ToRawRounded(amount Amount, mode RoundingMode) *big.Int {
+	return roundDecimal(amount.value.value, c.Decimals, mode).Shift(c.Decimals).BigInt()
+}
+
+// FromRaw converts raw integer units to a human-unit Amount.
+// Returns ErrNegativeAmount if raw is negative.
+func (c CurrencySpec) FromRaw(raw *big.Int) (Amount, error) {
+	if raw == nil {
+		return ZeroAmount(), nil
+	}
+	return NewAmount(Decimal{value: decimal.NewFromBigInt(raw, -c.Decimals)})
+}
+
+// FromRawAmount converts an Amount holding raw units to human units.
+func (c CurrencySpec) FromRawAmount(raw Amount) Amount {
+	return Amount{value: Decimal{value: raw.value.value.Shift(-c.Decimals)}}
+}
+
+// String returns the symbol and decimals, e.g. "USDC(6)".
+func (c CurrencySpec) String() string {
+	return fmt.Sprintf("%s(%d)", c.Symbol, c.Decimals)
+}
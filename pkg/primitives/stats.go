@@ -0,0 +1,26 @@
+package primitives
+
+// MeanVariance returns the arithmetic mean and population variance of values.
+// Returns ErrDivisionByZero if values is empty.
+func MeanVariance(values []Decimal) (mean, variance Decimal, err error) {
+	n := NewDecimal(int64(len(values)))
+	sum := Zero()
+	for _, v := range values {
+		sum = sum.Add(v)
+	}
+	mean, err = sum.Div(n)
+	if err != nil {
+		return Zero(), Zero(), err
+	}
+
+	sumSquares := Zero()
+	for _, v := range values {
+		diff := v.Sub(mean)
+		sumSquares = sumSquares.Add(diff.Mul(diff))
+	}
+	variance, err = sumSquares.Div(n)
+	if err != nil {
+		return Zero(), Zero(), err
+	}
+	return mean, variance, nil
+}
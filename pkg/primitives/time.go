@@ -21,7 +21,8 @@ func NewTime(t time.Time) Time {
 	return Time{value: t}
 }
 
-// Now returns the current time.
+// Now returns the current wall-clock time. Code that must be
+// reproducible in a backtest should read a Clock instead.
 func Now() Time {
 	return Time{value: time.Now()}
 }
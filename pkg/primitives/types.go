@@ -6,6 +6,7 @@ package primitives
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/shopspring/decimal"
 )
@@ -102,6 +103,11 @@ func (d Decimal) Neg() Decimal {
 	return Decimal{value: d.value.Neg()}
 }
 
+// Floor returns the largest integer value less than or equal to d.
+func (d Decimal) Floor() Decimal {
+	return Decimal{value: d.value.Floor()}
+}
+
 // IsZero returns true if the Decimal is zero.
 func (d Decimal) IsZero() bool {
 	return d.value.IsZero()
@@ -144,6 +150,21 @@ func (d Decimal) String() string {
 	return d.value.String()
 }
 
+// Fixed returns d as an integer number of 10^-places units (d × 10^places)
+// for fixed-point storage. ok is false if d has more than places
+// fractional digits and so cannot be stored exactly at that scale.
+func (d Decimal) Fixed(places int32) (v *big.Int, ok bool) {
+	if -d.value.Exponent() > places {
+		return nil, false
+	}
+	return d.value.Shift(places).BigInt(), true
+}
+
+// NewDecimalFromFixed creates the Decimal v × 10^-places, the inverse of Fixed.
+func NewDecimalFromFixed(v *big.Int, places int32) Decimal {
+	return Decimal{value: decimal.NewFromBigInt(v, -places)}
+}
+
 // Price represents a unit price of an asset.
 // Prices cannot be negative and support specific arithmetic operations
 // that maintain type safety (e.g., Price * Amount = Amount, not Price).
@@ -0,0 +1,237 @@
+// Package rates provides interest-rate term structures: zero curves built
+// from tenor/rate points, interpolation, discount factors and forward
+// rates.
+//
+// Pricers in this toolkit take a single scalar RiskFreeRate. A Curve
+// replaces that scalar with the zero rate matching each instrument's
+// maturity (see Curve.Apply), and carry analytics compare dated-future
+// prices against the curve's fair forwards. Curves travel with market
+// data as snapshot metadata under snapshotkeys.RateCurve.
+//
+// Rates are annualized and continuously compounded; times are in years,
+// matching mechanisms.PriceParams.TimeToExpiry.
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrInvalidCurve is returned when curve points are missing, duplicated
+	// or have non-positive tenors
+	ErrInvalidCurve = errors.New("invalid rate curve")
+
+	// ErrInvalidTenor is returned for negative times or reversed intervals
+	ErrInvalidTenor = errors.New("invalid tenor")
+)
+
+// Interpolation selects how a Curve fills in rates between points.
+type Interpolation int
+
+const (
+	// InterpolationLinear interpolates zero rates linearly
+	InterpolationLinear Interpolation = iota
+
+	// InterpolationLogDiscount interpolates log discount factors linearly,
+	// which holds forward rates constant between points
+	InterpolationLogDiscount
+)
+
+// String returns the interpolation name.
+func (i Interpolation) String() string {
+	switch i {
+	case InterpolationLinear:
+		return "linear"
+	case InterpolationLogDiscount:
+		return "log-discount"
+	default:
+		return fmt.Sprintf("Interpolation(%d)", int(i))
+	}
+}
+
+// Point is a zero rate at a tenor.
+type Point struct {
+	// Years is the tenor in years; it must be positive
+	Years primitives.Decimal
+
+	// Rate is the annualized, continuously compounded zero rate
+	Rate primitives.Decimal
+}
+
+// Years converts a duration to years of primitives.Year.
+func Years(d primitives.Duration) primitives.Decimal {
+	return primitives.NewDecimalFromFloat(d.Hours() / primitives.Year.Hours())
+}
+
+// Curve is a zero-rate term structure. Zero rates are held flat before
+// the first point and after the last.
+//
+// Thread Safety: Curve is immutable and safe for concurrent use.
+type Curve struct {
+	points        []Point
+	interpolation Interpolation
+}
+
+// NewCurve builds a curve from points in any order.
+// Returns ErrInvalidCurve if there are no points, a tenor is not positive,
+// or two points share a tenor.
+func NewCurve(interpolation Interpolation, points ...Point) (*Curve, error) {
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%w: no points", ErrInvalidCurve)
+	}
+	sorted := make([]Point, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Years.LessThan(sorted[j].Years) })
+	for i, p := range sorted {
+		if !p.Years.IsPositive() {
+			return nil, fmt.Errorf("%w: tenor %s must be positive", ErrInvalidCurve, p.Years)
+		}
+		if i > 0 && p.Years.Equal(sorted[i-1].Years) {
+			return nil, fmt.Errorf("%w: duplicate tenor %s", ErrInvalidCurve, p.Years)
+		}
+	}
+	return &Curve{points: sorted, interpolation: interpolation}, nil
+}
+
+// Flat returns a curve with the same zero rate at every tenor.
+func Flat(rate primitives.Decimal) *Curve {
+	return &Curve{points: []Point{{Years: primitives.One(), Rate: rate}}}
+}
+
+// Points returns the curve's points in tenor order.
+func (c *Curve) Points() []Point {
+	points := make([]Point, len(c.points))
+	copy(points, c.points)
+	return points
+}
+
+// Shift returns the curve with every zero rate raised by bump, a parallel
+// shift.
+func (c *Curve) Shift(bump primitives.Decimal) *Curve {
+	points := c.Points()
+	for i := range points {
+		points[i].Rate = points[i].Rate.Add(bump)
+	}
+	return &Curve{points: points, interpolation: c.interpolation}
+}
+
+// ShiftRate implements strategy.ShiftableRate, so strategy.BumpSnapshot
+// shifts curves in snapshot metadata.
+func (c *Curve) ShiftRate(bump primitives.Decimal) interface{} {
+	return c.Shift(bump)
+}
+
+// ZeroRate returns the zero rate for a maturity years from now.
+// Returns ErrInvalidTenor for negative years.
+func (c *Curve) ZeroRate(years primitives.Decimal) (primitives.Decimal, error) {
+	if years.IsNegative() {
+		return primitives.Zero(), fmt.Errorf("%w: %s years", ErrInvalidTenor, years)
+	}
+	return primitives.NewDecimalFromFloat(c.zero(years.Float64())), nil
+}
+
+func (c *Curve) zero(t float64) float64 {
+	first, last := c.points[0], c.points[len(c.points)-1]
+	if t <= first.Years.Float64() {
+		return first.Rate.Float64()
+	}
+	if t >= last.Years.Float64() {
+		return last.Rate.Float64()
+	}
+
+	i := sort.Search(len(c.points), func(i int) bool { return c.points[i].Years.Float64() >= t })
+	t0, r0 := c.points[i-1].Years.Float64(), c.points[i-1].Rate.Float64()
+	t1, r1 := c.points[i].Years.Float64(), c.points[i].Rate.Float64()
+	w := (t - t0) / (t1 - t0)
+	if c.interpolation == InterpolationLogDiscount {
+		// ln DF = −r·t is linear in t between points
+		return ((1-w)*r0*t0 + w*r1*t1) / t
+	}
+	return (1-w)*r0 + w*r1
+}
+
+// DiscountFactor returns e^(−r·t), the present value of 1 paid years
+// from now.
+func (c *Curve) DiscountFactor(years primitives.Decimal) (primitives.Decimal, error) {
+	if years.IsNegative() {
+		return primitives.Zero(), fmt.Errorf("%w: %s years", ErrInvalidTenor, years)
+	}
+	t := years.Float64()
+	return primitives.NewDecimalFromFloat(math.Exp(-c.zero(t) * t)), nil
+}
+
+// ForwardRate returns the continuously compounded rate agreed today for
+// borrowing between from and to years: (r₂·t₂ − r₁·t₁) / (t₂ − t₁).
+// Returns ErrInvalidTenor unless 0 <= from < to.
+func (c *Curve) ForwardRate(from, to primitives.Decimal) (primitives.Decimal, error) {
+	if from.IsNegative() || !to.GreaterThan(from) {
+		return primitives.Zero(), fmt.Errorf("%w: forward from %s to %s years", ErrInvalidTenor, from, to)
+	}
+	t1, t2 := from.Float64(), to.Float64()
+	return primitives.NewDecimalFromFloat((c.zero(t2)*t2 - c.zero(t1)*t1) / (t2 - t1)), nil
+}
+
+// FairForward returns the cost-of-carry forward price of spot for delivery
+// years from now, spot / DF(years), ignoring any yield on the asset.
+func (c *Curve) FairForward(spot primitives.Price, years primitives.Decimal) (primitives.Price, error) {
+	df, err := c.DiscountFactor(years)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	forward, err := spot.Decimal().Div(df)
+	if err != nil {
+		return primitives.ZeroPrice(), err
+	}
+	return primitives.NewPrice(forward)
+}
+
+// Apply returns params with RiskFreeRate set to the zero rate for
+// params.TimeToExpiry, so a scalar-rate pricer discounts on the curve.
+func (c *Curve) Apply(params mechanisms.PriceParams) (mechanisms.PriceParams, error) {
+	rate, err := c.ZeroRate(params.TimeToExpiry)
+	if err != nil {
+		return params, err
+	}
+	params.RiskFreeRate = rate
+	return params, nil
+}
+
+// ImpliedRate returns the continuously compounded rate implied by a
+// forward (e.g., a dated future) trading at forward against spot with
+// years to delivery: ln(forward/spot) / years. Compared with the curve's
+// ZeroRate it gives the annualized carry, or basis, of the future.
+func ImpliedRate(spot, forward primitives.Price, years primitives.Decimal) (primitives.Decimal, error) {
+	if !years.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: %s years", ErrInvalidTenor, years)
+	}
+	if spot.IsZero() || forward.IsZero() {
+		return primitives.Zero(), errors.New("spot and forward prices must be positive")
+	}
+	ratio := forward.Decimal().Float64() / spot.Decimal().Float64()
+	return primitives.NewDecimalFromFloat(math.Log(ratio) / years.Float64()), nil
+}
+
+// FromSnapshot returns the curve for currency stored in snapshot metadata
+// at snapshotkeys.RateCurve(currency). Errors wrap
+// strategy.ErrMetadataNotFound when absent and strategy.ErrMetadataType
+// when the value is not a *Curve.
+func FromSnapshot(snapshot strategy.MarketSnapshot, currency string) (*Curve, error) {
+	key := snapshotkeys.RateCurve(currency)
+	raw, ok := snapshot.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", strategy.ErrMetadataNotFound, key)
+	}
+	curve, ok := raw.(*Curve)
+	if !ok {
+		return nil, fmt.Errorf("%w: key %s: expected *rates.Curve, got %T", strategy.ErrMetadataType, key, raw)
+	}
+	return curve, nil
+}
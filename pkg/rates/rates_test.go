@@ -0,0 +1,188 @@
+package rates_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/rates"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func near(got primitives.Decimal, want float64) bool {
+	return math.Abs(got.Float64()-want) < 1e-9
+}
+
+// upward is 4% at six months rising to 6% at two years.
+func upward(t *testing.T, interpolation rates.Interpolation) *rates.Curve {
+	t.Helper()
+	curve, err := rates.NewCurve(interpolation,
+		rates.Point{Years: dec("2"), Rate: dec("0.06")},
+		rates.Point{Years: dec("0.5"), Rate: dec("0.04")},
+		rates.Point{Years: dec("1"), Rate: dec("0.05")},
+	)
+	if err != nil {
+		t.Fatalf("NewCurve: %v", err)
+	}
+	return curve
+}
+
+func TestZeroRate(t *testing.T) {
+	tests := []struct {
+		name          string
+		interpolation rates.Interpolation
+		years         string
+		want          float64
+	}{
+		{"on a point", rates.InterpolationLinear, "1", 0.05},
+		{"flat before first", rates.InterpolationLinear, "0.1", 0.04},
+		{"flat after last", rates.InterpolationLinear, "5", 0.06},
+		{"linear midpoint", rates.InterpolationLinear, "1.5", 0.055},
+		// ln DF linear: (0.05·1 + 0.06·2) / 2 / 1.5
+		{"log-discount midpoint", rates.InterpolationLogDiscount, "1.5", 0.085 / 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := upward(t, tt.interpolation).ZeroRate(dec(tt.years))
+			if err != nil || !near(rate, tt.want) {
+				t.Errorf("ZeroRate(%s) = %s (%v), want %f", tt.years, rate, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscountingAndForwards(t *testing.T) {
+	curve := upward(t, rates.InterpolationLinear)
+
+	df, err := curve.DiscountFactor(dec("2"))
+	if err != nil || !near(df, math.Exp(-0.12)) {
+		t.Errorf("DF(2) = %s (%v), want %f", df, err, math.Exp(-0.12))
+	}
+
+	// 1y→2y forward: (0.06·2 − 0.05·1) / 1
+	forward, err := curve.ForwardRate(dec("1"), dec("2"))
+	if err != nil || !near(forward, 0.07) {
+		t.Errorf("forward = %s (%v), want 0.07", forward, err)
+	}
+
+	// Log-discount interpolation holds forwards constant between points
+	logCurve := upward(t, rates.InterpolationLogDiscount)
+	a, _ := logCurve.ForwardRate(dec("1"), dec("1.25"))
+	b, _ := logCurve.ForwardRate(dec("1.5"), dec("2"))
+	if !near(a, 0.07) || !near(b, 0.07) {
+		t.Errorf("piecewise forwards = %s and %s, want 0.07", a, b)
+	}
+
+	fair, err := curve.FairForward(primitives.MustPrice(dec("2000")), dec("1"))
+	if err != nil || !near(fair.Decimal(), 2000*math.Exp(0.05)) {
+		t.Errorf("fair forward = %s (%v)", fair, err)
+	}
+	implied, err := rates.ImpliedRate(primitives.MustPrice(dec("2000")), fair, dec("1"))
+	if err != nil || !near(implied, 0.05) {
+		t.Errorf("implied rate = %s (%v), want the curve's 0.05", implied, err)
+	}
+
+	if _, err := curve.ForwardRate(dec("2"), dec("1")); !errors.Is(err, rates.ErrInvalidTenor) {
+		t.Errorf("expected %v, got %v", rates.ErrInvalidTenor, err)
+	}
+	if _, err := curve.DiscountFactor(dec("-1")); !errors.Is(err, rates.ErrInvalidTenor) {
+		t.Errorf("expected %v, got %v", rates.ErrInvalidTenor, err)
+	}
+}
+
+func TestApplyToOptionPricer(t *testing.T) {
+	curve := upward(t, rates.InterpolationLinear)
+	option, err := blackscholes.NewOption("call", mechanisms.OptionTypeCall,
+		primitives.MustPrice(dec("2000")), dec("2"), primitives.MustPrice(dec("100")), primitives.One())
+	if err != nil {
+		t.Fatalf("NewOption: %v", err)
+	}
+	params := mechanisms.PriceParams{
+		UnderlyingPrice: primitives.MustPrice(dec("2000")),
+		TimeToExpiry:    dec("2"),
+		Volatility:      dec("0.6"),
+	}
+	onCurve, err := curve.Apply(params)
+	if err != nil || !onCurve.RiskFreeRate.Equal(dec("0.06")) {
+		t.Fatalf("Apply: rate %s (%v), want 0.06", onCurve.RiskFreeRate, err)
+	}
+	withCurve, _ := option.Price(context.Background(), onCurve)
+	withoutCurve, _ := option.Price(context.Background(), params)
+	if !withCurve.Decimal().GreaterThan(withoutCurve.Decimal()) {
+		t.Errorf("call on a 6%% curve %s should be worth more than at 0%% %s", withCurve, withoutCurve)
+	}
+}
+
+func TestNewCurveValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []rates.Point
+	}{
+		{"no points", nil},
+		{"zero tenor", []rates.Point{{Years: primitives.Zero(), Rate: dec("0.05")}}},
+		{"duplicate tenor", []rates.Point{{Years: dec("1"), Rate: dec("0.05")}, {Years: dec("1"), Rate: dec("0.06")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rates.NewCurve(rates.InterpolationLinear, tt.points...); !errors.Is(err, rates.ErrInvalidCurve) {
+				t.Errorf("expected %v, got %v", rates.ErrInvalidCurve, err)
+			}
+		})
+	}
+}
+
+func TestFromSnapshot(t *testing.T) {
+	snapshot := strategy.NewSimpleSnapshot(primitives.NewTime(time.Now()), map[string]primitives.Price{})
+	if _, err := rates.FromSnapshot(snapshot, "USD"); !errors.Is(err, strategy.ErrMetadataNotFound) {
+		t.Errorf("expected %v, got %v", strategy.ErrMetadataNotFound, err)
+	}
+
+	snapshot.Set(snapshotkeys.RateCurve("USD"), rates.Flat(dec("0.045")))
+	curve, err := rates.FromSnapshot(snapshot, "USD")
+	if err != nil {
+		t.Fatalf("FromSnapshot: %v", err)
+	}
+	if rate, _ := curve.ZeroRate(dec("7")); !rate.Equal(dec("0.045")) {
+		t.Errorf("flat curve rate = %s, want 0.045", rate)
+	}
+
+	snapshot.Set(snapshotkeys.RateCurve("EUR"), dec("0.03"))
+	if _, err := rates.FromSnapshot(snapshot, "EUR"); !errors.Is(err, strategy.ErrMetadataType) {
+		t.Errorf("expected %v, got %v", strategy.ErrMetadataType, err)
+	}
+}
+
+func TestCurveShift(t *testing.T) {
+	curve, err := rates.NewCurve(rates.InterpolationLinear,
+		rates.Point{Years: dec("1"), Rate: dec("0.04")},
+		rates.Point{Years: dec("2"), Rate: dec("0.05")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := strategy.NewSimpleSnapshot(primitives.NewTime(time.Now()), map[string]primitives.Price{})
+	snapshot.Set(snapshotkeys.RateCurve("USD"), curve)
+
+	// A bumped snapshot shifts the curve in parallel, leaving the original
+	bumped := strategy.BumpSnapshot(snapshot, strategy.Bump{Rate: dec("0.01")})
+	shifted, err := rates.FromSnapshot(bumped, "USD")
+	if err != nil {
+		t.Fatalf("FromSnapshot: %v", err)
+	}
+	if rate, _ := shifted.ZeroRate(dec("1.5")); !rate.Equal(dec("0.055")) {
+		t.Errorf("shifted rate = %s, want 0.055", rate)
+	}
+	if rate, _ := curve.ZeroRate(dec("1.5")); !rate.Equal(dec("0.045")) {
+		t.Errorf("original rate = %s, want 0.045", rate)
+	}
+}
@@ -0,0 +1,325 @@
+// Package rewards models liquidity-mining incentives: reward tokens a pool
+// emits to its liquidity providers on top of trading fees.
+//
+// A Schedule lists each pool's emissions (tokens per period over a time
+// window). Wrapping an LP position in a Position accrues its pro-rata share
+// of those emissions and includes the accrued tokens, valued at snapshot
+// prices, in the position's value. Positions are immutable: Accrue returns a
+// new position with rewards folded in, and ClaimAction converts them to cash.
+//
+// Positions implement accounting.RewardBearer, so a ledger attributes reward
+// accrual to its own P&L account instead of unrealized P&L.
+package rewards
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrInvalidEmission is returned when an emission is missing fields or
+	// its window is empty
+	ErrInvalidEmission = errors.New("invalid emission")
+
+	// ErrNoPoolLiquidity is returned when a snapshot has no positive pool
+	// liquidity to compute a position's share of emissions
+	ErrNoPoolLiquidity = errors.New("pool liquidity unavailable")
+)
+
+// Emission is a stream of reward tokens paid to a pool's liquidity providers.
+type Emission struct {
+	// Pool is the pool ID receiving the emission
+	Pool string
+
+	// Token is the reward token symbol (e.g., "ARB")
+	Token string
+
+	// Pair is the snapshot price key of the reward token (e.g., "ARB/USD")
+	Pair string
+
+	// Rate is the number of tokens emitted per Period
+	Rate primitives.Decimal
+
+	// Period is the emission period (e.g., 24h for a daily rate)
+	Period primitives.Duration
+
+	// Start is when emission begins
+	Start primitives.Time
+
+	// End is when emission stops; zero means it never stops
+	End primitives.Time
+}
+
+// emitted returns the tokens emitted between from and to.
+func (e Emission) emitted(from, to primitives.Time) primitives.Decimal {
+	if from.Before(e.Start) {
+		from = e.Start
+	}
+	if !e.End.Time().IsZero() && to.After(e.End) {
+		to = e.End
+	}
+	if !to.After(from) {
+		return primitives.Zero()
+	}
+	elapsed := primitives.NewDecimal(int64(to.Sub(from).Duration()))
+	fraction, err := elapsed.Div(primitives.NewDecimal(int64(e.Period.Duration())))
+	if err != nil {
+		return primitives.Zero()
+	}
+	return e.Rate.Mul(fraction)
+}
+
+// Schedule holds the emissions of every incentivized pool.
+//
+// Thread Safety: Schedule is safe for concurrent reads once all emissions
+// are added.
+type Schedule struct {
+	emissions map[string][]Emission
+	pairs     map[string]string
+}
+
+// NewSchedule creates an empty schedule.
+func NewSchedule() *Schedule {
+	return &Schedule{
+		emissions: make(map[string][]Emission),
+		pairs:     make(map[string]string),
+	}
+}
+
+// Add registers an emission. A pool may have several emissions, in the
+// same or different tokens. Returns ErrInvalidEmission if a field is
+// missing, the rate is negative, the period is not positive, End is not
+// after Start, or Token was registered with a different price pair.
+func (s *Schedule) Add(e Emission) error {
+	switch {
+	case e.Pool == "" || e.Token == "" || e.Pair == "":
+		return fmt.Errorf("%w: pool, token and pair are required", ErrInvalidEmission)
+	case e.Rate.IsNegative():
+		return fmt.Errorf("%w: negative rate %s", ErrInvalidEmission, e.Rate)
+	case e.Period.Duration() <= 0:
+		return fmt.Errorf("%w: period must be positive", ErrInvalidEmission)
+	case !e.End.Time().IsZero() && !e.End.After(e.Start):
+		return fmt.Errorf("%w: end %s is not after start %s", ErrInvalidEmission, e.End, e.Start)
+	}
+	if pair, ok := s.pairs[e.Token]; ok && pair != e.Pair {
+		return fmt.Errorf("%w: token %s already priced by %s", ErrInvalidEmission, e.Token, pair)
+	}
+
+	s.pairs[e.Token] = e.Pair
+	s.emissions[e.Pool] = append(s.emissions[e.Pool], e)
+	return nil
+}
+
+// Emitted returns the tokens the pool emits to all of its liquidity
+// providers between from and to, by token.
+func (s *Schedule) Emitted(pool string, from, to primitives.Time) map[string]primitives.Decimal {
+	emitted := make(map[string]primitives.Decimal)
+	for _, e := range s.emissions[pool] {
+		if amount := e.emitted(from, to); amount.IsPositive() {
+			emitted[e.Token] = emitted[e.Token].Add(amount)
+		}
+	}
+	return emitted
+}
+
+// Value returns the value of tokens at snapshot prices.
+func (s *Schedule) Value(tokens map[string]primitives.Decimal, snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	total := primitives.Zero()
+	for _, token := range sortedTokens(tokens) {
+		price, err := snapshot.Price(s.pairs[token])
+		if err != nil {
+			return primitives.ZeroAmount(), fmt.Errorf("failed to price reward token %s: %w", token, err)
+		}
+		total = total.Add(tokens[token].Mul(price.Decimal()))
+	}
+	return primitives.NewAmount(total)
+}
+
+// Position wraps an LP position so its value includes the incentive
+// rewards it has earned but not claimed.
+//
+// Rewards accrue pro rata to Liquidity divided by the pool's liquidity,
+// read from snapshot metadata at snapshotkeys.PoolLiquidity. Between
+// Accrue calls the share at the valuation snapshot is applied to the
+// whole interval, so strategies whose pool liquidity moves a lot should
+// Accrue every rebalance.
+type Position struct {
+	inner     strategy.Position
+	pool      string
+	liquidity primitives.Decimal
+	schedule  *Schedule
+	since     primitives.Time
+	accrued   map[string]primitives.Decimal
+}
+
+// NewPosition wraps inner, an LP position holding liquidity in pool,
+// accruing rewards from schedule starting at opened.
+func NewPosition(inner strategy.Position, pool string, liquidity primitives.Decimal, schedule *Schedule, opened primitives.Time) *Position {
+	return &Position{
+		inner:     inner,
+		pool:      pool,
+		liquidity: liquidity,
+		schedule:  schedule,
+		since:     opened,
+		accrued:   map[string]primitives.Decimal{},
+	}
+}
+
+// ID returns the wrapped position's ID.
+func (p *Position) ID() string {
+	return p.inner.ID()
+}
+
+// Type returns the wrapped position's type.
+func (p *Position) Type() strategy.PositionType {
+	return p.inner.Type()
+}
+
+// Inner returns the wrapped position.
+func (p *Position) Inner() strategy.Position {
+	return p.inner
+}
+
+// Value returns the wrapped position's value plus RewardValue.
+func (p *Position) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	principal, err := p.inner.Value(snapshot)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	rewards, err := p.RewardValue(snapshot)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return principal.Add(rewards), nil
+}
+
+// Rewards returns the unclaimed reward tokens earned as of snapshot.
+func (p *Position) Rewards(snapshot strategy.MarketSnapshot) (map[string]primitives.Decimal, error) {
+	rewards := make(map[string]primitives.Decimal, len(p.accrued))
+	for token, amount := range p.accrued {
+		rewards[token] = amount
+	}
+	if !snapshot.Time().After(p.since) {
+		return rewards, nil
+	}
+
+	emitted := p.schedule.Emitted(p.pool, p.since, snapshot.Time())
+	if len(emitted) == 0 {
+		return rewards, nil
+	}
+	share, err := p.share(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	for token, amount := range emitted {
+		rewards[token] = rewards[token].Add(amount.Mul(share))
+	}
+	return rewards, nil
+}
+
+// RewardValue returns the value of the unclaimed rewards at snapshot prices.
+// It implements accounting.RewardBearer.
+func (p *Position) RewardValue(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	rewards, err := p.Rewards(snapshot)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return p.schedule.Value(rewards, snapshot)
+}
+
+// Accrue returns a copy of the position with rewards earned up to snapshot
+// folded in, so later valuations use later pool liquidity.
+func (p *Position) Accrue(snapshot strategy.MarketSnapshot) (*Position, error) {
+	rewards, err := p.Rewards(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	next := *p
+	next.accrued = rewards
+	if snapshot.Time().After(p.since) {
+		next.since = snapshot.Time()
+	}
+	return &next, nil
+}
+
+// share returns the position's fraction of the pool's liquidity.
+func (p *Position) share(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	poolLiquidity, err := strategy.GetDecimal(snapshot, snapshotkeys.PoolLiquidity(p.pool))
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("%w: pool %s: %v", ErrNoPoolLiquidity, p.pool, err)
+	}
+	if !poolLiquidity.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: pool %s has liquidity %s", ErrNoPoolLiquidity, p.pool, poolLiquidity)
+	}
+	share, err := p.liquidity.Div(poolLiquidity)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	if share.GreaterThan(primitives.One()) {
+		share = primitives.One()
+	}
+	return share, nil
+}
+
+// ClaimAction sells a position's unclaimed rewards at snapshot prices,
+// crediting the proceeds to cash and resetting the position's rewards.
+//
+// It implements strategy.DeferredAction: the backtest engine resolves it
+// against the snapshot it executes at. Applying it unresolved fails.
+type ClaimAction struct {
+	Position *Position
+}
+
+// NewClaimAction creates an action that claims position's rewards.
+func NewClaimAction(position *Position) *ClaimAction {
+	return &ClaimAction{Position: position}
+}
+
+// Apply fails: rewards can only be claimed at a snapshot's prices.
+func (a *ClaimAction) Apply(portfolio *strategy.Portfolio) error {
+	return fmt.Errorf("%w: reward claim must be resolved against a snapshot", strategy.ErrInvalidAction)
+}
+
+// Resolve replaces the position with one holding no rewards and credits
+// their value to cash.
+func (a *ClaimAction) Resolve(snapshot strategy.MarketSnapshot) (strategy.Action, error) {
+	if a.Position == nil {
+		return nil, fmt.Errorf("%w: cannot claim rewards of nil position", strategy.ErrInvalidAction)
+	}
+	value, err := a.Position.RewardValue(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := a.Position.Accrue(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	claimed.accrued = map[string]primitives.Decimal{}
+
+	return strategy.NewBatchAction(
+		strategy.NewReplacePositionAction(a.Position.ID(), claimed),
+		strategy.NewAdjustCashAction(value.Decimal(), "claim rewards "+a.Position.ID()),
+	), nil
+}
+
+// String returns a description of this action.
+func (a *ClaimAction) String() string {
+	if a.Position == nil {
+		return "ClaimRewards(nil)"
+	}
+	return fmt.Sprintf("ClaimRewards(%s)", a.Position.ID())
+}
+
+func sortedTokens(tokens map[string]primitives.Decimal) []string {
+	names := make([]string, 0, len(tokens))
+	for token := range tokens {
+		names = append(names, token)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,198 @@
+package rewards_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/accounting"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/rewards"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// lpPosition is an LP position with a fixed principal value.
+type lpPosition struct{ value int64 }
+
+func (p *lpPosition) ID() string                  { return "lp:eth-usdc" }
+func (p *lpPosition) Type() strategy.PositionType { return strategy.PositionTypeLiquidityPool }
+func (p *lpPosition) Value(strategy.MarketSnapshot) (primitives.Amount, error) {
+	return primitives.MustAmount(primitives.NewDecimal(p.value)), nil
+}
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func at(hours int) primitives.Time {
+	return primitives.NewTime(start.Add(time.Duration(hours) * time.Hour))
+}
+
+// snapshot prices ARB at arb and reports the pool's total liquidity.
+func snapshot(hours int, arb string, poolLiquidity int64) *strategy.SimpleSnapshot {
+	s := strategy.NewSimpleSnapshot(at(hours), map[string]primitives.Price{"ARB/USD": primitives.MustPrice(dec(arb))})
+	s.Set(snapshotkeys.PoolLiquidity("eth-usdc"), primitives.NewDecimal(poolLiquidity))
+	return s
+}
+
+// dailySchedule emits 100 ARB per day to eth-usdc.
+func dailySchedule(t *testing.T) *rewards.Schedule {
+	t.Helper()
+	schedule := rewards.NewSchedule()
+	err := schedule.Add(rewards.Emission{
+		Pool:   "eth-usdc",
+		Token:  "ARB",
+		Pair:   "ARB/USD",
+		Rate:   primitives.NewDecimal(100),
+		Period: primitives.NewDuration(24 * time.Hour),
+		Start:  at(0),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return schedule
+}
+
+func TestSchedule(t *testing.T) {
+	schedule := dailySchedule(t)
+	err := schedule.Add(rewards.Emission{
+		Pool: "eth-usdc", Token: "OP", Pair: "OP/USD", Rate: primitives.NewDecimal(48),
+		Period: primitives.NewDuration(24 * time.Hour), Start: at(6), End: at(18),
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		arb, op  string
+	}{
+		{"full day", 0, 24, "100", "24"},
+		{"before op window", 0, 6, "25", "0"},
+		{"clipped to op window", 3, 21, "75", "24"},
+		{"empty interval", 5, 5, "0", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emitted := schedule.Emitted("eth-usdc", at(tt.from), at(tt.to))
+			if !emitted["ARB"].Equal(dec(tt.arb)) || !emitted["OP"].Equal(dec(tt.op)) {
+				t.Errorf("emitted %v, want ARB %s and OP %s", emitted, tt.arb, tt.op)
+			}
+		})
+	}
+
+	invalid := []rewards.Emission{
+		{Token: "ARB", Pair: "ARB/USD", Rate: primitives.One(), Period: primitives.NewDuration(time.Hour)},
+		{Pool: "p", Token: "ARB", Pair: "ARB/USD", Rate: primitives.One().Neg(), Period: primitives.NewDuration(time.Hour)},
+		{Pool: "p", Token: "ARB", Pair: "ARB/USD", Rate: primitives.One()},
+		{Pool: "p", Token: "ARB", Pair: "ARB/USD", Rate: primitives.One(), Period: primitives.NewDuration(time.Hour), Start: at(2), End: at(1)},
+		{Pool: "p", Token: "ARB", Pair: "ARB/USDC", Rate: primitives.One(), Period: primitives.NewDuration(time.Hour)},
+	}
+	for i, e := range invalid {
+		if err := schedule.Add(e); !errors.Is(err, rewards.ErrInvalidEmission) {
+			t.Errorf("emission %d: expected %v, got %v", i, rewards.ErrInvalidEmission, err)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	schedule := dailySchedule(t)
+	// 100 of 1,000 pool liquidity earns 10% of emissions
+	position := rewards.NewPosition(&lpPosition{value: 1000}, "eth-usdc", primitives.NewDecimal(100), schedule, at(0))
+
+	t.Run("accrues pro rata", func(t *testing.T) {
+		value, err := position.Value(snapshot(12, "2", 1000))
+		if err != nil {
+			t.Fatalf("Value: %v", err)
+		}
+		// 50 ARB emitted × 10% × $2
+		if !value.Decimal().Equal(primitives.NewDecimal(1010)) {
+			t.Errorf("value = %s, want 1010", value)
+		}
+	})
+
+	t.Run("accrue folds in share", func(t *testing.T) {
+		accrued, err := position.Accrue(snapshot(12, "2", 1000))
+		if err != nil {
+			t.Fatalf("Accrue: %v", err)
+		}
+		// Pool liquidity doubles: the next 12 hours earn 5% instead of 10%
+		tokens, err := accrued.Rewards(snapshot(24, "2", 2000))
+		if err != nil {
+			t.Fatalf("Rewards: %v", err)
+		}
+		if !tokens["ARB"].Equal(dec("7.5")) {
+			t.Errorf("ARB = %s, want 7.5", tokens["ARB"])
+		}
+		if accrued.ID() != "lp:eth-usdc" || accrued.Type() != strategy.PositionTypeLiquidityPool {
+			t.Errorf("unexpected identity %s/%s", accrued.ID(), accrued.Type())
+		}
+	})
+
+	t.Run("missing pool liquidity", func(t *testing.T) {
+		s := strategy.NewSimpleSnapshot(at(1), map[string]primitives.Price{"ARB/USD": primitives.MustPrice(primitives.One())})
+		if _, err := position.Value(s); !errors.Is(err, rewards.ErrNoPoolLiquidity) {
+			t.Errorf("expected %v, got %v", rewards.ErrNoPoolLiquidity, err)
+		}
+	})
+}
+
+// farmStrategy opens a rewarded LP position and claims after 48 hours.
+type farmStrategy struct {
+	schedule *rewards.Schedule
+}
+
+func (s *farmStrategy) Rebalance(_ context.Context, p *strategy.Portfolio, m strategy.MarketSnapshot) ([]strategy.Action, error) {
+	if !p.HasPosition("lp:eth-usdc") {
+		lp := rewards.NewPosition(&lpPosition{value: 1000}, "eth-usdc", primitives.NewDecimal(100), s.schedule, m.Time())
+		return []strategy.Action{
+			strategy.NewAddPositionAction(lp),
+			strategy.NewAdjustCashAction(primitives.NewDecimal(-1000), "deposit"),
+		}, nil
+	}
+	if m.Time().Equal(at(48)) {
+		position, _ := p.GetPosition("lp:eth-usdc")
+		return []strategy.Action{rewards.NewClaimAction(position.(*rewards.Position))}, nil
+	}
+	return nil, nil
+}
+
+func TestClaimWithLedger(t *testing.T) {
+	// ARB trades at $2 then $3; the pool always holds 1,000 liquidity
+	snapshots := []strategy.MarketSnapshot{
+		snapshot(0, "2", 1000),
+		snapshot(24, "2", 1000),
+		snapshot(48, "3", 1000),
+		snapshot(72, "3", 1000),
+	}
+	ledger := accounting.NewLedger()
+	config := backtest.DefaultConfig()
+	config.Ledger = ledger
+
+	result, err := backtest.NewEngine(config).Run(context.Background(), &farmStrategy{schedule: dailySchedule(t)}, snapshots)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// 20 ARB earned over two days, claimed at $3
+	if want := primitives.NewDecimal(9060); !result.Portfolio.CashDecimal().Equal(want) {
+		t.Errorf("cash = %s, want %s", result.Portfolio.CashDecimal(), want)
+	}
+	// Marks at 24h ($20), 48h (+$40) and, after the claim resets the
+	// position, 72h (+$30) are all reward P&L
+	if got := ledger.Balance(accounting.AccountRewardPnL); !got.Equal(primitives.NewDecimal(-90)) {
+		t.Errorf("reward P&L balance = %s, want -90", got)
+	}
+	if got := ledger.Balance(accounting.AccountUnrealizedPnL); !got.IsZero() {
+		t.Errorf("unrealized P&L balance = %s, want 0", got)
+	}
+	if got := ledger.Balance(accounting.PositionAccount("lp:eth-usdc")); !got.Equal(primitives.NewDecimal(1030)) {
+		t.Errorf("position balance = %s, want 1030", got)
+	}
+}
@@ -0,0 +1,76 @@
+package sizing
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// riskParityIterations and riskParityTolerance bound the coordinate
+// descent in RiskParityWeights.
+const (
+	riskParityIterations = 500
+	riskParityTolerance  = 1e-12
+)
+
+// RiskParityWeights returns long-only weights, summing to one, under which
+// every pair contributes equally to portfolio variance:
+//
+//	wᵢ·(Σw)ᵢ = wⱼ·(Σw)ⱼ for all i, j
+//
+// With uncorrelated assets this reduces to inverse-volatility weighting.
+// Multiply a weight by equity and divide by price for a quantity.
+//
+// Returns ErrInvalidParams if any pair has no variance.
+func RiskParityWeights(cov analytics.Covariance) (map[string]primitives.Decimal, error) {
+	pairs := cov.Pairs()
+	decimals := cov.Matrix()
+	n := len(pairs)
+	sigma := make([][]float64, n)
+	for i := range decimals {
+		sigma[i] = make([]float64, n)
+		for j, v := range decimals[i] {
+			sigma[i][j] = v.Float64()
+		}
+		if sigma[i][i] <= 0 {
+			return nil, fmt.Errorf("%w: %s has no variance", ErrInvalidParams, pairs[i])
+		}
+	}
+
+	// Cyclical coordinate descent on ½wᵀΣw − Σ ln(wᵢ)/n, whose minimizer
+	// has equal risk contributions (Griveau-Billion, Richard & Roncalli)
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1 / math.Sqrt(sigma[i][i])
+	}
+	budget := 1 / float64(n)
+	for iter := 0; iter < riskParityIterations; iter++ {
+		var change float64
+		for i := range w {
+			var c float64
+			for j := range w {
+				if j != i {
+					c += sigma[i][j] * w[j]
+				}
+			}
+			next := (-c + math.Sqrt(c*c+4*sigma[i][i]*budget)) / (2 * sigma[i][i])
+			change = math.Max(change, math.Abs(next-w[i]))
+			w[i] = next
+		}
+		if change < riskParityTolerance {
+			break
+		}
+	}
+
+	var total float64
+	for _, x := range w {
+		total += x
+	}
+	weights := make(map[string]primitives.Decimal, n)
+	for i, pair := range pairs {
+		weights[pair] = primitives.NewDecimalFromFloat(w[i] / total)
+	}
+	return weights, nil
+}
@@ -0,0 +1,223 @@
+// Package sizing provides reusable position-sizing algorithms. Each sizer
+// converts account equity and market inputs into a position quantity that
+// strategies can pass to their position constructors and Actions.
+//
+// All sizers return quantities in units of the asset (not notional value)
+// using decimal arithmetic, and never return negative sizes; direction is
+// the strategy's decision.
+package sizing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInvalidParams indicates sizing inputs are missing or out of range
+	ErrInvalidParams = errors.New("invalid sizing parameters")
+)
+
+// Params contains the inputs available to a sizer.
+// Each sizer documents which fields it requires.
+type Params struct {
+	// Equity is the capital base to size against (e.g., portfolio value)
+	Equity primitives.Amount
+
+	// Price is the current price of the asset being sized
+	Price primitives.Price
+
+	// Volatility is the asset's annualized volatility (e.g., 0.6 for 60%)
+	Volatility primitives.Decimal
+
+	// ExpectedReturn is the estimated annualized excess return of the position
+	ExpectedReturn primitives.Decimal
+
+	// WinProbability is the probability of a winning trade, in [0, 1]
+	WinProbability primitives.Decimal
+
+	// PayoffRatio is the average win divided by the average loss
+	PayoffRatio primitives.Decimal
+}
+
+// Sizer computes a position quantity from sizing parameters.
+type Sizer interface {
+	// Size returns the quantity of the asset to hold.
+	// Returns ErrInvalidParams if required inputs are missing.
+	Size(params Params) (primitives.Decimal, error)
+}
+
+// quantityForNotional converts a notional value into asset units at price.
+func quantityForNotional(notional primitives.Decimal, price primitives.Price) (primitives.Decimal, error) {
+	if price.IsZero() {
+		return primitives.Zero(), fmt.Errorf("%w: price must be positive", ErrInvalidParams)
+	}
+	if !notional.IsPositive() {
+		return primitives.Zero(), nil
+	}
+	return notional.Div(price.Decimal())
+}
+
+// capFraction limits fraction to [0, max]. A zero max disables the cap.
+func capFraction(fraction, max primitives.Decimal) primitives.Decimal {
+	if fraction.IsNegative() {
+		return primitives.Zero()
+	}
+	if max.IsPositive() && fraction.GreaterThan(max) {
+		return max
+	}
+	return fraction
+}
+
+// FixedFractional allocates a constant fraction of equity to the position.
+//
+// Quantity = Equity * Fraction / Price
+//
+// Requires: Equity, Price.
+type FixedFractional struct {
+	// Fraction of equity to allocate (e.g., 0.1 for 10%)
+	Fraction primitives.Decimal
+}
+
+// NewFixedFractional creates a fixed-fractional sizer.
+// Returns error if fraction is negative.
+func NewFixedFractional(fraction primitives.Decimal) (*FixedFractional, error) {
+	if fraction.IsNegative() {
+		return nil, fmt.Errorf("%w: fraction cannot be negative", ErrInvalidParams)
+	}
+	return &FixedFractional{Fraction: fraction}, nil
+}
+
+// Size returns the quantity for a fixed fraction of equity.
+func (f *FixedFractional) Size(params Params) (primitives.Decimal, error) {
+	return quantityForNotional(params.Equity.Decimal().Mul(f.Fraction), params.Price)
+}
+
+// VolatilityTarget scales exposure so the position contributes a target
+// annualized volatility to the portfolio.
+//
+// Leverage = min(TargetVolatility / Volatility, MaxLeverage)
+// Quantity = Equity * Leverage / Price
+//
+// Requires: Equity, Price, Volatility.
+type VolatilityTarget struct {
+	// TargetVolatility is the desired annualized volatility (e.g., 0.2 for 20%)
+	TargetVolatility primitives.Decimal
+
+	// MaxLeverage caps exposure as a multiple of equity; zero disables the cap
+	MaxLeverage primitives.Decimal
+}
+
+// NewVolatilityTarget creates a volatility-targeting sizer.
+// Returns error if target volatility is not positive or max leverage is negative.
+func NewVolatilityTarget(targetVolatility, maxLeverage primitives.Decimal) (*VolatilityTarget, error) {
+	if !targetVolatility.IsPositive() {
+		return nil, fmt.Errorf("%w: target volatility must be positive", ErrInvalidParams)
+	}
+	if maxLeverage.IsNegative() {
+		return nil, fmt.Errorf("%w: max leverage cannot be negative", ErrInvalidParams)
+	}
+	return &VolatilityTarget{
+		TargetVolatility: targetVolatility,
+		MaxLeverage:      maxLeverage,
+	}, nil
+}
+
+// Size returns the quantity that targets the configured volatility.
+func (v *VolatilityTarget) Size(params Params) (primitives.Decimal, error) {
+	if !params.Volatility.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: volatility must be positive", ErrInvalidParams)
+	}
+	leverage, err := v.TargetVolatility.Div(params.Volatility)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	leverage = capFraction(leverage, v.MaxLeverage)
+	return quantityForNotional(params.Equity.Decimal().Mul(leverage), params.Price)
+}
+
+// Kelly sizes positions using a fraction of the Kelly criterion.
+//
+// When WinProbability and PayoffRatio are set (discrete bets):
+//
+//	f* = p - (1 - p) / b
+//
+// Otherwise, from ExpectedReturn and Volatility (continuous returns):
+//
+//	f* = mu / sigma²
+//
+// Quantity = Equity * min(Multiplier * f*, MaxFraction) / Price
+//
+// Negative Kelly fractions (no edge) size to zero.
+type Kelly struct {
+	// Multiplier scales the full Kelly fraction (e.g., 0.5 for half-Kelly)
+	Multiplier primitives.Decimal
+
+	// MaxFraction caps the allocation as a multiple of equity; zero disables the cap
+	MaxFraction primitives.Decimal
+}
+
+// NewKelly creates a fractional Kelly sizer.
+// Returns error if multiplier is not positive or max fraction is negative.
+func NewKelly(multiplier, maxFraction primitives.Decimal) (*Kelly, error) {
+	if !multiplier.IsPositive() {
+		return nil, fmt.Errorf("%w: kelly multiplier must be positive", ErrInvalidParams)
+	}
+	if maxFraction.IsNegative() {
+		return nil, fmt.Errorf("%w: max fraction cannot be negative", ErrInvalidParams)
+	}
+	return &Kelly{
+		Multiplier:  multiplier,
+		MaxFraction: maxFraction,
+	}, nil
+}
+
+// Size returns the quantity for the fractional Kelly allocation.
+func (k *Kelly) Size(params Params) (primitives.Decimal, error) {
+	fraction, err := k.Fraction(params)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return quantityForNotional(params.Equity.Decimal().Mul(fraction), params.Price)
+}
+
+// Fraction returns the capped fractional Kelly allocation as a multiple of equity.
+func (k *Kelly) Fraction(params Params) (primitives.Decimal, error) {
+	var full primitives.Decimal
+	var err error
+	if !params.PayoffRatio.IsZero() {
+		full, err = KellyDiscrete(params.WinProbability, params.PayoffRatio)
+	} else {
+		full, err = KellyContinuous(params.ExpectedReturn, params.Volatility)
+	}
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return capFraction(full.Mul(k.Multiplier), k.MaxFraction), nil
+}
+
+// KellyDiscrete returns the full Kelly fraction for a bet that wins with
+// probability p and pays b times the amount risked: f* = p - (1 - p) / b.
+func KellyDiscrete(winProbability, payoffRatio primitives.Decimal) (primitives.Decimal, error) {
+	if winProbability.IsNegative() || winProbability.GreaterThan(primitives.One()) {
+		return primitives.Zero(), fmt.Errorf("%w: win probability must be in [0, 1]", ErrInvalidParams)
+	}
+	if !payoffRatio.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: payoff ratio must be positive", ErrInvalidParams)
+	}
+	lossTerm, err := primitives.One().Sub(winProbability).Div(payoffRatio)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return winProbability.Sub(lossTerm), nil
+}
+
+// KellyContinuous returns the full Kelly leverage for a position with
+// annualized excess return mu and volatility sigma: f* = mu / sigma².
+func KellyContinuous(expectedReturn, volatility primitives.Decimal) (primitives.Decimal, error) {
+	if !volatility.IsPositive() {
+		return primitives.Zero(), fmt.Errorf("%w: volatility must be positive", ErrInvalidParams)
+	}
+	return expectedReturn.Div(volatility.Mul(volatility))
+}
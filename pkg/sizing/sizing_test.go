@@ -0,0 +1,229 @@
+package sizing_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/analytics"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/sizing"
+)
+
+const tolerance = 1e-9
+
+func d(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func baseParams() sizing.Params {
+	return sizing.Params{
+		Equity: primitives.MustAmount(primitives.NewDecimal(100000)),
+		Price:  primitives.MustPrice(primitives.NewDecimal(2000)),
+	}
+}
+
+func assertSize(t *testing.T, got primitives.Decimal, want float64) {
+	t.Helper()
+	if math.Abs(got.Float64()-want) > tolerance {
+		t.Errorf("expected size %f, got %s", want, got.String())
+	}
+}
+
+func TestFixedFractional(t *testing.T) {
+	sizer, err := sizing.NewFixedFractional(d("0.1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	size, err := sizer.Size(baseParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 10% of 100k at 2000 = 5 units
+	assertSize(t, size, 5)
+
+	if _, err := sizing.NewFixedFractional(d("-0.1")); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams, got %v", err)
+	}
+
+	params := baseParams()
+	params.Price = primitives.ZeroPrice()
+	if _, err := sizer.Size(params); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for zero price, got %v", err)
+	}
+}
+
+func TestVolatilityTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxLeverage string
+		volatility  string
+		want        float64
+	}{
+		// 20% / 80% = 0.25x → 25k notional → 12.5 units
+		{"high vol scales down", "0", "0.8", 12.5},
+		// 20% / 10% = 2x → 200k notional → 100 units
+		{"low vol scales up", "0", "0.1", 100},
+		// 2x capped at 1.5x → 150k notional → 75 units
+		{"leverage cap", "1.5", "0.1", 75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizer, err := sizing.NewVolatilityTarget(d("0.2"), d(tt.maxLeverage))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			params := baseParams()
+			params.Volatility = d(tt.volatility)
+			size, err := sizer.Size(params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertSize(t, size, tt.want)
+		})
+	}
+
+	sizer, _ := sizing.NewVolatilityTarget(d("0.2"), primitives.Zero())
+	if _, err := sizer.Size(baseParams()); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for missing volatility, got %v", err)
+	}
+	if _, err := sizing.NewVolatilityTarget(primitives.Zero(), primitives.Zero()); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for zero target, got %v", err)
+	}
+}
+
+func TestKellyFormulas(t *testing.T) {
+	// p = 0.6, b = 1 → f* = 0.6 - 0.4 = 0.2
+	f, err := sizing.KellyDiscrete(d("0.6"), d("1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSize(t, f, 0.2)
+
+	// mu = 0.1, sigma = 0.5 → f* = 0.1 / 0.25 = 0.4
+	f, err = sizing.KellyContinuous(d("0.1"), d("0.5"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSize(t, f, 0.4)
+
+	if _, err := sizing.KellyDiscrete(d("1.2"), d("1")); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for probability > 1, got %v", err)
+	}
+	if _, err := sizing.KellyContinuous(d("0.1"), primitives.Zero()); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for zero volatility, got %v", err)
+	}
+}
+
+func TestKellySizer(t *testing.T) {
+	halfKelly, err := sizing.NewKelly(d("0.5"), primitives.Zero())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("discrete edge", func(t *testing.T) {
+		params := baseParams()
+		params.WinProbability = d("0.6")
+		params.PayoffRatio = d("1")
+		size, err := halfKelly.Size(params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// 0.5 * 0.2 = 10% of equity → 5 units
+		assertSize(t, size, 5)
+	})
+
+	t.Run("continuous edge with cap", func(t *testing.T) {
+		capped, _ := sizing.NewKelly(primitives.One(), d("0.3"))
+		params := baseParams()
+		params.ExpectedReturn = d("0.1")
+		params.Volatility = d("0.5")
+		size, err := capped.Size(params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// 0.4 capped to 0.3 → 30k → 15 units
+		assertSize(t, size, 15)
+	})
+
+	t.Run("negative edge sizes to zero", func(t *testing.T) {
+		params := baseParams()
+		params.WinProbability = d("0.3")
+		params.PayoffRatio = d("1")
+		size, err := halfKelly.Size(params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !size.IsZero() {
+			t.Errorf("expected zero size, got %s", size.String())
+		}
+	})
+
+	if _, err := sizing.NewKelly(primitives.Zero(), primitives.Zero()); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for zero multiplier, got %v", err)
+	}
+}
+
+func TestSizerInterface(t *testing.T) {
+	ff, _ := sizing.NewFixedFractional(d("0.1"))
+	vt, _ := sizing.NewVolatilityTarget(d("0.2"), primitives.Zero())
+	k, _ := sizing.NewKelly(d("0.5"), primitives.Zero())
+	for _, s := range []sizing.Sizer{ff, vt, k} {
+		if s == nil {
+			t.Error("sizer should not be nil")
+		}
+	}
+}
+
+func TestRiskParityWeights(t *testing.T) {
+	// Uncorrelated: weights are inverse volatility (20%, 40% → 2/3, 1/3)
+	uncorrelated, _ := analytics.NewCovariance([]string{"BTC/USD", "ETH/USD"}, [][]primitives.Decimal{
+		{d("0.04"), d("0")},
+		{d("0"), d("0.16")},
+	})
+	weights, err := sizing.RiskParityWeights(uncorrelated)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertWeight := func(got primitives.Decimal, want float64) {
+		t.Helper()
+		if math.Abs(got.Float64()-want) > 1e-6 {
+			t.Errorf("expected weight %f, got %s", want, got)
+		}
+	}
+	assertWeight(weights["BTC/USD"], 2.0/3)
+	assertWeight(weights["ETH/USD"], 1.0/3)
+
+	// Correlated: every asset contributes the same share of variance
+	pairs := []string{"BTC/USD", "ETH/USD", "SOL/USD"}
+	correlated, _ := analytics.NewCovariance(pairs, [][]primitives.Decimal{
+		{d("0.36"), d("0.30"), d("0.20")},
+		{d("0.30"), d("0.64"), d("0.40")},
+		{d("0.20"), d("0.40"), d("1.00")},
+	})
+	weights, err = sizing.RiskParityWeights(correlated)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	matrix := correlated.Matrix()
+	var total float64
+	contributions := make([]float64, len(pairs))
+	for i, a := range pairs {
+		total += weights[a].Float64()
+		for j, b := range pairs {
+			contributions[i] += weights[a].Float64() * matrix[i][j].Float64() * weights[b].Float64()
+		}
+	}
+	assertWeight(primitives.NewDecimalFromFloat(total), 1)
+	for i := 1; i < len(contributions); i++ {
+		if math.Abs(contributions[i]-contributions[0]) > 1e-9 {
+			t.Errorf("risk contributions differ: %v", contributions)
+		}
+	}
+
+	flat, _ := analytics.NewCovariance([]string{"USDC/USD"}, [][]primitives.Decimal{{primitives.Zero()}})
+	if _, err := sizing.RiskParityWeights(flat); !errors.Is(err, sizing.ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for zero variance, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package snapshotkeys builds namespaced MarketSnapshot metadata keys.
+//
+// Metadata keys follow the convention "<namespace>:<id>:<field>". Building
+// them through these functions instead of string literals keeps producers
+// (data loaders) and consumers (strategies, positions) in agreement and
+// turns typos into compile errors.
+//
+// Example:
+//
+//	snapshot.Set(snapshotkeys.PoolSqrtPrice("eth-usdc"), "1584563250000000000000000000000")
+//	sqrtPrice, err := strategy.GetString(snapshot, snapshotkeys.PoolSqrtPrice("eth-usdc"))
+package snapshotkeys
+
+import "strings"
+
+// Namespaces used by the built-in key builders.
+const (
+	// NamespacePool holds liquidity pool state keyed by pool ID
+	NamespacePool = "pool"
+
+	// NamespacePerp holds perpetual futures data keyed by market symbol
+	NamespacePerp = "perp"
+
+	// NamespaceOption holds option market data keyed by underlying or instrument
+	NamespaceOption = "option"
+
+	// NamespaceOracle holds oracle feed data keyed by pair
+	NamespaceOracle = "oracle"
+
+	// NamespaceRates holds interest-rate data keyed by currency
+	NamespaceRates = "rates"
+
+	// NamespaceMarket holds traded-market activity keyed by pair
+	NamespaceMarket = "market"
+
+	// NamespaceState holds mechanism state tracked across a run, keyed by
+	// pool or venue ID
+	NamespaceState = "state"
+)
+
+// Key joins a namespace, identifier and field into a metadata key.
+// Use it for custom namespaces not covered by the helpers below.
+func Key(namespace, id, field string) string {
+	return strings.Join([]string{namespace, id, field}, ":")
+}
+
+// PoolSqrtPrice is the key for a pool's sqrt price in Q64.96 format (string).
+func PoolSqrtPrice(poolID string) string {
+	return Key(NamespacePool, poolID, "sqrt_price_x96")
+}
+
+// PoolCurrentTick is the key for a pool's current tick (int).
+func PoolCurrentTick(poolID string) string {
+	return Key(NamespacePool, poolID, "current_tick")
+}
+
+// PoolLiquidity is the key for a pool's active liquidity (string or decimal).
+func PoolLiquidity(poolID string) string {
+	return Key(NamespacePool, poolID, "liquidity")
+}
+
+// PoolReserveA is the key for a pool's reserve of token A (decimal).
+func PoolReserveA(poolID string) string {
+	return Key(NamespacePool, poolID, "reserve_a")
+}
+
+// PoolReserveB is the key for a pool's reserve of token B (decimal).
+func PoolReserveB(poolID string) string {
+	return Key(NamespacePool, poolID, "reserve_b")
+}
+
+// PoolTickLiquidity is the key for a pool's liquidity by tick
+// (*concentrated_liquidity.LiquidityDistribution).
+func PoolTickLiquidity(poolID string) string {
+	return Key(NamespacePool, poolID, "tick_liquidity")
+}
+
+// PoolVolume is the key for a pool's trading volume over the snapshot period (decimal).
+func PoolVolume(poolID string) string {
+	return Key(NamespacePool, poolID, "volume")
+}
+
+// PoolSwapCount is the key for the number of swaps in a pool over the
+// snapshot period (int).
+func PoolSwapCount(poolID string) string {
+	return Key(NamespacePool, poolID, "swap_count")
+}
+
+// MechanismState is the key for the tracked state of a pool or venue
+// (strategy.MechanismState); see strategy.StateOf.
+func MechanismState(id string) string {
+	return Key(NamespaceState, id, "current")
+}
+
+// PerpFundingRate is the key for a perpetual's funding rate per period (decimal).
+func PerpFundingRate(symbol string) string {
+	return Key(NamespacePerp, symbol, "funding_rate")
+}
+
+// PerpMarkPrice is the key for a perpetual's mark price (decimal).
+func PerpMarkPrice(symbol string) string {
+	return Key(NamespacePerp, symbol, "mark_price")
+}
+
+// PerpIndexPrice is the key for a perpetual's index price (decimal).
+func PerpIndexPrice(symbol string) string {
+	return Key(NamespacePerp, symbol, "index_price")
+}
+
+// OptionImpliedVol is the key for an option market's implied volatility (decimal).
+func OptionImpliedVol(underlying string) string {
+	return Key(NamespaceOption, underlying, "implied_vol")
+}
+
+// OptionChain is the key for the option chain listed on an underlying
+// (*marketdata.OptionChain).
+func OptionChain(underlying string) string {
+	return Key(NamespaceOption, underlying, "chain")
+}
+
+// OptionSurface is the key for an underlying's implied volatility surface
+// (*marketdata.VolSurface).
+func OptionSurface(underlying string) string {
+	return Key(NamespaceOption, underlying, "surface")
+}
+
+// OraclePublishTime is the key for when an oracle last published pair's
+// price (primitives.Time).
+func OraclePublishTime(pair string) string {
+	return Key(NamespaceOracle, pair, "publish_time")
+}
+
+// OracleConfidence is the key for the half-width of an oracle's confidence
+// interval around pair's price (decimal; zero for feeds without one).
+func OracleConfidence(pair string) string {
+	return Key(NamespaceOracle, pair, "confidence")
+}
+
+// OracleRound is the key for the oracle round or update sequence number
+// behind pair's price (uint64).
+func OracleRound(pair string) string {
+	return Key(NamespaceOracle, pair, "round")
+}
+
+// RateCurve is the key for a currency's zero-rate term structure
+// (*rates.Curve).
+func RateCurve(currency string) string {
+	return Key(NamespaceRates, currency, "curve")
+}
+
+// RateCashYield is the key for the annual yield earned on idle cash in
+// currency, such as an sDAI or T-bill rate, as a fraction (decimal).
+func RateCashYield(currency string) string {
+	return Key(NamespaceRates, currency, "cash_yield")
+}
+
+// RateBorrow is the key for the annual rate charged on cash borrowed in
+// currency, as a fraction (decimal).
+func RateBorrow(currency string) string {
+	return Key(NamespaceRates, currency, "borrow")
+}
+
+// LiquidationVolume is the key for the size of pair's forced liquidations
+// over the snapshot period (decimal).
+func LiquidationVolume(pair string) string {
+	return Key(NamespaceMarket, pair, "liquidation_volume")
+}
@@ -0,0 +1,46 @@
+package snapshotkeys_test
+
+import (
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"custom", snapshotkeys.Key("lending", "aave", "supply_rate"), "lending:aave:supply_rate"},
+		{"pool sqrt price", snapshotkeys.PoolSqrtPrice("eth-usdc-pool"), "pool:eth-usdc-pool:sqrt_price_x96"},
+		{"pool tick", snapshotkeys.PoolCurrentTick("eth-usdc-pool"), "pool:eth-usdc-pool:current_tick"},
+		{"pool liquidity", snapshotkeys.PoolLiquidity("eth-usdc-pool"), "pool:eth-usdc-pool:liquidity"},
+		{"pool reserve a", snapshotkeys.PoolReserveA("eth-usdc-pool"), "pool:eth-usdc-pool:reserve_a"},
+		{"pool reserve b", snapshotkeys.PoolReserveB("eth-usdc-pool"), "pool:eth-usdc-pool:reserve_b"},
+		{"pool tick liquidity", snapshotkeys.PoolTickLiquidity("eth-usdc-pool"), "pool:eth-usdc-pool:tick_liquidity"},
+		{"pool volume", snapshotkeys.PoolVolume("eth-usdc-pool"), "pool:eth-usdc-pool:volume"},
+		{"pool swap count", snapshotkeys.PoolSwapCount("eth-usdc-pool"), "pool:eth-usdc-pool:swap_count"},
+		{"mechanism state", snapshotkeys.MechanismState("eth-usdc-pool"), "state:eth-usdc-pool:current"},
+		{"perp funding", snapshotkeys.PerpFundingRate("eth"), "perp:eth:funding_rate"},
+		{"perp mark", snapshotkeys.PerpMarkPrice("eth"), "perp:eth:mark_price"},
+		{"perp index", snapshotkeys.PerpIndexPrice("eth"), "perp:eth:index_price"},
+		{"option iv", snapshotkeys.OptionImpliedVol("ETH"), "option:ETH:implied_vol"},
+		{"option chain", snapshotkeys.OptionChain("ETH/USD"), "option:ETH/USD:chain"},
+		{"option surface", snapshotkeys.OptionSurface("ETH/USD"), "option:ETH/USD:surface"},
+		{"oracle publish time", snapshotkeys.OraclePublishTime("ETH/USD"), "oracle:ETH/USD:publish_time"},
+		{"oracle confidence", snapshotkeys.OracleConfidence("ETH/USD"), "oracle:ETH/USD:confidence"},
+		{"oracle round", snapshotkeys.OracleRound("ETH/USD"), "oracle:ETH/USD:round"},
+		{"rate curve", snapshotkeys.RateCurve("USD"), "rates:USD:curve"},
+		{"cash yield", snapshotkeys.RateCashYield("USD"), "rates:USD:cash_yield"},
+		{"borrow rate", snapshotkeys.RateBorrow("USD"), "rates:USD:borrow"},
+		{"liquidation volume", snapshotkeys.LiquidationVolume("ETH/USD"), "market:ETH/USD:liquidation_volume"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, tt.got)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package statarb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Engle-Granger critical values for the residual unit-root statistic with
+// two variables and a constant (MacKinnon, 2010; asymptotic). A statistic
+// below the critical value rejects "no cointegration" at that level.
+var (
+	CriticalValue1  = primitives.MustDecimalFromString("-3.90")
+	CriticalValue5  = primitives.MustDecimalFromString("-3.34")
+	CriticalValue10 = primitives.MustDecimalFromString("-3.04")
+)
+
+// minCointegrationObservations is the shortest series EngleGranger accepts.
+const minCointegrationObservations = 10
+
+// Cointegration is the result of an Engle-Granger test.
+type Cointegration struct {
+	// Hedge is the OLS fit of Y on X over the whole sample
+	Hedge HedgeRatio
+
+	// Statistic is the Dickey-Fuller t-statistic of the fit's residuals;
+	// more negative is stronger evidence of mean reversion
+	Statistic primitives.Decimal
+
+	// HalfLife is the residuals' mean-reversion half-life in observations,
+	// −ln 2 / ln(1 + γ) for Δeₜ = γ·eₜ₋₁; zero if they do not revert
+	HalfLife primitives.Decimal
+}
+
+// Cointegrated reports whether the test rejects "no cointegration" at
+// the 5% level.
+func (c Cointegration) Cointegrated() bool {
+	return c.Statistic.LessThan(CriticalValue5)
+}
+
+// EngleGranger runs the two-step Engle-Granger cointegration test on
+// equally spaced price series y and x: regress y on x, then test the
+// residuals for a unit root with a Dickey-Fuller regression (no lags).
+//
+// Use it offline to screen candidate pairs; a pair that is not cointegrated
+// has no reason for its spread to revert.
+//
+// Returns ErrInsufficientData for fewer than 10 observations or a flat
+// series, and ErrInvalidConfig if the series lengths differ.
+func EngleGranger(y, x []primitives.Decimal) (Cointegration, error) {
+	if len(y) != len(x) {
+		return Cointegration{}, fmt.Errorf("%w: series lengths %d and %d differ", ErrInvalidConfig, len(y), len(x))
+	}
+	if len(y) < minCointegrationObservations {
+		return Cointegration{}, fmt.Errorf("%w: need at least %d observations", ErrInsufficientData, minCointegrationObservations)
+	}
+	ys, xs := make([]float64, len(y)), make([]float64, len(x))
+	for i := range y {
+		ys[i], xs[i] = y[i].Float64(), x[i].Float64()
+	}
+	ratio, intercept, ok := ols(ys, xs)
+	if !ok {
+		return Cointegration{}, fmt.Errorf("%w: X is flat", ErrInsufficientData)
+	}
+
+	residuals := make([]float64, len(ys))
+	for i := range ys {
+		residuals[i] = ys[i] - intercept - ratio*xs[i]
+	}
+
+	// Δeₜ = γ·eₜ₋₁ + uₜ, through the origin since residuals have zero mean
+	var sxy, sxx float64
+	for t := 1; t < len(residuals); t++ {
+		sxy += residuals[t-1] * (residuals[t] - residuals[t-1])
+		sxx += residuals[t-1] * residuals[t-1]
+	}
+	if sxx == 0 {
+		return Cointegration{}, fmt.Errorf("%w: Y is an exact linear function of X", ErrInsufficientData)
+	}
+	gamma := sxy / sxx
+	var sse float64
+	for t := 1; t < len(residuals); t++ {
+		u := residuals[t] - residuals[t-1] - gamma*residuals[t-1]
+		sse += u * u
+	}
+	if sse == 0 {
+		return Cointegration{}, fmt.Errorf("%w: residuals are deterministic", ErrInsufficientData)
+	}
+	dof := float64(len(residuals) - 2)
+	statistic := gamma / math.Sqrt(sse/dof/sxx)
+
+	halfLife := 0.0
+	if gamma < 0 && gamma > -1 {
+		halfLife = -math.Ln2 / math.Log(1+gamma)
+	}
+	return Cointegration{
+		Hedge: HedgeRatio{
+			Ratio:     primitives.NewDecimalFromFloat(ratio),
+			Intercept: primitives.NewDecimalFromFloat(intercept),
+		},
+		Statistic: primitives.NewDecimalFromFloat(statistic),
+		HalfLife:  primitives.NewDecimalFromFloat(halfLife),
+	}, nil
+}
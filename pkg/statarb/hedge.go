@@ -0,0 +1,206 @@
+// Package statarb provides building blocks for statistical arbitrage:
+// hedge-ratio estimation between two assets (rolling OLS or a Kalman
+// filter), a spread z-score signal with entry/exit bands, and an
+// Engle-Granger cointegration test for screening candidate pairs.
+//
+// The spread of a pair Y, X is Y − (Intercept + Ratio·X). A pairs strategy
+// buys the spread (long Y, short Ratio·X) when its z-score is well below
+// zero and sells it when well above, betting on mean reversion.
+//
+// Like the analytics package, these components observe data; strategies
+// turn their output into positions.
+package statarb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrInsufficientData indicates not enough observations for an estimate
+	ErrInsufficientData = errors.New("insufficient data")
+
+	// ErrInvalidConfig indicates a component was configured incorrectly
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// HedgeRatio is a linear fit Y ≈ Intercept + Ratio·X.
+type HedgeRatio struct {
+	// Ratio is the units of X that hedge one unit of Y
+	Ratio primitives.Decimal
+
+	// Intercept is the fitted level of Y when X is zero
+	Intercept primitives.Decimal
+}
+
+// Spread returns y − (Intercept + Ratio·x).
+func (h HedgeRatio) Spread(y, x primitives.Decimal) primitives.Decimal {
+	return y.Sub(h.Intercept.Add(h.Ratio.Mul(x)))
+}
+
+// HedgeRatioEstimator estimates a hedge ratio from a stream of paired
+// observations.
+//
+// Thread Safety: Implementations are not required to be thread-safe.
+type HedgeRatioEstimator interface {
+	// Update feeds the next (y, x) observation.
+	Update(y, x primitives.Decimal)
+
+	// HedgeRatio returns the current estimate.
+	// Returns ErrInsufficientData until enough observations have been seen.
+	HedgeRatio() (HedgeRatio, error)
+}
+
+// RollingOLS fits Y on X by ordinary least squares over the last Window
+// observations.
+type RollingOLS struct {
+	window int
+	ys, xs []float64
+}
+
+// NewRollingOLS creates a rolling OLS estimator over window observations.
+// Returns ErrInvalidConfig if window is less than 3.
+func NewRollingOLS(window int) (*RollingOLS, error) {
+	if window < 3 {
+		return nil, fmt.Errorf("%w: OLS window must be at least 3", ErrInvalidConfig)
+	}
+	return &RollingOLS{
+		window: window,
+		ys:     make([]float64, 0, window+1),
+		xs:     make([]float64, 0, window+1),
+	}, nil
+}
+
+// Update implements HedgeRatioEstimator.
+func (o *RollingOLS) Update(y, x primitives.Decimal) {
+	o.ys = append(o.ys, y.Float64())
+	o.xs = append(o.xs, x.Float64())
+	if len(o.ys) > o.window {
+		o.ys, o.xs = o.ys[1:], o.xs[1:]
+	}
+}
+
+// HedgeRatio implements HedgeRatioEstimator.
+// Returns ErrInsufficientData until the window is full or while X is flat.
+func (o *RollingOLS) HedgeRatio() (HedgeRatio, error) {
+	if len(o.ys) < o.window {
+		return HedgeRatio{}, ErrInsufficientData
+	}
+	ratio, intercept, ok := ols(o.ys, o.xs)
+	if !ok {
+		return HedgeRatio{}, fmt.Errorf("%w: X has no variance over the window", ErrInsufficientData)
+	}
+	return HedgeRatio{
+		Ratio:     primitives.NewDecimalFromFloat(ratio),
+		Intercept: primitives.NewDecimalFromFloat(intercept),
+	}, nil
+}
+
+// KalmanFilter tracks a time-varying hedge ratio and intercept as a random
+// walk observed through Y = Intercept + Ratio·X + noise. Unlike RollingOLS
+// it adapts continuously instead of dropping observations off a window.
+//
+// Delta in (0, 1) sets how fast the state may drift: the state noise
+// covariance is Delta/(1−Delta)·I. Small values (e.g., 1e-5) give a slowly
+// moving ratio; larger values track regime changes faster but are noisier.
+// ObservationVariance is the variance of Y around the fit.
+type KalmanFilter struct {
+	drift       float64
+	observation float64
+
+	// state is [ratio, intercept] with covariance p
+	state [2]float64
+	p     [2][2]float64
+	count int
+}
+
+// kalmanWarmup is the number of observations before a KalmanFilter reports
+// an estimate; the first only initializes the state.
+const kalmanWarmup = 2
+
+// NewKalmanFilter creates a Kalman filter hedge-ratio estimator.
+// Returns ErrInvalidConfig unless 0 < delta < 1 and observationVariance > 0.
+func NewKalmanFilter(delta, observationVariance primitives.Decimal) (*KalmanFilter, error) {
+	if !delta.IsPositive() || !delta.LessThan(primitives.One()) {
+		return nil, fmt.Errorf("%w: delta must be in (0, 1)", ErrInvalidConfig)
+	}
+	if !observationVariance.IsPositive() {
+		return nil, fmt.Errorf("%w: observation variance must be positive", ErrInvalidConfig)
+	}
+	d := delta.Float64()
+	return &KalmanFilter{
+		drift:       d / (1 - d),
+		observation: observationVariance.Float64(),
+	}, nil
+}
+
+// Update implements HedgeRatioEstimator.
+func (k *KalmanFilter) Update(y, x primitives.Decimal) {
+	h := [2]float64{x.Float64(), 1}
+	if k.count == 0 && h[0] != 0 {
+		// Start from the zero-intercept fit through the first observation,
+		// with a prior wide enough for either term to absorb the level
+		ratio := y.Float64() / h[0]
+		k.state = [2]float64{ratio, 0}
+		k.p = [2][2]float64{{ratio * ratio, 0}, {0, y.Float64() * y.Float64()}}
+		k.count++
+		return
+	}
+
+	// Predict: the state is a random walk, so only its covariance grows
+	var r [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			r[i][j] = k.p[i][j]
+		}
+		r[i][i] += k.drift
+	}
+
+	// Update with the innovation y − h·state
+	innovation := y.Float64() - (h[0]*k.state[0] + h[1]*k.state[1])
+	rh := [2]float64{r[0][0]*h[0] + r[0][1]*h[1], r[1][0]*h[0] + r[1][1]*h[1]}
+	q := h[0]*rh[0] + h[1]*rh[1] + k.observation
+	gain := [2]float64{rh[0] / q, rh[1] / q}
+	for i := 0; i < 2; i++ {
+		k.state[i] += gain[i] * innovation
+		for j := 0; j < 2; j++ {
+			k.p[i][j] = r[i][j] - gain[i]*rh[j]
+		}
+	}
+	k.count++
+}
+
+// HedgeRatio implements HedgeRatioEstimator.
+func (k *KalmanFilter) HedgeRatio() (HedgeRatio, error) {
+	if k.count < kalmanWarmup {
+		return HedgeRatio{}, ErrInsufficientData
+	}
+	return HedgeRatio{
+		Ratio:     primitives.NewDecimalFromFloat(k.state[0]),
+		Intercept: primitives.NewDecimalFromFloat(k.state[1]),
+	}, nil
+}
+
+// ols returns the least-squares slope and intercept of ys on xs, and false
+// if xs has no variance.
+func ols(ys, xs []float64) (slope, intercept float64, ok bool) {
+	n := float64(len(xs))
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i] / n
+		my += ys[i] / n
+	}
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if sxx == 0 || math.IsNaN(sxx) {
+		return 0, 0, false
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx, true
+}
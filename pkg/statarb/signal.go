@@ -0,0 +1,203 @@
+package statarb
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// SignalConfig configures a SpreadSignal.
+type SignalConfig struct {
+	// Y and X are the snapshot price keys of the pair (e.g., "ETH/USD"
+	// hedged with "BTC/USD")
+	Y, X string
+
+	// Hedge estimates the hedge ratio from the pair's prices
+	Hedge HedgeRatioEstimator
+
+	// Window is the number of spread observations in the z-score
+	Window int
+}
+
+// Spread is a SpreadSignal reading.
+type Spread struct {
+	// Time is the snapshot time of the reading
+	Time primitives.Time
+
+	// Hedge is the hedge ratio used for this reading
+	Hedge HedgeRatio
+
+	// Value is Y − (Intercept + Ratio·X) at the snapshot prices
+	Value primitives.Decimal
+
+	// ZScore is Value's distance from the window mean in standard deviations
+	ZScore primitives.Decimal
+}
+
+// SpreadSignal tracks a pair's spread and its rolling z-score. Call Update
+// once per snapshot.
+//
+// Each snapshot first updates the hedge estimator, then measures the spread
+// with the refreshed ratio. The z-score compares that spread with the last
+// Window spreads, itself included, each measured with the ratio of its day.
+//
+// Thread Safety: SpreadSignal is not thread-safe.
+type SpreadSignal struct {
+	config  SignalConfig
+	spreads []primitives.Decimal
+	current Spread
+	ready   bool
+}
+
+// NewSpreadSignal creates a spread signal.
+// Returns ErrInvalidConfig if a pair is missing, Y equals X, there is no
+// hedge estimator or Window is less than 2.
+func NewSpreadSignal(config SignalConfig) (*SpreadSignal, error) {
+	if config.Y == "" || config.X == "" || config.Y == config.X {
+		return nil, fmt.Errorf("%w: Y and X must be distinct pairs", ErrInvalidConfig)
+	}
+	if config.Hedge == nil {
+		return nil, fmt.Errorf("%w: hedge estimator cannot be nil", ErrInvalidConfig)
+	}
+	if config.Window < 2 {
+		return nil, fmt.Errorf("%w: z-score window must be at least 2", ErrInvalidConfig)
+	}
+	return &SpreadSignal{
+		config:  config,
+		spreads: make([]primitives.Decimal, 0, config.Window+1),
+	}, nil
+}
+
+// Update records the snapshot prices and recomputes the spread.
+// Returns the new reading, or ErrInsufficientData while the hedge estimator
+// or z-score window is warming up.
+func (s *SpreadSignal) Update(snapshot strategy.MarketSnapshot) (Spread, error) {
+	y, err := snapshot.Price(s.config.Y)
+	if err != nil {
+		return Spread{}, fmt.Errorf("spread signal: failed to get price for %s: %w", s.config.Y, err)
+	}
+	x, err := snapshot.Price(s.config.X)
+	if err != nil {
+		return Spread{}, fmt.Errorf("spread signal: failed to get price for %s: %w", s.config.X, err)
+	}
+
+	s.config.Hedge.Update(y.Decimal(), x.Decimal())
+	hedge, err := s.config.Hedge.HedgeRatio()
+	if err != nil {
+		return Spread{}, err
+	}
+	value := hedge.Spread(y.Decimal(), x.Decimal())
+
+	s.spreads = append(s.spreads, value)
+	if len(s.spreads) > s.config.Window {
+		s.spreads = s.spreads[1:]
+	}
+	if len(s.spreads) < s.config.Window {
+		return Spread{}, ErrInsufficientData
+	}
+
+	mean, variance, err := primitives.MeanVariance(s.spreads)
+	if err != nil {
+		return Spread{}, err
+	}
+	z := primitives.Zero()
+	if std := variance.Sqrt(); !std.IsZero() {
+		if z, err = value.Sub(mean).Div(std); err != nil {
+			return Spread{}, err
+		}
+	}
+
+	s.current = Spread{Time: snapshot.Time(), Hedge: hedge, Value: value, ZScore: z}
+	s.ready = true
+	return s.current, nil
+}
+
+// Spread returns the latest reading.
+func (s *SpreadSignal) Spread() (Spread, error) {
+	if !s.ready {
+		return Spread{}, ErrInsufficientData
+	}
+	return s.current, nil
+}
+
+// Side is a pairs position: long, short or flat the spread.
+type Side int
+
+const (
+	// SideFlat holds neither leg
+	SideFlat Side = iota
+
+	// SideLong is long Y and short Ratio·X, profiting as the spread rises
+	SideLong
+
+	// SideShort is short Y and long Ratio·X, profiting as the spread falls
+	SideShort
+)
+
+// String returns the side name.
+func (s Side) String() string {
+	switch s {
+	case SideFlat:
+		return "flat"
+	case SideLong:
+		return "long"
+	case SideShort:
+		return "short"
+	default:
+		return fmt.Sprintf("Side(%d)", int(s))
+	}
+}
+
+// Band turns spread z-scores into target sides with hysteresis: open when
+// |z| reaches Entry, close once the spread reverts inside Exit or diverges
+// beyond Stop.
+type Band struct {
+	// Entry is the |z| at which a position opens (e.g., 2)
+	Entry primitives.Decimal
+
+	// Exit is the |z| inside which a position closes (e.g., 0.5)
+	Exit primitives.Decimal
+
+	// Stop is the |z| beyond which a position is abandoned; zero disables it
+	Stop primitives.Decimal
+}
+
+// Validate checks that 0 <= Exit < Entry and that Stop, when set, is
+// beyond Entry.
+func (b Band) Validate() error {
+	if b.Exit.IsNegative() || !b.Entry.GreaterThan(b.Exit) {
+		return fmt.Errorf("%w: band needs 0 <= exit < entry", ErrInvalidConfig)
+	}
+	if !b.Stop.IsZero() && !b.Stop.GreaterThan(b.Entry) {
+		return fmt.Errorf("%w: band stop must exceed entry", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Target returns the side to hold at z given the current side. A spread
+// below −Entry is bought and one above Entry is sold; from flat, a spread
+// already beyond Stop is left alone.
+func (b Band) Target(z primitives.Decimal, current Side) Side {
+	abs := z.Abs()
+	stopped := !b.Stop.IsZero() && !abs.LessThan(b.Stop)
+	switch current {
+	case SideLong:
+		if stopped || !z.LessThan(b.Exit.Neg()) {
+			return SideFlat
+		}
+		return SideLong
+	case SideShort:
+		if stopped || !z.GreaterThan(b.Exit) {
+			return SideFlat
+		}
+		return SideShort
+	}
+	if stopped || abs.LessThan(b.Entry) {
+		return SideFlat
+	}
+	if z.IsNegative() {
+		return SideLong
+	}
+	return SideShort
+}
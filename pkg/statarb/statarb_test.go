@@ -0,0 +1,197 @@
+package statarb_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/statarb"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func f(x float64) primitives.Decimal {
+	return primitives.NewDecimalFromFloat(x)
+}
+
+// cointegrated returns X as a random walk and Y = intercept + ratio·X plus
+// AR(1) noise with coefficient phi; phi = 1 makes the noise a random walk.
+func cointegrated(seed int64, n int, ratio, intercept, phi float64) (ys, xs []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	ys, xs = make([]float64, n), make([]float64, n)
+	x, noise := 40000.0, 0.0
+	for i := range xs {
+		x += 400 * rng.NormFloat64()
+		noise = phi*noise + 20*rng.NormFloat64()
+		xs[i] = x
+		ys[i] = intercept + ratio*x + noise
+	}
+	return ys, xs
+}
+
+func TestHedgeRatioEstimators(t *testing.T) {
+	ys, xs := cointegrated(1, 500, 0.05, 100, 0.8)
+
+	ols, err := statarb.NewRollingOLS(250)
+	if err != nil {
+		t.Fatalf("NewRollingOLS: %v", err)
+	}
+	kalman, err := statarb.NewKalmanFilter(dec("0.00001"), dec("400"))
+	if err != nil {
+		t.Fatalf("NewKalmanFilter: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		estimator statarb.HedgeRatioEstimator
+		warmup    int
+	}{
+		{"rolling ols", ols, 250},
+		{"kalman", kalman, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range ys {
+				if _, err := tt.estimator.HedgeRatio(); i < tt.warmup && !errors.Is(err, statarb.ErrInsufficientData) {
+					t.Fatalf("expected warm-up before observation %d, got %v", i, err)
+				}
+				tt.estimator.Update(f(ys[i]), f(xs[i]))
+			}
+			hedge, err := tt.estimator.HedgeRatio()
+			if err != nil {
+				t.Fatalf("HedgeRatio: %v", err)
+			}
+			if math.Abs(hedge.Ratio.Float64()-0.05) > 0.005 {
+				t.Errorf("ratio = %s, want about 0.05", hedge.Ratio)
+			}
+			// Residual spread should be the AR(1) noise, tens of dollars
+			last := len(ys) - 1
+			if spread := hedge.Spread(f(ys[last]), f(xs[last])); spread.Abs().Float64() > 200 {
+				t.Errorf("spread = %s, want within noise", spread)
+			}
+		})
+	}
+}
+
+func TestEngleGranger(t *testing.T) {
+	toDecimals := func(values []float64) []primitives.Decimal {
+		out := make([]primitives.Decimal, len(values))
+		for i, v := range values {
+			out[i] = f(v)
+		}
+		return out
+	}
+
+	ys, xs := cointegrated(2, 500, 0.05, 100, 0.8)
+	result, err := statarb.EngleGranger(toDecimals(ys), toDecimals(xs))
+	if err != nil {
+		t.Fatalf("EngleGranger: %v", err)
+	}
+	if !result.Cointegrated() {
+		t.Errorf("stationary spread should be cointegrated, statistic %s", result.Statistic)
+	}
+	// AR(1) with φ = 0.8 has half-life ln 2 / −ln 0.8 ≈ 3.1
+	if h := result.HalfLife.Float64(); h < 2 || h > 5 {
+		t.Errorf("half-life = %s, want about 3", result.HalfLife)
+	}
+
+	ys, xs = cointegrated(3, 500, 0.05, 100, 1)
+	result, err = statarb.EngleGranger(toDecimals(ys), toDecimals(xs))
+	if err != nil {
+		t.Fatalf("EngleGranger: %v", err)
+	}
+	if result.Cointegrated() {
+		t.Errorf("random-walk spread should not be cointegrated, statistic %s", result.Statistic)
+	}
+
+	if _, err := statarb.EngleGranger(toDecimals(ys[:5]), toDecimals(xs[:5])); !errors.Is(err, statarb.ErrInsufficientData) {
+		t.Errorf("expected %v, got %v", statarb.ErrInsufficientData, err)
+	}
+	if _, err := statarb.EngleGranger(toDecimals(ys[:20]), toDecimals(xs[:10])); !errors.Is(err, statarb.ErrInvalidConfig) {
+		t.Errorf("expected %v, got %v", statarb.ErrInvalidConfig, err)
+	}
+}
+
+func TestSpreadSignal(t *testing.T) {
+	ols, _ := statarb.NewRollingOLS(5)
+	signal, err := statarb.NewSpreadSignal(statarb.SignalConfig{Y: "ETH/USD", X: "BTC/USD", Hedge: ols, Window: 3})
+	if err != nil {
+		t.Fatalf("NewSpreadSignal: %v", err)
+	}
+	if _, err := signal.Spread(); !errors.Is(err, statarb.ErrInsufficientData) {
+		t.Errorf("expected %v before warm-up, got %v", statarb.ErrInsufficientData, err)
+	}
+
+	// ETH tracks 0.05 × BTC exactly, then jumps $100 rich on the last day
+	btc := []float64{40000, 41000, 39000, 42000, 40500, 41500, 40000, 43000}
+	var spread statarb.Spread
+	for i, x := range btc {
+		y := 0.05 * x
+		if i == len(btc)-1 {
+			y += 100
+		}
+		snapshot := strategy.NewSimpleSnapshot(primitives.NewTime(time.Unix(int64(i)*86400, 0)), map[string]primitives.Price{
+			"ETH/USD": primitives.MustPrice(f(y)),
+			"BTC/USD": primitives.MustPrice(f(x)),
+		})
+		spread, err = signal.Update(snapshot)
+		if i < 6 && !errors.Is(err, statarb.ErrInsufficientData) {
+			t.Fatalf("expected warm-up at %d, got %v", i, err)
+		}
+	}
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !spread.Value.IsPositive() || !spread.ZScore.GreaterThan(dec("1")) {
+		t.Errorf("rich ETH should give a positive spread and z-score, got %s (z %s)", spread.Value, spread.ZScore)
+	}
+
+	missing := strategy.NewSimpleSnapshot(primitives.NewTime(time.Unix(0, 0)), map[string]primitives.Price{})
+	if _, err := signal.Update(missing); !errors.Is(err, strategy.ErrPriceNotAvailable) {
+		t.Errorf("expected %v, got %v", strategy.ErrPriceNotAvailable, err)
+	}
+	if _, err := statarb.NewSpreadSignal(statarb.SignalConfig{Y: "ETH/USD", X: "ETH/USD", Hedge: ols, Window: 3}); !errors.Is(err, statarb.ErrInvalidConfig) {
+		t.Errorf("expected %v, got %v", statarb.ErrInvalidConfig, err)
+	}
+}
+
+func TestBandTarget(t *testing.T) {
+	band := statarb.Band{Entry: dec("2"), Exit: dec("0.5"), Stop: dec("4")}
+	if err := band.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	tests := []struct {
+		z       string
+		current statarb.Side
+		want    statarb.Side
+	}{
+		{"-2.5", statarb.SideFlat, statarb.SideLong},
+		{"2.5", statarb.SideFlat, statarb.SideShort},
+		{"1.5", statarb.SideFlat, statarb.SideFlat},
+		{"-1", statarb.SideLong, statarb.SideLong},
+		{"-0.4", statarb.SideLong, statarb.SideFlat},
+		{"0.6", statarb.SideShort, statarb.SideShort},
+		{"0.5", statarb.SideShort, statarb.SideFlat},
+		{"-4.5", statarb.SideLong, statarb.SideFlat},
+		{"5", statarb.SideFlat, statarb.SideFlat},
+	}
+	for _, tt := range tests {
+		if got := band.Target(dec(tt.z), tt.current); got != tt.want {
+			t.Errorf("Target(%s, %s) = %s, want %s", tt.z, tt.current, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []statarb.Band{
+		{Entry: dec("1"), Exit: dec("1")},
+		{Entry: dec("2"), Exit: dec("0.5"), Stop: dec("1.5")},
+	} {
+		if err := invalid.Validate(); !errors.Is(err, statarb.ErrInvalidConfig) {
+			t.Errorf("Validate(%+v) = %v, want %v", invalid, err, statarb.ErrInvalidConfig)
+		}
+	}
+}
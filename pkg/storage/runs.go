@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// RunSummary is the stored headline of a run: its metrics without the
+// value history and trade log.
+type RunSummary struct {
+	ID        string
+	CreatedAt primitives.Time
+
+	InitialValue      primitives.Amount
+	FinalValue        primitives.Amount
+	TotalReturn       primitives.Decimal
+	AnnualizedReturn  primitives.Decimal
+	Sharpe            primitives.Decimal
+	MaxDrawdown       primitives.Decimal
+	MaxDrawdownAmount primitives.Amount
+}
+
+// SaveResult stores result under runID: its metrics, its value history
+// (Result.History), its trade log (Result.Fills) and its experiment record
+// (Result.Experiment) when it has one. An empty runID takes the run ID of
+// the experiment record. A run already stored under runID is replaced.
+func (s *Store) SaveResult(ctx context.Context, runID string, result *backtest.Result) error {
+	if runID == "" && result.Experiment != nil {
+		runID = result.Experiment.RunID
+	}
+	if runID == "" {
+		return errors.New("run ID is required")
+	}
+	var experiment []byte
+	if result.Experiment != nil {
+		var err error
+		if experiment, err = json.Marshal(result.Experiment); err != nil {
+			return fmt.Errorf("failed to encode experiment of run %s: %w", runID, err)
+		}
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.deleteRun(ctx, tx, runID); err != nil {
+			return err
+		}
+		if err := s.exec(ctx, tx, `INSERT INTO runs (run_id, created_at, initial_value, final_value, total_return,
+			annualized_return, sharpe, max_drawdown, max_drawdown_amount) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, s.now().UnixNano(), result.InitialValue.String(), result.FinalValue.String(),
+			result.TotalReturn.String(), result.AnnualizedReturn.String(), result.Sharpe.String(),
+			result.MaxDrawdown.String(), result.MaxDrawdownAmount.String(),
+		); err != nil {
+			return err
+		}
+
+		history := result.History()
+		for i := 0; i < history.Len(); i++ {
+			if err := s.exec(ctx, tx, `INSERT INTO run_values (run_id, seq, time_ns, value) VALUES (?, ?, ?, ?)`,
+				runID, i, history.Time(i).UnixNano(), history.Value(i).String(),
+			); err != nil {
+				return err
+			}
+		}
+		if experiment != nil {
+			if err := s.exec(ctx, tx, `INSERT INTO run_experiments (run_id, data) VALUES (?, ?)`, runID, string(experiment)); err != nil {
+				return err
+			}
+		}
+		for i, fill := range result.Fills {
+			if err := s.exec(ctx, tx, `INSERT INTO run_fills (run_id, seq, order_id, pair, side, time_ns, quantity, price)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, i, fill.OrderID, fill.Pair, string(fill.Side), fill.Time.UnixNano(),
+				fill.Quantity.String(), fill.Price.String(),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save run %s: %w", runID, err)
+	}
+	return nil
+}
+
+// DeleteRun removes the run stored under runID. Returns ErrRunNotFound if
+// there is none.
+func (s *Store) DeleteRun(ctx context.Context, runID string) error {
+	if _, err := s.Run(ctx, runID); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.deleteRun(ctx, tx, runID)
+	})
+}
+
+func (s *Store) deleteRun(ctx context.Context, tx *sql.Tx, runID string) error {
+	for _, table := range []string{"runs", "run_values", "run_fills", "run_experiments"} {
+		if err := s.exec(ctx, tx, `DELETE FROM `+table+` WHERE run_id = ?`, runID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runColumns are the columns of runs scanned by scanRun, in order.
+const runColumns = `run_id, created_at, initial_value, final_value, total_return, annualized_return,
+	sharpe, max_drawdown, max_drawdown_amount`
+
+// Runs returns the summaries of all stored runs, oldest first.
+func (s *Store) Runs(ctx context.Context) ([]RunSummary, error) {
+	rows, err := s.query(ctx, `SELECT `+runColumns+` FROM runs ORDER BY created_at, run_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+	var runs []RunSummary
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Run returns the summary of the run stored under runID. Returns
+// ErrRunNotFound if there is none.
+func (s *Store) Run(ctx context.Context, runID string) (RunSummary, error) {
+	rows, err := s.query(ctx, `SELECT `+runColumns+` FROM runs WHERE run_id = ?`, runID)
+	if err != nil {
+		return RunSummary{}, fmt.Errorf("failed to read run %s: %w", runID, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return RunSummary{}, err
+		}
+		return RunSummary{}, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+	return scanRun(rows)
+}
+
+func scanRun(rows *sql.Rows) (RunSummary, error) {
+	var run RunSummary
+	var createdAt int64
+	var initial, final, total, annualized, sharpe, drawdown, drawdownAmount string
+	if err := rows.Scan(&run.ID, &createdAt, &initial, &final, &total, &annualized, &sharpe, &drawdown, &drawdownAmount); err != nil {
+		return RunSummary{}, err
+	}
+	run.CreatedAt = primitives.Unix(0, createdAt)
+	var err error
+	if run.InitialValue, err = parseAmount("initial value", initial); err != nil {
+		return RunSummary{}, err
+	}
+	if run.FinalValue, err = parseAmount("final value", final); err != nil {
+		return RunSummary{}, err
+	}
+	if run.TotalReturn, err = parseDecimal("total return", total); err != nil {
+		return RunSummary{}, err
+	}
+	if run.AnnualizedReturn, err = parseDecimal("annualized return", annualized); err != nil {
+		return RunSummary{}, err
+	}
+	if run.Sharpe, err = parseDecimal("sharpe", sharpe); err != nil {
+		return RunSummary{}, err
+	}
+	if run.MaxDrawdown, err = parseDecimal("max drawdown", drawdown); err != nil {
+		return RunSummary{}, err
+	}
+	if run.MaxDrawdownAmount, err = parseAmount("max drawdown amount", drawdownAmount); err != nil {
+		return RunSummary{}, err
+	}
+	return run, nil
+}
+
+// LoadResult reloads the run stored under runID as a Result holding its
+// metrics, value history, fills and experiment record. The portfolio and
+// the other details of the original Result are not stored. Returns ErrRunNotFound if there
+// is no such run.
+func (s *Store) LoadResult(ctx context.Context, runID string) (*backtest.Result, error) {
+	run, err := s.Run(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	result := &backtest.Result{
+		InitialValue:      run.InitialValue,
+		FinalValue:        run.FinalValue,
+		TotalReturn:       run.TotalReturn,
+		AnnualizedReturn:  run.AnnualizedReturn,
+		Sharpe:            run.Sharpe,
+		MaxDrawdown:       run.MaxDrawdown,
+		MaxDrawdownAmount: run.MaxDrawdownAmount,
+	}
+	if result.ValueHistory, err = s.loadValues(ctx, runID); err != nil {
+		return nil, fmt.Errorf("failed to load run %s: %w", runID, err)
+	}
+	if result.Fills, err = s.loadFills(ctx, runID); err != nil {
+		return nil, fmt.Errorf("failed to load run %s: %w", runID, err)
+	}
+	if result.Experiment, err = s.loadExperiment(ctx, runID); err != nil {
+		return nil, fmt.Errorf("failed to load run %s: %w", runID, err)
+	}
+	return result, nil
+}
+
+// loadExperiment reads the experiment record of a run, or nil if it was
+// saved without one.
+func (s *Store) loadExperiment(ctx context.Context, runID string) (*backtest.Experiment, error) {
+	rows, err := s.query(ctx, `SELECT data FROM run_experiments WHERE run_id = ?`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var data string
+	if err := rows.Scan(&data); err != nil {
+		return nil, err
+	}
+	var experiment backtest.Experiment
+	if err := json.Unmarshal([]byte(data), &experiment); err != nil {
+		return nil, fmt.Errorf("invalid experiment record: %w", err)
+	}
+	return &experiment, nil
+}
+
+func (s *Store) loadValues(ctx context.Context, runID string) ([]backtest.ValuePoint, error) {
+	rows, err := s.query(ctx, `SELECT time_ns, value FROM run_values WHERE run_id = ? ORDER BY seq`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []backtest.ValuePoint
+	for rows.Next() {
+		var ns int64
+		var value string
+		if err := rows.Scan(&ns, &value); err != nil {
+			return nil, err
+		}
+		amount, err := parseAmount("value", value)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, backtest.ValuePoint{Time: primitives.Unix(0, ns), Value: amount})
+	}
+	return points, rows.Err()
+}
+
+func (s *Store) loadFills(ctx context.Context, runID string) ([]strategy.Fill, error) {
+	rows, err := s.query(ctx, `SELECT order_id, pair, side, time_ns, quantity, price FROM run_fills
+		WHERE run_id = ? ORDER BY seq`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var fills []strategy.Fill
+	for rows.Next() {
+		var fill strategy.Fill
+		var side, quantity, price string
+		var ns int64
+		if err := rows.Scan(&fill.OrderID, &fill.Pair, &side, &ns, &quantity, &price); err != nil {
+			return nil, err
+		}
+		fill.Side = mechanisms.OrderSide(side)
+		fill.Time = primitives.Unix(0, ns)
+		if fill.Quantity, err = parseDecimal("fill quantity", quantity); err != nil {
+			return nil, err
+		}
+		p, err := parseDecimal("fill price", price)
+		if err != nil {
+			return nil, err
+		}
+		if fill.Price, err = primitives.NewPrice(p); err != nil {
+			return nil, fmt.Errorf("invalid fill price %q: %w", price, err)
+		}
+		fills = append(fills, fill)
+	}
+	return fills, rows.Err()
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/marketdata"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// SaveSnapshots stores snapshots as the series name, replacing any series
+// stored under it. Snapshots are encoded as marketdata.Recorder records
+// them, with the same limits: prices, price times, depth and metadata are
+// kept, OHLCV bars and quotes are not. Saving no snapshots deletes the
+// series.
+func (s *Store) SaveSnapshots(ctx context.Context, name string, snapshots []strategy.MarketSnapshot) error {
+	if name == "" {
+		return errors.New("series name is required")
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.exec(ctx, tx, `DELETE FROM snapshots WHERE series = ?`, name); err != nil {
+			return err
+		}
+		for i, snapshot := range snapshots {
+			data, err := marketdata.MarshalSnapshot(snapshot)
+			if err != nil {
+				return err
+			}
+			if err := s.exec(ctx, tx, `INSERT INTO snapshots (series, seq, time_ns, data) VALUES (?, ?, ?, ?)`,
+				name, i, snapshot.Time().UnixNano(), string(data),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save series %s: %w", name, err)
+	}
+	return nil
+}
+
+// LoadSnapshots reloads the series stored under name, in saved order, as
+// marketdata.Replayer replays a recording. Returns ErrSeriesNotFound if
+// there is no such series.
+func (s *Store) LoadSnapshots(ctx context.Context, name string) ([]strategy.MarketSnapshot, error) {
+	rows, err := s.query(ctx, `SELECT data FROM snapshots WHERE series = ? ORDER BY seq`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load series %s: %w", name, err)
+	}
+	defer rows.Close()
+	var snapshots []strategy.MarketSnapshot
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		snapshot, err := marketdata.UnmarshalSnapshot([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot %d of series %s: %w", len(snapshots), name, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSeriesNotFound, name)
+	}
+	return snapshots, nil
+}
+
+// Series returns the names of the stored snapshot series in order.
+func (s *Store) Series(ctx context.Context) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT DISTINCT series FROM snapshots ORDER BY series`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,197 @@
+// Package storage persists snapshot series and backtest results in a SQL
+// database, so research that accumulates many runs can list, compare and
+// reload them later. It works through database/sql with a Dialect for
+// SQLite or Postgres; the application imports the driver of its choice:
+//
+//	import _ "modernc.org/sqlite" // or "github.com/jackc/pgx/v5/stdlib"
+//
+//	db, err := sql.Open("sqlite", "research.db")
+//	store, err := storage.Open(ctx, db, storage.SQLite)
+//	err = store.SaveResult(ctx, "eth-lp-2024-01", result)
+//	reloaded, err := store.LoadResult(ctx, "eth-lp-2024-01")
+//
+// Decimal values are stored as text, so reloaded numbers are exact, and
+// times as Unix nanoseconds.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrRunNotFound is returned when no run is stored under an ID
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrSeriesNotFound is returned when no snapshot series is stored
+	// under a name
+	ErrSeriesNotFound = errors.New("snapshot series not found")
+)
+
+// Dialect adapts the store's SQL to a database.
+type Dialect struct {
+	// Name identifies the dialect
+	Name string
+
+	// numbered selects $1, $2, ... placeholders instead of ?
+	numbered bool
+}
+
+var (
+	// SQLite is the dialect of SQLite 3
+	SQLite = Dialect{Name: "sqlite"}
+
+	// Postgres is the dialect of PostgreSQL
+	Postgres = Dialect{Name: "postgres", numbered: true}
+)
+
+// rebind rewrites the ? placeholders of query for the dialect.
+func (d Dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// schema creates the store's tables. The statements are valid in both
+// dialects.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS runs (
+		run_id TEXT PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		initial_value TEXT NOT NULL,
+		final_value TEXT NOT NULL,
+		total_return TEXT NOT NULL,
+		annualized_return TEXT NOT NULL,
+		sharpe TEXT NOT NULL,
+		max_drawdown TEXT NOT NULL,
+		max_drawdown_amount TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS run_values (
+		run_id TEXT NOT NULL,
+		seq BIGINT NOT NULL,
+		time_ns BIGINT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (run_id, seq)
+	)`,
+	`CREATE TABLE IF NOT EXISTS run_fills (
+		run_id TEXT NOT NULL,
+		seq BIGINT NOT NULL,
+		order_id TEXT NOT NULL,
+		pair TEXT NOT NULL,
+		side TEXT NOT NULL,
+		time_ns BIGINT NOT NULL,
+		quantity TEXT NOT NULL,
+		price TEXT NOT NULL,
+		PRIMARY KEY (run_id, seq)
+	)`,
+	`CREATE TABLE IF NOT EXISTS run_experiments (
+		run_id TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS snapshots (
+		series TEXT NOT NULL,
+		seq BIGINT NOT NULL,
+		time_ns BIGINT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (series, seq)
+	)`,
+}
+
+// Store persists snapshot series and backtest results in a database.
+//
+// Thread Safety: Store is safe for concurrent use, as *sql.DB is; each
+// save runs in its own transaction.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+
+	// Clock stamps saved runs; the wall clock if nil
+	Clock primitives.Clock
+}
+
+// Open creates a store on db, creating its tables if they do not exist.
+func Open(ctx context.Context, db *sql.DB, dialect Dialect) (*Store, error) {
+	s := &Store{db: db, dialect: dialect}
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return nil, fmt.Errorf("failed to create %s schema: %w", dialect.Name, err)
+		}
+	}
+	return s, nil
+}
+
+// DB returns the underlying database, for queries the store does not
+// offer.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) now() primitives.Time {
+	if s.Clock == nil {
+		return primitives.Now()
+	}
+	return s.Clock.Now()
+}
+
+// inTx runs fn in a transaction, committing if it succeeds.
+func (s *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exec runs a statement written with ? placeholders.
+func (s *Store) exec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) error {
+	_, err := tx.ExecContext(ctx, s.dialect.rebind(query), args...)
+	return err
+}
+
+// query runs a query written with ? placeholders.
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// parseDecimal parses a stored decimal column.
+func parseDecimal(column, value string) (primitives.Decimal, error) {
+	d, err := primitives.NewDecimalFromString(value)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("invalid %s %q: %w", column, value, err)
+	}
+	return d, nil
+}
+
+// parseAmount parses a stored amount column.
+func parseAmount(column, value string) (primitives.Amount, error) {
+	d, err := parseDecimal(column, value)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	amount, err := primitives.NewAmount(d)
+	if err != nil {
+		return primitives.Amount{}, fmt.Errorf("invalid %s %q: %w", column, value, err)
+	}
+	return amount, nil
+}
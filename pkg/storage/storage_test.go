@@ -0,0 +1,380 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/storage"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// memDriver is an in-memory database/sql driver understanding the small
+// SQL subset the store issues, so the store is tested without cgo or a
+// database server. Each DSN is a separate database.
+type memDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*memDB
+}
+
+type memDB struct {
+	mu      sync.Mutex
+	tables  map[string]*memTable
+	queries []string
+}
+
+type memTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var mem = &memDriver{dbs: make(map[string]*memDB)}
+
+func init() {
+	sql.Register("storagemem", mem)
+}
+
+func (d *memDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[dsn]
+	if !ok {
+		db = &memDB{tables: make(map[string]*memTable)}
+		d.dbs[dsn] = db
+	}
+	return &memConn{db: db}, nil
+}
+
+type memConn struct{ db *memDB }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c *memConn) Close() error              { return nil }
+func (c *memConn) Begin() (driver.Tx, error) { return memTx{}, nil }
+
+type memTx struct{}
+
+func (memTx) Commit() error   { return nil }
+func (memTx) Rollback() error { return nil }
+
+var (
+	numbered  = regexp.MustCompile(`\$\d+`)
+	createRe  = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \((.*)\)$`)
+	insertRe  = regexp.MustCompile(`^INSERT INTO (\w+) \(([^)]*)\) VALUES`)
+	deleteRe  = regexp.MustCompile(`^DELETE FROM (\w+) WHERE (\w+) = \?$`)
+	selectRe  = regexp.MustCompile(`^SELECT (DISTINCT )?(.+?) FROM (\w+)(?: WHERE (\w+) = \?)?(?: ORDER BY (.+))?$`)
+	columnDef = regexp.MustCompile(`^(\w+) (TEXT|BIGINT)\b`)
+)
+
+type memStmt struct {
+	db    *memDB
+	query string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.queries = append(s.db.queries, s.query)
+	query := numbered.ReplaceAllString(s.query, "?")
+	if m := createRe.FindStringSubmatch(query); m != nil {
+		if _, ok := s.db.tables[m[1]]; !ok {
+			table := &memTable{}
+			for _, def := range strings.Split(m[2], ",") {
+				if c := columnDef.FindStringSubmatch(strings.TrimSpace(def)); c != nil {
+					table.columns = append(table.columns, c[1])
+				}
+			}
+			s.db.tables[m[1]] = table
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if m := insertRe.FindStringSubmatch(query); m != nil {
+		table, err := s.db.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		row := make([]driver.Value, len(table.columns))
+		for i, column := range strings.Split(m[2], ",") {
+			row[table.index(strings.TrimSpace(column))] = args[i]
+		}
+		table.rows = append(table.rows, row)
+		return driver.RowsAffected(1), nil
+	}
+	if m := deleteRe.FindStringSubmatch(query); m != nil {
+		table, err := s.db.table(m[1])
+		if err != nil {
+			return nil, err
+		}
+		column := table.index(m[2])
+		kept := table.rows[:0]
+		for _, row := range table.rows {
+			if row[column] != args[0] {
+				kept = append(kept, row)
+			}
+		}
+		table.rows = kept
+		return driver.RowsAffected(0), nil
+	}
+	return nil, fmt.Errorf("unsupported statement %q", s.query)
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.queries = append(s.db.queries, s.query)
+	m := selectRe.FindStringSubmatch(numbered.ReplaceAllString(s.query, "?"))
+	if m == nil {
+		return nil, fmt.Errorf("unsupported query %q", s.query)
+	}
+	table, err := s.db.table(m[3])
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]driver.Value
+	for _, row := range table.rows {
+		if m[4] == "" || row[table.index(m[4])] == args[0] {
+			rows = append(rows, row)
+		}
+	}
+	if m[5] != "" {
+		var order []int
+		for _, column := range strings.Split(m[5], ",") {
+			order = append(order, table.index(strings.TrimSpace(column)))
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, c := range order {
+				if a, b := fmt.Sprint(rows[i][c]), fmt.Sprint(rows[j][c]); a != b {
+					if x, ok := rows[i][c].(int64); ok {
+						return x < rows[j][c].(int64)
+					}
+					return a < b
+				}
+			}
+			return false
+		})
+	}
+	columns := strings.Split(m[2], ",")
+	out := &memRows{columns: columns}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		selected := make([]driver.Value, len(columns))
+		for i, column := range columns {
+			columns[i] = strings.TrimSpace(column)
+			selected[i] = row[table.index(columns[i])]
+		}
+		if m[1] != "" {
+			key := fmt.Sprint(selected)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		out.rows = append(out.rows, selected)
+	}
+	return out, nil
+}
+
+func (db *memDB) table(name string) (*memTable, error) {
+	table, ok := db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("no table %s", name)
+	}
+	return table, nil
+}
+
+func (t *memTable) index(column string) int {
+	for i, c := range t.columns {
+		if c == column {
+			return i
+		}
+	}
+	panic("no column " + column)
+}
+
+type memRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *memRows) Columns() []string { return r.columns }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var testStart = primitives.Unix(1700000000, 0)
+
+func d(s string) primitives.Decimal { return primitives.MustDecimalFromString(s) }
+
+func amount(s string) primitives.Amount { return primitives.MustAmount(d(s)) }
+
+func openStore(t *testing.T, dsn string, dialect storage.Dialect) (*storage.Store, *memDB) {
+	t.Helper()
+	db, err := sql.Open("storagemem", dsn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := storage.Open(context.Background(), db, dialect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return store, mem.dbs[dsn]
+}
+
+func TestStoreResults(t *testing.T) {
+	for _, dialect := range []storage.Dialect{storage.SQLite, storage.Postgres} {
+		t.Run(dialect.Name, func(t *testing.T) {
+			ctx := context.Background()
+			store, db := openStore(t, t.Name(), dialect)
+			clock := primitives.NewManualClock(testStart)
+			store.Clock = clock
+
+			result := &backtest.Result{
+				InitialValue: amount("10000"),
+				FinalValue:   amount("10250.125"),
+				ValueHistory: []backtest.ValuePoint{
+					{Time: testStart, Value: amount("10000")},
+					{Time: testStart.Add(primitives.Hours(1)), Value: amount("9900.5")},
+					{Time: testStart.Add(primitives.Hours(2)), Value: amount("10250.125")},
+				},
+				Fills: []strategy.Fill{{
+					OrderID: "o-1", Pair: "ETH/USD", Side: mechanisms.OrderSideBuy,
+					Time: testStart.Add(primitives.Hours(1)), Quantity: d("1.5"), Price: primitives.MustPrice(d("2000.25")),
+				}},
+				Experiment: &backtest.Experiment{
+					RunID: "run-b", ConfigHash: "c0ffee", DataFingerprint: "f00d",
+					Parameters: strategy.Params{"fast": float64(2)},
+				},
+				TotalReturn:       d("0.0250125"),
+				Sharpe:            d("1.234567890123"),
+				MaxDrawdown:       d("0.00995"),
+				MaxDrawdownAmount: amount("99.5"),
+			}
+			if err := store.SaveResult(ctx, "run-b", result); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			clock.Set(testStart.Add(primitives.Hours(1)))
+			if err := store.SaveResult(ctx, "run-a", &backtest.Result{InitialValue: amount("1"), FinalValue: amount("1")}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Saving again replaces the run rather than appending to it; an
+			// empty ID takes the experiment's
+			if err := store.SaveResult(ctx, "", result); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			loaded, err := store.LoadResult(ctx, "run-b")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if loaded.FinalValue.String() != "10250.125" || !loaded.Sharpe.Equal(result.Sharpe) || loaded.MaxDrawdownAmount.String() != "99.5" {
+				t.Errorf("expected exact metrics, got final %s sharpe %s drawdown %s", loaded.FinalValue, loaded.Sharpe, loaded.MaxDrawdownAmount)
+			}
+			if e := loaded.Experiment; e == nil || e.ConfigHash != "c0ffee" || e.Parameters["fast"] != float64(2) {
+				t.Errorf("expected the experiment record reloaded, got %+v", e)
+			}
+			history := loaded.History()
+			if history.Len() != 3 || history.Value(1).String() != "9900.5" || !history.Time(2).Equal(testStart.Add(primitives.Hours(2))) {
+				t.Errorf("expected the value history reloaded, got %v", history.Points())
+			}
+			if len(loaded.Fills) != 1 || loaded.Fills[0].Notional().String() != "3000.375" {
+				t.Errorf("expected the fill reloaded, got %+v", loaded.Fills)
+			}
+			if fill := loaded.Fills[0]; fill.Side != mechanisms.OrderSideBuy || fill.OrderID != "o-1" || !fill.Time.Equal(result.Fills[0].Time) {
+				t.Errorf("unexpected fill %+v", fill)
+			}
+
+			runs, err := store.Runs(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []string
+			for _, run := range runs {
+				ids = append(ids, run.ID)
+			}
+			if fmt.Sprint(ids) != "[run-a run-b]" {
+				t.Errorf("expected runs oldest first, got %v", ids)
+			}
+
+			if err := store.DeleteRun(ctx, "run-b"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := store.LoadResult(ctx, "run-b"); !errors.Is(err, storage.ErrRunNotFound) {
+				t.Errorf("expected %v, got %v", storage.ErrRunNotFound, err)
+			}
+			if err := store.DeleteRun(ctx, "run-b"); !errors.Is(err, storage.ErrRunNotFound) {
+				t.Errorf("expected %v, got %v", storage.ErrRunNotFound, err)
+			}
+
+			placeholders := false
+			for _, query := range db.queries {
+				placeholders = placeholders || strings.Contains(query, "$1")
+			}
+			if placeholders != (dialect == storage.Postgres) {
+				t.Errorf("expected numbered placeholders only for postgres")
+			}
+		})
+	}
+}
+
+func TestStoreSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store, _ := openStore(t, t.Name(), storage.SQLite)
+
+	first := strategy.NewSimpleSnapshot(testStart, map[string]primitives.Price{"ETH/USD": primitives.MustPrice(d("2000.5"))})
+	first.Set(snapshotkeys.PerpFundingRate("ETH/USD"), d("0.0001"))
+	second := strategy.NewSimpleSnapshot(testStart.Add(primitives.Hours(1)), map[string]primitives.Price{"ETH/USD": primitives.MustPrice(d("2010"))})
+	if err := store.SaveSnapshots(ctx, "eth-hourly", []strategy.MarketSnapshot{first, second}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.SaveSnapshots(ctx, "btc-hourly", []strategy.MarketSnapshot{second}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := store.LoadSnapshots(ctx, "eth-hourly")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded) != 2 || !loaded[1].Time().Equal(second.Time()) {
+		t.Fatalf("expected two snapshots in order, got %d", len(loaded))
+	}
+	if price, err := loaded[0].Price("ETH/USD"); err != nil || price.String() != "2000.5" {
+		t.Errorf("expected price 2000.5, got %v (%v)", price, err)
+	}
+	if rate, err := strategy.GetDecimal(loaded[0], snapshotkeys.PerpFundingRate("ETH/USD")); err != nil || rate.String() != "0.0001" {
+		t.Errorf("expected funding 0.0001, got %v (%v)", rate, err)
+	}
+
+	names, err := store.Series(ctx)
+	if err != nil || fmt.Sprint(names) != "[btc-hourly eth-hourly]" {
+		t.Errorf("expected both series listed, got %v (%v)", names, err)
+	}
+	if err := store.SaveSnapshots(ctx, "btc-hourly", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.LoadSnapshots(ctx, "btc-hourly"); !errors.Is(err, storage.ErrSeriesNotFound) {
+		t.Errorf("expected %v, got %v", storage.ErrSeriesNotFound, err)
+	}
+}
@@ -30,11 +30,15 @@ type Action interface {
 // AddPositionAction adds a new position to the portfolio.
 type AddPositionAction struct {
 	Position Position
+
+	// Tags label the position for reporting (see Portfolio.AddPosition)
+	Tags Tags
 }
 
-// NewAddPositionAction creates an action to add a position to the portfolio.
-func NewAddPositionAction(position Position) *AddPositionAction {
-	return &AddPositionAction{Position: position}
+// NewAddPositionAction creates an action to add a position to the
+// portfolio, labelled with the union of tags.
+func NewAddPositionAction(position Position, tags ...Tags) *AddPositionAction {
+	return &AddPositionAction{Position: position, Tags: mergeTags(tags...)}
 }
 
 // Apply adds the position to the portfolio.
@@ -46,7 +50,7 @@ func (a *AddPositionAction) Apply(portfolio *Portfolio) error {
 		return fmt.Errorf("%w: cannot add nil position", ErrInvalidAction)
 	}
 
-	return portfolio.AddPosition(a.Position)
+	return portfolio.AddPosition(a.Position, a.Tags)
 }
 
 // String returns a description of this action.
@@ -86,6 +90,7 @@ func (a *RemovePositionAction) String() string {
 
 // ReplacePositionAction replaces an existing position with a new one.
 // This is useful for updating positions (e.g., adjusting LP range, rolling options).
+// The new position keeps the old one's tags and cost basis.
 type ReplacePositionAction struct {
 	OldPositionID string
 	NewPosition   Position
@@ -111,16 +116,22 @@ func (a *ReplacePositionAction) Apply(portfolio *Portfolio) error {
 		return fmt.Errorf("%w: new position cannot be nil", ErrInvalidAction)
 	}
 
-	// Remove old position first, then add new one
+	// Remove old position first, then add new one with its tags and cost
+	// basis
+	tags := portfolio.Tags(a.OldPositionID)
+	basis, hasBasis := portfolio.costBasis(a.OldPositionID)
 	if err := portfolio.RemovePosition(a.OldPositionID); err != nil {
 		return err
 	}
 
-	if err := portfolio.AddPosition(a.NewPosition); err != nil {
+	if err := portfolio.AddPosition(a.NewPosition, tags); err != nil {
 		// Attempt to restore the old position on failure
 		// Note: This is a best-effort rollback; in production consider transaction semantics
 		return fmt.Errorf("failed to add new position after removing old: %w", err)
 	}
+	if hasBasis {
+		return portfolio.SetCostBasis(a.NewPosition.ID(), basis)
+	}
 
 	return nil
 }
@@ -201,3 +212,199 @@ func (a *BatchAction) Apply(portfolio *Portfolio) error {
 func (a *BatchAction) String() string {
 	return fmt.Sprintf("BatchAction(%d actions)", len(a.Actions))
 }
+
+// DeferredAction is an optional interface for actions priced when they
+// execute rather than when the strategy decides on them, such as market
+// orders subject to venue latency. The backtest engine calls Resolve with
+// the snapshot the action executes at and applies the returned action.
+type DeferredAction interface {
+	Action
+
+	// Resolve returns the concrete action to apply at snapshot.
+	// Returns error if the action cannot execute at this snapshot.
+	Resolve(snapshot MarketSnapshot) (Action, error)
+}
+
+// VenueAction routes an action to a named venue so the backtest engine can
+// apply that venue's execution latency before the action takes effect.
+type VenueAction struct {
+	Venue  string
+	Action Action
+}
+
+// NewVenueAction creates an action that executes action at venue.
+func NewVenueAction(venue string, action Action) *VenueAction {
+	return &VenueAction{Venue: venue, Action: action}
+}
+
+// Apply applies the routed action immediately, ignoring latency.
+func (a *VenueAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	if a.Action == nil {
+		return fmt.Errorf("%w: venue %s has nil action", ErrInvalidAction, a.Venue)
+	}
+
+	return a.Action.Apply(portfolio)
+}
+
+// Resolve resolves the routed action at snapshot when it is a
+// DeferredAction and returns it unchanged otherwise.
+func (a *VenueAction) Resolve(snapshot MarketSnapshot) (Action, error) {
+	if a.Action == nil {
+		return nil, fmt.Errorf("%w: venue %s has nil action", ErrInvalidAction, a.Venue)
+	}
+	if deferred, ok := a.Action.(DeferredAction); ok {
+		return deferred.Resolve(snapshot)
+	}
+	return a.Action, nil
+}
+
+// String returns a description of this action.
+func (a *VenueAction) String() string {
+	if a.Action == nil {
+		return fmt.Sprintf("%s: nil", a.Venue)
+	}
+	return fmt.Sprintf("%s: %s", a.Venue, a.Action.String())
+}
+
+// OpenPositionAction adds a position and pays for it from cash in one
+// step: the position's EntryCost if it is a CostedPosition, otherwise its
+// Value. Prefer it to pairing AddPositionAction with a hand-computed
+// AdjustCashAction.
+//
+// The cost is priced at the snapshot the action executes at, so the action
+// is a DeferredAction; to apply it directly, call Resolve first.
+type OpenPositionAction struct {
+	Position Position
+
+	// Tags label the position for reporting (see Portfolio.AddPosition)
+	Tags Tags
+
+	snapshot MarketSnapshot
+}
+
+// NewOpenPositionAction creates an action opening position, labelled with
+// the union of tags.
+func NewOpenPositionAction(position Position, tags ...Tags) *OpenPositionAction {
+	return &OpenPositionAction{Position: position, Tags: mergeTags(tags...)}
+}
+
+// Resolve implements DeferredAction, binding the action to snapshot.
+func (a *OpenPositionAction) Resolve(snapshot MarketSnapshot) (Action, error) {
+	resolved := *a
+	resolved.snapshot = snapshot
+	return &resolved, nil
+}
+
+// Apply adds the position and debits its cost, which becomes its cost
+// basis (see PnL).
+// Returns ErrInvalidAction if the position is nil or the action has not
+// been resolved, and any error pricing the position.
+func (a *OpenPositionAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	if a.Position == nil {
+		return fmt.Errorf("%w: cannot open nil position", ErrInvalidAction)
+	}
+	if a.snapshot == nil {
+		return fmt.Errorf("%w: open of %s must be resolved against a snapshot", ErrInvalidAction, a.Position.ID())
+	}
+
+	cost, err := EntryCost(a.Position, a.snapshot)
+	if err != nil {
+		return err
+	}
+	if cost, err = portfolio.toBase(a.Position, a.snapshot, cost); err != nil {
+		return fmt.Errorf("failed to convert entry cost of %s: %w", a.Position.ID(), err)
+	}
+	if err := portfolio.AddPosition(a.Position, a.Tags); err != nil {
+		return err
+	}
+	if err := portfolio.SetCostBasis(a.Position.ID(), cost.Decimal()); err != nil {
+		return err
+	}
+	return portfolio.AdjustCash(cost.Decimal().Neg())
+}
+
+// String returns a description of this action.
+func (a *OpenPositionAction) String() string {
+	if a.Position == nil {
+		return "OpenPosition(nil)"
+	}
+	return fmt.Sprintf("OpenPosition(%s)", a.Position.ID())
+}
+
+// ClosePositionAction removes a position and credits its Value at the
+// executing snapshot to cash in one step.
+//
+// Like OpenPositionAction it is a DeferredAction; to apply it directly,
+// call Resolve first.
+type ClosePositionAction struct {
+	PositionID string
+
+	snapshot MarketSnapshot
+}
+
+// NewClosePositionAction creates an action closing positionID.
+func NewClosePositionAction(positionID string) *ClosePositionAction {
+	return &ClosePositionAction{PositionID: positionID}
+}
+
+// Resolve implements DeferredAction, binding the action to snapshot.
+func (a *ClosePositionAction) Resolve(snapshot MarketSnapshot) (Action, error) {
+	resolved := *a
+	resolved.snapshot = snapshot
+	return &resolved, nil
+}
+
+// Apply removes the position and credits its value.
+// Returns ErrInvalidAction if the action has not been resolved,
+// ErrPositionNotFound if the position does not exist, and any error
+// valuing it.
+func (a *ClosePositionAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	if a.snapshot == nil {
+		return fmt.Errorf("%w: close of %s must be resolved against a snapshot", ErrInvalidAction, a.PositionID)
+	}
+
+	position, err := portfolio.GetPosition(a.PositionID)
+	if err != nil {
+		return err
+	}
+	value, err := portfolio.PositionValue(position, a.snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to value %s for close: %w", a.PositionID, err)
+	}
+	if err := portfolio.RemovePosition(a.PositionID); err != nil {
+		return err
+	}
+	return portfolio.AdjustCash(value.Decimal())
+}
+
+// String returns a description of this action.
+func (a *ClosePositionAction) String() string {
+	return fmt.Sprintf("ClosePosition(%s)", a.PositionID)
+}
+
+// EntryCost returns the cash needed to open position at snapshot: its
+// EntryCost if it is a CostedPosition, otherwise its Value.
+func EntryCost(position Position, snapshot MarketSnapshot) (primitives.Amount, error) {
+	var (
+		cost primitives.Amount
+		err  error
+	)
+	if costed, ok := position.(CostedPosition); ok {
+		cost, err = costed.EntryCost(snapshot)
+	} else {
+		cost, err = position.Value(snapshot)
+	}
+	if err != nil {
+		return primitives.ZeroAmount(), fmt.Errorf("failed to price entry of %s: %w", position.ID(), err)
+	}
+	return cost, nil
+}
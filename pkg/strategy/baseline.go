@@ -0,0 +1,175 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// BuyAndHold is a baseline Strategy that invests a fixed fraction of the
+// portfolio in one asset at the first snapshot it can trade and never
+// trades again. Backtest it over a strategy's snapshots to benchmark the
+// strategy against simply holding the asset (see backtest.Compare).
+//
+// The purchase is a Holding with ID "buy-and-hold:<pair>". It is retried
+// at each snapshot until the holding exists, so it happens at the first
+// snapshot after a warm-up window and after a skipped action.
+type BuyAndHold struct {
+	pair   string
+	weight primitives.Decimal
+}
+
+// NewBuyAndHold creates a strategy investing weight (e.g., 1 for the whole
+// portfolio) in pair. Returns ErrInvalidParams if pair is empty or weight
+// is not in (0, 1].
+func NewBuyAndHold(pair string, weight primitives.Decimal) (*BuyAndHold, error) {
+	if pair == "" {
+		return nil, fmt.Errorf("%w: buy-and-hold pair cannot be empty", ErrInvalidParams)
+	}
+	if !weight.IsPositive() || weight.GreaterThan(primitives.One()) {
+		return nil, fmt.Errorf("%w: buy-and-hold weight must be in (0, 1], got %s", ErrInvalidParams, weight)
+	}
+	return &BuyAndHold{pair: pair, weight: weight}, nil
+}
+
+// PositionID returns the ID of the holding the strategy buys.
+func (b *BuyAndHold) PositionID() string {
+	return "buy-and-hold:" + b.pair
+}
+
+// Rebalance buys the holding if the portfolio does not have it yet.
+func (b *BuyAndHold) Rebalance(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	if WarmingUp(ctx) || portfolio.HasPosition(b.PositionID()) {
+		return nil, nil
+	}
+	value, err := portfolio.Value(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("buy-and-hold: %w", err)
+	}
+	quantity, err := baselineQuantity(snapshot, b.pair, value.Mul(b.weight))
+	if err != nil || quantity.IsZero() {
+		return nil, err
+	}
+	return []Action{NewOpenPositionAction(NewHolding(b.PositionID(), b.pair, quantity))}, nil
+}
+
+// DCA is a baseline Strategy that dollar-cost averages into one asset:
+// it buys a fixed cash amount of it at the first snapshot and then at the
+// first snapshot at least one cadence after each purchase, until cash
+// runs out. A purchase larger than the remaining cash buys with what is
+// left.
+//
+// Purchases accumulate in a Holding with ID "dca:<pair>". A purchase
+// counts once the holding shows it, so a purchase that was skipped or
+// rolled back is retried at the next snapshot, and a portfolio without the
+// holding starts the schedule over, as in a new run.
+//
+// Thread Safety: DCA is not thread-safe; it tracks the time of its last
+// purchase.
+type DCA struct {
+	pair    string
+	amount  primitives.Amount
+	cadence primitives.Duration
+
+	bought   bool
+	lastTime primitives.Time
+
+	// pending is the purchase last emitted, until the holding shows it
+	pending *dcaPurchase
+}
+
+// dcaPurchase is a DCA purchase awaiting confirmation.
+type dcaPurchase struct {
+	time primitives.Time
+
+	// holding is the holding's quantity once the purchase is applied
+	holding primitives.Decimal
+}
+
+// NewDCA creates a strategy buying amount of pair every cadence. Returns
+// ErrInvalidParams if pair is empty or amount or cadence is not positive.
+func NewDCA(pair string, amount primitives.Amount, cadence primitives.Duration) (*DCA, error) {
+	if pair == "" {
+		return nil, fmt.Errorf("%w: DCA pair cannot be empty", ErrInvalidParams)
+	}
+	if amount.IsZero() {
+		return nil, fmt.Errorf("%w: DCA amount must be positive", ErrInvalidParams)
+	}
+	if cadence.Duration() <= 0 {
+		return nil, fmt.Errorf("%w: DCA cadence must be positive, got %s", ErrInvalidParams, cadence)
+	}
+	return &DCA{pair: pair, amount: amount, cadence: cadence}, nil
+}
+
+// PositionID returns the ID of the holding the strategy accumulates.
+func (d *DCA) PositionID() string {
+	return "dca:" + d.pair
+}
+
+// Rebalance buys the next installment when one is due.
+func (d *DCA) Rebalance(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	if WarmingUp(ctx) {
+		return nil, nil
+	}
+	held, err := d.held(portfolio)
+	if err != nil {
+		return nil, err
+	}
+	if held.IsZero() {
+		d.bought = false
+	}
+	if d.pending != nil {
+		if !held.LessThan(d.pending.holding) {
+			d.bought, d.lastTime = true, d.pending.time
+		}
+		d.pending = nil
+	}
+	if d.bought && snapshot.Time().Before(d.lastTime.Add(d.cadence)) {
+		return nil, nil
+	}
+	amount := d.amount
+	if cash := portfolio.Cash(); cash.LessThan(amount) {
+		amount = cash
+	}
+	quantity, err := baselineQuantity(snapshot, d.pair, amount)
+	if err != nil || quantity.IsZero() {
+		return nil, err
+	}
+	d.pending = &dcaPurchase{time: snapshot.Time(), holding: held.Add(quantity)}
+
+	if held.IsZero() {
+		return []Action{NewOpenPositionAction(NewHolding(d.PositionID(), d.pair, quantity))}, nil
+	}
+	return []Action{NewResizePositionAction(d.PositionID(), held.Add(quantity))}, nil
+}
+
+// held returns the quantity of the holding, zero if there is none.
+func (d *DCA) held(portfolio *Portfolio) (primitives.Decimal, error) {
+	position, err := portfolio.GetPosition(d.PositionID())
+	if err != nil {
+		return primitives.Zero(), nil
+	}
+	holding, ok := position.(*Holding)
+	if !ok {
+		return primitives.Zero(), fmt.Errorf("%w: DCA position %s is a %T, not a holding", ErrInvalidAction, d.PositionID(), position)
+	}
+	return holding.Quantity(), nil
+}
+
+// baselineQuantity returns the units of pair that notional buys at
+// snapshot.
+func baselineQuantity(snapshot MarketSnapshot, pair string, notional primitives.Amount) (primitives.Decimal, error) {
+	if notional.IsZero() {
+		return primitives.Zero(), nil
+	}
+	price, err := snapshot.Price(pair)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	quantity, err := notional.DivPrice(price)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("failed to size %s purchase: %w", pair, err)
+	}
+	return quantity.Decimal(), nil
+}
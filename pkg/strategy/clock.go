@@ -0,0 +1,28 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+type clockKey struct{}
+
+// WithClock returns a copy of ctx carrying clock as the run's time source.
+// The backtest engine and live sessions install one that reads the time
+// of the snapshot being processed.
+func WithClock(ctx context.Context, clock primitives.Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// Clock returns the run's time source from the context passed to
+// Rebalance. Strategies that stamp or schedule by the current time should
+// read it rather than time.Now, so that a backtest runs on snapshot time
+// and reproduces exactly; pass it on to components that take a Clock.
+// Outside a run, Clock returns the wall clock.
+func Clock(ctx context.Context) primitives.Clock {
+	if clock, ok := ctx.Value(clockKey{}).(primitives.Clock); ok && clock != nil {
+		return clock
+	}
+	return primitives.SystemClock{}
+}
@@ -0,0 +1,159 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// CrossRateResolver derives prices for pairs a snapshot does not quote
+// directly by chaining quoted pairs through intermediate assets, e.g.
+// ETH/USD = ETH/BTC * BTC/USD. Quotes are usable in either direction
+// (inverse quotes contribute their reciprocal).
+//
+// The shortest path (fewest hops) is used; ties are broken by asset name
+// so results are deterministic.
+type CrossRateResolver struct {
+	// maxHops bounds the number of quoted pairs chained together
+	maxHops int
+
+	// maxStaleness excludes quotes older than this relative to the snapshot
+	// time (zero disables the check); requires PriceTimestamper snapshots
+	maxStaleness primitives.Duration
+}
+
+// NewCrossRateResolver creates a resolver chaining at most maxHops quotes.
+// Quotes older than maxStaleness (when positive) are ignored.
+// Returns error if maxHops is less than 1.
+func NewCrossRateResolver(maxHops int, maxStaleness primitives.Duration) (*CrossRateResolver, error) {
+	if maxHops < 1 {
+		return nil, fmt.Errorf("max hops must be at least 1, got %d", maxHops)
+	}
+	return &CrossRateResolver{maxHops: maxHops, maxStaleness: maxStaleness}, nil
+}
+
+// edge is a usable conversion from one asset to another.
+type edge struct {
+	to   string
+	rate primitives.Decimal
+}
+
+// Resolve returns the price of pair and the quoted pairs used to derive it.
+// A direct quote (in any key format) is returned with a single-element path.
+// Returns ErrPriceNotAvailable if no path exists within the hop limit.
+func (r *CrossRateResolver) Resolve(snapshot MarketSnapshot, pair primitives.Pair) (primitives.Price, []primitives.Pair, error) {
+	graph := r.buildGraph(snapshot)
+
+	type node struct {
+		asset string
+		rate  primitives.Decimal
+		path  []primitives.Pair
+	}
+
+	visited := map[string]bool{pair.Base: true}
+	frontier := []node{{asset: pair.Base, rate: primitives.One()}}
+	for hop := 0; hop < r.maxHops && len(frontier) > 0; hop++ {
+		var next []node
+		for _, n := range frontier {
+			for _, e := range graph[n.asset] {
+				if visited[e.to] {
+					continue
+				}
+				step := node{
+					asset: e.to,
+					rate:  n.rate.Mul(e.rate),
+					path:  append(append([]primitives.Pair(nil), n.path...), primitives.Pair{Base: n.asset, Quote: e.to}),
+				}
+				if e.to == pair.Quote {
+					price, err := primitives.NewPrice(step.rate)
+					if err != nil {
+						return primitives.Price{}, nil, err
+					}
+					return price, step.path, nil
+				}
+				visited[e.to] = true
+				next = append(next, step)
+			}
+		}
+		frontier = next
+	}
+	return primitives.Price{}, nil, fmt.Errorf("%w: no conversion path for %s within %d hops",
+		ErrPriceNotAvailable, pair, r.maxHops)
+}
+
+// buildGraph indexes fresh, parseable, non-zero quotes as bidirectional edges.
+func (r *CrossRateResolver) buildGraph(snapshot MarketSnapshot) map[string][]edge {
+	timestamps, _ := snapshot.(PriceTimestamper)
+	graph := make(map[string][]edge)
+	prices := snapshot.Prices()
+	// Sorted keys so duplicate spellings of a pair ("ETH/USD", "ETHUSD")
+	// always contribute edges in the same order
+	for _, key := range sortedPriceKeys(prices) {
+		price := prices[key]
+		if price.IsZero() || r.isStale(snapshot, timestamps, key) {
+			continue
+		}
+		pair, err := primitives.ParsePair(key)
+		if err != nil {
+			continue
+		}
+		inverse, err := primitives.One().Div(price.Decimal())
+		if err != nil {
+			continue
+		}
+		graph[pair.Base] = append(graph[pair.Base], edge{to: pair.Quote, rate: price.Decimal()})
+		graph[pair.Quote] = append(graph[pair.Quote], edge{to: pair.Base, rate: inverse})
+	}
+	for asset := range graph {
+		edges := graph[asset]
+		sort.SliceStable(edges, func(i, j int) bool { return edges[i].to < edges[j].to })
+	}
+	return graph
+}
+
+func (r *CrossRateResolver) isStale(snapshot MarketSnapshot, timestamps PriceTimestamper, key string) bool {
+	if r.maxStaleness.Duration() <= 0 || timestamps == nil {
+		return false
+	}
+	observed, ok := timestamps.PriceTime(key)
+	if !ok {
+		return false
+	}
+	return snapshot.Time().Sub(observed).GreaterThan(r.maxStaleness)
+}
+
+// CrossRateSnapshot wraps a MarketSnapshot so Price falls back to derived
+// cross rates when a pair is not quoted directly. All other methods pass
+// through to the wrapped snapshot.
+type CrossRateSnapshot struct {
+	MarketSnapshot
+	resolver *CrossRateResolver
+}
+
+// NewCrossRateSnapshot wraps snapshot with cross-rate resolution.
+func NewCrossRateSnapshot(snapshot MarketSnapshot, resolver *CrossRateResolver) *CrossRateSnapshot {
+	return &CrossRateSnapshot{MarketSnapshot: snapshot, resolver: resolver}
+}
+
+// Price returns the quoted price for pair, or a derived cross rate if the
+// pair parses as a primitives.Pair and a conversion path exists.
+func (s *CrossRateSnapshot) Price(pair string) (primitives.Price, error) {
+	if price, err := s.MarketSnapshot.Price(pair); err == nil {
+		return price, nil
+	}
+	parsed, err := primitives.ParsePair(pair)
+	if err != nil {
+		return primitives.Price{}, fmt.Errorf("%w: %s", ErrPriceNotAvailable, pair)
+	}
+	price, _, err := s.resolver.Resolve(s.MarketSnapshot, parsed)
+	return price, err
+}
+
+// MetadataKeys returns the wrapped snapshot's metadata keys, if it lists them.
+func (s *CrossRateSnapshot) MetadataKeys() []string {
+	if lister, ok := s.MarketSnapshot.(MetadataLister); ok {
+		return lister.MetadataKeys()
+	}
+	return nil
+}
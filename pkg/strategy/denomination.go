@@ -0,0 +1,128 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DenominatedPosition is an optional Position extension naming the
+// currency its Value is returned in, such as "ETH" for a coin-margined
+// perpetual. When the portfolio has a base currency (see
+// Portfolio.SetBaseCurrency), values in another currency are converted
+// with FXRate. Positions without it are taken to be in the base currency.
+type DenominatedPosition interface {
+	Position
+
+	// Denomination returns the currency of Value.
+	Denomination() string
+}
+
+// FXRate returns the number of units of to that one unit of from is
+// worth at snapshot: the price of from/to, or the reciprocal of to/from.
+// Pairs are looked up with PairPricer when the snapshot implements it, so
+// aliases and key formats are matched; wrap the snapshot in a
+// CrossRateSnapshot to chain quotes through other assets. The rate of a
+// currency to itself is 1. Returns ErrPriceNotAvailable if neither
+// direction is quoted.
+func FXRate(snapshot MarketSnapshot, from, to string) (primitives.Decimal, error) {
+	if from == to {
+		return primitives.One(), nil
+	}
+	pair := primitives.Pair{Base: from, Quote: to}
+	if price, err := pairPrice(snapshot, pair); err == nil && !price.IsZero() {
+		return price.Decimal(), nil
+	}
+	if price, err := pairPrice(snapshot, pair.Inverse()); err == nil && !price.IsZero() {
+		return primitives.One().Div(price.Decimal())
+	}
+	return primitives.Zero(), fmt.Errorf("%w: no %s rate to convert %s", ErrPriceNotAvailable, pair, from)
+}
+
+func pairPrice(snapshot MarketSnapshot, pair primitives.Pair) (primitives.Price, error) {
+	if pricer, ok := snapshot.(PairPricer); ok {
+		return pricer.PriceOf(pair)
+	}
+	return snapshot.Price(pair.String())
+}
+
+// SetBaseCurrency sets the currency the portfolio's cash and values are
+// denominated in, such as "USD". Position values in another currency
+// (see DenominatedPosition) are then converted to it. The default, empty,
+// performs no conversion.
+func (p *Portfolio) SetBaseCurrency(currency string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.baseCurrency = currency
+}
+
+// BaseCurrency returns the portfolio's base currency, empty if unset.
+func (p *Portfolio) BaseCurrency() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.baseCurrency
+}
+
+// PositionValue returns the Value of position converted to the base
+// currency at snapshot. A ContextValuedPosition is valued with ValueIn, in
+// snapshot itself if it is a ValuationContext. Returns error if the position cannot be valued or
+// its denomination has no FX rate in the snapshot.
+func (p *Portfolio) PositionValue(position Position, snapshot MarketSnapshot) (primitives.Amount, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.positionValue(position, snapshot)
+}
+
+func (p *Portfolio) positionValue(position Position, snapshot MarketSnapshot) (primitives.Amount, error) {
+	value, err := ValueIn(position, valuationContext(snapshot))
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	return p.toBase(position, snapshot, value)
+}
+
+// ToBase converts amount, in position's denomination, to the base
+// currency at snapshot as PositionValue converts its value, for amounts a
+// position reports besides Value, such as accrued rewards.
+func (p *Portfolio) ToBase(position Position, snapshot MarketSnapshot, amount primitives.Amount) (primitives.Amount, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.toBase(position, snapshot, amount)
+}
+
+// toBase converts an amount in position's denomination to the base
+// currency.
+func (p *Portfolio) toBase(position Position, snapshot MarketSnapshot, amount primitives.Amount) (primitives.Amount, error) {
+	denominated, ok := position.(DenominatedPosition)
+	if !ok || p.baseCurrency == "" {
+		return amount, nil
+	}
+	currency := denominated.Denomination()
+	if currency == "" || currency == p.baseCurrency {
+		return amount, nil
+	}
+	rate, err := FXRate(snapshot, currency, p.baseCurrency)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	return amount.Mul(rate), nil
+}
+
+// ValueIn returns the portfolio's Value converted from the base currency
+// to currency at snapshot. Returns error if the portfolio has no base
+// currency or the snapshot no rate between the two.
+func (p *Portfolio) ValueIn(snapshot MarketSnapshot, currency string) (primitives.Amount, error) {
+	base := p.BaseCurrency()
+	if base == "" {
+		return primitives.Amount{}, fmt.Errorf("portfolio has no base currency to convert to %s", currency)
+	}
+	value, err := p.Value(snapshot)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	rate, err := FXRate(snapshot, base, currency)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	return value.Mul(rate), nil
+}
@@ -0,0 +1,125 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DepthSnapshot is an optional MarketSnapshot extension exposing order book
+// depth. Execution and slippage models type-assert snapshots to DepthSnapshot
+// and fall back to Price when depth is unavailable.
+type DepthSnapshot interface {
+	MarketSnapshot
+
+	// Depth returns the bid/ask levels for pair.
+	// Bids are sorted by price descending, asks ascending.
+	// Returns ErrDepthNotAvailable if the snapshot has no depth for pair.
+	Depth(pair string) (mechanisms.OrderBookDepth, error)
+}
+
+// SimpleDepthSnapshot extends SimpleSnapshot with per-pair order book depth.
+// Useful for tests and for loaders that capture L2 data.
+type SimpleDepthSnapshot struct {
+	*SimpleSnapshot
+	depth map[string]mechanisms.OrderBookDepth
+}
+
+// NewSimpleDepthSnapshot creates a snapshot with the given prices and no depth.
+// Use SetDepth to attach order book levels.
+func NewSimpleDepthSnapshot(time primitives.Time, prices map[string]primitives.Price) *SimpleDepthSnapshot {
+	return &SimpleDepthSnapshot{
+		SimpleSnapshot: NewSimpleSnapshot(time, prices),
+		depth:          make(map[string]mechanisms.OrderBookDepth),
+	}
+}
+
+// Depth returns the order book depth for pair.
+func (s *SimpleDepthSnapshot) Depth(pair string) (mechanisms.OrderBookDepth, error) {
+	depth, ok := s.depth[pair]
+	if !ok {
+		return mechanisms.OrderBookDepth{}, fmt.Errorf("%w: %s", ErrDepthNotAvailable, pair)
+	}
+	return depth, nil
+}
+
+// SetDepth stores order book depth for pair.
+// Returns error if levels are unsorted or the book is crossed.
+func (s *SimpleDepthSnapshot) SetDepth(pair string, depth mechanisms.OrderBookDepth) error {
+	if err := ValidateDepth(depth); err != nil {
+		return fmt.Errorf("depth %s: %w", pair, err)
+	}
+	if depth.Timestamp == (primitives.Time{}) {
+		depth.Timestamp = s.Time()
+	}
+	s.depth[pair] = depth
+	return nil
+}
+
+// ValidateDepth checks that bids are sorted descending, asks ascending,
+// and the best bid does not exceed the best ask.
+func ValidateDepth(depth mechanisms.OrderBookDepth) error {
+	for i := 1; i < len(depth.Bids); i++ {
+		if depth.Bids[i].Price.GreaterThan(depth.Bids[i-1].Price) {
+			return fmt.Errorf("%w: bids not sorted descending at level %d", ErrInvalidMarketData, i)
+		}
+	}
+	for i := 1; i < len(depth.Asks); i++ {
+		if depth.Asks[i].Price.LessThan(depth.Asks[i-1].Price) {
+			return fmt.Errorf("%w: asks not sorted ascending at level %d", ErrInvalidMarketData, i)
+		}
+	}
+	if len(depth.Bids) > 0 && len(depth.Asks) > 0 && depth.Bids[0].Price.GreaterThan(depth.Asks[0].Price) {
+		return fmt.Errorf("%w: crossed book (bid %s > ask %s)",
+			ErrInvalidMarketData, depth.Bids[0].Price, depth.Asks[0].Price)
+	}
+	return nil
+}
+
+// EstimateFill walks the book to estimate a market order of size on side.
+// Buys consume asks, sells consume bids.
+//
+// Returns the volume-weighted average fill price and the filled amount,
+// which is less than size when the book is too thin. Returns
+// ErrInsufficientDepth if nothing can be filled.
+func EstimateFill(
+	depth mechanisms.OrderBookDepth,
+	side mechanisms.OrderSide,
+	size primitives.Amount,
+) (primitives.Price, primitives.Amount, error) {
+	levels := depth.Asks
+	if side == mechanisms.OrderSideSell {
+		levels = depth.Bids
+	}
+
+	remaining := size.Decimal()
+	filled := primitives.Zero()
+	notional := primitives.Zero()
+	for _, level := range levels {
+		if !remaining.IsPositive() {
+			break
+		}
+		take := level.Size.Decimal()
+		if take.GreaterThan(remaining) {
+			take = remaining
+		}
+		filled = filled.Add(take)
+		notional = notional.Add(take.Mul(level.Price.Decimal()))
+		remaining = remaining.Sub(take)
+	}
+
+	if filled.IsZero() {
+		return primitives.ZeroPrice(), primitives.ZeroAmount(), ErrInsufficientDepth
+	}
+
+	avg, err := notional.Div(filled)
+	if err != nil {
+		return primitives.ZeroPrice(), primitives.ZeroAmount(), err
+	}
+	price, err := primitives.NewPrice(avg)
+	if err != nil {
+		return primitives.ZeroPrice(), primitives.ZeroAmount(), err
+	}
+	return price, primitives.MustAmount(filled), nil
+}
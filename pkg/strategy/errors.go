@@ -20,4 +20,57 @@ var (
 
 	// ErrNilPosition indicates a nil position was provided
 	ErrNilPosition = errors.New("position cannot be nil")
+
+	// ErrMetadataNotFound indicates the requested snapshot metadata key is missing
+	ErrMetadataNotFound = errors.New("metadata not found")
+
+	// ErrMetadataType indicates snapshot metadata has an unexpected type
+	ErrMetadataType = errors.New("metadata has unexpected type")
+
+	// ErrBarNotAvailable indicates the snapshot has no OHLCV bar for the pair
+	ErrBarNotAvailable = errors.New("bar not available for pair")
+
+	// ErrQuoteNotAvailable indicates the snapshot has no bid/ask quote for the pair
+	ErrQuoteNotAvailable = errors.New("quote not available for pair")
+
+	// ErrDepthNotAvailable indicates the snapshot has no order book depth for the pair
+	ErrDepthNotAvailable = errors.New("depth not available for pair")
+
+	// ErrInsufficientDepth indicates the order book cannot fill any of the requested size
+	ErrInsufficientDepth = errors.New("insufficient order book depth")
+
+	// ErrInvalidMarketData indicates inconsistent market data (e.g., crossed quotes)
+	ErrInvalidMarketData = errors.New("invalid market data")
+
+	// ErrHistoryDisabled indicates a history query on a portfolio that is not recording history
+	ErrHistoryDisabled = errors.New("portfolio history not available")
+
+	// ErrUnknownStrategy indicates no factory is registered under the requested name
+	ErrUnknownStrategy = errors.New("unknown strategy")
+
+	// ErrStrategyExists indicates a strategy name is already registered
+	ErrStrategyExists = errors.New("strategy already registered")
+
+	// ErrInvalidParams indicates missing or malformed strategy parameters
+	ErrInvalidParams = errors.New("invalid strategy parameters")
+
+	// ErrOrderNotFound indicates no open order has the requested ID
+	ErrOrderNotFound = errors.New("order not found")
+
+	// ErrPositionExists indicates a position with the same ID is already held
+	ErrPositionExists = errors.New("position already exists")
+
+	// ErrConstraintViolated indicates a portfolio constraint does not hold
+	ErrConstraintViolated = errors.New("constraint violated")
+
+	// ErrGreekLimitBreached indicates the book's Greeks exceed a GreekLimits bound
+	ErrGreekLimitBreached = errors.New("greek limit breached")
+
+	// ErrStateNotFound indicates no mechanism state is tracked under the
+	// requested ID
+	ErrStateNotFound = errors.New("mechanism state not found")
+
+	// ErrMissingData indicates a snapshot lacks market data a position or
+	// strategy declared it requires
+	ErrMissingData = errors.New("required market data missing")
 )
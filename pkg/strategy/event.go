@@ -0,0 +1,184 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+// Event is a discrete market event, such as a trade, funding payment or
+// liquidation, for backtests at tick granularity. A MarketState folds
+// events into the snapshots strategies value positions against.
+type Event interface {
+	// Time returns when the event occurred.
+	Time() primitives.Time
+
+	// String returns a description of the event.
+	String() string
+}
+
+// TradeEvent is a trade printed on a pair. It sets the pair's price and
+// adds to its bar volume.
+type TradeEvent struct {
+	At    primitives.Time
+	Pair  string
+	Price primitives.Price
+	Size  primitives.Amount
+
+	// Side is the aggressor's side
+	Side mechanisms.OrderSide
+}
+
+// Time returns when the trade printed.
+func (e TradeEvent) Time() primitives.Time { return e.At }
+
+// String returns a description of the trade.
+func (e TradeEvent) String() string {
+	return fmt.Sprintf("Trade(%s %s %s @ %s)", e.Pair, e.Side, e.Size, e.Price)
+}
+
+// FundingEvent is a perpetual funding rate taking effect. It sets the
+// snapshotkeys PerpFundingRate, and PerpMarkPrice when MarkPrice is set.
+type FundingEvent struct {
+	At     primitives.Time
+	Symbol string
+
+	// Rate is the funding rate per funding period
+	Rate primitives.Decimal
+
+	// MarkPrice is the mark price the funding applies at; zero if unknown
+	MarkPrice primitives.Price
+}
+
+// Time returns when the funding rate took effect.
+func (e FundingEvent) Time() primitives.Time { return e.At }
+
+// String returns a description of the funding event.
+func (e FundingEvent) String() string {
+	return fmt.Sprintf("Funding(%s %s)", e.Symbol, e.Rate)
+}
+
+// LiquidationEvent is a forced liquidation on a pair. It adds to the
+// snapshotkeys LiquidationVolume of the pair without moving its price.
+type LiquidationEvent struct {
+	At    primitives.Time
+	Pair  string
+	Price primitives.Price
+	Size  primitives.Amount
+
+	// Side is the side of the forced order: a liquidated long sells
+	Side mechanisms.OrderSide
+}
+
+// Time returns when the liquidation occurred.
+func (e LiquidationEvent) Time() primitives.Time { return e.At }
+
+// String returns a description of the liquidation.
+func (e LiquidationEvent) String() string {
+	return fmt.Sprintf("Liquidation(%s %s %s @ %s)", e.Pair, e.Side, e.Size, e.Price)
+}
+
+// EventStrategy is an optional Strategy extension for strategies that react
+// to individual events. In an event-driven backtest the engine calls
+// OnEvent instead of Rebalance, with the snapshot of the market state
+// after the event.
+type EventStrategy interface {
+	Strategy
+
+	// OnEvent returns the actions to take in response to event.
+	OnEvent(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot, event Event) ([]Action, error)
+}
+
+// MarketState folds a stream of events into snapshots. Prices and funding
+// persist from event to event; bars and liquidation volume cover the
+// events since the previous snapshot.
+//
+// Thread Safety: MarketState is not thread-safe.
+type MarketState struct {
+	last         map[string]primitives.Price
+	bars         map[string]Bar
+	funding      map[string]FundingEvent
+	liquidations map[string]primitives.Decimal
+}
+
+// NewMarketState creates an empty market state.
+func NewMarketState() *MarketState {
+	return &MarketState{
+		last:         make(map[string]primitives.Price),
+		bars:         make(map[string]Bar),
+		funding:      make(map[string]FundingEvent),
+		liquidations: make(map[string]primitives.Decimal),
+	}
+}
+
+// Apply folds event into the state. Event types other than TradeEvent,
+// FundingEvent and LiquidationEvent are ignored, so custom events can
+// still reach an EventStrategy.
+func (s *MarketState) Apply(event Event) {
+	switch e := event.(type) {
+	case TradeEvent:
+		s.trade(e)
+	case *TradeEvent:
+		s.trade(*e)
+	case FundingEvent:
+		s.funding[e.Symbol] = e
+	case *FundingEvent:
+		s.funding[e.Symbol] = *e
+	case LiquidationEvent:
+		s.liquidations[e.Pair] = s.liquidations[e.Pair].Add(e.Size.Decimal())
+	case *LiquidationEvent:
+		s.liquidations[e.Pair] = s.liquidations[e.Pair].Add(e.Size.Decimal())
+	}
+}
+
+func (s *MarketState) trade(e TradeEvent) {
+	s.last[e.Pair] = e.Price
+	bar, ok := s.bars[e.Pair]
+	if !ok {
+		s.bars[e.Pair] = Bar{Open: e.Price, High: e.Price, Low: e.Price, Close: e.Price, Volume: e.Size}
+		return
+	}
+	if e.Price.GreaterThan(bar.High) {
+		bar.High = e.Price
+	}
+	if e.Price.LessThan(bar.Low) {
+		bar.Low = e.Price
+	}
+	bar.Close = e.Price
+	bar.Volume = bar.Volume.Add(e.Size)
+	s.bars[e.Pair] = bar
+}
+
+// Snapshot returns the market state at t and starts a new interval. Each
+// traded pair gets a bar of the trades since the previous snapshot, or a
+// flat zero-volume bar at its last price if it did not trade.
+func (s *MarketState) Snapshot(t primitives.Time) (*OHLCVSnapshot, error) {
+	bars := make(map[string]Bar, len(s.last))
+	for pair, price := range s.last {
+		if bar, ok := s.bars[pair]; ok {
+			bars[pair] = bar
+			continue
+		}
+		bars[pair] = Bar{Open: price, High: price, Low: price, Close: price, Volume: primitives.ZeroAmount()}
+	}
+	snapshot, err := NewOHLCVSnapshot(t, bars, nil)
+	if err != nil {
+		return nil, err
+	}
+	for symbol, funding := range s.funding {
+		snapshot.Set(snapshotkeys.PerpFundingRate(symbol), funding.Rate)
+		if !funding.MarkPrice.IsZero() {
+			snapshot.Set(snapshotkeys.PerpMarkPrice(symbol), funding.MarkPrice.Decimal())
+		}
+	}
+	for pair, volume := range s.liquidations {
+		snapshot.Set(snapshotkeys.LiquidationVolume(pair), volume)
+	}
+
+	clear(s.bars)
+	clear(s.liquidations)
+	return snapshot, nil
+}
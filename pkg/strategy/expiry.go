@@ -0,0 +1,106 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Settleable is an optional Position extension for positions that settle
+// for a cash flow rather than close at their value, such as an option
+// paying its intrinsic value or a perpetual paying out its P&L (see
+// blackscholes.OptionPosition and perpetual.FuturePosition).
+// SettlePositionAction settles them.
+type Settleable interface {
+	Position
+
+	// Settle returns the cash the position pays at settlement, using the
+	// snapshot's prices (e.g., an option's intrinsic value times its
+	// quantity). It is negative for a liability such as a short option or
+	// a losing perpetual. Returns error if required prices are not
+	// available.
+	Settle(snapshot MarketSnapshot) (primitives.Decimal, error)
+}
+
+// Expirable is an optional Position extension for positions that settle at
+// a fixed time, such as options and dated futures. The backtest engine
+// settles an Expirable position at the first snapshot at or after its
+// expiry, so strategies need not settle it by hand.
+type Expirable interface {
+	Settleable
+
+	// Expiry returns when the position settles.
+	Expiry() primitives.Time
+}
+
+// Expired reports whether position is Expirable and its expiry is at or
+// before now.
+func Expired(position Position, now primitives.Time) bool {
+	expirable, ok := position.(Expirable)
+	return ok && !expirable.Expiry().After(now)
+}
+
+// SettlePositionAction settles a Settleable position, such as an
+// Expirable one: it removes the position and adjusts cash by its signed
+// settlement cash flow in one step, so a losing settlement debits cash.
+// The cash beyond the position's cost basis is realized P&L (see PnL).
+//
+// The payoff depends on prices, so the action is a DeferredAction: the
+// backtest engine resolves it against the snapshot it executes at. To
+// apply it directly, call Resolve first.
+type SettlePositionAction struct {
+	PositionID string
+
+	snapshot MarketSnapshot
+}
+
+// NewSettlePositionAction creates an action settling positionID.
+func NewSettlePositionAction(positionID string) *SettlePositionAction {
+	return &SettlePositionAction{PositionID: positionID}
+}
+
+// Resolve implements DeferredAction, binding the action to snapshot.
+func (a *SettlePositionAction) Resolve(snapshot MarketSnapshot) (Action, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("%w: settlement of %s needs a snapshot", ErrInvalidAction, a.PositionID)
+	}
+	resolved := *a
+	resolved.snapshot = snapshot
+	return &resolved, nil
+}
+
+// Apply removes the position and adjusts cash by its settlement value.
+// Returns ErrInvalidAction if the action has not been resolved or the
+// position is not Settleable, and ErrPositionNotFound if the position does
+// not exist.
+func (a *SettlePositionAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	if a.snapshot == nil {
+		return fmt.Errorf("%w: settlement of %s must be resolved against a snapshot", ErrInvalidAction, a.PositionID)
+	}
+
+	position, err := portfolio.GetPosition(a.PositionID)
+	if err != nil {
+		return err
+	}
+	settleable, ok := position.(Settleable)
+	if !ok {
+		return fmt.Errorf("%w: position %s does not settle", ErrInvalidAction, a.PositionID)
+	}
+	cash, err := settleable.Settle(a.snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to settle %s: %w", a.PositionID, err)
+	}
+
+	if err := portfolio.RemovePosition(a.PositionID); err != nil {
+		return err
+	}
+	return portfolio.AdjustCash(cash)
+}
+
+// String returns a description of this action.
+func (a *SettlePositionAction) String() string {
+	return fmt.Sprintf("SettlePosition(%s)", a.PositionID)
+}
@@ -0,0 +1,234 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// UnderlyingPosition is an optional Position extension naming the asset
+// whose price drives the position's Greeks, such as "ETH" for an ETH call
+// or perpetual. Portfolio.Greeks groups delta by underlying; positions
+// without it are grouped under UnknownUnderlying.
+type UnderlyingPosition interface {
+	Position
+
+	// Underlying returns the underlying asset symbol.
+	Underlying() string
+}
+
+// UnknownUnderlying is the group of positions that do not implement
+// UnderlyingPosition.
+const UnknownUnderlying = ""
+
+// GreekExposure is the summed Greeks of a set of positions.
+type GreekExposure struct {
+	Delta primitives.Decimal
+	Gamma primitives.Decimal
+	Vega  primitives.Decimal
+	Theta primitives.Decimal
+	Rho   primitives.Decimal
+}
+
+func (g GreekExposure) add(risk RiskMetrics) GreekExposure {
+	return GreekExposure{
+		Delta: g.Delta.Add(risk.Delta),
+		Gamma: g.Gamma.Add(risk.Gamma),
+		Vega:  g.Vega.Add(risk.Vega),
+		Theta: g.Theta.Add(risk.Theta),
+		Rho:   g.Rho.Add(risk.Rho),
+	}
+}
+
+// BookGreeks is the portfolio's Greeks aggregated from the RiskMetrics of
+// its positions, in total and per underlying.
+type BookGreeks struct {
+	Total        GreekExposure
+	ByUnderlying map[string]GreekExposure
+
+	// Numerical lists the positions whose Greeks were estimated by
+	// revaluation rather than reported by Risk, in ID order
+	Numerical []string
+}
+
+// NetDelta returns the net delta to underlying.
+func (g BookGreeks) NetDelta(underlying string) primitives.Decimal {
+	return g.ByUnderlying[underlying].Delta
+}
+
+// Value returns the Greek a limit applies to: the net delta to
+// underlying, or the book's total gamma or vega.
+func (g BookGreeks) Value(greek Greek, underlying string) primitives.Decimal {
+	switch greek {
+	case GreekDelta:
+		return g.NetDelta(underlying)
+	case GreekGamma:
+		return g.Total.Gamma
+	case GreekVega:
+		return g.Total.Vega
+	}
+	return primitives.Zero()
+}
+
+// Greeks aggregates the Greeks of every position at snapshot. Positions
+// implementing PositionWithRisk report their own; the Greeks of others,
+// such as LPs and structured products, are estimated by NumericalGreeks
+// with DefaultBumps. A position naming its underlying is bumped in that
+// asset; others are bumped in every priced asset and grouped under each
+// asset they are sensitive to, with their theta and rho under
+// UnknownUnderlying. With a nil snapshot only reported Greeks are
+// aggregated. Returns error if a position's Risk or revaluation fails.
+func (p *Portfolio) Greeks(snapshot MarketSnapshot) (BookGreeks, error) {
+	return greeksOf(p.sortedPositions(), snapshot)
+}
+
+// greeksOf aggregates the Greeks of positions as Portfolio.Greeks does.
+func greeksOf(positions []Position, snapshot MarketSnapshot) (BookGreeks, error) {
+	greeks := BookGreeks{ByUnderlying: make(map[string]GreekExposure)}
+	for _, position := range positions {
+		risky, ok := position.(PositionWithRisk)
+		if !ok {
+			if snapshot == nil {
+				continue
+			}
+			exposures, err := numericalExposures(position, snapshot)
+			if err != nil {
+				return BookGreeks{}, fmt.Errorf("failed to estimate greeks of %s: %w", position.ID(), err)
+			}
+			for underlying, risk := range exposures {
+				greeks.Total = greeks.Total.add(risk)
+				greeks.ByUnderlying[underlying] = greeks.ByUnderlying[underlying].add(risk)
+			}
+			greeks.Numerical = append(greeks.Numerical, position.ID())
+			continue
+		}
+		risk, err := risky.Risk(snapshot)
+		if err != nil {
+			return BookGreeks{}, fmt.Errorf("failed to get risk of %s: %w", position.ID(), err)
+		}
+		underlying := UnknownUnderlying
+		if u, ok := position.(UnderlyingPosition); ok {
+			underlying = u.Underlying()
+		}
+		greeks.Total = greeks.Total.add(risk)
+		greeks.ByUnderlying[underlying] = greeks.ByUnderlying[underlying].add(risk)
+	}
+	return greeks, nil
+}
+
+// Greek names a sensitivity that GreekLimits bounds.
+type Greek string
+
+const (
+	GreekDelta Greek = "delta"
+	GreekGamma Greek = "gamma"
+	GreekVega  Greek = "vega"
+)
+
+// GreekBreach is a Greek whose magnitude exceeds its limit.
+type GreekBreach struct {
+	Greek Greek
+
+	// Underlying is the underlying of a delta breach; gamma and vega are
+	// limited across the book
+	Underlying string
+
+	Value primitives.Decimal
+	Limit primitives.Decimal
+}
+
+// Error describes the breach and wraps ErrGreekLimitBreached.
+func (b GreekBreach) Error() string {
+	return fmt.Sprintf("%s: %s", ErrGreekLimitBreached, b.String())
+}
+
+// Unwrap returns ErrGreekLimitBreached.
+func (b GreekBreach) Unwrap() error {
+	return ErrGreekLimitBreached
+}
+
+// String returns a description such as "|ETH delta| 12 > 10".
+func (b GreekBreach) String() string {
+	name := string(b.Greek)
+	if b.Underlying != "" {
+		name = b.Underlying + " " + name
+	}
+	return fmt.Sprintf("|%s| %s > %s", name, b.Value.Abs(), b.Limit)
+}
+
+// GreekLimits bounds the book's Greeks the way a derivatives desk limits
+// its risk: the absolute net delta to each underlying, and the absolute
+// gamma and vega of the whole book. Only positive limits are enforced, so
+// the zero value imposes none. GreekLimits is a Constraint, so it can be
+// checked by Portfolio.Validate; the backtest engine can also enforce it
+// with a breach policy.
+type GreekLimits struct {
+	// NetDelta maps an underlying to its maximum absolute net delta
+	NetDelta map[string]primitives.Decimal
+
+	// Gamma is the maximum absolute book gamma
+	Gamma primitives.Decimal
+
+	// Vega is the maximum absolute book vega
+	Vega primitives.Decimal
+}
+
+// IsZero reports whether no limit is set.
+func (l GreekLimits) IsZero() bool {
+	for _, limit := range l.NetDelta {
+		if limit.IsPositive() {
+			return false
+		}
+	}
+	return !l.Gamma.IsPositive() && !l.Vega.IsPositive()
+}
+
+// Breaches returns the limits greeks exceed: deltas in underlying order,
+// then gamma, then vega.
+func (l GreekLimits) Breaches(greeks BookGreeks) []GreekBreach {
+	var breaches []GreekBreach
+	underlyings := make([]string, 0, len(l.NetDelta))
+	for underlying := range l.NetDelta {
+		underlyings = append(underlyings, underlying)
+	}
+	sort.Strings(underlyings)
+	for _, underlying := range underlyings {
+		if limit := l.NetDelta[underlying]; exceeds(greeks.NetDelta(underlying), limit) {
+			breaches = append(breaches, GreekBreach{Greek: GreekDelta, Underlying: underlying, Value: greeks.NetDelta(underlying), Limit: limit})
+		}
+	}
+	if exceeds(greeks.Total.Gamma, l.Gamma) {
+		breaches = append(breaches, GreekBreach{Greek: GreekGamma, Value: greeks.Total.Gamma, Limit: l.Gamma})
+	}
+	if exceeds(greeks.Total.Vega, l.Vega) {
+		breaches = append(breaches, GreekBreach{Greek: GreekVega, Value: greeks.Total.Vega, Limit: l.Vega})
+	}
+	return breaches
+}
+
+// Check implements Constraint, failing if any limit is breached.
+func (l GreekLimits) Check(portfolio *Portfolio, snapshot MarketSnapshot) error {
+	if l.IsZero() {
+		return nil
+	}
+	greeks, err := portfolio.Greeks(snapshot)
+	if err != nil {
+		return err
+	}
+	breaches := l.Breaches(greeks)
+	if len(breaches) == 0 {
+		return nil
+	}
+	descriptions := make([]string, len(breaches))
+	for i, breach := range breaches {
+		descriptions[i] = breach.String()
+	}
+	return fmt.Errorf("%w: %s", ErrGreekLimitBreached, strings.Join(descriptions, ", "))
+}
+
+// exceeds reports whether a positive limit is below |value|.
+func exceeds(value, limit primitives.Decimal) bool {
+	return limit.IsPositive() && value.Abs().GreaterThan(limit)
+}
@@ -0,0 +1,303 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// GridConfig configures a GridTrader.
+type GridConfig struct {
+	// Pair is the snapshot price key the grid trades (e.g., "ETH/USD")
+	Pair string
+
+	// Lower and Upper bound the grid: the lowest and highest levels
+	Lower primitives.Price
+	Upper primitives.Price
+
+	// Levels is the number of price levels from Lower to Upper inclusive,
+	// at least 2
+	Levels int
+
+	// Geometric spaces levels by a constant ratio instead of a constant
+	// difference, so each step is the same percentage move
+	Geometric bool
+
+	// Size is the units of Pair bought or sold at each level
+	Size primitives.Amount
+
+	// PositionID is the Holding the grid's fills accumulate in; empty uses
+	// "grid:<pair>"
+	PositionID string
+}
+
+// GridTrader is a reusable grid trading Strategy: it keeps a ladder of
+// limit orders at fixed levels within a price range, buying Size at each
+// level below the price and selling Size at each level above it, and
+// turns each fill into the opposite order one level away. Every buy that
+// fills is followed by a sell one level up, and every sell by a buy one
+// level down, so the grid harvests the spacing each time the price
+// oscillates across a level.
+//
+// At the first snapshot it trades, the grid takes the price there as its
+// reference: it submits a market buy for the inventory its sell levels
+// need and a limit order at every level except one equal to the
+// reference. Outside the range the orders simply rest; the grid does not
+// re-center. Reused for another run, on a portfolio that does not hold
+// its inventory, the grid discards its orders and inventory and lays
+// itself out afresh.
+//
+// GridTrader uses the order management layer: it must run on an engine
+// that manages an order book (such as the backtest engine), which reports
+// fills to it as an OrderListener. A level's counterpart order is placed
+// once its order has filled completely, at the next rebalance. A level
+// whose order is rejected is left empty. Inventory and realized P&L are
+// tracked from the fills at average cost.
+//
+// Thread Safety: GridTrader is not thread-safe; it tracks its open orders
+// and inventory.
+type GridTrader struct {
+	config GridConfig
+	levels []primitives.Price
+
+	started   bool
+	portfolio *Portfolio
+	sequence  int
+	open      map[string]gridOrder
+	pending   []Action
+
+	inventory primitives.Decimal
+	cost      primitives.Decimal
+	realized  primitives.Decimal
+	trips     int
+}
+
+// gridOrder records the level and side of an open grid order. The initial
+// inventory purchase has level -1.
+type gridOrder struct {
+	level int
+	side  mechanisms.OrderSide
+}
+
+// NewGridTrader creates a grid trader. Returns ErrInvalidParams if the
+// pair is empty, the range is empty, there are fewer than 2 levels or the
+// size is zero.
+func NewGridTrader(config GridConfig) (*GridTrader, error) {
+	if config.Pair == "" {
+		return nil, fmt.Errorf("%w: grid pair cannot be empty", ErrInvalidParams)
+	}
+	if config.Lower.IsZero() || !config.Lower.LessThan(config.Upper) {
+		return nil, fmt.Errorf("%w: grid range must satisfy 0 < lower < upper, got [%s, %s]", ErrInvalidParams, config.Lower, config.Upper)
+	}
+	if config.Levels < 2 {
+		return nil, fmt.Errorf("%w: grid needs at least 2 levels, got %d", ErrInvalidParams, config.Levels)
+	}
+	if config.Size.IsZero() {
+		return nil, fmt.Errorf("%w: grid size must be positive", ErrInvalidParams)
+	}
+	if config.PositionID == "" {
+		config.PositionID = "grid:" + config.Pair
+	}
+
+	levels, err := gridLevels(config)
+	if err != nil {
+		return nil, err
+	}
+	return &GridTrader{
+		config:    config,
+		levels:    levels,
+		open:      make(map[string]gridOrder),
+		inventory: primitives.Zero(),
+		cost:      primitives.Zero(),
+		realized:  primitives.Zero(),
+	}, nil
+}
+
+// gridLevels returns the level prices from Lower to Upper.
+func gridLevels(config GridConfig) ([]primitives.Price, error) {
+	lower, upper := config.Lower.Decimal(), config.Upper.Decimal()
+	steps := primitives.NewDecimal(int64(config.Levels - 1))
+	levels := make([]primitives.Price, config.Levels)
+	for i := range levels {
+		fraction, err := primitives.NewDecimal(int64(i)).Div(steps)
+		if err != nil {
+			return nil, err
+		}
+		price := lower.Add(upper.Sub(lower).Mul(fraction))
+		if config.Geometric {
+			ratio, err := upper.Div(lower)
+			if err != nil {
+				return nil, err
+			}
+			growth, err := ratio.Pow(fraction)
+			if err != nil {
+				return nil, fmt.Errorf("failed to space grid levels: %w", err)
+			}
+			price = lower.Mul(growth)
+		}
+		if levels[i], err = primitives.NewPrice(price); err != nil {
+			return nil, err
+		}
+	}
+	// Pin the ends so rounding in the spacing cannot move the range
+	levels[0], levels[len(levels)-1] = config.Lower, config.Upper
+	return levels, nil
+}
+
+// Levels returns the grid's level prices in ascending order.
+func (g *GridTrader) Levels() []primitives.Price {
+	return append([]primitives.Price(nil), g.levels...)
+}
+
+// PositionID returns the ID of the holding the grid's fills accumulate in.
+func (g *GridTrader) PositionID() string {
+	return g.config.PositionID
+}
+
+// Inventory returns the units of the pair the grid's fills have bought
+// net of those sold.
+func (g *GridTrader) Inventory() primitives.Decimal {
+	return g.inventory
+}
+
+// RealizedPnL returns the profit realized by the grid's sells against the
+// average cost of its inventory.
+func (g *GridTrader) RealizedPnL() primitives.Decimal {
+	return g.realized
+}
+
+// RoundTrips returns the number of sell orders the grid has filled
+// completely.
+func (g *GridTrader) RoundTrips() int {
+	return g.trips
+}
+
+// OpenOrders returns the number of grid orders resting or awaiting
+// submission.
+func (g *GridTrader) OpenOrders() int {
+	return len(g.open)
+}
+
+// Rebalance lays out the grid at the first snapshot it can trade, then
+// submits the counterpart orders of levels filled since the last
+// snapshot.
+func (g *GridTrader) Rebalance(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	if WarmingUp(ctx) {
+		return nil, nil
+	}
+	if g.started && portfolio != g.portfolio && !portfolio.HasPosition(g.config.PositionID) {
+		g.reset()
+	}
+	g.portfolio = portfolio
+	if g.started {
+		actions := g.pending
+		g.pending = nil
+		return actions, nil
+	}
+
+	reference, err := snapshot.Price(g.config.Pair)
+	if err != nil {
+		return nil, fmt.Errorf("grid: %w", err)
+	}
+	g.started = true
+
+	var orders []Action
+	above := 0
+	for level, price := range g.levels {
+		switch {
+		case price.LessThan(reference):
+			orders = append(orders, g.order(level, mechanisms.OrderSideBuy))
+		case reference.LessThan(price):
+			orders = append(orders, g.order(level, mechanisms.OrderSideSell))
+			above++
+		}
+	}
+	if above == 0 {
+		return orders, nil
+	}
+
+	id := g.nextID()
+	g.open[id] = gridOrder{level: -1, side: mechanisms.OrderSideBuy}
+	size := primitives.MustAmount(g.config.Size.Decimal().Mul(primitives.NewDecimal(int64(above))))
+	inventory := NewMarketOrder(id, g.config.Pair, mechanisms.OrderSideBuy, size)
+	inventory.PositionID = g.config.PositionID
+	return append([]Action{NewSubmitOrderAction(inventory)}, orders...), nil
+}
+
+// reset discards the grid's orders, inventory and statistics so it can be
+// laid out again.
+func (g *GridTrader) reset() {
+	g.started = false
+	g.open = make(map[string]gridOrder)
+	g.pending = nil
+	g.inventory = primitives.Zero()
+	g.cost = primitives.Zero()
+	g.realized = primitives.Zero()
+	g.trips = 0
+}
+
+// order records and returns an action submitting a limit order at level.
+func (g *GridTrader) order(level int, side mechanisms.OrderSide) Action {
+	id := g.nextID()
+	g.open[id] = gridOrder{level: level, side: side}
+	order := NewLimitOrder(id, g.config.Pair, side, g.config.Size, g.levels[level])
+	order.PositionID = g.config.PositionID
+	return NewSubmitOrderAction(order)
+}
+
+// nextID returns a new order ID, unique within the grid.
+func (g *GridTrader) nextID() string {
+	g.sequence++
+	return fmt.Sprintf("grid:%s:%d", g.config.Pair, g.sequence)
+}
+
+// OnOrderUpdate tracks the grid's fills and queues the counterpart order
+// of each level that fills completely. Updates to other orders are
+// ignored.
+func (g *GridTrader) OnOrderUpdate(update OrderUpdate) {
+	placed, ok := g.open[update.Order.ID]
+	if !ok {
+		return
+	}
+	if update.Fill != nil {
+		g.record(*update.Fill)
+	}
+
+	switch update.Status {
+	case OrderStatusFilled:
+		delete(g.open, update.Order.ID)
+		switch {
+		case placed.level < 0:
+		case placed.side == mechanisms.OrderSideBuy && placed.level+1 < len(g.levels):
+			g.pending = append(g.pending, g.order(placed.level+1, mechanisms.OrderSideSell))
+		case placed.side == mechanisms.OrderSideSell:
+			g.trips++
+			if placed.level > 0 {
+				g.pending = append(g.pending, g.order(placed.level-1, mechanisms.OrderSideBuy))
+			}
+		}
+	case OrderStatusCanceled, OrderStatusExpired, OrderStatusRejected:
+		delete(g.open, update.Order.ID)
+	}
+}
+
+// record updates inventory, cost and realized P&L for a fill.
+func (g *GridTrader) record(fill Fill) {
+	if fill.Side == mechanisms.OrderSideBuy {
+		g.inventory = g.inventory.Add(fill.Quantity)
+		g.cost = g.cost.Add(fill.Notional())
+		return
+	}
+	basis := g.cost
+	if fill.Quantity.LessThan(g.inventory) {
+		share, err := fill.Quantity.Div(g.inventory)
+		if err == nil {
+			basis = g.cost.Mul(share)
+		}
+	}
+	g.realized = g.realized.Add(fill.Notional().Sub(basis))
+	g.cost = g.cost.Sub(basis)
+	g.inventory = g.inventory.Sub(fill.Quantity)
+}
@@ -0,0 +1,167 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PortfolioEventType classifies a recorded portfolio change.
+type PortfolioEventType string
+
+const (
+	// EventPositionAdded records a position entering the portfolio
+	EventPositionAdded PortfolioEventType = "position_added"
+
+	// EventPositionRemoved records a position leaving the portfolio
+	EventPositionRemoved PortfolioEventType = "position_removed"
+
+	// EventCashAdjusted records a relative cash change
+	EventCashAdjusted PortfolioEventType = "cash_adjusted"
+
+	// EventCashSet records cash being set to an absolute balance
+	EventCashSet PortfolioEventType = "cash_set"
+
+	// EventCleared records all positions being removed and cash reset to zero
+	EventCleared PortfolioEventType = "cleared"
+)
+
+// PortfolioEvent is a single recorded change to a portfolio.
+type PortfolioEvent struct {
+	// Time is the portfolio clock when the change happened (see Portfolio.SetTime)
+	Time primitives.Time
+
+	// Type is the kind of change
+	Type PortfolioEventType
+
+	// PositionID and Position are set for position events
+	PositionID string
+	Position   Position
+
+	// CashDelta is the signed cash change (zero for position events)
+	CashDelta primitives.Decimal
+
+	// Cash is the cash balance after the change
+	Cash primitives.Decimal
+}
+
+// portfolioHistory holds the state needed to replay a portfolio's changes.
+type portfolioHistory struct {
+	baseTime      primitives.Time
+	basePositions map[string]Position
+	baseCash      primitives.Decimal
+	events        []PortfolioEvent
+}
+
+// EnableHistory starts recording every position and cash change so the
+// portfolio can later be reconstructed with At. The current state becomes
+// the baseline. Calling EnableHistory again resets the recorded history.
+//
+// History is off by default because it retains every position ever held.
+func (p *Portfolio) EnableHistory() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	base := make(map[string]Position, len(p.positions))
+	for id, pos := range p.positions {
+		base[id] = pos
+	}
+	p.history = &portfolioHistory{
+		baseTime:      p.now,
+		basePositions: base,
+		baseCash:      p.cashDecimal,
+	}
+}
+
+// HistoryEnabled reports whether the portfolio is recording history.
+func (p *Portfolio) HistoryEnabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.history != nil
+}
+
+// SetTime sets the portfolio clock used to timestamp recorded events and
+// log records. The backtest engine advances it to each snapshot's time
+// before rebalancing.
+func (p *Portfolio) SetTime(t primitives.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.now = t
+}
+
+// History returns a copy of all recorded events in the order they occurred.
+// Returns nil if history is disabled.
+func (p *Portfolio) History() []PortfolioEvent {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.history == nil {
+		return nil
+	}
+	events := make([]PortfolioEvent, len(p.history.events))
+	copy(events, p.history.events)
+	return events
+}
+
+// At reconstructs the portfolio as it was at time t by replaying recorded
+// events up to and including t. The returned portfolio is independent and
+// does not record history.
+//
+// Returns ErrHistoryDisabled if history is not enabled, or an error if t
+// precedes the time history was enabled.
+func (p *Portfolio) At(t primitives.Time) (*Portfolio, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	if t.Before(p.history.baseTime) {
+		return nil, fmt.Errorf("%w: %s precedes history start %s", ErrHistoryDisabled, t, p.history.baseTime)
+	}
+
+	positions := make(map[string]Position, len(p.history.basePositions))
+	for id, pos := range p.history.basePositions {
+		positions[id] = pos
+	}
+	cash := p.history.baseCash
+
+	for _, event := range p.history.events {
+		if event.Time.After(t) {
+			break
+		}
+		switch event.Type {
+		case EventPositionAdded:
+			positions[event.PositionID] = event.Position
+		case EventPositionRemoved:
+			delete(positions, event.PositionID)
+		case EventCleared:
+			positions = make(map[string]Position)
+		}
+		cash = event.Cash
+	}
+
+	return &Portfolio{
+		positions:   positions,
+		ids:         sortedIDs(positions),
+		cashDecimal: cash,
+	}, nil
+}
+
+// record appends an event stamped with the portfolio clock.
+// Caller must hold the write lock.
+func (p *Portfolio) record(event PortfolioEvent) {
+	if p.history == nil && p.logger == nil {
+		return
+	}
+	event.Time = p.now
+	event.Cash = p.cashDecimal
+	if p.logger != nil {
+		p.log(event)
+	}
+	if p.history != nil {
+		p.history.events = append(p.history.events, event)
+	}
+}
@@ -5,6 +5,10 @@
 package strategy
 
 import (
+	"fmt"
+	"sort"
+	"sync"
+
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 )
 
@@ -26,7 +30,9 @@ type MarketSnapshot interface {
 
 	// Price returns the current price for the given asset pair.
 	// The pair format is implementation-specific but should be consistent
-	// (e.g., "ETH/USDC", "ETH-USDC", "ETHUSDC").
+	// (e.g., "ETH/USDC", "ETH-USDC", "ETHUSDC"). The canonical form is
+	// primitives.Pair.String() ("ETH/USDC"); use PairPrice to look up a
+	// primitives.Pair across formats, asset aliases and inversions.
 	//
 	// Returns error if the price is not available for the given pair.
 	Price(pair string) (primitives.Price, error)
@@ -42,7 +48,9 @@ type MarketSnapshot interface {
 	// (e.g., liquidity depth, funding rates, volatility surfaces) without
 	// extending the interface.
 	//
-	// Returns false if the key doesn't exist.
+	// Returns false if the key doesn't exist. Prefer the typed accessors
+	// (GetString, GetDecimal, GetInt, GetPrice) and snapshotkeys builders
+	// over direct type assertions.
 	//
 	// Example:
 	//   liquidity, ok := snapshot.Get("uniswap-v3:ETH/USDC:liquidity")
@@ -50,12 +58,52 @@ type MarketSnapshot interface {
 	Get(key string) (interface{}, bool)
 }
 
+// MetadataLister is an optional MarketSnapshot extension that enumerates
+// metadata keys. Utilities that copy or merge snapshots (e.g., resampling)
+// use it to carry metadata across; snapshots without it only carry prices.
+type MetadataLister interface {
+	// MetadataKeys returns all metadata keys in sorted order.
+	MetadataKeys() []string
+}
+
+// PairPricer is an optional MarketSnapshot extension that looks up prices
+// by primitives.Pair. Implementations match price keys in any ParsePair
+// format, apply their configured asset aliases and fall back to the
+// inverse pair. PairPrice uses it when available.
+type PairPricer interface {
+	// PriceOf returns the price of pair.
+	// Returns ErrPriceNotAvailable if no form of the pair is quoted.
+	PriceOf(pair primitives.Pair) (primitives.Price, error)
+}
+
+// PriceTimestamper is an optional MarketSnapshot extension reporting when
+// each price was observed, for snapshots that merge quotes of different
+// ages. Prices without a recorded time are assumed to be as of Time().
+type PriceTimestamper interface {
+	// PriceTime returns the observation time of pair's price.
+	// Returns false if no separate time is recorded.
+	PriceTime(pair string) (primitives.Time, bool)
+}
+
 // SimpleSnapshot provides a basic implementation of MarketSnapshot
 // backed by an in-memory map. Useful for testing and simple strategies.
 type SimpleSnapshot struct {
 	time   primitives.Time
 	prices map[string]primitives.Price
 	data   map[string]interface{}
+
+	// priceTimes optionally records per-pair observation times
+	priceTimes map[string]primitives.Time
+
+	// aliases canonicalizes asset symbols for pair lookups
+	aliases primitives.AssetAliases
+
+	// pairKeys maps each canonical pair to its price key, built lazily for
+	// lookups that miss the exact key; pairKeysLen detects added keys.
+	// pairMu guards both, as snapshots are shared across goroutines.
+	pairMu      sync.Mutex
+	pairKeys    map[primitives.Pair]string
+	pairKeysLen int
 }
 
 // NewSimpleSnapshot creates a new SimpleSnapshot with the given time and prices.
@@ -73,14 +121,78 @@ func (s *SimpleSnapshot) Time() primitives.Time {
 }
 
 // Price returns the price for the given pair.
+// Keys are matched exactly first, then by canonical pair, so "eth-usd",
+// "ETHUSD" and (with aliases) "WETH/USD" all find a price stored under
+// "ETH/USD". Inverse pairs are not resolved here; use PriceOf.
 func (s *SimpleSnapshot) Price(pair string) (primitives.Price, error) {
-	price, ok := s.prices[pair]
-	if !ok {
+	if price, ok := s.prices[pair]; ok {
+		return price, nil
+	}
+	parsed, err := primitives.ParsePair(pair)
+	if err != nil {
 		return primitives.Price{}, ErrPriceNotAvailable
 	}
+	if price, ok := s.canonicalPrice(parsed); ok {
+		return price, nil
+	}
+	return primitives.Price{}, ErrPriceNotAvailable
+}
+
+// PriceOf returns the price of pair, matching keys in any format and with
+// the snapshot's asset aliases, and falling back to the reciprocal of the
+// inverse pair's price.
+func (s *SimpleSnapshot) PriceOf(pair primitives.Pair) (primitives.Price, error) {
+	if price, ok := s.canonicalPrice(pair); ok {
+		return price, nil
+	}
+	inverse, ok := s.canonicalPrice(pair.Inverse())
+	if !ok {
+		return primitives.Price{}, fmt.Errorf("%w: %s", ErrPriceNotAvailable, pair)
+	}
+	price, err := pair.Inverse().InvertPrice(inverse)
+	if err != nil {
+		return primitives.Price{}, fmt.Errorf("%w: %s (inverse quote is zero)", ErrPriceNotAvailable, pair)
+	}
 	return price, nil
 }
 
+// SetAssetAliases sets the asset aliases applied to pair lookups, e.g.
+// primitives.CommonAssetAliases to match "WETH/USDC" with "ETH/USDC".
+func (s *SimpleSnapshot) SetAssetAliases(aliases primitives.AssetAliases) {
+	s.pairMu.Lock()
+	defer s.pairMu.Unlock()
+	s.aliases = aliases
+	s.pairKeys = nil
+}
+
+// canonicalPrice looks up pair by its canonical form. When several keys
+// canonicalize to the same pair, the first in sorted key order wins.
+func (s *SimpleSnapshot) canonicalPrice(pair primitives.Pair) (primitives.Price, bool) {
+	s.pairMu.Lock()
+	defer s.pairMu.Unlock()
+
+	if s.pairKeys == nil || s.pairKeysLen != len(s.prices) {
+		s.pairKeys = make(map[primitives.Pair]string, len(s.prices))
+		s.pairKeysLen = len(s.prices)
+		for _, key := range sortedPriceKeys(s.prices) {
+			parsed, err := primitives.ParsePair(key)
+			if err != nil {
+				continue
+			}
+			canonical := s.aliases.Pair(parsed)
+			if _, exists := s.pairKeys[canonical]; !exists {
+				s.pairKeys[canonical] = key
+			}
+		}
+	}
+	key, ok := s.pairKeys[s.aliases.Pair(pair)]
+	if !ok {
+		return primitives.Price{}, false
+	}
+	price, ok := s.prices[key]
+	return price, ok
+}
+
 // Prices returns all available prices in this snapshot.
 func (s *SimpleSnapshot) Prices() map[string]primitives.Price {
 	return s.prices
@@ -93,7 +205,49 @@ func (s *SimpleSnapshot) Get(key string) (interface{}, bool) {
 }
 
 // Set stores custom metadata in the snapshot.
-// This method is provided for test and setup purposes.
+// This method is provided for test and setup purposes. It changes the
+// snapshot for everyone holding it; use WithMetadata, or an
+// ImmutableSnapshot, for snapshots shared between tests or runs.
 func (s *SimpleSnapshot) Set(key string, value interface{}) {
 	s.data[key] = value
 }
+
+// PriceTime returns the observation time recorded for pair's price.
+func (s *SimpleSnapshot) PriceTime(pair string) (primitives.Time, bool) {
+	t, ok := s.priceTimes[pair]
+	return t, ok
+}
+
+// SetPriceTime records when pair's price was observed, for quotes older
+// than the snapshot time. This method is provided for test and setup purposes.
+func (s *SimpleSnapshot) SetPriceTime(pair string, t primitives.Time) {
+	if s.priceTimes == nil {
+		s.priceTimes = make(map[string]primitives.Time)
+	}
+	s.priceTimes[pair] = t
+}
+
+// MetadataKeys returns all metadata keys in sorted order.
+func (s *SimpleSnapshot) MetadataKeys() []string {
+	return sortedKeys(s.data)
+}
+
+// sortedPriceKeys returns the keys of a price map in sorted order.
+func sortedPriceKeys(prices map[string]primitives.Price) []string {
+	keys := make([]string, 0, len(prices))
+	for key := range prices {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys returns the keys of a metadata map in sorted order.
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
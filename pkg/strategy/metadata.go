@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Typed accessors for MarketSnapshot metadata.
+//
+// MarketSnapshot.Get returns interface{} so that any data can be attached
+// to a snapshot. These helpers perform the type assertion and conversion
+// in one place and report descriptive errors, so strategies don't repeat
+// unchecked casts:
+//
+//	sqrtPrice, err := strategy.GetString(snapshot, snapshotkeys.PoolSqrtPrice("eth-usdc"))
+//	funding, err := strategy.GetDecimal(snapshot, snapshotkeys.PerpFundingRate("eth"))
+//
+// Missing keys wrap ErrMetadataNotFound; values of an unsupported type wrap
+// ErrMetadataType.
+
+// GetString returns the metadata value for key as a string.
+func GetString(snapshot MarketSnapshot, key string) (string, error) {
+	raw, err := getMetadata(snapshot, key)
+	if err != nil {
+		return "", err
+	}
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		return "", typeError(key, "string", raw)
+	}
+}
+
+// GetDecimal returns the metadata value for key as a Decimal.
+// Accepts Decimal, Price, Amount, numeric Go types and decimal strings.
+func GetDecimal(snapshot MarketSnapshot, key string) (primitives.Decimal, error) {
+	raw, err := getMetadata(snapshot, key)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	switch v := raw.(type) {
+	case primitives.Decimal:
+		return v, nil
+	case primitives.Price:
+		return v.Decimal(), nil
+	case primitives.Amount:
+		return v.Decimal(), nil
+	case float64:
+		return primitives.NewDecimalFromFloat(v), nil
+	case float32:
+		return primitives.NewDecimalFromFloat(float64(v)), nil
+	case int:
+		return primitives.NewDecimal(int64(v)), nil
+	case int64:
+		return primitives.NewDecimal(v), nil
+	case int32:
+		return primitives.NewDecimal(int64(v)), nil
+	case string:
+		d, err := primitives.NewDecimalFromString(v)
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("%w: key %s: %v", ErrMetadataType, key, err)
+		}
+		return d, nil
+	default:
+		return primitives.Zero(), typeError(key, "decimal", raw)
+	}
+}
+
+// GetInt returns the metadata value for key as an int.
+// Accepts int, int32 and int64 values.
+func GetInt(snapshot MarketSnapshot, key string) (int, error) {
+	raw, err := getMetadata(snapshot, key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := raw.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	default:
+		return 0, typeError(key, "int", raw)
+	}
+}
+
+// GetPrice returns the metadata value for key as a Price.
+// Accepts any value GetDecimal accepts; negative values are rejected.
+func GetPrice(snapshot MarketSnapshot, key string) (primitives.Price, error) {
+	d, err := GetDecimal(snapshot, key)
+	if err != nil {
+		return primitives.Price{}, err
+	}
+	price, err := primitives.NewPrice(d)
+	if err != nil {
+		return primitives.Price{}, fmt.Errorf("%w: key %s: %v", ErrMetadataType, key, err)
+	}
+	return price, nil
+}
+
+// getMetadata fetches a raw metadata value, wrapping ErrMetadataNotFound when absent.
+func getMetadata(snapshot MarketSnapshot, key string) (interface{}, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("%w: nil snapshot", ErrMetadataNotFound)
+	}
+	raw, ok := snapshot.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMetadataNotFound, key)
+	}
+	return raw, nil
+}
+
+// typeError reports a metadata value that cannot be converted to the wanted type.
+func typeError(key, want string, got interface{}) error {
+	return fmt.Errorf("%w: key %s: expected %s, got %T", ErrMetadataType, key, want, got)
+}
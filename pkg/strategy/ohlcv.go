@@ -0,0 +1,180 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Bar is an OHLCV candle for a single pair over the snapshot interval.
+type Bar struct {
+	Open   primitives.Price
+	High   primitives.Price
+	Low    primitives.Price
+	Close  primitives.Price
+	Volume primitives.Amount
+}
+
+// Validate checks that the bar is internally consistent:
+// Low <= Open, Close <= High.
+func (b Bar) Validate() error {
+	if b.High.LessThan(b.Low) {
+		return fmt.Errorf("%w: bar high %s below low %s", ErrInvalidMarketData, b.High, b.Low)
+	}
+	for _, p := range []primitives.Price{b.Open, b.Close} {
+		if p.LessThan(b.Low) || p.GreaterThan(b.High) {
+			return fmt.Errorf("%w: bar open/close %s outside [%s, %s]", ErrInvalidMarketData, p, b.Low, b.High)
+		}
+	}
+	return nil
+}
+
+// Range returns High - Low.
+func (b Bar) Range() primitives.Decimal {
+	return b.High.Decimal().Sub(b.Low.Decimal())
+}
+
+// Quote is the top-of-book bid and ask for a pair.
+type Quote struct {
+	Bid primitives.Price
+	Ask primitives.Price
+}
+
+// Validate checks that the quote is not crossed (Bid <= Ask).
+func (q Quote) Validate() error {
+	if q.Bid.GreaterThan(q.Ask) {
+		return fmt.Errorf("%w: crossed quote bid %s > ask %s", ErrInvalidMarketData, q.Bid, q.Ask)
+	}
+	return nil
+}
+
+// Mid returns the midpoint (Bid + Ask) / 2.
+func (q Quote) Mid() primitives.Price {
+	// Safe to ignore error: divisor is non-zero
+	mid, _ := q.Bid.Add(q.Ask).Div(primitives.NewDecimal(2))
+	return mid
+}
+
+// Spread returns Ask - Bid.
+func (q Quote) Spread() primitives.Decimal {
+	return q.Ask.Decimal().Sub(q.Bid.Decimal())
+}
+
+// BarSnapshot is an optional MarketSnapshot extension exposing OHLCV bars.
+// Strategies can type-assert a snapshot to BarSnapshot to reason about
+// intrabar moves while remaining compatible with close-only data.
+type BarSnapshot interface {
+	MarketSnapshot
+
+	// Bar returns the OHLCV bar for pair.
+	// Returns ErrBarNotAvailable if no bar is present.
+	Bar(pair string) (Bar, error)
+}
+
+// QuoteSnapshot is an optional MarketSnapshot extension exposing bid/ask quotes.
+type QuoteSnapshot interface {
+	MarketSnapshot
+
+	// Quote returns the top-of-book quote for pair.
+	// Returns ErrQuoteNotAvailable if no quote is present.
+	Quote(pair string) (Quote, error)
+}
+
+// OHLCVSnapshot is a MarketSnapshot backed by OHLCV bars and bid/ask quotes.
+//
+// Price(pair) returns the bar close when a bar exists, otherwise the quote
+// midpoint, so code written against MarketSnapshot keeps working unchanged.
+type OHLCVSnapshot struct {
+	time   primitives.Time
+	bars   map[string]Bar
+	quotes map[string]Quote
+	prices map[string]primitives.Price
+	data   map[string]interface{}
+}
+
+// NewOHLCVSnapshot creates a snapshot from bars and quotes (either may be nil).
+// Returns error if any bar or quote is inconsistent.
+func NewOHLCVSnapshot(time primitives.Time, bars map[string]Bar, quotes map[string]Quote) (*OHLCVSnapshot, error) {
+	if bars == nil {
+		bars = make(map[string]Bar)
+	}
+	if quotes == nil {
+		quotes = make(map[string]Quote)
+	}
+
+	prices := make(map[string]primitives.Price, len(bars)+len(quotes))
+	for pair, quote := range quotes {
+		if err := quote.Validate(); err != nil {
+			return nil, fmt.Errorf("quote %s: %w", pair, err)
+		}
+		prices[pair] = quote.Mid()
+	}
+	for pair, bar := range bars {
+		if err := bar.Validate(); err != nil {
+			return nil, fmt.Errorf("bar %s: %w", pair, err)
+		}
+		prices[pair] = bar.Close
+	}
+
+	return &OHLCVSnapshot{
+		time:   time,
+		bars:   bars,
+		quotes: quotes,
+		prices: prices,
+		data:   make(map[string]interface{}),
+	}, nil
+}
+
+// Time returns the timestamp of this snapshot (the bar close time).
+func (s *OHLCVSnapshot) Time() primitives.Time {
+	return s.time
+}
+
+// Price returns the bar close, or the quote midpoint if no bar exists.
+func (s *OHLCVSnapshot) Price(pair string) (primitives.Price, error) {
+	price, ok := s.prices[pair]
+	if !ok {
+		return primitives.Price{}, ErrPriceNotAvailable
+	}
+	return price, nil
+}
+
+// Prices returns the close/mid price for every pair in this snapshot.
+func (s *OHLCVSnapshot) Prices() map[string]primitives.Price {
+	return s.prices
+}
+
+// Bar returns the OHLCV bar for pair.
+func (s *OHLCVSnapshot) Bar(pair string) (Bar, error) {
+	bar, ok := s.bars[pair]
+	if !ok {
+		return Bar{}, fmt.Errorf("%w: %s", ErrBarNotAvailable, pair)
+	}
+	return bar, nil
+}
+
+// Quote returns the bid/ask quote for pair.
+func (s *OHLCVSnapshot) Quote(pair string) (Quote, error) {
+	quote, ok := s.quotes[pair]
+	if !ok {
+		return Quote{}, fmt.Errorf("%w: %s", ErrQuoteNotAvailable, pair)
+	}
+	return quote, nil
+}
+
+// Get retrieves custom metadata from the snapshot.
+func (s *OHLCVSnapshot) Get(key string) (interface{}, bool) {
+	val, ok := s.data[key]
+	return val, ok
+}
+
+// Set stores custom metadata in the snapshot.
+// This method is provided for test and setup purposes.
+func (s *OHLCVSnapshot) Set(key string, value interface{}) {
+	s.data[key] = value
+}
+
+// MetadataKeys returns all metadata keys in sorted order.
+func (s *OHLCVSnapshot) MetadataKeys() []string {
+	return sortedKeys(s.data)
+}
@@ -0,0 +1,365 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Order is a resting instruction to trade Pair that an order-managing
+// engine (such as the backtest engine) holds and fills against later
+// snapshots. It embeds mechanisms.Order for the side, type, prices, size
+// and time in force. Supported types are market, limit, stop-loss and
+// stop-limit; an empty TimeInForce means good till cancel.
+//
+// Fills accumulate in a Holding: buys move cash into it at the fill price
+// and sells move it back out.
+type Order struct {
+	// ID uniquely identifies the order among the engine's open orders
+	ID string
+
+	// Pair is the snapshot price key the order trades (e.g., "ETH/USD")
+	Pair string
+
+	// PositionID is the Holding fills accumulate in; empty uses HoldingID(Pair)
+	PositionID string
+
+	mechanisms.Order
+}
+
+// NewMarketOrder creates a market order for size units of pair.
+func NewMarketOrder(id, pair string, side mechanisms.OrderSide, size primitives.Amount) Order {
+	return Order{ID: id, Pair: pair, Order: mechanisms.Order{Side: side, Type: mechanisms.OrderTypeMarket, Size: size}}
+}
+
+// NewLimitOrder creates a limit order for size units of pair at limit or better.
+func NewLimitOrder(id, pair string, side mechanisms.OrderSide, size primitives.Amount, limit primitives.Price) Order {
+	return Order{ID: id, Pair: pair, Order: mechanisms.Order{Side: side, Type: mechanisms.OrderTypeLimit, Size: size, Price: limit}}
+}
+
+// NewStopOrder creates a stop-loss order that becomes a market order for
+// size units of pair once the price reaches stop: at or above it for a
+// buy, at or below it for a sell.
+func NewStopOrder(id, pair string, side mechanisms.OrderSide, size primitives.Amount, stop primitives.Price) Order {
+	return Order{ID: id, Pair: pair, Order: mechanisms.Order{Side: side, Type: mechanisms.OrderTypeStopLoss, Size: size, StopPrice: stop}}
+}
+
+// Holding returns the ID of the Holding the order's fills accumulate in.
+func (o Order) Holding() string {
+	if o.PositionID != "" {
+		return o.PositionID
+	}
+	return HoldingID(o.Pair)
+}
+
+// Validate checks that the order is complete for its type.
+// Returns ErrInvalidAction describing the first problem found.
+func (o Order) Validate() error {
+	if o.ID == "" || o.Pair == "" {
+		return fmt.Errorf("%w: order needs an ID and a pair", ErrInvalidAction)
+	}
+	if o.Side != mechanisms.OrderSideBuy && o.Side != mechanisms.OrderSideSell {
+		return fmt.Errorf("%w: order %s has unknown side %q", ErrInvalidAction, o.ID, o.Side)
+	}
+	if o.Size.IsZero() {
+		return fmt.Errorf("%w: order %s has zero size", ErrInvalidAction, o.ID)
+	}
+	switch o.Type {
+	case mechanisms.OrderTypeMarket:
+	case mechanisms.OrderTypeLimit:
+		if o.Price.IsZero() {
+			return fmt.Errorf("%w: limit order %s needs a price", ErrInvalidAction, o.ID)
+		}
+	case mechanisms.OrderTypeStopLoss:
+		if o.StopPrice.IsZero() {
+			return fmt.Errorf("%w: stop order %s needs a stop price", ErrInvalidAction, o.ID)
+		}
+	case mechanisms.OrderTypeStopLimit:
+		if o.StopPrice.IsZero() || o.Price.IsZero() {
+			return fmt.Errorf("%w: stop-limit order %s needs stop and limit prices", ErrInvalidAction, o.ID)
+		}
+	default:
+		return fmt.Errorf("%w: order %s has unsupported type %q", ErrInvalidAction, o.ID, o.Type)
+	}
+	switch o.TimeInForce {
+	case "", mechanisms.TimeInForceGTC, mechanisms.TimeInForceIOC, mechanisms.TimeInForceFOK:
+	case mechanisms.TimeInForceGTD:
+		if o.ExpiryTime.Time().IsZero() {
+			return fmt.Errorf("%w: GTD order %s needs an expiry time", ErrInvalidAction, o.ID)
+		}
+	default:
+		return fmt.Errorf("%w: order %s has unknown time in force %q", ErrInvalidAction, o.ID, o.TimeInForce)
+	}
+	return nil
+}
+
+// String returns a description of the order.
+func (o Order) String() string {
+	return fmt.Sprintf("%s %s %s %s %s", o.ID, o.Type, o.Side, o.Size, o.Pair)
+}
+
+// OrderStatus is the state of an order after an update.
+type OrderStatus string
+
+const (
+	// OrderStatusOpen is a resting order, possibly partially filled
+	OrderStatusOpen OrderStatus = "open"
+
+	// OrderStatusFilled is an order whose full size has filled
+	OrderStatusFilled OrderStatus = "filled"
+
+	// OrderStatusCanceled is an order canceled by the strategy, or the
+	// unfilled remainder of an IOC or FOK order
+	OrderStatusCanceled OrderStatus = "canceled"
+
+	// OrderStatusExpired is a GTD order that reached its expiry time
+	OrderStatusExpired OrderStatus = "expired"
+
+	// OrderStatusRejected is an order that never rested: its submission
+	// failed, or was skipped or rolled back with the rest of its batch
+	OrderStatusRejected OrderStatus = "rejected"
+)
+
+// Fill is a single execution against an order.
+type Fill struct {
+	OrderID string
+	Pair    string
+	Side    mechanisms.OrderSide
+	Time    primitives.Time
+
+	// Quantity is the units filled by this execution
+	Quantity primitives.Decimal
+
+	// Price is the execution price
+	Price primitives.Price
+}
+
+// Notional returns Quantity × Price.
+func (f Fill) Notional() primitives.Decimal {
+	return f.Quantity.Mul(f.Price.Decimal())
+}
+
+// OrderUpdate reports a change to an order: a fill, a cancellation or an
+// expiry.
+type OrderUpdate struct {
+	Order  Order
+	Status OrderStatus
+	Time   primitives.Time
+
+	// Filled is the cumulative quantity filled so far
+	Filled primitives.Decimal
+
+	// Fill is the execution that caused the update, or nil
+	Fill *Fill
+}
+
+// OrderListener is an optional Strategy extension notified of order
+// updates. The backtest engine reports fills and expiries after applying
+// them and before the strategy rebalances on the same snapshot, and
+// reports the strategy's own submissions and cancellations once they have
+// been applied.
+type OrderListener interface {
+	OnOrderUpdate(update OrderUpdate)
+}
+
+// SubmitOrderAction submits an order to the engine's order book. The
+// order rests from the next snapshot on; it cannot fill on the snapshot it
+// was submitted at.
+type SubmitOrderAction struct {
+	Order Order
+}
+
+// NewSubmitOrderAction creates an action submitting order.
+func NewSubmitOrderAction(order Order) *SubmitOrderAction {
+	return &SubmitOrderAction{Order: order}
+}
+
+// Apply rejects the order: orders must be submitted to an engine that
+// manages an order book, which handles this action itself.
+func (a *SubmitOrderAction) Apply(portfolio *Portfolio) error {
+	return fmt.Errorf("%w: order %s needs an engine that manages orders", ErrInvalidAction, a.Order.ID)
+}
+
+// String returns a description of this action.
+func (a *SubmitOrderAction) String() string {
+	return fmt.Sprintf("SubmitOrder(%s)", a.Order)
+}
+
+// CancelOrderAction cancels an open order by ID.
+type CancelOrderAction struct {
+	OrderID string
+}
+
+// NewCancelOrderAction creates an action canceling the order with orderID.
+func NewCancelOrderAction(orderID string) *CancelOrderAction {
+	return &CancelOrderAction{OrderID: orderID}
+}
+
+// Apply rejects the cancellation: like SubmitOrderAction it is handled by
+// the engine that manages orders.
+func (a *CancelOrderAction) Apply(portfolio *Portfolio) error {
+	return fmt.Errorf("%w: cancel of %s needs an engine that manages orders", ErrInvalidAction, a.OrderID)
+}
+
+// String returns a description of this action.
+func (a *CancelOrderAction) String() string {
+	return fmt.Sprintf("CancelOrder(%s)", a.OrderID)
+}
+
+// FillAction applies a fill to a portfolio: it moves the fill's notional
+// between cash and the Holding with ID PositionID, creating the holding on
+// the first buy and removing it when a sell empties it. The holding's cost
+// basis is kept at average cost (see PnL).
+type FillAction struct {
+	Fill       Fill
+	PositionID string
+}
+
+// NewFillAction creates an action applying fill to the holding positionID.
+func NewFillAction(fill Fill, positionID string) *FillAction {
+	return &FillAction{Fill: fill, PositionID: positionID}
+}
+
+// Apply moves cash and units between the portfolio and the holding.
+// Returns ErrInvalidAction if a sell exceeds the holding or the position
+// is not a Holding of the fill's pair.
+func (a *FillAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	quantity := a.Fill.Quantity
+	if a.Fill.Side == mechanisms.OrderSideSell {
+		quantity = quantity.Neg()
+	}
+
+	held := primitives.Zero()
+	existing, err := portfolio.GetPosition(a.PositionID)
+	if err == nil {
+		holding, ok := existing.(*Holding)
+		if !ok || holding.Pair() != a.Fill.Pair {
+			return fmt.Errorf("%w: position %s is not a %s holding", ErrInvalidAction, a.PositionID, a.Fill.Pair)
+		}
+		held = holding.Quantity()
+	}
+	next := held.Add(quantity)
+	if next.IsNegative() {
+		return fmt.Errorf("%w: sell of %s exceeds %s held in %s", ErrInvalidAction, a.Fill.Quantity, held, a.PositionID)
+	}
+
+	// Buys add their cost to the basis; sells release it at average cost
+	notional := quantity.Mul(a.Fill.Price.Decimal())
+	basis, _ := portfolio.costBasis(a.PositionID)
+	if quantity.IsNegative() {
+		if basis, err = scaleBasis(basis, next, held); err != nil {
+			return err
+		}
+	} else {
+		basis = basis.Add(notional)
+	}
+
+	switch {
+	case existing == nil:
+		err = portfolio.AddPosition(NewHolding(a.PositionID, a.Fill.Pair, next))
+	case next.IsZero():
+		err = portfolio.RemovePosition(a.PositionID)
+	default:
+		err = NewReplacePositionAction(a.PositionID, NewHolding(a.PositionID, a.Fill.Pair, next)).Apply(portfolio)
+	}
+	if err != nil {
+		return err
+	}
+	if !next.IsZero() {
+		if err := portfolio.SetCostBasis(a.PositionID, basis); err != nil {
+			return err
+		}
+	}
+	return portfolio.AdjustCash(notional.Neg())
+}
+
+// String returns a description of this action.
+func (a *FillAction) String() string {
+	return fmt.Sprintf("Fill(%s %s %s %s @ %s)", a.Fill.OrderID, a.Fill.Side, a.Fill.Quantity, a.Fill.Pair, a.Fill.Price)
+}
+
+// HoldingID returns the default Holding ID for pair, "holding:<pair>".
+func HoldingID(pair string) string {
+	return "holding:" + pair
+}
+
+// Holding is a spot inventory of one asset, valued at the snapshot price
+// of its pair. Order fills build and reduce holdings.
+//
+// Thread Safety: Holding is immutable and safe for concurrent use.
+type Holding struct {
+	id       string
+	pair     string
+	quantity primitives.Decimal
+}
+
+// NewHolding creates a holding of quantity units of pair.
+func NewHolding(id, pair string, quantity primitives.Decimal) *Holding {
+	return &Holding{id: id, pair: pair, quantity: quantity}
+}
+
+// ID returns the holding ID.
+func (h *Holding) ID() string {
+	return h.id
+}
+
+// Type returns PositionTypeSpot.
+func (h *Holding) Type() PositionType {
+	return PositionTypeSpot
+}
+
+// Pair returns the pair the holding is valued at.
+func (h *Holding) Pair() string {
+	return h.pair
+}
+
+// Underlying implements UnderlyingPosition with the base asset of Pair, or
+// Pair itself if it does not parse.
+func (h *Holding) Underlying() string {
+	pair, err := primitives.ParsePair(h.pair)
+	if err != nil {
+		return h.pair
+	}
+	return pair.Base
+}
+
+// Quantity returns the units held.
+func (h *Holding) Quantity() primitives.Decimal {
+	return h.quantity
+}
+
+// Size implements Resizable: a holding's size is its quantity.
+func (h *Holding) Size() primitives.Decimal {
+	return h.quantity
+}
+
+// ResizeTo implements Resizable, buying or selling the difference at the
+// snapshot price of Pair.
+func (h *Holding) ResizeTo(snapshot MarketSnapshot, newSize primitives.Decimal) (Position, primitives.Decimal, error) {
+	if newSize.IsNegative() {
+		return nil, primitives.Zero(), fmt.Errorf("%w: holding %s cannot go short", ErrInvalidAction, h.id)
+	}
+	price, err := snapshot.Price(h.pair)
+	if err != nil {
+		return nil, primitives.Zero(), fmt.Errorf("failed to resize holding %s: %w", h.id, err)
+	}
+	return NewHolding(h.id, h.pair, newSize), h.quantity.Sub(newSize).Mul(price.Decimal()), nil
+}
+
+// Value returns Quantity × the snapshot price of Pair.
+func (h *Holding) Value(snapshot MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price(h.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), fmt.Errorf("failed to value holding %s: %w", h.id, err)
+	}
+	return primitives.NewAmount(h.quantity.Mul(price.Decimal()))
+}
+
+// Risk implements PositionWithRisk: a holding's delta is its quantity.
+func (h *Holding) Risk(snapshot MarketSnapshot) (RiskMetrics, error) {
+	return RiskMetrics{Delta: h.quantity, Leverage: primitives.One()}, nil
+}
@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PairPrice returns the price of pair from snapshot regardless of how the
+// snapshot spells its price keys. It tries, in order:
+//  1. the canonical key ("ETH/USD")
+//  2. any key that parses to the same pair ("eth-usd", "ETHUSD")
+//  3. the inverse pair, returning the reciprocal price
+//
+// Snapshots implementing PairPricer answer directly, applying their own
+// asset aliases.
+//
+// Returns ErrPriceNotAvailable if no form of the pair is quoted.
+func PairPrice(snapshot MarketSnapshot, pair primitives.Pair) (primitives.Price, error) {
+	if pricer, ok := snapshot.(PairPricer); ok {
+		return pricer.PriceOf(pair)
+	}
+	if price, err := snapshot.Price(pair.String()); err == nil {
+		return price, nil
+	}
+
+	prices := snapshot.Prices()
+	// Sorted for a deterministic choice when several keys match
+	keys := sortedPriceKeys(prices)
+
+	inverse := pair.Inverse()
+	var inversePrice primitives.Price
+	foundInverse := false
+	for _, key := range keys {
+		price := prices[key]
+		parsed, err := primitives.ParsePair(key)
+		if err != nil {
+			continue
+		}
+		if parsed == pair {
+			return price, nil
+		}
+		if parsed == inverse && !foundInverse {
+			inversePrice, foundInverse = price, true
+		}
+	}
+
+	if foundInverse {
+		price, err := inverse.InvertPrice(inversePrice)
+		if err != nil {
+			return primitives.Price{}, fmt.Errorf("%w: %s (inverse quote is zero)", ErrPriceNotAvailable, pair)
+		}
+		return price, nil
+	}
+	return primitives.Price{}, fmt.Errorf("%w: %s", ErrPriceNotAvailable, pair)
+}
@@ -0,0 +1,175 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PnL is a portfolio's profit and loss split as on a broker statement.
+//
+// A position's cost basis is the net cash paid into it: OpenPositionAction
+// sets it to the entry cost, fills and resizes adjust it at average cost,
+// and ReplacePositionAction carries it over. Realized P&L is every other
+// cash flow since the start: proceeds of closes and settlements beyond
+// their basis, fees, funding and interest. Unrealized P&L is the open
+// positions' value beyond their basis. Positions added without a cash
+// flow, such as with AddPositionAction, have a zero basis, so their whole
+// value is unrealized, and cash moved with AdjustCashAction is realized;
+// open positions with OpenPositionAction, or set their basis with
+// Portfolio.SetCostBasis, for the split to attribute them.
+type PnL struct {
+	Realized   primitives.Decimal
+	Unrealized primitives.Decimal
+}
+
+// Total returns realized plus unrealized P&L: the change in the
+// portfolio's value beyond the capital contributed.
+func (p PnL) Total() primitives.Decimal {
+	return p.Realized.Add(p.Unrealized)
+}
+
+// String returns the realized, unrealized and total P&L.
+func (p PnL) String() string {
+	return fmt.Sprintf("realized %s, unrealized %s, total %s", p.Realized, p.Unrealized, p.Total())
+}
+
+// Capital returns the cash contributed to the portfolio: its initial cash
+// and any changes made with SetCash.
+func (p *Portfolio) Capital() primitives.Decimal {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.capital
+}
+
+// CostBasis returns the cost basis of a position, zero if none was set or
+// it is not held.
+func (p *Portfolio) CostBasis(positionID string) primitives.Decimal {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if basis, ok := p.basis[positionID]; ok {
+		return basis
+	}
+	return primitives.Zero()
+}
+
+// SetCostBasis sets the cost basis of a held position, for positions
+// opened by custom actions that move cash themselves.
+// Returns ErrPositionNotFound if the position is not held.
+func (p *Portfolio) SetCostBasis(positionID string, basis primitives.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.positions[positionID]; !ok {
+		return fmt.Errorf("%w: %s", ErrPositionNotFound, positionID)
+	}
+	p.setBasis(positionID, basis)
+	return nil
+}
+
+// AdjustCostBasis adds delta to the cost basis of a held position.
+// Returns ErrPositionNotFound if the position is not held.
+func (p *Portfolio) AdjustCostBasis(positionID string, delta primitives.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.positions[positionID]; !ok {
+		return fmt.Errorf("%w: %s", ErrPositionNotFound, positionID)
+	}
+	p.setBasis(positionID, p.basis[positionID].Add(delta))
+	return nil
+}
+
+// RealizedPnL returns the P&L realized so far: cash less the capital
+// contributed, plus the cost basis still held in open positions.
+func (p *Portfolio) RealizedPnL() primitives.Decimal {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	realized := p.cashDecimal.Sub(p.capital)
+	for _, basis := range p.basis {
+		realized = realized.Add(basis)
+	}
+	return realized
+}
+
+// UnrealizedPnL returns the open positions' value in the base currency at
+// snapshot (see PositionValue) beyond their cost basis.
+func (p *Portfolio) UnrealizedPnL(snapshot MarketSnapshot) (primitives.Decimal, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	unrealized := primitives.Zero()
+	for _, position := range p.sortedPositions() {
+		value, err := p.positionValue(position, snapshot)
+		if err != nil {
+			return primitives.Zero(), fmt.Errorf("failed to value position %s: %w", position.ID(), err)
+		}
+		unrealized = unrealized.Add(value.Decimal())
+		if basis, ok := p.basis[position.ID()]; ok {
+			unrealized = unrealized.Sub(basis)
+		}
+	}
+	return unrealized, nil
+}
+
+// PnL returns the realized and unrealized P&L at snapshot.
+func (p *Portfolio) PnL(snapshot MarketSnapshot) (PnL, error) {
+	unrealized, err := p.UnrealizedPnL(snapshot)
+	if err != nil {
+		return PnL{}, err
+	}
+	return PnL{Realized: p.RealizedPnL(), Unrealized: unrealized}, nil
+}
+
+// setBasis sets a position's cost basis, journaling the prior one.
+// Caller must hold the lock.
+func (p *Portfolio) setBasis(id string, basis primitives.Decimal) {
+	prior, hasPrior := p.basis[id]
+	p.undo(journalEntry{id: id, basis: prior, hasBasis: hasPrior, basisOnly: true})
+	p.restoreBasis(id, basis, true)
+}
+
+// restoreBasis sets a position's cost basis, or removes it when has is
+// false. Caller must hold the lock.
+func (p *Portfolio) restoreBasis(id string, basis primitives.Decimal, has bool) {
+	if !has {
+		delete(p.basis, id)
+		return
+	}
+	if p.basis == nil {
+		p.basis = make(map[string]primitives.Decimal)
+	}
+	p.basis[id] = basis
+}
+
+// scaleBasis returns basis scaled by size/from, the basis left after a
+// position of size from is reduced to size at average cost.
+func scaleBasis(basis, size, from primitives.Decimal) (primitives.Decimal, error) {
+	if from.IsZero() {
+		return primitives.Zero(), nil
+	}
+	return basis.Mul(size).Div(from)
+}
+
+func copyBasis(basis map[string]primitives.Decimal) map[string]primitives.Decimal {
+	if basis == nil {
+		return nil
+	}
+	copied := make(map[string]primitives.Decimal, len(basis))
+	for id, b := range basis {
+		copied[id] = b
+	}
+	return copied
+}
+
+// costBasis returns a position's cost basis and whether one is set.
+func (p *Portfolio) costBasis(positionID string) (primitives.Decimal, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	basis, ok := p.basis[positionID]
+	return basis, ok
+}
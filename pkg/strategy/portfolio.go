@@ -1,9 +1,14 @@
 package strategy
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
 	"sync"
 
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/logging"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
 )
 
@@ -16,6 +21,10 @@ import (
 // is required. Read operations (Value, GetPosition, Positions, Cash) are safe
 // when no writes are occurring.
 //
+// Determinism: every method that iterates positions (Positions,
+// PositionsByType, Value, ...) visits them in ascending ID order, so runs
+// over the same inputs produce identical logs, valuations and errors.
+//
 // Design: Portfolio is intentionally simple and doesn't prescribe strategy logic.
 // It's a data structure for tracking positions, not a strategy coordinator.
 type Portfolio struct {
@@ -26,9 +35,42 @@ type Portfolio struct {
 	// Using a map allows O(1) lookups and prevents duplicate IDs
 	positions map[string]Position
 
+	// ids holds the position IDs in ascending order, kept in step with
+	// positions so ordered iteration does not sort on every call
+	ids []string
+
+	// tags maps a position ID to its tags; untagged positions have no
+	// entry
+	tags map[string]Tags
+
 	// cash tracks the current cash balance in the portfolio's denomination currency as a Decimal
 	// (can be negative to represent borrowed funds/leverage)
 	cashDecimal primitives.Decimal
+
+	// basis maps a position ID to its cost basis; positions without an
+	// entry have a zero basis (see CostBasis)
+	basis map[string]primitives.Decimal
+
+	// capital is the cash contributed rather than earned: the initial cash
+	// and changes made with SetCash (see RealizedPnL)
+	capital primitives.Decimal
+
+	// history records changes when enabled via EnableHistory (nil otherwise)
+	history *portfolioHistory
+
+	// journal records how to undo each change while a journal is open
+	// (nil otherwise), see BeginJournal
+	journal *portfolioJournal
+
+	// now is the portfolio clock, see SetTime
+	now primitives.Time
+
+	// logger receives a debug record per change when set, see SetLogger
+	logger *slog.Logger
+
+	// baseCurrency is the denomination of cash and values, see
+	// SetBaseCurrency
+	baseCurrency string
 }
 
 // NewPortfolio creates a new empty portfolio with the specified initial cash.
@@ -36,12 +78,14 @@ func NewPortfolio(initialCash primitives.Amount) *Portfolio {
 	return &Portfolio{
 		positions:   make(map[string]Position),
 		cashDecimal: initialCash.Decimal(),
+		capital:     initialCash.Decimal(),
 	}
 }
 
-// AddPosition adds a position to the portfolio.
-// Returns error if a position with the same ID already exists.
-func (p *Portfolio) AddPosition(position Position) error {
+// AddPosition adds a position to the portfolio, labelled with the union of
+// tags for reporting (see Tags).
+// Returns ErrPositionExists if a position with the same ID already exists.
+func (p *Portfolio) AddPosition(position Position, tags ...Tags) error {
 	if position == nil {
 		return ErrNilPosition
 	}
@@ -51,10 +95,12 @@ func (p *Portfolio) AddPosition(position Position) error {
 
 	id := position.ID()
 	if _, exists := p.positions[id]; exists {
-		return fmt.Errorf("position %s already exists", id)
+		return fmt.Errorf("%w: %s", ErrPositionExists, id)
 	}
 
-	p.positions[id] = position
+	p.insert(id, position, mergeTags(tags...))
+	p.undo(journalEntry{id: id})
+	p.record(PortfolioEvent{Type: EventPositionAdded, PositionID: id, Position: position})
 	return nil
 }
 
@@ -64,11 +110,15 @@ func (p *Portfolio) RemovePosition(positionID string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if _, exists := p.positions[positionID]; !exists {
+	position, exists := p.positions[positionID]
+	if !exists {
 		return fmt.Errorf("%w: %s", ErrPositionNotFound, positionID)
 	}
 
-	delete(p.positions, positionID)
+	basis, hasBasis := p.basis[positionID]
+	p.undo(journalEntry{id: positionID, position: position, tags: p.tags[positionID], basis: basis, hasBasis: hasBasis})
+	p.remove(positionID)
+	p.record(PortfolioEvent{Type: EventPositionRemoved, PositionID: positionID})
 	return nil
 }
 
@@ -95,28 +145,39 @@ func (p *Portfolio) HasPosition(positionID string) bool {
 	return exists
 }
 
-// Positions returns all positions in the portfolio.
+// Positions returns all positions in the portfolio, sorted by ID.
 // The returned slice is a snapshot and safe to iterate over.
 // Modifications to the slice do not affect the portfolio.
 func (p *Portfolio) Positions() []Position {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	positions := make([]Position, 0, len(p.positions))
-	for _, pos := range p.positions {
-		positions = append(positions, pos)
-	}
+	return p.sortedPositions()
+}
+
+// PositionsSeeded returns all positions in a pseudo-random order fixed by
+// seed. The same seed and holdings always yield the same order, so tests
+// can check that strategy logic does not depend on position ordering
+// while remaining reproducible.
+func (p *Portfolio) PositionsSeeded(seed int64) []Position {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
+	positions := p.sortedPositions()
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(positions), func(i, j int) {
+		positions[i], positions[j] = positions[j], positions[i]
+	})
 	return positions
 }
 
-// PositionsByType returns all positions of the given type.
+// PositionsByType returns all positions of the given type, sorted by ID.
 func (p *Portfolio) PositionsByType(posType PositionType) []Position {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	var positions []Position
-	for _, pos := range p.positions {
+	for _, pos := range p.sortedPositions() {
 		if pos.Type() == posType {
 			positions = append(positions, pos)
 		}
@@ -154,6 +215,11 @@ func (p *Portfolio) CashDecimal() primitives.Decimal {
 	return p.cashDecimal
 }
 
+// CashQuantity returns the signed cash balance (negative for debt).
+func (p *Portfolio) CashQuantity() primitives.Quantity {
+	return primitives.NewQuantity(p.CashDecimal())
+}
+
 // AdjustCash adds or removes cash from the portfolio.
 // Positive values add cash, negative values remove cash.
 // Negative cash balance represents borrowed funds / leverage.
@@ -161,21 +227,29 @@ func (p *Portfolio) AdjustCash(delta primitives.Decimal) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.undo(journalEntry{cash: p.cashDecimal, capital: p.capital, cashOnly: true})
 	p.cashDecimal = p.cashDecimal.Add(delta)
+	p.record(PortfolioEvent{Type: EventCashAdjusted, CashDelta: delta})
 	return nil
 }
 
 // SetCash sets the cash balance to a specific amount.
-// Useful for initialization and testing.
+// Useful for initialization and testing. The change counts as capital
+// contributed or withdrawn, not P&L (see RealizedPnL).
 func (p *Portfolio) SetCash(amount primitives.Amount) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	delta := amount.Decimal().Sub(p.cashDecimal)
+	p.undo(journalEntry{cash: p.cashDecimal, capital: p.capital, cashOnly: true})
 	p.cashDecimal = amount.Decimal()
+	p.capital = p.capital.Add(delta)
+	p.record(PortfolioEvent{Type: EventCashSet, CashDelta: delta})
 }
 
 // Value returns the total value of the portfolio (positions + cash)
-// using prices from the provided market snapshot.
+// using prices from the provided market snapshot, in the base currency
+// (see PositionValue).
 //
 // If any position fails to calculate its value, the error is returned
 // and the total value calculation is aborted.
@@ -185,10 +259,10 @@ func (p *Portfolio) Value(snapshot MarketSnapshot) (primitives.Amount, error) {
 
 	totalValueDecimal := p.cashDecimal
 
-	for id, position := range p.positions {
-		posValue, err := position.Value(snapshot)
+	for _, position := range p.sortedPositions() {
+		posValue, err := p.positionValue(position, snapshot)
 		if err != nil {
-			return primitives.Amount{}, fmt.Errorf("failed to value position %s: %w", id, err)
+			return primitives.Amount{}, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
 		}
 		totalValueDecimal = totalValueDecimal.Add(posValue.Decimal())
 	}
@@ -207,10 +281,10 @@ func (p *Portfolio) PositionsValue(snapshot MarketSnapshot) (primitives.Amount,
 
 	totalValue := primitives.ZeroAmount()
 
-	for id, position := range p.positions {
-		posValue, err := position.Value(snapshot)
+	for _, position := range p.sortedPositions() {
+		posValue, err := p.positionValue(position, snapshot)
 		if err != nil {
-			return primitives.Amount{}, fmt.Errorf("failed to value position %s: %w", id, err)
+			return primitives.Amount{}, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
 		}
 		totalValue = totalValue.Add(posValue)
 	}
@@ -220,7 +294,8 @@ func (p *Portfolio) PositionsValue(snapshot MarketSnapshot) (primitives.Amount,
 
 // Clone creates a deep copy of the portfolio.
 // The cloned portfolio has independent position and cash state.
-// Note: Positions themselves are not cloned (they should be immutable).
+// Note: Positions themselves are not cloned (they should be immutable),
+// and recorded history is not carried over to the clone.
 func (p *Portfolio) Clone() *Portfolio {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -231,19 +306,256 @@ func (p *Portfolio) Clone() *Portfolio {
 	}
 
 	return &Portfolio{
-		positions:   positions,
-		cashDecimal: p.cashDecimal,
+		positions:    positions,
+		ids:          append([]string(nil), p.ids...),
+		tags:         copyTags(p.tags),
+		cashDecimal:  p.cashDecimal,
+		basis:        copyBasis(p.basis),
+		capital:      p.capital,
+		baseCurrency: p.baseCurrency,
 	}
 }
 
-// Clear removes all positions and resets cash to zero.
+// PortfolioCheckpoint is a saved portfolio state that Restore can roll back to.
+type PortfolioCheckpoint struct {
+	positions map[string]Position
+	ids       []string
+	tags      map[string]Tags
+	cash      primitives.Decimal
+	basis     map[string]primitives.Decimal
+	capital   primitives.Decimal
+	events    int
+}
+
+// Checkpoint saves the current positions, cash and history length so a
+// batch of changes can be undone with Restore.
+func (p *Portfolio) Checkpoint() PortfolioCheckpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	positions := make(map[string]Position, len(p.positions))
+	for id, pos := range p.positions {
+		positions[id] = pos
+	}
+	cp := PortfolioCheckpoint{
+		positions: positions,
+		ids:       append([]string(nil), p.ids...),
+		tags:      copyTags(p.tags),
+		cash:      p.cashDecimal,
+		basis:     copyBasis(p.basis),
+		capital:   p.capital,
+	}
+	if p.history != nil {
+		cp.events = len(p.history.events)
+	}
+	return cp
+}
+
+// Restore rolls the portfolio back to a checkpoint taken with Checkpoint,
+// discarding any history recorded since. The checkpoint can be reused.
+func (p *Portfolio) Restore(cp PortfolioCheckpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.positions = make(map[string]Position, len(cp.positions))
+	for id, pos := range cp.positions {
+		p.positions[id] = pos
+	}
+	p.ids = append(p.ids[:0], cp.ids...)
+	p.tags = copyTags(cp.tags)
+	p.cashDecimal = cp.cash
+	p.basis = copyBasis(cp.basis)
+	p.capital = cp.capital
+	if p.history != nil && cp.events <= len(p.history.events) {
+		p.history.events = p.history.events[:cp.events]
+	}
+}
+
+// PortfolioSavepoint marks a point in an open journal that RollbackTo can
+// return to.
+type PortfolioSavepoint struct {
+	entries int
+	events  int
+}
+
+// portfolioJournal is an undo log of changes made since BeginJournal.
+type portfolioJournal struct {
+	entries []journalEntry
+}
+
+// journalEntry undoes one change: a cash change (cashOnly) restores cash
+// and capital, a cost basis change (basisOnly) restores id's basis,
+// otherwise a nil position removes id (undoing an add) and a non-nil
+// position re-adds it with its tags and basis (undoing a removal).
+type journalEntry struct {
+	id        string
+	position  Position
+	tags      Tags
+	cash      primitives.Decimal
+	capital   primitives.Decimal
+	cashOnly  bool
+	basis     primitives.Decimal
+	hasBasis  bool
+	basisOnly bool
+}
+
+// BeginJournal starts recording an undo log so changes can be rolled back
+// to a Savepoint. Unlike Checkpoint, which copies every position, a
+// savepoint is O(1) and rolling back costs only the changes made since,
+// which suits taking a savepoint before each of many small changes.
+// Calling BeginJournal again discards the previous log.
+func (p *Portfolio) BeginJournal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.journal = &portfolioJournal{}
+}
+
+// EndJournal stops recording and discards the undo log.
+func (p *Portfolio) EndJournal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.journal = nil
+}
+
+// Savepoint marks the current state in the open journal.
+// BeginJournal must be called first.
+func (p *Portfolio) Savepoint() PortfolioSavepoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var sp PortfolioSavepoint
+	if p.journal != nil {
+		sp.entries = len(p.journal.entries)
+	}
+	if p.history != nil {
+		sp.events = len(p.history.events)
+	}
+	return sp
+}
+
+// RollbackTo undoes every change recorded since sp was taken, discarding
+// any history recorded since. Rolling back to a savepoint invalidates
+// savepoints taken after it.
+func (p *Portfolio) RollbackTo(sp PortfolioSavepoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.journal != nil {
+		entries := p.journal.entries
+		for i := len(entries) - 1; i >= sp.entries; i-- {
+			entry := entries[i]
+			switch {
+			case entry.cashOnly:
+				p.cashDecimal, p.capital = entry.cash, entry.capital
+			case entry.basisOnly:
+				p.restoreBasis(entry.id, entry.basis, entry.hasBasis)
+			case entry.position == nil:
+				p.remove(entry.id)
+			default:
+				p.insert(entry.id, entry.position, entry.tags)
+				p.restoreBasis(entry.id, entry.basis, entry.hasBasis)
+			}
+		}
+		if sp.entries < len(entries) {
+			p.journal.entries = entries[:sp.entries]
+		}
+	}
+	if p.history != nil && sp.events <= len(p.history.events) {
+		p.history.events = p.history.events[:sp.events]
+	}
+}
+
+// insert adds a position with its tags and keeps the ID index sorted.
+// Caller must hold the lock.
+func (p *Portfolio) insert(id string, position Position, tags Tags) {
+	p.positions[id] = position
+	if len(tags) > 0 {
+		if p.tags == nil {
+			p.tags = make(map[string]Tags)
+		}
+		p.tags[id] = tags
+	}
+	i := sort.SearchStrings(p.ids, id)
+	p.ids = append(p.ids, "")
+	copy(p.ids[i+1:], p.ids[i:])
+	p.ids[i] = id
+}
+
+// remove deletes a position, its tags, cost basis and ID index entry.
+// Caller must hold the lock.
+func (p *Portfolio) remove(id string) {
+	delete(p.positions, id)
+	delete(p.tags, id)
+	delete(p.basis, id)
+	i := sort.SearchStrings(p.ids, id)
+	if i < len(p.ids) && p.ids[i] == id {
+		p.ids = append(p.ids[:i], p.ids[i+1:]...)
+	}
+}
+
+// undo appends to the journal when one is open. Caller must hold the lock.
+func (p *Portfolio) undo(entry journalEntry) {
+	if p.journal != nil {
+		p.journal.entries = append(p.journal.entries, entry)
+	}
+}
+
+// SetLogger makes the portfolio log each position and cash change at debug
+// level, tagged with the portfolio component. A nil logger disables
+// logging. Clones do not inherit the logger.
+func (p *Portfolio) SetLogger(logger *slog.Logger) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if logger != nil {
+		logger = logging.For(logger, logging.ComponentPortfolio)
+	}
+	p.logger = logger
+}
+
+// log writes a recorded change to the logger.
+// Caller must hold the write lock.
+func (p *Portfolio) log(event PortfolioEvent) {
+	ctx := context.Background()
+	if !p.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.Time(logging.KeySnapshotTime, event.Time.Time()),
+		slog.String("cash", event.Cash.String()),
+	}
+	if event.PositionID != "" {
+		attrs = append(attrs, slog.String(logging.KeyPosition, event.PositionID))
+	}
+	if event.Type == EventCashAdjusted {
+		attrs = append(attrs, slog.String("cash_delta", event.CashDelta.String()))
+	}
+	p.logger.LogAttrs(ctx, slog.LevelDebug, string(event.Type), attrs...)
+}
+
+// Clear removes all positions and resets cash and capital to zero.
 // Useful for testing and resetting portfolio state.
 func (p *Portfolio) Clear() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	delta := p.cashDecimal.Neg()
+	if p.journal != nil {
+		p.undo(journalEntry{cash: p.cashDecimal, capital: p.capital, cashOnly: true})
+		for _, id := range p.ids {
+			basis, hasBasis := p.basis[id]
+			p.undo(journalEntry{id: id, position: p.positions[id], tags: p.tags[id], basis: basis, hasBasis: hasBasis})
+		}
+	}
 	p.positions = make(map[string]Position)
+	p.ids = nil
+	p.tags = nil
+	p.basis = nil
 	p.cashDecimal = primitives.Zero()
+	p.capital = primitives.Zero()
+	p.record(PortfolioEvent{Type: EventCleared, CashDelta: delta})
 }
 
 // Summary returns a human-readable summary of the portfolio.
@@ -268,3 +580,23 @@ func (p *Portfolio) Summary(snapshot MarketSnapshot) string {
 
 	return summary
 }
+
+// sortedPositions returns positions in ascending ID order.
+// Caller must hold the lock.
+func (p *Portfolio) sortedPositions() []Position {
+	positions := make([]Position, len(p.ids))
+	for i, id := range p.ids {
+		positions[i] = p.positions[id]
+	}
+	return positions
+}
+
+// sortedIDs returns the keys of positions in ascending order.
+func sortedIDs(positions map[string]Position) []string {
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
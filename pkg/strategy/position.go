@@ -93,6 +93,10 @@ type RiskMetrics struct {
 	// Primarily for options, but can apply to funding-rate positions.
 	Theta primitives.Decimal
 
+	// Rho measures sensitivity to a 1% parallel rise in interest rates.
+	// Only applicable to rate-sensitive positions; usually left zero.
+	Rho primitives.Decimal
+
 	// Leverage indicates the position's leverage ratio.
 	// 1.0 for spot, >1.0 for leveraged positions.
 	Leverage primitives.Decimal
@@ -120,6 +124,32 @@ type PositionWithRisk interface {
 	Risk(snapshot MarketSnapshot) (RiskMetrics, error)
 }
 
+// CostedPosition is an optional interface for positions whose entry cost
+// differs from their value, such as positions that pay fees or slippage on
+// entry. OpenPositionAction debits EntryCost; positions without it are
+// assumed to cost their Value at entry.
+type CostedPosition interface {
+	Position
+
+	// EntryCost returns the cash needed to open the position at snapshot.
+	// Returns error if required market data is unavailable.
+	EntryCost(snapshot MarketSnapshot) (primitives.Amount, error)
+}
+
+// LiquidatablePosition is an optional interface for leveraged positions
+// that are liquidated when they breach a margin or health threshold, such
+// as a perpetual whose equity runs out or a borrow whose health factor
+// falls below 1. Stress simulations (see backtest.SimulateLiquidations)
+// liquidate positions when it reports a breach.
+type LiquidatablePosition interface {
+	Position
+
+	// Liquidatable reports whether the position breaches its liquidation
+	// threshold at snapshot. Returns error if required market data is
+	// unavailable.
+	Liquidatable(snapshot MarketSnapshot) (bool, error)
+}
+
 // PositionMetadata provides optional descriptive information about a position.
 // Useful for logging, debugging, and user interfaces.
 type PositionMetadata interface {
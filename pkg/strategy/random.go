@@ -0,0 +1,36 @@
+package strategy
+
+import (
+	"context"
+	"math/rand"
+)
+
+type randKey struct{}
+
+// WithRand returns a copy of ctx carrying rng as the run's source of
+// randomness. The backtest engine and live sessions install one seeded
+// from their configuration before the first snapshot.
+func WithRand(ctx context.Context, rng *rand.Rand) context.Context {
+	return context.WithValue(ctx, randKey{}, rng)
+}
+
+// NewRand returns a generator seeded with seed, as the backtest engine and
+// live sessions create for each run.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Rand returns the run's source of randomness from the context passed to
+// Rebalance. Strategies that randomize (jittered rebalance times,
+// exploration) should draw from it rather than the global math/rand
+// functions, so that the same configuration and seed always produce the
+// same run. Outside a run, Rand returns a generator seeded with 0.
+//
+// The generator is shared by every draw in the run and is not safe for
+// concurrent use: draw from it only on the goroutine calling Rebalance.
+func Rand(ctx context.Context) *rand.Rand {
+	if rng, ok := ctx.Value(randKey{}).(*rand.Rand); ok && rng != nil {
+		return rng
+	}
+	return NewRand(0)
+}
@@ -0,0 +1,234 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// PositionBuilder constructs a position holding the given quantity of an asset.
+// The rebalancer uses it to create or resize managed positions without
+// knowing their concrete type.
+type PositionBuilder func(snapshot MarketSnapshot, quantity primitives.Decimal) (Position, error)
+
+// WeightTarget describes the desired allocation for one managed position.
+type WeightTarget struct {
+	// PositionID is the ID of the managed position (existing or to be created).
+	// The builder must produce positions with this ID.
+	PositionID string
+
+	// Weight is the desired fraction of total portfolio value (e.g., 0.6 for 60%)
+	Weight primitives.Decimal
+
+	// Pair is the price pair used to convert target notional into quantity
+	Pair string
+
+	// LotSize is the quantity increment; target quantities are rounded down to it.
+	// Zero disables lot rounding.
+	LotSize primitives.Decimal
+
+	// Build constructs the position for a given quantity
+	Build PositionBuilder
+}
+
+// RebalancerConfig controls when and how the TargetWeightRebalancer trades.
+type RebalancerConfig struct {
+	// Tolerance is the absolute weight drift allowed before a target is traded
+	// (e.g., 0.05 means a 60% target is left alone between 55% and 65%)
+	Tolerance primitives.Decimal
+
+	// MinTradeValue skips trades whose notional change is below this value,
+	// avoiding trades where costs would outweigh the benefit
+	MinTradeValue primitives.Decimal
+
+	// CostRate is the proportional trading cost charged to cash on each trade
+	// (e.g., 0.001 for 10 bps)
+	CostRate primitives.Decimal
+}
+
+// TargetWeightRebalancer computes the actions that bring a portfolio back to
+// target weights. Only targets whose weight has drifted outside the tolerance
+// band are traded; positions not listed as targets are left untouched and
+// cash absorbs the residual weight.
+//
+// Each traded target produces a position action (add, replace or remove)
+// followed by an AdjustCashAction for the value change plus trading costs.
+// Reductions are emitted before increases so cash freed by sales is
+// available to purchases within the same batch.
+//
+// Thread Safety: TargetWeightRebalancer is safe for concurrent use if its
+// targets' builders are.
+type TargetWeightRebalancer struct {
+	targets []WeightTarget
+	config  RebalancerConfig
+}
+
+// NewTargetWeightRebalancer creates a rebalancer for the given targets.
+// Returns error if weights are negative, sum above one, or targets are incomplete.
+func NewTargetWeightRebalancer(targets []WeightTarget, config RebalancerConfig) (*TargetWeightRebalancer, error) {
+	total := primitives.Zero()
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if target.PositionID == "" {
+			return nil, fmt.Errorf("%w: target position ID cannot be empty", ErrInvalidAction)
+		}
+		if seen[target.PositionID] {
+			return nil, fmt.Errorf("%w: duplicate target %s", ErrInvalidAction, target.PositionID)
+		}
+		seen[target.PositionID] = true
+		if target.Weight.IsNegative() {
+			return nil, fmt.Errorf("%w: target %s has negative weight", ErrInvalidAction, target.PositionID)
+		}
+		if target.Build == nil {
+			return nil, fmt.Errorf("%w: target %s has no builder", ErrInvalidAction, target.PositionID)
+		}
+		total = total.Add(target.Weight)
+	}
+	if total.GreaterThan(primitives.One()) {
+		return nil, fmt.Errorf("%w: target weights sum to %s (must be <= 1)", ErrInvalidAction, total.String())
+	}
+
+	return &TargetWeightRebalancer{
+		targets: targets,
+		config:  config,
+	}, nil
+}
+
+// trade is a planned change to a single target.
+type trade struct {
+	actions []Action
+	delta   primitives.Decimal
+}
+
+// Actions returns the actions required to restore target weights.
+// Returns an empty slice if every target is within tolerance.
+func (r *TargetWeightRebalancer) Actions(portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	if portfolio == nil {
+		return nil, ErrNilPortfolio
+	}
+
+	totalValue, err := portfolio.Value(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("rebalancer: failed to value portfolio: %w", err)
+	}
+	total := totalValue.Decimal()
+	if total.IsZero() {
+		return nil, nil
+	}
+
+	trades := make([]trade, 0, len(r.targets))
+	for _, target := range r.targets {
+		t, ok, err := r.planTarget(portfolio, snapshot, target, total)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			trades = append(trades, t)
+		}
+	}
+
+	// Reductions first so their proceeds fund increases
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].delta.LessThan(trades[j].delta)
+	})
+
+	actions := make([]Action, 0, 2*len(trades))
+	for _, t := range trades {
+		actions = append(actions, t.actions...)
+	}
+	return actions, nil
+}
+
+// planTarget computes the trade for one target, reporting false if no trade is needed.
+func (r *TargetWeightRebalancer) planTarget(
+	portfolio *Portfolio,
+	snapshot MarketSnapshot,
+	target WeightTarget,
+	total primitives.Decimal,
+) (trade, bool, error) {
+	currentValue := primitives.Zero()
+	existing, err := portfolio.GetPosition(target.PositionID)
+	held := err == nil
+	if held {
+		value, err := portfolio.PositionValue(existing, snapshot)
+		if err != nil {
+			return trade{}, false, fmt.Errorf("rebalancer: failed to value %s: %w", target.PositionID, err)
+		}
+		currentValue = value.Decimal()
+	}
+
+	currentWeight, err := currentValue.Div(total)
+	if err != nil {
+		return trade{}, false, err
+	}
+	if !currentWeight.Sub(target.Weight).Abs().GreaterThan(r.config.Tolerance) {
+		return trade{}, false, nil
+	}
+
+	price, err := snapshot.Price(target.Pair)
+	if err != nil {
+		return trade{}, false, fmt.Errorf("rebalancer: failed to get price for %s: %w", target.Pair, err)
+	}
+	quantity, err := total.Mul(target.Weight).Div(price.Decimal())
+	if err != nil {
+		return trade{}, false, fmt.Errorf("rebalancer: invalid price for %s: %w", target.Pair, err)
+	}
+	quantity = roundDownToLot(quantity, target.LotSize)
+
+	var positionAction Action
+	newValue := primitives.Zero()
+	if quantity.IsZero() {
+		if !held {
+			return trade{}, false, nil
+		}
+		positionAction = NewRemovePositionAction(target.PositionID)
+	} else {
+		position, err := target.Build(snapshot, quantity)
+		if err != nil {
+			return trade{}, false, fmt.Errorf("rebalancer: failed to build %s: %w", target.PositionID, err)
+		}
+		if position.ID() != target.PositionID {
+			return trade{}, false, fmt.Errorf("%w: builder for %s returned position %s",
+				ErrInvalidAction, target.PositionID, position.ID())
+		}
+		value, err := portfolio.PositionValue(position, snapshot)
+		if err != nil {
+			return trade{}, false, fmt.Errorf("rebalancer: failed to value new %s: %w", target.PositionID, err)
+		}
+		newValue = value.Decimal()
+		if held {
+			positionAction = NewReplacePositionAction(target.PositionID, position)
+		} else {
+			positionAction = NewAddPositionAction(position)
+		}
+	}
+
+	delta := newValue.Sub(currentValue)
+	if delta.Abs().LessThan(r.config.MinTradeValue) || delta.IsZero() {
+		return trade{}, false, nil
+	}
+
+	cost := delta.Abs().Mul(r.config.CostRate)
+	cashDelta := delta.Neg().Sub(cost)
+
+	return trade{
+		actions: []Action{
+			positionAction,
+			NewAdjustCashAction(cashDelta, fmt.Sprintf("rebalance %s to weight %s", target.PositionID, target.Weight.String())),
+		},
+		delta: delta,
+	}, true, nil
+}
+
+// roundDownToLot truncates quantity to a multiple of lot. A zero lot leaves quantity unchanged.
+func roundDownToLot(quantity, lot primitives.Decimal) primitives.Decimal {
+	if !lot.IsPositive() {
+		return quantity
+	}
+	lots, err := quantity.Div(lot)
+	if err != nil {
+		return quantity
+	}
+	return lots.Floor().Mul(lot)
+}
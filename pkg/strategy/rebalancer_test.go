@@ -0,0 +1,163 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// spotHolding is a test position valued at quantity * snapshot price.
+type spotHolding struct {
+	id       string
+	pair     string
+	quantity primitives.Decimal
+}
+
+func (s *spotHolding) ID() string         { return s.id }
+func (s *spotHolding) Type() PositionType { return PositionTypeSpot }
+func (s *spotHolding) Value(snapshot MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price(s.pair)
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	return primitives.NewAmount(s.quantity.Mul(price.Decimal()))
+}
+
+func spotBuilder(id, pair string) PositionBuilder {
+	return func(snapshot MarketSnapshot, quantity primitives.Decimal) (Position, error) {
+		return &spotHolding{id: id, pair: pair, quantity: quantity}, nil
+	}
+}
+
+func rebalanceSnapshot() *SimpleSnapshot {
+	return NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(2000)),
+		"BTC/USD": primitives.MustPrice(primitives.NewDecimal(40000)),
+	})
+}
+
+func TestNewTargetWeightRebalancerValidation(t *testing.T) {
+	build := spotBuilder("eth", "ETH/USD")
+	tests := []struct {
+		name    string
+		targets []WeightTarget
+	}{
+		{"empty ID", []WeightTarget{{Weight: primitives.One(), Build: build}}},
+		{"negative weight", []WeightTarget{{PositionID: "eth", Weight: primitives.NewDecimal(-1), Build: build}}},
+		{"nil builder", []WeightTarget{{PositionID: "eth", Weight: primitives.One()}}},
+		{"over-allocated", []WeightTarget{
+			{PositionID: "eth", Weight: primitives.MustDecimalFromString("0.6"), Build: build},
+			{PositionID: "btc", Weight: primitives.MustDecimalFromString("0.6"), Build: build},
+		}},
+		{"duplicate", []WeightTarget{
+			{PositionID: "eth", Weight: primitives.MustDecimalFromString("0.1"), Build: build},
+			{PositionID: "eth", Weight: primitives.MustDecimalFromString("0.1"), Build: build},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTargetWeightRebalancer(tt.targets, RebalancerConfig{}); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestTargetWeightRebalancer(t *testing.T) {
+	snapshot := rebalanceSnapshot()
+	targets := []WeightTarget{
+		{
+			PositionID: "eth",
+			Weight:     primitives.MustDecimalFromString("0.5"),
+			Pair:       "ETH/USD",
+			LotSize:    primitives.MustDecimalFromString("0.1"),
+			Build:      spotBuilder("eth", "ETH/USD"),
+		},
+		{
+			PositionID: "btc",
+			Weight:     primitives.MustDecimalFromString("0.2"),
+			Pair:       "BTC/USD",
+			Build:      spotBuilder("btc", "BTC/USD"),
+		},
+	}
+	rebalancer, err := NewTargetWeightRebalancer(targets, RebalancerConfig{
+		Tolerance: primitives.MustDecimalFromString("0.05"),
+		CostRate:  primitives.MustDecimalFromString("0.001"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("initial allocation from cash", func(t *testing.T) {
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10001)))
+
+		actions, err := rebalancer.Actions(portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(actions) != 4 {
+			t.Fatalf("expected 4 actions, got %d", len(actions))
+		}
+		for _, action := range actions {
+			if err := action.Apply(portfolio); err != nil {
+				t.Fatalf("failed to apply %s: %v", action, err)
+			}
+		}
+
+		// 50% of 10001 = 5000.5 → 2.50025 ETH → 2.5 ETH after lot rounding
+		eth, err := portfolio.GetPosition("eth")
+		if err != nil {
+			t.Fatalf("expected eth position: %v", err)
+		}
+		if q := eth.(*spotHolding).quantity; !q.Equal(primitives.MustDecimalFromString("2.5")) {
+			t.Errorf("expected 2.5 ETH, got %s", q.String())
+		}
+
+		// Cash = 10001 - 5000 - 2000.2 - costs (5 + 2.0002)
+		wantCash := primitives.MustDecimalFromString("2993.7998")
+		if !portfolio.CashDecimal().Equal(wantCash) {
+			t.Errorf("expected cash %s, got %s", wantCash.String(), portfolio.CashDecimal().String())
+		}
+	})
+
+	t.Run("within tolerance produces no actions", func(t *testing.T) {
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(3000)))
+		_ = portfolio.AddPosition(&spotHolding{id: "eth", pair: "ETH/USD", quantity: primitives.MustDecimalFromString("2.6")})
+		_ = portfolio.AddPosition(&spotHolding{id: "btc", pair: "BTC/USD", quantity: primitives.MustDecimalFromString("0.05")})
+
+		actions, err := rebalancer.Actions(portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(actions) != 0 {
+			t.Errorf("expected no actions, got %d", len(actions))
+		}
+	})
+
+	t.Run("reductions ordered before increases", func(t *testing.T) {
+		portfolio := NewPortfolio(primitives.ZeroAmount())
+		_ = portfolio.AddPosition(&spotHolding{id: "eth", pair: "ETH/USD", quantity: primitives.NewDecimal(5)})
+
+		actions, err := rebalancer.Actions(portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(actions) != 4 {
+			t.Fatalf("expected 4 actions, got %d", len(actions))
+		}
+		if _, ok := actions[0].(*ReplacePositionAction); !ok {
+			t.Errorf("expected first action to resize eth, got %s", actions[0])
+		}
+		if _, ok := actions[2].(*AddPositionAction); !ok {
+			t.Errorf("expected third action to add btc, got %s", actions[2])
+		}
+	})
+
+	t.Run("missing price", func(t *testing.T) {
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+		empty := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{})
+		if _, err := rebalancer.Actions(portfolio, empty); err == nil {
+			t.Error("expected error for missing price")
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Params holds strategy construction parameters, typically decoded from a
+// JSON config. Numbers may be given as JSON numbers or decimal strings.
+type Params map[string]interface{}
+
+// String returns the string parameter key, or fallback if it is absent.
+// An empty fallback makes the parameter required.
+func (p Params) String(key, fallback string) (string, error) {
+	raw, ok := p[key]
+	if !ok {
+		if fallback == "" {
+			return "", fmt.Errorf("%w: param %q is required", ErrInvalidParams, key)
+		}
+		return fallback, nil
+	}
+	s, ok := raw.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("%w: param %q must be a non-empty string", ErrInvalidParams, key)
+	}
+	return s, nil
+}
+
+// Decimal returns the numeric parameter key, or fallback if it is absent.
+func (p Params) Decimal(key string, fallback primitives.Decimal) (primitives.Decimal, error) {
+	raw, ok := p[key]
+	if !ok {
+		return fallback, nil
+	}
+	var s string
+	switch v := raw.(type) {
+	case primitives.Decimal:
+		return v, nil
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return primitives.NewDecimal(int64(v)), nil
+	case string:
+		s = v
+	default:
+		return primitives.Zero(), fmt.Errorf("%w: param %q must be a number", ErrInvalidParams, key)
+	}
+	d, err := primitives.NewDecimalFromString(s)
+	if err != nil {
+		return primitives.Zero(), fmt.Errorf("%w: param %q: %v", ErrInvalidParams, key, err)
+	}
+	return d, nil
+}
+
+// Int returns the integer parameter key, or fallback if it is absent.
+func (p Params) Int(key string, fallback int) (int, error) {
+	raw, ok := p[key]
+	if !ok {
+		return fallback, nil
+	}
+	switch v := raw.(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: param %q must be an integer", ErrInvalidParams, key)
+}
+
+// Map returns the nested object parameter key, or nil if it is absent.
+func (p Params) Map(key string) (Params, error) {
+	raw, ok := p[key]
+	if !ok {
+		return nil, nil
+	}
+	switch v := raw.(type) {
+	case Params:
+		return v, nil
+	case map[string]interface{}:
+		return Params(v), nil
+	default:
+		return nil, fmt.Errorf("%w: param %q must be an object", ErrInvalidParams, key)
+	}
+}
+
+// ParameterReporter is an optional Strategy extension reporting the
+// parameters the strategy was built with, which backtest experiment
+// records store with each run.
+type ParameterReporter interface {
+	Parameters() Params
+}
+
+// Factory constructs a strategy from parameters.
+type Factory func(params Params) (Strategy, error)
+
+// Registry maps strategy names to factories so strategies can be built
+// from configuration without compile-time wiring.
+//
+// Thread Safety: Registry is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds a factory under name.
+// Returns error if name is empty, factory is nil, or name is already registered.
+func (r *Registry) Register(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("%w: strategy name cannot be empty", ErrInvalidParams)
+	}
+	if factory == nil {
+		return fmt.Errorf("%w: factory for %s cannot be nil", ErrInvalidParams, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("%w: %s", ErrStrategyExists, name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// New constructs the strategy registered under name.
+// Returns ErrUnknownStrategy if name is not registered.
+func (r *Registry) New(name string, params Params) (Strategy, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+	if params == nil {
+		params = Params{}
+	}
+	strat, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build strategy %s: %w", name, err)
+	}
+	return strat, nil
+}
+
+// Names returns all registered strategy names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultRegistry backs the package-level Register, New and Registered functions.
+var defaultRegistry = NewRegistry()
+
+// Register adds a factory to the default registry.
+// Intended to be called from init functions; panics on an invalid or
+// duplicate registration, like database/sql.Register.
+func Register(name string, factory Factory) {
+	if err := defaultRegistry.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// New constructs a strategy from the default registry.
+func New(name string, params Params) (Strategy, error) {
+	return defaultRegistry.New(name, params)
+}
+
+// Registered returns the names in the default registry in sorted order.
+func Registered() []string {
+	return defaultRegistry.Names()
+}
@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// namedStrategy is a no-op strategy recording its construction parameter.
+type namedStrategy struct {
+	pair string
+}
+
+func (s *namedStrategy) Rebalance(context.Context, *Portfolio, MarketSnapshot) ([]Action, error) {
+	return nil, nil
+}
+
+func newNamedStrategy(params Params) (Strategy, error) {
+	pair, err := params.String("pair", "")
+	if err != nil {
+		return nil, err
+	}
+	return &namedStrategy{pair: pair}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("named", newNamedStrategy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("New builds by name", func(t *testing.T) {
+		strat, err := registry.New("named", Params{"pair": "ETH/USD"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strat.(*namedStrategy).pair != "ETH/USD" {
+			t.Errorf("expected pair ETH/USD, got %s", strat.(*namedStrategy).pair)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := registry.New("missing", nil); !errors.Is(err, ErrUnknownStrategy) {
+			t.Errorf("expected %v, got %v", ErrUnknownStrategy, err)
+		}
+		if _, err := registry.New("named", nil); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("expected %v, got %v", ErrInvalidParams, err)
+		}
+		if err := registry.Register("named", newNamedStrategy); !errors.Is(err, ErrStrategyExists) {
+			t.Errorf("expected %v, got %v", ErrStrategyExists, err)
+		}
+		if err := registry.Register("nil", nil); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("expected %v, got %v", ErrInvalidParams, err)
+		}
+	})
+
+	t.Run("Names sorted", func(t *testing.T) {
+		_ = registry.Register("alpha", newNamedStrategy)
+		names := registry.Names()
+		if len(names) != 2 || names[0] != "alpha" || names[1] != "named" {
+			t.Errorf("unexpected names %v", names)
+		}
+	})
+}
+
+func TestDefaultRegistryPanicsOnDuplicate(t *testing.T) {
+	Register("registry-test", newNamedStrategy)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	Register("registry-test", newNamedStrategy)
+}
+
+func TestParams(t *testing.T) {
+	params := Params{
+		"rate":   0.25,
+		"text":   "1.5",
+		"period": 14.0,
+		"frac":   2.5,
+		"nested": map[string]interface{}{"ETH/USD": 0.5},
+		"bad":    true,
+	}
+
+	tests := []struct {
+		name    string
+		get     func() (interface{}, error)
+		want    string
+		wantErr bool
+	}{
+		{"decimal from number", func() (interface{}, error) { return params.Decimal("rate", primitives.Zero()) }, "0.25", false},
+		{"decimal from string", func() (interface{}, error) { return params.Decimal("text", primitives.Zero()) }, "1.5", false},
+		{"decimal fallback", func() (interface{}, error) { return params.Decimal("absent", primitives.One()) }, "1", false},
+		{"decimal wrong type", func() (interface{}, error) { return params.Decimal("bad", primitives.Zero()) }, "", true},
+		{"int", func() (interface{}, error) { return params.Int("period", 0) }, "14", false},
+		{"int fractional", func() (interface{}, error) { return params.Int("frac", 0) }, "", true},
+		{"string fallback", func() (interface{}, error) { return params.String("absent", "x") }, "x", false},
+		{"string required", func() (interface{}, error) { return params.String("absent", "") }, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidParams) {
+					t.Errorf("expected %v, got %v", ErrInvalidParams, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := toString(got); s != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, s)
+			}
+		})
+	}
+
+	nested, err := params.Map("nested")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w, _ := nested.Decimal("ETH/USD", primitives.Zero()); w.String() != "0.5" {
+		t.Errorf("expected nested weight 0.5, got %s", w.String())
+	}
+	if _, err := params.Map("bad"); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("expected %v, got %v", ErrInvalidParams, err)
+	}
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case primitives.Decimal:
+		return x.String()
+	case int:
+		return primitives.NewDecimal(int64(x)).String()
+	case string:
+		return x
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package strategy
+
+import (
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// DataKind is the kind of market data a DataKey names.
+type DataKind string
+
+const (
+	// DataPrice is a pair's price, available in any key format or as its
+	// inverse (see PairPrice)
+	DataPrice DataKind = "price"
+
+	// DataMetadata is a snapshot metadata key, such as those built by the
+	// snapshotkeys package
+	DataMetadata DataKind = "metadata"
+)
+
+// DataKey names a market data input a position or strategy needs.
+type DataKey struct {
+	Kind DataKind
+	Key  string
+}
+
+// PriceKey returns the DataKey of pair's price.
+func PriceKey(pair string) DataKey {
+	return DataKey{Kind: DataPrice, Key: pair}
+}
+
+// MetadataKey returns the DataKey of snapshot metadata key.
+func MetadataKey(key string) DataKey {
+	return DataKey{Kind: DataMetadata, Key: key}
+}
+
+// String returns the kind and key, e.g. "price ETH/USD".
+func (k DataKey) String() string {
+	return string(k.Kind) + " " + k.Key
+}
+
+// Available reports whether snapshot provides the input.
+func (k DataKey) Available(snapshot MarketSnapshot) bool {
+	switch k.Kind {
+	case DataPrice:
+		if pair, err := primitives.ParsePair(k.Key); err == nil {
+			_, err = PairPrice(snapshot, pair)
+			return err == nil
+		}
+		_, err := snapshot.Price(k.Key)
+		return err == nil
+	case DataMetadata:
+		_, ok := snapshot.Get(k.Key)
+		return ok
+	default:
+		return false
+	}
+}
+
+// DataDependent is an optional extension for positions and strategies
+// that declare the market data they need, so a backtest can check its
+// snapshots up front and report exactly which inputs are missing rather
+// than failing mid-run on whichever lookup happens first.
+//
+// Example:
+//
+//	func (p *LPPosition) Requirements() []strategy.DataKey {
+//		return []strategy.DataKey{
+//			strategy.PriceKey("ETH/USDC"),
+//			strategy.MetadataKey(snapshotkeys.PoolSqrtPrice(p.poolID)),
+//		}
+//	}
+type DataDependent interface {
+	// Requirements returns the inputs every snapshot must provide
+	Requirements() []DataKey
+}
+
+// MissingData returns the requirements snapshot does not provide, in
+// order and without duplicates.
+func MissingData(snapshot MarketSnapshot, requirements []DataKey) []DataKey {
+	var missing []DataKey
+	seen := make(map[DataKey]bool, len(requirements))
+	for _, key := range requirements {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !key.Available(snapshot) {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// DataRequirements returns the requirements of the held positions that
+// are DataDependent, each with the sorted IDs of the positions needing it,
+// or nil if none declare any.
+func (p *Portfolio) DataRequirements() map[DataKey][]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var requirements map[DataKey][]string
+	for _, id := range p.ids {
+		dependent, ok := p.positions[id].(DataDependent)
+		if !ok {
+			continue
+		}
+		for _, key := range dependent.Requirements() {
+			if requirements == nil {
+				requirements = make(map[DataKey][]string)
+			}
+			ids := requirements[key]
+			if len(ids) == 0 || ids[len(ids)-1] != id {
+				requirements[key] = append(ids, id)
+			}
+		}
+	}
+	return requirements
+}
+
+// SortDataKeys sorts keys by kind, then key.
+func SortDataKeys(keys []DataKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Kind != keys[j].Kind {
+			return keys[i].Kind < keys[j].Kind
+		}
+		return keys[i].Key < keys[j].Key
+	})
+}
@@ -0,0 +1,133 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Resizable is an optional Position extension for positions that can be
+// partially closed or increased in place, such as half an LP position or
+// a perpetual reduced from 5 to 2 contracts.
+//
+// Size units are the position's own: tokens for a holding, contracts for
+// a derivative, liquidity for an LP position.
+type Resizable interface {
+	Position
+
+	// Size returns the current size.
+	Size() primitives.Decimal
+
+	// ResizeTo returns the position resized to newSize at snapshot prices,
+	// and the cash the change releases (positive when shrinking) or
+	// consumes (negative when growing). Resizing to zero returns the cash
+	// a full close releases; the returned position is then discarded.
+	// Returns error if newSize is negative or the position cannot be
+	// priced at snapshot.
+	ResizeTo(snapshot MarketSnapshot, newSize primitives.Decimal) (Position, primitives.Decimal, error)
+}
+
+// ResizePositionAction resizes a Resizable position and moves the
+// resulting cash in one step. Resizing to zero removes the position.
+//
+// The cash flow depends on prices, so the action is a DeferredAction: the
+// backtest engine resolves it against the snapshot it executes at. To
+// apply it directly, call Resolve first.
+type ResizePositionAction struct {
+	PositionID string
+
+	// Size is the target size, or the factor applied to the current size
+	// when Relative is set (e.g., 0.5 closes half)
+	Size     primitives.Decimal
+	Relative bool
+
+	snapshot MarketSnapshot
+}
+
+// NewResizePositionAction creates an action resizing positionID to size.
+func NewResizePositionAction(positionID string, size primitives.Decimal) *ResizePositionAction {
+	return &ResizePositionAction{PositionID: positionID, Size: size}
+}
+
+// NewScalePositionAction creates an action scaling positionID's size by
+// factor: 0.5 closes half, 2 doubles it.
+func NewScalePositionAction(positionID string, factor primitives.Decimal) *ResizePositionAction {
+	return &ResizePositionAction{PositionID: positionID, Size: factor, Relative: true}
+}
+
+// Resolve implements DeferredAction, binding the action to snapshot.
+func (a *ResizePositionAction) Resolve(snapshot MarketSnapshot) (Action, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("%w: resize of %s needs a snapshot", ErrInvalidAction, a.PositionID)
+	}
+	resolved := *a
+	resolved.snapshot = snapshot
+	return &resolved, nil
+}
+
+// Apply replaces the position with its resized version, or removes it
+// when resized to zero, and adjusts cash by the resize's cash flow. Cash
+// paid to grow the position adds to its cost basis, and a reduction
+// releases basis at average cost (see PnL).
+// Returns ErrInvalidAction if the action has not been resolved, the size
+// is negative or the position is not Resizable, and ErrPositionNotFound if
+// the position does not exist.
+func (a *ResizePositionAction) Apply(portfolio *Portfolio) error {
+	if portfolio == nil {
+		return ErrNilPortfolio
+	}
+	if a.snapshot == nil {
+		return fmt.Errorf("%w: resize of %s must be resolved against a snapshot", ErrInvalidAction, a.PositionID)
+	}
+	if a.Size.IsNegative() {
+		return fmt.Errorf("%w: cannot resize %s to negative size %s", ErrInvalidAction, a.PositionID, a.Size)
+	}
+
+	position, err := portfolio.GetPosition(a.PositionID)
+	if err != nil {
+		return err
+	}
+	resizable, ok := position.(Resizable)
+	if !ok {
+		return fmt.Errorf("%w: position %s is not resizable", ErrInvalidAction, a.PositionID)
+	}
+
+	size := a.Size
+	if a.Relative {
+		size = resizable.Size().Mul(a.Size)
+	}
+	resized, cash, err := resizable.ResizeTo(a.snapshot, size)
+	if err != nil {
+		return fmt.Errorf("failed to resize %s: %w", a.PositionID, err)
+	}
+
+	// Cash paid to grow the position adds to its basis; a reduction
+	// releases it at average cost
+	basis, _ := portfolio.costBasis(a.PositionID)
+	if size.GreaterThan(resizable.Size()) {
+		basis = basis.Sub(cash)
+	} else if basis, err = scaleBasis(basis, size, resizable.Size()); err != nil {
+		return err
+	}
+
+	if size.IsZero() {
+		err = portfolio.RemovePosition(a.PositionID)
+	} else {
+		err = NewReplacePositionAction(a.PositionID, resized).Apply(portfolio)
+		if err == nil {
+			err = portfolio.SetCostBasis(resized.ID(), basis)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return portfolio.AdjustCash(cash)
+}
+
+// String returns a description of this action.
+func (a *ResizePositionAction) String() string {
+	if a.Relative {
+		return fmt.Sprintf("ScalePosition(%s ×%s)", a.PositionID, a.Size)
+	}
+	return fmt.Sprintf("ResizePosition(%s → %s)", a.PositionID, a.Size)
+}
@@ -0,0 +1,180 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// RiskSlideConfig configures Portfolio.RiskSlide.
+type RiskSlideConfig struct {
+	// Underlying is the asset shocked (see Bump); empty shocks every
+	// price and implied volatility together
+	Underlying string
+
+	// PriceShocks are the relative price shocks of the rows, each above
+	// -1 (default -30% to +30% in steps of 10%)
+	PriceShocks []primitives.Decimal
+
+	// VolShocks are the relative implied volatility shocks of the
+	// columns, each -1 or above (default -50% to +50% in steps of 25%)
+	VolShocks []primitives.Decimal
+}
+
+// DefaultPriceShocks returns -30% to +30% in steps of 10%.
+func DefaultPriceShocks() []primitives.Decimal {
+	return shockRange(-3, 3, "0.1")
+}
+
+// DefaultVolShocks returns -50% to +50% in steps of 25%.
+func DefaultVolShocks() []primitives.Decimal {
+	return shockRange(-2, 2, "0.25")
+}
+
+func shockRange(from, to int64, step string) []primitives.Decimal {
+	shocks := make([]primitives.Decimal, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		shocks = append(shocks, primitives.NewDecimal(i).Mul(primitives.MustDecimalFromString(step)))
+	}
+	return shocks
+}
+
+// RiskSlide is a portfolio's P&L across a grid of price and implied
+// volatility shocks, the "risk slide" of a derivatives desk. Unlike the
+// Greeks, which are local, it shows the P&L of large moves, where gamma
+// and vega change: the strikes a short option book is exposed to, or an
+// LP's loss once the price leaves its range.
+type RiskSlide struct {
+	Underlying  string
+	PriceShocks []primitives.Decimal
+	VolShocks   []primitives.Decimal
+
+	// Value is the unshocked value of the positions
+	Value primitives.Decimal
+
+	// PnL[i][j] is the change in the positions' value under PriceShocks[i]
+	// and VolShocks[j]
+	PnL [][]primitives.Decimal
+
+	// ByPosition is each position's PnL grid, keyed by position ID
+	ByPosition map[string][][]primitives.Decimal
+}
+
+// Worst returns the grid's largest loss and the shocks producing it, the
+// first in row order on ties.
+func (s *RiskSlide) Worst() (priceShock, volShock, pnl primitives.Decimal) {
+	for i, row := range s.PnL {
+		for j, v := range row {
+			if (i == 0 && j == 0) || v.LessThan(pnl) {
+				priceShock, volShock, pnl = s.PriceShocks[i], s.VolShocks[j], v
+			}
+		}
+	}
+	return priceShock, volShock, pnl
+}
+
+// String formats the grid as a table of P&L with a row per price shock
+// and a column per volatility shock.
+func (s *RiskSlide) String() string {
+	underlying := s.Underlying
+	if underlying == "" {
+		underlying = "all"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s", underlying+" px\\vol")
+	for _, vol := range s.VolShocks {
+		fmt.Fprintf(&b, " %12s", percent(vol))
+	}
+	b.WriteString("\n")
+	for i, price := range s.PriceShocks {
+		fmt.Fprintf(&b, "%-12s", percent(price))
+		for _, v := range s.PnL[i] {
+			fmt.Fprintf(&b, " %12.2f", v.Float64())
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// percent formats a relative shock such as "+10%".
+func percent(shock primitives.Decimal) string {
+	return fmt.Sprintf("%+.0f%%", shock.Float64()*100)
+}
+
+// RiskSlide revalues every position under each combination of
+// config.PriceShocks and config.VolShocks applied to snapshot (see
+// BumpSnapshot) and returns the P&L grid against the unshocked values.
+// Cash is unaffected by the shocks, so the grid is the portfolio's P&L in
+// its base currency. Every position is revalued in full, including those
+// with analytic Greeks, rather than approximated by its Greeks.
+// Returns error if a shock is out of range or a position cannot be valued
+// under one.
+func (p *Portfolio) RiskSlide(snapshot MarketSnapshot, config RiskSlideConfig) (*RiskSlide, error) {
+	if snapshot == nil {
+		return nil, fmt.Errorf("risk slide requires a snapshot")
+	}
+	prices, vols := config.PriceShocks, config.VolShocks
+	if len(prices) == 0 {
+		prices = DefaultPriceShocks()
+	}
+	if len(vols) == 0 {
+		vols = DefaultVolShocks()
+	}
+	minusOne := primitives.One().Neg()
+	for _, shock := range prices {
+		if !shock.GreaterThan(minusOne) {
+			return nil, fmt.Errorf("price shock %s must be above -1", shock)
+		}
+	}
+	for _, shock := range vols {
+		if shock.LessThan(minusOne) {
+			return nil, fmt.Errorf("volatility shock %s must be -1 or above", shock)
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	slide := &RiskSlide{
+		Underlying:  config.Underlying,
+		PriceShocks: prices,
+		VolShocks:   vols,
+		Value:       primitives.Zero(),
+		PnL:         zeroGrid(len(prices), len(vols)),
+		ByPosition:  make(map[string][][]primitives.Decimal, len(p.ids)),
+	}
+	for _, position := range p.sortedPositions() {
+		base, err := p.positionValue(position, snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to value position %s: %w", position.ID(), err)
+		}
+		slide.Value = slide.Value.Add(base.Decimal())
+		grid := zeroGrid(len(prices), len(vols))
+		for i, price := range prices {
+			for j, vol := range vols {
+				shocked := BumpSnapshot(snapshot, Bump{Underlying: config.Underlying, Price: price, VolScale: vol})
+				value, err := p.positionValue(position, shocked)
+				if err != nil {
+					return nil, fmt.Errorf("failed to value position %s at %s price, %s vol: %w",
+						position.ID(), percent(price), percent(vol), err)
+				}
+				grid[i][j] = value.Decimal().Sub(base.Decimal())
+				slide.PnL[i][j] = slide.PnL[i][j].Add(grid[i][j])
+			}
+		}
+		slide.ByPosition[position.ID()] = grid
+	}
+	return slide, nil
+}
+
+func zeroGrid(rows, columns int) [][]primitives.Decimal {
+	grid := make([][]primitives.Decimal, rows)
+	for i := range grid {
+		grid[i] = make([]primitives.Decimal, columns)
+		for j := range grid[i] {
+			grid[i][j] = primitives.Zero()
+		}
+	}
+	return grid
+}
@@ -0,0 +1,137 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// StrategyFunc adapts a function to the Strategy interface.
+type StrategyFunc func(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error)
+
+// Rebalance calls f.
+func (f StrategyFunc) Rebalance(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	return f(ctx, portfolio, snapshot)
+}
+
+// Scheduler is a Strategy that runs different logic at different cadences
+// within one run, e.g., hedging hourly and re-ranging an LP position daily.
+// Each registered strategy runs on the snapshots where its trigger fires.
+//
+// A trigger with interval d fires on the first snapshot and on each
+// snapshot that falls in a later d-long interval counted from the Unix
+// epoch, so an hourly trigger fires on the first snapshot of each UTC
+// hour and a daily one on the first of each UTC day, whatever the
+// snapshot spacing. A zero interval fires on every snapshot.
+//
+// Triggered strategies run in registration order against the same
+// portfolio state, and their actions are concatenated. The context passed
+// to them lists every trigger that fired (see FiredTriggers), so one
+// strategy can also branch on triggers it was not registered for.
+//
+// Thread Safety: Scheduler is not thread-safe, like the strategies it runs.
+type Scheduler struct {
+	triggers []*trigger
+}
+
+type trigger struct {
+	name     string
+	interval primitives.Duration
+	strategy Strategy
+
+	fired  bool
+	bucket int64
+}
+
+// NewScheduler creates a scheduler with no triggers.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers strat to run under name every interval. Returns error if
+// name is empty or already registered, interval is negative, or strat is
+// nil.
+func (s *Scheduler) Every(name string, interval primitives.Duration, strat Strategy) error {
+	if name == "" {
+		return fmt.Errorf("%w: trigger name is required", ErrInvalidParams)
+	}
+	if interval.Duration() < 0 {
+		return fmt.Errorf("%w: trigger %q has negative interval %s", ErrInvalidParams, name, interval)
+	}
+	if strat == nil {
+		return fmt.Errorf("%w: trigger %q has no strategy", ErrInvalidParams, name)
+	}
+	for _, t := range s.triggers {
+		if t.name == name {
+			return fmt.Errorf("%w: trigger %q already registered", ErrInvalidParams, name)
+		}
+	}
+	s.triggers = append(s.triggers, &trigger{name: name, interval: interval, strategy: strat})
+	return nil
+}
+
+// Rebalance runs the strategies whose triggers fire at snapshot.
+func (s *Scheduler) Rebalance(ctx context.Context, portfolio *Portfolio, snapshot MarketSnapshot) ([]Action, error) {
+	now := snapshot.Time().UnixNano()
+	var fired []*trigger
+	var names []string
+	for _, t := range s.triggers {
+		if t.due(now) {
+			fired = append(fired, t)
+			names = append(names, t.name)
+		}
+	}
+	if len(fired) == 0 {
+		return nil, nil
+	}
+
+	ctx = context.WithValue(ctx, firedKey{}, names)
+	var actions []Action
+	for _, t := range fired {
+		triggered, err := t.strategy.Rebalance(ctx, portfolio, snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %q: %w", t.name, err)
+		}
+		actions = append(actions, triggered...)
+	}
+	return actions, nil
+}
+
+// due reports whether the trigger fires at now, advancing it if so.
+func (t *trigger) due(now int64) bool {
+	interval := t.interval.Duration().Nanoseconds()
+	if interval == 0 {
+		return true
+	}
+	bucket := now / interval
+	if now < 0 && now%interval != 0 {
+		bucket--
+	}
+	if t.fired && bucket <= t.bucket {
+		return false
+	}
+	t.fired, t.bucket = true, bucket
+	return true
+}
+
+type firedKey struct{}
+
+// FiredTriggers returns the names of the Scheduler triggers that fired at
+// the snapshot being rebalanced, in registration order, or nil outside a
+// Scheduler.
+func FiredTriggers(ctx context.Context) []string {
+	names, _ := ctx.Value(firedKey{}).([]string)
+	return names
+}
+
+// TriggerFired reports whether the named Scheduler trigger fired at the
+// snapshot being rebalanced.
+func TriggerFired(ctx context.Context, name string) bool {
+	for _, fired := range FiredTriggers(ctx) {
+		if fired == name {
+			return true
+		}
+	}
+	return false
+}
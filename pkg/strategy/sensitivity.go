@@ -0,0 +1,401 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+// ShiftableRate is implemented by rate metadata that can be shifted in
+// parallel, such as rates.Curve. BumpSnapshot shifts values under
+// snapshotkeys.NamespaceRates that implement it.
+type ShiftableRate interface {
+	// ShiftRate returns the value with every rate raised by bump
+	ShiftRate(bump primitives.Decimal) interface{}
+}
+
+// Bump is a shock to a snapshot's market data, applied by BumpSnapshot.
+type Bump struct {
+	// Underlying is the asset whose prices and implied volatilities are
+	// shocked; empty shocks every price and volatility
+	Underlying string
+
+	// Price is the relative price shock, 0.01 raising the underlying's
+	// prices 1% (and lowering those of pairs quoted in it)
+	Price primitives.Decimal
+
+	// VolScale is the relative shock to the underlying's
+	// snapshotkeys.OptionImpliedVol metadata, 0.5 raising implied vols by
+	// half
+	VolScale primitives.Decimal
+
+	// Vol is added to the underlying's implied vols after VolScale,
+	// flooring them at zero
+	Vol primitives.Decimal
+
+	// Rate is added to every rate under snapshotkeys.NamespaceRates
+	Rate primitives.Decimal
+
+	// Time moves the snapshot's time forward
+	Time primitives.Duration
+}
+
+// BumpSnapshot returns snapshot with bump applied, for revaluing positions
+// under shocked market data. Prices are shocked through Price, Prices and
+// PairPrice; implied volatilities only where quoted as decimal
+// OptionImpliedVol metadata for the underlying or one of its pairs; rates
+// where decimal or ShiftableRate metadata. Other metadata, depth, bars and
+// quotes are passed through unshocked.
+func BumpSnapshot(snapshot MarketSnapshot, bump Bump) MarketSnapshot {
+	return &bumpedSnapshot{MarketSnapshot: snapshot, bump: bump}
+}
+
+// bumpedSnapshot is a MarketSnapshot under a Bump.
+type bumpedSnapshot struct {
+	MarketSnapshot
+	bump Bump
+}
+
+// Time returns the underlying snapshot's time moved by the bump.
+func (s *bumpedSnapshot) Time() primitives.Time {
+	return s.MarketSnapshot.Time().Add(s.bump.Time)
+}
+
+// Price returns the shocked price of pair.
+func (s *bumpedSnapshot) Price(pair string) (primitives.Price, error) {
+	price, err := s.MarketSnapshot.Price(pair)
+	if err != nil {
+		return price, err
+	}
+	return s.shock(pair, price), nil
+}
+
+// Prices returns every shocked price.
+func (s *bumpedSnapshot) Prices() map[string]primitives.Price {
+	prices := s.MarketSnapshot.Prices()
+	shocked := make(map[string]primitives.Price, len(prices))
+	for pair, price := range prices {
+		shocked[pair] = s.shock(pair, price)
+	}
+	return shocked
+}
+
+// PriceOf resolves pair against the underlying snapshot and shocks it.
+func (s *bumpedSnapshot) PriceOf(pair primitives.Pair) (primitives.Price, error) {
+	price, err := PairPrice(s.MarketSnapshot, pair)
+	if err != nil {
+		return price, err
+	}
+	return s.shock(pair.String(), price), nil
+}
+
+// PriceTime returns the underlying snapshot's observation time of pair.
+func (s *bumpedSnapshot) PriceTime(pair string) (primitives.Time, bool) {
+	if timestamps, ok := s.MarketSnapshot.(PriceTimestamper); ok {
+		return timestamps.PriceTime(pair)
+	}
+	return primitives.Time{}, false
+}
+
+// MetadataKeys returns the underlying snapshot's keys.
+func (s *bumpedSnapshot) MetadataKeys() []string {
+	if lister, ok := s.MarketSnapshot.(MetadataLister); ok {
+		return lister.MetadataKeys()
+	}
+	return nil
+}
+
+// Get returns metadata, shocking implied volatilities and rates.
+func (s *bumpedSnapshot) Get(key string) (interface{}, bool) {
+	raw, ok := s.MarketSnapshot.Get(key)
+	if !ok {
+		return raw, ok
+	}
+	if !s.bump.Rate.IsZero() && strings.HasPrefix(key, snapshotkeys.NamespaceRates+":") {
+		if rate, ok := raw.(ShiftableRate); ok {
+			return rate.ShiftRate(s.bump.Rate), true
+		}
+		if rate, err := GetDecimal(s.MarketSnapshot, key); err == nil {
+			return rate.Add(s.bump.Rate), true
+		}
+		return raw, true
+	}
+	if (!s.bump.Vol.IsZero() || !s.bump.VolScale.IsZero()) && s.isVolKey(key) {
+		if vol, err := GetDecimal(s.MarketSnapshot, key); err == nil {
+			vol = vol.Mul(primitives.One().Add(s.bump.VolScale)).Add(s.bump.Vol)
+			if vol.IsNegative() {
+				vol = primitives.Zero()
+			}
+			return vol, true
+		}
+	}
+	return raw, true
+}
+
+// isVolKey reports whether key is the implied volatility of the bump's
+// underlying or one of its pairs.
+func (s *bumpedSnapshot) isVolKey(key string) bool {
+	prefix := snapshotkeys.NamespaceOption + ":"
+	suffix := strings.TrimPrefix(snapshotkeys.OptionImpliedVol(""), prefix)
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) {
+		return false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(key, prefix), suffix)
+	if s.bump.Underlying == "" || id == s.bump.Underlying {
+		return true
+	}
+	pair, err := primitives.ParsePair(id)
+	return err == nil && pair.Base == s.bump.Underlying
+}
+
+// shock applies the price bump to the price of pair: up for pairs with the
+// underlying as base, down for pairs quoted in it.
+func (s *bumpedSnapshot) shock(pair string, price primitives.Price) primitives.Price {
+	if s.bump.Price.IsZero() {
+		return price
+	}
+	factor := primitives.One().Add(s.bump.Price)
+	if s.bump.Underlying == "" {
+		return primitives.MustPrice(price.Decimal().Mul(factor))
+	}
+	parsed, err := primitives.ParsePair(pair)
+	if err != nil {
+		return price
+	}
+	switch {
+	case parsed.Base == s.bump.Underlying && parsed.Quote != s.bump.Underlying:
+		return primitives.MustPrice(price.Decimal().Mul(factor))
+	case parsed.Quote == s.bump.Underlying && parsed.Base != s.bump.Underlying:
+		shocked, err := price.Decimal().Div(factor)
+		if err != nil {
+			return price
+		}
+		return primitives.MustPrice(shocked)
+	}
+	return price
+}
+
+// Bumps sizes the shocks NumericalGreeks differentiates with.
+type Bumps struct {
+	// Price is the relative price bump (default 1%)
+	Price primitives.Decimal
+
+	// Vol is the absolute implied volatility bump (default 0.01)
+	Vol primitives.Decimal
+
+	// Rate is the absolute rate bump (default 1bp)
+	Rate primitives.Decimal
+
+	// Time is the time step of theta (default one day)
+	Time primitives.Duration
+}
+
+// DefaultBumps returns the bumps Portfolio.Greeks uses.
+func DefaultBumps() Bumps {
+	return Bumps{
+		Price: primitives.MustDecimalFromString("0.01"),
+		Vol:   primitives.MustDecimalFromString("0.01"),
+		Rate:  primitives.MustDecimalFromString("0.0001"),
+		Time:  primitives.Days(1),
+	}
+}
+
+func (b Bumps) withDefaults() Bumps {
+	defaults := DefaultBumps()
+	if !b.Price.IsPositive() {
+		b.Price = defaults.Price
+	}
+	if !b.Vol.IsPositive() {
+		b.Vol = defaults.Vol
+	}
+	if !b.Rate.IsPositive() {
+		b.Rate = defaults.Rate
+	}
+	if b.Time.Hours() <= 0 {
+		b.Time = defaults.Time
+	}
+	return b
+}
+
+// NumericalGreeks estimates the Greeks of a position without analytic
+// risk metrics by revaluing it under bumped snapshots (see BumpSnapshot),
+// with central differences in the underlying's price, implied volatility
+// and rates:
+//
+//	Delta = (V(S+h) − V(S−h)) / 2h          per unit of the underlying
+//	Gamma = (V(S+h) − 2V(S) + V(S−h)) / h²
+//	Vega  = (V(σ+k) − V(σ−k)) / 2k / 100    per 1% of volatility
+//	Rho   = (V(r+b) − V(r−b)) / 2b / 100    per 1% of rates
+//	Theta = (V(t+τ) − V(t)) / τ             per day
+//
+// S is the underlying's price on its reference pair and h = bumps.Price·S.
+// The reference pair is the first, in sorted order, with the underlying as
+// base and a quote that is not itself the base of a priced pair, e.g.
+// ETH/USD over ETH/BTC when BTC/USD is quoted. Zero bumps take
+// DefaultBumps. Vega and rho are zero when the position does not read the
+// shocked metadata. Returns error if the position cannot be valued or the
+// underlying has no price.
+func NumericalGreeks(position Position, snapshot MarketSnapshot, underlying string, bumps Bumps) (RiskMetrics, error) {
+	bumps = bumps.withDefaults()
+	risk, err := priceGreeks(position, snapshot, underlying, bumps)
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	theta, rho, err := carryGreeks(position, snapshot, bumps)
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	risk.Theta, risk.Rho = theta, rho
+	return risk, nil
+}
+
+// priceGreeks estimates delta, gamma and vega to underlying.
+func priceGreeks(position Position, snapshot MarketSnapshot, underlying string, bumps Bumps) (RiskMetrics, error) {
+	spot, err := referencePrice(snapshot, underlying)
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	h := bumps.Price.Float64()
+	base, err := revalue(position, snapshot, Bump{})
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	up, err := revalue(position, snapshot, Bump{Underlying: underlying, Price: bumps.Price})
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	down, err := revalue(position, snapshot, Bump{Underlying: underlying, Price: bumps.Price.Neg()})
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	volUp, err := revalue(position, snapshot, Bump{Underlying: underlying, Vol: bumps.Vol})
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	volDown, err := revalue(position, snapshot, Bump{Underlying: underlying, Vol: bumps.Vol.Neg()})
+	if err != nil {
+		return RiskMetrics{}, err
+	}
+	step := h * spot
+	k := bumps.Vol.Float64()
+	return RiskMetrics{
+		Delta: primitives.NewDecimalFromFloat((up - down) / (2 * step)),
+		Gamma: primitives.NewDecimalFromFloat((up - 2*base + down) / (step * step)),
+		Vega:  primitives.NewDecimalFromFloat((volUp - volDown) / (2 * k) / 100),
+	}, nil
+}
+
+// carryGreeks estimates theta and rho, which do not depend on an
+// underlying.
+func carryGreeks(position Position, snapshot MarketSnapshot, bumps Bumps) (theta, rho primitives.Decimal, err error) {
+	base, err := revalue(position, snapshot, Bump{})
+	if err != nil {
+		return theta, rho, err
+	}
+	later, err := revalue(position, snapshot, Bump{Time: bumps.Time})
+	if err != nil {
+		return theta, rho, err
+	}
+	rateUp, err := revalue(position, snapshot, Bump{Rate: bumps.Rate})
+	if err != nil {
+		return theta, rho, err
+	}
+	rateDown, err := revalue(position, snapshot, Bump{Rate: bumps.Rate.Neg()})
+	if err != nil {
+		return theta, rho, err
+	}
+	days := bumps.Time.Hours() / 24
+	b := bumps.Rate.Float64()
+	return primitives.NewDecimalFromFloat((later - base) / days),
+		primitives.NewDecimalFromFloat((rateUp - rateDown) / (2 * b) / 100), nil
+}
+
+// revalue values position at snapshot under bump.
+func revalue(position Position, snapshot MarketSnapshot, bump Bump) (float64, error) {
+	value, err := ValueIn(position, valuationContext(BumpSnapshot(snapshot, bump)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to revalue %s: %w", position.ID(), err)
+	}
+	return value.Decimal().Float64(), nil
+}
+
+// referencePrice returns the price of underlying on its reference pair
+// (see NumericalGreeks).
+func referencePrice(snapshot MarketSnapshot, underlying string) (float64, error) {
+	prices := snapshot.Prices()
+	bases := make(map[string]bool)
+	for _, key := range sortedPriceKeys(prices) {
+		if pair, err := primitives.ParsePair(key); err == nil {
+			bases[pair.Base] = true
+		}
+	}
+	var fallback *primitives.Price
+	for _, key := range sortedPriceKeys(prices) {
+		pair, err := primitives.ParsePair(key)
+		if err != nil || pair.Base != underlying || !prices[key].Decimal().IsPositive() {
+			continue
+		}
+		price := prices[key]
+		if !bases[pair.Quote] {
+			return price.Decimal().Float64(), nil
+		}
+		if fallback == nil {
+			fallback = &price
+		}
+	}
+	if fallback == nil {
+		return 0, fmt.Errorf("%w: no pair prices %s", ErrPriceNotAvailable, underlying)
+	}
+	return fallback.Decimal().Float64(), nil
+}
+
+// pricedAssets returns the sorted base assets of the snapshot's prices.
+func pricedAssets(snapshot MarketSnapshot) []string {
+	var assets []string
+	seen := make(map[string]bool)
+	for _, key := range sortedPriceKeys(snapshot.Prices()) {
+		pair, err := primitives.ParsePair(key)
+		if err != nil || seen[pair.Base] {
+			continue
+		}
+		seen[pair.Base] = true
+		assets = append(assets, pair.Base)
+	}
+	return assets
+}
+
+// numericalExposures estimates a position's Greeks by underlying for
+// Portfolio.Greeks. A position naming its underlying is bumped in that
+// asset alone. Others are bumped in every priced asset in turn, keeping
+// the assets they are sensitive to, with theta and rho, which belong to no
+// asset, under UnknownUnderlying.
+func numericalExposures(position Position, snapshot MarketSnapshot) (map[string]RiskMetrics, error) {
+	bumps := DefaultBumps()
+	if u, ok := position.(UnderlyingPosition); ok {
+		risk, err := NumericalGreeks(position, snapshot, u.Underlying(), bumps)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]RiskMetrics{u.Underlying(): risk}, nil
+	}
+	exposures := make(map[string]RiskMetrics)
+	for _, asset := range pricedAssets(snapshot) {
+		risk, err := priceGreeks(position, snapshot, asset, bumps)
+		if err != nil {
+			return nil, err
+		}
+		if !risk.Delta.IsZero() || !risk.Gamma.IsZero() || !risk.Vega.IsZero() {
+			exposures[asset] = risk
+		}
+	}
+	theta, rho, err := carryGreeks(position, snapshot, bumps)
+	if err != nil {
+		return nil, err
+	}
+	if !theta.IsZero() || !rho.IsZero() {
+		exposures[UnknownUnderlying] = RiskMetrics{Theta: theta, Rho: rho}
+	}
+	return exposures, nil
+}
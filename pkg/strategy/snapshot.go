@@ -0,0 +1,209 @@
+package strategy
+
+import (
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// ImmutableSnapshot is a MarketSnapshot that cannot change once built, so
+// it can be shared between tests, goroutines and runs without one user's
+// changes leaking into another's. Build it with a SnapshotBuilder and
+// derive variants with WithMetadata or ToBuilder, which copy instead of
+// modifying it. It otherwise behaves as SimpleSnapshot, which remains for
+// code that sets data in place.
+type ImmutableSnapshot struct {
+	snapshot *SimpleSnapshot
+}
+
+// Time returns the timestamp of this snapshot.
+func (s *ImmutableSnapshot) Time() primitives.Time {
+	return s.snapshot.Time()
+}
+
+// Price returns the price for the given pair (see SimpleSnapshot.Price).
+func (s *ImmutableSnapshot) Price(pair string) (primitives.Price, error) {
+	return s.snapshot.Price(pair)
+}
+
+// PriceOf returns the price of pair (see SimpleSnapshot.PriceOf).
+func (s *ImmutableSnapshot) PriceOf(pair primitives.Pair) (primitives.Price, error) {
+	return s.snapshot.PriceOf(pair)
+}
+
+// Prices returns a copy of all available prices in this snapshot.
+func (s *ImmutableSnapshot) Prices() map[string]primitives.Price {
+	return copyPrices(s.snapshot.prices)
+}
+
+// Get retrieves custom metadata from the snapshot.
+func (s *ImmutableSnapshot) Get(key string) (interface{}, bool) {
+	return s.snapshot.Get(key)
+}
+
+// PriceTime returns the observation time recorded for pair's price.
+func (s *ImmutableSnapshot) PriceTime(pair string) (primitives.Time, bool) {
+	return s.snapshot.PriceTime(pair)
+}
+
+// MetadataKeys returns all metadata keys in sorted order.
+func (s *ImmutableSnapshot) MetadataKeys() []string {
+	return s.snapshot.MetadataKeys()
+}
+
+// WithMetadata returns a copy of the snapshot with key set to value,
+// leaving the snapshot unchanged.
+func (s *ImmutableSnapshot) WithMetadata(key string, value interface{}) *ImmutableSnapshot {
+	return s.ToBuilder().Metadata(key, value).Build()
+}
+
+// ToBuilder returns a builder starting from the snapshot's data, for
+// deriving a variant with several changes.
+func (s *ImmutableSnapshot) ToBuilder() *SnapshotBuilder {
+	return &SnapshotBuilder{
+		time:       s.snapshot.time,
+		prices:     copyPrices(s.snapshot.prices),
+		data:       copyMetadata(s.snapshot.data),
+		priceTimes: copyTimes(s.snapshot.priceTimes),
+		aliases:    s.snapshot.aliases,
+	}
+}
+
+// SnapshotBuilder assembles an ImmutableSnapshot. Build copies the data
+// gathered so far, so a builder can go on to build further snapshots
+// without affecting those already built.
+//
+//	snapshot := strategy.NewSnapshotBuilder(now).
+//		Price("ETH/USD", ethPrice).
+//		Metadata(snapshotkeys.OptionImpliedVol("ETH"), vol).
+//		Build()
+type SnapshotBuilder struct {
+	time       primitives.Time
+	prices     map[string]primitives.Price
+	data       map[string]interface{}
+	priceTimes map[string]primitives.Time
+	aliases    primitives.AssetAliases
+}
+
+// NewSnapshotBuilder creates a builder for a snapshot at time.
+func NewSnapshotBuilder(time primitives.Time) *SnapshotBuilder {
+	return &SnapshotBuilder{
+		time:   time,
+		prices: make(map[string]primitives.Price),
+		data:   make(map[string]interface{}),
+	}
+}
+
+// Time sets the snapshot time.
+func (b *SnapshotBuilder) Time(time primitives.Time) *SnapshotBuilder {
+	b.time = time
+	return b
+}
+
+// Price sets the price of pair.
+func (b *SnapshotBuilder) Price(pair string, price primitives.Price) *SnapshotBuilder {
+	b.prices[pair] = price
+	return b
+}
+
+// Prices sets the price of every pair in prices.
+func (b *SnapshotBuilder) Prices(prices map[string]primitives.Price) *SnapshotBuilder {
+	for pair, price := range prices {
+		b.prices[pair] = price
+	}
+	return b
+}
+
+// PriceTime records when pair's price was observed (see
+// SimpleSnapshot.SetPriceTime).
+func (b *SnapshotBuilder) PriceTime(pair string, t primitives.Time) *SnapshotBuilder {
+	if b.priceTimes == nil {
+		b.priceTimes = make(map[string]primitives.Time)
+	}
+	b.priceTimes[pair] = t
+	return b
+}
+
+// Metadata sets the metadata at key.
+func (b *SnapshotBuilder) Metadata(key string, value interface{}) *SnapshotBuilder {
+	b.data[key] = value
+	return b
+}
+
+// AssetAliases sets the asset aliases applied to pair lookups (see
+// SimpleSnapshot.SetAssetAliases).
+func (b *SnapshotBuilder) AssetAliases(aliases primitives.AssetAliases) *SnapshotBuilder {
+	b.aliases = aliases
+	return b
+}
+
+// Build returns the snapshot.
+func (b *SnapshotBuilder) Build() *ImmutableSnapshot {
+	return &ImmutableSnapshot{snapshot: &SimpleSnapshot{
+		time:       b.time,
+		prices:     copyPrices(b.prices),
+		data:       copyMetadata(b.data),
+		priceTimes: copyTimes(b.priceTimes),
+		aliases:    b.aliases,
+	}}
+}
+
+// WithMetadata returns a copy of the snapshot with key set to value,
+// leaving the snapshot, which other tests or goroutines may share,
+// unchanged. Prefer it to Set on a snapshot that is not yours alone.
+func (s *SimpleSnapshot) WithMetadata(key string, value interface{}) *SimpleSnapshot {
+	s.pairMu.Lock()
+	aliases := s.aliases
+	s.pairMu.Unlock()
+
+	copied := &SimpleSnapshot{
+		time:       s.time,
+		prices:     copyPrices(s.prices),
+		data:       copyMetadata(s.data),
+		priceTimes: copyTimes(s.priceTimes),
+		aliases:    aliases,
+	}
+	copied.data[key] = value
+	return copied
+}
+
+// Freeze returns an ImmutableSnapshot holding a copy of the snapshot's
+// data.
+func (s *SimpleSnapshot) Freeze() *ImmutableSnapshot {
+	s.pairMu.Lock()
+	aliases := s.aliases
+	s.pairMu.Unlock()
+
+	return (&SnapshotBuilder{
+		time:       s.time,
+		prices:     s.prices,
+		data:       s.data,
+		priceTimes: s.priceTimes,
+		aliases:    aliases,
+	}).Build()
+}
+
+func copyPrices(prices map[string]primitives.Price) map[string]primitives.Price {
+	copied := make(map[string]primitives.Price, len(prices))
+	for pair, price := range prices {
+		copied[pair] = price
+	}
+	return copied
+}
+
+func copyMetadata(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = value
+	}
+	return copied
+}
+
+func copyTimes(times map[string]primitives.Time) map[string]primitives.Time {
+	if times == nil {
+		return nil
+	}
+	copied := make(map[string]primitives.Time, len(times))
+	for pair, t := range times {
+		copied[pair] = t
+	}
+	return copied
+}
@@ -0,0 +1,512 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+func px(s string) primitives.Price {
+	return primitives.MustPrice(primitives.MustDecimalFromString(s))
+}
+
+// TestOHLCVSnapshot tests bar and quote backed snapshots
+func TestOHLCVSnapshot(t *testing.T) {
+	bars := map[string]Bar{
+		"ETH/USD": {
+			Open:   px("2000"),
+			High:   px("2100"),
+			Low:    px("1950"),
+			Close:  px("2050"),
+			Volume: primitives.MustAmount(primitives.NewDecimal(1234)),
+		},
+	}
+	quotes := map[string]Quote{
+		"ETH/USD": {Bid: px("2049"), Ask: px("2051")},
+		"BTC/USD": {Bid: px("39990"), Ask: px("40010")},
+	}
+
+	snapshot, err := NewOHLCVSnapshot(primitives.Now(), bars, quotes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var _ BarSnapshot = snapshot
+	var _ QuoteSnapshot = snapshot
+
+	t.Run("Price prefers bar close", func(t *testing.T) {
+		price, err := snapshot.Price("ETH/USD")
+		if err != nil || !price.Equal(px("2050")) {
+			t.Errorf("price = %v, %v; want 2050", price, err)
+		}
+	})
+
+	t.Run("Price falls back to quote mid", func(t *testing.T) {
+		price, err := snapshot.Price("BTC/USD")
+		if err != nil || !price.Equal(px("40000")) {
+			t.Errorf("price = %v, %v; want 40000", price, err)
+		}
+		if len(snapshot.Prices()) != 2 {
+			t.Errorf("got %d prices, want 2", len(snapshot.Prices()))
+		}
+	})
+
+	t.Run("Bar and Quote accessors", func(t *testing.T) {
+		bar, err := snapshot.Bar("ETH/USD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bar.Range().String() != "150" {
+			t.Errorf("range = %s, want 150", bar.Range().String())
+		}
+		quote, err := snapshot.Quote("BTC/USD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if quote.Spread().String() != "20" {
+			t.Errorf("spread = %s, want 20", quote.Spread().String())
+		}
+		if _, err := snapshot.Bar("BTC/USD"); !errors.Is(err, ErrBarNotAvailable) {
+			t.Errorf("error = %v, want %v", err, ErrBarNotAvailable)
+		}
+		if _, err := snapshot.Quote("SOL/USD"); !errors.Is(err, ErrQuoteNotAvailable) {
+			t.Errorf("error = %v, want %v", err, ErrQuoteNotAvailable)
+		}
+		if _, err := snapshot.Price("SOL/USD"); !errors.Is(err, ErrPriceNotAvailable) {
+			t.Errorf("error = %v, want %v", err, ErrPriceNotAvailable)
+		}
+	})
+
+	t.Run("invalid data rejected", func(t *testing.T) {
+		badBar := map[string]Bar{"ETH/USD": {Open: px("10"), High: px("9"), Low: px("8"), Close: px("9")}}
+		if _, err := NewOHLCVSnapshot(primitives.Now(), badBar, nil); !errors.Is(err, ErrInvalidMarketData) {
+			t.Errorf("error = %v, want %v", err, ErrInvalidMarketData)
+		}
+		crossed := map[string]Quote{"ETH/USD": {Bid: px("11"), Ask: px("10")}}
+		if _, err := NewOHLCVSnapshot(primitives.Now(), nil, crossed); !errors.Is(err, ErrInvalidMarketData) {
+			t.Errorf("error = %v, want %v", err, ErrInvalidMarketData)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		snapshot.Set("k", 1)
+		if v, ok := snapshot.Get("k"); !ok || v != 1 {
+			t.Errorf("Get = %v, %v", v, ok)
+		}
+	})
+}
+
+func level(price, size string) mechanisms.PriceLevel {
+	return mechanisms.PriceLevel{
+		Price: px(price),
+		Size:  primitives.MustAmount(primitives.MustDecimalFromString(size)),
+	}
+}
+
+// TestDepthSnapshot tests order book depth attached to snapshots
+func TestDepthSnapshot(t *testing.T) {
+	snapshot := NewSimpleDepthSnapshot(primitives.Now(), map[string]primitives.Price{"ETH/USD": px("2000")})
+	var _ DepthSnapshot = snapshot
+
+	depth := mechanisms.OrderBookDepth{
+		Bids: []mechanisms.PriceLevel{level("1999", "1"), level("1998", "2")},
+		Asks: []mechanisms.PriceLevel{level("2001", "1"), level("2003", "3")},
+	}
+	if err := snapshot.SetDepth("ETH/USD", depth); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := snapshot.Depth("ETH/USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Timestamp.Equal(snapshot.Time()) {
+		t.Error("depth timestamp should default to snapshot time")
+	}
+	if _, err := snapshot.Depth("BTC/USD"); !errors.Is(err, ErrDepthNotAvailable) {
+		t.Errorf("error = %v, want %v", err, ErrDepthNotAvailable)
+	}
+
+	t.Run("invalid depth", func(t *testing.T) {
+		tests := []mechanisms.OrderBookDepth{
+			{Bids: []mechanisms.PriceLevel{level("1998", "1"), level("1999", "1")}},
+			{Asks: []mechanisms.PriceLevel{level("2003", "1"), level("2001", "1")}},
+			{Bids: []mechanisms.PriceLevel{level("2002", "1")}, Asks: []mechanisms.PriceLevel{level("2001", "1")}},
+		}
+		for i, d := range tests {
+			if err := snapshot.SetDepth("ETH/USD", d); !errors.Is(err, ErrInvalidMarketData) {
+				t.Errorf("case %d: error = %v, want %v", i, err, ErrInvalidMarketData)
+			}
+		}
+	})
+
+	t.Run("EstimateFill", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			side       mechanisms.OrderSide
+			size       string
+			wantPrice  string
+			wantFilled string
+		}{
+			{"buy within top level", mechanisms.OrderSideBuy, "0.5", "2001", "0.5"},
+			// (1*2001 + 1*2003) / 2
+			{"buy walks book", mechanisms.OrderSideBuy, "2", "2002", "2"},
+			// (1*1999 + 2*1998) / 3
+			{"sell exhausts book", mechanisms.OrderSideSell, "10", "1998.3333333333333333", "3"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				price, filled, err := EstimateFill(got, tt.side, primitives.MustAmount(primitives.MustDecimalFromString(tt.size)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if price.String() != tt.wantPrice {
+					t.Errorf("price = %s, want %s", price.String(), tt.wantPrice)
+				}
+				if filled.String() != tt.wantFilled {
+					t.Errorf("filled = %s, want %s", filled.String(), tt.wantFilled)
+				}
+			})
+		}
+
+		_, _, err := EstimateFill(mechanisms.OrderBookDepth{}, mechanisms.OrderSideBuy, primitives.MustAmount(primitives.One()))
+		if !errors.Is(err, ErrInsufficientDepth) {
+			t.Errorf("error = %v, want %v", err, ErrInsufficientDepth)
+		}
+	})
+}
+
+// TestMetadataKeys tests metadata enumeration on built-in snapshots
+func TestMetadataKeys(t *testing.T) {
+	simple := NewSimpleSnapshot(primitives.Now(), nil)
+	simple.Set("b", 2)
+	simple.Set("a", 1)
+	ohlcv, _ := NewOHLCVSnapshot(primitives.Now(), nil, nil)
+	ohlcv.Set("z", 1)
+
+	tests := []struct {
+		name     string
+		snapshot MetadataLister
+		want     []string
+	}{
+		{"simple", simple, []string{"a", "b"}},
+		{"ohlcv", ohlcv, []string{"z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.snapshot.MetadataKeys()
+			if len(got) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("keys = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestPairPrice tests pair lookups across key formats and inversions
+func TestPairPrice(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{
+		"eth-usdc": px("2000"),
+		"USD/BTC":  px("0.00002"),
+		"SOLUSDT":  px("150"),
+	})
+
+	tests := []struct {
+		pair    string
+		want    string
+		wantErr bool
+	}{
+		{"ETH/USDC", "2000", false},
+		{"SOL/USDT", "150", false},
+		{"BTC/USD", "50000", false},
+		{"USDC/ETH", "0.0005", false},
+		{"ETH/DAI", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pair, func(t *testing.T) {
+			price, err := PairPrice(snapshot, primitives.MustPair(tt.pair))
+			if tt.wantErr {
+				if !errors.Is(err, ErrPriceNotAvailable) {
+					t.Errorf("error = %v, want %v", err, ErrPriceNotAvailable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if price.String() != tt.want {
+				t.Errorf("price = %s, want %s", price, tt.want)
+			}
+		})
+	}
+
+	t.Run("Price matches canonical keys", func(t *testing.T) {
+		for _, key := range []string{"ETH/USDC", "ethusdc", "ETH_USDC"} {
+			if price, err := snapshot.Price(key); err != nil || price.String() != "2000" {
+				t.Errorf("Price(%q) = %s, %v; want 2000", key, price, err)
+			}
+		}
+		if _, err := snapshot.Price("USDC/ETH"); !errors.Is(err, ErrPriceNotAvailable) {
+			t.Errorf("Price should not resolve inverse pairs, got %v", err)
+		}
+	})
+
+	t.Run("asset aliases", func(t *testing.T) {
+		if _, err := snapshot.Price("WETH/USDC"); !errors.Is(err, ErrPriceNotAvailable) {
+			t.Fatalf("WETH should not match ETH without aliases, got %v", err)
+		}
+		aliased := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{"WETH/USDC": px("2000")})
+		aliased.SetAssetAliases(primitives.CommonAssetAliases)
+		for _, key := range []string{"ETH/USDC", "WETH/USDC"} {
+			if price, err := aliased.Price(key); err != nil || price.String() != "2000" {
+				t.Errorf("Price(%q) = %s, %v; want 2000", key, price, err)
+			}
+		}
+		if price, err := PairPrice(aliased, primitives.MustPair("USDC/ETH")); err != nil || price.String() != "0.0005" {
+			t.Errorf("PairPrice(USDC/ETH) = %s, %v; want 0.0005", price, err)
+		}
+	})
+
+	t.Run("duplicate spellings resolve deterministically", func(t *testing.T) {
+		dup := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{"ETHUSD": px("2001"), "ETH-USD": px("2000")})
+		for i := 0; i < 20; i++ {
+			if price, err := dup.PriceOf(primitives.MustPair("ETH/USD")); err != nil || price.String() != "2000" {
+				t.Fatalf("PriceOf(ETH/USD) = %s, %v; want 2000 from the first sorted key", price, err)
+			}
+		}
+	})
+}
+
+func TestCrossRateResolver(t *testing.T) {
+	now := primitives.Now()
+	snapshot := NewSimpleSnapshot(now, map[string]primitives.Price{
+		"BTC/USD":  px("50000"),
+		"ETH/BTC":  px("0.04"),
+		"SOL/ETH":  px("0.05"),
+		"USDC/USD": px("1"),
+		"DOGE/USD": px("0.1"),
+	})
+	snapshot.SetPriceTime("DOGE/USD", now.Add(primitives.Hours(-2)))
+
+	resolver, err := NewCrossRateResolver(2, primitives.Hours(1))
+	if err != nil {
+		t.Fatalf("NewCrossRateResolver: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		pair     string
+		want     string
+		wantHops int
+		wantErr  bool
+	}{
+		{"direct", "BTC/USD", "50000", 1, false},
+		{"two hops", "ETH/USD", "2000", 2, false},
+		{"inverse chain", "USD/ETH", "0.0005", 2, false},
+		{"inverse quote", "USD/BTC", "0.00002", 1, false},
+		{"beyond hop limit", "SOL/USD", "", 0, true},
+		{"stale quote excluded", "DOGE/BTC", "", 0, true},
+		{"unknown asset", "XRP/USD", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, path, err := resolver.Resolve(snapshot, primitives.MustPair(tt.pair))
+			if tt.wantErr {
+				if !errors.Is(err, ErrPriceNotAvailable) {
+					t.Errorf("error = %v, want %v", err, ErrPriceNotAvailable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if price.String() != tt.want {
+				t.Errorf("price = %s, want %s", price, tt.want)
+			}
+			if len(path) != tt.wantHops {
+				t.Errorf("path = %v, want %d hops", path, tt.wantHops)
+			}
+		})
+	}
+
+	if _, err := NewCrossRateResolver(0, primitives.Duration{}); err == nil {
+		t.Error("expected error for zero max hops")
+	}
+
+	wrapped := NewCrossRateSnapshot(snapshot, resolver)
+	price, err := wrapped.Price("ETH/USD")
+	if err != nil || price.String() != "2000" {
+		t.Errorf("CrossRateSnapshot.Price(ETH/USD) = %s, %v; want 2000", price, err)
+	}
+	if _, err := wrapped.Price("not a pair"); !errors.Is(err, ErrPriceNotAvailable) {
+		t.Errorf("error = %v, want %v", err, ErrPriceNotAvailable)
+	}
+
+	// Duplicate spellings of a pair must resolve the same way on every run
+	dup := NewSimpleSnapshot(now, map[string]primitives.Price{
+		"ETH/USD": px("2000"),
+		"ETHUSD":  px("2001"),
+		"BTC/USD": px("50000"),
+	})
+	first, _, err := resolver.Resolve(dup, primitives.MustPair("ETH/BTC"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		price, _, err := resolver.Resolve(dup, primitives.MustPair("ETH/BTC"))
+		if err != nil || !price.Equal(first) {
+			t.Fatalf("run %d resolved %s, %v; first run resolved %s", i, price, err, first)
+		}
+	}
+}
+
+func TestImmutableSnapshot(t *testing.T) {
+	start := primitives.Unix(1700000000, 0)
+	builder := NewSnapshotBuilder(start).
+		Price("WETH/USDC", px("2000")).
+		Metadata("vol", "0.6").
+		AssetAliases(primitives.CommonAssetAliases)
+	base := builder.Build()
+
+	// The builder and the caller's maps no longer reach a built snapshot
+	builder.Price("BTC/USD", px("40000")).Metadata("vol", "0.9")
+	base.Prices()["WETH/USDC"] = px("1")
+	if _, err := base.Price("BTC/USD"); !errors.Is(err, ErrPriceNotAvailable) {
+		t.Errorf("builder change leaked into a built snapshot: %v", err)
+	}
+	if price, err := PairPrice(base, primitives.MustPair("ETH/USDC")); err != nil || !price.Equal(px("2000")) {
+		t.Errorf("aliased price = %s (%v), want 2000", price, err)
+	}
+
+	bumped := base.WithMetadata("vol", "0.8")
+	if v, _ := base.Get("vol"); v != "0.6" {
+		t.Errorf("WithMetadata changed the original: vol = %v", v)
+	}
+	if v, _ := bumped.Get("vol"); v != "0.8" || !bumped.Time().Equal(start) {
+		t.Errorf("derived vol = %v", v)
+	}
+	later := base.ToBuilder().Time(start.Add(primitives.Hours(1))).PriceTime("WETH/USDC", start).Build()
+	if at, ok := later.PriceTime("WETH/USDC"); !ok || !at.Equal(start) || base.Time().Equal(later.Time()) {
+		t.Errorf("derived price time %v, %v", at, ok)
+	}
+	if keys := later.MetadataKeys(); len(keys) != 1 || keys[0] != "vol" {
+		t.Errorf("metadata keys = %v", keys)
+	}
+
+	shared := NewSimpleSnapshot(start, map[string]primitives.Price{"ETH/USD": px("2000")})
+	shared.Set("fee", "0.003")
+	copied := shared.WithMetadata("fee", "0.01")
+	if v, _ := shared.Get("fee"); v != "0.003" {
+		t.Errorf("SimpleSnapshot.WithMetadata changed the original: fee = %v", v)
+	}
+	frozen := shared.Freeze()
+	shared.Set("fee", "0.05")
+	if v, _ := frozen.Get("fee"); v != "0.003" {
+		t.Errorf("frozen fee = %v, want 0.003", v)
+	}
+	if v, _ := copied.Get("fee"); v != "0.01" {
+		t.Errorf("copied fee = %v, want 0.01", v)
+	}
+}
+
+// discountedPosition pays units of ETH in a year, valued in USD through a
+// ValuationContext
+type discountedPosition struct {
+	units primitives.Decimal
+}
+
+func (d *discountedPosition) ID() string         { return "discounted" }
+func (d *discountedPosition) Type() PositionType { return PositionTypeSpot }
+
+func (d *discountedPosition) Value(snapshot MarketSnapshot) (primitives.Amount, error) {
+	return d.ValueIn(NewValuationContext(context.Background(), snapshot))
+}
+
+func (d *discountedPosition) ValueIn(vc *ValuationContext) (primitives.Amount, error) {
+	if err := vc.Context().Err(); err != nil {
+		return primitives.Amount{}, err
+	}
+	price, err := vc.PriceOf(primitives.MustPair("ETH/USD"))
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	curve, err := vc.RateCurve("USD")
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	df, err := curve.DiscountFactor(primitives.One())
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	return primitives.MustAmount(d.units.Mul(price.Decimal()).Mul(df).Round(2, primitives.RoundHalfUp)), nil
+}
+
+// TestValuationContext tests valuing positions through a ValuationContext
+func TestValuationContext(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{
+		"BTC/USD": px("50000"),
+		"ETH/BTC": px("0.04"),
+	})
+	snapshot.Set(snapshotkeys.RateCurve("USD"), primitives.Zero())
+	resolver, err := NewCrossRateResolver(2, primitives.Duration{})
+	if err != nil {
+		t.Fatalf("NewCrossRateResolver: %v", err)
+	}
+	position := &discountedPosition{units: primitives.NewDecimal(2)}
+	portfolio := NewPortfolio(primitives.ZeroAmount())
+	if err := portfolio.AddPosition(position); err != nil {
+		t.Fatalf("AddPosition: %v", err)
+	}
+
+	// ETH/USD is only a cross rate
+	if _, err := portfolio.Value(snapshot); !errors.Is(err, ErrPriceNotAvailable) {
+		t.Errorf("value without cross rates: err = %v, want %v", err, ErrPriceNotAvailable)
+	}
+	vc := NewValuationContext(context.Background(), snapshot, WithCrossRates(resolver))
+	value, err := portfolio.Value(vc)
+	if err != nil || value.String() != "4000" {
+		t.Errorf("value = %s (%v), want 4000", value, err)
+	}
+	if price, err := vc.Price("ETH/USD"); err != nil || !price.Equal(px("2000")) {
+		t.Errorf("Price(ETH/USD) = %s (%v), want 2000", price, err)
+	}
+	if price, err := PairPrice(vc, primitives.MustPair("USD/ETH")); err != nil || !price.Equal(px("0.0005")) {
+		t.Errorf("PairPrice(USD/ETH) = %s (%v), want 0.0005", price, err)
+	}
+
+	// A flat 5% curve discounts the payment
+	snapshot.Set(snapshotkeys.RateCurve("USD"), "0.05")
+	if value, err := portfolio.Value(vc); err != nil || value.String() != "3804.92" {
+		t.Errorf("discounted value = %s (%v), want 3804.92", value, err)
+	}
+	snapshot.Set(snapshotkeys.RateCurve("USD"), true)
+	if _, err := vc.RateCurve("USD"); !errors.Is(err, ErrMetadataType) {
+		t.Errorf("RateCurve of a bool: err = %v, want %v", err, ErrMetadataType)
+	}
+	if _, err := vc.RateCurve("EUR"); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("missing RateCurve: err = %v, want %v", err, ErrMetadataNotFound)
+	}
+	snapshot.Set(snapshotkeys.RateCurve("USD"), "0")
+
+	// Rebinding keeps the resolver and carries the new context to positions
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rebound := NewValuationContext(ctx, vc)
+	if rebound.Snapshot() != MarketSnapshot(snapshot) {
+		t.Error("rebinding nested the valuation context")
+	}
+	if _, err := portfolio.Value(rebound); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled valuation: err = %v, want %v", err, context.Canceled)
+	}
+	if value, err := portfolio.PositionValue(position, NewValuationContext(nil, vc)); err != nil || value.String() != "4000" {
+		t.Errorf("PositionValue = %s (%v), want 4000", value, err)
+	}
+}
@@ -0,0 +1,472 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+// MechanismState is the state of a pool or venue that evolves over a run,
+// such as the reserves of an AMM. Market data moves it at every snapshot
+// through Advance, and strategies move it with UpdateStateAction, so
+// positions valued later in the run see the effect of earlier trades.
+//
+// States are registered with a MechanismStates registry (for the backtest
+// engine, Config.States) and exposed to strategies and positions through
+// the snapshot: look one up with StateOf.
+type MechanismState interface {
+	// Advance evolves the state to snapshot, e.g., adopting reserves
+	// observed in its metadata. It is called once per snapshot, in time
+	// order, before the snapshot is valued.
+	Advance(snapshot MarketSnapshot) error
+
+	// Clone returns an independent copy of the state. Engines clone states
+	// so each run starts from the registered state and a failed action can
+	// be rolled back.
+	Clone() MechanismState
+}
+
+// MechanismStates is a registry of mechanism states by pool or venue ID.
+//
+// Thread Safety: MechanismStates is not thread-safe.
+type MechanismStates struct {
+	states map[string]MechanismState
+}
+
+// NewMechanismStates creates an empty registry.
+func NewMechanismStates() *MechanismStates {
+	return &MechanismStates{states: make(map[string]MechanismState)}
+}
+
+// Register tracks state under id.
+// Returns ErrInvalidParams if id is empty, state is nil or id is taken.
+func (r *MechanismStates) Register(id string, state MechanismState) error {
+	if id == "" || state == nil {
+		return fmt.Errorf("%w: mechanism state needs an ID and a state", ErrInvalidParams)
+	}
+	if _, exists := r.states[id]; exists {
+		return fmt.Errorf("%w: mechanism state %s already registered", ErrInvalidParams, id)
+	}
+	r.states[id] = state
+	return nil
+}
+
+// Get returns the state tracked under id.
+func (r *MechanismStates) Get(id string) (MechanismState, bool) {
+	if r == nil {
+		return nil, false
+	}
+	state, ok := r.states[id]
+	return state, ok
+}
+
+// IDs returns the IDs of the tracked states in sorted order.
+func (r *MechanismStates) IDs() []string {
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.states))
+	for id := range r.states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Len returns the number of tracked states.
+func (r *MechanismStates) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.states)
+}
+
+// Clone returns a registry of independent copies of every state.
+func (r *MechanismStates) Clone() *MechanismStates {
+	clone := NewMechanismStates()
+	if r == nil {
+		return clone
+	}
+	for id, state := range r.states {
+		clone.states[id] = state.Clone()
+	}
+	return clone
+}
+
+// Restore replaces the registry's states with those of saved, a Clone
+// taken earlier, so a rollback is visible through snapshots the registry
+// is attached to.
+func (r *MechanismStates) Restore(saved *MechanismStates) {
+	r.states = saved.Clone().states
+}
+
+// Advance advances every state to snapshot, in ID order.
+func (r *MechanismStates) Advance(snapshot MarketSnapshot) error {
+	for _, id := range r.IDs() {
+		if err := r.states[id].Advance(snapshot); err != nil {
+			return fmt.Errorf("failed to advance mechanism state %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Update applies an UpdateStateAction to the state it names.
+// Returns ErrStateNotFound if no state is tracked under its ID.
+func (r *MechanismStates) Update(action *UpdateStateAction) error {
+	state, ok := r.Get(action.StateID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStateNotFound, action.StateID)
+	}
+	if action.Update == nil {
+		return fmt.Errorf("%w: update of %s has no function", ErrInvalidAction, action.StateID)
+	}
+	return action.Update(state)
+}
+
+// Attach returns snapshot with the registry's states exposed at
+// snapshotkeys.MechanismState, where StateOf finds them. The states are
+// live: updates made after attaching are visible through the returned
+// snapshot. Prices, metadata and the optional DepthSnapshot, BarSnapshot,
+// QuoteSnapshot, PairPricer, PriceTimestamper and MetadataLister
+// extensions are passed through to snapshot.
+func (r *MechanismStates) Attach(snapshot MarketSnapshot) MarketSnapshot {
+	return &stateSnapshot{MarketSnapshot: snapshot, states: r}
+}
+
+// StateOf returns the mechanism state tracked under id in snapshot.
+// Returns ErrStateNotFound if the snapshot carries no such state.
+//
+// Positions and strategies must not modify the returned state outside an
+// UpdateStateAction, which the engine can roll back.
+func StateOf(snapshot MarketSnapshot, id string) (MechanismState, error) {
+	raw, ok := snapshot.Get(snapshotkeys.MechanismState(id))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStateNotFound, id)
+	}
+	state, ok := raw.(MechanismState)
+	if !ok {
+		return nil, typeError(snapshotkeys.MechanismState(id), "MechanismState", raw)
+	}
+	return state, nil
+}
+
+// stateSnapshot is a MarketSnapshot carrying a registry of mechanism
+// states.
+type stateSnapshot struct {
+	MarketSnapshot
+	states *MechanismStates
+}
+
+// Get returns a tracked state for its snapshotkeys.MechanismState key and
+// the underlying snapshot's metadata otherwise.
+func (s *stateSnapshot) Get(key string) (interface{}, bool) {
+	for id, state := range s.states.states {
+		if key == snapshotkeys.MechanismState(id) {
+			return state, true
+		}
+	}
+	return s.MarketSnapshot.Get(key)
+}
+
+// MetadataKeys returns the underlying snapshot's keys and the state keys.
+func (s *stateSnapshot) MetadataKeys() []string {
+	var keys []string
+	if lister, ok := s.MarketSnapshot.(MetadataLister); ok {
+		keys = append(keys, lister.MetadataKeys()...)
+	}
+	for _, id := range s.states.IDs() {
+		keys = append(keys, snapshotkeys.MechanismState(id))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PriceOf resolves pair against the underlying snapshot.
+func (s *stateSnapshot) PriceOf(pair primitives.Pair) (primitives.Price, error) {
+	return PairPrice(s.MarketSnapshot, pair)
+}
+
+// PriceTime returns the underlying snapshot's observation time of pair.
+func (s *stateSnapshot) PriceTime(pair string) (primitives.Time, bool) {
+	if timestamps, ok := s.MarketSnapshot.(PriceTimestamper); ok {
+		return timestamps.PriceTime(pair)
+	}
+	return primitives.Time{}, false
+}
+
+// Depth returns the underlying snapshot's depth for pair.
+func (s *stateSnapshot) Depth(pair string) (mechanisms.OrderBookDepth, error) {
+	if depth, ok := s.MarketSnapshot.(DepthSnapshot); ok {
+		return depth.Depth(pair)
+	}
+	return mechanisms.OrderBookDepth{}, fmt.Errorf("%w: %s", ErrDepthNotAvailable, pair)
+}
+
+// Bar returns the underlying snapshot's bar for pair.
+func (s *stateSnapshot) Bar(pair string) (Bar, error) {
+	if bars, ok := s.MarketSnapshot.(BarSnapshot); ok {
+		return bars.Bar(pair)
+	}
+	return Bar{}, fmt.Errorf("%w: %s", ErrBarNotAvailable, pair)
+}
+
+// Quote returns the underlying snapshot's quote for pair.
+func (s *stateSnapshot) Quote(pair string) (Quote, error) {
+	if quotes, ok := s.MarketSnapshot.(QuoteSnapshot); ok {
+		return quotes.Quote(pair)
+	}
+	return Quote{}, fmt.Errorf("%w: %s", ErrQuoteNotAvailable, pair)
+}
+
+// UpdateStateAction changes a tracked mechanism state, e.g., moving a
+// pool's reserves by a swap the strategy made. Like SubmitOrderAction it is
+// handled by an engine that tracks states, which applies it in the same
+// transaction as the snapshot's other actions and rolls it back with them.
+// Pair it with the portfolio actions recording the trade itself.
+type UpdateStateAction struct {
+	StateID string
+
+	// Description names the update in String, e.g., "swap 10 ETH"
+	Description string
+
+	// Update changes the state in place
+	Update func(state MechanismState) error
+}
+
+// NewUpdateStateAction creates an action applying update to the state
+// tracked under stateID.
+func NewUpdateStateAction(stateID, description string, update func(state MechanismState) error) *UpdateStateAction {
+	return &UpdateStateAction{StateID: stateID, Description: description, Update: update}
+}
+
+// Apply rejects the update: states are tracked by the engine, which
+// handles this action itself.
+func (a *UpdateStateAction) Apply(portfolio *Portfolio) error {
+	return fmt.Errorf("%w: update of %s needs an engine that tracks mechanism states", ErrInvalidAction, a.StateID)
+}
+
+// String returns a description of this action.
+func (a *UpdateStateAction) String() string {
+	if a.Description == "" {
+		return fmt.Sprintf("UpdateState(%s)", a.StateID)
+	}
+	return fmt.Sprintf("UpdateState(%s: %s)", a.StateID, a.Description)
+}
+
+// TrackedPool is the MechanismState of a constant-product pool: its
+// reserves and fee rate. At each snapshot it adopts the reserves observed
+// at snapshotkeys.PoolReserveA and PoolReserveB when both are present, and
+// keeps its evolved reserves otherwise; Swap, Deposit and Withdraw move
+// them within the run.
+//
+// The reserve changes made by Swap, Deposit and Withdraw are the
+// strategy's own impact on the pool, which market data does not include.
+// Persistence carries a fraction of that impact over the observed
+// reserves at each snapshot: zero, the default, lets data replace it,
+// and one keeps it for the rest of the run.
+type TrackedPool struct {
+	PoolID string
+	Params mechanisms.PoolParams
+
+	// Persistence is the fraction of the strategy's impact kept at each
+	// snapshot with observed reserves, in [0, 1]
+	Persistence primitives.Decimal
+
+	// impactA and impactB are the strategy's net changes to the reserves
+	impactA, impactB primitives.Decimal
+}
+
+// NewTrackedPool creates the tracked state of poolID starting at params.
+func NewTrackedPool(poolID string, params mechanisms.PoolParams) *TrackedPool {
+	return &TrackedPool{PoolID: poolID, Params: params}
+}
+
+// Advance adopts the reserves observed in snapshot, if any.
+func (p *TrackedPool) Advance(snapshot MarketSnapshot) error {
+	reserveA, errA := GetDecimal(snapshot, snapshotkeys.PoolReserveA(p.PoolID))
+	reserveB, errB := GetDecimal(snapshot, snapshotkeys.PoolReserveB(p.PoolID))
+	if errors.Is(errA, ErrMetadataNotFound) || errors.Is(errB, ErrMetadataNotFound) {
+		return nil
+	}
+	if errA != nil {
+		return errA
+	}
+	if errB != nil {
+		return errB
+	}
+	if reserveA.IsNegative() || reserveB.IsNegative() {
+		return fmt.Errorf("%w: pool %s has negative reserves", ErrInvalidMarketData, p.PoolID)
+	}
+	if p.Persistence.IsNegative() || p.Persistence.GreaterThan(primitives.One()) {
+		return fmt.Errorf("%w: persistence of pool %s must be in [0, 1], got %s", ErrInvalidParams, p.PoolID, p.Persistence)
+	}
+
+	p.impactA, p.impactB = p.impactA.Mul(p.Persistence), p.impactB.Mul(p.Persistence)
+	p.Params.ReserveA = nonNegativeAmount(reserveA.Add(p.impactA))
+	p.Params.ReserveB = nonNegativeAmount(reserveB.Add(p.impactB))
+	return nil
+}
+
+// nonNegativeAmount returns d as an Amount, floored at zero.
+func nonNegativeAmount(d primitives.Decimal) primitives.Amount {
+	if d.IsNegative() {
+		return primitives.ZeroAmount()
+	}
+	return primitives.MustAmount(d)
+}
+
+// Clone returns a copy of the pool state.
+func (p *TrackedPool) Clone() MechanismState {
+	clone := *p
+	if p.Params.Metadata != nil {
+		clone.Params.Metadata = make(map[string]interface{}, len(p.Params.Metadata))
+		for key, value := range p.Params.Metadata {
+			clone.Params.Metadata[key] = value
+		}
+	}
+	return &clone
+}
+
+// SpotPrice returns the price of token A in token B, ReserveB / ReserveA.
+// Returns an error if reserve A is zero.
+func (p *TrackedPool) SpotPrice() (primitives.Price, error) {
+	price, err := p.Params.ReserveB.Decimal().Div(p.Params.ReserveA.Decimal())
+	if err != nil {
+		return primitives.Price{}, fmt.Errorf("pool %s has no reserve A: %w", p.PoolID, err)
+	}
+	return primitives.NewPrice(price)
+}
+
+// Swap trades amountIn through the pool by constant-product math and
+// returns the output. A buy pays token B for token A, a sell pays token A
+// for token B; the fee stays in the pool. Returns an error if the pool is
+// empty.
+func (p *TrackedPool) Swap(side mechanisms.OrderSide, amountIn primitives.Amount) (primitives.Amount, error) {
+	reserveIn, reserveOut := &p.Params.ReserveB, &p.Params.ReserveA
+	if side == mechanisms.OrderSideSell {
+		reserveIn, reserveOut = &p.Params.ReserveA, &p.Params.ReserveB
+	}
+	if reserveIn.IsZero() || reserveOut.IsZero() {
+		return primitives.Amount{}, fmt.Errorf("pool %s has no liquidity", p.PoolID)
+	}
+	in := amountIn.Decimal().Mul(primitives.One().Sub(p.Params.FeeRate))
+	out, err := reserveOut.Decimal().Mul(in).Div(reserveIn.Decimal().Add(in))
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	amountOut, err := primitives.NewAmount(out)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	remaining, err := reserveOut.Sub(amountOut)
+	if err != nil {
+		return primitives.Amount{}, err
+	}
+	*reserveIn, *reserveOut = reserveIn.Add(amountIn), remaining
+	if side == mechanisms.OrderSideSell {
+		p.impactA, p.impactB = p.impactA.Add(amountIn.Decimal()), p.impactB.Sub(out)
+	} else {
+		p.impactA, p.impactB = p.impactA.Sub(out), p.impactB.Add(amountIn.Decimal())
+	}
+	return amountOut, nil
+}
+
+// Deposit adds liquidity to the reserves.
+func (p *TrackedPool) Deposit(amounts mechanisms.TokenAmounts) {
+	p.Params.ReserveA = p.Params.ReserveA.Add(amounts.AmountA)
+	p.Params.ReserveB = p.Params.ReserveB.Add(amounts.AmountB)
+	p.impactA = p.impactA.Add(amounts.AmountA.Decimal())
+	p.impactB = p.impactB.Add(amounts.AmountB.Decimal())
+}
+
+// Withdraw removes liquidity from the reserves.
+// Returns an error if either amount exceeds its reserve.
+func (p *TrackedPool) Withdraw(amounts mechanisms.TokenAmounts) error {
+	reserveA, err := p.Params.ReserveA.Sub(amounts.AmountA)
+	if err != nil {
+		return fmt.Errorf("withdrawal exceeds reserve A of pool %s: %w", p.PoolID, err)
+	}
+	reserveB, err := p.Params.ReserveB.Sub(amounts.AmountB)
+	if err != nil {
+		return fmt.Errorf("withdrawal exceeds reserve B of pool %s: %w", p.PoolID, err)
+	}
+	p.Params.ReserveA, p.Params.ReserveB = reserveA, reserveB
+	p.impactA = p.impactA.Sub(amounts.AmountA.Decimal())
+	p.impactB = p.impactB.Sub(amounts.AmountB.Decimal())
+	return nil
+}
+
+// ApplyTrade moves the reserves by a trade of the strategy's.
+func (p *TrackedPool) ApplyTrade(trade PoolTrade) error {
+	switch trade.Kind {
+	case PoolSwap:
+		amountIn := trade.Amounts.AmountB
+		if trade.Side == mechanisms.OrderSideSell {
+			amountIn = trade.Amounts.AmountA
+		}
+		_, err := p.Swap(trade.Side, amountIn)
+		return err
+	case PoolDeposit:
+		p.Deposit(trade.Amounts)
+		return nil
+	case PoolWithdraw:
+		return p.Withdraw(trade.Amounts)
+	}
+	return fmt.Errorf("%w: unknown pool trade kind %d", ErrInvalidAction, trade.Kind)
+}
+
+// PoolTradeKind is the kind of a PoolTrade.
+type PoolTradeKind int
+
+const (
+	// PoolSwap swaps one token of the pool for the other
+	PoolSwap PoolTradeKind = iota
+
+	// PoolDeposit adds liquidity
+	PoolDeposit
+
+	// PoolWithdraw removes liquidity
+	PoolWithdraw
+)
+
+// PoolTrade is a trade the strategy makes against a tracked pool.
+type PoolTrade struct {
+	Kind PoolTradeKind
+
+	// Side is the direction of a swap: a buy pays token B for token A, a
+	// sell pays token A for token B
+	Side mechanisms.OrderSide
+
+	// Amounts are the tokens deposited or withdrawn, or the input of a
+	// swap: AmountB for a buy and AmountA for a sell
+	Amounts mechanisms.TokenAmounts
+}
+
+// ImpactedState is a MechanismState the strategy's own trades move, such
+// as TrackedPool. Engines apply the trades they attribute to a pool (see
+// backtest.PoolImpact) so later valuations in the run see the impact.
+type ImpactedState interface {
+	MechanismState
+
+	// ApplyTrade moves the state by trade.
+	ApplyTrade(trade PoolTrade) error
+}
+
+// PoolLiquidityPosition is a Position providing liquidity to a tracked
+// pool. An engine applying pool impact deposits its PoolAmounts into the
+// pool's state when the position is added and withdraws them when it is
+// removed.
+type PoolLiquidityPosition interface {
+	Position
+
+	// TrackedPoolID returns the ID the pool's MechanismState is
+	// registered under.
+	TrackedPoolID() string
+
+	// PoolAmounts returns the tokens the position holds in the pool.
+	PoolAmounts() mechanisms.TokenAmounts
+}
@@ -3,9 +3,15 @@ package strategy
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
 	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
 )
 
 // mockPosition is a test implementation of the Position interface
@@ -314,6 +320,43 @@ func TestPortfolioPositions(t *testing.T) {
 	}
 }
 
+// TestPortfolioPositionsOrder tests that position iteration is deterministic
+func TestPortfolioPositionsOrder(t *testing.T) {
+	ids := []string{"eth", "btc", "sol", "arb", "op", "link"}
+	p := NewPortfolio(primitives.ZeroAmount())
+	for _, id := range ids {
+		_ = p.AddPosition(&mockPosition{id: id, posType: PositionTypeSpot, value: primitives.ZeroAmount()})
+	}
+
+	idsOf := func(positions []Position) string {
+		out := ""
+		for _, pos := range positions {
+			out += pos.ID() + ","
+		}
+		return out
+	}
+
+	if got := idsOf(p.Positions()); got != "arb,btc,eth,link,op,sol," {
+		t.Errorf("Positions() order = %s, want sorted by ID", got)
+	}
+	if got := idsOf(p.Clone().Positions()); got != idsOf(p.Positions()) {
+		t.Errorf("Clone().Positions() order = %s", got)
+	}
+
+	seeded := idsOf(p.PositionsSeeded(42))
+	if len(p.PositionsSeeded(42)) != len(ids) {
+		t.Fatalf("PositionsSeeded returned %d positions", len(p.PositionsSeeded(42)))
+	}
+	for i := 0; i < 5; i++ {
+		if got := idsOf(p.PositionsSeeded(42)); got != seeded {
+			t.Errorf("PositionsSeeded(42) = %s, previously %s", got, seeded)
+		}
+	}
+	if idsOf(p.PositionsSeeded(7)) == seeded && idsOf(p.PositionsSeeded(8)) == seeded {
+		t.Error("expected different seeds to produce different orders")
+	}
+}
+
 // TestPortfolioPositionsByType tests filtering positions by type
 func TestPortfolioPositionsByType(t *testing.T) {
 	pos1 := &mockPosition{id: "pos1", posType: PositionTypeSpot, value: primitives.MustAmount(primitives.NewDecimal(1000))}
@@ -557,6 +600,55 @@ func TestPortfolioClear(t *testing.T) {
 	}
 }
 
+func TestPortfolioJournal(t *testing.T) {
+	position := func(id string) Position {
+		return &mockPosition{id: id, posType: PositionTypeSpot, value: primitives.MustAmount(primitives.NewDecimal(100))}
+	}
+	p := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	_ = p.AddPosition(position("b"))
+	_ = p.AddPosition(position("d"))
+	p.EnableHistory()
+	p.BeginJournal()
+	defer p.EndJournal()
+
+	start := p.Savepoint()
+	_ = p.AddPosition(position("a"))
+	_ = p.AdjustCash(primitives.NewDecimal(-250))
+
+	mid := p.Savepoint()
+	_ = p.RemovePosition("d")
+	_ = p.AddPosition(position("c"))
+	p.SetCash(primitives.MustAmount(primitives.NewDecimal(5)))
+	p.Clear()
+
+	p.RollbackTo(mid)
+	if got := positionIDs(p.Positions()); got != "a,b,d" {
+		t.Errorf("positions after rollback to mid = %s, want a,b,d", got)
+	}
+	if !p.CashDecimal().Equal(primitives.NewDecimal(750)) {
+		t.Errorf("cash after rollback to mid = %s, want 750", p.CashDecimal())
+	}
+	if got := len(p.History()); got != 2 {
+		t.Errorf("history after rollback to mid has %d events, want 2", got)
+	}
+
+	p.RollbackTo(start)
+	if got := positionIDs(p.Positions()); got != "b,d" {
+		t.Errorf("positions after rollback to start = %s, want b,d", got)
+	}
+	if !p.CashDecimal().Equal(primitives.NewDecimal(1000)) || len(p.History()) != 0 {
+		t.Errorf("expected cash 1000 and no history, got %s and %d events", p.CashDecimal(), len(p.History()))
+	}
+}
+
+func positionIDs(positions []Position) string {
+	ids := make([]string, len(positions))
+	for i, pos := range positions {
+		ids[i] = pos.ID()
+	}
+	return strings.Join(ids, ",")
+}
+
 // TestActions tests action implementations
 func TestActions(t *testing.T) {
 	t.Run("AddPositionAction", func(t *testing.T) {
@@ -724,6 +816,94 @@ func TestMarketSnapshot(t *testing.T) {
 	})
 }
 
+// TestTypedMetadataAccessors tests the typed snapshot metadata helpers
+func TestTypedMetadataAccessors(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Time{}, map[string]primitives.Price{})
+	snapshot.Set("str", "1584563250000000000000000000000")
+	snapshot.Set("float", 0.0001)
+	snapshot.Set("int", 200000)
+	snapshot.Set("int64", int64(42))
+	snapshot.Set("dec", primitives.MustDecimalFromString("1.5"))
+	snapshot.Set("bad-dec", "not-a-number")
+	snapshot.Set("negative", -3)
+	snapshot.Set("slice", []int{1})
+
+	t.Run("GetString", func(t *testing.T) {
+		v, err := GetString(snapshot, "str")
+		if err != nil || v != "1584563250000000000000000000000" {
+			t.Errorf("GetString = %q, %v", v, err)
+		}
+		v, err = GetString(snapshot, "dec")
+		if err != nil || v != "1.5" {
+			t.Errorf("GetString(Stringer) = %q, %v", v, err)
+		}
+		if _, err := GetString(snapshot, "int"); !errors.Is(err, ErrMetadataType) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataType)
+		}
+	})
+
+	t.Run("GetDecimal", func(t *testing.T) {
+		tests := []struct {
+			key  string
+			want string
+		}{
+			{"float", "0.0001"},
+			{"int", "200000"},
+			{"int64", "42"},
+			{"dec", "1.5"},
+			{"str", "1584563250000000000000000000000"},
+		}
+		for _, tt := range tests {
+			v, err := GetDecimal(snapshot, tt.key)
+			if err != nil {
+				t.Fatalf("GetDecimal(%s) error: %v", tt.key, err)
+			}
+			if v.String() != tt.want {
+				t.Errorf("GetDecimal(%s) = %s, want %s", tt.key, v.String(), tt.want)
+			}
+		}
+		if _, err := GetDecimal(snapshot, "bad-dec"); !errors.Is(err, ErrMetadataType) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataType)
+		}
+		if _, err := GetDecimal(snapshot, "slice"); !errors.Is(err, ErrMetadataType) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataType)
+		}
+	})
+
+	t.Run("GetInt", func(t *testing.T) {
+		v, err := GetInt(snapshot, "int")
+		if err != nil || v != 200000 {
+			t.Errorf("GetInt = %d, %v", v, err)
+		}
+		v, err = GetInt(snapshot, "int64")
+		if err != nil || v != 42 {
+			t.Errorf("GetInt(int64) = %d, %v", v, err)
+		}
+		if _, err := GetInt(snapshot, "float"); !errors.Is(err, ErrMetadataType) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataType)
+		}
+	})
+
+	t.Run("GetPrice", func(t *testing.T) {
+		p, err := GetPrice(snapshot, "dec")
+		if err != nil || p.String() != "1.5" {
+			t.Errorf("GetPrice = %s, %v", p.String(), err)
+		}
+		if _, err := GetPrice(snapshot, "negative"); !errors.Is(err, ErrMetadataType) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataType)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := GetString(snapshot, "missing"); !errors.Is(err, ErrMetadataNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataNotFound)
+		}
+		if _, err := GetDecimal(nil, "missing"); !errors.Is(err, ErrMetadataNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrMetadataNotFound)
+		}
+	})
+}
+
 // mockStrategyImpl is a test implementation of Strategy interface
 type mockStrategyImpl struct{}
 
@@ -937,3 +1117,1291 @@ func containsCheck(s, substr string) bool {
 	}
 	return false
 }
+
+// TestPortfolioHistory tests event recording and time-travel reconstruction
+func TestPortfolioHistory(t *testing.T) {
+	start := primitives.Unix(1700000000, 0)
+	at := func(hours int64) primitives.Time { return start.Add(primitives.Hours(hours)) }
+	pos := func(id string) *mockPosition {
+		return &mockPosition{id: id, posType: PositionTypeSpot, value: primitives.MustAmount(primitives.NewDecimal(100))}
+	}
+
+	portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	if _, err := portfolio.At(start); !errors.Is(err, ErrHistoryDisabled) {
+		t.Errorf("expected %v, got %v", ErrHistoryDisabled, err)
+	}
+	if portfolio.History() != nil {
+		t.Error("expected nil history when disabled")
+	}
+
+	portfolio.EnableHistory()
+	portfolio.SetTime(at(1))
+	_ = portfolio.AddPosition(pos("a"))
+	_ = portfolio.AdjustCash(primitives.NewDecimal(-100))
+	portfolio.SetTime(at(2))
+	_ = portfolio.AddPosition(pos("b"))
+	_ = portfolio.AdjustCash(primitives.NewDecimal(-100))
+	portfolio.SetTime(at(3))
+	_ = portfolio.RemovePosition("a")
+	_ = portfolio.AdjustCash(primitives.NewDecimal(150))
+
+	events := portfolio.History()
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events, got %d", len(events))
+	}
+	if events[2].Type != EventPositionAdded || !events[2].Time.Equal(at(2)) {
+		t.Errorf("unexpected event %+v", events[2])
+	}
+	if !events[5].Cash.Equal(primitives.NewDecimal(950)) {
+		t.Errorf("expected cash 950 after last event, got %s", events[5].Cash)
+	}
+
+	tests := []struct {
+		name      string
+		time      primitives.Time
+		positions []string
+		cash      int64
+	}{
+		{"before any change", start, nil, 1000},
+		{"after first trade", at(1), []string{"a"}, 900},
+		{"between trades", at(2).Add(primitives.Minutes(30)), []string{"a", "b"}, 800},
+		{"latest", at(5), []string{"b"}, 950},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			past, err := portfolio.At(tt.time)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if past.PositionCount() != len(tt.positions) {
+				t.Errorf("expected %d positions, got %d", len(tt.positions), past.PositionCount())
+			}
+			for _, id := range tt.positions {
+				if !past.HasPosition(id) {
+					t.Errorf("expected position %s", id)
+				}
+			}
+			if !past.CashDecimal().Equal(primitives.NewDecimal(tt.cash)) {
+				t.Errorf("expected cash %d, got %s", tt.cash, past.CashDecimal())
+			}
+			if past.HistoryEnabled() {
+				t.Error("reconstructed portfolio should not record history")
+			}
+		})
+	}
+}
+
+func TestResizePositionAction(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(2000)),
+	})
+	quantity := func(v string) primitives.Decimal { return primitives.MustDecimalFromString(v) }
+
+	tests := []struct {
+		name     string
+		action   *ResizePositionAction
+		wantHeld string // "" when the holding is removed
+		wantCash int64
+		wantErr  error
+	}{
+		{"reduce", NewResizePositionAction("eth", quantity("2")), "2", 2000 + 6000, nil},
+		{"increase", NewResizePositionAction("eth", quantity("6")), "6", 2000 - 2000, nil},
+		{"close half", NewScalePositionAction("eth", quantity("0.5")), "2.5", 2000 + 5000, nil},
+		{"close fully", NewResizePositionAction("eth", primitives.Zero()), "", 2000 + 10000, nil},
+		{"negative size", NewResizePositionAction("eth", quantity("-1")), "5", 2000, ErrInvalidAction},
+		{"missing position", NewResizePositionAction("btc", quantity("1")), "5", 2000, ErrPositionNotFound},
+		{"not resizable", NewResizePositionAction("mock", quantity("1")), "5", 2000, ErrInvalidAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(2000)))
+			_ = p.AddPosition(NewHolding("eth", "ETH/USD", quantity("5")))
+			_ = p.AddPosition(&mockPosition{id: "mock", posType: PositionTypeSpot, value: primitives.ZeroAmount()})
+
+			resolved, err := tt.action.Resolve(snapshot)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			err = resolved.Apply(p)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+			}
+
+			held := ""
+			if position, err := p.GetPosition("eth"); err == nil {
+				held = position.(*Holding).Quantity().String()
+			}
+			if held != tt.wantHeld {
+				t.Errorf("held = %q, want %q", held, tt.wantHeld)
+			}
+			if !p.CashDecimal().Equal(primitives.NewDecimal(tt.wantCash)) {
+				t.Errorf("cash = %s, want %d", p.CashDecimal(), tt.wantCash)
+			}
+		})
+	}
+
+	t.Run("unresolved", func(t *testing.T) {
+		p := NewPortfolio(primitives.ZeroAmount())
+		if err := NewResizePositionAction("eth", primitives.One()).Apply(p); !errors.Is(err, ErrInvalidAction) {
+			t.Errorf("error = %v, want %v", err, ErrInvalidAction)
+		}
+	})
+}
+
+// costedPosition charges a fixed fee on top of its value at entry.
+type costedPosition struct {
+	mockPosition
+	fee primitives.Decimal
+}
+
+func (c *costedPosition) EntryCost(snapshot MarketSnapshot) (primitives.Amount, error) {
+	return primitives.NewAmount(c.value.Decimal().Add(c.fee))
+}
+
+func TestOpenClosePositionActions(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(2000)),
+	})
+	amount := func(v int64) primitives.Amount { return primitives.MustAmount(primitives.NewDecimal(v)) }
+	resolve := func(t *testing.T, action DeferredAction) Action {
+		t.Helper()
+		resolved, err := action.Resolve(snapshot)
+		if err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+		return resolved
+	}
+
+	tests := []struct {
+		name     string
+		action   DeferredAction
+		wantCash int64
+		wantIDs  []string
+		wantErr  error // nil with wantFail accepts any error
+		wantFail bool
+	}{
+		{"open pays value", NewOpenPositionAction(NewHolding("eth", "ETH/USD", primitives.NewDecimal(2))), 10000 - 4000, []string{"eth", "held"}, nil, false},
+		{"open pays entry cost", NewOpenPositionAction(&costedPosition{mockPosition{id: "lp", value: amount(1000)}, primitives.NewDecimal(30)}), 10000 - 1030, []string{"held", "lp"}, nil, false},
+		{"open duplicate", NewOpenPositionAction(NewHolding("held", "ETH/USD", primitives.One())), 10000, []string{"held"}, nil, true},
+		{"open unpriced", NewOpenPositionAction(NewHolding("btc", "BTC/USD", primitives.One())), 10000, []string{"held"}, ErrPriceNotAvailable, true},
+		{"close credits value", NewClosePositionAction("held"), 10000 + 6000, nil, nil, false},
+		{"close missing", NewClosePositionAction("btc"), 10000, []string{"held"}, ErrPositionNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPortfolio(amount(10000))
+			_ = p.AddPosition(NewHolding("held", "ETH/USD", primitives.NewDecimal(3)))
+
+			err := resolve(t, tt.action).Apply(p)
+			if (err != nil) != tt.wantFail || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("Apply error = %v, want %v", err, tt.wantErr)
+			}
+			if !p.CashDecimal().Equal(primitives.NewDecimal(tt.wantCash)) {
+				t.Errorf("cash = %s, want %d", p.CashDecimal(), tt.wantCash)
+			}
+			var ids []string
+			for _, position := range p.Positions() {
+				ids = append(ids, position.ID())
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("positions = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	t.Run("unresolved", func(t *testing.T) {
+		p := NewPortfolio(amount(100))
+		for _, action := range []Action{NewOpenPositionAction(NewHolding("eth", "ETH/USD", primitives.One())), NewClosePositionAction("eth")} {
+			if err := action.Apply(p); !errors.Is(err, ErrInvalidAction) {
+				t.Errorf("%s: error = %v, want %v", action, err, ErrInvalidAction)
+			}
+		}
+	})
+}
+
+func TestPortfolioValidate(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(2000)),
+	})
+	amount := func(v int64) primitives.Amount { return primitives.MustAmount(primitives.NewDecimal(v)) }
+	eth := func(id string, units int64) Position { return NewHolding(id, "ETH/USD", primitives.NewDecimal(units)) }
+
+	tests := []struct {
+		name        string
+		actions     []Action
+		constraints []Constraint
+		want        []error // sentinel per diagnostic, in order
+		wantIndex   []int
+		wantCash    int64
+	}{
+		{
+			name:     "feasible batch",
+			actions:  []Action{NewOpenPositionAction(eth("a", 1)), NewClosePositionAction("held")},
+			wantCash: 10000 - 2000 + 2000,
+		},
+		{
+			name:      "duplicate IDs in the portfolio and the batch",
+			actions:   []Action{NewAddPositionAction(eth("held", 1)), NewAddPositionAction(eth("a", 1)), NewAddPositionAction(eth("a", 1))},
+			want:      []error{ErrPositionExists, ErrPositionExists},
+			wantIndex: []int{0, 2},
+			wantCash:  10000,
+		},
+		{
+			name:      "insufficient cash under the cost model",
+			actions:   []Action{NewOpenPositionAction(eth("a", 4)), NewOpenPositionAction(eth("b", 2))},
+			want:      []error{ErrInsufficientCash},
+			wantIndex: []int{1},
+			wantCash:  10000 - 8000 - 4000,
+		},
+		{
+			name:        "constraints checked after the batch",
+			actions:     []Action{NewOpenPositionAction(eth("a", 4))},
+			constraints: []Constraint{MinCash(primitives.NewDecimal(5000)), MaxPositions(2)},
+			want:        []error{ErrConstraintViolated},
+			wantIndex:   []int{-1},
+			wantCash:    10000 - 8000,
+		},
+		{
+			name:      "failed actions are skipped",
+			actions:   []Action{NewRemovePositionAction("missing"), NewSubmitOrderAction(Order{ID: "o"}), NewAdjustCashAction(primitives.NewDecimal(-1), "fee")},
+			want:      []error{ErrPositionNotFound, ErrInvalidAction},
+			wantIndex: []int{0, 1},
+			wantCash:  9999,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPortfolio(amount(10000))
+			_ = p.AddPosition(eth("held", 1))
+
+			report := p.Validate(tt.actions, snapshot, tt.constraints...)
+			if len(report.Diagnostics) != len(tt.want) {
+				t.Fatalf("diagnostics = %v, want %d", report, len(tt.want))
+			}
+			for i, d := range report.Diagnostics {
+				if !errors.Is(d, tt.want[i]) || d.Index != tt.wantIndex[i] {
+					t.Errorf("diagnostic %d = %v (index %d), want %v (index %d)", i, d, d.Index, tt.want[i], tt.wantIndex[i])
+				}
+			}
+			if report.OK() != (len(tt.want) == 0) || (report.Err() == nil) != report.OK() {
+				t.Errorf("OK() = %t, Err() = %v", report.OK(), report.Err())
+			}
+			if !report.Cash.Equal(primitives.NewDecimal(tt.wantCash)) {
+				t.Errorf("Cash = %s, want %d", report.Cash, tt.wantCash)
+			}
+
+			// The portfolio itself is untouched
+			if !p.CashDecimal().Equal(primitives.NewDecimal(10000)) || p.PositionCount() != 1 {
+				t.Errorf("portfolio changed: cash %s, %d positions", p.CashDecimal(), p.PositionCount())
+			}
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	calls := make(map[string][]string)
+	record := func(name string) Strategy {
+		return StrategyFunc(func(ctx context.Context, p *Portfolio, s MarketSnapshot) ([]Action, error) {
+			clock := s.Time().Time().Format("15:04")
+			calls[name] = append(calls[name], clock)
+			if name == "range" && !TriggerFired(ctx, "hedge") {
+				t.Errorf("range ran at %s without the hedge trigger in context", clock)
+			}
+			return []Action{NewAdjustCashAction(primitives.Zero(), name)}, nil
+		})
+	}
+
+	scheduler := NewScheduler()
+	for _, trigger := range []struct {
+		name     string
+		interval time.Duration
+	}{{"hedge", time.Hour}, {"range", 24 * time.Hour}, {"tick", 0}} {
+		if err := scheduler.Every(trigger.name, primitives.NewDuration(trigger.interval), record(trigger.name)); err != nil {
+			t.Fatalf("Every(%s): %v", trigger.name, err)
+		}
+	}
+	if err := scheduler.Every("hedge", primitives.NewDuration(time.Minute), record("hedge")); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("duplicate trigger: got %v, want ErrInvalidParams", err)
+	}
+
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	var actions [][]Action
+	for i := 0; i < 6; i++ {
+		snapshot := NewSimpleSnapshot(primitives.NewTime(start.Add(time.Duration(i)*30*time.Minute)), nil)
+		batch, err := scheduler.Rebalance(context.Background(), portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("Rebalance: %v", err)
+		}
+		actions = append(actions, batch)
+	}
+
+	want := map[string]string{
+		"hedge": "23:00 00:00 01:00",
+		"range": "23:00 00:00",
+		"tick":  "23:00 23:30 00:00 00:30 01:00 01:30",
+	}
+	for name, clocks := range want {
+		if got := strings.Join(calls[name], " "); got != clocks {
+			t.Errorf("%s ran at %q, want %q", name, got, clocks)
+		}
+	}
+	if len(actions[2]) != 3 || actions[2][0].String() != NewAdjustCashAction(primitives.Zero(), "hedge").String() {
+		t.Errorf("midnight actions = %v, want hedge, range and tick in order", actions[2])
+	}
+
+	failing := NewScheduler()
+	boom := errors.New("boom")
+	_ = failing.Every("hedge", primitives.Duration{}, StrategyFunc(func(context.Context, *Portfolio, MarketSnapshot) ([]Action, error) {
+		return nil, boom
+	}))
+	if _, err := failing.Rebalance(context.Background(), portfolio, NewSimpleSnapshot(primitives.NewTime(start), nil)); !errors.Is(err, boom) {
+		t.Errorf("got %v, want the trigger's error", err)
+	}
+	if FiredTriggers(context.Background()) != nil {
+		t.Error("FiredTriggers outside a Scheduler should be nil")
+	}
+}
+
+// expiringPosition pays payoff at expiry.
+type expiringPosition struct {
+	id     string
+	expiry primitives.Time
+	payoff primitives.Decimal
+}
+
+func (p *expiringPosition) ID() string         { return p.id }
+func (p *expiringPosition) Type() PositionType { return PositionTypeOption }
+func (p *expiringPosition) Value(MarketSnapshot) (primitives.Amount, error) {
+	return primitives.ZeroAmount(), nil
+}
+func (p *expiringPosition) Expiry() primitives.Time { return p.expiry }
+func (p *expiringPosition) Settle(MarketSnapshot) (primitives.Decimal, error) {
+	return p.payoff, nil
+}
+
+// closingPerp settles for its P&L whenever closed.
+type closingPerp struct {
+	mockPosition
+	pnl primitives.Decimal
+}
+
+func (p *closingPerp) Settle(MarketSnapshot) (primitives.Decimal, error) {
+	return p.pnl, nil
+}
+
+func TestSettlePositionAction(t *testing.T) {
+	expiry := primitives.Unix(1000, 0)
+	snapshot := NewSimpleSnapshot(expiry, nil)
+	call := &expiringPosition{id: "call", expiry: expiry, payoff: primitives.NewDecimal(250)}
+	short := &expiringPosition{id: "short-put", expiry: expiry, payoff: primitives.NewDecimal(-100)}
+
+	if Expired(call, primitives.Unix(999, 0)) || !Expired(call, expiry) {
+		t.Error("Expired should hold from the expiry time on")
+	}
+	if Expired(NewHolding("eth", "ETH/USD", primitives.One()), expiry) {
+		t.Error("a holding never expires")
+	}
+
+	portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	for _, position := range []Position{call, short, NewHolding("eth", "ETH/USD", primitives.One())} {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewSettlePositionAction("call").Apply(portfolio); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("unresolved settlement: got %v, want ErrInvalidAction", err)
+	}
+	for _, id := range []string{"call", "short-put"} {
+		action, err := NewSettlePositionAction(id).Resolve(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := action.Apply(portfolio); err != nil {
+			t.Fatalf("settle %s: %v", id, err)
+		}
+	}
+	if portfolio.HasPosition("call") || portfolio.HasPosition("short-put") {
+		t.Error("settled positions still held")
+	}
+	if !portfolio.CashDecimal().Equal(primitives.NewDecimal(1150)) {
+		t.Errorf("cash = %s, want 1150", portfolio.CashDecimal())
+	}
+
+	// A losing settlement debits cash, and settling needs no expiry
+	perp := &closingPerp{mockPosition{id: "perp"}, primitives.NewDecimal(-400)}
+	if err := portfolio.AddPosition(perp); err != nil {
+		t.Fatal(err)
+	}
+	if Expired(perp, expiry) {
+		t.Error("a Settleable position without an expiry never expires")
+	}
+	action, _ := NewSettlePositionAction("perp").Resolve(snapshot)
+	if err := action.Apply(portfolio); err != nil {
+		t.Fatalf("settle perp: %v", err)
+	}
+	if portfolio.HasPosition("perp") || !portfolio.CashDecimal().Equal(primitives.NewDecimal(750)) {
+		t.Errorf("after losing settlement cash = %s, want 750 and the position removed", portfolio.CashDecimal())
+	}
+
+	action, _ = NewSettlePositionAction("eth").Resolve(snapshot)
+	if err := action.Apply(portfolio); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("settling a holding: got %v, want ErrInvalidAction", err)
+	}
+	action, _ = NewSettlePositionAction("missing").Resolve(snapshot)
+	if err := action.Apply(portfolio); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("settling a missing position: got %v, want ErrPositionNotFound", err)
+	}
+}
+
+func TestPortfolioGreeks(t *testing.T) {
+	d := primitives.NewDecimal
+	portfolio := NewPortfolio(primitives.MustAmount(d(1000)))
+	for _, position := range []Position{
+		NewHolding("eth", "ETH/USD", d(3)),
+		NewHolding("btc", "BTC-USD", d(1)),
+		&mockPosition{id: "straddle", posType: PositionTypeOption, withRisk: true, risk: RiskMetrics{Delta: d(-1), Gamma: d(2), Vega: d(50)}},
+	} {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	greeks, err := portfolio.Greeks(nil)
+	if err != nil {
+		t.Fatalf("Greeks: %v", err)
+	}
+	if !greeks.NetDelta("ETH").Equal(d(3)) || !greeks.NetDelta("BTC").Equal(d(1)) || !greeks.NetDelta(UnknownUnderlying).Equal(d(-1)) {
+		t.Errorf("unexpected deltas %v", greeks.ByUnderlying)
+	}
+	if !greeks.Total.Delta.Equal(d(3)) || !greeks.Total.Gamma.Equal(d(2)) || !greeks.Value(GreekVega, "").Equal(d(50)) {
+		t.Errorf("unexpected totals %+v", greeks.Total)
+	}
+
+	if !(GreekLimits{}).IsZero() || (GreekLimits{Vega: d(1)}).IsZero() {
+		t.Error("IsZero should hold only without positive limits")
+	}
+	limits := GreekLimits{NetDelta: map[string]primitives.Decimal{"ETH": d(2), "BTC": d(2)}, Gamma: d(5), Vega: d(40)}
+	breaches := limits.Breaches(greeks)
+	if len(breaches) != 2 || breaches[0].Underlying != "ETH" || breaches[1].Greek != GreekVega {
+		t.Fatalf("unexpected breaches %v", breaches)
+	}
+	if got := breaches[0].String(); got != "|ETH delta| 3 > 2" {
+		t.Errorf("breach = %q", got)
+	}
+	if !errors.Is(breaches[1], ErrGreekLimitBreached) {
+		t.Error("a breach should wrap ErrGreekLimitBreached")
+	}
+
+	report := portfolio.Validate([]Action{NewRemovePositionAction("eth")}, nil, limits)
+	if report.OK() || !strings.Contains(report.String(), "|vega| 50 > 40") {
+		t.Errorf("vega limit not reported: %s", report)
+	}
+	limits.Vega = d(60)
+	if report := portfolio.Validate([]Action{NewRemovePositionAction("eth")}, nil, limits); !report.OK() {
+		t.Errorf("batch within limits rejected: %s", report)
+	}
+}
+
+func TestMarketState(t *testing.T) {
+	d := primitives.NewDecimal
+	price := func(v int64) primitives.Price { return primitives.MustPrice(d(v)) }
+	size := func(v int64) primitives.Amount { return primitives.MustAmount(d(v)) }
+	start := primitives.Unix(1700000000, 0)
+
+	state := NewMarketState()
+	for _, event := range []Event{
+		TradeEvent{At: start, Pair: "ETH/USD", Price: price(100), Size: size(1), Side: mechanisms.OrderSideBuy},
+		TradeEvent{At: start, Pair: "ETH/USD", Price: price(104), Size: size(2), Side: mechanisms.OrderSideBuy},
+		TradeEvent{At: start, Pair: "ETH/USD", Price: price(97), Size: size(1), Side: mechanisms.OrderSideSell},
+		FundingEvent{At: start, Symbol: "ETH-PERP", Rate: d(1), MarkPrice: price(98)},
+		&LiquidationEvent{At: start, Pair: "ETH/USD", Price: price(96), Size: size(5), Side: mechanisms.OrderSideSell},
+	} {
+		state.Apply(event)
+	}
+
+	first, err := state.Snapshot(start)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	bar, err := first.Bar("ETH/USD")
+	if err != nil {
+		t.Fatalf("Bar: %v", err)
+	}
+	if got := fmt.Sprint(bar.Open, bar.High, bar.Low, bar.Close, bar.Volume); got != "100 104 97 97 4" {
+		t.Errorf("bar = %s, want 100 104 97 97 4", got)
+	}
+	if v, ok := first.Get(snapshotkeys.LiquidationVolume("ETH/USD")); !ok || !v.(primitives.Decimal).Equal(d(5)) {
+		t.Errorf("liquidation volume = %v, want 5", v)
+	}
+
+	// Without new trades the price carries forward on a flat bar, funding
+	// persists and liquidation volume resets
+	second, err := state.Snapshot(start.Add(primitives.Minutes(1)))
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	bar, _ = second.Bar("ETH/USD")
+	if !bar.Open.Equal(price(97)) || !bar.High.Equal(price(97)) || !bar.Volume.IsZero() {
+		t.Errorf("carried bar = %+v, want flat at 97", bar)
+	}
+	if v, ok := second.Get(snapshotkeys.PerpFundingRate("ETH-PERP")); !ok || !v.(primitives.Decimal).Equal(d(1)) {
+		t.Errorf("funding rate = %v, want 1", v)
+	}
+	if v, ok := second.Get(snapshotkeys.PerpMarkPrice("ETH-PERP")); !ok || !v.(primitives.Decimal).Equal(d(98)) {
+		t.Errorf("mark price = %v, want 98", v)
+	}
+	if _, ok := second.Get(snapshotkeys.LiquidationVolume("ETH/USD")); ok {
+		t.Error("liquidation volume should reset between snapshots")
+	}
+}
+
+// coinPosition is a mockPosition valued in currency
+type coinPosition struct {
+	mockPosition
+	currency string
+}
+
+func (c *coinPosition) Denomination() string { return c.currency }
+
+func TestPortfolioBaseCurrency(t *testing.T) {
+	d := primitives.NewDecimal
+	snapshot := NewSimpleSnapshot(primitives.NewTime(time.Now()), map[string]primitives.Price{
+		"ETH/USD":  primitives.MustPrice(d(2000)),
+		"USDC-USD": primitives.MustPrice(primitives.One()),
+	})
+	portfolio := NewPortfolio(primitives.MustAmount(d(1000)))
+	perp := &coinPosition{mockPosition: mockPosition{id: "perp", posType: PositionTypePerpetual, value: primitives.MustAmount(primitives.MustDecimalFromString("0.5"))}, currency: "ETH"}
+	if err := portfolio.AddPosition(perp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a base currency values are summed as returned
+	if value, err := portfolio.Value(snapshot); err != nil || !value.Decimal().Equal(primitives.MustDecimalFromString("1000.5")) {
+		t.Errorf("unconverted value = %s (%v), want 1000.5", value, err)
+	}
+	if _, err := portfolio.ValueIn(snapshot, "ETH"); err == nil {
+		t.Error("ValueIn without a base currency should fail")
+	}
+
+	portfolio.SetBaseCurrency("USD")
+	if value, err := portfolio.PositionValue(perp, snapshot); err != nil || !value.Decimal().Equal(d(1000)) {
+		t.Errorf("perp value = %s (%v), want 1000 USD", value, err)
+	}
+	if value, err := portfolio.Value(snapshot); err != nil || !value.Decimal().Equal(d(2000)) {
+		t.Errorf("portfolio value = %s (%v), want 2000 USD", value, err)
+	}
+	if value, err := portfolio.ValueIn(snapshot, "ETH"); err != nil || !value.Decimal().Equal(d(1)) {
+		t.Errorf("value in ETH = %s (%v), want 1", value, err)
+	}
+	if clone := portfolio.Clone(); clone.BaseCurrency() != "USD" {
+		t.Errorf("clone base currency = %q", clone.BaseCurrency())
+	}
+
+	// The inverse quote and aliased key formats both resolve
+	if rate, err := FXRate(snapshot, "USD", "USDC"); err != nil || !rate.Equal(primitives.One()) {
+		t.Errorf("USD/USDC = %s (%v), want 1", rate, err)
+	}
+	perp.currency = "BTC"
+	if _, err := portfolio.Value(snapshot); !errors.Is(err, ErrPriceNotAvailable) {
+		t.Errorf("expected ErrPriceNotAvailable for an unquoted denomination, got %v", err)
+	}
+
+	// Closing credits the converted value
+	perp.currency = "ETH"
+	closing, err := NewClosePositionAction("perp").Resolve(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := closing.Apply(portfolio); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if !portfolio.Cash().Decimal().Equal(d(2000)) {
+		t.Errorf("cash after close = %s, want 2000", portfolio.Cash())
+	}
+}
+
+func TestBaselineStrategies(t *testing.T) {
+	ctx := context.Background()
+	at := func(day int64, price int64) MarketSnapshot {
+		return NewSimpleSnapshot(primitives.Unix(day*86400, 0), map[string]primitives.Price{
+			"ETH/USD": primitives.MustPrice(primitives.NewDecimal(price)),
+		})
+	}
+	// apply runs one Rebalance and applies its actions as the engine would
+	apply := func(t *testing.T, strat Strategy, portfolio *Portfolio, snapshot MarketSnapshot) int {
+		t.Helper()
+		actions, err := strat.Rebalance(ctx, portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("Rebalance: %v", err)
+		}
+		for _, action := range actions {
+			if deferred, ok := action.(DeferredAction); ok {
+				if action, err = deferred.Resolve(snapshot); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := action.Apply(portfolio); err != nil {
+				t.Fatalf("Apply %s: %v", action, err)
+			}
+		}
+		return len(actions)
+	}
+	quantity := func(portfolio *Portfolio, id string) string {
+		position, err := portfolio.GetPosition(id)
+		if err != nil {
+			return err.Error()
+		}
+		return position.(*Holding).Quantity().String()
+	}
+
+	t.Run("buy and hold", func(t *testing.T) {
+		hold, err := NewBuyAndHold("ETH/USD", primitives.NewDecimalFromFloat(0.5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000)))
+		if n := apply(t, hold, portfolio, at(0, 2000)); n != 1 {
+			t.Fatalf("expected one purchase, got %d actions", n)
+		}
+		if got := quantity(portfolio, hold.PositionID()); got != "2.5" || !portfolio.CashDecimal().Equal(primitives.NewDecimal(5000)) {
+			t.Errorf("holding %s with cash %s, want 2.5 and 5000", got, portfolio.CashDecimal())
+		}
+		if n := apply(t, hold, portfolio, at(1, 1000)); n != 0 {
+			t.Errorf("expected no trades after the purchase, got %d actions", n)
+		}
+		if n := apply(t, hold, NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000))), at(0, 2000)); n != 1 {
+			t.Errorf("expected a fresh portfolio to buy again")
+		}
+		if actions, err := hold.Rebalance(WithWarmUp(ctx), NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000))), at(0, 2000)); err != nil || len(actions) != 0 {
+			t.Errorf("expected no purchase during warm-up, got %v (%v)", actions, err)
+		}
+	})
+
+	t.Run("dca", func(t *testing.T) {
+		dca, err := NewDCA("ETH/USD", primitives.MustAmount(primitives.NewDecimal(1000)), primitives.Hours(24*7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(2500)))
+		steps := []struct {
+			day, price int64
+			buys       int
+			quantity   string
+		}{
+			{0, 2000, 1, "0.5"},
+			{1, 1500, 0, "0.5"},
+			{7, 1000, 1, "1.5"},
+			{14, 500, 1, "2.5"}, // only 500 of cash left
+			{21, 500, 0, "2.5"},
+		}
+		for _, step := range steps {
+			if n := apply(t, dca, portfolio, at(step.day, step.price)); n != step.buys {
+				t.Errorf("day %d: %d actions, want %d", step.day, n, step.buys)
+			}
+			if got := quantity(portfolio, dca.PositionID()); got != step.quantity {
+				t.Errorf("day %d: holding %s, want %s", step.day, got, step.quantity)
+			}
+		}
+		if !portfolio.CashDecimal().IsZero() {
+			t.Errorf("cash %s, want all invested", portfolio.CashDecimal())
+		}
+	})
+
+	t.Run("dca retries skipped purchases", func(t *testing.T) {
+		dca, err := NewDCA("ETH/USD", primitives.MustAmount(primitives.NewDecimal(1000)), primitives.Hours(24*7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(2500)))
+
+		// The engine skips the first purchase, so the next snapshot retries it
+		if actions, err := dca.Rebalance(ctx, portfolio, at(0, 2000)); err != nil || len(actions) != 1 {
+			t.Fatalf("expected a purchase, got %v (%v)", actions, err)
+		}
+		if n := apply(t, dca, portfolio, at(1, 2000)); n != 1 {
+			t.Errorf("expected the skipped purchase retried, got %d actions", n)
+		}
+		if n := apply(t, dca, portfolio, at(7, 2000)); n != 0 {
+			t.Errorf("expected the cadence to run from the retried purchase, got %d actions", n)
+		}
+
+		// A new run with the same instance starts the schedule over
+		fresh := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(2500)))
+		if n := apply(t, dca, fresh, at(8, 2000)); n != 1 {
+			t.Errorf("expected a fresh portfolio to buy at once, got %d actions", n)
+		}
+		if got := quantity(fresh, dca.PositionID()); got != "0.5" {
+			t.Errorf("fresh holding %s, want 0.5", got)
+		}
+	})
+
+	if _, err := NewBuyAndHold("ETH/USD", primitives.NewDecimal(2)); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for a weight above 1, got %v", err)
+	}
+	if _, err := NewDCA("ETH/USD", primitives.ZeroAmount(), primitives.Hours(1)); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams for a zero amount, got %v", err)
+	}
+}
+
+func TestGridTrader(t *testing.T) {
+	price := func(v int64) primitives.Price { return primitives.MustPrice(primitives.NewDecimal(v)) }
+	config := GridConfig{Pair: "ETH/USD", Lower: price(100), Upper: price(400), Levels: 3, Size: primitives.MustAmount(primitives.One())}
+
+	for name, mutate := range map[string]func(*GridConfig){
+		"empty pair":     func(c *GridConfig) { c.Pair = "" },
+		"inverted range": func(c *GridConfig) { c.Lower, c.Upper = c.Upper, c.Lower },
+		"one level":      func(c *GridConfig) { c.Levels = 1 },
+		"zero size":      func(c *GridConfig) { c.Size = primitives.ZeroAmount() },
+	} {
+		invalid := config
+		mutate(&invalid)
+		if _, err := NewGridTrader(invalid); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("%s: expected ErrInvalidParams, got %v", name, err)
+		}
+	}
+
+	arithmetic, err := NewGridTrader(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geometric := config
+	geometric.Geometric = true
+	ratio, err := NewGridTrader(geometric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(arithmetic.Levels()); got != "[100 250 400]" {
+		t.Errorf("arithmetic levels = %s", got)
+	}
+	if got := ratio.Levels()[1].Decimal().Round(8, primitives.RoundHalfEven).String(); got != "200" {
+		t.Errorf("geometric middle level = %s, want 200", got)
+	}
+
+	// Laying out the grid at 250 buys inventory for the sell at 400
+	snapshot := NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{"ETH/USD": price(250)})
+	if actions, err := arithmetic.Rebalance(WithWarmUp(context.Background()), NewPortfolio(primitives.ZeroAmount()), snapshot); err != nil || len(actions) != 0 {
+		t.Fatalf("expected no orders during warm-up, got %v (%v)", actions, err)
+	}
+	portfolio := NewPortfolio(primitives.ZeroAmount())
+	actions, err := arithmetic.Rebalance(context.Background(), portfolio, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orders []string
+	for _, action := range actions {
+		order := action.(*SubmitOrderAction).Order
+		orders = append(orders, fmt.Sprintf("%s %s %s@%s", order.Type, order.Side, order.Size, order.Price))
+	}
+	if want := "[market buy 1@0 limit buy 1@100 limit sell 1@400]"; fmt.Sprint(orders) != want {
+		t.Errorf("orders = %v, want %s", orders, want)
+	}
+
+	// A filled buy queues the sell one level up
+	buy := actions[1].(*SubmitOrderAction).Order
+	fill := Fill{OrderID: buy.ID, Pair: "ETH/USD", Side: buy.Side, Quantity: primitives.One(), Price: price(100)}
+	arithmetic.OnOrderUpdate(OrderUpdate{Order: buy, Status: OrderStatusFilled, Filled: primitives.One(), Fill: &fill})
+	arithmetic.OnOrderUpdate(OrderUpdate{Order: buy, Status: OrderStatusFilled, Filled: primitives.One(), Fill: &fill})
+	next, err := arithmetic.Rebalance(context.Background(), portfolio, snapshot)
+	if err != nil || len(next) != 1 {
+		t.Fatalf("expected one counterpart order, got %v (%v)", next, err)
+	}
+	if order := next[0].(*SubmitOrderAction).Order; order.Side != mechanisms.OrderSideSell || !order.Price.Equal(price(250)) {
+		t.Errorf("counterpart = %s at %s, want a sell at 250", order, order.Price)
+	}
+	if !arithmetic.Inventory().Equal(primitives.One()) {
+		t.Errorf("inventory = %s, want 1 after a repeated update", arithmetic.Inventory())
+	}
+
+	// A rejected order leaves its level empty rather than open forever
+	sell := actions[2].(*SubmitOrderAction).Order
+	arithmetic.OnOrderUpdate(OrderUpdate{Order: sell, Status: OrderStatusRejected, Filled: primitives.Zero()})
+	if arithmetic.OpenOrders() != 2 {
+		t.Errorf("open orders = %d, want 2 after the sell at 400 is rejected", arithmetic.OpenOrders())
+	}
+	if next, err := arithmetic.Rebalance(context.Background(), portfolio, snapshot); err != nil || len(next) != 0 {
+		t.Errorf("expected no orders after a rejection, got %v (%v)", next, err)
+	}
+
+	// Reused on a portfolio without its holding, the grid starts over
+	rerun, err := arithmetic.Rebalance(context.Background(), NewPortfolio(primitives.ZeroAmount()), snapshot)
+	if err != nil || len(rerun) != 3 {
+		t.Fatalf("expected the grid laid out again, got %v (%v)", rerun, err)
+	}
+	if arithmetic.OpenOrders() != 3 || !arithmetic.Inventory().IsZero() {
+		t.Errorf("open orders = %d, inventory = %s; want 3 and 0 on a new run", arithmetic.OpenOrders(), arithmetic.Inventory())
+	}
+	// but not on one holding its inventory, which it keeps trading
+	held := NewPortfolio(primitives.ZeroAmount())
+	if err := held.AddPosition(NewHolding(arithmetic.PositionID(), "ETH/USD", primitives.One())); err != nil {
+		t.Fatal(err)
+	}
+	if resumed, err := arithmetic.Rebalance(context.Background(), held, snapshot); err != nil || len(resumed) != 0 {
+		t.Errorf("expected no new layout on a portfolio holding the grid, got %v (%v)", resumed, err)
+	}
+}
+
+func TestMechanismStates(t *testing.T) {
+	amount := func(s string) primitives.Amount { return primitives.MustAmount(primitives.MustDecimalFromString(s)) }
+	pool := NewTrackedPool("eth-usdc", mechanisms.PoolParams{
+		ReserveA: amount("100"),
+		ReserveB: amount("200000"),
+		FeeRate:  primitives.MustDecimalFromString("0.003"),
+	})
+	states := NewMechanismStates()
+	if err := states.Register("eth-usdc", pool); err != nil {
+		t.Fatal(err)
+	}
+	if err := states.Register("eth-usdc", pool); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("duplicate registration: expected ErrInvalidParams, got %v", err)
+	}
+
+	bar := Bar{Open: px("2000"), High: px("2100"), Low: px("1900"), Close: px("2050"), Volume: amount("5")}
+	base, err := NewOHLCVSnapshot(primitives.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)), map[string]Bar{"ETH/USD": bar}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := states.Attach(base)
+	if got, err := snapshot.(BarSnapshot).Bar("ETH/USD"); err != nil || got != bar {
+		t.Errorf("bar not passed through: %v, %v", got, err)
+	}
+	if _, err := snapshot.(DepthSnapshot).Depth("ETH/USD"); !errors.Is(err, ErrDepthNotAvailable) {
+		t.Errorf("expected ErrDepthNotAvailable, got %v", err)
+	}
+	if _, err := StateOf(base, "eth-usdc"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound without attached states, got %v", err)
+	}
+
+	// Updates are visible through the attached snapshot
+	saved := states.Clone()
+	update := NewUpdateStateAction("eth-usdc", "swap 1 ETH", func(state MechanismState) error {
+		out, err := state.(*TrackedPool).Swap(mechanisms.OrderSideSell, amount("1"))
+		if err == nil && !out.Decimal().Round(2, primitives.RoundHalfEven).Equal(primitives.MustDecimalFromString("1974.32")) {
+			return fmt.Errorf("swap returned %s", out)
+		}
+		return err
+	})
+	if err := update.Apply(NewPortfolio(amount("0"))); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected ErrInvalidAction applying to a portfolio, got %v", err)
+	}
+	if err := states.Update(update); err != nil {
+		t.Fatal(err)
+	}
+	state, err := StateOf(snapshot, "eth-usdc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.(*TrackedPool).Params.ReserveA; !got.Equal(amount("101")) {
+		t.Errorf("reserve A after swap = %s, want 101", got)
+	}
+
+	// Restoring a clone rolls the update back
+	states.Restore(saved)
+	state, _ = StateOf(snapshot, "eth-usdc")
+	if got := state.(*TrackedPool).Params.ReserveA; !got.Equal(amount("100")) {
+		t.Errorf("reserve A after restore = %s, want 100", got)
+	}
+
+	// Observed reserves replace evolved ones
+	base.Set(snapshotkeys.PoolReserveA("eth-usdc"), "120")
+	base.Set(snapshotkeys.PoolReserveB("eth-usdc"), "240000")
+	if err := states.Advance(base); err != nil {
+		t.Fatal(err)
+	}
+	if price, err := state.(*TrackedPool).SpotPrice(); err != nil || !price.Decimal().Equal(primitives.NewDecimal(2000)) {
+		t.Errorf("spot price after advance = %v, %v; want 2000", price, err)
+	}
+	if err := state.(*TrackedPool).Withdraw(mechanisms.TokenAmounts{AmountA: amount("121"), AmountB: amount("0")}); err == nil {
+		t.Error("expected error withdrawing more than the reserve")
+	}
+	if err := states.Update(NewUpdateStateAction("missing", "", nil)); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("expected ErrStateNotFound, got %v", err)
+	}
+}
+
+// convexPosition has no analytic Greeks. It is worth S²/100 + 1000·σ +
+// 10000·r plus a unit a day of carry, for S the ETH/USD price, σ the ETH
+// implied vol and r the USD cash yield.
+type convexPosition struct {
+	id    string
+	start primitives.Time
+}
+
+func (p *convexPosition) ID() string         { return p.id }
+func (p *convexPosition) Type() PositionType { return PositionTypeLiquidityPool }
+
+func (p *convexPosition) Value(snapshot MarketSnapshot) (primitives.Amount, error) {
+	price, err := snapshot.Price("ETH/USD")
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	vol, err := GetDecimal(snapshot, snapshotkeys.OptionImpliedVol("ETH"))
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	rate, err := GetDecimal(snapshot, snapshotkeys.RateCashYield("USD"))
+	if err != nil {
+		return primitives.ZeroAmount(), err
+	}
+	s := price.Decimal().Float64()
+	days := snapshot.Time().Sub(p.start).Hours() / 24
+	value := s*s/100 + 1000*vol.Float64() + 10000*rate.Float64() + days
+	return primitives.MustAmount(primitives.NewDecimalFromFloat(value)), nil
+}
+
+type convexUnderlyingPosition struct{ convexPosition }
+
+func (p *convexUnderlyingPosition) Underlying() string { return "ETH" }
+
+func TestNumericalGreeks(t *testing.T) {
+	start := primitives.Unix(1700000000, 0)
+	snapshot := NewSimpleSnapshot(start, map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(100)),
+		"ETH/BTC": primitives.MustPrice(primitives.NewDecimal(4)),
+		"BTC/USD": primitives.MustPrice(primitives.NewDecimal(25)),
+	})
+	snapshot.Set(snapshotkeys.OptionImpliedVol("ETH"), primitives.MustDecimalFromString("0.6"))
+	snapshot.Set(snapshotkeys.RateCashYield("USD"), primitives.MustDecimalFromString("0.05"))
+	near := func(got primitives.Decimal, want float64) bool {
+		return math.Abs(got.Float64()-want) < 1e-6
+	}
+
+	// Bumping BTC raises BTC/USD and lowers ETH/BTC, leaving ETH/USD
+	bumped := BumpSnapshot(snapshot, Bump{Underlying: "BTC", Price: primitives.MustDecimalFromString("0.25")})
+	if p, _ := bumped.Price("BTC/USD"); !near(p.Decimal(), 31.25) {
+		t.Errorf("bumped BTC/USD = %s, want 31.25", p)
+	}
+	if p, _ := bumped.Price("ETH/BTC"); !near(p.Decimal(), 3.2) {
+		t.Errorf("bumped ETH/BTC = %s, want 3.2", p)
+	}
+	if p, _ := bumped.Price("ETH/USD"); !near(p.Decimal(), 100) {
+		t.Errorf("bumped ETH/USD = %s, want 100", p)
+	}
+
+	// Δ = 2S/100 and Γ = 2/100 exactly for a quadratic; vega and rho are
+	// per 1%
+	risk, err := NumericalGreeks(&convexPosition{id: "lp", start: start}, snapshot, "ETH", Bumps{})
+	if err != nil {
+		t.Fatalf("NumericalGreeks: %v", err)
+	}
+	for name, check := range map[string][2]interface{}{
+		"delta": {risk.Delta, 2.0},
+		"gamma": {risk.Gamma, 0.02},
+		"vega":  {risk.Vega, 10.0},
+		"theta": {risk.Theta, 1.0},
+		"rho":   {risk.Rho, 100.0},
+	} {
+		if !near(check[0].(primitives.Decimal), check[1].(float64)) {
+			t.Errorf("%s = %s, want %g", name, check[0], check[1])
+		}
+	}
+	if _, err := NumericalGreeks(&convexPosition{id: "lp", start: start}, snapshot, "SOL", Bumps{}); !errors.Is(err, ErrPriceNotAvailable) {
+		t.Errorf("unpriced underlying: got %v, want ErrPriceNotAvailable", err)
+	}
+
+	portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	for _, position := range []Position{
+		&convexPosition{id: "lp", start: start},
+		&convexUnderlyingPosition{convexPosition{id: "product", start: start}},
+		NewHolding("btc", "BTC/USD", primitives.One()),
+	} {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatal(err)
+		}
+	}
+	greeks, err := portfolio.Greeks(snapshot)
+	if err != nil {
+		t.Fatalf("Greeks: %v", err)
+	}
+	if len(greeks.Numerical) != 2 || greeks.Numerical[0] != "lp" || greeks.Numerical[1] != "product" {
+		t.Errorf("numerical positions = %v, want [lp product]", greeks.Numerical)
+	}
+	// The LP is found sensitive to ETH alone; its carry is unattributed
+	if !near(greeks.NetDelta("ETH"), 4) || !near(greeks.NetDelta("BTC"), 1) || !near(greeks.Total.Vega, 20) {
+		t.Errorf("unexpected exposures %v", greeks.ByUnderlying)
+	}
+	if !near(greeks.ByUnderlying[UnknownUnderlying].Theta, 1) || !near(greeks.Total.Rho, 200) {
+		t.Errorf("unexpected carry %+v", greeks.ByUnderlying[UnknownUnderlying])
+	}
+}
+
+func TestRiskSlide(t *testing.T) {
+	start := primitives.Unix(1700000000, 0)
+	snapshot := NewSimpleSnapshot(start, map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(100)),
+		"BTC/USD": primitives.MustPrice(primitives.NewDecimal(25)),
+	})
+	snapshot.Set(snapshotkeys.OptionImpliedVol("ETH/USD"), primitives.MustDecimalFromString("0.6"))
+	snapshot.Set(snapshotkeys.OptionImpliedVol("ETH"), primitives.MustDecimalFromString("0.6"))
+	snapshot.Set(snapshotkeys.RateCashYield("USD"), primitives.Zero())
+	portfolio := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(1000)))
+	for _, position := range []Position{
+		&convexPosition{id: "lp", start: start},
+		NewHolding("btc", "BTC/USD", primitives.One()),
+	} {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slide, err := portfolio.RiskSlide(snapshot, RiskSlideConfig{Underlying: "ETH"})
+	if err != nil {
+		t.Fatalf("RiskSlide: %v", err)
+	}
+	if len(slide.PnL) != 7 || len(slide.PnL[0]) != 5 || !slide.Value.Equal(primitives.NewDecimal(725)) {
+		t.Fatalf("unexpected grid %dx%d of value %s", len(slide.PnL), len(slide.PnL[0]), slide.Value)
+	}
+	// +10% price: 110²/100 − 100 = 21; +50% vol: 1000 × 0.3 = 300
+	if got := slide.PnL[4][4].Float64(); math.Abs(got-321) > 1e-9 {
+		t.Errorf("P&L at +10%%, +50%% = %g, want 321", got)
+	}
+	if got := slide.PnL[3][2]; !got.IsZero() {
+		t.Errorf("unshocked P&L = %s, want 0", got)
+	}
+	for _, row := range slide.ByPosition["btc"] {
+		for _, v := range row {
+			if !v.IsZero() {
+				t.Fatalf("BTC holding moved under an ETH shock: %v", slide.ByPosition["btc"])
+			}
+		}
+	}
+	price, vol, pnl := slide.Worst()
+	if price.Float64() != -0.3 || vol.Float64() != -0.5 || math.Abs(pnl.Float64()+351) > 1e-9 {
+		t.Errorf("worst = %s at %s price, %s vol; want -351 at -0.3, -0.5", pnl, price, vol)
+	}
+	if s := slide.String(); !strings.HasPrefix(s, "ETH px\\vol") || !strings.Contains(s, "+10%") || !strings.Contains(s, "321.00") {
+		t.Errorf("unexpected table:\n%s", s)
+	}
+
+	// A market-wide shock moves the holding too
+	all, err := portfolio.RiskSlide(snapshot, RiskSlideConfig{
+		PriceShocks: []primitives.Decimal{primitives.MustDecimalFromString("-0.2")},
+		VolShocks:   []primitives.Decimal{primitives.Zero()},
+	})
+	if err != nil {
+		t.Fatalf("RiskSlide: %v", err)
+	}
+	if got := all.ByPosition["btc"][0][0]; !got.Equal(primitives.NewDecimal(-5)) {
+		t.Errorf("BTC P&L at -20%% = %s, want -5", got)
+	}
+
+	if _, err := portfolio.RiskSlide(snapshot, RiskSlideConfig{PriceShocks: []primitives.Decimal{primitives.One().Neg()}}); err == nil {
+		t.Error("a -100% price shock should be rejected")
+	}
+}
+
+func TestPositionTags(t *testing.T) {
+	d := primitives.NewDecimal
+	portfolio := NewPortfolio(primitives.MustAmount(d(1000)))
+	tags := Tags{TagStrategy: "basis", TagBook: "delta-one"}
+	if err := portfolio.AddPosition(NewHolding("eth", "ETH/USD", d(1)), tags, Tags{TagIdea: "carry"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := portfolio.AddPosition(NewHolding("btc", "BTC/USD", d(1)), Tags{TagStrategy: "trend"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := portfolio.AddPosition(NewHolding("sol", "SOL/USD", d(1))); err != nil {
+		t.Fatal(err)
+	}
+	tags[TagStrategy] = "changed"
+	got := portfolio.Tags("eth")
+	if got[TagStrategy] != "basis" || got[TagIdea] != "carry" || len(got) != 3 {
+		t.Errorf("eth tags = %v", got)
+	}
+	got[TagBook] = "mutated"
+	if portfolio.Tags("eth")[TagBook] != "delta-one" {
+		t.Error("Tags should return a copy")
+	}
+	if keys := portfolio.TagKeys(); strings.Join(keys, ",") != "book,idea,strategy" {
+		t.Errorf("tag keys = %v", keys)
+	}
+	if tagged := portfolio.PositionsTagged(TagStrategy, "trend"); len(tagged) != 1 || tagged[0].ID() != "btc" {
+		t.Errorf("trend positions = %v", tagged)
+	}
+	groups := portfolio.GroupByTag(TagStrategy)
+	if len(groups) != 3 || groups[Untagged][0].ID() != "sol" {
+		t.Errorf("strategy groups = %v", groups)
+	}
+	greeks, err := portfolio.GreeksByTag(nil, TagStrategy)
+	if err != nil {
+		t.Fatalf("GreeksByTag: %v", err)
+	}
+	if !greeks["basis"].NetDelta("ETH").Equal(d(1)) || !greeks["basis"].NetDelta("BTC").IsZero() {
+		t.Errorf("basis greeks = %v", greeks["basis"].ByUnderlying)
+	}
+
+	// Replacing keeps the tags; rolling back a removal restores them
+	if err := NewReplacePositionAction("eth", NewHolding("eth", "ETH/USD", d(2))).Apply(portfolio); err != nil {
+		t.Fatal(err)
+	}
+	if portfolio.Tags("eth")[TagStrategy] != "basis" {
+		t.Errorf("replaced position lost its tags: %v", portfolio.Tags("eth"))
+	}
+	checkpoint := portfolio.Checkpoint()
+	portfolio.BeginJournal()
+	savepoint := portfolio.Savepoint()
+	if err := portfolio.RemovePosition("eth"); err != nil {
+		t.Fatal(err)
+	}
+	if portfolio.Tags("eth") != nil {
+		t.Error("a removed position should have no tags")
+	}
+	portfolio.RollbackTo(savepoint)
+	portfolio.EndJournal()
+	if portfolio.Tags("eth")[TagIdea] != "carry" {
+		t.Errorf("rollback lost tags: %v", portfolio.Tags("eth"))
+	}
+	portfolio.Clear()
+	portfolio.Restore(checkpoint)
+	if portfolio.Tags("btc")[TagStrategy] != "trend" || portfolio.Clone().Tags("eth")[TagBook] != "delta-one" {
+		t.Error("checkpoint or clone lost tags")
+	}
+
+	action := NewAddPositionAction(NewHolding("ada", "ADA/USD", d(1)), Tags{TagBook: "alts"})
+	if err := action.Apply(portfolio); err != nil || portfolio.Tags("ada")[TagBook] != "alts" {
+		t.Errorf("AddPositionAction tags = %v (%v)", portfolio.Tags("ada"), err)
+	}
+}
+
+// dependentPosition declares the market data it needs
+type dependentPosition struct {
+	mockPosition
+	requires []DataKey
+}
+
+func (d *dependentPosition) Requirements() []DataKey { return d.requires }
+
+// TestDataRequirements tests declared requirements and MissingData
+func TestDataRequirements(t *testing.T) {
+	snapshot := NewSimpleSnapshot(primitives.Now(), map[string]primitives.Price{
+		"ETH/USD": primitives.MustPrice(primitives.NewDecimal(2000)),
+	})
+	snapshot.Set("vol", "0.6")
+
+	requirements := []DataKey{
+		PriceKey("usd-eth"), MetadataKey("vol"), PriceKey("BTC/USD"),
+		MetadataKey("funding"), PriceKey("BTC/USD"), {Kind: "depth", Key: "ETH/USD"},
+	}
+	missing := MissingData(snapshot, requirements)
+	want := []DataKey{PriceKey("BTC/USD"), MetadataKey("funding"), {Kind: "depth", Key: "ETH/USD"}}
+	if len(missing) != len(want) {
+		t.Fatalf("missing = %v, want %v", missing, want)
+	}
+	for i := range want {
+		if missing[i] != want[i] {
+			t.Errorf("missing[%d] = %s, want %s", i, missing[i], want[i])
+		}
+	}
+	if got := PriceKey("BTC/USD").String(); got != "price BTC/USD" {
+		t.Errorf("String() = %q", got)
+	}
+
+	portfolio := NewPortfolio(primitives.ZeroAmount())
+	if portfolio.DataRequirements() != nil {
+		t.Error("empty portfolio has requirements")
+	}
+	positions := []Position{
+		&dependentPosition{mockPosition: mockPosition{id: "b"}, requires: []DataKey{PriceKey("ETH/USD"), PriceKey("ETH/USD")}},
+		&dependentPosition{mockPosition: mockPosition{id: "a"}, requires: []DataKey{PriceKey("ETH/USD"), MetadataKey("vol")}},
+		&mockPosition{id: "c"},
+	}
+	for _, position := range positions {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatalf("AddPosition: %v", err)
+		}
+	}
+	needs := portfolio.DataRequirements()
+	if len(needs) != 2 || fmt.Sprint(needs[PriceKey("ETH/USD")]) != "[a b]" || fmt.Sprint(needs[MetadataKey("vol")]) != "[a]" {
+		t.Errorf("DataRequirements() = %v", needs)
+	}
+	keys := []DataKey{PriceKey("ETH/USD"), MetadataKey("vol"), MetadataKey("a")}
+	SortDataKeys(keys)
+	if fmt.Sprint(keys) != "[metadata a metadata vol price ETH/USD]" {
+		t.Errorf("sorted keys = %v", keys)
+	}
+}
+
+func TestPortfolioPnL(t *testing.T) {
+	price := func(v int64) primitives.Price { return primitives.MustPrice(primitives.NewDecimal(v)) }
+	at := func(eth int64) MarketSnapshot {
+		return NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{"ETH/USD": price(eth)})
+	}
+	fill := func(side mechanisms.OrderSide, units, px int64) Action {
+		return NewFillAction(Fill{Pair: "ETH/USD", Side: side, Quantity: primitives.NewDecimal(units), Price: price(px)}, "eth")
+	}
+	check := func(t *testing.T, p *Portfolio, snapshot MarketSnapshot, realized, unrealized int64) {
+		t.Helper()
+		pnl, err := p.PnL(snapshot)
+		if err != nil {
+			t.Fatalf("PnL: %v", err)
+		}
+		if !pnl.Realized.Equal(primitives.NewDecimal(realized)) || !pnl.Unrealized.Equal(primitives.NewDecimal(unrealized)) {
+			t.Errorf("PnL = %s, want realized %d, unrealized %d", pnl, realized, unrealized)
+		}
+		value, err := p.Value(snapshot)
+		if err != nil {
+			t.Fatalf("Value: %v", err)
+		}
+		if want := value.Decimal().Sub(p.Capital()); !pnl.Total().Equal(want) {
+			t.Errorf("total P&L = %s, want value less capital %s", pnl.Total(), want)
+		}
+	}
+
+	p := NewPortfolio(primitives.MustAmount(primitives.NewDecimal(10000)))
+	apply := func(t *testing.T, action Action) {
+		t.Helper()
+		if err := action.Apply(p); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+
+	// Buys at 1000 and 1600 average 1200; selling half realizes 400/unit
+	apply(t, fill(mechanisms.OrderSideBuy, 2, 1000))
+	apply(t, fill(mechanisms.OrderSideBuy, 1, 1600))
+	check(t, p, at(1500), 0, 900)
+	if got := p.CostBasis("eth"); !got.Equal(primitives.NewDecimal(3600)) {
+		t.Errorf("basis = %s, want 3600", got)
+	}
+	apply(t, fill(mechanisms.OrderSideSell, 2, 1600))
+	check(t, p, at(1500), 800, 300)
+
+	// Fees are realized; closing realizes the rest
+	apply(t, NewAdjustCashAction(primitives.NewDecimal(-25), "fee"))
+	check(t, p, at(1500), 775, 300)
+
+	p.BeginJournal()
+	sp := p.Savepoint()
+	closed, err := NewClosePositionAction("eth").Resolve(at(1500))
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	apply(t, closed)
+	check(t, p, at(1500), 1075, 0)
+	p.RollbackTo(sp)
+	p.EndJournal()
+	check(t, p, at(1500), 775, 300)
+
+	// Open positions pay their value into the basis, and a replacement
+	// carries it over
+	opened, err := NewOpenPositionAction(NewHolding("btc", "BTC/USD", primitives.One())).Resolve(
+		NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{"ETH/USD": price(1500), "BTC/USD": price(3000)}))
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	apply(t, opened)
+	apply(t, NewReplacePositionAction("btc", NewHolding("btc2", "BTC/USD", primitives.One())))
+	if got := p.CostBasis("btc2"); !got.Equal(primitives.NewDecimal(3000)) {
+		t.Errorf("replaced basis = %s, want 3000", got)
+	}
+	both := NewSimpleSnapshot(primitives.Unix(0, 0), map[string]primitives.Price{"ETH/USD": price(1500), "BTC/USD": price(3500)})
+	check(t, p, both, 775, 800)
+
+	// Positions added without cash flow have no basis
+	apply(t, NewAddPositionAction(NewHolding("gift", "ETH/USD", primitives.One())))
+	check(t, p, both, 775, 2300)
+
+	if err := p.SetCostBasis("missing", primitives.One()); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("SetCostBasis error = %v, want %v", err, ErrPositionNotFound)
+	}
+}
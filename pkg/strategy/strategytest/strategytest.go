@@ -0,0 +1,408 @@
+// Package strategytest provides helpers for unit-testing Strategy
+// implementations: fluent snapshot and portfolio builders, a fake position
+// with scripted values, assertions on the actions Rebalance returns, and a
+// harness that runs a strategy over a scripted scenario:
+//
+//	func TestRebalance(t *testing.T) {
+//	    snapshots := strategytest.PriceSeries(start, time.Hour, "ETH/USD", "2000", "1900", "2100")
+//	    run := strategytest.Run(t, strat, strategytest.Scenario{Cash: "10000", Snapshots: snapshots})
+//	    strategytest.AssertAddsPositionOfType(t, run.Steps[0].Actions, strategy.PositionTypeSpot)
+//	}
+//
+// Numbers are given as decimal strings so expectations are exact; a
+// malformed number is a bug in the test and panics. Assertions report
+// failures through the testing.TB they are given and return what they
+// matched for further checks.
+package strategytest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// SnapshotBuilder builds a strategy.SimpleSnapshot fluently.
+type SnapshotBuilder struct {
+	at       time.Time
+	prices   map[string]primitives.Price
+	metadata map[string]interface{}
+}
+
+// Snapshot starts a snapshot at time at.
+func Snapshot(at time.Time) *SnapshotBuilder {
+	return &SnapshotBuilder{
+		at:       at,
+		prices:   make(map[string]primitives.Price),
+		metadata: make(map[string]interface{}),
+	}
+}
+
+// Price sets pair's price.
+func (b *SnapshotBuilder) Price(pair, price string) *SnapshotBuilder {
+	b.prices[pair] = primitives.MustPrice(primitives.MustDecimalFromString(price))
+	return b
+}
+
+// Meta sets a metadata value, such as one keyed by a snapshotkeys builder.
+func (b *SnapshotBuilder) Meta(key string, value interface{}) *SnapshotBuilder {
+	b.metadata[key] = value
+	return b
+}
+
+// Build returns the snapshot. The builder can be changed and built again
+// without affecting snapshots already built.
+func (b *SnapshotBuilder) Build() *strategy.SimpleSnapshot {
+	prices := make(map[string]primitives.Price, len(b.prices))
+	for pair, price := range b.prices {
+		prices[pair] = price
+	}
+	snapshot := strategy.NewSimpleSnapshot(primitives.NewTime(b.at), prices)
+	for key, value := range b.metadata {
+		snapshot.Set(key, value)
+	}
+	return snapshot
+}
+
+// PriceSeries returns one snapshot per price of pair, the first at start
+// and each later one interval after the previous.
+func PriceSeries(start time.Time, interval time.Duration, pair string, prices ...string) []strategy.MarketSnapshot {
+	snapshots := make([]strategy.MarketSnapshot, len(prices))
+	for i, price := range prices {
+		snapshots[i] = Snapshot(start.Add(time.Duration(i)*interval)).Price(pair, price).Build()
+	}
+	return snapshots
+}
+
+// PortfolioBuilder builds a strategy.Portfolio with scripted state.
+type PortfolioBuilder struct {
+	cash      primitives.Decimal
+	positions []strategy.Position
+}
+
+// Portfolio starts a portfolio holding cash.
+func Portfolio(cash string) *PortfolioBuilder {
+	return &PortfolioBuilder{cash: primitives.MustDecimalFromString(cash)}
+}
+
+// With adds positions to the portfolio. Their cost is not debited from
+// cash.
+func (b *PortfolioBuilder) With(positions ...strategy.Position) *PortfolioBuilder {
+	b.positions = append(b.positions, positions...)
+	return b
+}
+
+// Build returns the portfolio, failing t if a position cannot be added.
+// Negative cash is kept, as a portfolio borrowing cash would hold.
+func (b *PortfolioBuilder) Build(t testing.TB) *strategy.Portfolio {
+	t.Helper()
+	portfolio := strategy.NewPortfolio(primitives.ZeroAmount())
+	if err := portfolio.AdjustCash(b.cash); err != nil {
+		t.Fatalf("strategytest: failed to set cash %s: %v", b.cash, err)
+	}
+	for _, position := range b.positions {
+		if err := portfolio.AddPosition(position); err != nil {
+			t.Fatalf("strategytest: failed to add position %s: %v", position.ID(), err)
+		}
+	}
+	return portfolio
+}
+
+// Position is a fake strategy.Position whose value is scripted by time:
+// it is valued at the latest value set at or before the snapshot time, or
+// its initial value before the first.
+//
+// Thread Safety: Position is not thread-safe while being scripted.
+type Position struct {
+	id      string
+	posType strategy.PositionType
+	initial primitives.Amount
+	script  []scriptedValue
+	err     error
+}
+
+// scriptedValue is a Position's value from a time on.
+type scriptedValue struct {
+	from  time.Time
+	value primitives.Amount
+}
+
+// NewPosition creates a fake position of posType valued at value.
+func NewPosition(id string, posType strategy.PositionType, value string) *Position {
+	return &Position{id: id, posType: posType, initial: primitives.MustAmount(primitives.MustDecimalFromString(value))}
+}
+
+// ValueFrom scripts the position's value from time from on.
+func (p *Position) ValueFrom(from time.Time, value string) *Position {
+	p.script = append(p.script, scriptedValue{from: from, value: primitives.MustAmount(primitives.MustDecimalFromString(value))})
+	sort.SliceStable(p.script, func(i, j int) bool { return p.script[i].from.Before(p.script[j].from) })
+	return p
+}
+
+// Failing makes every valuation of the position return err.
+func (p *Position) Failing(err error) *Position {
+	p.err = err
+	return p
+}
+
+// ID returns the position ID.
+func (p *Position) ID() string {
+	return p.id
+}
+
+// Type returns the position type.
+func (p *Position) Type() strategy.PositionType {
+	return p.posType
+}
+
+// Value returns the scripted value at snapshot's time.
+func (p *Position) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	if p.err != nil {
+		return primitives.ZeroAmount(), p.err
+	}
+	value := p.initial
+	for _, scripted := range p.script {
+		if scripted.from.After(snapshot.Time().Time()) {
+			break
+		}
+		value = scripted.value
+	}
+	return value, nil
+}
+
+// Flatten returns actions with batches expanded into their actions and
+// venue routing removed, in execution order.
+func Flatten(actions []strategy.Action) []strategy.Action {
+	var flat []strategy.Action
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *strategy.BatchAction:
+			flat = append(flat, Flatten(a.Actions)...)
+		case *strategy.VenueAction:
+			flat = append(flat, Flatten([]strategy.Action{a.Action})...)
+		default:
+			flat = append(flat, action)
+		}
+	}
+	return flat
+}
+
+// AssertNoActions fails t if actions is not empty.
+func AssertNoActions(t testing.TB, actions []strategy.Action) {
+	t.Helper()
+	if len(actions) > 0 {
+		t.Errorf("expected no actions, got %s", describe(actions))
+	}
+}
+
+// AssertAddsPositionOfType fails t unless an action adds a position of
+// posType (through AddPositionAction, OpenPositionAction or the new
+// position of a ReplacePositionAction), and returns the first one.
+func AssertAddsPositionOfType(t testing.TB, actions []strategy.Action, posType strategy.PositionType) strategy.Position {
+	t.Helper()
+	for _, action := range Flatten(actions) {
+		var added strategy.Position
+		switch a := action.(type) {
+		case *strategy.AddPositionAction:
+			added = a.Position
+		case *strategy.OpenPositionAction:
+			added = a.Position
+		case *strategy.ReplacePositionAction:
+			added = a.NewPosition
+		}
+		if added != nil && added.Type() == posType {
+			return added
+		}
+	}
+	t.Errorf("expected an action adding a %s position, got %s", posType, describe(actions))
+	return nil
+}
+
+// AssertRemovesPosition fails t unless an action removes the position
+// with ID id (through RemovePositionAction, ClosePositionAction,
+// ReplacePositionAction or resizing it to zero).
+func AssertRemovesPosition(t testing.TB, actions []strategy.Action, id string) {
+	t.Helper()
+	for _, action := range Flatten(actions) {
+		switch a := action.(type) {
+		case *strategy.RemovePositionAction:
+			if a.PositionID == id {
+				return
+			}
+		case *strategy.ClosePositionAction:
+			if a.PositionID == id {
+				return
+			}
+		case *strategy.ReplacePositionAction:
+			if a.OldPositionID == id {
+				return
+			}
+		case *strategy.ResizePositionAction:
+			if a.PositionID == id && a.Size.IsZero() {
+				return
+			}
+		}
+	}
+	t.Errorf("expected an action removing position %s, got %s", id, describe(actions))
+}
+
+// AssertSubmitsOrder fails t unless an action submits an order on pair
+// and side, and returns the first one.
+func AssertSubmitsOrder(t testing.TB, actions []strategy.Action, pair string, side mechanisms.OrderSide) strategy.Order {
+	t.Helper()
+	for _, action := range Flatten(actions) {
+		if submit, ok := action.(*strategy.SubmitOrderAction); ok && submit.Order.Pair == pair && submit.Order.Side == side {
+			return submit.Order
+		}
+	}
+	t.Errorf("expected an action submitting a %s order on %s, got %s", side, pair, describe(actions))
+	return strategy.Order{}
+}
+
+// AssertCashDelta applies actions at snapshot to a clone of portfolio and
+// fails t unless its cash changed by want (negative for spending). The
+// portfolio itself is unchanged.
+func AssertCashDelta(t testing.TB, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, actions []strategy.Action, want string) {
+	t.Helper()
+	clone := portfolio.Clone()
+	if err := apply(clone, snapshot, actions); err != nil {
+		t.Errorf("failed to apply actions %s: %v", describe(actions), err)
+		return
+	}
+	delta := clone.CashDecimal().Sub(portfolio.CashDecimal())
+	if expected := primitives.MustDecimalFromString(want); !delta.Equal(expected) {
+		t.Errorf("cash changed by %s, want %s (actions %s)", delta, expected, describe(actions))
+	}
+}
+
+// Scenario is a scripted run for Run.
+type Scenario struct {
+	// Cash is the starting cash when Portfolio is nil (default "0")
+	Cash string
+
+	// Portfolio is the starting portfolio; Run works on a clone of it
+	Portfolio *strategy.Portfolio
+
+	Snapshots []strategy.MarketSnapshot
+
+	// Seed seeds strategy.Rand, as backtest.Config.Seed does
+	Seed int64
+}
+
+// Step is one snapshot of a Run.
+type Step struct {
+	Snapshot strategy.MarketSnapshot
+
+	// Actions are those Rebalance returned
+	Actions []strategy.Action
+
+	// Cash and Value are the portfolio's after the actions were applied
+	Cash  primitives.Decimal
+	Value primitives.Amount
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	Portfolio *strategy.Portfolio
+	Steps     []Step
+}
+
+// Actions returns every action the strategy returned, in order.
+func (r *Result) Actions() []strategy.Action {
+	var actions []strategy.Action
+	for _, step := range r.Steps {
+		actions = append(actions, step.Actions...)
+	}
+	return actions
+}
+
+// Run calls strat's Rebalance at each of the scenario's snapshots and
+// applies the actions it returns, failing t on the first error. As in the
+// backtest engine, the context carries strategy.Clock at the snapshot time
+// and strategy.Rand seeded with Seed, and deferred actions are resolved at
+// the snapshot that produced them.
+//
+// Run is a unit-test harness, not a backtest: actions apply at the
+// snapshot they were returned at, with no fees, latency or constraints,
+// and order actions fail because no order book is managed. Test strategies
+// that trade through orders with the backtest engine.
+func Run(t testing.TB, strat strategy.Strategy, scenario Scenario) *Result {
+	t.Helper()
+	portfolio := scenario.Portfolio
+	if portfolio == nil {
+		cash := scenario.Cash
+		if cash == "" {
+			cash = "0"
+		}
+		portfolio = Portfolio(cash).Build(t)
+	} else {
+		portfolio = portfolio.Clone()
+	}
+
+	clock := primitives.NewManualClock(primitives.Time{})
+	ctx := strategy.WithClock(context.Background(), clock)
+	ctx = strategy.WithRand(ctx, strategy.NewRand(scenario.Seed))
+
+	result := &Result{Portfolio: portfolio}
+	for i, snapshot := range scenario.Snapshots {
+		clock.Set(snapshot.Time())
+		actions, err := strat.Rebalance(ctx, portfolio, snapshot)
+		if err != nil {
+			t.Fatalf("strategytest: rebalance failed at snapshot %d (%s): %v", i, snapshot.Time(), err)
+		}
+		if err := apply(portfolio, snapshot, actions); err != nil {
+			t.Fatalf("strategytest: failed to apply %s at snapshot %d (%s): %v", describe(actions), i, snapshot.Time(), err)
+		}
+		value, err := portfolio.Value(snapshot)
+		if err != nil {
+			t.Fatalf("strategytest: failed to value portfolio at snapshot %d (%s): %v", i, snapshot.Time(), err)
+		}
+		result.Steps = append(result.Steps, Step{
+			Snapshot: snapshot,
+			Actions:  actions,
+			Cash:     portfolio.CashDecimal(),
+			Value:    value,
+		})
+	}
+	return result
+}
+
+// apply resolves deferred actions at snapshot and applies actions in order.
+func apply(portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot, actions []strategy.Action) error {
+	for _, action := range actions {
+		if deferred, ok := action.(strategy.DeferredAction); ok {
+			resolved, err := deferred.Resolve(snapshot)
+			if err != nil {
+				return err
+			}
+			action = resolved
+		}
+		if batch, ok := action.(*strategy.BatchAction); ok {
+			if err := apply(portfolio, snapshot, batch.Actions); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := action.Apply(portfolio); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describe lists actions for failure messages.
+func describe(actions []strategy.Action) string {
+	if len(actions) == 0 {
+		return "[]"
+	}
+	descriptions := make([]string, len(actions))
+	for i, action := range actions {
+		descriptions[i] = action.String()
+	}
+	return fmt.Sprintf("%v", descriptions)
+}
@@ -0,0 +1,116 @@
+package strategytest_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy/strategytest"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRunScenario(t *testing.T) {
+	dca, err := strategy.NewDCA("ETH/USD", primitives.MustAmount(primitives.NewDecimal(1000)), primitives.Hours(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots := strategytest.PriceSeries(start, 12*time.Hour, "ETH/USD", "2000", "1000", "2500")
+	run := strategytest.Run(t, dca, strategytest.Scenario{Cash: "5000", Snapshots: snapshots})
+
+	if len(run.Steps) != 3 {
+		t.Fatalf("got %d steps, want 3", len(run.Steps))
+	}
+	holding := strategytest.AssertAddsPositionOfType(t, run.Steps[0].Actions, strategy.PositionTypeSpot)
+	if holding == nil || holding.ID() != dca.PositionID() {
+		t.Errorf("added %v, want %s", holding, dca.PositionID())
+	}
+	strategytest.AssertNoActions(t, run.Steps[1].Actions)
+	if !run.Steps[2].Cash.Equal(primitives.NewDecimal(3000)) {
+		t.Errorf("cash after two installments = %s, want 3000", run.Steps[2].Cash)
+	}
+	// 0.5 ETH at 2000 plus 0.4 at 2500, all at 2500, plus 3000 cash
+	if !run.Steps[2].Value.Decimal().Equal(primitives.NewDecimal(5250)) {
+		t.Errorf("final value = %s, want 5250", run.Steps[2].Value)
+	}
+	if len(run.Actions()) != 2 {
+		t.Errorf("got %d actions, want 2", len(run.Actions()))
+	}
+
+	// The scenario's portfolio is not changed by the run
+	portfolio := strategytest.Portfolio("100").Build(t)
+	strategytest.Run(t, dca, strategytest.Scenario{Portfolio: portfolio, Snapshots: snapshots[2:]})
+	if !portfolio.CashDecimal().Equal(primitives.NewDecimal(100)) || portfolio.PositionCount() != 0 {
+		t.Error("Run modified the scenario portfolio")
+	}
+}
+
+func TestBuildersAndAssertions(t *testing.T) {
+	snapshot := strategytest.Snapshot(start).Price("ETH/USD", "2000").Meta("custom:key", 7).Build()
+	if price, err := snapshot.Price("ETH/USD"); err != nil || !price.Decimal().Equal(primitives.NewDecimal(2000)) {
+		t.Errorf("price = %s (%v), want 2000", price, err)
+	}
+	if value, err := strategy.GetInt(snapshot, "custom:key"); err != nil || value != 7 {
+		t.Errorf("metadata = %d (%v), want 7", value, err)
+	}
+
+	lp := strategytest.NewPosition("lp", strategy.PositionTypeLiquidityPool, "1000").
+		ValueFrom(start.Add(time.Hour), "900")
+	portfolio := strategytest.Portfolio("500").With(lp).Build(t)
+	later := strategytest.Snapshot(start.Add(2 * time.Hour)).Build()
+	for _, tt := range []struct {
+		snapshot strategy.MarketSnapshot
+		want     int64
+	}{{snapshot, 1500}, {later, 1400}} {
+		if value, err := portfolio.Value(tt.snapshot); err != nil || !value.Decimal().Equal(primitives.NewDecimal(tt.want)) {
+			t.Errorf("value at %s = %s (%v), want %d", tt.snapshot.Time(), value, err, tt.want)
+		}
+	}
+	boom := errors.New("boom")
+	if _, err := strategytest.NewPosition("bad", strategy.PositionTypeSpot, "1").Failing(boom).Value(snapshot); !errors.Is(err, boom) {
+		t.Errorf("failing position returned %v", err)
+	}
+
+	holding := strategy.NewHolding("eth", "ETH/USD", primitives.MustDecimalFromString("0.1"))
+	order := strategy.NewMarketOrder("o1", "ETH/USD", mechanisms.OrderSideSell, primitives.MustAmount(primitives.One()))
+	actions := []strategy.Action{
+		strategy.NewBatchAction(strategy.NewOpenPositionAction(holding), strategy.NewRemovePositionAction("lp")),
+		strategy.NewVenueAction("dex", strategy.NewSubmitOrderAction(order)),
+	}
+	if got := len(strategytest.Flatten(actions)); got != 3 {
+		t.Errorf("flattened to %d actions, want 3", got)
+	}
+	strategytest.AssertAddsPositionOfType(t, actions, strategy.PositionTypeSpot)
+	strategytest.AssertRemovesPosition(t, actions, "lp")
+	if got := strategytest.AssertSubmitsOrder(t, actions, "ETH/USD", mechanisms.OrderSideSell); got.ID != "o1" {
+		t.Errorf("matched order %s, want o1", got.ID)
+	}
+	strategytest.AssertCashDelta(t, portfolio, snapshot, actions[:1], "-200")
+
+	// Mismatches are reported as failures
+	r := &recorder{TB: t}
+	strategytest.AssertNoActions(r, actions)
+	strategytest.AssertAddsPositionOfType(r, actions, strategy.PositionTypeOption)
+	strategytest.AssertRemovesPosition(r, actions, "eth")
+	strategytest.AssertSubmitsOrder(r, actions, "ETH/USD", mechanisms.OrderSideBuy)
+	strategytest.AssertCashDelta(r, portfolio, snapshot, actions[:1], "-100")
+	if len(r.failures) != 5 {
+		t.Errorf("recorded %d failures, want 5: %v", len(r.failures), r.failures)
+	}
+}
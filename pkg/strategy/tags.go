@@ -0,0 +1,126 @@
+package strategy
+
+import "sort"
+
+// Tags label a position for reporting, such as the strategy that opened
+// it, the book it belongs to and the trade idea behind it. Keys and values
+// are free-form; TagStrategy, TagBook and TagIdea are conventional keys.
+// Tags are set when a position is added (see Portfolio.AddPosition) and
+// carried over when it is replaced.
+type Tags map[string]string
+
+// Conventional tag keys.
+const (
+	TagStrategy = "strategy"
+	TagBook     = "book"
+	TagIdea     = "idea"
+)
+
+// Untagged is the group of positions without the tag being grouped by.
+const Untagged = ""
+
+// Keys returns the tag keys in sorted order.
+func (t Tags) Keys() []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeTags returns the union of tags, later values winning, or nil if
+// there are none.
+func mergeTags(tags ...Tags) Tags {
+	var merged Tags
+	for _, t := range tags {
+		for key, value := range t {
+			if merged == nil {
+				merged = make(Tags, len(t))
+			}
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// copyTags copies the tags of every position.
+func copyTags(tags map[string]Tags) map[string]Tags {
+	if tags == nil {
+		return nil
+	}
+	copied := make(map[string]Tags, len(tags))
+	for id, t := range tags {
+		copied[id] = mergeTags(t)
+	}
+	return copied
+}
+
+// Tags returns a copy of the tags of a position, or nil if it has none or
+// is not held.
+func (p *Portfolio) Tags(positionID string) Tags {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return mergeTags(p.tags[positionID])
+}
+
+// TagKeys returns every tag key used by a held position, in sorted order.
+func (p *Portfolio) TagKeys() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	keys := make(Tags)
+	for _, tags := range p.tags {
+		for key := range tags {
+			keys[key] = ""
+		}
+	}
+	return keys.Keys()
+}
+
+// PositionsTagged returns the positions tagged key=value, sorted by ID.
+func (p *Portfolio) PositionsTagged(key, value string) []Position {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var positions []Position
+	for _, id := range p.ids {
+		if tag, ok := p.tags[id][key]; ok && tag == value {
+			positions = append(positions, p.positions[id])
+		}
+	}
+	return positions
+}
+
+// GroupByTag groups the positions by their value of the tag key, with
+// positions lacking it under Untagged. Each group is sorted by ID.
+func (p *Portfolio) GroupByTag(key string) map[string][]Position {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	groups := make(map[string][]Position)
+	for _, id := range p.ids {
+		value := Untagged
+		if tag, ok := p.tags[id][key]; ok {
+			value = tag
+		}
+		groups[value] = append(groups[value], p.positions[id])
+	}
+	return groups
+}
+
+// GreeksByTag aggregates the Greeks of each group of GroupByTag(key), as
+// Greeks does for the whole book.
+func (p *Portfolio) GreeksByTag(snapshot MarketSnapshot, key string) (map[string]BookGreeks, error) {
+	groups := p.GroupByTag(key)
+	greeks := make(map[string]BookGreeks, len(groups))
+	for value, positions := range groups {
+		g, err := greeksOf(positions, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		greeks[value] = g
+	}
+	return greeks, nil
+}
@@ -0,0 +1,223 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+// Constraint is a portfolio-level rule that must hold after a batch of
+// actions applies, checked by Portfolio.Validate.
+type Constraint interface {
+	// Check returns an error describing the violation, or nil.
+	Check(portfolio *Portfolio, snapshot MarketSnapshot) error
+}
+
+// ConstraintFunc adapts a function to the Constraint interface.
+type ConstraintFunc func(portfolio *Portfolio, snapshot MarketSnapshot) error
+
+// Check calls f.
+func (f ConstraintFunc) Check(portfolio *Portfolio, snapshot MarketSnapshot) error {
+	return f(portfolio, snapshot)
+}
+
+// MinCash requires at least amount of cash, e.g., a reserve for fees.
+func MinCash(amount primitives.Decimal) Constraint {
+	return ConstraintFunc(func(portfolio *Portfolio, snapshot MarketSnapshot) error {
+		if portfolio.CashDecimal().LessThan(amount) {
+			return fmt.Errorf("cash %s below minimum %s", portfolio.CashDecimal(), amount)
+		}
+		return nil
+	})
+}
+
+// MaxPositions limits the number of open positions.
+func MaxPositions(n int) Constraint {
+	return ConstraintFunc(func(portfolio *Portfolio, snapshot MarketSnapshot) error {
+		if count := portfolio.PositionCount(); count > n {
+			return fmt.Errorf("%d positions exceed maximum %d", count, n)
+		}
+		return nil
+	})
+}
+
+// Diagnostic describes one problem found by Portfolio.Validate.
+type Diagnostic struct {
+	// Index is the action's position in the validated batch, or -1 for a
+	// constraint checked after the batch
+	Index int
+
+	// Action is the failing action, or nil for a constraint
+	Action Action
+
+	// PositionID is the position the action adds, removes or changes, when
+	// known
+	PositionID string
+
+	// Err describes the problem and wraps a sentinel such as
+	// ErrInsufficientCash, ErrPositionExists, ErrPositionNotFound or
+	// ErrConstraintViolated
+	Err error
+}
+
+// Error returns a description of the diagnostic.
+func (d Diagnostic) Error() string {
+	if d.Index < 0 {
+		return d.Err.Error()
+	}
+	return fmt.Sprintf("action %d (%s): %v", d.Index, d.Action, d.Err)
+}
+
+// Unwrap returns Err.
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// ValidationReport is the outcome of Portfolio.Validate.
+type ValidationReport struct {
+	// Diagnostics lists every problem found, in batch order
+	Diagnostics []Diagnostic
+
+	// Cash is the cash balance the batch would leave, ignoring failed
+	// actions
+	Cash primitives.Decimal
+
+	// Positions is the number of positions the batch would leave,
+	// ignoring failed actions
+	Positions int
+}
+
+// OK reports whether the batch is feasible.
+func (r ValidationReport) OK() bool {
+	return len(r.Diagnostics) == 0
+}
+
+// Err joins the diagnostics into one error, or returns nil if the batch is
+// feasible.
+func (r ValidationReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	errs := make([]error, len(r.Diagnostics))
+	for i, d := range r.Diagnostics {
+		errs[i] = d
+	}
+	return errors.Join(errs...)
+}
+
+// String returns a multi-line summary of the report.
+func (r ValidationReport) String() string {
+	if r.OK() {
+		return fmt.Sprintf("valid: cash %s, %d positions", r.Cash, r.Positions)
+	}
+	lines := make([]string, 0, len(r.Diagnostics)+1)
+	lines = append(lines, fmt.Sprintf("%d problems:", len(r.Diagnostics)))
+	for _, d := range r.Diagnostics {
+		lines = append(lines, "  "+d.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate dry-runs a batch of actions against a copy of the portfolio
+// and reports whether it is feasible, without changing the portfolio.
+//
+// Actions apply in order, DeferredActions resolved against snapshot, and a
+// failing action is reported and skipped so that one pass finds every
+// problem. Besides errors from the actions themselves, Validate reports:
+//   - ErrPositionExists when an action adds an ID already held, including
+//     one added earlier in the batch
+//   - ErrInsufficientCash when an action leaves cash negative; strategies
+//     that borrow against the portfolio can filter these out
+//   - ErrConstraintViolated for each constraint that fails after the batch
+//
+// Order submissions are checked with Order.Validate; cancellations and
+// other engine-handled actions are assumed to succeed.
+func (p *Portfolio) Validate(actions []Action, snapshot MarketSnapshot, constraints ...Constraint) ValidationReport {
+	dry := p.Clone()
+	dry.BeginJournal()
+	var report ValidationReport
+
+	for i, action := range actions {
+		fail := func(id string, err error) {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{Index: i, Action: action, PositionID: id, Err: err})
+		}
+		if action == nil {
+			fail("", fmt.Errorf("%w: nil action", ErrInvalidAction))
+			continue
+		}
+
+		resolved := action
+		if routed, ok := resolved.(*VenueAction); ok && routed.Action != nil {
+			resolved = routed.Action
+		}
+		switch a := resolved.(type) {
+		case *SubmitOrderAction:
+			if err := a.Order.Validate(); err != nil {
+				fail(a.Order.Holding(), err)
+			}
+			continue
+		case *CancelOrderAction, *UpdateStateAction:
+			continue
+		}
+		if deferred, ok := resolved.(DeferredAction); ok && snapshot != nil {
+			var err error
+			if resolved, err = deferred.Resolve(snapshot); err != nil {
+				fail(affectedPosition(action), err)
+				continue
+			}
+		}
+
+		cash := dry.CashDecimal()
+		savepoint := dry.Savepoint()
+		if err := resolved.Apply(dry); err != nil {
+			dry.RollbackTo(savepoint)
+			fail(affectedPosition(resolved), err)
+			continue
+		}
+		if after := dry.CashDecimal(); after.IsNegative() && after.LessThan(cash) {
+			fail(affectedPosition(resolved), fmt.Errorf("%w: cash would fall to %s", ErrInsufficientCash, after))
+		}
+	}
+
+	for _, constraint := range constraints {
+		if err := constraint.Check(dry, snapshot); err != nil {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{Index: -1, Err: fmt.Errorf("%w: %v", ErrConstraintViolated, err)})
+		}
+	}
+
+	report.Cash = dry.CashDecimal()
+	report.Positions = dry.PositionCount()
+	return report
+}
+
+// affectedPosition returns the ID of the position an action changes, when
+// its type makes that known.
+func affectedPosition(action Action) string {
+	switch a := action.(type) {
+	case *AddPositionAction:
+		if a.Position != nil {
+			return a.Position.ID()
+		}
+	case *OpenPositionAction:
+		if a.Position != nil {
+			return a.Position.ID()
+		}
+	case *RemovePositionAction:
+		return a.PositionID
+	case *ClosePositionAction:
+		return a.PositionID
+	case *ReplacePositionAction:
+		return a.OldPositionID
+	case *ResizePositionAction:
+		return a.PositionID
+	case *SettlePositionAction:
+		return a.PositionID
+	case *FillAction:
+		return a.PositionID
+	case *VenueAction:
+		return affectedPosition(a.Action)
+	}
+	return ""
+}
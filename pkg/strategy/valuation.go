@@ -0,0 +1,212 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+)
+
+// ContextValuedPosition is an optional Position extension for positions
+// that value themselves with a ValuationContext: the caller's context for
+// mechanism calls, prices resolved through the cross-rate graph and rate
+// curves, rather than context.Background() and hard-coded price keys.
+// Portfolio valuation calls ValueIn in place of Value when a position
+// implements it; Value remains for callers holding only a snapshot and
+// can delegate with NewValuationContext(context.Background(), snapshot).
+type ContextValuedPosition interface {
+	Position
+
+	// ValueIn returns the position's value in the market vc describes.
+	ValueIn(vc *ValuationContext) (primitives.Amount, error)
+}
+
+// RateCurve is a zero-rate term structure, such as rates.Curve, as read
+// by ValuationContext.RateCurve.
+type RateCurve interface {
+	// ZeroRate returns the continuously compounded zero rate for a
+	// maturity years from now.
+	ZeroRate(years primitives.Decimal) (primitives.Decimal, error)
+
+	// DiscountFactor returns the value today of one unit paid years from
+	// now.
+	DiscountFactor(years primitives.Decimal) (primitives.Decimal, error)
+}
+
+// ValuationContext is the market a position is valued in: a
+// MarketSnapshot, which it embeds so it can be passed wherever one is
+// expected, together with the context of the valuation and the services
+// positions price themselves with. The optional snapshot extensions
+// (DepthSnapshot, BarSnapshot, QuoteSnapshot, PriceTimestamper and
+// MetadataLister) pass through to the snapshot.
+type ValuationContext struct {
+	MarketSnapshot
+
+	ctx      context.Context
+	resolver *CrossRateResolver
+}
+
+// ValuationOption configures a ValuationContext.
+type ValuationOption func(*ValuationContext)
+
+// WithCrossRates resolves prices the snapshot does not quote through
+// resolver's price graph, e.g. ETH/EUR from ETH/USD and EUR/USD.
+func WithCrossRates(resolver *CrossRateResolver) ValuationOption {
+	return func(vc *ValuationContext) {
+		vc.resolver = resolver
+	}
+}
+
+// NewValuationContext returns the valuation context of snapshot under ctx.
+// If snapshot is already a ValuationContext, it is rebound to ctx and
+// keeps its services unless options replace them.
+func NewValuationContext(ctx context.Context, snapshot MarketSnapshot, options ...ValuationOption) *ValuationContext {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	vc := &ValuationContext{MarketSnapshot: snapshot, ctx: ctx}
+	if existing, ok := snapshot.(*ValuationContext); ok {
+		vc.MarketSnapshot, vc.resolver = existing.MarketSnapshot, existing.resolver
+	}
+	for _, option := range options {
+		option(vc)
+	}
+	return vc
+}
+
+// Context returns the context of the valuation, for mechanism calls such
+// as a pool's RemoveLiquidity.
+func (vc *ValuationContext) Context() context.Context {
+	return vc.ctx
+}
+
+// Snapshot returns the underlying market snapshot.
+func (vc *ValuationContext) Snapshot() MarketSnapshot {
+	return vc.MarketSnapshot
+}
+
+// Price returns the quoted price of pair, or its cross rate when the
+// context resolves cross rates.
+func (vc *ValuationContext) Price(pair string) (primitives.Price, error) {
+	price, err := vc.MarketSnapshot.Price(pair)
+	if err == nil || vc.resolver == nil {
+		return price, err
+	}
+	parsed, parseErr := primitives.ParsePair(pair)
+	if parseErr != nil {
+		return price, err
+	}
+	price, _, err = vc.resolver.Resolve(vc.MarketSnapshot, parsed)
+	return price, err
+}
+
+// PriceOf returns the price of pair in any key format, its inverse or,
+// when the context resolves cross rates, a derived cross rate. It makes
+// PairPrice resolve through the context.
+func (vc *ValuationContext) PriceOf(pair primitives.Pair) (primitives.Price, error) {
+	price, err := PairPrice(vc.MarketSnapshot, pair)
+	if err == nil || vc.resolver == nil {
+		return price, err
+	}
+	price, _, err = vc.resolver.Resolve(vc.MarketSnapshot, pair)
+	return price, err
+}
+
+// Decimal returns the decimal metadata at key (see GetDecimal).
+func (vc *ValuationContext) Decimal(key string) (primitives.Decimal, error) {
+	return GetDecimal(vc.MarketSnapshot, key)
+}
+
+// RateCurve returns the zero-rate curve of currency published at
+// snapshotkeys.RateCurve: a RateCurve such as rates.Curve, or a decimal
+// read as a flat curve. Returns ErrMetadataNotFound if none is published
+// and ErrMetadataType for any other value.
+func (vc *ValuationContext) RateCurve(currency string) (RateCurve, error) {
+	key := snapshotkeys.RateCurve(currency)
+	raw, ok := vc.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMetadataNotFound, key)
+	}
+	if curve, ok := raw.(RateCurve); ok {
+		return curve, nil
+	}
+	rate, err := GetDecimal(vc.MarketSnapshot, key)
+	if err != nil {
+		return nil, typeError(key, "RateCurve", raw)
+	}
+	return flatCurve{rate: rate}, nil
+}
+
+// MetadataKeys returns the snapshot's metadata keys, if it lists them.
+func (vc *ValuationContext) MetadataKeys() []string {
+	if lister, ok := vc.MarketSnapshot.(MetadataLister); ok {
+		return lister.MetadataKeys()
+	}
+	return nil
+}
+
+// PriceTime returns the snapshot's observation time of pair.
+func (vc *ValuationContext) PriceTime(pair string) (primitives.Time, bool) {
+	if timestamps, ok := vc.MarketSnapshot.(PriceTimestamper); ok {
+		return timestamps.PriceTime(pair)
+	}
+	return primitives.Time{}, false
+}
+
+// Depth returns the snapshot's depth for pair.
+func (vc *ValuationContext) Depth(pair string) (mechanisms.OrderBookDepth, error) {
+	if depth, ok := vc.MarketSnapshot.(DepthSnapshot); ok {
+		return depth.Depth(pair)
+	}
+	return mechanisms.OrderBookDepth{}, fmt.Errorf("%w: %s", ErrDepthNotAvailable, pair)
+}
+
+// Bar returns the snapshot's bar for pair.
+func (vc *ValuationContext) Bar(pair string) (Bar, error) {
+	if bars, ok := vc.MarketSnapshot.(BarSnapshot); ok {
+		return bars.Bar(pair)
+	}
+	return Bar{}, fmt.Errorf("%w: %s", ErrBarNotAvailable, pair)
+}
+
+// Quote returns the snapshot's quote for pair.
+func (vc *ValuationContext) Quote(pair string) (Quote, error) {
+	if quotes, ok := vc.MarketSnapshot.(QuoteSnapshot); ok {
+		return quotes.Quote(pair)
+	}
+	return Quote{}, fmt.Errorf("%w: %s", ErrQuoteNotAvailable, pair)
+}
+
+// ValueIn values position in vc: through ValueIn for a
+// ContextValuedPosition and Value otherwise.
+func ValueIn(position Position, vc *ValuationContext) (primitives.Amount, error) {
+	if valued, ok := position.(ContextValuedPosition); ok {
+		return valued.ValueIn(vc)
+	}
+	return position.Value(vc)
+}
+
+// valuationContext returns snapshot as a ValuationContext, wrapping it
+// under context.Background() unless it is one already.
+func valuationContext(snapshot MarketSnapshot) *ValuationContext {
+	if vc, ok := snapshot.(*ValuationContext); ok {
+		return vc
+	}
+	return NewValuationContext(context.Background(), snapshot)
+}
+
+// flatCurve is a RateCurve with one rate at every tenor.
+type flatCurve struct {
+	rate primitives.Decimal
+}
+
+func (c flatCurve) ZeroRate(years primitives.Decimal) (primitives.Decimal, error) {
+	return c.rate, nil
+}
+
+func (c flatCurve) DiscountFactor(years primitives.Decimal) (primitives.Decimal, error) {
+	return primitives.NewDecimalFromFloat(math.Exp(-c.rate.Float64() * years.Float64())), nil
+}
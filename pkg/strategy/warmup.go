@@ -0,0 +1,21 @@
+package strategy
+
+import "context"
+
+type warmUpKey struct{}
+
+// WithWarmUp returns a copy of ctx marking the snapshot being rebalanced
+// as part of a warm-up window, as the backtest engine does under
+// backtest.Config.WarmUp.
+func WithWarmUp(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warmUpKey{}, true)
+}
+
+// WarmingUp reports whether the snapshot being rebalanced is in a warm-up
+// window: the strategy should update its indicators, and any actions it
+// returns are discarded. Strategies can use it to skip building orders
+// while their signals are not yet valid.
+func WarmingUp(ctx context.Context) bool {
+	warming, _ := ctx.Value(warmUpKey{}).(bool)
+	return warming
+}
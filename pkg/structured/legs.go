@@ -0,0 +1,175 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/implementations/blackscholes"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// Leg is one component of a structured product. Legs are immutable.
+type Leg interface {
+	// Name describes the leg (e.g., "-1 ETH/USD call 2200 expiring 2024-01-08")
+	Name() string
+
+	// Value returns the leg's value at snapshot, negative for liabilities
+	// such as short options
+	Value(snapshot strategy.MarketSnapshot) (primitives.Decimal, error)
+
+	// Delta returns the leg's exposure to its underlying, in units
+	Delta(snapshot strategy.MarketSnapshot) (primitives.Decimal, error)
+
+	// Expiry returns when the leg settles; ok is false for legs that
+	// never expire
+	Expiry() (expiry primitives.Time, ok bool)
+}
+
+// SpotLeg holds Quantity units of the asset priced at Pair.
+type SpotLeg struct {
+	Pair     string
+	Quantity primitives.Decimal
+}
+
+// Name implements Leg.
+func (l SpotLeg) Name() string {
+	return fmt.Sprintf("%s %s spot", l.Quantity, l.Pair)
+}
+
+// Value implements Leg.
+func (l SpotLeg) Value(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	price, err := snapshot.Price(l.Pair)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return l.Quantity.Mul(price.Decimal()), nil
+}
+
+// Delta implements Leg.
+func (l SpotLeg) Delta(strategy.MarketSnapshot) (primitives.Decimal, error) {
+	return l.Quantity, nil
+}
+
+// Expiry implements Leg; spot never expires.
+func (l SpotLeg) Expiry() (primitives.Time, bool) {
+	return primitives.Time{}, false
+}
+
+// BondLeg is a zero-coupon bond paying Face at Maturity, discounted at
+// the continuously compounded annual Yield.
+type BondLeg struct {
+	Face     primitives.Decimal
+	Yield    primitives.Decimal
+	Maturity primitives.Time
+}
+
+// Name implements Leg.
+func (l BondLeg) Name() string {
+	return fmt.Sprintf("zero-coupon %s at %s", l.Face, l.Maturity.Format("2006-01-02"))
+}
+
+// Value implements Leg: Face × e^(−Yield·τ), or Face at and after maturity.
+func (l BondLeg) Value(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	years := yearsUntil(l.Maturity, snapshot.Time())
+	discount := math.Exp(-l.Yield.Float64() * years.Float64())
+	return l.Face.Mul(primitives.NewDecimalFromFloat(discount)), nil
+}
+
+// Delta implements Leg; bonds have no price exposure.
+func (l BondLeg) Delta(strategy.MarketSnapshot) (primitives.Decimal, error) {
+	return primitives.Zero(), nil
+}
+
+// Expiry implements Leg.
+func (l BondLeg) Expiry() (primitives.Time, bool) {
+	return l.Maturity, true
+}
+
+// OptionLeg holds Quantity European options on the asset priced at Pair,
+// negative for a short. It is priced with Black-Scholes using the implied
+// volatility in snapshot metadata at snapshotkeys.OptionImpliedVol(Pair)
+// when present, and Volatility otherwise.
+type OptionLeg struct {
+	Pair         string
+	Type         mechanisms.OptionType
+	Strike       primitives.Price
+	Expiration   primitives.Time
+	Quantity     primitives.Decimal
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+}
+
+// Name implements Leg.
+func (l OptionLeg) Name() string {
+	return fmt.Sprintf("%s %s %s %s expiring %s", l.Quantity, l.Pair, l.Type, l.Strike, l.Expiration.Format("2006-01-02"))
+}
+
+// Value implements Leg. At and after expiry it is the intrinsic value.
+func (l OptionLeg) Value(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	price, _, err := l.price(snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return l.Quantity.Mul(price.Decimal()), nil
+}
+
+// Delta implements Leg.
+func (l OptionLeg) Delta(snapshot strategy.MarketSnapshot) (primitives.Decimal, error) {
+	_, greeks, err := l.price(snapshot)
+	if err != nil {
+		return primitives.Zero(), err
+	}
+	return l.Quantity.Mul(greeks.Delta), nil
+}
+
+// Expiry implements Leg.
+func (l OptionLeg) Expiry() (primitives.Time, bool) {
+	return l.Expiration, true
+}
+
+// price returns the per-unit price and Greeks at snapshot.
+func (l OptionLeg) price(snapshot strategy.MarketSnapshot) (primitives.Price, mechanisms.Greeks, error) {
+	underlying, err := snapshot.Price(l.Pair)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	vol := l.Volatility
+	if implied, err := strategy.GetDecimal(snapshot, snapshotkeys.OptionImpliedVol(l.Pair)); err == nil {
+		vol = implied
+	}
+	years := yearsUntil(l.Expiration, snapshot.Time())
+
+	// Entry price does not affect pricing; the strike stands in
+	option, err := blackscholes.NewOption(l.Name(), l.Type, l.Strike, years, l.Strike, l.Quantity)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	params := mechanisms.PriceParams{
+		UnderlyingPrice: underlying,
+		TimeToExpiry:    years,
+		Volatility:      vol,
+		RiskFreeRate:    l.RiskFreeRate,
+	}
+	ctx := context.Background()
+	price, err := option.Price(ctx, params)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	greeks, err := option.Greeks(ctx, params)
+	if err != nil {
+		return primitives.ZeroPrice(), mechanisms.Greeks{}, err
+	}
+	return price, greeks, nil
+}
+
+// yearsUntil returns the years from now to t, or zero once t has passed.
+func yearsUntil(t, now primitives.Time) primitives.Decimal {
+	if !t.After(now) {
+		return primitives.Zero()
+	}
+	return primitives.NewDecimalFromFloat(t.Sub(now).Hours() / primitives.Year.Hours())
+}
@@ -0,0 +1,146 @@
+package structured
+
+import (
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/mechanisms"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+// CoveredCallConfig configures a covered-call vault.
+type CoveredCallConfig struct {
+	ID string
+
+	// Pair is the snapshot price key of the underlying (e.g., "ETH/USD")
+	Pair string
+
+	// Principal is spent on the underlying at the opening price
+	Principal primitives.Decimal
+
+	// Moneyness sets each call's strike at spot × (1 + Moneyness) on the
+	// roll date (e.g., 0.1 for 10% out of the money)
+	Moneyness primitives.Decimal
+
+	// Tenor is both the calls' time to expiry and the roll interval
+	// (e.g., 7 days)
+	Tenor primitives.Duration
+
+	// Volatility and RiskFreeRate price the calls when the snapshot has
+	// no implied volatility
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+}
+
+// CoveredCallVault opens a vault that buys the underlying with its
+// principal and sells one call per unit every Tenor, collecting premium
+// into cash. Calls settle at intrinsic value on expiry, so upside above
+// the strike is paid away.
+func CoveredCallVault(config CoveredCallConfig, snapshot strategy.MarketSnapshot) (*Product, error) {
+	if config.Tenor.Duration() <= 0 || config.Moneyness.IsNegative() {
+		return nil, fmt.Errorf("%w: covered call needs a positive tenor and non-negative moneyness", ErrInvalidProduct)
+	}
+	spot, err := snapshot.Price(config.Pair)
+	if err != nil {
+		return nil, err
+	}
+	units, err := config.Principal.Div(spot.Decimal())
+	if err != nil {
+		return nil, err
+	}
+
+	roller := RollerFunc(func(_ *Product, snapshot strategy.MarketSnapshot) ([]Leg, error) {
+		spot, err := snapshot.Price(config.Pair)
+		if err != nil {
+			return nil, err
+		}
+		strike, err := primitives.NewPrice(spot.Decimal().Mul(primitives.One().Add(config.Moneyness)))
+		if err != nil {
+			return nil, err
+		}
+		return []Leg{OptionLeg{
+			Pair:         config.Pair,
+			Type:         mechanisms.OptionTypeCall,
+			Strike:       strike,
+			Expiration:   snapshot.Time().Add(config.Tenor),
+			Quantity:     units.Neg(),
+			Volatility:   config.Volatility,
+			RiskFreeRate: config.RiskFreeRate,
+		}}, nil
+	})
+
+	return Open(config.ID, config.Principal,
+		[]Leg{SpotLeg{Pair: config.Pair, Quantity: units}},
+		Lifecycle{Roller: roller, RollEvery: config.Tenor},
+		snapshot)
+}
+
+// PrincipalProtectedConfig configures a principal-protected note.
+type PrincipalProtectedConfig struct {
+	ID string
+
+	// Pair is the snapshot price key of the underlying
+	Pair string
+
+	// Principal is returned in full at Maturity
+	Principal primitives.Decimal
+
+	// Maturity is when the note unwinds
+	Maturity primitives.Time
+
+	// Yield discounts the zero-coupon bond that protects principal
+	Yield primitives.Decimal
+
+	// Moneyness sets the call strike at spot × (1 + Moneyness)
+	Moneyness primitives.Decimal
+
+	// Volatility and RiskFreeRate price the calls when the snapshot has
+	// no implied volatility
+	Volatility   primitives.Decimal
+	RiskFreeRate primitives.Decimal
+}
+
+// PrincipalProtectedNote opens a note that buys a zero-coupon bond
+// repaying Principal at Maturity and spends the remainder on calls
+// expiring at Maturity. At maturity the holder receives the principal plus
+// the calls' payoff.
+func PrincipalProtectedNote(config PrincipalProtectedConfig, snapshot strategy.MarketSnapshot) (*Product, error) {
+	if !config.Maturity.After(snapshot.Time()) {
+		return nil, fmt.Errorf("%w: maturity must be after the opening snapshot", ErrInvalidProduct)
+	}
+	spot, err := snapshot.Price(config.Pair)
+	if err != nil {
+		return nil, err
+	}
+	bond := BondLeg{Face: config.Principal, Yield: config.Yield, Maturity: config.Maturity}
+	cost, err := bond.Value(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	strike, err := primitives.NewPrice(spot.Decimal().Mul(primitives.One().Add(config.Moneyness)))
+	if err != nil {
+		return nil, err
+	}
+	call := OptionLeg{
+		Pair:         config.Pair,
+		Type:         mechanisms.OptionTypeCall,
+		Strike:       strike,
+		Expiration:   config.Maturity,
+		Quantity:     primitives.One(),
+		Volatility:   config.Volatility,
+		RiskFreeRate: config.RiskFreeRate,
+	}
+	premium, err := call.Value(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := config.Principal.Sub(cost).Div(premium)
+	if err != nil || !quantity.IsPositive() {
+		return nil, fmt.Errorf("%w: no budget for calls after the bond (premium %s)", ErrInvalidProduct, premium)
+	}
+	// Round down so the legs never cost more than the principal
+	call.Quantity = quantity.Round(8, primitives.RoundDown)
+
+	return Open(config.ID, config.Principal, []Leg{bond, call}, Lifecycle{Maturity: config.Maturity}, snapshot)
+}
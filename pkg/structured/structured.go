@@ -0,0 +1,307 @@
+// Package structured packages several mechanisms into a single
+// strategy.Position representing a structured product, such as a
+// covered-call vault (spot plus a short call rolled weekly) or a
+// principal-protected note (a zero-coupon bond plus calls).
+//
+// A Product holds cash and a set of Legs. Its Lifecycle settles expired
+// legs into cash, rolls into new legs on a schedule, and unwinds the
+// product at maturity; Step applies it and returns the next Product, which
+// is immutable like every other position. Manager runs a product as a
+// strategy: it subscribes, steps the product every snapshot and redeems it
+// at maturity.
+package structured
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+)
+
+var (
+	// ErrInsufficientPrincipal is returned when a product's legs cost more
+	// than the principal it is opened with
+	ErrInsufficientPrincipal = errors.New("legs cost more than principal")
+
+	// ErrInvalidProduct is returned when a product's configuration is
+	// missing fields or out of range
+	ErrInvalidProduct = errors.New("invalid structured product")
+)
+
+// Roller opens the legs a product rolls into. It is called at each roll
+// date after expired legs have settled, and the new legs are bought or
+// sold at their snapshot value from the product's cash.
+type Roller interface {
+	Roll(product *Product, snapshot strategy.MarketSnapshot) ([]Leg, error)
+}
+
+// RollerFunc adapts a function to Roller.
+type RollerFunc func(product *Product, snapshot strategy.MarketSnapshot) ([]Leg, error)
+
+// Roll implements Roller.
+func (f RollerFunc) Roll(product *Product, snapshot strategy.MarketSnapshot) ([]Leg, error) {
+	return f(product, snapshot)
+}
+
+// Lifecycle configures how a product evolves after it opens.
+type Lifecycle struct {
+	// Roller opens new legs every RollEvery; nil means the product never rolls
+	Roller    Roller
+	RollEvery primitives.Duration
+
+	// Maturity is when every leg is unwound to cash; zero means the
+	// product is open-ended
+	Maturity primitives.Time
+}
+
+// Event records a lifecycle step.
+type Event struct {
+	Time primitives.Time
+
+	// Kind is "settle", "roll" or "mature"
+	Kind string
+
+	// Leg is the leg settled or opened; empty for "mature"
+	Leg string
+
+	// Cash is the cash flow into the product, negative when it pays
+	Cash primitives.Decimal
+}
+
+// Product is a structured product: cash plus legs, with a lifecycle.
+//
+// Thread Safety: Product is immutable and safe for concurrent reads.
+type Product struct {
+	id        string
+	cash      primitives.Decimal
+	legs      []Leg
+	lifecycle Lifecycle
+	nextRoll  primitives.Time
+	matured   bool
+}
+
+// Open creates a product at snapshot funded with principal, buying (or,
+// for negative-valued legs, selling) each leg at its snapshot value.
+// Returns ErrInsufficientPrincipal if the legs cost more than principal.
+func Open(id string, principal primitives.Decimal, legs []Leg, lifecycle Lifecycle, snapshot strategy.MarketSnapshot) (*Product, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: ID cannot be empty", ErrInvalidProduct)
+	}
+	if !principal.IsPositive() {
+		return nil, fmt.Errorf("%w: principal must be positive", ErrInvalidProduct)
+	}
+	if lifecycle.Roller != nil && lifecycle.RollEvery.Duration() <= 0 {
+		return nil, fmt.Errorf("%w: roll interval must be positive", ErrInvalidProduct)
+	}
+
+	product := &Product{id: id, cash: principal, lifecycle: lifecycle}
+	if err := product.open(legs, snapshot, nil); err != nil {
+		return nil, err
+	}
+	if product.cash.IsNegative() {
+		return nil, fmt.Errorf("%w: %s short of %s", ErrInsufficientPrincipal, product.cash.Neg(), principal)
+	}
+	if lifecycle.Roller != nil {
+		product.nextRoll = snapshot.Time()
+	}
+	return product, nil
+}
+
+// ID returns the product ID.
+func (p *Product) ID() string {
+	return p.id
+}
+
+// Type returns PositionTypeOption, the closest built-in classification of
+// a product whose payoff is shaped by options.
+func (p *Product) Type() strategy.PositionType {
+	return strategy.PositionTypeOption
+}
+
+// Cash returns the product's cash: unspent principal, option premium
+// collected and settlement proceeds.
+func (p *Product) Cash() primitives.Decimal {
+	return p.cash
+}
+
+// Legs returns the open legs.
+func (p *Product) Legs() []Leg {
+	legs := make([]Leg, len(p.legs))
+	copy(legs, p.legs)
+	return legs
+}
+
+// Matured reports whether the product has unwound at maturity.
+func (p *Product) Matured() bool {
+	return p.matured
+}
+
+// Value returns cash plus the value of every leg: the product's net asset
+// value. It is zero if liabilities exceed assets.
+func (p *Product) Value(snapshot strategy.MarketSnapshot) (primitives.Amount, error) {
+	nav := p.cash
+	for _, leg := range p.legs {
+		value, err := leg.Value(snapshot)
+		if err != nil {
+			return primitives.ZeroAmount(), fmt.Errorf("failed to value %s: %w", leg.Name(), err)
+		}
+		nav = nav.Add(value)
+	}
+	if nav.IsNegative() {
+		return primitives.ZeroAmount(), nil
+	}
+	return primitives.NewAmount(nav)
+}
+
+// Risk implements strategy.PositionWithRisk with the summed leg deltas.
+func (p *Product) Risk(snapshot strategy.MarketSnapshot) (strategy.RiskMetrics, error) {
+	delta := primitives.Zero()
+	for _, leg := range p.legs {
+		d, err := leg.Delta(snapshot)
+		if err != nil {
+			return strategy.RiskMetrics{}, err
+		}
+		delta = delta.Add(d)
+	}
+	return strategy.RiskMetrics{Delta: delta, Leverage: primitives.One()}, nil
+}
+
+// Step advances the product to snapshot: legs that have expired settle
+// into cash at their final value, the product rolls if a roll is due, and
+// at maturity every leg is unwound. It returns the next product and the
+// events that occurred.
+func (p *Product) Step(snapshot strategy.MarketSnapshot) (*Product, []Event, error) {
+	if p.matured {
+		return p, nil, nil
+	}
+	now := snapshot.Time()
+	next := *p
+	next.legs = nil
+	var events []Event
+
+	matures := !p.lifecycle.Maturity.Time().IsZero() && !now.Before(p.lifecycle.Maturity)
+	for _, leg := range p.legs {
+		expiry, expires := leg.Expiry()
+		if !matures && (!expires || now.Before(expiry)) {
+			next.legs = append(next.legs, leg)
+			continue
+		}
+		value, err := leg.Value(snapshot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to settle %s: %w", leg.Name(), err)
+		}
+		next.cash = next.cash.Add(value)
+		events = append(events, Event{Time: now, Kind: "settle", Leg: leg.Name(), Cash: value})
+	}
+	if matures {
+		next.matured = true
+		events = append(events, Event{Time: now, Kind: "mature", Cash: primitives.Zero()})
+		return &next, events, nil
+	}
+
+	if p.lifecycle.Roller != nil && !now.Before(p.nextRoll) {
+		legs, err := p.lifecycle.Roller.Roll(&next, snapshot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to roll %s: %w", p.id, err)
+		}
+		if err := next.open(legs, snapshot, &events); err != nil {
+			return nil, nil, err
+		}
+		for !now.Before(next.nextRoll) {
+			next.nextRoll = next.nextRoll.Add(p.lifecycle.RollEvery)
+		}
+	}
+	return &next, events, nil
+}
+
+// open adds legs bought at their snapshot value, recording roll events
+// when events is non-nil.
+func (p *Product) open(legs []Leg, snapshot strategy.MarketSnapshot, events *[]Event) error {
+	for _, leg := range legs {
+		value, err := leg.Value(snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to price %s: %w", leg.Name(), err)
+		}
+		p.cash = p.cash.Sub(value)
+		p.legs = append(p.legs, leg)
+		if events != nil {
+			*events = append(*events, Event{Time: snapshot.Time(), Kind: "roll", Leg: leg.Name(), Cash: value.Neg()})
+		}
+	}
+	return nil
+}
+
+// Manager runs a structured product as a strategy. The first Rebalance
+// opens the product with Open and subscribes its principal; later calls
+// step it, and once it matures Manager redeems it to portfolio cash.
+//
+// Thread Safety: Manager is not thread-safe, matching the backtest
+// engine's single-goroutine execution model.
+type Manager struct {
+	id     string
+	open   func(snapshot strategy.MarketSnapshot) (*Product, error)
+	events []Event
+}
+
+// NewManager creates a manager for the product open builds. id must match
+// the ID of the products open returns.
+func NewManager(id string, open func(snapshot strategy.MarketSnapshot) (*Product, error)) *Manager {
+	return &Manager{id: id, open: open}
+}
+
+// Events returns every lifecycle event so far, in order.
+func (m *Manager) Events() []Event {
+	events := make([]Event, len(m.events))
+	copy(events, m.events)
+	return events
+}
+
+// Rebalance implements strategy.Strategy.
+func (m *Manager) Rebalance(ctx context.Context, portfolio *strategy.Portfolio, snapshot strategy.MarketSnapshot) ([]strategy.Action, error) {
+	position, err := portfolio.GetPosition(m.id)
+	if err != nil {
+		product, err := m.open(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if product.ID() != m.id {
+			return nil, fmt.Errorf("%w: opened %s, manager expects %s", ErrInvalidProduct, product.ID(), m.id)
+		}
+		principal, err := product.Value(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		// Step immediately so the first roll happens at subscription
+		stepped, events, err := product.Step(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		m.events = append(m.events, events...)
+		return []strategy.Action{
+			strategy.NewAdjustCashAction(principal.Decimal().Neg(), "subscribe "+m.id),
+			strategy.NewAddPositionAction(stepped),
+		}, nil
+	}
+
+	product, ok := position.(*Product)
+	if !ok {
+		return nil, fmt.Errorf("%w: position %s is %T, not a product", ErrInvalidProduct, m.id, position)
+	}
+	if product.Matured() {
+		return nil, nil
+	}
+	stepped, events, err := product.Step(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	m.events = append(m.events, events...)
+	if stepped.Matured() {
+		return []strategy.Action{
+			strategy.NewRemovePositionAction(m.id),
+			strategy.NewAdjustCashAction(stepped.Cash(), "redeem "+m.id),
+		}, nil
+	}
+	return []strategy.Action{strategy.NewReplacePositionAction(m.id, stepped)}, nil
+}
@@ -0,0 +1,190 @@
+package structured_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/backtest"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/snapshotkeys"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/strategy"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/structured"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func dec(s string) primitives.Decimal {
+	return primitives.MustDecimalFromString(s)
+}
+
+func at(days int) primitives.Time {
+	return primitives.NewTime(start.Add(time.Duration(days) * 24 * time.Hour))
+}
+
+func snapshot(days int, eth string) *strategy.SimpleSnapshot {
+	return strategy.NewSimpleSnapshot(at(days), map[string]primitives.Price{"ETH/USD": primitives.MustPrice(dec(eth))})
+}
+
+// path returns daily snapshots at the given prices.
+func path(prices ...string) []strategy.MarketSnapshot {
+	snapshots := make([]strategy.MarketSnapshot, len(prices))
+	for day, eth := range prices {
+		snapshots[day] = snapshot(day, eth)
+	}
+	return snapshots
+}
+
+func vaultConfig() structured.CoveredCallConfig {
+	return structured.CoveredCallConfig{
+		ID:         "ccv:eth",
+		Pair:       "ETH/USD",
+		Principal:  dec("10000"),
+		Moneyness:  dec("0.1"),
+		Tenor:      primitives.NewDuration(7 * 24 * time.Hour),
+		Volatility: dec("0.8"),
+	}
+}
+
+func TestCoveredCallVault(t *testing.T) {
+	t.Run("flat market collects premium", func(t *testing.T) {
+		product, err := structured.CoveredCallVault(vaultConfig(), snapshot(0, "2000"))
+		if err != nil {
+			t.Fatalf("CoveredCallVault: %v", err)
+		}
+		var rolls, settles int
+		for day := 0; day <= 14; day++ {
+			var events []structured.Event
+			product, events, err = product.Step(snapshot(day, "2000"))
+			if err != nil {
+				t.Fatalf("Step day %d: %v", day, err)
+			}
+			for _, e := range events {
+				switch e.Kind {
+				case "roll":
+					rolls++
+					if !e.Cash.IsPositive() {
+						t.Errorf("selling a call should collect premium, got %s", e.Cash)
+					}
+				case "settle":
+					settles++
+					if !e.Cash.IsZero() {
+						t.Errorf("out-of-the-money call settled for %s", e.Cash)
+					}
+				}
+			}
+		}
+		// Rolls on days 0, 7 and 14; the first two calls expire worthless
+		if rolls != 3 || settles != 2 {
+			t.Errorf("got %d rolls and %d settles, want 3 and 2", rolls, settles)
+		}
+		value, err := product.Value(snapshot(14, "2000"))
+		if err != nil || !value.Decimal().GreaterThan(dec("10000")) {
+			t.Errorf("value = %s (%v), want premium above principal", value, err)
+		}
+		risk, err := product.Risk(snapshot(14, "2000"))
+		if err != nil || !risk.Delta.LessThan(dec("5")) || !risk.Delta.IsPositive() {
+			t.Errorf("delta = %s (%v), want below the 5 ETH held", risk.Delta, err)
+		}
+	})
+
+	t.Run("rally is capped at the strike", func(t *testing.T) {
+		product, _ := structured.CoveredCallVault(vaultConfig(), snapshot(0, "2000"))
+		product, _, _ = product.Step(snapshot(0, "2000"))
+		premium := product.Cash()
+
+		expiry, _, err := product.Step(snapshot(7, "3000"))
+		if err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+		// 5 ETH settle the 2200 call for 5 × 800 = 4000; the next roll sells
+		// at 3300 and is valued at its own premium, so NAV is 5 × 2200 + premium
+		value, err := expiry.Value(snapshot(7, "3000"))
+		if err != nil {
+			t.Fatalf("Value: %v", err)
+		}
+		if want := dec("11000").Add(premium); !value.Decimal().Round(6, primitives.RoundHalfEven).Equal(want.Round(6, primitives.RoundHalfEven)) {
+			t.Errorf("value = %s, want %s", value, want)
+		}
+	})
+
+	t.Run("implied vol metadata overrides config", func(t *testing.T) {
+		calm, _ := structured.CoveredCallVault(vaultConfig(), snapshot(0, "2000"))
+		calm, _, _ = calm.Step(snapshot(0, "2000"))
+		stressed := snapshot(0, "2000")
+		stressed.Set(snapshotkeys.OptionImpliedVol("ETH/USD"), dec("1.5"))
+		rich, _ := structured.CoveredCallVault(vaultConfig(), stressed)
+		rich, _, _ = rich.Step(stressed)
+		if !rich.Cash().GreaterThan(calm.Cash()) {
+			t.Errorf("premium at 150 vol %s should exceed premium at 80 vol %s", rich.Cash(), calm.Cash())
+		}
+	})
+}
+
+func noteConfig() structured.PrincipalProtectedConfig {
+	return structured.PrincipalProtectedConfig{
+		ID:         "ppn:eth",
+		Pair:       "ETH/USD",
+		Principal:  dec("10000"),
+		Maturity:   at(30),
+		Yield:      dec("0.1"),
+		Volatility: dec("0.6"),
+	}
+}
+
+func TestPrincipalProtectedNote(t *testing.T) {
+	tests := []struct {
+		name  string
+		final string
+		check func(redeemed primitives.Decimal) bool
+	}{
+		{"crash returns principal", "1000", func(r primitives.Decimal) bool { return r.Equal(dec("10000")) }},
+		{"rally participates", "3000", func(r primitives.Decimal) bool { return r.GreaterThan(dec("10500")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := make([]string, 31)
+			for i := range prices {
+				prices[i] = "2000"
+			}
+			prices[30] = tt.final
+
+			manager := structured.NewManager("ppn:eth", func(s strategy.MarketSnapshot) (*structured.Product, error) {
+				return structured.PrincipalProtectedNote(noteConfig(), s)
+			})
+			result, err := backtest.NewEngine(backtest.DefaultConfig()).Run(context.Background(), manager, path(prices...))
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if result.Portfolio.HasPosition("ppn:eth") {
+				t.Fatal("note should be redeemed at maturity")
+			}
+			// The whole default $10k is subscribed, so cash is the redemption
+			redeemed := result.Portfolio.CashDecimal()
+			if !tt.check(redeemed.Round(4, primitives.RoundHalfEven)) {
+				t.Errorf("redeemed %s at %s", redeemed, tt.final)
+			}
+			events := manager.Events()
+			if last := events[len(events)-1]; last.Kind != "mature" || !last.Time.Equal(at(30)) {
+				t.Errorf("last event = %+v, want maturity on day 30", last)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	legs := []structured.Leg{structured.SpotLeg{Pair: "ETH/USD", Quantity: dec("6")}}
+	if _, err := structured.Open("p", dec("10000"), legs, structured.Lifecycle{}, snapshot(0, "2000")); !errors.Is(err, structured.ErrInsufficientPrincipal) {
+		t.Errorf("expected %v, got %v", structured.ErrInsufficientPrincipal, err)
+	}
+	roller := structured.RollerFunc(func(*structured.Product, strategy.MarketSnapshot) ([]structured.Leg, error) { return nil, nil })
+	if _, err := structured.Open("p", dec("10000"), nil, structured.Lifecycle{Roller: roller}, snapshot(0, "2000")); !errors.Is(err, structured.ErrInvalidProduct) {
+		t.Errorf("expected %v, got %v", structured.ErrInvalidProduct, err)
+	}
+	config := noteConfig()
+	config.Maturity = at(0)
+	if _, err := structured.PrincipalProtectedNote(config, snapshot(0, "2000")); !errors.Is(err, structured.ErrInvalidProduct) {
+		t.Errorf("expected %v, got %v", structured.ErrInvalidProduct, err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package telemetry defines optional tracing and metrics hooks for the
+// backtest engine. The hooks are small interfaces shaped after
+// OpenTelemetry's tracer and counter APIs, so the toolkit stays free of
+// the OpenTelemetry dependency while an adapter of a few lines connects
+// it to an existing observability stack:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, telemetry.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) End(err error) {
+//		if err != nil {
+//			s.RecordError(err)
+//			s.SetStatus(codes.Error, err.Error())
+//		}
+//		s.Span.End()
+//	}
+//
+// Recorder is an in-memory implementation for tests and quick profiling.
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Span and counter names emitted by the backtest engine.
+const (
+	// SpanRun covers a whole backtest run
+	SpanRun = "backtest.run"
+
+	// SpanRebalance covers one Strategy.Rebalance call
+	SpanRebalance = "strategy.rebalance"
+
+	// SpanApplyActions covers applying one batch of actions
+	SpanApplyActions = "backtest.apply_actions"
+
+	// SpanValuation covers valuing the portfolio at one snapshot
+	SpanValuation = "portfolio.value"
+
+	// CounterSnapshots counts snapshots processed
+	CounterSnapshots = "backtest.snapshots"
+
+	// CounterActions counts actions applied successfully
+	CounterActions = "backtest.actions"
+
+	// CounterActionErrors counts actions that failed to apply, whether
+	// skipped or aborting the run
+	CounterActionErrors = "backtest.action_errors"
+
+	// CounterFills counts order fills
+	CounterFills = "backtest.fills"
+)
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start begins a span named name as a child of any span in ctx, and
+	// returns a context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Span is an operation in progress.
+type Span interface {
+	// End completes the span, marking it failed when err is not nil.
+	End(err error)
+}
+
+// Meter records counters.
+type Meter interface {
+	// Add increments the counter named name by delta.
+	Add(ctx context.Context, name string, delta int64, attrs ...slog.Attr)
+}
+
+// Instrumentation bundles the hooks a component reports to. Either field
+// may be nil to disable that signal.
+type Instrumentation struct {
+	Tracer Tracer
+	Meter  Meter
+}
+
+// Enabled reports whether any hook is set.
+func (i Instrumentation) Enabled() bool {
+	return i.Tracer != nil || i.Meter != nil
+}
+
+// Start starts a span with the Tracer, or returns ctx and a no-op span.
+func (i Instrumentation) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	if i.Tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return i.Tracer.Start(ctx, name, attrs...)
+}
+
+// Add increments a counter with the Meter, if set.
+func (i Instrumentation) Add(ctx context.Context, name string, delta int64, attrs ...slog.Attr) {
+	if i.Meter != nil && delta != 0 {
+		i.Meter.Add(ctx, name, delta, attrs...)
+	}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(error) {}
+
+// SpanRecord is a span captured by a Recorder.
+type SpanRecord struct {
+	Name     string
+	Attrs    []slog.Attr
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+
+	// ID identifies the span in the order spans started; Parent is the ID
+	// of the enclosing span, or -1
+	ID     int
+	Parent int
+}
+
+// SpanStats summarizes the recorded spans of one name.
+type SpanStats struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Recorder is an in-memory Tracer and Meter. Spans are kept in the order
+// they end.
+//
+// Thread Safety: Recorder is safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	spans    []SpanRecord
+	counters map[string]int64
+	nextID   int
+}
+
+// NewRecorder creates an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{counters: make(map[string]int64)}
+}
+
+// Instrumentation returns hooks reporting to the recorder.
+func (r *Recorder) Instrumentation() Instrumentation {
+	return Instrumentation{Tracer: r, Meter: r}
+}
+
+type spanKey struct{}
+
+// Start implements Tracer.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	r.mu.Lock()
+	id := r.nextID
+	r.nextID++
+	r.mu.Unlock()
+
+	parent, ok := ctx.Value(spanKey{}).(int)
+	if !ok {
+		parent = -1
+	}
+	span := &recordedSpan{recorder: r, id: id, parent: parent, name: name, attrs: attrs, start: time.Now()}
+	return context.WithValue(ctx, spanKey{}, id), span
+}
+
+// Add implements Meter.
+func (r *Recorder) Add(ctx context.Context, name string, delta int64, attrs ...slog.Attr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name] += delta
+}
+
+// Spans returns the ended spans.
+func (r *Recorder) Spans() []SpanRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]SpanRecord(nil), r.spans...)
+}
+
+// Counter returns the total of the counter named name.
+func (r *Recorder) Counter(name string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.counters[name]
+}
+
+// Stats returns span statistics by name.
+func (r *Recorder) Stats() map[string]SpanStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]SpanStats)
+	for _, span := range r.spans {
+		s := stats[span.Name]
+		s.Count++
+		if span.Err != nil {
+			s.Errors++
+		}
+		s.Total += span.Duration
+		if span.Duration > s.Max {
+			s.Max = span.Duration
+		}
+		stats[span.Name] = s
+	}
+	return stats
+}
+
+// Names returns the recorded span names in ascending order.
+func (r *Recorder) Names() []string {
+	stats := r.Stats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type recordedSpan struct {
+	recorder *Recorder
+	id       int
+	parent   int
+	name     string
+	attrs    []slog.Attr
+	start    time.Time
+	ended    bool
+}
+
+// End records the span; later calls do nothing.
+func (s *recordedSpan) End(err error) {
+	r := s.recorder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	s.ended = true
+	r.spans = append(r.spans, SpanRecord{
+		Name:     s.name,
+		Attrs:    s.attrs,
+		Start:    s.start,
+		Duration: time.Since(s.start),
+		Err:      err,
+		ID:       s.id,
+		Parent:   s.parent,
+	})
+}
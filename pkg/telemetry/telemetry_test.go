@@ -0,0 +1,78 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/telemetry"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := telemetry.NewRecorder()
+	hooks := recorder.Instrumentation()
+	failure := errors.New("boom")
+
+	ctx, parent := hooks.Start(context.Background(), "parent", slog.Int("snapshot", 3))
+	_, child := hooks.Start(ctx, "child")
+	child.End(failure)
+	child.End(nil) // ignored
+	parent.End(nil)
+
+	hooks.Add(ctx, "actions", 2)
+	hooks.Add(ctx, "actions", 1)
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans recorded, want 2", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Name != "child" || parentSpan.Name != "parent" {
+		t.Fatalf("spans recorded out of end order: %v", spans)
+	}
+	if childSpan.Parent != parentSpan.ID || parentSpan.Parent != -1 {
+		t.Errorf("child parent = %d, parent parent = %d; want %d and -1", childSpan.Parent, parentSpan.Parent, parentSpan.ID)
+	}
+	if !errors.Is(childSpan.Err, failure) || parentSpan.Err != nil {
+		t.Errorf("unexpected span errors %v, %v", childSpan.Err, parentSpan.Err)
+	}
+	if len(parentSpan.Attrs) != 1 || parentSpan.Attrs[0].Key != "snapshot" {
+		t.Errorf("unexpected attributes %v", parentSpan.Attrs)
+	}
+
+	if got := recorder.Counter("actions"); got != 3 {
+		t.Errorf("actions counter = %d, want 3", got)
+	}
+	stats := recorder.Stats()
+	if stats["child"].Count != 1 || stats["child"].Errors != 1 || stats["parent"].Errors != 0 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+	if names := recorder.Names(); len(names) != 2 || names[0] != "child" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+func TestInstrumentationZeroValue(t *testing.T) {
+	var hooks telemetry.Instrumentation
+	if hooks.Enabled() {
+		t.Error("zero Instrumentation reports enabled")
+	}
+
+	ctx := context.Background()
+	got, span := hooks.Start(ctx, "span")
+	if got != ctx {
+		t.Error("disabled Start changed the context")
+	}
+	span.End(errors.New("ignored"))
+	hooks.Add(ctx, "counter", 1)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, span := hooks.Start(ctx, "span")
+		hooks.Add(ctx, "counter", 1)
+		span.End(nil)
+	})
+	if allocs != 0 {
+		t.Errorf("disabled hooks allocate %.0f times per call", allocs)
+	}
+}
@@ -0,0 +1,239 @@
+// Package tokens provides a registry of ERC-20 tokens by chain: each
+// symbol's contract address and decimals.
+//
+// Pool constructors and data loaders look tokens up here instead of
+// repeating raw addresses and decimals at every call site, so amounts are
+// converted between raw integer units and human units consistently. The
+// default registry ships with major tokens on Ethereum mainnet, Arbitrum
+// One and Base; Register adds others.
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+)
+
+var (
+	// ErrUnknownToken is returned when a symbol or address is not registered
+	// on a chain
+	ErrUnknownToken = errors.New("unknown token")
+
+	// ErrTokenExists is returned when a symbol or address is already
+	// registered on a chain
+	ErrTokenExists = errors.New("token already registered")
+
+	// ErrInvalidToken is returned for tokens without a symbol, chain or
+	// address, or with out-of-range decimals
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// ChainID is an EVM chain ID.
+type ChainID uint
+
+const (
+	Mainnet  ChainID = 1
+	Arbitrum ChainID = 42161
+	Base     ChainID = 8453
+)
+
+// String returns the chain's name, or its numeric ID if unnamed.
+func (c ChainID) String() string {
+	switch c {
+	case Mainnet:
+		return "mainnet"
+	case Arbitrum:
+		return "arbitrum"
+	case Base:
+		return "base"
+	}
+	return fmt.Sprintf("chain-%d", uint(c))
+}
+
+// Token is an ERC-20 token deployed on a chain.
+type Token struct {
+	ChainID  ChainID
+	Symbol   string
+	Address  common.Address
+	Decimals int32
+}
+
+// Spec returns the token's denomination for raw unit conversion.
+func (t Token) Spec() primitives.CurrencySpec {
+	return primitives.CurrencySpec{Symbol: t.Symbol, Decimals: t.Decimals}
+}
+
+// FromRaw converts raw integer units to a human-unit amount.
+func (t Token) FromRaw(raw *big.Int) (primitives.Amount, error) {
+	return t.Spec().FromRaw(raw)
+}
+
+// ToRaw converts a human-unit amount to raw integer units.
+// Returns primitives.ErrPrecisionLoss if the amount is finer than one unit.
+func (t Token) ToRaw(amount primitives.Amount) (*big.Int, error) {
+	return t.Spec().ToRaw(amount)
+}
+
+// String returns the symbol and chain, e.g. "USDC@arbitrum".
+func (t Token) String() string {
+	return t.Symbol + "@" + t.ChainID.String()
+}
+
+type symbolKey struct {
+	chain  ChainID
+	symbol string
+}
+
+type addressKey struct {
+	chain   ChainID
+	address common.Address
+}
+
+// Registry maps symbols and addresses on each chain to tokens. Symbols are
+// matched case-insensitively.
+//
+// Thread Safety: Registry is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	bySymbol  map[symbolKey]Token
+	byAddress map[addressKey]Token
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		bySymbol:  make(map[symbolKey]Token),
+		byAddress: make(map[addressKey]Token),
+	}
+}
+
+// NewDefaultRegistry creates a registry holding the built-in tokens, for
+// callers that want to extend it without touching the default registry.
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, token := range builtin {
+		if err := r.Register(token); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a token.
+// Returns ErrInvalidToken if the token is incomplete, or ErrTokenExists if
+// its symbol or address is already registered on its chain.
+func (r *Registry) Register(token Token) error {
+	if token.Symbol == "" || token.ChainID == 0 || token.Address == (common.Address{}) {
+		return fmt.Errorf("%w: %s needs a symbol, chain and address", ErrInvalidToken, token)
+	}
+	if _, err := primitives.NewCurrencySpec(token.Symbol, token.Decimals); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sk := symbolKey{chain: token.ChainID, symbol: strings.ToUpper(token.Symbol)}
+	ak := addressKey{chain: token.ChainID, address: token.Address}
+	if _, exists := r.bySymbol[sk]; exists {
+		return fmt.Errorf("%w: %s", ErrTokenExists, token)
+	}
+	if existing, exists := r.byAddress[ak]; exists {
+		return fmt.Errorf("%w: %s is registered as %s", ErrTokenExists, token.Address.Hex(), existing)
+	}
+	r.bySymbol[sk] = token
+	r.byAddress[ak] = token
+	return nil
+}
+
+// Lookup returns the token with symbol on chain.
+// Returns ErrUnknownToken if it is not registered.
+func (r *Registry) Lookup(chain ChainID, symbol string) (Token, error) {
+	r.mu.RLock()
+	token, ok := r.bySymbol[symbolKey{chain: chain, symbol: strings.ToUpper(symbol)}]
+	r.mu.RUnlock()
+
+	if !ok {
+		return Token{}, fmt.Errorf("%w: %s on %s", ErrUnknownToken, symbol, chain)
+	}
+	return token, nil
+}
+
+// ByAddress returns the token deployed at address on chain.
+// Returns ErrUnknownToken if it is not registered.
+func (r *Registry) ByAddress(chain ChainID, address common.Address) (Token, error) {
+	r.mu.RLock()
+	token, ok := r.byAddress[addressKey{chain: chain, address: address}]
+	r.mu.RUnlock()
+
+	if !ok {
+		return Token{}, fmt.Errorf("%w: %s on %s", ErrUnknownToken, address.Hex(), chain)
+	}
+	return token, nil
+}
+
+// Tokens returns the tokens registered on chain, sorted by symbol.
+func (r *Registry) Tokens(chain ChainID) []Token {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tokens []Token
+	for key, token := range r.bySymbol {
+		if key.chain == chain {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Symbol < tokens[j].Symbol })
+	return tokens
+}
+
+// defaultRegistry backs the package-level Register, Lookup and ByAddress
+// functions.
+var defaultRegistry = NewDefaultRegistry()
+
+// Register adds a token to the default registry.
+// Intended to be called from init functions; panics on an invalid or
+// duplicate registration, like database/sql.Register.
+func Register(token Token) {
+	if err := defaultRegistry.Register(token); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns a token from the default registry.
+func Lookup(chain ChainID, symbol string) (Token, error) {
+	return defaultRegistry.Lookup(chain, symbol)
+}
+
+// ByAddress returns a token from the default registry by address.
+func ByAddress(chain ChainID, address common.Address) (Token, error) {
+	return defaultRegistry.ByAddress(chain, address)
+}
+
+// builtin lists the tokens in the default registry.
+var builtin = []Token{
+	{Mainnet, "WETH", common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"), 18},
+	{Mainnet, "USDC", common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"), 6},
+	{Mainnet, "USDT", common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"), 6},
+	{Mainnet, "DAI", common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F"), 18},
+	{Mainnet, "WBTC", common.HexToAddress("0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599"), 8},
+
+	{Arbitrum, "WETH", common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"), 18},
+	{Arbitrum, "USDC", common.HexToAddress("0xaf88d065e77c8cC2239327C5EDb3A432268e5831"), 6},
+	{Arbitrum, "USDC.e", common.HexToAddress("0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8"), 6},
+	{Arbitrum, "USDT", common.HexToAddress("0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9"), 6},
+	{Arbitrum, "DAI", common.HexToAddress("0xDA10009cBd5D07dd0CeCc66161FC93D7c9000da1"), 18},
+	{Arbitrum, "WBTC", common.HexToAddress("0x2f2a2543B76A4166549F7aaB2e75Bef0aefC5B0f"), 8},
+	{Arbitrum, "ARB", common.HexToAddress("0x912CE59144191C1204E64559FE8253a0e49E6548"), 18},
+
+	{Base, "WETH", common.HexToAddress("0x4200000000000000000000000000000000000006"), 18},
+	{Base, "USDC", common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"), 6},
+	{Base, "DAI", common.HexToAddress("0x50c5725949A6F0c72E6C4a641F24049A917DB0Cb"), 18},
+	{Base, "cbETH", common.HexToAddress("0x2Ae3F1Ec7F1F5012CFEab0185bfc7aa3cf0DEc22"), 18},
+}
@@ -0,0 +1,99 @@
+package tokens_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/primitives"
+	"github.com/johnayoung/go-crypto-quant-toolkit/pkg/tokens"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	tests := []struct {
+		chain    tokens.ChainID
+		symbol   string
+		address  string
+		decimals int32
+	}{
+		{tokens.Mainnet, "USDC", "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", 6},
+		{tokens.Mainnet, "wbtc", "0x2260FAC5E5542a773Aa44fBCfeDf7C193bc2C599", 8},
+		{tokens.Arbitrum, "WETH", "0x82aF49447D8a07e3bd95BD0d56f35241523fBab1", 18},
+		{tokens.Arbitrum, "USDC.e", "0xFF970A61A04b1cA14834A43f5dE4533eBDDB5CC8", 6},
+		{tokens.Base, "WETH", "0x4200000000000000000000000000000000000006", 18},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain.String()+"/"+tt.symbol, func(t *testing.T) {
+			token, err := tokens.Lookup(tt.chain, tt.symbol)
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if token.Address != common.HexToAddress(tt.address) || token.Decimals != tt.decimals {
+				t.Errorf("got %s at %s with %d decimals", token, token.Address.Hex(), token.Decimals)
+			}
+			byAddress, err := tokens.ByAddress(tt.chain, token.Address)
+			if err != nil || byAddress != token {
+				t.Errorf("ByAddress = %v, %v", byAddress, err)
+			}
+		})
+	}
+
+	if _, err := tokens.Lookup(tokens.Base, "WBTC"); !errors.Is(err, tokens.ErrUnknownToken) {
+		t.Errorf("expected ErrUnknownToken, got %v", err)
+	}
+	if _, err := tokens.ByAddress(tokens.Arbitrum, common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")); !errors.Is(err, tokens.ErrUnknownToken) {
+		t.Error("a mainnet address should not resolve on arbitrum")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := tokens.NewDefaultRegistry()
+	gmx := tokens.Token{ChainID: tokens.Arbitrum, Symbol: "GMX", Address: common.HexToAddress("0xfc5A1A6EB076a2C7aD06eD22C90d7E710E35ad0a"), Decimals: 18}
+	if err := r.Register(gmx); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := tokens.Lookup(tokens.Arbitrum, "GMX"); err == nil {
+		t.Error("registering on a new registry should not touch the default registry")
+	}
+
+	duplicate := gmx
+	duplicate.Address = common.HexToAddress("0x1")
+	if err := r.Register(duplicate); !errors.Is(err, tokens.ErrTokenExists) {
+		t.Errorf("duplicate symbol: expected ErrTokenExists, got %v", err)
+	}
+	alias := gmx
+	alias.Symbol = "GMX2"
+	if err := r.Register(alias); !errors.Is(err, tokens.ErrTokenExists) {
+		t.Errorf("duplicate address: expected ErrTokenExists, got %v", err)
+	}
+	if err := r.Register(tokens.Token{ChainID: tokens.Base, Symbol: "X"}); !errors.Is(err, tokens.ErrInvalidToken) {
+		t.Errorf("missing address: expected ErrInvalidToken, got %v", err)
+	}
+
+	var symbols []string
+	for _, token := range r.Tokens(tokens.Base) {
+		symbols = append(symbols, token.Symbol)
+	}
+	if len(symbols) != 4 || symbols[0] != "DAI" {
+		t.Errorf("base tokens = %v", symbols)
+	}
+}
+
+func TestTokenRawConversion(t *testing.T) {
+	usdc, err := tokens.Lookup(tokens.Arbitrum, "USDC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, err := usdc.FromRaw(big.NewInt(1_500_000))
+	if err != nil || amount.String() != "1.5" {
+		t.Errorf("FromRaw = %s, %v; want 1.5", amount, err)
+	}
+	raw, err := usdc.ToRaw(primitives.MustAmount(primitives.MustDecimalFromString("2.25")))
+	if err != nil || raw.Int64() != 2_250_000 {
+		t.Errorf("ToRaw = %v, %v; want 2250000", raw, err)
+	}
+	if _, err := usdc.ToRaw(primitives.MustAmount(primitives.MustDecimalFromString("0.0000001"))); !errors.Is(err, primitives.ErrPrecisionLoss) {
+		t.Errorf("expected ErrPrecisionLoss, got %v", err)
+	}
+}